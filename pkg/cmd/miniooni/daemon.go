@@ -0,0 +1,180 @@
+package main
+
+//
+// Daemon
+//
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"syscall"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-engine/pkg/enginemetrics"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/oonirun"
+	"github.com/ooni/probe-engine/pkg/runevents"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+	"github.com/ooni/probe-engine/pkg/scheduler"
+	"github.com/spf13/cobra"
+)
+
+// daemonConfig is the configuration file of the daemon subcommand.
+type daemonConfig struct {
+	// HookCommand is the OPTIONAL command we run before each job to
+	// know whether we can run it (e.g., `["on_ac_power"]`). We skip
+	// running the job when the command fails.
+	HookCommand []string `json:"hook_command"`
+
+	// Jobs contains the jobs to run.
+	Jobs []*scheduler.Job `json:"jobs"`
+
+	// SkipIfMetered OPTIONALLY skips running jobs when NetworkManager
+	// says that the network is metered.
+	SkipIfMetered bool `json:"skip_if_metered"`
+}
+
+// registerDaemon registers the daemon subcommand
+func registerDaemon(rootCmd *cobra.Command, globalOptions *Options) {
+	subCmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "Periodically runs OONI Run v2 descriptors and groups of experiments",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			daemonMain(globalOptions)
+		},
+	}
+	rootCmd.AddCommand(subCmd)
+	flags := subCmd.Flags()
+	flags.StringVarP(
+		&globalOptions.DaemonConfig,
+		"config",
+		"f",
+		"",
+		"path to the JSON file describing the jobs to run",
+	)
+	flags.Int64Var(
+		&globalOptions.MaxRuntime,
+		"max-runtime",
+		0,
+		"maximum runtime in seconds for each experiment (zero means infinite)",
+	)
+	flags.StringVar(
+		&globalOptions.StatusAddress,
+		"status-address",
+		"127.0.0.1:9876",
+		"address where to serve the JSON status (empty means disabled)",
+	)
+	_ = subCmd.MarkFlagRequired("config")
+}
+
+// daemonMain is the main function of the daemon subcommand.
+func daemonMain(currentOptions *Options) {
+	logger := setupOrPanic(currentOptions)
+
+	data, err := os.ReadFile(currentOptions.DaemonConfig)
+	runtimex.PanicOnError(err, "cannot read daemon config")
+	var config daemonConfig
+	err = json.Unmarshal(data, &config)
+	runtimex.PanicOnError(err, "cannot parse daemon config")
+
+	miniooniDir := miniooniDirOrPanic(currentOptions)
+	acquireUserConsent(miniooniDir, currentOptions)
+
+	// We open the key-value store once and share it between the scheduler
+	// and the sessions we create for running each job.
+	kvstore := newKVStoreOrPanic(currentOptions, miniooniDir)
+	defer closeKVStore(kvstore)
+
+	runner := &daemonRunner{
+		currentOptions: currentOptions,
+		events:         newEventsSink(currentOptions),
+		kvstore:        kvstore,
+		logger:         logger,
+		metrics:        startMetricsServerOrPanic(currentOptions),
+		miniooniDir:    miniooniDir,
+	}
+	sched, err := scheduler.NewScheduler(kvstore, runner, logger, config.Jobs...)
+	runtimex.PanicOnError(err, "cannot create scheduler")
+	if config.SkipIfMetered {
+		sched.Gates = append(sched.Gates, &scheduler.NetworkManagerMeteredGate{Logger: logger})
+	}
+	if len(config.HookCommand) > 0 {
+		sched.Gates = append(sched.Gates, &scheduler.CommandGate{Argv: config.HookCommand})
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if currentOptions.StatusAddress != "" {
+		srv := &http.Server{
+			Addr:              currentOptions.StatusAddress,
+			Handler:           sched,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			log.Infof("daemon: serving status at http://%s/", currentOptions.StatusAddress)
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Warnf("daemon: cannot serve status: %s", err.Error())
+			}
+		}()
+		defer srv.Close()
+	}
+
+	log.Info("daemon: use Ctrl-C to interrupt miniooni")
+	_ = sched.Run(ctx)
+}
+
+// daemonRunner implements [scheduler.Runner].
+type daemonRunner struct {
+	currentOptions *Options
+	events         runevents.Sink
+	kvstore        model.KeyValueStore
+	logger         model.Logger
+	metrics        *enginemetrics.Registry
+	miniooniDir    string
+}
+
+var _ scheduler.Runner = &daemonRunner{}
+
+// RunJob implements scheduler.Runner.
+func (r *daemonRunner) RunJob(ctx context.Context, job *scheduler.Job) (err error) {
+	// The code we reuse from the main subcommand panics on failure but
+	// we want the daemon to keep running, so we convert panics to errors.
+	defer func() {
+		if p := recover(); p != nil {
+			log.Debugf("recovered from panic: %+v\n%s\n", p, debug.Stack())
+			err = fmt.Errorf("%+v", p)
+		}
+	}()
+
+	config := newSessionConfigOrPanic(
+		r.currentOptions, r.miniooniDir, r.kvstore, r.logger, r.events, r.metrics)
+	sess := newSessionWithConfigOrPanic(ctx, config)
+	defer sess.Close()
+	lookupBackendsOrPanic(ctx, sess)
+	lookupLocationOrPanic(ctx, sess)
+
+	cfg := &oonirun.LinkConfig{
+		AcceptChanges: r.currentOptions.Yes,
+		AuthFile:      r.currentOptions.AuthFile,
+		Annotations:   mustMakeMapStringString(r.currentOptions.Annotations),
+		Events:        r.events,
+		KVStore:       sess.KeyValueStore(),
+		MaxRuntime:    r.currentOptions.MaxRuntime,
+		NoCollector:   r.currentOptions.NoCollector,
+		NoJSON:        r.currentOptions.NoJSON,
+		ReportFile:    r.currentOptions.ReportFile,
+		Session:       sess,
+	}
+	if job.URL != "" {
+		return oonirun.NewLinkRunner(cfg, job.URL).Run(ctx)
+	}
+	return oonirun.V2MeasureDescriptor(ctx, cfg, job.Descriptor)
+}
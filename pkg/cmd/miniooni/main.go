@@ -13,6 +13,7 @@ import (
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-engine/pkg/engine"
+	"github.com/ooni/probe-engine/pkg/enginemetrics"
 	"github.com/ooni/probe-engine/pkg/humanize"
 	"github.com/ooni/probe-engine/pkg/legacy/assetsdir"
 	"github.com/ooni/probe-engine/pkg/logx"
@@ -27,12 +28,17 @@ import (
 type Options struct {
 	Annotations         []string
 	AuthFile            string
+	DaemonConfig        string
 	Emoji               bool
+	Events              bool
 	ExtraOptions        []string
+	HealthMaxAge        int64
 	HomeDir             string
 	Inputs              []string
 	InputFilePaths      []string
+	KVStore             string
 	MaxRuntime          int64
+	MetricsAddress      string
 	NoJSON              bool
 	NoCollector         bool
 	ProbeServicesURL    string
@@ -43,6 +49,7 @@ type Options struct {
 	SnowflakeRendezvous string
 	SoftwareName        string
 	SoftwareVersion     string
+	StatusAddress       string
 	TorArgs             []string
 	TorBinary           string
 	Tunnel              string
@@ -77,6 +84,20 @@ func main() {
 		"whether to use emojis when logging",
 	)
 
+	flags.BoolVar(
+		&globalOptions.Events,
+		"events",
+		false,
+		"emit a JSON-lines stream of structured events on the standard output",
+	)
+
+	flags.Int64Var(
+		&globalOptions.HealthMaxAge,
+		"health-max-age",
+		86400,
+		"seconds without successful submissions after which /healthz reports failure",
+	)
+
 	flags.StringVar(
 		&globalOptions.HomeDir,
 		"home",
@@ -84,6 +105,20 @@ func main() {
 		"force specific home directory",
 	)
 
+	flags.StringVar(
+		&globalOptions.KVStore,
+		"kvstore",
+		"fs",
+		"key-value store for the engine state (one of: \"fs\" and \"sqlite\")",
+	)
+
+	flags.StringVar(
+		&globalOptions.MetricsAddress,
+		"metrics-address",
+		"",
+		"address where to serve Prometheus metrics at /metrics and health at /healthz (empty means disabled)",
+	)
+
 	flags.BoolVarP(
 		&globalOptions.NoJSON,
 		"no-json",
@@ -192,6 +227,8 @@ func main() {
 	registerAllExperiments(rootCmd, &globalOptions)
 	registerOONIRun(rootCmd, &globalOptions)
 	registerJavaScript(rootCmd, &globalOptions)
+	registerDaemon(rootCmd, &globalOptions)
+	registerResults(rootCmd, &globalOptions)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -311,6 +348,22 @@ func registerAllExperiments(rootCmd *cobra.Command, globalOptions *Options) {
 // This function will panic in case of a fatal error. It is up to you that
 // integrate this function to either handle the panic of ignore it.
 func MainWithConfiguration(experimentName string, currentOptions *Options) {
+	logger := setupOrPanic(currentOptions)
+	metrics := startMetricsServerOrPanic(currentOptions)
+	for {
+		mainSingleIteration(logger, metrics, experimentName, currentOptions)
+		if currentOptions.RepeatEvery <= 0 {
+			break
+		}
+		log.Infof("waiting %ds before repeating the measurement", currentOptions.RepeatEvery)
+		log.Info("use Ctrl-C to interrupt miniooni")
+		time.Sleep(time.Duration(currentOptions.RepeatEvery) * time.Second)
+	}
+}
+
+// setupOrPanic checks and completes the current options and configures
+// logging. It returns the logger to use and panics on failure.
+func setupOrPanic(currentOptions *Options) *log.Logger {
 	runtimex.PanicOnError(engine.CheckEmbeddedPsiphonConfig(), "Invalid embedded psiphon config")
 	if currentOptions.Tunnel != "" {
 		currentOptions.Proxy = fmt.Sprintf("%s:///", currentOptions.Tunnel)
@@ -326,20 +379,13 @@ func MainWithConfiguration(experimentName string, currentOptions *Options) {
 		currentOptions.ReportFile = "report.jsonl"
 	}
 	log.Log = logger
-	for {
-		mainSingleIteration(logger, experimentName, currentOptions)
-		if currentOptions.RepeatEvery <= 0 {
-			break
-		}
-		log.Infof("waiting %ds before repeating the measurement", currentOptions.RepeatEvery)
-		log.Info("use Ctrl-C to interrupt miniooni")
-		time.Sleep(time.Duration(currentOptions.RepeatEvery) * time.Second)
-	}
+	return logger
 }
 
 // mainSingleIteration runs a single iteration. There may be multiple iterations
 // when the user specifies the --repeat-every command line flag.
-func mainSingleIteration(logger model.Logger, experimentName string, currentOptions *Options) {
+func mainSingleIteration(logger model.Logger, metrics *enginemetrics.Registry,
+	experimentName string, currentOptions *Options) {
 
 	// We allow the inner code to fail but we stop propagating the panic here
 	// such that --repeat-every works as intended anyway
@@ -359,27 +405,13 @@ func mainSingleIteration(logger model.Logger, experimentName string, currentOpti
 	//Mon Jan 2 15:04:05 -0700 MST 2006
 	log.Infof("Current time: %s", time.Now().UTC().Format("2006-01-02 15:04:05 MST"))
 
-	homeDir := gethomedir(currentOptions.HomeDir)
-	runtimex.Assert(homeDir != "", "home directory is empty")
-	miniooniDir := path.Join(homeDir, ".miniooni")
-	err := os.MkdirAll(miniooniDir, 0700)
-	runtimex.PanicOnError(err, "cannot create $HOME/.miniooni directory")
-
-	// We cleanup the assets files used by versions of ooniprobe
-	// older than v3.9.0, where we started embedding the assets
-	// into the binary and use that directly. This cleanup doesn't
-	// remove the whole directory but only known files inside it
-	// and then the directory itself, if empty. We explicitly discard
-	// the return value as it does not matter to us here.
-	assetsDir := path.Join(miniooniDir, "assets")
-	_, _ = assetsdir.Cleanup(assetsDir)
-
-	log.Debugf("miniooni state directory: %s", miniooniDir)
-	log.Info("miniooni home directory: $HOME/.miniooni")
+	miniooniDir := miniooniDirOrPanic(currentOptions)
 
 	acquireUserConsent(miniooniDir, currentOptions)
 
-	sess := newSessionOrPanic(ctx, currentOptions, miniooniDir, logger)
+	events := newEventsSink(currentOptions)
+
+	sess := newSessionOrPanic(ctx, currentOptions, miniooniDir, logger, events, metrics)
 	defer func() {
 		_ = sess.Close()
 		log.Infof("whole session: recv %s, sent %s",
@@ -393,12 +425,12 @@ func mainSingleIteration(logger model.Logger, experimentName string, currentOpti
 	// We handle the oonirun experiment name specially. The user must specify
 	// `miniooni -i {OONIRunURL} oonirun` to run a OONI Run URL (v1 or v2).
 	if experimentName == "oonirun" {
-		ooniRunMain(ctx, sess, currentOptions, annotations)
+		ooniRunMain(ctx, sess, currentOptions, annotations, events)
 		return
 	}
 
 	// Otherwise just run OONI experiments as we normally do.
-	runx(ctx, sess, experimentName, annotations, extraOptions, currentOptions)
+	runx(ctx, sess, experimentName, annotations, extraOptions, currentOptions, events)
 }
 
 func documentationForOptions(factory *registry.Factory) string {
@@ -418,3 +450,25 @@ func documentationForOptions(factory *registry.Factory) string {
 	}
 	return sb.String()
 }
+
+// miniooniDirOrPanic creates and returns the miniooni state directory.
+func miniooniDirOrPanic(currentOptions *Options) string {
+	homeDir := gethomedir(currentOptions.HomeDir)
+	runtimex.Assert(homeDir != "", "home directory is empty")
+	miniooniDir := path.Join(homeDir, ".miniooni")
+	err := os.MkdirAll(miniooniDir, 0700)
+	runtimex.PanicOnError(err, "cannot create $HOME/.miniooni directory")
+
+	// We cleanup the assets files used by versions of ooniprobe
+	// older than v3.9.0, where we started embedding the assets
+	// into the binary and use that directly. This cleanup doesn't
+	// remove the whole directory but only known files inside it
+	// and then the directory itself, if empty. We explicitly discard
+	// the return value as it does not matter to us here.
+	assetsDir := path.Join(miniooniDir, "assets")
+	_, _ = assetsdir.Cleanup(assetsDir)
+
+	log.Debugf("miniooni state directory: %s", miniooniDir)
+	log.Info("miniooni home directory: $HOME/.miniooni")
+	return miniooniDir
+}
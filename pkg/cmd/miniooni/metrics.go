@@ -0,0 +1,45 @@
+package main
+
+//
+// Metrics
+//
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-engine/pkg/enginemetrics"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+)
+
+// startMetricsServerOrPanic creates a metrics registry and serves it in the
+// background when the user specified --metrics-address. Otherwise, it returns
+// a nil registry, which causes sessions to not collect metrics.
+func startMetricsServerOrPanic(currentOptions *Options) *enginemetrics.Registry {
+	if currentOptions.MetricsAddress == "" {
+		return nil
+	}
+	metrics := enginemetrics.NewRegistry()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	maxAge := time.Duration(currentOptions.HealthMaxAge) * time.Second
+	mux.Handle("/healthz", metrics.HealthHandler(maxAge))
+
+	// listen synchronously such that we fail early when the address is busy
+	listener, err := net.Listen("tcp", currentOptions.MetricsAddress)
+	runtimex.PanicOnError(err, "cannot listen for serving metrics")
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Infof("serving metrics at http://%s/metrics", listener.Addr().String())
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Warnf("cannot serve metrics: %s", err.Error())
+		}
+	}()
+	return metrics
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestStartMetricsServerOrPanic(t *testing.T) {
+	t.Run("when metrics are disabled", func(t *testing.T) {
+		if metrics := startMetricsServerOrPanic(&Options{}); metrics != nil {
+			t.Fatal("expected nil registry")
+		}
+	})
+
+	t.Run("when metrics are enabled", func(t *testing.T) {
+		metrics := startMetricsServerOrPanic(&Options{MetricsAddress: "127.0.0.1:0"})
+		if metrics == nil {
+			t.Fatal("expected non-nil registry")
+		}
+	})
+
+	t.Run("when we cannot listen", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("expected a panic")
+			}
+		}()
+		startMetricsServerOrPanic(&Options{MetricsAddress: "127.0.0.1:-1"})
+	})
+}
@@ -12,17 +12,19 @@ import (
 
 	"github.com/ooni/probe-engine/pkg/engine"
 	"github.com/ooni/probe-engine/pkg/oonirun"
+	"github.com/ooni/probe-engine/pkg/runevents"
 )
 
 // ooniRunMain runs the experiments described by the given OONI Run URLs. This
 // function works with both v1 and v2 OONI Run URLs.
-func ooniRunMain(ctx context.Context,
-	sess *engine.Session, currentOptions *Options, annotations map[string]string) {
+func ooniRunMain(ctx context.Context, sess *engine.Session,
+	currentOptions *Options, annotations map[string]string, events runevents.Sink) {
 	logger := sess.Logger()
 	cfg := &oonirun.LinkConfig{
 		AcceptChanges: currentOptions.Yes,
 		AuthFile:      currentOptions.AuthFile,
 		Annotations:   annotations,
+		Events:        events,
 		KVStore:       sess.KeyValueStore(),
 		MaxRuntime:    currentOptions.MaxRuntime,
 		NoCollector:   currentOptions.NoCollector,
@@ -0,0 +1,528 @@
+package main
+
+//
+// Results
+//
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-engine/pkg/database"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+	"github.com/spf13/cobra"
+)
+
+// resultsOptions contains the options of the results subcommands.
+type resultsOptions struct {
+	ASN          int64
+	Anomaly      string
+	CategoryCode string
+	Database     string
+	Failed       string
+	Format       string
+	JSON         bool
+	Output       string
+	Since        string
+	TestName     string
+	URL          string
+	Until        string
+}
+
+// registerResults registers the results subcommand
+func registerResults(rootCmd *cobra.Command, globalOptions *Options) {
+	ro := &resultsOptions{}
+	subCmd := &cobra.Command{
+		Use:   "results",
+		Short: "Inspects and exports the measurements stored in the results database",
+	}
+	rootCmd.AddCommand(subCmd)
+	subCmd.PersistentFlags().StringVar(
+		&ro.Database,
+		"database",
+		"",
+		"path to the results database (default: $HOME/.ooniprobe/db/main.sqlite3)",
+	)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Lists the results along with a summary per network and per test",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			db := openResultsDatabaseOrPanic(globalOptions, ro)
+			defer db.Close()
+			runtimex.Try0(resultsList(os.Stdout, db, mustNewResultsFilter(ro)))
+		},
+	}
+	registerResultsFilterFlags(listCmd, ro, false)
+	subCmd.AddCommand(listCmd)
+
+	showCmd := &cobra.Command{
+		Use:   "show MEASUREMENT_ID",
+		Short: "Shows a single measurement",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			db := openResultsDatabaseOrPanic(globalOptions, ro)
+			defer db.Close()
+			runtimex.Try0(resultsShow(os.Stdout, db, mustParseID(args[0]), ro.JSON))
+		},
+	}
+	showCmd.Flags().BoolVar(
+		&ro.JSON,
+		"json",
+		false,
+		"print the full measurement as JSON",
+	)
+	subCmd.AddCommand(showCmd)
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Exports the selected measurements as JSONL or CSV",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			db := openResultsDatabaseOrPanic(globalOptions, ro)
+			defer db.Close()
+			var w io.Writer = os.Stdout
+			if ro.Output != "" {
+				filep, err := os.Create(ro.Output)
+				runtimex.PanicOnError(err, "cannot create output file")
+				defer filep.Close()
+				w = filep
+			}
+			runtimex.Try0(resultsExport(w, db, mustNewResultsFilter(ro), ro.Format))
+		},
+	}
+	registerResultsFilterFlags(exportCmd, ro, true)
+	exportCmd.Flags().StringVar(
+		&ro.Format,
+		"format",
+		"jsonl",
+		"export format (one of: \"jsonl\" and \"csv\")",
+	)
+	exportCmd.Flags().StringVar(
+		&ro.Output,
+		"output",
+		"",
+		"write the export to this file rather than to the standard output",
+	)
+	subCmd.AddCommand(exportCmd)
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete RESULT_ID",
+		Short: "Deletes a result along with its measurements",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runtimex.Assert(globalOptions.Yes, "refusing to delete a result without `-y`")
+			db := openResultsDatabaseOrPanic(globalOptions, ro)
+			defer db.Close()
+			runtimex.Try0(db.DeleteResult(mustParseID(args[0])))
+		},
+	}
+	subCmd.AddCommand(deleteCmd)
+
+	uploadCmd := &cobra.Command{
+		Use:   "upload",
+		Short: "Uploads the selected measurements that we have not uploaded yet",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := setupOrPanic(globalOptions)
+			db := openResultsDatabaseOrPanic(globalOptions, ro)
+			defer db.Close()
+			ctx := context.Background()
+			miniooniDir := miniooniDirOrPanic(globalOptions)
+			kvstore := newKVStoreOrPanic(globalOptions, miniooniDir)
+			defer closeKVStore(kvstore)
+			config := newSessionConfigOrPanic(globalOptions, miniooniDir, kvstore, logger, nil, nil)
+			config.SubmitQueueDatabase = db
+			sess := newSessionWithConfigOrPanic(ctx, config)
+			defer sess.Close()
+			queue, err := sess.NewSubmitQueue(ctx)
+			runtimex.PanicOnError(err, "cannot create submit queue")
+			count, err := resultsUpload(ctx, db, queue, mustNewResultsFilter(ro))
+			runtimex.PanicOnError(err, "cannot upload measurements")
+			log.Infof("uploaded %d measurements", count)
+		},
+	}
+	registerResultsFilterFlags(uploadCmd, ro, true)
+	subCmd.AddCommand(uploadCmd)
+}
+
+// registerResultsFilterFlags registers the flags to select measurements. When
+// full is false, we only register the flags that make sense for results.
+func registerResultsFilterFlags(cmd *cobra.Command, ro *resultsOptions, full bool) {
+	flags := cmd.Flags()
+	flags.Int64Var(&ro.ASN, "asn", 0, "only select measurements collected from this ASN")
+	flags.StringVar(&ro.Since, "since", "", "only select measurements started at or after this date")
+	flags.StringVar(&ro.Until, "until", "", "only select measurements started before this date")
+	if !full {
+		return
+	}
+	flags.StringVar(&ro.Anomaly, "anomaly", "", "only select measurements with this anomaly state (true or false)")
+	flags.StringVar(&ro.CategoryCode, "category", "", "only select measurements of URLs with this category code")
+	flags.StringVar(&ro.Failed, "failed", "", "only select measurements with this failure state (true or false)")
+	flags.StringVar(&ro.TestName, "test", "", "only select measurements of this test")
+	flags.StringVar(&ro.URL, "url", "", "only select measurements whose URL contains this string")
+}
+
+// openResultsDatabaseOrPanic opens the results database or panics on failure.
+func openResultsDatabaseOrPanic(currentOptions *Options, ro *resultsOptions) *database.Database {
+	dbpath := ro.Database
+	if dbpath == "" {
+		homeDir := gethomedir(currentOptions.HomeDir)
+		runtimex.Assert(homeDir != "", "home directory is empty")
+		dbpath = filepath.Join(homeDir, ".ooniprobe", "db", "main.sqlite3")
+	}
+	// Note: opening the database creates it when it does not exist, which is
+	// not what we want when we're only going to inspect it.
+	_, err := os.Stat(dbpath)
+	runtimex.PanicOnError(err, "cannot access results database")
+	db, err := database.Open(dbpath)
+	runtimex.PanicOnError(err, "cannot open results database")
+	return db
+}
+
+// mustParseID parses a database ID or panics on failure.
+func mustParseID(s string) int64 {
+	id, err := strconv.ParseInt(s, 10, 64)
+	runtimex.PanicOnError(err, "cannot parse ID")
+	return id
+}
+
+// mustNewResultsFilter creates the filter to select measurements or panics on failure.
+func mustNewResultsFilter(ro *resultsOptions) *model.DatabaseMeasurementFilter {
+	filter, err := newResultsFilter(ro)
+	runtimex.PanicOnError(err, "invalid filter")
+	return filter
+}
+
+// newResultsFilter creates the filter to select measurements.
+func newResultsFilter(ro *resultsOptions) (*model.DatabaseMeasurementFilter, error) {
+	filter := &model.DatabaseMeasurementFilter{
+		CategoryCode: ro.CategoryCode,
+		TestName:     ro.TestName,
+		URL:          ro.URL,
+	}
+	if ro.ASN > 0 {
+		filter.ASN = sql.NullInt64{Int64: ro.ASN, Valid: true}
+	}
+	var err error
+	if filter.Since, err = parseResultsTime(ro.Since); err != nil {
+		return nil, err
+	}
+	if filter.Until, err = parseResultsTime(ro.Until); err != nil {
+		return nil, err
+	}
+	if filter.IsAnomaly, err = parseResultsBool(ro.Anomaly); err != nil {
+		return nil, err
+	}
+	if filter.IsFailed, err = parseResultsBool(ro.Failed); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// parseResultsTime parses a date or a RFC3339 time, if not empty.
+func parseResultsTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// parseResultsBool parses an optional boolean.
+func parseResultsBool(s string) (sql.NullBool, error) {
+	if s == "" {
+		return sql.NullBool{}, nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return sql.NullBool{}, err
+	}
+	return sql.NullBool{Bool: v, Valid: true}, nil
+}
+
+// resultsList prints the results along with a summary per network and per test.
+func resultsList(w io.Writer, db model.ReadableDatabase, filter *model.DatabaseMeasurementFilter) error {
+	done, incomplete, err := db.ListResults()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESULT\tGROUP\tSTART TIME\tNETWORK\tMEASUREMENTS\tANOMALIES\tUPLOADED\tDONE")
+	for _, entry := range append(done, incomplete...) {
+		if !resultMatchesFilter(&entry, filter) {
+			continue
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\tAS%d (%s)\t%d\t%d\t%s\t%s\n",
+			entry.DatabaseResult.ID,
+			entry.TestGroupName,
+			entry.DatabaseResult.StartTime.UTC().Format(time.RFC3339),
+			entry.ASN,
+			entry.DatabaseNetwork.CountryCode,
+			entry.TotalCount,
+			entry.AnomalyCount,
+			yesOrNo(entry.DatabaseResult.IsUploaded),
+			yesOrNo(entry.DatabaseResult.IsDone),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	networks, err := db.CountMeasurementsByNetwork(filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(tw, "NETWORK\tNAME\tCOUNTRY\tMEASUREMENTS\tANOMALIES\tFAILURES")
+	for _, entry := range networks {
+		fmt.Fprintf(tw, "AS%d\t%s\t%s\t%d\t%d\t%d\n", entry.ASN, entry.NetworkName,
+			entry.CountryCode, entry.TotalCount, entry.AnomalyCount, entry.FailureCount)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	tests, err := db.CountMeasurementsByTest(filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(tw, "TEST\tMEASUREMENTS\tANOMALIES\tFAILURES")
+	for _, entry := range tests {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", entry.TestName,
+			entry.TotalCount, entry.AnomalyCount, entry.FailureCount)
+	}
+	return tw.Flush()
+}
+
+// resultMatchesFilter returns whether a result matches the filter fields
+// that make sense for results, i.e., the ASN and the time range.
+func resultMatchesFilter(entry *model.DatabaseResultNetwork, filter *model.DatabaseMeasurementFilter) bool {
+	if filter.ASN.Valid && int64(entry.ASN) != filter.ASN.Int64 {
+		return false
+	}
+	if !filter.Since.IsZero() && entry.DatabaseResult.StartTime.Before(filter.Since) {
+		return false
+	}
+	if !filter.Until.IsZero() && !entry.DatabaseResult.StartTime.Before(filter.Until) {
+		return false
+	}
+	return true
+}
+
+// errNoSuchMeasurement indicates that we cannot find a measurement.
+var errNoSuchMeasurement = errors.New("no such measurement")
+
+// resultsShow prints a single measurement. When raw is true, we print the
+// whole measurement as JSON rather than printing a summary.
+func resultsShow(w io.Writer, db model.ReadableDatabase, msmtID int64, raw bool) error {
+	if raw {
+		measurement, err := db.GetMeasurementJSON(msmtID)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(measurement, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+	msmt, err := db.GetMeasurement(msmtID)
+	if err != nil {
+		return err
+	}
+	// Note: listing the measurements of the result gives us the URL and the
+	// network, which we cannot obtain from the measurement alone.
+	entries, err := db.ListMeasurements(msmt.ResultID)
+	if err != nil {
+		return err
+	}
+	var entry *model.DatabaseMeasurementURLNetwork
+	for idx := range entries {
+		if entries[idx].DatabaseMeasurement.ID == msmtID {
+			entry = &entries[idx]
+			break
+		}
+	}
+	if entry == nil {
+		return errNoSuchMeasurement
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "measurement:\t%d\n", entry.DatabaseMeasurement.ID)
+	fmt.Fprintf(tw, "result:\t%d (%s)\n", entry.DatabaseResult.ID, entry.TestGroupName)
+	fmt.Fprintf(tw, "test:\t%s\n", entry.TestName)
+	fmt.Fprintf(tw, "start time:\t%s\n", entry.DatabaseMeasurement.StartTime.UTC().Format(time.RFC3339))
+	fmt.Fprintf(tw, "runtime:\t%.3f s\n", entry.DatabaseMeasurement.Runtime)
+	if entry.DatabaseURL.URL.Valid {
+		fmt.Fprintf(tw, "input:\t%s (%s)\n", entry.DatabaseURL.URL.String, entry.DatabaseURL.CategoryCode.String)
+	}
+	fmt.Fprintf(tw, "network:\tAS%d %s (%s)\n", entry.ASN, entry.NetworkName, entry.DatabaseNetwork.CountryCode)
+	fmt.Fprintf(tw, "verdict:\t%s\n", measurementVerdict(&entry.DatabaseMeasurement))
+	if entry.FailureMsg.Valid {
+		fmt.Fprintf(tw, "failure:\t%s\n", entry.FailureMsg.String)
+	}
+	fmt.Fprintf(tw, "uploaded:\t%s\n", yesOrNo(entry.DatabaseMeasurement.IsUploaded))
+	if entry.ReportID.Valid {
+		fmt.Fprintf(tw, "report id:\t%s\n", entry.ReportID.String)
+	}
+	if entry.UploadFailureMsg.Valid {
+		fmt.Fprintf(tw, "upload failure:\t%s\n", entry.UploadFailureMsg.String)
+	}
+	fmt.Fprintf(tw, "summary:\t%s\n", entry.DatabaseMeasurement.TestKeys)
+	return tw.Flush()
+}
+
+// measurementVerdict returns the verdict of a measurement according to the
+// anomaly flag we stored using the measurement's MeasurementSummaryKeys.
+func measurementVerdict(msmt *model.DatabaseMeasurement) string {
+	switch {
+	case msmt.IsFailed:
+		return "failed"
+	case !msmt.IsAnomaly.Valid:
+		return "unknown"
+	case msmt.IsAnomaly.Bool:
+		return "anomaly"
+	default:
+		return "ok"
+	}
+}
+
+// yesOrNo converts a bool to "yes" or "no".
+func yesOrNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
+
+// errUnsupportedExportFormat indicates that the export format is not supported.
+var errUnsupportedExportFormat = errors.New("unsupported export format")
+
+// resultsExport exports the measurements selected by filter using the given format.
+func resultsExport(w io.Writer, db model.ReadableDatabase,
+	filter *model.DatabaseMeasurementFilter, format string) error {
+	switch format {
+	case "jsonl", "csv":
+	default:
+		return fmt.Errorf("%w: %s", errUnsupportedExportFormat, format)
+	}
+	entries, _, err := db.SearchMeasurements(filter)
+	if err != nil {
+		return err
+	}
+	if format == "csv" {
+		return resultsExportCSV(w, entries)
+	}
+	for _, entry := range entries {
+		measurement, err := db.GetMeasurementJSON(entry.DatabaseMeasurement.ID)
+		if err != nil {
+			log.Warnf("cannot export measurement %d: %s", entry.DatabaseMeasurement.ID, err.Error())
+			continue
+		}
+		data, err := json.Marshal(measurement)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resultsExportCSV exports a summary of each measurement as CSV.
+func resultsExportCSV(w io.Writer, entries []model.DatabaseMeasurementURLNetwork) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{
+		"measurement_id", "result_id", "test_name", "start_time", "runtime",
+		"asn", "network_name", "country_code", "url", "category_code",
+		"verdict", "failure", "is_uploaded", "report_id",
+	})
+	for _, entry := range entries {
+		_ = cw.Write([]string{
+			strconv.FormatInt(entry.DatabaseMeasurement.ID, 10),
+			strconv.FormatInt(entry.DatabaseResult.ID, 10),
+			entry.TestName,
+			entry.DatabaseMeasurement.StartTime.UTC().Format(time.RFC3339),
+			strconv.FormatFloat(entry.DatabaseMeasurement.Runtime, 'f', -1, 64),
+			strconv.FormatUint(uint64(entry.ASN), 10),
+			entry.NetworkName,
+			entry.DatabaseNetwork.CountryCode,
+			entry.DatabaseURL.URL.String,
+			entry.DatabaseURL.CategoryCode.String,
+			measurementVerdict(&entry.DatabaseMeasurement),
+			entry.FailureMsg.String,
+			strconv.FormatBool(entry.DatabaseMeasurement.IsUploaded),
+			entry.ReportID.String,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// resultsSubmitQueue is the resultsUpload's view of [*probeservices.SubmitQueue].
+type resultsSubmitQueue interface {
+	Enqueue(m *model.Measurement, msmtID, resultID int64) (string, error)
+	Flush(ctx context.Context) (int, error)
+}
+
+// resultsUpload enqueues the completed measurements selected by filter that we
+// have not uploaded yet and then flushes the queue. The queue updates the
+// database as it uploads. It returns the number of uploaded measurements.
+func resultsUpload(ctx context.Context, db model.ReadableDatabase,
+	queue resultsSubmitQueue, filter *model.DatabaseMeasurementFilter) (int, error) {
+	filter.IsUploaded = sql.NullBool{Bool: false, Valid: true}
+	entries, _, err := db.SearchMeasurements(filter)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if !entry.DatabaseMeasurement.IsDone {
+			continue
+		}
+		msmtID := entry.DatabaseMeasurement.ID
+		measurement, err := resultsLoadMeasurement(db, msmtID)
+		if err != nil {
+			log.Warnf("cannot load measurement %d: %s", msmtID, err.Error())
+			continue
+		}
+		if _, err := queue.Enqueue(measurement, msmtID, entry.DatabaseMeasurement.ResultID); err != nil {
+			return 0, err
+		}
+	}
+	return queue.Flush(ctx)
+}
+
+// resultsLoadMeasurement loads the JSON measurement with the given ID.
+func resultsLoadMeasurement(db model.ReadableDatabase, msmtID int64) (*model.Measurement, error) {
+	raw, err := db.GetMeasurementJSON(msmtID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var measurement model.Measurement
+	if err := json.Unmarshal(data, &measurement); err != nil {
+		return nil, err
+	}
+	return &measurement, nil
+}
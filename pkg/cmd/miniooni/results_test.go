@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/mocks"
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+// newResultsDatabaseForTesting returns a database containing a single
+// result with a single web_connectivity measurement.
+func newResultsDatabaseForTesting() *mocks.Database {
+	result := model.DatabaseResult{
+		ID:            7,
+		TestGroupName: "websites",
+		StartTime:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		IsDone:        true,
+	}
+	network := model.DatabaseNetwork{
+		NetworkName: "Vodafone",
+		ASN:         30722,
+		CountryCode: "IT",
+	}
+	msmt := model.DatabaseMeasurement{
+		ID:        42,
+		TestName:  "web_connectivity",
+		StartTime: result.StartTime,
+		Runtime:   1.5,
+		ReportID:  sql.NullString{String: "20240102T030405Z_webconnectivity_IT_30722_n1_xx", Valid: true},
+		IsAnomaly: sql.NullBool{Bool: true, Valid: true},
+		TestKeys:  `{"blocking":"dns"}`,
+		ResultID:  result.ID,
+	}
+	entry := model.DatabaseMeasurementURLNetwork{
+		DatabaseMeasurement: msmt,
+		DatabaseNetwork:     network,
+		DatabaseResult:      result,
+		DatabaseURL: model.DatabaseURL{
+			URL:          sql.NullString{String: "https://www.example.com/", Valid: true},
+			CategoryCode: sql.NullString{String: "NEWS", Valid: true},
+		},
+	}
+	return &mocks.Database{
+		MockListResults: func() ([]model.DatabaseResultNetwork, []model.DatabaseResultNetwork, error) {
+			done := []model.DatabaseResultNetwork{{
+				DatabaseResult:  result,
+				DatabaseNetwork: network,
+				AnomalyCount:    1,
+				TotalCount:      1,
+			}}
+			return done, nil, nil
+		},
+		MockCountMeasurementsByNetwork: func(filter *model.DatabaseMeasurementFilter) ([]model.DatabaseNetworkCount, error) {
+			return []model.DatabaseNetworkCount{{DatabaseNetwork: network, AnomalyCount: 1, TotalCount: 1}}, nil
+		},
+		MockCountMeasurementsByTest: func(filter *model.DatabaseMeasurementFilter) ([]model.DatabaseTestCount, error) {
+			return []model.DatabaseTestCount{{TestName: "web_connectivity", AnomalyCount: 1, TotalCount: 1}}, nil
+		},
+		MockGetMeasurement: func(msmtID int64) (*model.DatabaseMeasurement, error) {
+			if msmtID != msmt.ID {
+				return nil, errors.New("no more items in the collection")
+			}
+			return &msmt, nil
+		},
+		MockListMeasurements: func(resultID int64) ([]model.DatabaseMeasurementURLNetwork, error) {
+			return []model.DatabaseMeasurementURLNetwork{entry}, nil
+		},
+		MockGetMeasurementJSON: func(msmtID int64) (map[string]interface{}, error) {
+			return map[string]interface{}{"test_name": "web_connectivity"}, nil
+		},
+		MockSearchMeasurements: func(filter *model.DatabaseMeasurementFilter) ([]model.DatabaseMeasurementURLNetwork, int64, error) {
+			return []model.DatabaseMeasurementURLNetwork{entry}, 1, nil
+		},
+	}
+}
+
+func TestResultsList(t *testing.T) {
+	t.Run("we print results, networks, and tests", func(t *testing.T) {
+		var buf bytes.Buffer
+		db := newResultsDatabaseForTesting()
+		if err := resultsList(&buf, db, &model.DatabaseMeasurementFilter{}); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		for _, expect := range []string{"websites", "AS30722 (IT)", "Vodafone", "web_connectivity"} {
+			if !strings.Contains(out, expect) {
+				t.Fatal("output does not contain", expect)
+			}
+		}
+	})
+
+	t.Run("we filter results by ASN", func(t *testing.T) {
+		var buf bytes.Buffer
+		db := newResultsDatabaseForTesting()
+		filter := &model.DatabaseMeasurementFilter{ASN: sql.NullInt64{Int64: 137, Valid: true}}
+		if err := resultsList(&buf, db, filter); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(buf.String(), "websites") {
+			t.Fatal("should not have printed the result")
+		}
+	})
+
+	t.Run("we handle errors", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		db := newResultsDatabaseForTesting()
+		db.MockListResults = func() ([]model.DatabaseResultNetwork, []model.DatabaseResultNetwork, error) {
+			return nil, nil, expected
+		}
+		if err := resultsList(&bytes.Buffer{}, db, &model.DatabaseMeasurementFilter{}); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestResultsShow(t *testing.T) {
+	t.Run("we print a summary including the verdict", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := resultsShow(&buf, newResultsDatabaseForTesting(), 42, false); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		for _, expect := range []string{"https://www.example.com/ (NEWS)", "anomaly", `{"blocking":"dns"}`} {
+			if !strings.Contains(out, expect) {
+				t.Fatal("output does not contain", expect)
+			}
+		}
+	})
+
+	t.Run("we print the raw JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := resultsShow(&buf, newResultsDatabaseForTesting(), 42, true); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), `"test_name": "web_connectivity"`) {
+			t.Fatal("unexpected output", buf.String())
+		}
+	})
+
+	t.Run("when the measurement is not in its result", func(t *testing.T) {
+		db := newResultsDatabaseForTesting()
+		db.MockListMeasurements = func(resultID int64) ([]model.DatabaseMeasurementURLNetwork, error) {
+			return nil, nil
+		}
+		if err := resultsShow(&bytes.Buffer{}, db, 42, false); !errors.Is(err, errNoSuchMeasurement) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestResultsExport(t *testing.T) {
+	t.Run("as JSONL", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := resultsExport(&buf, newResultsDatabaseForTesting(), &model.DatabaseMeasurementFilter{}, "jsonl"); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(`{"test_name":"web_connectivity"}`+"\n", buf.String()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("as CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := resultsExport(&buf, newResultsDatabaseForTesting(), &model.DatabaseMeasurementFilter{}, "csv"); err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 {
+			t.Fatal("expected two records, got", len(records))
+		}
+		expect := []string{
+			"42", "7", "web_connectivity", "2024-01-02T03:04:05Z", "1.5",
+			"30722", "Vodafone", "IT", "https://www.example.com/", "NEWS",
+			"anomaly", "", "false", "20240102T030405Z_webconnectivity_IT_30722_n1_xx",
+		}
+		if diff := cmp.Diff(expect, records[1]); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with unsupported format", func(t *testing.T) {
+		err := resultsExport(&bytes.Buffer{}, newResultsDatabaseForTesting(), &model.DatabaseMeasurementFilter{}, "xml")
+		if !errors.Is(err, errUnsupportedExportFormat) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestNewResultsFilter(t *testing.T) {
+	t.Run("with valid options", func(t *testing.T) {
+		filter, err := newResultsFilter(&resultsOptions{
+			ASN:     30722,
+			Anomaly: "true",
+			Since:   "2024-01-02",
+			Until:   "2024-02-01T00:00:00Z",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := &model.DatabaseMeasurementFilter{
+			ASN:       sql.NullInt64{Int64: 30722, Valid: true},
+			IsAnomaly: sql.NullBool{Bool: true, Valid: true},
+			Since:     time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			Until:     time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		}
+		if diff := cmp.Diff(expect, filter); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with invalid time", func(t *testing.T) {
+		if _, err := newResultsFilter(&resultsOptions{Since: "yesterday"}); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("with invalid bool", func(t *testing.T) {
+		if _, err := newResultsFilter(&resultsOptions{Failed: "maybe"}); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestMeasurementVerdict(t *testing.T) {
+	cases := map[string]*model.DatabaseMeasurement{
+		"failed":  {IsFailed: true},
+		"unknown": {},
+		"anomaly": {IsAnomaly: sql.NullBool{Bool: true, Valid: true}},
+		"ok":      {IsAnomaly: sql.NullBool{Bool: false, Valid: true}},
+	}
+	for expect, msmt := range cases {
+		if got := measurementVerdict(msmt); got != expect {
+			t.Fatal("expected", expect, "got", got)
+		}
+	}
+}
+
+// resultsSubmitQueueForTesting is a [resultsSubmitQueue] recording enqueued measurements.
+type resultsSubmitQueueForTesting struct {
+	enqueued []int64
+	err      error
+}
+
+func (q *resultsSubmitQueueForTesting) Enqueue(m *model.Measurement, msmtID, resultID int64) (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+	q.enqueued = append(q.enqueued, msmtID)
+	return "key", nil
+}
+
+func (q *resultsSubmitQueueForTesting) Flush(ctx context.Context) (int, error) {
+	return len(q.enqueued), nil
+}
+
+func TestResultsUpload(t *testing.T) {
+	newDatabase := func(isDone bool) *mocks.Database {
+		db := newResultsDatabaseForTesting()
+		db.MockSearchMeasurements = func(filter *model.DatabaseMeasurementFilter) ([]model.DatabaseMeasurementURLNetwork, int64, error) {
+			if !filter.IsUploaded.Valid || filter.IsUploaded.Bool {
+				t.Fatal("expected to only select measurements we did not upload")
+			}
+			entry := model.DatabaseMeasurementURLNetwork{}
+			entry.DatabaseMeasurement.ID = 42
+			entry.DatabaseMeasurement.ResultID = 7
+			entry.DatabaseMeasurement.IsDone = isDone
+			return []model.DatabaseMeasurementURLNetwork{entry}, 1, nil
+		}
+		return db
+	}
+
+	t.Run("we enqueue and flush completed measurements", func(t *testing.T) {
+		queue := &resultsSubmitQueueForTesting{}
+		count, err := resultsUpload(context.Background(), newDatabase(true), queue, &model.DatabaseMeasurementFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 || cmp.Diff([]int64{42}, queue.enqueued) != "" {
+			t.Fatal("unexpected result", count, queue.enqueued)
+		}
+	})
+
+	t.Run("we skip incomplete measurements", func(t *testing.T) {
+		queue := &resultsSubmitQueueForTesting{}
+		count, err := resultsUpload(context.Background(), newDatabase(false), queue, &model.DatabaseMeasurementFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatal("expected zero, got", count)
+		}
+	})
+
+	t.Run("we skip measurements we cannot load", func(t *testing.T) {
+		db := newDatabase(true)
+		db.MockGetMeasurementJSON = func(msmtID int64) (map[string]interface{}, error) {
+			return nil, errors.New("mocked error")
+		}
+		queue := &resultsSubmitQueueForTesting{}
+		count, err := resultsUpload(context.Background(), db, queue, &model.DatabaseMeasurementFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatal("expected zero, got", count)
+		}
+	})
+
+	t.Run("we handle enqueue errors", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		queue := &resultsSubmitQueueForTesting{err: expected}
+		_, err := resultsUpload(context.Background(), newDatabase(true), queue, &model.DatabaseMeasurementFilter{})
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
@@ -8,14 +8,17 @@ import (
 	"context"
 
 	"github.com/ooni/probe-engine/pkg/oonirun"
+	"github.com/ooni/probe-engine/pkg/runevents"
 	"github.com/ooni/probe-engine/pkg/runtimex"
 )
 
 // runx runs the given experiment by name
 func runx(ctx context.Context, sess oonirun.Session, experimentName string,
-	annotations map[string]string, extraOptions map[string]any, currentOptions *Options) {
+	annotations map[string]string, extraOptions map[string]any, currentOptions *Options,
+	events runevents.Sink) {
 	desc := &oonirun.Experiment{
 		Annotations:    annotations,
+		Events:         events,
 		ExtraOptions:   extraOptions,
 		Inputs:         currentOptions.Inputs,
 		InputFilePaths: currentOptions.InputFilePaths,
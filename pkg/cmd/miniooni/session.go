@@ -2,44 +2,58 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path/filepath"
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-engine/pkg/engine"
+	"github.com/ooni/probe-engine/pkg/enginemetrics"
 	"github.com/ooni/probe-engine/pkg/kvstore"
 	"github.com/ooni/probe-engine/pkg/legacy/kvstore2dir"
 	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/runevents"
 	"github.com/ooni/probe-engine/pkg/runtimex"
 )
 
 // newSessionOrPanic creates and starts a new session or panics on failure
 func newSessionOrPanic(ctx context.Context, currentOptions *Options,
-	miniooniDir string, logger model.Logger) *engine.Session {
+	miniooniDir string, logger model.Logger, events runevents.Sink,
+	metrics *enginemetrics.Registry) *engine.Session {
+	kvstore := newKVStoreOrPanic(currentOptions, miniooniDir)
+	config := newSessionConfigOrPanic(currentOptions, miniooniDir, kvstore, logger, events, metrics)
+	return newSessionWithConfigOrPanic(ctx, config)
+}
+
+// newSessionConfigOrPanic creates the configuration of a new session using
+// the given key-value store or panics on failure
+func newSessionConfigOrPanic(currentOptions *Options, miniooniDir string, kvstore model.KeyValueStore,
+	logger model.Logger, events runevents.Sink, metrics *enginemetrics.Registry) engine.SessionConfig {
 	var proxyURL *url.URL
 	if currentOptions.Proxy != "" {
 		proxyURL = mustParseURL(currentOptions.Proxy)
 	}
 
-	// We renamed kvstore2 to engine in the 3.20 development cycle
-	_ = kvstore2dir.Move(miniooniDir)
-
-	enginedir := filepath.Join(miniooniDir, "engine")
-	kvstore, err := kvstore.NewFS(enginedir)
-	runtimex.PanicOnError(err, "cannot create engine directory")
-
 	tunnelDir := filepath.Join(miniooniDir, "tunnel")
-	err = os.MkdirAll(tunnelDir, 0700)
+	err := os.MkdirAll(tunnelDir, 0700)
 	runtimex.PanicOnError(err, "cannot create tunnelDir")
 
+	submitQueueDir := filepath.Join(miniooniDir, "submitqueue")
+	err = os.MkdirAll(submitQueueDir, 0700)
+	runtimex.PanicOnError(err, "cannot create submitQueueDir")
+
 	config := engine.SessionConfig{
+		Events:              events,
 		KVStore:             kvstore,
 		Logger:              logger,
+		Metrics:             metrics,
 		ProxyURL:            proxyURL,
 		SnowflakeRendezvous: currentOptions.SnowflakeRendezvous,
 		SoftwareName:        currentOptions.SoftwareName,
 		SoftwareVersion:     currentOptions.SoftwareVersion,
+		SubmitQueueDir:      submitQueueDir,
 		TorArgs:             currentOptions.TorArgs,
 		TorBinary:           currentOptions.TorBinary,
 		TunnelDir:           tunnelDir,
@@ -50,7 +64,11 @@ func newSessionOrPanic(ctx context.Context, currentOptions *Options,
 			Type:    "https",
 		}}
 	}
+	return config
+}
 
+// newSessionWithConfigOrPanic creates and starts a new session using the given config or panics on failure
+func newSessionWithConfigOrPanic(ctx context.Context, config engine.SessionConfig) *engine.Session {
 	sess, err := engine.NewSession(ctx, config)
 	runtimex.PanicOnError(err, "cannot create measurement session")
 
@@ -76,3 +94,52 @@ func lookupLocationOrPanic(ctx context.Context, sess *engine.Session) {
 	log.Infof("- resolver's network: %s (%s)", sess.ResolverNetworkName(),
 		sess.ResolverASNString())
 }
+
+// newKVStoreOrPanic creates the key-value store selected using the command
+// line options or panics on failure.
+func newKVStoreOrPanic(currentOptions *Options, miniooniDir string) model.KeyValueStore {
+	// We renamed kvstore2 to engine in the 3.20 development cycle
+	_ = kvstore2dir.Move(miniooniDir)
+
+	enginedir := filepath.Join(miniooniDir, "engine")
+	fsstore, err := kvstore.NewFS(enginedir)
+	runtimex.PanicOnError(err, "cannot create engine directory")
+
+	switch currentOptions.KVStore {
+	case "", "fs":
+		return fsstore
+
+	case "sqlite":
+		// Note: because we commit each write, it is fine to let the database be
+		// closed when we exit. Long running code should call closeKVStore.
+		dbstore, err := kvstore.NewSQLite(filepath.Join(miniooniDir, "engine.sqlite3"))
+		runtimex.PanicOnError(err, "cannot open engine database")
+
+		// Import the state we previously saved using the file-system layout
+		err = kvstore.Migrate(dbstore, fsstore)
+		runtimex.PanicOnError(err, "cannot migrate engine directory to engine database")
+		return dbstore
+
+	default:
+		panic(fmt.Sprintf("unsupported key-value store: %s", currentOptions.KVStore))
+	}
+}
+
+// closeKVStore closes the key-value store returned by newKVStoreOrPanic
+// if it needs closing, e.g., because it is a database.
+func closeKVStore(kvstore model.KeyValueStore) {
+	if closer, ok := kvstore.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
+
+// newEventsSink returns the sink for structured events, which is nil
+// unless the user asked us to emit events using --events.
+func newEventsSink(currentOptions *Options) runevents.Sink {
+	if !currentOptions.Events {
+		return nil
+	}
+	// Note: the logger writes on the standard error, so the standard
+	// output only contains the events when using this mode.
+	return runevents.NewJSONLWriter(os.Stdout)
+}
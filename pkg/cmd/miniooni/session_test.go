@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ooni/probe-engine/pkg/kvstore"
+)
+
+func TestNewKVStoreOrPanic(t *testing.T) {
+	t.Run("we can share and close the SQLite store", func(t *testing.T) {
+		miniooniDir := t.TempDir()
+		store := newKVStoreOrPanic(&Options{KVStore: "sqlite"}, miniooniDir)
+		config := newSessionConfigOrPanic(&Options{}, miniooniDir, store, nil, nil, nil)
+		if config.KVStore != store {
+			t.Fatal("the session config does not use our store")
+		}
+		if err := store.Set("antani", []byte("mascetti")); err != nil {
+			t.Fatal(err)
+		}
+		closeKVStore(store)
+		if err := store.Set("antani", []byte("mascetti")); err == nil {
+			t.Fatal("expected an error after closing the store")
+		}
+	})
+
+	t.Run("closing the FS store is a no-op", func(t *testing.T) {
+		miniooniDir := t.TempDir()
+		store := newKVStoreOrPanic(&Options{}, miniooniDir)
+		if _, ok := store.(*kvstore.FS); !ok {
+			t.Fatal("expected a file-system store")
+		}
+		closeKVStore(store)
+		if err := store.Set("antani", []byte("mascetti")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Get("antani"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
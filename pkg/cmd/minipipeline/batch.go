@@ -0,0 +1,378 @@
+package main
+
+//
+// Batch mode
+//
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ooni/probe-engine/pkg/experiment/webconnectivitylte"
+	"github.com/ooni/probe-engine/pkg/minipipeline"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/must"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+)
+
+// batchSummary is the summary produced by the batch mode.
+type batchSummary struct {
+	// Measurements is the number of measurements we analyzed.
+	Measurements int64 `json:"measurements"`
+
+	// Skipped is the number of documents that are not Web Connectivity measurements.
+	Skipped int64 `json:"skipped"`
+
+	// Errors contains the errors that occurred processing measurements.
+	Errors []string `json:"errors"`
+
+	// ByASN contains the per-ASN summary.
+	ByASN []*batchSummaryRow `json:"by_asn"`
+
+	// ByDomain contains the per-domain summary.
+	ByDomain []*batchSummaryRow `json:"by_domain"`
+
+	// Disagreements lists the measurements where classic and LTE disagree.
+	Disagreements []*batchDisagreement `json:"disagreements"`
+}
+
+// batchSummaryRow is a row of the per-ASN or per-domain summary.
+type batchSummaryRow struct {
+	// Key is the ASN or the domain.
+	Key string `json:"key"`
+
+	// Measurements is the number of measurements.
+	Measurements int64 `json:"measurements"`
+
+	// ClassicAnomalies is the number of anomalies according to the classic analysis.
+	ClassicAnomalies int64 `json:"classic_anomalies"`
+
+	// LTEAnomalies is the number of anomalies according to the LTE analysis.
+	LTEAnomalies int64 `json:"lte_anomalies"`
+
+	// Disagreements is the number of measurements where the two analyses disagree.
+	Disagreements int64 `json:"disagreements"`
+}
+
+// batchDisagreement describes a measurement where classic and LTE disagree.
+type batchDisagreement struct {
+	// Source is the file containing the measurement followed by the
+	// one-based index of the measurement inside such a file.
+	Source string `json:"source"`
+
+	// Input is the measured URL.
+	Input string `json:"input"`
+
+	// ProbeASN is the probe ASN.
+	ProbeASN string `json:"probe_asn"`
+
+	// Classic is the classic verdict.
+	Classic string `json:"classic"`
+
+	// LTE is the LTE verdict.
+	LTE string `json:"lte"`
+}
+
+// batchAnalysis is the [*minipipeline.WebAnalysis] of a measurement or
+// the error that occurred while processing the measurement.
+type batchAnalysis struct {
+	// Source is the same as [batchDisagreement.Source].
+	Source string `json:"source"`
+
+	// Analysis is the web analysis or nil on error.
+	Analysis *minipipeline.WebAnalysis `json:"analysis,omitempty"`
+
+	// Error is the error that occurred or empty on success.
+	Error string `json:"error,omitempty"`
+}
+
+// batchMeasurementMeta contains the measurement fields that
+// [minipipeline.WebMeasurement] does not contain.
+type batchMeasurementMeta struct {
+	ProbeASN string `json:"probe_asn"`
+	TestName string `json:"test_name"`
+}
+
+// batchAnalyzer analyzes measurements and aggregates the results.
+type batchAnalyzer struct {
+	byASN         map[string]*batchSummaryRow
+	byDomain      map[string]*batchSummaryRow
+	lookupper     model.GeoIPASNLookupper
+	summary       *batchSummary
+	writeAnalysis func(entry *batchAnalysis)
+}
+
+// newBatchAnalyzer creates a new [*batchAnalyzer].
+func newBatchAnalyzer(lookupper model.GeoIPASNLookupper) *batchAnalyzer {
+	return &batchAnalyzer{
+		byASN:     map[string]*batchSummaryRow{},
+		byDomain:  map[string]*batchSummaryRow{},
+		lookupper: lookupper,
+		summary: &batchSummary{
+			Errors:        []string{},
+			ByASN:         []*batchSummaryRow{},
+			ByDomain:      []*batchSummaryRow{},
+			Disagreements: []*batchDisagreement{},
+		},
+		writeAnalysis: func(entry *batchAnalysis) {
+			// nothing
+		},
+	}
+}
+
+// errBatchNotWebConnectivity indicates that a document is not a Web Connectivity measurement.
+var errBatchNotWebConnectivity = errors.New("not a web_connectivity measurement")
+
+// walk processes the given directory or file. When given a directory, we process
+// all the files ending in .json or .jsonl. Each file may contain either a single
+// measurement or several measurements, one per line.
+func (ba *batchAnalyzer) walk(path string) error {
+	return filepath.WalkDir(path, func(fpath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if fpath != path && !strings.HasSuffix(fpath, ".json") && !strings.HasSuffix(fpath, ".jsonl") {
+			return nil
+		}
+		ba.processFile(fpath)
+		return nil
+	})
+}
+
+// processFile processes all the measurements inside the given file. We read
+// the measurements one at a time, such that we can process large files.
+func (ba *batchAnalyzer) processFile(fpath string) {
+	filep, err := os.Open(fpath)
+	if err != nil {
+		ba.addError(fpath, err)
+		return
+	}
+	defer filep.Close()
+	decoder := json.NewDecoder(bufio.NewReader(filep))
+	for idx := 1; ; idx++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if !errors.Is(err, io.EOF) {
+				// we cannot resynchronize after a syntax error
+				ba.addError(fmt.Sprintf("%s:%d", fpath, idx), err)
+			}
+			return
+		}
+		source := fmt.Sprintf("%s:%d", fpath, idx)
+		switch err := ba.processMeasurementSafe(source, raw); {
+		case errors.Is(err, errBatchNotWebConnectivity):
+			ba.summary.Skipped++
+		case err != nil:
+			ba.addError(source, err)
+			ba.writeAnalysis(&batchAnalysis{Source: source, Error: err.Error()})
+		}
+	}
+}
+
+// processMeasurementSafe is like processMeasurement but converts a panic
+// to an error, such that a malformed measurement does not stop the batch.
+func (ba *batchAnalyzer) processMeasurementSafe(source string, raw []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %+v", errBatchPanic, r)
+		}
+	}()
+	return ba.processMeasurement(source, raw)
+}
+
+// errBatchPanic indicates that processing a measurement caused a panic.
+var errBatchPanic = errors.New("panic while processing measurement")
+
+// addError records an error that occurred while processing the given source.
+func (ba *batchAnalyzer) addError(source string, err error) {
+	ba.summary.Errors = append(ba.summary.Errors, fmt.Sprintf("%s: %s", source, err.Error()))
+}
+
+// processMeasurement processes a single raw measurement.
+func (ba *batchAnalyzer) processMeasurement(source string, raw []byte) error {
+	var meta batchMeasurementMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return err
+	}
+	if meta.TestName != "web_connectivity" {
+		return errBatchNotWebConnectivity
+	}
+	var parsed minipipeline.WebMeasurement
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return err
+	}
+
+	// produce the web analysis
+	container, err := minipipeline.IngestWebMeasurement(ba.lookupper, &parsed)
+	if err != nil {
+		return err
+	}
+	ba.writeAnalysis(&batchAnalysis{
+		Source:   source,
+		Analysis: minipipeline.AnalyzeWebObservationsWithLinearAnalysis(ba.lookupper, container),
+	})
+
+	// produce the classic and LTE verdicts
+	tk, err := webconnectivitylte.AnalyzeWebMeasurement(ba.lookupper, &parsed)
+	if err != nil {
+		return err
+	}
+	classic := batchVerdictString(tk.Blocking)
+	lte := batchVerdictString(tk.BlockingFlagsVerdict())
+	disagree := classic != lte
+	if disagree {
+		ba.summary.Disagreements = append(ba.summary.Disagreements, &batchDisagreement{
+			Source:   source,
+			Input:    parsed.Input,
+			ProbeASN: meta.ProbeASN,
+			Classic:  classic,
+			LTE:      lte,
+		})
+	}
+
+	// aggregate the results
+	ba.summary.Measurements++
+	domain := parsed.Input
+	if URL, err := url.Parse(parsed.Input); err == nil && URL.Hostname() != "" {
+		domain = URL.Hostname()
+	}
+	for _, row := range []*batchSummaryRow{
+		ba.row(ba.byASN, &ba.summary.ByASN, meta.ProbeASN),
+		ba.row(ba.byDomain, &ba.summary.ByDomain, domain),
+	} {
+		row.Measurements++
+		if batchVerdictIsAnomaly(classic) {
+			row.ClassicAnomalies++
+		}
+		if batchVerdictIsAnomaly(lte) {
+			row.LTEAnomalies++
+		}
+		if disagree {
+			row.Disagreements++
+		}
+	}
+	return nil
+}
+
+// row returns the row for the given key, creating it if needed.
+func (ba *batchAnalyzer) row(index map[string]*batchSummaryRow, rows *[]*batchSummaryRow, key string) *batchSummaryRow {
+	row := index[key]
+	if row == nil {
+		row = &batchSummaryRow{Key: key}
+		index[key] = row
+		*rows = append(*rows, row)
+	}
+	return row
+}
+
+// finish sorts and returns the summary. Rows with more disagreements sort first
+// and we break ties by sorting by descending measurements and ascending key.
+func (ba *batchAnalyzer) finish() *batchSummary {
+	for _, rows := range [][]*batchSummaryRow{ba.summary.ByASN, ba.summary.ByDomain} {
+		sort.SliceStable(rows, func(i, j int) bool {
+			if rows[i].Disagreements != rows[j].Disagreements {
+				return rows[i].Disagreements > rows[j].Disagreements
+			}
+			if rows[i].Measurements != rows[j].Measurements {
+				return rows[i].Measurements > rows[j].Measurements
+			}
+			return rows[i].Key < rows[j].Key
+		})
+	}
+	return ba.summary
+}
+
+// batchVerdictString converts the value of the blocking field to a string.
+func batchVerdictString(blocking any) string {
+	switch value := blocking.(type) {
+	case nil:
+		return "null"
+	case string:
+		return value
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// batchVerdictIsAnomaly returns whether the given verdict is an anomaly.
+func batchVerdictIsAnomaly(verdict string) bool {
+	return verdict != "null" && verdict != "false"
+}
+
+// batchWriteTable writes the human readable version of the summary.
+func batchWriteTable(w io.Writer, summary *batchSummary) {
+	fmt.Fprintf(w, "measurements: %d, skipped: %d, errors: %d, disagreements: %d\n",
+		summary.Measurements, summary.Skipped, len(summary.Errors), len(summary.Disagreements))
+	for _, section := range []struct {
+		name string
+		rows []*batchSummaryRow
+	}{{"ASN", summary.ByASN}, {"DOMAIN", summary.ByDomain}} {
+		fmt.Fprintf(w, "\n")
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(tw, "%s\tMEASUREMENTS\tCLASSIC\tLTE\tDISAGREE\t\n", section.name)
+		for _, row := range section.rows {
+			marker := ""
+			if row.Disagreements > 0 {
+				marker = "*"
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\n", row.Key, row.Measurements,
+				row.ClassicAnomalies, row.LTEAnomalies, row.Disagreements, marker)
+		}
+		tw.Flush()
+	}
+	if len(summary.Disagreements) > 0 {
+		fmt.Fprintf(w, "\n")
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(tw, "SOURCE\tASN\tINPUT\tCLASSIC\tLTE\n")
+		for _, entry := range summary.Disagreements {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", entry.Source, entry.ProbeASN,
+				entry.Input, entry.Classic, entry.LTE)
+		}
+		tw.Flush()
+	}
+	for _, entry := range summary.Errors {
+		fmt.Fprintf(w, "error: %s\n", entry)
+	}
+}
+
+// batchMain is the main function of the batch mode.
+func batchMain(lookupper model.GeoIPASNLookupper) {
+	// we write each analysis as soon as we have it, such that we do not need
+	// to keep all of them in memory when processing many measurements
+	analysesPath := filepath.Join(*destdirFlag, *prefixFlag+"analyses.jsonl")
+	analyses := mustCreateFileFn(analysesPath)
+	ba := newBatchAnalyzer(lookupper)
+	ba.writeAnalysis = func(entry *batchAnalysis) {
+		must.Fprintf(analyses, "%s\n", must.MarshalJSON(entry))
+	}
+	err := ba.walk(*batchFlag)
+	runtimex.PanicOnError(analyses.Close(), "cannot close the analyses file")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "minipipeline: %s\n", err.Error())
+		osExitFn(1)
+	}
+	summary := ba.finish()
+
+	summaryPath := filepath.Join(*destdirFlag, *prefixFlag+"summary.json")
+	mustWriteFileFn(summaryPath, must.MarshalAndIndentJSON(summary, "", "  "), 0600)
+
+	var table bytes.Buffer
+	batchWriteTable(&table, summary)
+	tablePath := filepath.Join(*destdirFlag, *prefixFlag+"summary.txt")
+	mustWriteFileFn(tablePath, table.Bytes(), 0600)
+	fmt.Printf("%s", table.String())
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/geoipx"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/must"
+)
+
+func TestMainBatch(t *testing.T) {
+	// reconfigure the global options for main
+	*batchFlag = filepath.Join("testdata", "batch.jsonl")
+	defer func() {
+		*batchFlag = ""
+	}()
+	*destdirFlag = "xo"
+	*measurementFlag = ""
+	contentmap := make(map[string][]byte)
+	mustWriteFileFn = func(filename string, content []byte, mode fs.FileMode) {
+		contentmap[filename] = content
+	}
+	analysesFile := &batchTestingFile{}
+	mustCreateFileFn = func(filename string) io.WriteCloser {
+		if filename != filepath.Join("xo", "y-analyses.jsonl") {
+			t.Fatal("unexpected filename", filename)
+		}
+		return analysesFile
+	}
+	osExitFn = os.Exit
+	*prefixFlag = "y-"
+
+	// run the main function
+	main()
+
+	// make sure the summary is good
+	var summary batchSummary
+	must.UnmarshalJSON(contentmap[filepath.Join("xo", "y-summary.json")], &summary)
+	source := filepath.Join("testdata", "batch.jsonl")
+	expect := batchSummary{
+		Measurements: 3,
+		Skipped:      1,
+		Errors:       []string{source + ":5: minipipeline: no test keys"},
+		ByASN: []*batchSummaryRow{{
+			Key:              "AS137",
+			Measurements:     2,
+			ClassicAnomalies: 1,
+			LTEAnomalies:     2,
+			Disagreements:    1,
+		}, {
+			Key:          "AS30722",
+			Measurements: 1,
+		}},
+		ByDomain: []*batchSummaryRow{{
+			Key:              "www.example.com",
+			Measurements:     2,
+			ClassicAnomalies: 1,
+			LTEAnomalies:     2,
+			Disagreements:    1,
+		}, {
+			Key:          "nexa.polito.it",
+			Measurements: 1,
+		}},
+		Disagreements: []*batchDisagreement{{
+			Source:   source + ":2",
+			Input:    "https://www.example.com/",
+			ProbeASN: "AS137",
+			Classic:  "false",
+			LTE:      "dns",
+		}},
+	}
+	if diff := cmp.Diff(expect, summary); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// make sure we have written an analysis for each measurement and an
+	// error entry for each measurement we could not process
+	if !analysesFile.closed {
+		t.Fatal("expected the analyses file to be closed")
+	}
+	analyses := bytes.Split(bytes.TrimSpace(analysesFile.Bytes()), []byte("\n"))
+	if len(analyses) != 4 {
+		t.Fatal("expected four entries, got", len(analyses))
+	}
+	var first batchAnalysis
+	must.UnmarshalJSON(analyses[0], &first)
+	if first.Source != source+":1" || first.Analysis == nil || first.Error != "" {
+		t.Fatalf("unexpected analysis %+v", first)
+	}
+	var last batchAnalysis
+	must.UnmarshalJSON(analyses[3], &last)
+	if last.Source != source+":5" || last.Analysis != nil || last.Error != "minipipeline: no test keys" {
+		t.Fatalf("unexpected error entry %+v", last)
+	}
+
+	// make sure the table highlights the disagreements
+	table := string(contentmap[filepath.Join("xo", "y-summary.txt")])
+	for _, expect := range []string{"AS137", "www.example.com", "false    dns", "errors: 1"} {
+		if !strings.Contains(table, expect) {
+			t.Fatal("table does not contain", expect)
+		}
+	}
+}
+
+func TestBatchAnalyzer(t *testing.T) {
+	lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
+
+	t.Run("we walk a directory and only process JSON files", func(t *testing.T) {
+		dir := t.TempDir()
+		measurement := must.ReadFile(filepath.Join("testdata", "measurement.json"))
+		must.WriteFile(filepath.Join(dir, "a.json"), measurement, 0600)
+		must.WriteFile(filepath.Join(dir, "b.txt"), measurement, 0600)
+		must.WriteFile(filepath.Join(dir, "c.jsonl"), []byte("{}\n{"), 0600)
+
+		ba := newBatchAnalyzer(lookupper)
+		if err := ba.walk(dir); err != nil {
+			t.Fatal(err)
+		}
+		summary := ba.finish()
+		if summary.Measurements != 1 || summary.Skipped != 1 || len(summary.Errors) != 1 {
+			t.Fatalf("unexpected summary %+v", summary)
+		}
+		if !strings.HasPrefix(summary.Errors[0], filepath.Join(dir, "c.jsonl")+":2: ") {
+			t.Fatal("unexpected error", summary.Errors[0])
+		}
+	})
+
+	t.Run("we recover from panics while processing a measurement", func(t *testing.T) {
+		fpath := filepath.Join(t.TempDir(), "a.jsonl")
+		must.WriteFile(fpath, must.ReadFile(filepath.Join("testdata", "measurement.json")), 0600)
+
+		ba := newBatchAnalyzer(lookupper)
+		var entries []*batchAnalysis
+		ba.writeAnalysis = func(entry *batchAnalysis) {
+			if entry.Error == "" {
+				panic("mocked panic")
+			}
+			entries = append(entries, entry)
+		}
+		if err := ba.walk(fpath); err != nil {
+			t.Fatal(err)
+		}
+		summary := ba.finish()
+		if summary.Measurements != 0 || len(summary.Errors) != 1 {
+			t.Fatalf("unexpected summary %+v", summary)
+		}
+		if len(entries) != 1 || !strings.Contains(entries[0].Error, "mocked panic") {
+			t.Fatalf("unexpected entries %+v", entries)
+		}
+	})
+
+	t.Run("we fail when the path does not exist", func(t *testing.T) {
+		ba := newBatchAnalyzer(lookupper)
+		if err := ba.walk(filepath.Join("testdata", "nonexistent")); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("we sort rows by disagreements, measurements, and key", func(t *testing.T) {
+		ba := newBatchAnalyzer(lookupper)
+		ba.summary.ByASN = []*batchSummaryRow{
+			{Key: "AS3", Measurements: 1},
+			{Key: "AS2", Measurements: 1},
+			{Key: "AS1", Measurements: 2},
+			{Key: "AS4", Measurements: 1, Disagreements: 1},
+		}
+		var keys []string
+		for _, row := range ba.finish().ByASN {
+			keys = append(keys, row.Key)
+		}
+		if diff := cmp.Diff([]string{"AS4", "AS1", "AS2", "AS3"}, keys); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestBatchVerdictString(t *testing.T) {
+	cases := map[string]any{
+		"null":   nil,
+		"false":  false,
+		"dns":    "dns",
+		"tcp_ip": "tcp_ip",
+	}
+	for expect, input := range cases {
+		got := batchVerdictString(input)
+		if got != expect {
+			t.Fatal("expected", expect, "got", got)
+		}
+		if isAnomaly := batchVerdictIsAnomaly(got); isAnomaly != (input != nil && input != false) {
+			t.Fatal("unexpected anomaly status for", input)
+		}
+	}
+}
+
+// batchTestingFile is an in-memory [io.WriteCloser].
+type batchTestingFile struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (f *batchTestingFile) Close() error {
+	f.closed = true
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -14,6 +15,9 @@ import (
 )
 
 var (
+	// batchFlag is the -batch flag
+	batchFlag = flag.String("batch", "", "directory or JSONL file containing measurements to analyze")
+
 	// destdirFlag is the -destdir flag
 	destdirFlag = flag.String("destdir", ".", "destination directory to use")
 
@@ -23,6 +27,11 @@ var (
 	// measurementFlag is the -measurement flag
 	measurementFlag = flag.String("measurement", "", "measurement file to analyze")
 
+	// mustCreateFileFn allows overwriting must.CreateFile in tests
+	mustCreateFileFn = func(filename string) io.WriteCloser {
+		return must.CreateFile(filename)
+	}
+
 	// mustWriteFileLn allows overwriting must.WriteFile in tests
 	mustWriteFileFn = must.WriteFile
 
@@ -35,9 +44,10 @@ var (
 
 func main() {
 	flag.Parse()
-	if *helpFlag || *measurementFlag == "" {
+	if *helpFlag || (*measurementFlag == "") == (*batchFlag == "") {
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "usage: %s -measurement <file> [-destdir <dir>] [-prefix <prefix>]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "       %s -batch <dir|file> [-destdir <dir>] [-prefix <prefix>]\n", filepath.Base(os.Args[0]))
 		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Mini measurement processing pipeline to reprocess recent probe measurements\n")
 		fmt.Fprintf(os.Stderr, "and align results calculation with ooni/data.\n")
@@ -48,17 +58,31 @@ func main() {
 		fmt.Fprintf(os.Stderr, "analysis like the one in Web Connectivity v0.4 and generate accordingly the\n")
 		fmt.Fprintf(os.Stderr, "observations_classic.json and analysis_classic.json files.\n")
 		fmt.Fprintf(os.Stderr, "\n")
+		fmt.Fprintf(os.Stderr, "Use -batch <dir|file> instead of -measurement <file> to analyze all the Web\n")
+		fmt.Fprintf(os.Stderr, "Connectivity measurements inside the .json and .jsonl files in <dir> or inside\n")
+		fmt.Fprintf(os.Stderr, "<file>, which may contain a measurement per line. In batch mode, we write the\n")
+		fmt.Fprintf(os.Stderr, "analysis of each measurement into analyses.jsonl and we compare the verdicts\n")
+		fmt.Fprintf(os.Stderr, "of the classic and LTE algorithms, writing a per-ASN and per-domain summary\n")
+		fmt.Fprintf(os.Stderr, "highlighting their disagreements into summary.json and summary.txt.\n")
+		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "Use -prefix <prefix> to add <prefix> in front of the generated files names.\n")
 		fmt.Fprintf(os.Stderr, "\n")
 		osExitFn(1)
 	}
 
+	lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
+
+	// handle the batch mode
+	if *batchFlag != "" {
+		batchMain(lookupper)
+		return
+	}
+
 	// parse the measurement file
 	var parsed minipipeline.WebMeasurement
 	must.UnmarshalJSON(must.ReadFile(*measurementFlag), &parsed)
 
 	// generate and write observations
-	lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
 	observationsPath := filepath.Join(*destdirFlag, *prefixFlag+"observations.json")
 	container := runtimex.Try1(minipipeline.IngestWebMeasurement(lookupper, &parsed))
 	mustWriteFileFn(observationsPath, must.MarshalAndIndentJSON(container, "", "  "), 0600)
@@ -8,8 +8,10 @@ import (
 	"net"
 	"net/http"
 	"net/http/pprof"
+	"net/netip"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -26,12 +28,24 @@ var (
 	// apiEndpoint is the endpoint where we serve ooniprobe requests
 	apiEndpoint = flag.String("api-endpoint", "127.0.0.1:8080", "API endpoint")
 
+	// clientIPHeader is the header containing the client IP when we're behind a reverse proxy
+	clientIPHeader = flag.String("client-ip-header", "", "Header containing the client IP address (e.g., X-Forwarded-For)")
+
 	// debug controls whether to enable verbose logging
 	debug = flag.Bool("debug", false, "Toggle debug mode")
 
+	// denyNetworks contains the networks we refuse to measure in addition to bogons
+	denyNetworks = flag.String("deny-networks", "", "Comma-separated list of networks we refuse to measure")
+
 	// pprofEndpoint is the endpoint where we serve pprof info.
 	pprofEndpoint = flag.String("pprof-endpoint", "127.0.0.1:6061", "Pprof endpoint")
 
+	// rateLimit is the number of requests per second we allow for each client IP
+	rateLimit = flag.Float64("rate-limit", 0, "Requests per second allowed for each client IP (zero means no limit)")
+
+	// rateLimitBurst is the maximum burst of requests we allow for each client IP
+	rateLimitBurst = flag.Int("rate-limit-burst", 10, "Maximum burst of requests allowed for each client IP")
+
 	// replace runs the commands to replace a running oohelperd.
 	replace = flag.Bool("replace", false, "Replaces a running oohelperd instance")
 
@@ -48,8 +62,37 @@ var (
 	versionFlag = flag.Bool("version", false, "Prints version information on the stdout")
 
 	prometheusMetricsPassword = os.Getenv("PROMETHEUS_METRICS_PASSWORD")
+
+	// sharedSecret is the optional secret clients must send to use the test helper
+	sharedSecret = os.Getenv("OOHELPERD_SHARED_SECRET")
+
+	// tokenKey is the optional key for verifying the tokens clients must send to use the test helper
+	tokenKey = os.Getenv("OOHELPERD_TOKEN_KEY")
 )
 
+// newHandler creates the [*oohelperd.Handler] configured using the command line
+// flags and the environment variables, or panics on failure.
+func newHandler() *oohelperd.Handler {
+	handler := oohelperd.NewHandler(log.Log, &netxlite.Netx{})
+	handler.ClientIPHeader = *clientIPHeader
+	for _, entry := range strings.Split(*denyNetworks, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		network, err := netip.ParsePrefix(entry)
+		runtimex.PanicOnError(err, "netip.ParsePrefix failed")
+		handler.DenyNetworks = append(handler.DenyNetworks, network.Masked())
+	}
+	if *rateLimit > 0 {
+		handler.RateLimiter = oohelperd.NewRateLimiter(*rateLimit, *rateLimitBurst)
+	}
+	handler.SharedSecret = sharedSecret
+	if tokenKey != "" {
+		handler.TokenKey = []byte(tokenKey)
+	}
+	return handler
+}
+
 // shutdown calls srv.Shutdown with a reasonably long timeout. The srv.Shutdown
 // function will immediately close any open listener and then will wait until
 // all pending connections are closed or the context has expired. By giving pending
@@ -92,7 +135,7 @@ func main() {
 	mux := http.NewServeMux()
 
 	// add the main oohelperd handler to the mux
-	mux.Handle("/", oohelperd.NewHandler(log.Log, &netxlite.Netx{}))
+	mux.Handle("/", newHandler())
 	mux.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
 		user, pass, ok := req.BasicAuth()
 		if ok && user == "prom" && pass == prometheusMetricsPassword {
@@ -5,11 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"strings"
 	"syscall"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-engine/pkg/model"
 	"github.com/ooni/probe-engine/pkg/netxlite"
 	"github.com/ooni/probe-engine/pkg/runtimex"
@@ -104,3 +106,58 @@ func TestMainVersionWorkingAsIntended(t *testing.T) {
 	main()
 	*versionFlag = false
 }
+
+func TestNewHandler(t *testing.T) {
+	t.Run("by default we do not protect the handler", func(t *testing.T) {
+		handler := newHandler()
+		if handler.ClientIPHeader != "" || len(handler.DenyNetworks) != 0 || handler.RateLimiter != nil {
+			t.Fatal("unexpected handler configuration")
+		}
+		if handler.SharedSecret != "" || handler.TokenKey != nil {
+			t.Fatal("unexpected handler authentication")
+		}
+	})
+
+	t.Run("we configure the handler using flags and environment variables", func(t *testing.T) {
+		*clientIPHeader = "X-Forwarded-For"
+		*denyNetworks = "10.0.0.1/8, ,2001:db8::/32"
+		*rateLimit = 1
+		sharedSecret = "antani"
+		tokenKey = "mascetti"
+		defer func() {
+			*clientIPHeader = ""
+			*denyNetworks = ""
+			*rateLimit = 0
+			sharedSecret = ""
+			tokenKey = ""
+		}()
+
+		handler := newHandler()
+		if handler.ClientIPHeader != "X-Forwarded-For" || handler.RateLimiter == nil {
+			t.Fatal("unexpected handler configuration")
+		}
+		expectNetworks := []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+			netip.MustParsePrefix("2001:db8::/32"),
+		}
+		if diff := cmp.Diff(expectNetworks, handler.DenyNetworks, cmp.Comparer(func(a, b netip.Prefix) bool {
+			return a == b
+		})); diff != "" {
+			t.Fatal(diff)
+		}
+		if handler.SharedSecret != "antani" || string(handler.TokenKey) != "mascetti" {
+			t.Fatal("unexpected handler authentication")
+		}
+	})
+
+	t.Run("we panic with invalid networks", func(t *testing.T) {
+		*denyNetworks = "10.0.0.0"
+		defer func() {
+			*denyNetworks = ""
+			if recover() == nil {
+				t.Fatal("expected a panic")
+			}
+		}()
+		newHandler()
+	})
+}
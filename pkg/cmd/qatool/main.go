@@ -93,6 +93,9 @@ func runWebConnectivityLTE(tc *webconnectivityqa.TestCase) {
 		tk.TLSHandshakes = minipipeline.SortTLSHandshakeResults(tk.TLSHandshakes)
 		minipipeline.NormalizeTLSHandshakeResults(tk.TLSHandshakes)
 
+		tk.QUICHandshakes = minipipeline.SortTLSHandshakeResults(tk.QUICHandshakes)
+		minipipeline.NormalizeTLSHandshakeResults(tk.QUICHandshakes)
+
 		minipipeline.NormalizeHTTPRequestResults(tk.Requests)
 
 		// normalize measurement fields
@@ -40,6 +40,11 @@ func (d *Database) Session() db.Session {
 	return d.sess
 }
 
+// SchemaVersion returns the version of the database schema.
+func (d *Database) SchemaVersion() (int64, error) {
+	return SchemaVersion(d.sess.Driver().(*sql.DB))
+}
+
 // ListMeasurements implements ReadableDatabase.ListMeasurements
 func (d *Database) ListMeasurements(resultID int64) ([]model.DatabaseMeasurementURLNetwork, error) {
 	measurements := []model.DatabaseMeasurementURLNetwork{}
@@ -124,6 +129,26 @@ func (d *Database) GetMeasurementJSON(msmtID int64) (map[string]interface{}, err
 	return msmtJSON, nil
 }
 
+// GetMeasurement implements ReadableDatabase.GetMeasurement
+func (d *Database) GetMeasurement(msmtID int64) (*model.DatabaseMeasurement, error) {
+	var msmt model.DatabaseMeasurement
+	if err := d.sess.Collection("measurements").Find("measurement_id", msmtID).One(&msmt); err != nil {
+		log.WithError(err).Error("failed to retrieve the measurement")
+		return nil, err
+	}
+	return &msmt, nil
+}
+
+// GetResult implements ReadableDatabase.GetResult
+func (d *Database) GetResult(resultID int64) (*model.DatabaseResult, error) {
+	var result model.DatabaseResult
+	if err := d.sess.Collection("results").Find("result_id", resultID).One(&result); err != nil {
+		log.WithError(err).Error("failed to retrieve the result")
+		return nil, err
+	}
+	return &result, nil
+}
+
 // ListResults implements ReadableDatabase.ListResults
 func (d *Database) ListResults() ([]model.DatabaseResultNetwork, []model.DatabaseResultNetwork, error) {
 	doneResults := []model.DatabaseResultNetwork{}
@@ -262,6 +262,78 @@ func TestDeleteResult(t *testing.T) {
 	}
 }
 
+func TestGetMeasurementAndResult(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	database, err := Open(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	location := locationInfo{
+		asn:         0,
+		countryCode: "IT",
+		networkName: "Unknown",
+	}
+	network, err := database.CreateNetwork(&location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := database.CreateResult(tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reportID := sql.NullString{String: "20240101T000000Z_webconnectivity_IT_0_n1_xyz", Valid: true}
+	urlID := sql.NullInt64{Int64: 0, Valid: false}
+	msmt, err := database.CreateMeasurement(reportID, "web_connectivity", tmpdir, 0, result.ID, urlID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GetMeasurement", func(t *testing.T) {
+		got, err := database.GetMeasurement(msmt.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != msmt.ID || got.ResultID != result.ID {
+			t.Fatal("unexpected measurement", got)
+		}
+		if got.ReportID != reportID {
+			t.Fatal("unexpected report ID", got.ReportID)
+		}
+
+		if _, err := database.GetMeasurement(msmt.ID + 1); err != db.ErrNoMoreRows {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("GetResult", func(t *testing.T) {
+		got, err := database.GetResult(result.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != result.ID || got.TestGroupName != "websites" || got.NetworkID != network.ID {
+			t.Fatal("unexpected result", got)
+		}
+
+		if _, err := database.GetResult(result.ID + 1); err != db.ErrNoMoreRows {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
 func TestNetworkCreate(t *testing.T) {
 	tmpfile, err := ioutil.TempFile("", "dbtest")
 	if err != nil {
@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"embed"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-engine/pkg/netxlite"
@@ -51,6 +54,28 @@ func RunMigrations(sess *sql.DB) error {
 	return nil
 }
 
+// SchemaVersion returns the version of the database schema, which is
+// the number of the most recent migration applied to the database.
+func SchemaVersion(sess *sql.DB) (int64, error) {
+	records, err := migrate.GetMigrationRecords(sess, "sqlite3")
+	if err != nil {
+		return 0, err
+	}
+	var version int64
+	for _, record := range records {
+		// The migration ID is the file name, e.g., `3_results_is_uploaded.sql`
+		prefix, _, _ := strings.Cut(record.Id, "_")
+		value, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration ID: %s", record.Id)
+		}
+		if value > version {
+			version = value
+		}
+	}
+	return version, nil
+}
+
 // Connect to the database
 func Connect(path string) (sess db.Session, err error) {
 	settings := sqlite.ConnectionURL{
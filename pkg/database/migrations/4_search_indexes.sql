@@ -0,0 +1,31 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP INDEX `idx_measurements_result_id`;
+DROP INDEX `idx_measurements_url_id`;
+DROP INDEX `idx_measurements_test_name`;
+DROP INDEX `idx_measurements_start_time`;
+DROP INDEX `idx_measurements_is_anomaly`;
+DROP INDEX `idx_results_network_id`;
+DROP INDEX `idx_networks_asn`;
+DROP INDEX `idx_urls_category_code`;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- These indexes speed up the JOINs between tables, which SQLite does
+-- not index automatically for foreign keys.
+CREATE INDEX `idx_measurements_result_id` ON `measurements`(`result_id`);
+CREATE INDEX `idx_measurements_url_id` ON `measurements`(`url_id`);
+CREATE INDEX `idx_results_network_id` ON `results`(`network_id`);
+
+-- These indexes speed up searching and aggregating measurements.
+CREATE INDEX `idx_measurements_test_name` ON `measurements`(`test_name`);
+CREATE INDEX `idx_measurements_start_time` ON `measurements`(`measurement_start_time`);
+CREATE INDEX `idx_measurements_is_anomaly` ON `measurements`(`is_anomaly`);
+CREATE INDEX `idx_networks_asn` ON `networks`(`asn`);
+CREATE INDEX `idx_urls_category_code` ON `urls`(`category_code`);
+
+-- +migrate StatementEnd
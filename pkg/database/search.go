@@ -0,0 +1,130 @@
+package database
+
+import (
+	"github.com/apex/log"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// measurementFilterConds returns the conditions implementing a filter.
+func measurementFilterConds(filter *model.DatabaseMeasurementFilter) []db.LogicalExpr {
+	var conds []db.LogicalExpr
+	if filter.URL != "" {
+		// Note: we use instr rather than LIKE such that we don't need to
+		// escape the wildcards that may appear inside URLs
+		conds = append(conds, db.Raw("instr(urls.url, ?) > 0", filter.URL))
+	}
+	if filter.CategoryCode != "" {
+		conds = append(conds, db.Raw("urls.category_code = ?", filter.CategoryCode))
+	}
+	if filter.TestName != "" {
+		conds = append(conds, db.Raw("measurements.test_name = ?", filter.TestName))
+	}
+	if filter.ASN.Valid {
+		conds = append(conds, db.Raw("networks.asn = ?", filter.ASN.Int64))
+	}
+	// Note: we store times in UTC, hence we must also compare using UTC
+	if !filter.Since.IsZero() {
+		conds = append(conds, db.Raw("measurements.measurement_start_time >= ?", filter.Since.UTC()))
+	}
+	if !filter.Until.IsZero() {
+		conds = append(conds, db.Raw("measurements.measurement_start_time < ?", filter.Until.UTC()))
+	}
+	if filter.IsAnomaly.Valid {
+		conds = append(conds, db.Raw("measurements.is_anomaly = ?", filter.IsAnomaly.Bool))
+	}
+	if filter.IsFailed.Valid {
+		conds = append(conds, db.Raw("measurements.measurement_is_failed = ?", filter.IsFailed.Bool))
+	}
+	if filter.IsUploaded.Valid {
+		conds = append(conds, db.Raw("measurements.measurement_is_uploaded = ?", filter.IsUploaded.Bool))
+	}
+	return conds
+}
+
+// selectMeasurements returns a selector for the given columns joining the measurements
+// with their results, networks, and URLs and applying the given filter.
+func (d *Database) selectMeasurements(filter *model.DatabaseMeasurementFilter, columns ...interface{}) db.Selector {
+	req := d.sess.SQL().Select(columns...).From("measurements").
+		Join("results").On("results.result_id = measurements.result_id").
+		Join("networks").On("results.network_id = networks.network_id").
+		LeftJoin("urls").On("urls.url_id = measurements.url_id")
+	if conds := measurementFilterConds(filter); len(conds) > 0 {
+		req = req.Where(db.And(conds...))
+	}
+	return req
+}
+
+// SearchMeasurements implements ReadableDatabase.SearchMeasurements
+func (d *Database) SearchMeasurements(
+	filter *model.DatabaseMeasurementFilter) ([]model.DatabaseMeasurementURLNetwork, int64, error) {
+	var total struct {
+		Count int64 `db:"total_count"`
+	}
+	countReq := d.selectMeasurements(filter, db.Raw("COUNT(*) AS total_count"))
+	if err := countReq.One(&total); err != nil {
+		log.Errorf("failed to run query %s: %v", countReq.String(), err)
+		return nil, 0, errors.Wrap(err, "failed to count measurements")
+	}
+	measurements := []model.DatabaseMeasurementURLNetwork{}
+	req := d.selectMeasurements(
+		filter,
+		db.Raw("networks.*"),
+		db.Raw("urls.*"),
+		db.Raw("measurements.*"),
+		db.Raw("results.*"),
+	).OrderBy("-measurements.measurement_start_time", "-measurements.measurement_id")
+	if filter.Limit > 0 {
+		req = req.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		req = req.Offset(filter.Offset)
+	}
+	if err := req.All(&measurements); err != nil {
+		log.Errorf("failed to run query %s: %v", req.String(), err)
+		return nil, 0, errors.Wrap(err, "failed to search measurements")
+	}
+	return measurements, total.Count, nil
+}
+
+// CountMeasurementsByNetwork implements ReadableDatabase.CountMeasurementsByNetwork
+func (d *Database) CountMeasurementsByNetwork(
+	filter *model.DatabaseMeasurementFilter) ([]model.DatabaseNetworkCount, error) {
+	counts := []model.DatabaseNetworkCount{}
+	req := d.selectMeasurements(
+		filter,
+		db.Raw("networks.network_id"),
+		db.Raw("networks.network_name"),
+		db.Raw("networks.network_type"),
+		db.Raw("networks.ip"),
+		db.Raw("networks.asn"),
+		db.Raw("networks.network_country_code"),
+		db.Raw("COUNT(CASE WHEN measurements.is_anomaly = TRUE THEN 1 END) AS anomaly_count"),
+		db.Raw("COUNT(CASE WHEN measurements.measurement_is_failed = TRUE THEN 1 END) AS failure_count"),
+		db.Raw("COUNT(*) AS total_count"),
+	).GroupBy(db.Raw("networks.network_id")).OrderBy("networks.network_id")
+	if err := req.All(&counts); err != nil {
+		log.Errorf("failed to run query %s: %v", req.String(), err)
+		return nil, errors.Wrap(err, "failed to count measurements by network")
+	}
+	return counts, nil
+}
+
+// CountMeasurementsByTest implements ReadableDatabase.CountMeasurementsByTest
+func (d *Database) CountMeasurementsByTest(
+	filter *model.DatabaseMeasurementFilter) ([]model.DatabaseTestCount, error) {
+	counts := []model.DatabaseTestCount{}
+	req := d.selectMeasurements(
+		filter,
+		db.Raw("measurements.test_name"),
+		db.Raw("COUNT(CASE WHEN measurements.is_anomaly = TRUE THEN 1 END) AS anomaly_count"),
+		db.Raw("COUNT(CASE WHEN measurements.measurement_is_failed = TRUE THEN 1 END) AS failure_count"),
+		db.Raw("COUNT(*) AS total_count"),
+	).GroupBy(db.Raw("measurements.test_name")).OrderBy("measurements.test_name")
+	if err := req.All(&counts); err != nil {
+		log.Errorf("failed to run query %s: %v", req.String(), err)
+		return nil, errors.Wrap(err, "failed to count measurements by test")
+	}
+	return counts, nil
+}
@@ -0,0 +1,427 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+// searchTestMeasurement describes a measurement created by newSearchTestDatabase.
+type searchTestMeasurement struct {
+	asn       uint
+	url       string
+	category  string
+	testName  string
+	startTime time.Time
+	isAnomaly sql.NullBool
+	isFailed  bool
+}
+
+// searchTestBaseTime is the time of the first measurement used by tests.
+var searchTestBaseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newSearchTestDatabase creates a database containing the measurements we use for testing.
+func newSearchTestDatabase(t *testing.T) *Database {
+	tmpdir := t.TempDir()
+	database, err := Open(filepath.Join(tmpdir, "main.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		database.Close()
+	})
+
+	measurements := []searchTestMeasurement{{
+		asn:       30722,
+		url:       "https://www.example.com/",
+		category:  "NEWS",
+		testName:  "web_connectivity",
+		startTime: searchTestBaseTime,
+		isAnomaly: sql.NullBool{Bool: false, Valid: true},
+	}, {
+		asn:       30722,
+		url:       "https://www.example.org/a_b%c",
+		category:  "GRP",
+		testName:  "web_connectivity",
+		startTime: searchTestBaseTime.Add(time.Hour),
+		isAnomaly: sql.NullBool{Bool: true, Valid: true},
+	}, {
+		asn:       30722,
+		testName:  "telegram",
+		startTime: searchTestBaseTime.Add(2 * time.Hour),
+		isAnomaly: sql.NullBool{Bool: true, Valid: true},
+	}, {
+		asn:       3269,
+		url:       "https://www.example.com/",
+		category:  "NEWS",
+		testName:  "web_connectivity",
+		startTime: searchTestBaseTime.Add(24 * time.Hour),
+		isFailed:  true,
+	}, {
+		asn:       3269,
+		testName:  "ndt",
+		startTime: searchTestBaseTime.Add(48 * time.Hour),
+		isAnomaly: sql.NullBool{Bool: false, Valid: true},
+	}}
+
+	sess := database.Session()
+	networks := make(map[uint]int64)
+	for idx, entry := range measurements {
+		networkID, found := networks[entry.asn]
+		if !found {
+			network, err := database.CreateNetwork(&locationInfo{
+				asn:         entry.asn,
+				countryCode: "IT",
+				networkName: "Unknown",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			networkID = network.ID
+			networks[entry.asn] = networkID
+		}
+
+		result, err := database.CreateResult(tmpdir, entry.testName, networkID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		urlID := sql.NullInt64{}
+		if entry.url != "" {
+			id, err := database.CreateOrUpdateURL(entry.url, entry.category, "IT")
+			if err != nil {
+				t.Fatal(err)
+			}
+			urlID = sql.NullInt64{Int64: id, Valid: true}
+		}
+
+		msmt, err := database.CreateMeasurement(
+			sql.NullString{}, entry.testName, result.MeasurementDir, idx, result.ID, urlID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msmt.StartTime = entry.startTime
+		msmt.IsAnomaly = entry.isAnomaly
+		msmt.IsFailed = entry.isFailed
+		if err := sess.Collection("measurements").Find("measurement_id", msmt.ID).Update(msmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return database
+}
+
+// newBrokenSearchTestDatabase creates a database where searching fails.
+func newBrokenSearchTestDatabase(t *testing.T) *Database {
+	database, err := Open(filepath.Join(t.TempDir(), "main.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		database.Close()
+	})
+	if _, err := database.Session().SQL().Exec("DROP TABLE urls"); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+func TestSearchMeasurements(t *testing.T) {
+	database := newSearchTestDatabase(t)
+
+	type testcase struct {
+		name        string
+		filter      *model.DatabaseMeasurementFilter
+		expectTests []string
+		expectTotal int64
+	}
+
+	cases := []testcase{{
+		name:        "with an empty filter",
+		filter:      &model.DatabaseMeasurementFilter{},
+		expectTests: []string{"ndt", "web_connectivity", "telegram", "web_connectivity", "web_connectivity"},
+		expectTotal: 5,
+	}, {
+		name:        "with pagination",
+		filter:      &model.DatabaseMeasurementFilter{Limit: 2, Offset: 1},
+		expectTests: []string{"web_connectivity", "telegram"},
+		expectTotal: 5,
+	}, {
+		name:        "with an offset past the end",
+		filter:      &model.DatabaseMeasurementFilter{Limit: 2, Offset: 10},
+		expectTests: []string{},
+		expectTotal: 5,
+	}, {
+		name:        "with URL",
+		filter:      &model.DatabaseMeasurementFilter{URL: "example.com"},
+		expectTests: []string{"web_connectivity", "web_connectivity"},
+		expectTotal: 2,
+	}, {
+		name:        "with URL containing wildcards",
+		filter:      &model.DatabaseMeasurementFilter{URL: "a_b%"},
+		expectTests: []string{"web_connectivity"},
+		expectTotal: 1,
+	}, {
+		name:        "with URL containing only a wildcard",
+		filter:      &model.DatabaseMeasurementFilter{URL: "%"},
+		expectTests: []string{"web_connectivity"},
+		expectTotal: 1,
+	}, {
+		name:        "with category code",
+		filter:      &model.DatabaseMeasurementFilter{CategoryCode: "GRP"},
+		expectTests: []string{"web_connectivity"},
+		expectTotal: 1,
+	}, {
+		name:        "with test name",
+		filter:      &model.DatabaseMeasurementFilter{TestName: "telegram"},
+		expectTests: []string{"telegram"},
+		expectTotal: 1,
+	}, {
+		name:        "with ASN",
+		filter:      &model.DatabaseMeasurementFilter{ASN: sql.NullInt64{Int64: 3269, Valid: true}},
+		expectTests: []string{"ndt", "web_connectivity"},
+		expectTotal: 2,
+	}, {
+		name: "with date range",
+		filter: &model.DatabaseMeasurementFilter{
+			Since: searchTestBaseTime.Add(time.Hour),
+			Until: searchTestBaseTime.Add(24 * time.Hour),
+		},
+		expectTests: []string{"telegram", "web_connectivity"},
+		expectTotal: 2,
+	}, {
+		name: "with date range using another time zone",
+		filter: &model.DatabaseMeasurementFilter{
+			Since: searchTestBaseTime.Add(24 * time.Hour).In(time.FixedZone("CET", 3600)),
+		},
+		expectTests: []string{"ndt", "web_connectivity"},
+		expectTotal: 2,
+	}, {
+		name:        "with anomalies",
+		filter:      &model.DatabaseMeasurementFilter{IsAnomaly: sql.NullBool{Bool: true, Valid: true}},
+		expectTests: []string{"telegram", "web_connectivity"},
+		expectTotal: 2,
+	}, {
+		name: "with failures",
+		filter: &model.DatabaseMeasurementFilter{
+			IsFailed: sql.NullBool{Bool: true, Valid: true},
+		},
+		expectTests: []string{"web_connectivity"},
+		expectTotal: 1,
+	}, {
+		name: "with uploaded measurements",
+		filter: &model.DatabaseMeasurementFilter{
+			IsUploaded: sql.NullBool{Bool: true, Valid: true},
+		},
+		expectTests: []string{},
+		expectTotal: 0,
+	}, {
+		name: "with measurements that we did not upload",
+		filter: &model.DatabaseMeasurementFilter{
+			IsUploaded: sql.NullBool{Bool: false, Valid: true},
+		},
+		expectTests: []string{"ndt", "web_connectivity", "telegram", "web_connectivity", "web_connectivity"},
+		expectTotal: 5,
+	}, {
+		name: "with several conditions",
+		filter: &model.DatabaseMeasurementFilter{
+			ASN:       sql.NullInt64{Int64: 30722, Valid: true},
+			TestName:  "web_connectivity",
+			IsAnomaly: sql.NullBool{Bool: false, Valid: true},
+		},
+		expectTests: []string{"web_connectivity"},
+		expectTotal: 1,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			measurements, total, err := database.SearchMeasurements(tc.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != tc.expectTotal {
+				t.Fatal("expected", tc.expectTotal, "got", total)
+			}
+			gotTests := []string{}
+			for _, msmt := range measurements {
+				gotTests = append(gotTests, msmt.TestName)
+			}
+			if diff := cmp.Diff(tc.expectTests, gotTests); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+
+	t.Run("we join the measurement with its network and URL", func(t *testing.T) {
+		measurements, _, err := database.SearchMeasurements(&model.DatabaseMeasurementFilter{
+			CategoryCode: "GRP",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(measurements) != 1 {
+			t.Fatal("expected a single measurement")
+		}
+		if measurements[0].DatabaseNetwork.ASN != 30722 {
+			t.Fatal("unexpected ASN", measurements[0].DatabaseNetwork.ASN)
+		}
+		if measurements[0].DatabaseURL.URL.String != "https://www.example.org/a_b%c" {
+			t.Fatal("unexpected URL", measurements[0].DatabaseURL.URL.String)
+		}
+	})
+
+	t.Run("when the query fails", func(t *testing.T) {
+		database := newBrokenSearchTestDatabase(t)
+		measurements, total, err := database.SearchMeasurements(&model.DatabaseMeasurementFilter{})
+		if err == nil {
+			t.Fatal("expected an error here")
+		}
+		if measurements != nil || total != 0 {
+			t.Fatal("expected no results")
+		}
+	})
+}
+
+func TestCountMeasurementsByNetwork(t *testing.T) {
+	database := newSearchTestDatabase(t)
+
+	t.Run("with an empty filter", func(t *testing.T) {
+		counts, err := database.CountMeasurementsByNetwork(&model.DatabaseMeasurementFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		type summary struct {
+			ASN                                    uint
+			AnomalyCount, FailureCount, TotalCount uint64
+		}
+		var got []summary
+		for _, entry := range counts {
+			got = append(got, summary{
+				ASN:          entry.ASN,
+				AnomalyCount: entry.AnomalyCount,
+				FailureCount: entry.FailureCount,
+				TotalCount:   entry.TotalCount,
+			})
+		}
+		expect := []summary{{
+			ASN:          30722,
+			AnomalyCount: 2,
+			FailureCount: 0,
+			TotalCount:   3,
+		}, {
+			ASN:          3269,
+			AnomalyCount: 0,
+			FailureCount: 1,
+			TotalCount:   2,
+		}}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with a filter", func(t *testing.T) {
+		counts, err := database.CountMeasurementsByNetwork(&model.DatabaseMeasurementFilter{
+			TestName: "ndt",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(counts) != 1 || counts[0].ASN != 3269 || counts[0].TotalCount != 1 {
+			t.Fatalf("unexpected counts: %+v", counts)
+		}
+	})
+
+	t.Run("when the query fails", func(t *testing.T) {
+		database := newBrokenSearchTestDatabase(t)
+		counts, err := database.CountMeasurementsByNetwork(&model.DatabaseMeasurementFilter{})
+		if err == nil {
+			t.Fatal("expected an error here")
+		}
+		if counts != nil {
+			t.Fatal("expected nil counts")
+		}
+	})
+}
+
+func TestCountMeasurementsByTest(t *testing.T) {
+	database := newSearchTestDatabase(t)
+
+	t.Run("with an empty filter", func(t *testing.T) {
+		counts, err := database.CountMeasurementsByTest(&model.DatabaseMeasurementFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := []model.DatabaseTestCount{{
+			TestName:     "ndt",
+			AnomalyCount: 0,
+			FailureCount: 0,
+			TotalCount:   1,
+		}, {
+			TestName:     "telegram",
+			AnomalyCount: 1,
+			FailureCount: 0,
+			TotalCount:   1,
+		}, {
+			TestName:     "web_connectivity",
+			AnomalyCount: 1,
+			FailureCount: 1,
+			TotalCount:   3,
+		}}
+		if diff := cmp.Diff(expect, counts); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with a filter", func(t *testing.T) {
+		counts, err := database.CountMeasurementsByTest(&model.DatabaseMeasurementFilter{
+			ASN: sql.NullInt64{Int64: 30722, Valid: true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := []model.DatabaseTestCount{{
+			TestName:     "telegram",
+			AnomalyCount: 1,
+			FailureCount: 0,
+			TotalCount:   1,
+		}, {
+			TestName:     "web_connectivity",
+			AnomalyCount: 1,
+			FailureCount: 0,
+			TotalCount:   2,
+		}}
+		if diff := cmp.Diff(expect, counts); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when the query fails", func(t *testing.T) {
+		database := newBrokenSearchTestDatabase(t)
+		counts, err := database.CountMeasurementsByTest(&model.DatabaseMeasurementFilter{})
+		if err == nil {
+			t.Fatal("expected an error here")
+		}
+		if counts != nil {
+			t.Fatal("expected nil counts")
+		}
+	})
+}
+
+func TestSchemaVersion(t *testing.T) {
+	database, err := Open(filepath.Join(t.TempDir(), "main.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	version, err := database.SchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 4 {
+		t.Fatal("unexpected schema version", version)
+	}
+}
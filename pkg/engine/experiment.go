@@ -246,6 +246,7 @@ func (e *experiment) MeasureWithContext(
 
 	// Record when the experiment finished running.
 	stop := time.Now()
+	e.session.metrics.ObserveMeasurement(e.testName, err)
 
 	// Handle the case where there was a fundamental error.
 	if err != nil {
@@ -260,10 +261,9 @@ func (e *experiment) MeasureWithContext(
 	// going to submit the measurement in case we can't scrub it, so we just return an error
 	// if this specific corner case happens.
 	//
-	// TODO(bassosimone,DecFox): a dual stack client MAY be such that we discover its IPv4
-	// address but the IPv6 address is present inside the measurement. We should ensure that
-	// we improve our discovering capabilities to also cover this specific case.
-	if err := model.ScrubMeasurement(measurement, e.session.ProbeIP()); err != nil {
+	// Note that a dual stack client has both an IPv4 and an IPv6 address and either of
+	// them could be present inside the measurement, so we scrub all the known addresses.
+	if err := model.ScrubMeasurement(measurement, e.session.ProbeIPs()...); err != nil {
 		e.session.Logger().Warnf("can't scrub measurement: %s", err.Error())
 		return nil, err
 	}
@@ -6,17 +6,20 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"slices"
 	"sync"
 	"sync/atomic"
 
 	"github.com/ooni/probe-engine/pkg/bytecounter"
 	"github.com/ooni/probe-engine/pkg/enginelocate"
+	"github.com/ooni/probe-engine/pkg/enginemetrics"
 	"github.com/ooni/probe-engine/pkg/enginenetx"
 	"github.com/ooni/probe-engine/pkg/engineresolver"
 	"github.com/ooni/probe-engine/pkg/kvstore"
 	"github.com/ooni/probe-engine/pkg/model"
 	"github.com/ooni/probe-engine/pkg/platform"
 	"github.com/ooni/probe-engine/pkg/probeservices"
+	"github.com/ooni/probe-engine/pkg/runevents"
 	"github.com/ooni/probe-engine/pkg/runtimex"
 	"github.com/ooni/probe-engine/pkg/tunnel"
 	"github.com/ooni/probe-engine/pkg/version"
@@ -25,6 +28,7 @@ import (
 // SessionConfig contains the Session config
 type SessionConfig struct {
 	AvailableProbeServices []model.OOAPIService
+	Events                 runevents.Sink
 	KVStore                model.KeyValueStore
 	GeoipDB                string
 	Logger                 model.Logger
@@ -35,10 +39,32 @@ type SessionConfig struct {
 	TorArgs                []string
 	TorBinary              string
 
+	// Metrics is the OPTIONAL registry where to record metrics. The
+	// session attaches itself to the registry when it is created and
+	// detaches itself when it is closed.
+	Metrics *enginemetrics.Registry
+
+	// RaceQUIC OPTIONALLY enables racing TLS over TCP with QUIC when
+	// communicating with the OONI backend. Racing is experimental, hence
+	// we only race when this field is true and we're not using a proxy.
+	RaceQUIC bool
+
 	// SnowflakeRendezvous is the rendezvous method
 	// to be used by the torsf tunnel
 	SnowflakeRendezvous string
 
+	// SubmitQueueDir is the OPTIONAL directory where we should
+	// persist the measurements that we could not submit yet. When
+	// this field is set, NewSubmitter returns a submitter that
+	// persists each measurement before submitting it and retries
+	// submitting pending measurements across sessions.
+	SubmitQueueDir string
+
+	// SubmitQueueDatabase is the OPTIONAL results database that the
+	// queue inside SubmitQueueDir uses to mark the measurements that
+	// reference rows inside such a database as uploaded.
+	SubmitQueueDatabase probeservices.SubmitQueueDatabase
+
 	// TunnelDir is the directory where we should store
 	// the state of persistent tunnels. This field is
 	// optional _unless_ you want to use tunnels. In such
@@ -55,10 +81,12 @@ type Session struct {
 	availableProbeServices   []model.OOAPIService
 	availableTestHelpers     map[string][]model.OOAPIService
 	byteCounter              *bytecounter.Counter
+	events                   runevents.Sink
 	network                  *enginenetx.Network
 	kvStore                  model.KeyValueStore
 	location                 *enginelocate.Results
 	logger                   model.Logger
+	metrics                  *enginemetrics.Registry
 	proxyURL                 *url.URL
 	queryProbeServicesCount  *atomic.Int64
 	resolver                 *engineresolver.Resolver
@@ -67,6 +95,8 @@ type Session struct {
 	geoipDB                  string
 	softwareName             string
 	softwareVersion          string
+	submitQueueDir           string
+	submitQueueDatabase      probeservices.SubmitQueueDatabase
 	tempDir                  string
 
 	// closeOnce allows us to call Close just once.
@@ -174,12 +204,16 @@ func NewSession(ctx context.Context, config SessionConfig) (*Session, error) {
 	sess := &Session{
 		availableProbeServices:  config.AvailableProbeServices,
 		byteCounter:             bytecounter.New(),
+		events:                  config.Events,
 		kvStore:                 config.KVStore,
 		logger:                  config.Logger,
+		metrics:                 config.Metrics,
 		geoipDB:                 config.GeoipDB,
 		queryProbeServicesCount: &atomic.Int64{},
 		softwareName:            config.SoftwareName,
 		softwareVersion:         config.SoftwareVersion,
+		submitQueueDir:          config.SubmitQueueDir,
+		submitQueueDatabase:     config.SubmitQueueDatabase,
 		tempDir:                 tempDir,
 		torArgs:                 config.TorArgs,
 		torBinary:               config.TorBinary,
@@ -223,10 +257,24 @@ func NewSession(ctx context.Context, config SessionConfig) (*Session, error) {
 		sess.logger,
 		proxyURL,
 		sess.resolver,
+		enginenetx.NetworkOptionRaceQUIC(config.RaceQUIC),
 	)
+	sess.metrics.Attach(sess)
 	return sess, nil
 }
 
+var _ enginemetrics.Source = &Session{}
+
+// MetricsSnapshot implements enginemetrics.Source.
+func (s *Session) MetricsSnapshot() *enginemetrics.Snapshot {
+	return &enginemetrics.Snapshot{
+		BytesReceived:   s.byteCounter.BytesReceived(),
+		BytesSent:       s.byteCounter.BytesSent(),
+		DomainEndpoints: s.network.Stats(),
+		ResolverScores:  s.resolver.Scores(),
+	}
+}
+
 // TunnelDir returns the persistent directory used by tunnels.
 func (s *Session) TunnelDir() string {
 	return s.tunnelDir
@@ -302,8 +350,14 @@ func (s *Session) CheckIn(
 	}
 	resp, err := client.CheckIn(ctx, *config)
 	if err != nil {
+		runevents.Emit(s.events, &runevents.CheckIn{Failure: runevents.NewFailure(err)})
 		return nil, err
 	}
+	ev := &runevents.CheckIn{}
+	if wc := resp.Tests.WebConnectivity; wc != nil {
+		ev.ReportID, ev.URLs = wc.ReportID, len(wc.URLs)
+	}
+	runevents.Emit(s.events, ev)
 	return resp, nil
 }
 
@@ -352,6 +406,13 @@ func (s *Session) doClose() {
 		s.tunnel.Stop()
 	}
 	_ = os.RemoveAll(s.tempDir)
+	s.metrics.Detach(s)
+
+	runevents.Emit(s.events, &runevents.Bytes{
+		Scope:             "session",
+		KibiBytesSent:     s.KibiBytesSent(),
+		KibiBytesReceived: s.KibiBytesReceived(),
+	})
 }
 
 // GetTestHelpersByName returns the available test helpers that
@@ -467,7 +528,76 @@ func (s *Session) NewSubmitter(ctx context.Context) (model.Submitter, error) {
 	if err != nil {
 		return nil, err
 	}
-	return probeservices.NewSubmitter(psc, s.Logger()), nil
+	if s.submitQueueDir == "" {
+		return s.maybeWrapSubmitterWithMetrics(probeservices.NewSubmitter(psc, s.Logger())), nil
+	}
+	queue, err := s.newSubmitQueue(psc)
+	if err != nil {
+		return nil, err
+	}
+	// Note: a failure here is not fatal because we'll try again the
+	// next time someone creates a new submitter.
+	count, err := queue.Flush(ctx)
+	if count > 0 {
+		s.Logger().Infof("submitted %d previously-queued measurements", count)
+	}
+	if err != nil {
+		s.Logger().Warnf("cannot submit previously-queued measurements: %s", err.Error())
+	}
+	return s.maybeWrapSubmitterWithMetrics(queue), nil
+}
+
+// ErrNoSubmitQueueDir indicates that we cannot create a submission
+// queue because the session has no SubmitQueueDir.
+var ErrNoSubmitQueueDir = errors.New("session: no SubmitQueueDir")
+
+// NewSubmitQueue creates a new [*probeservices.SubmitQueue] persisting
+// measurements inside the session's SubmitQueueDir. Unlike NewSubmitter,
+// this function does not attempt to submit the pending measurements.
+func (s *Session) NewSubmitQueue(ctx context.Context) (*probeservices.SubmitQueue, error) {
+	if s.submitQueueDir == "" {
+		return nil, ErrNoSubmitQueueDir
+	}
+	psc, err := s.newProbeServicesClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.newSubmitQueue(psc)
+}
+
+// newSubmitQueue creates a new [*probeservices.SubmitQueue] using the given client.
+func (s *Session) newSubmitQueue(psc *probeservices.Client) (*probeservices.SubmitQueue, error) {
+	store, err := kvstore.NewFS(s.submitQueueDir)
+	if err != nil {
+		return nil, err
+	}
+	queue := probeservices.NewSubmitQueue(store, psc, s.Logger())
+	queue.Database = s.submitQueueDatabase
+	return queue, nil
+}
+
+// maybeWrapSubmitterWithMetrics wraps the given [model.Submitter] such that
+// we record submission metrics, if metrics are enabled.
+func (s *Session) maybeWrapSubmitterWithMetrics(submitter model.Submitter) model.Submitter {
+	if s.metrics == nil {
+		return submitter
+	}
+	return &sessionMetricsSubmitter{metrics: s.metrics, submitter: submitter}
+}
+
+// sessionMetricsSubmitter is a [model.Submitter] recording metrics.
+type sessionMetricsSubmitter struct {
+	metrics   *enginemetrics.Registry
+	submitter model.Submitter
+}
+
+var _ model.Submitter = &sessionMetricsSubmitter{}
+
+// Submit implements model.Submitter.
+func (sms *sessionMetricsSubmitter) Submit(ctx context.Context, m *model.Measurement) (string, error) {
+	reportID, err := sms.submitter.Submit(ctx, m)
+	sms.metrics.ObserveSubmission(m.TestName, err)
+	return reportID, err
 }
 
 // newOrchestraClient creates a new orchestra client. This client is registered
@@ -545,6 +675,45 @@ func (s *Session) ProbeIP() string {
 	return ip
 }
 
+// ProbeIPv4 returns the probe IPv4 address or an empty string if unknown.
+func (s *Session) ProbeIPv4() string {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+	var ip string
+	if s.location != nil {
+		ip = s.location.ProbeIPv4
+	}
+	return ip
+}
+
+// ProbeIPv6 returns the probe IPv6 address or an empty string if unknown.
+func (s *Session) ProbeIPv6() string {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+	var ip string
+	if s.location != nil {
+		ip = s.location.ProbeIPv6
+	}
+	return ip
+}
+
+// ProbeIPs returns all the known probe IP addresses, which include both
+// the IPv4 and the IPv6 addresses when the probe is dual stack.
+func (s *Session) ProbeIPs() []string {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+	if s.location == nil {
+		return []string{model.DefaultProbeIP}
+	}
+	ips := []string{s.location.ProbeIP}
+	for _, ip := range []string{s.location.ProbeIPv4, s.location.ProbeIPv6} {
+		if ip != "" && !slices.Contains(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
 // GeoipDB return the geoip database path
 func (s *Session) GeoipDB() string {
 	defer s.mu.Unlock()
@@ -694,8 +863,12 @@ func (s *Session) MaybeLookupBackendsContext(ctx context.Context) error {
 	candidates := probeservices.TryAll(ctx, s, s.getAvailableProbeServicesUnlocked())
 	selected := probeservices.SelectBest(candidates)
 	if selected == nil {
+		runevents.Emit(s.events, &runevents.SessionBootstrap{
+			Failure: runevents.NewFailure(ErrAllProbeServicesFailed),
+		})
 		return ErrAllProbeServicesFailed
 	}
+	runevents.Emit(s.events, &runevents.SessionBootstrap{ProbeServicesURL: selected.Service.Address})
 	s.logger.Infof("session: using probe services: %+v", selected.Service)
 	s.selectedProbeService = &selected.Service
 	s.availableTestHelpers = selected.TestHelpers
@@ -735,9 +908,18 @@ func (s *Session) MaybeLookupLocationContext(ctx context.Context) error {
 	if s.location == nil {
 		location, err := s.lookupLocationContext(ctx)
 		if err != nil {
+			runevents.Emit(s.events, &runevents.Geolocation{Failure: runevents.NewFailure(err)})
 			return err
 		}
 		s.location = location
+		runevents.Emit(s.events, &runevents.Geolocation{
+			ProbeASN:            location.ASNString(),
+			ProbeCC:             location.CountryCode,
+			ProbeNetworkName:    location.NetworkName,
+			ResolverASN:         fmt.Sprintf("AS%d", location.ResolverASN),
+			ResolverIP:          location.ResolverIP,
+			ResolverNetworkName: location.ResolverNetworkName,
+		})
 	}
 	return nil
 }
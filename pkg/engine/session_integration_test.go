@@ -505,3 +505,19 @@ func TestSessionNewSubmitterReturnsNonNilSubmitter(t *testing.T) {
 		t.Fatal("expected non nil submitter here")
 	}
 }
+
+func TestSessionNewSubmitterWithSubmitQueueDir(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+
+	sess := newSessionForTesting(t)
+	sess.submitQueueDir = t.TempDir()
+	subm, err := sess.NewSubmitter(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, good := subm.(*probeservices.SubmitQueue); !good {
+		t.Fatal("expected a submit queue here")
+	}
+}
@@ -1,9 +1,13 @@
 package engine
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -12,11 +16,15 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-engine/pkg/checkincache"
 	"github.com/ooni/probe-engine/pkg/enginelocate"
+	"github.com/ooni/probe-engine/pkg/enginemetrics"
 	"github.com/ooni/probe-engine/pkg/experiment/webconnectivity"
 	"github.com/ooni/probe-engine/pkg/experiment/webconnectivitylte"
 	"github.com/ooni/probe-engine/pkg/kvstore"
+	"github.com/ooni/probe-engine/pkg/mocks"
 	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/probeservices"
 	"github.com/ooni/probe-engine/pkg/registry"
+	"github.com/ooni/probe-engine/pkg/runevents"
 )
 
 func (s *Session) GetAvailableProbeServices() []model.OOAPIService {
@@ -159,6 +167,71 @@ func TestSessionCheckInNetworkError(t *testing.T) {
 	}
 }
 
+func TestSessionCheckInEmitsEvents(t *testing.T) {
+	run := func(t *testing.T, clnt *mockableProbeServicesClientForCheckIn) *runevents.CheckIn {
+		var buf bytes.Buffer
+		s := &Session{
+			events: runevents.NewJSONLWriter(&buf),
+			location: &enginelocate.Results{
+				ASN:         137,
+				CountryCode: "IT",
+			},
+			kvStore:         &kvstore.Memory{},
+			softwareName:    "miniooni",
+			softwareVersion: "0.1.0-dev",
+			testMaybeLookupLocationContext: func(ctx context.Context) error {
+				return nil
+			},
+			testNewProbeServicesClientForCheckIn: func(
+				ctx context.Context) (sessionProbeServicesClientForCheckIn, error) {
+				return clnt, nil
+			},
+		}
+		_, _ = s.CheckIn(context.Background(), &model.OOAPICheckInConfig{})
+		var ev struct {
+			Type string            `json:"type"`
+			Data runevents.CheckIn `json:"data"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Type != "check_in" {
+			t.Fatal("unexpected event type", ev.Type)
+		}
+		return &ev.Data
+	}
+
+	t.Run("on success", func(t *testing.T) {
+		ev := run(t, &mockableProbeServicesClientForCheckIn{
+			Results: &model.OOAPICheckInResult{
+				Tests: model.OOAPICheckInResultNettests{
+					WebConnectivity: &model.OOAPICheckInInfoWebConnectivity{
+						ReportID: "xxx-x-xx",
+						URLs: []model.OOAPIURLInfo{{
+							CategoryCode: "NEWS",
+							CountryCode:  "IT",
+							URL:          "https://www.repubblica.it/",
+						}},
+					},
+				},
+			},
+		})
+		expect := &runevents.CheckIn{ReportID: "xxx-x-xx", URLs: 1}
+		if diff := cmp.Diff(expect, ev); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("on failure", func(t *testing.T) {
+		ev := run(t, &mockableProbeServicesClientForCheckIn{
+			Error: errors.New("mocked error"),
+		})
+		if ev.Failure == nil || *ev.Failure != "mocked error" {
+			t.Fatal("unexpected failure", ev.Failure)
+		}
+	})
+}
+
 func TestSessionCheckInCannotLookupLocation(t *testing.T) {
 	errMocked := errors.New("mocked error")
 	s := &Session{
@@ -241,6 +314,35 @@ func TestSessionNewSubmitterWithCancelledContext(t *testing.T) {
 	}
 }
 
+func TestSessionNewSubmitQueue(t *testing.T) {
+	t.Run("without a SubmitQueueDir", func(t *testing.T) {
+		sess := &Session{}
+		queue, err := sess.NewSubmitQueue(context.Background())
+		if !errors.Is(err, ErrNoSubmitQueueDir) {
+			t.Fatal("not the error we expected", err)
+		}
+		if queue != nil {
+			t.Fatal("expected nil queue here")
+		}
+	})
+
+	t.Run("we use the configured results database", func(t *testing.T) {
+		database := &mocks.Database{}
+		sess := &Session{
+			logger:              model.DiscardLogger,
+			submitQueueDir:      t.TempDir(),
+			submitQueueDatabase: database,
+		}
+		queue, err := sess.newSubmitQueue(&probeservices.Client{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if queue.Database != database {
+			t.Fatal("unexpected database")
+		}
+	})
+}
+
 func TestSessionMaybeLookupLocationContextLookupLocationContextFailure(t *testing.T) {
 	errMocked := errors.New("mocked error")
 	sess := newSessionForTestingNoLookups(t)
@@ -419,3 +521,92 @@ func TestSessionNewExperimentBuilder(t *testing.T) {
 		}
 	})
 }
+
+func TestSessionProbeIPs(t *testing.T) {
+	t.Run("without a location", func(t *testing.T) {
+		sess := &Session{}
+		if diff := cmp.Diff([]string{model.DefaultProbeIP}, sess.ProbeIPs()); diff != "" {
+			t.Fatal(diff)
+		}
+		if sess.ProbeIPv4() != "" || sess.ProbeIPv6() != "" {
+			t.Fatal("expected empty addresses")
+		}
+	})
+
+	t.Run("with a single stack location", func(t *testing.T) {
+		sess := &Session{location: &enginelocate.Results{
+			ProbeIP:   "130.192.91.211",
+			ProbeIPv4: "130.192.91.211",
+		}}
+		if diff := cmp.Diff([]string{"130.192.91.211"}, sess.ProbeIPs()); diff != "" {
+			t.Fatal(diff)
+		}
+		if sess.ProbeIPv4() != "130.192.91.211" || sess.ProbeIPv6() != "" {
+			t.Fatal("unexpected addresses")
+		}
+	})
+
+	t.Run("with a dual stack location", func(t *testing.T) {
+		sess := &Session{location: &enginelocate.Results{
+			ProbeIP:   "2001:db8::1",
+			ProbeIPv4: "130.192.91.211",
+			ProbeIPv6: "2001:db8::1",
+		}}
+		if diff := cmp.Diff([]string{"2001:db8::1", "130.192.91.211"}, sess.ProbeIPs()); diff != "" {
+			t.Fatal(diff)
+		}
+		if sess.ProbeIPv4() != "130.192.91.211" || sess.ProbeIPv6() != "2001:db8::1" {
+			t.Fatal("unexpected addresses")
+		}
+	})
+}
+
+func TestSessionMetrics(t *testing.T) {
+	t.Run("the session attaches to and detaches from the registry", func(t *testing.T) {
+		metrics := enginemetrics.NewRegistry()
+		sess, err := NewSession(context.Background(), SessionConfig{
+			Logger:          model.DiscardLogger,
+			Metrics:         metrics,
+			SoftwareName:    "miniooni",
+			SoftwareVersion: "0.1.0-dev",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sess.byteCounter.CountBytesReceived(128)
+		snapshot := sess.MetricsSnapshot()
+		if snapshot.BytesReceived != 128 || len(snapshot.ResolverScores) <= 0 {
+			t.Fatalf("unexpected snapshot %+v", snapshot)
+		}
+		if err := sess.Close(); err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		metrics.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+		if !strings.Contains(rr.Body.String(), "ooniprobe_bytes_received_total 128") {
+			t.Fatal("unexpected metrics", rr.Body.String())
+		}
+	})
+
+	t.Run("we do not wrap the submitter without metrics", func(t *testing.T) {
+		sess := &Session{}
+		submitter := &mocks.Submitter{}
+		if sess.maybeWrapSubmitterWithMetrics(submitter) != submitter {
+			t.Fatal("should not have wrapped the submitter")
+		}
+	})
+
+	t.Run("we wrap the submitter with metrics", func(t *testing.T) {
+		sess := &Session{metrics: enginemetrics.NewRegistry()}
+		expected := errors.New("mocked error")
+		submitter := sess.maybeWrapSubmitterWithMetrics(&mocks.Submitter{
+			MockSubmit: func(ctx context.Context, m *model.Measurement) (string, error) {
+				return "", expected
+			},
+		})
+		meas := &model.Measurement{TestName: "web_connectivity"}
+		if _, err := submitter.Submit(context.Background(), meas); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
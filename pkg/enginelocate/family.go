@@ -0,0 +1,67 @@
+package enginelocate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/netxlite"
+)
+
+const (
+	// familyIPv4 restricts the IP lookup to IPv4.
+	familyIPv4 = "ipv4"
+
+	// familyIPv6 restricts the IP lookup to IPv6.
+	familyIPv6 = "ipv6"
+)
+
+// ErrWrongIPFamily indicates that a lookupper restricted to a given
+// IP family returned to us an IP address of the other family.
+var ErrWrongIPFamily = errors.New("lookupper returned an IP of the wrong family")
+
+// isFamily returns whether the given IP address belongs to family.
+func isFamily(ipAddr, family string) bool {
+	isv6, err := netxlite.IsIPv6(ipAddr)
+	if err != nil {
+		return false
+	}
+	switch family {
+	case familyIPv4:
+		return !isv6
+	case familyIPv6:
+		return isv6
+	default:
+		return true
+	}
+}
+
+// familyResolver is a [model.Resolver] only returning the addresses
+// belonging to a given IP family. Because we dial the addresses returned
+// by the resolver, wrapping the resolver used by an IP lookup method
+// forces such a method to communicate using the given IP family.
+type familyResolver struct {
+	model.Resolver
+	family string
+}
+
+var _ model.Resolver = &familyResolver{}
+
+// LookupHost implements model.Resolver.
+func (r *familyResolver) LookupHost(ctx context.Context, hostname string) ([]string, error) {
+	addrs, err := r.Resolver.LookupHost(ctx, hostname)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, addr := range addrs {
+		if isFamily(addr, r.family) {
+			out = append(out, addr)
+		}
+	}
+	if len(out) <= 0 {
+		return nil, fmt.Errorf("%w: no %s addresses for %s", netxlite.ErrOODNSNoAnswer, r.family, hostname)
+	}
+	return out, nil
+}
@@ -0,0 +1,91 @@
+package enginelocate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/mocks"
+	"github.com/ooni/probe-engine/pkg/netxlite"
+)
+
+func TestIsFamily(t *testing.T) {
+	type testcase struct {
+		ipAddr string
+		family string
+		expect bool
+	}
+
+	cases := []testcase{
+		{ipAddr: "1.2.3.4", family: familyIPv4, expect: true},
+		{ipAddr: "1.2.3.4", family: familyIPv6, expect: false},
+		{ipAddr: "1.2.3.4", family: "", expect: true},
+		{ipAddr: "2001:db8::1", family: familyIPv4, expect: false},
+		{ipAddr: "2001:db8::1", family: familyIPv6, expect: true},
+		{ipAddr: "2001:db8::1", family: "", expect: true},
+		{ipAddr: "invalid", family: "", expect: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.ipAddr+"/"+tc.family, func(t *testing.T) {
+			if got := isFamily(tc.ipAddr, tc.family); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
+
+func TestFamilyResolver(t *testing.T) {
+	newResolver := func(family string, addrs []string, err error) *familyResolver {
+		return &familyResolver{
+			Resolver: &mocks.Resolver{
+				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+					return addrs, err
+				},
+			},
+			family: family,
+		}
+	}
+
+	t.Run("we only return addresses of the given family", func(t *testing.T) {
+		addrs := []string{"1.2.3.4", "2001:db8::1", "5.6.7.8", "2001:db8::2"}
+
+		got, err := newResolver(familyIPv4, addrs, nil).LookupHost(context.Background(), "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"1.2.3.4", "5.6.7.8"}, got); diff != "" {
+			t.Fatal(diff)
+		}
+
+		got, err = newResolver(familyIPv6, addrs, nil).LookupHost(context.Background(), "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"2001:db8::1", "2001:db8::2"}, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we fail when there are no addresses of the given family", func(t *testing.T) {
+		got, err := newResolver(familyIPv6, []string{"1.2.3.4"}, nil).LookupHost(context.Background(), "example.com")
+		if !errors.Is(err, netxlite.ErrOODNSNoAnswer) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(got) != 0 {
+			t.Fatal("expected no addresses")
+		}
+	})
+
+	t.Run("we forward the underlying resolver error", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		got, err := newResolver(familyIPv4, nil, expected).LookupHost(context.Background(), "example.com")
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(got) != 0 {
+			t.Fatal("expected no addresses")
+		}
+	})
+}
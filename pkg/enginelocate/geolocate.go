@@ -27,6 +27,12 @@ type Results struct {
 	// IP is the probe IP.
 	ProbeIP string
 
+	// ProbeIPv4 is the probe IPv4 address or empty if unknown.
+	ProbeIPv4 string
+
+	// ProbeIPv6 is the probe IPv6 address or empty if unknown.
+	ProbeIPv6 string
+
 	// ResolverASN is the resolver ASN.
 	ResolverASN uint
 
@@ -100,6 +106,18 @@ func NewTask(config Config) *Task {
 			Logger:    config.Logger,
 			UserAgent: config.UserAgent,
 		},
+		probeIPv4Lookupper: ipLookupClient{
+			Family:    familyIPv4,
+			Resolver:  config.Resolver,
+			Logger:    config.Logger,
+			UserAgent: config.UserAgent,
+		},
+		probeIPv6Lookupper: ipLookupClient{
+			Family:    familyIPv6,
+			Resolver:  config.Resolver,
+			Logger:    config.Logger,
+			UserAgent: config.UserAgent,
+		},
 		probeASNLookupper:    mmdbLookupper,
 		resolverASNLookupper: mmdbLookupper,
 		resolverIPLookupper: resolverLookupClient{
@@ -113,6 +131,8 @@ func NewTask(config Config) *Task {
 type Task struct {
 	countryLookupper     countryLookupper
 	probeIPLookupper     probeIPLookupper
+	probeIPv4Lookupper   probeIPLookupper
+	probeIPv6Lookupper   probeIPLookupper
 	probeASNLookupper    asnLookupper
 	resolverASNLookupper asnLookupper
 	resolverIPLookupper  resolverIPLookupper
@@ -135,6 +155,7 @@ func (op Task) Run(ctx context.Context) (*Results, error) {
 		return out, fmt.Errorf("lookupProbeIP failed: %w", err)
 	}
 	out.ProbeIP = ip
+	op.lookupDualStackProbeIPs(ctx, out)
 	asn, networkName, err := op.probeASNLookupper.LookupASN(out.ProbeIP)
 	if err != nil {
 		return out, fmt.Errorf("lookupASN failed: %w", err)
@@ -166,3 +187,29 @@ func (op Task) Run(ctx context.Context) (*Results, error) {
 	out.ResolverNetworkName = resolverNetworkName
 	return out, nil
 }
+
+// lookupDualStackProbeIPs fills the ProbeIPv4 and ProbeIPv6 fields. We already know
+// the address belonging to the family used for discovering the ProbeIP, so we only
+// need to discover the address belonging to the other family. Because most networks
+// are not dual stack, failing to discover such an address is not an error.
+func (op Task) lookupDualStackProbeIPs(ctx context.Context, out *Results) {
+	lookupper := op.probeIPv6Lookupper
+	if isFamily(out.ProbeIP, familyIPv6) {
+		out.ProbeIPv6 = out.ProbeIP
+		lookupper = op.probeIPv4Lookupper
+	} else {
+		out.ProbeIPv4 = out.ProbeIP
+	}
+	if lookupper == nil {
+		return
+	}
+	ip, err := lookupper.LookupProbeIP(ctx)
+	if err != nil {
+		return // intentional
+	}
+	if isFamily(ip, familyIPv6) {
+		out.ProbeIPv6 = ip
+	} else {
+		out.ProbeIPv4 = ip
+	}
+}
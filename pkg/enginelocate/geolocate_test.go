@@ -265,6 +265,65 @@ func TestLocationLookupSuccessWithResolverLookup(t *testing.T) {
 	}
 }
 
+func TestLocationLookupDualStack(t *testing.T) {
+	newTask := func(probeIP string, v4, v6 probeIPLookupper) Task {
+		return Task{
+			probeIPLookupper:     taskProbeIPLookupper{ip: probeIP},
+			probeIPv4Lookupper:   v4,
+			probeIPv6Lookupper:   v6,
+			probeASNLookupper:    taskASNLookupper{asn: 1234, name: "1234.com"},
+			countryLookupper:     taskCCLookupper{cc: "IT"},
+			resolverIPLookupper:  taskResolverIPLookupper{ip: "4.3.2.1"},
+			resolverASNLookupper: taskASNLookupper{asn: 4321, name: "4321.com"},
+		}
+	}
+
+	t.Run("when the probe IP is IPv4 we discover the IPv6 address", func(t *testing.T) {
+		op := newTask(
+			"1.2.3.4",
+			taskProbeIPLookupper{err: errors.New("should not be called")},
+			taskProbeIPLookupper{ip: "2001:db8::1"},
+		)
+		out, err := op.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.ProbeIPv4 != "1.2.3.4" || out.ProbeIPv6 != "2001:db8::1" {
+			t.Fatal("unexpected addresses", out.ProbeIPv4, out.ProbeIPv6)
+		}
+	})
+
+	t.Run("when the probe IP is IPv6 we discover the IPv4 address", func(t *testing.T) {
+		op := newTask(
+			"2001:db8::1",
+			taskProbeIPLookupper{ip: "1.2.3.4"},
+			taskProbeIPLookupper{err: errors.New("should not be called")},
+		)
+		out, err := op.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.ProbeIPv4 != "1.2.3.4" || out.ProbeIPv6 != "2001:db8::1" {
+			t.Fatal("unexpected addresses", out.ProbeIPv4, out.ProbeIPv6)
+		}
+	})
+
+	t.Run("failing to discover the other address is not an error", func(t *testing.T) {
+		op := newTask(
+			"1.2.3.4",
+			taskProbeIPLookupper{ip: "5.6.7.8"},
+			taskProbeIPLookupper{err: errors.New("mocked error")},
+		)
+		out, err := op.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.ProbeIP != "1.2.3.4" || out.ProbeIPv4 != "1.2.3.4" || out.ProbeIPv6 != "" {
+			t.Fatal("unexpected addresses", out.ProbeIP, out.ProbeIPv4, out.ProbeIPv6)
+		}
+	})
+}
+
 func TestSmoke(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skip test in short mode")
@@ -57,6 +57,10 @@ var (
 )
 
 type ipLookupClient struct {
+	// Family is the OPTIONAL IP family to use (either "ipv4" or "ipv6"). When
+	// empty, we use the IP family preferred by the resolver and the network.
+	Family string
+
 	// Resolver is the resolver to use for HTTP.
 	Resolver model.Resolver
 
@@ -92,22 +96,32 @@ func (c ipLookupClient) doWithCustomFunc(
 	ctx, cancel := contextForIPLookupWithTimeout(ctx)
 	defer cancel()
 
+	// Restricting the addresses returned by the resolver is enough to force
+	// the lookup methods to use the IP family we would like to discover.
+	var resolver model.Resolver = c.Resolver
+	if c.Family != "" {
+		resolver = &familyResolver{Resolver: c.Resolver, family: c.Family}
+	}
+
 	// Implementation note: we MUST use an HTTP client that we're
 	// sure IS NOT using any proxy. To this end, we construct a
 	// client ourself that we know is not proxied.
 	// TODO(https://github.com/ooni/probe/issues/2534): the NewHTTPTransportWithResolver has QUIRKS but
 	// we don't care about them in this context
 	netx := &netxlite.Netx{}
-	txp := netxlite.NewHTTPTransportWithResolver(netx, c.Logger, c.Resolver)
+	txp := netxlite.NewHTTPTransportWithResolver(netx, c.Logger, resolver)
 	clnt := &http.Client{Transport: txp}
 	defer clnt.CloseIdleConnections()
-	ip, err := fn(ctx, clnt, c.Logger, c.UserAgent, c.Resolver)
+	ip, err := fn(ctx, clnt, c.Logger, c.UserAgent, resolver)
 	if err != nil {
 		return model.DefaultProbeIP, err
 	}
 	if net.ParseIP(ip) == nil {
 		return model.DefaultProbeIP, fmt.Errorf("%w: %s", ErrInvalidIPAddress, ip)
 	}
+	if !isFamily(ip, c.Family) {
+		return model.DefaultProbeIP, fmt.Errorf("%w: %s", ErrWrongIPFamily, ip)
+	}
 	c.Logger.Debugf("iplookup: IP: %s", ip)
 	return ip, nil
 }
@@ -115,7 +129,11 @@ func (c ipLookupClient) doWithCustomFunc(
 func (c ipLookupClient) LookupProbeIP(ctx context.Context) (string, error) {
 	union := multierror.New(ErrAllIPLookuppersFailed)
 	for _, method := range makeSlice() {
-		c.Logger.Infof("iplookup: using %s", method.name)
+		if c.Family != "" {
+			c.Logger.Infof("iplookup: using %s over %s", method.name, c.Family)
+		} else {
+			c.Logger.Infof("iplookup: using %s", method.name)
+		}
 		ip, err := c.doWithCustomFunc(ctx, method.fn)
 		if err == nil {
 			return ip, nil
@@ -64,6 +64,52 @@ func TestIPLookupInvalidIP(t *testing.T) {
 	}
 }
 
+func TestIPLookupWithFamily(t *testing.T) {
+	t.Run("we pass the lookup function a family resolver", func(t *testing.T) {
+		netx := &netxlite.Netx{}
+		var got model.Resolver
+		ip, err := (ipLookupClient{
+			Family:    familyIPv6,
+			Logger:    log.Log,
+			Resolver:  netx.NewStdlibResolver(model.DiscardLogger),
+			UserAgent: "ooniprobe-engine/0.1.0",
+		}).doWithCustomFunc(context.Background(), func(ctx context.Context, httpClient model.HTTPClient,
+			logger model.Logger, userAgent string, resolver model.Resolver) (string, error) {
+			got = resolver
+			return "2001:db8::1", nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ip != "2001:db8::1" {
+			t.Fatal("unexpected IP", ip)
+		}
+		reso, good := got.(*familyResolver)
+		if !good || reso.family != familyIPv6 {
+			t.Fatal("expected a family resolver")
+		}
+	})
+
+	t.Run("we reject IP addresses of the wrong family", func(t *testing.T) {
+		netx := &netxlite.Netx{}
+		ip, err := (ipLookupClient{
+			Family:    familyIPv6,
+			Logger:    log.Log,
+			Resolver:  netx.NewStdlibResolver(model.DiscardLogger),
+			UserAgent: "ooniprobe-engine/0.1.0",
+		}).doWithCustomFunc(context.Background(), func(ctx context.Context, httpClient model.HTTPClient,
+			logger model.Logger, userAgent string, resolver model.Resolver) (string, error) {
+			return "1.2.3.4", nil
+		})
+		if !errors.Is(err, ErrWrongIPFamily) {
+			t.Fatal("unexpected error", err)
+		}
+		if ip != model.DefaultProbeIP {
+			t.Fatal("expected the default IP here")
+		}
+	})
+}
+
 func TestContextForIPLookupWithTimeout(t *testing.T) {
 	now := time.Now()
 	ctx, cancel := contextForIPLookupWithTimeout(context.Background())
@@ -0,0 +1,99 @@
+package enginemetrics
+
+//
+// Metrics computed when scraping
+//
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// descBytesReceived describes the bytes received.
+	descBytesReceived = prometheus.NewDesc(
+		"ooniprobe_bytes_received_total",
+		"Total number of bytes received by measurement sessions",
+		nil, nil,
+	)
+
+	// descBytesSent describes the bytes sent.
+	descBytesSent = prometheus.NewDesc(
+		"ooniprobe_bytes_sent_total",
+		"Total number of bytes sent by measurement sessions",
+		nil, nil,
+	)
+
+	// descDialAttempts describes the dial attempts for each domain endpoint.
+	descDialAttempts = prometheus.NewDesc(
+		"ooniprobe_enginenetx_dial_attempts",
+		"Number of attempts to dial a domain endpoint using any tactic",
+		[]string{"domain_endpoint"}, nil,
+	)
+
+	// descDialSuccessRate describes the dial success rate for each domain endpoint.
+	descDialSuccessRate = prometheus.NewDesc(
+		"ooniprobe_enginenetx_dial_success_rate",
+		"Fraction of successful attempts to dial a domain endpoint using any tactic",
+		[]string{"domain_endpoint"}, nil,
+	)
+
+	// descResolverScore describes the score of each resolver.
+	descResolverScore = prometheus.NewDesc(
+		"ooniprobe_engineresolver_score",
+		"Score of the resolvers used by measurement sessions",
+		[]string{"url"}, nil,
+	)
+)
+
+// collector implements [prometheus.Collector] for a [*Registry].
+type collector struct {
+	r *Registry
+}
+
+var _ prometheus.Collector = &collector{}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descBytesReceived
+	ch <- descBytesSent
+	ch <- descDialAttempts
+	ch <- descDialSuccessRate
+	ch <- descResolverScore
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	// collect the snapshots while holding the mutex
+	c.r.mu.Lock()
+	bytesReceived, bytesSent := c.r.prevBytesReceived, c.r.prevBytesSent
+	latest := c.r.lastSnapshot
+	for _, src := range c.r.sources {
+		snapshot := src.MetricsSnapshot()
+		bytesReceived += snapshot.BytesReceived
+		bytesSent += snapshot.BytesSent
+		latest = snapshot
+	}
+	c.r.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(descBytesReceived, prometheus.CounterValue, float64(bytesReceived))
+	ch <- prometheus.MustNewConstMetric(descBytesSent, prometheus.CounterValue, float64(bytesSent))
+
+	// the dialing stats and the resolver scores are persisted across sessions, so
+	// we only report the ones of the most recently attached or detached session
+	if latest == nil {
+		return
+	}
+	for _, entry := range latest.DomainEndpoints {
+		ch <- prometheus.MustNewConstMetric(descDialAttempts, prometheus.GaugeValue,
+			float64(entry.CountStarted), entry.DomainEndpoint)
+		var rate float64
+		if entry.CountStarted > 0 {
+			rate = float64(entry.CountSuccess) / float64(entry.CountStarted)
+		}
+		ch <- prometheus.MustNewConstMetric(descDialSuccessRate, prometheus.GaugeValue,
+			rate, entry.DomainEndpoint)
+	}
+	for URL, score := range latest.ResolverScores {
+		ch <- prometheus.MustNewConstMetric(descResolverScore, prometheus.GaugeValue, score, URL)
+	}
+}
@@ -0,0 +1,201 @@
+// Package enginemetrics contains Prometheus metrics for long-running probes.
+//
+// A [*Registry] outlives the sessions using it, such that a probe that creates
+// a new session for each run keeps reporting monotonically increasing counters.
+package enginemetrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-engine/pkg/enginenetx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Snapshot contains the metrics that a [Source] collects.
+type Snapshot struct {
+	// BytesReceived is the number of bytes received so far.
+	BytesReceived int64
+
+	// BytesSent is the number of bytes sent so far.
+	BytesSent int64
+
+	// DomainEndpoints contains the dialing stats for each domain endpoint.
+	DomainEndpoints []*enginenetx.DomainEndpointStats
+
+	// ResolverScores maps each resolver URL to its score.
+	ResolverScores map[string]float64
+}
+
+// Source is a source of metrics (e.g., a measurement session) that we
+// query every time someone scrapes the metrics.
+type Source interface {
+	MetricsSnapshot() *Snapshot
+}
+
+// Registry contains the metrics.
+//
+// The zero value is invalid; construct using [NewRegistry].
+//
+// The Observe, Attach, and Detach methods of a nil [*Registry] do nothing, such
+// that code using a [*Registry] does not need to check whether metrics are enabled.
+type Registry struct {
+	// lastSnapshot is the snapshot of the most recently detached source.
+	lastSnapshot *Snapshot
+
+	// lastSubmission is the last time we successfully submitted a measurement.
+	lastSubmission time.Time
+
+	// measurements counts the measurements by experiment and result.
+	measurements *prometheus.CounterVec
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// prevBytesReceived is the bytes received by detached sources.
+	prevBytesReceived int64
+
+	// prevBytesSent is the bytes sent by detached sources.
+	prevBytesSent int64
+
+	// registry is the underlying prometheus registry.
+	registry *prometheus.Registry
+
+	// sources contains the attached sources.
+	sources []Source
+
+	// startTime is when we created the registry.
+	startTime time.Time
+
+	// submissions counts the submissions by experiment and result.
+	submissions *prometheus.CounterVec
+
+	// timeNow allows overriding time.Now in tests.
+	timeNow func() time.Time
+}
+
+// NewRegistry creates a new [*Registry].
+func NewRegistry() *Registry {
+	r := &Registry{
+		measurements: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ooniprobe_measurements_total",
+			Help: "Total number of measurements by experiment and result",
+		}, []string{"experiment", "result"}),
+		registry: prometheus.NewRegistry(),
+		submissions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ooniprobe_submissions_total",
+			Help: "Total number of measurement submissions by experiment and result",
+		}, []string{"experiment", "result"}),
+		timeNow: time.Now,
+	}
+	r.startTime = r.timeNow()
+	r.registry.MustRegister(r.measurements, r.submissions, &collector{r})
+	return r
+}
+
+// result maps an error to the value of the "result" label.
+func result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// ObserveMeasurement records that we finished running a measurement.
+func (r *Registry) ObserveMeasurement(experiment string, err error) {
+	if r == nil {
+		return
+	}
+	r.measurements.WithLabelValues(experiment, result(err)).Inc()
+}
+
+// ObserveSubmission records that we attempted to submit a measurement.
+func (r *Registry) ObserveSubmission(experiment string, err error) {
+	if r == nil {
+		return
+	}
+	r.submissions.WithLabelValues(experiment, result(err)).Inc()
+	if err == nil {
+		r.mu.Lock()
+		r.lastSubmission = r.timeNow()
+		r.mu.Unlock()
+	}
+}
+
+// Attach starts collecting metrics from the given [Source].
+func (r *Registry) Attach(src Source) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.sources = append(r.sources, src)
+	r.mu.Unlock()
+}
+
+// Detach stops collecting metrics from the given [Source]. We remember the
+// bytes it exchanged and the last values of its dialing stats and resolver
+// scores, such that the metrics persist after we close a session.
+func (r *Registry) Detach(src Source) {
+	if r == nil {
+		return
+	}
+	snapshot := src.MetricsSnapshot()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	idx := slices.Index(r.sources, src)
+	if idx < 0 {
+		return
+	}
+	r.sources = slices.Delete(r.sources, idx, idx+1)
+	r.prevBytesReceived += snapshot.BytesReceived
+	r.prevBytesSent += snapshot.BytesSent
+	r.lastSnapshot = snapshot
+}
+
+// Handler returns the [http.Handler] serving the metrics.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{})
+}
+
+// Health is the response returned by the health handler.
+type Health struct {
+	// Healthy indicates whether the probe is healthy.
+	Healthy bool `json:"healthy"`
+
+	// LastSubmission is the last time we successfully submitted a measurement.
+	LastSubmission *time.Time `json:"last_submission"`
+
+	// StartTime is when we started collecting metrics.
+	StartTime time.Time `json:"start_time"`
+}
+
+// HealthHandler returns an [http.Handler] returning 200 when we have successfully
+// submitted a measurement during the last maxAge and 503 otherwise. To give the
+// probe a chance to submit, we consider it healthy during the first maxAge.
+func (r *Registry) HealthHandler(maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		health := &Health{StartTime: r.startTime}
+		reference := r.startTime
+		if !r.lastSubmission.IsZero() {
+			lastSubmission := r.lastSubmission
+			health.LastSubmission = &lastSubmission
+			reference = lastSubmission
+		}
+		health.Healthy = r.timeNow().Sub(reference) <= maxAge
+		r.mu.Unlock()
+
+		status := http.StatusOK
+		if !health.Healthy {
+			status = http.StatusServiceUnavailable
+		}
+		data, _ := json.Marshal(health) // cannot fail
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write(data)
+	})
+}
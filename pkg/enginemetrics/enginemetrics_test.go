@@ -0,0 +1,170 @@
+package enginemetrics
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-engine/pkg/enginenetx"
+)
+
+// sourceFunc adapts a func to be a [Source].
+type sourceFunc func() *Snapshot
+
+func (fx sourceFunc) MetricsSnapshot() *Snapshot {
+	return fx()
+}
+
+// newSourceForTesting returns a [Source] returning the given snapshot.
+func newSourceForTesting(snapshot *Snapshot) *sourceFunc {
+	fx := sourceFunc(func() *Snapshot {
+		return snapshot
+	})
+	return &fx
+}
+
+// scrapeForTesting returns the metrics exported by the registry.
+func scrapeForTesting(t *testing.T, r *Registry) string {
+	srv := httptest.NewServer(r.Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("we export counters and the metrics of the sources", func(t *testing.T) {
+		r := NewRegistry()
+		r.ObserveMeasurement("web_connectivity", nil)
+		r.ObserveMeasurement("web_connectivity", nil)
+		r.ObserveMeasurement("web_connectivity", errors.New("mocked error"))
+		r.ObserveSubmission("web_connectivity", errors.New("mocked error"))
+
+		closed := newSourceForTesting(&Snapshot{BytesReceived: 1000, BytesSent: 100})
+		r.Attach(closed)
+		r.Detach(closed)
+		r.Detach(closed) // make sure we don't count bytes twice
+
+		r.Attach(newSourceForTesting(&Snapshot{
+			BytesReceived: 20,
+			BytesSent:     2,
+			DomainEndpoints: []*enginenetx.DomainEndpointStats{{
+				DomainEndpoint: "api.ooni.io:443",
+				CountStarted:   4,
+				CountSuccess:   1,
+			}},
+			ResolverScores: map[string]float64{
+				"https://dns.google/dns-query": 0.5,
+			},
+		}))
+
+		out := scrapeForTesting(t, r)
+		for _, expect := range []string{
+			`ooniprobe_measurements_total{experiment="web_connectivity",result="success"} 2`,
+			`ooniprobe_measurements_total{experiment="web_connectivity",result="failure"} 1`,
+			`ooniprobe_submissions_total{experiment="web_connectivity",result="failure"} 1`,
+			`ooniprobe_bytes_received_total 1020`,
+			`ooniprobe_bytes_sent_total 102`,
+			`ooniprobe_enginenetx_dial_attempts{domain_endpoint="api.ooni.io:443"} 4`,
+			`ooniprobe_enginenetx_dial_success_rate{domain_endpoint="api.ooni.io:443"} 0.25`,
+			`ooniprobe_engineresolver_score{url="https://dns.google/dns-query"} 0.5`,
+		} {
+			if !strings.Contains(out, expect) {
+				t.Fatal("metrics do not contain", expect, "\n", out)
+			}
+		}
+	})
+
+	t.Run("we remember the metrics of the last detached source", func(t *testing.T) {
+		r := NewRegistry()
+		src := newSourceForTesting(&Snapshot{
+			ResolverScores: map[string]float64{"system:///": 1},
+		})
+		r.Attach(src)
+		r.Detach(src)
+		if out := scrapeForTesting(t, r); !strings.Contains(out, `ooniprobe_engineresolver_score{url="system:///"} 1`) {
+			t.Fatal("unexpected metrics", out)
+		}
+	})
+
+	t.Run("a nil registry does nothing", func(t *testing.T) {
+		var r *Registry
+		r.ObserveMeasurement("web_connectivity", nil)
+		r.ObserveSubmission("web_connectivity", nil)
+		src := newSourceForTesting(&Snapshot{})
+		r.Attach(src)
+		r.Detach(src)
+	})
+}
+
+func TestRegistryHealthHandler(t *testing.T) {
+	type testcase struct {
+		name       string
+		submit     bool
+		elapsed    time.Duration
+		expectCode int
+	}
+
+	cases := []testcase{{
+		name:       "when we just started",
+		elapsed:    time.Minute,
+		expectCode: http.StatusOK,
+	}, {
+		name:       "when we never submitted",
+		elapsed:    2 * time.Hour,
+		expectCode: http.StatusServiceUnavailable,
+	}, {
+		name:       "when we recently submitted",
+		submit:     true,
+		elapsed:    time.Minute,
+		expectCode: http.StatusOK,
+	}, {
+		name:       "when we stopped submitting",
+		submit:     true,
+		elapsed:    2 * time.Hour,
+		expectCode: http.StatusServiceUnavailable,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			r := NewRegistry()
+			r.startTime = now
+			r.timeNow = func() time.Time {
+				return now
+			}
+			if tc.submit {
+				r.ObserveSubmission("web_connectivity", nil)
+			}
+			now = now.Add(tc.elapsed)
+
+			rr := httptest.NewRecorder()
+			r.HealthHandler(time.Hour).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+			if rr.Code != tc.expectCode {
+				t.Fatal("expected", tc.expectCode, "got", rr.Code)
+			}
+			var health Health
+			if err := json.Unmarshal(rr.Body.Bytes(), &health); err != nil {
+				t.Fatal(err)
+			}
+			if health.Healthy != (tc.expectCode == http.StatusOK) {
+				t.Fatal("unexpected healthy value", health.Healthy)
+			}
+			if (health.LastSubmission != nil) != tc.submit {
+				t.Fatal("unexpected last submission", health.LastSubmission)
+			}
+		})
+	}
+}
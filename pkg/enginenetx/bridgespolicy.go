@@ -46,7 +46,6 @@ func bridgesTacticsForDomain(domain, port string) <-chan *httpsDialerTactic {
 			for _, sni := range bridgesDomainsInRandomOrder() {
 				out <- &httpsDialerTactic{
 					Address:        ipAddr,
-					InitialDelay:   0, // set when dialing
 					Port:           port,
 					SNI:            sni,
 					VerifyHostname: domain,
@@ -41,10 +41,6 @@ func TestBridgesPolicyV2(t *testing.T) {
 				t.Fatal("the host should always be 162.55.247.208")
 			}
 
-			if tactic.InitialDelay != 0 {
-				t.Fatal("unexpected InitialDelay")
-			}
-
 			if tactic.SNI == "api.ooni.io" {
 				t.Fatal("we should not see the `api.ooni.io` SNI on the wire")
 			}
@@ -0,0 +1,246 @@
+package enginenetx
+
+//
+// dial scheduler - decides when to start each dialing attempt
+//
+
+import (
+	"context"
+	"time"
+
+	"github.com/ooni/probe-engine/pkg/netxlite"
+)
+
+// dialSchedule is the strategy deciding when to start the next dialing
+// attempt while all the previously started attempts are still running.
+type dialSchedule interface {
+	// Interval returns how long to wait after starting the attempt with the
+	// given zero-based index before starting the next attempt.
+	Interval(idx int) time.Duration
+}
+
+// happyEyeballsSchedule is the default [dialSchedule], which spaces
+// attempts according to the [happyEyeballsDelay] algorithm.
+//
+// The zero value is ready to use.
+type happyEyeballsSchedule struct{}
+
+var _ dialSchedule = happyEyeballsSchedule{}
+
+// Interval implements dialSchedule.
+func (happyEyeballsSchedule) Interval(idx int) time.Duration {
+	return happyEyeballsDelay(idx+1) - happyEyeballsDelay(idx)
+}
+
+// dialTimer abstracts a [*time.Timer].
+type dialTimer interface {
+	// C returns the channel where the timer posts when it expires.
+	C() <-chan time.Time
+
+	// Stop stops the timer.
+	Stop() bool
+}
+
+// dialClock abstracts the clock, such that we can test using a fake clock.
+type dialClock interface {
+	// NewTimer creates a new [dialTimer] expiring after the given duration.
+	NewTimer(d time.Duration) dialTimer
+}
+
+// realDialClock is the [dialClock] using the standard library.
+//
+// The zero value is ready to use.
+type realDialClock struct{}
+
+var _ dialClock = realDialClock{}
+
+// NewTimer implements dialClock.
+func (realDialClock) NewTimer(d time.Duration) dialTimer {
+	return &realDialTimer{time.NewTimer(d)}
+}
+
+// realDialTimer is the [dialTimer] returned by [realDialClock].
+type realDialTimer struct {
+	t *time.Timer
+}
+
+// C implements dialTimer.
+func (t *realDialTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+// Stop implements dialTimer.
+func (t *realDialTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// dialSchedulerDefaultParallelism is the default maximum number of
+// dialing attempts that we allow to run in parallel.
+const dialSchedulerDefaultParallelism = 16
+
+// dialScheduler schedules dialing attempts. We start the first attempt
+// immediately, then we start the next attempt either when the interval
+// defined by the [dialSchedule] expires or as soon as an attempt fails,
+// whichever comes first. When an attempt succeeds, we interrupt all the
+// other attempts and we stop scheduling new attempts.
+//
+// This is the same algorithm implemented by the [httpclientx] package for
+// overlapped HTTP calls, except that here we react immediately to failures.
+//
+// Construct using [newDialScheduler].
+type dialScheduler struct {
+	// Clock is the MANDATORY clock to use.
+	Clock dialClock
+
+	// Parallelism is the MANDATORY maximum number of attempts to run in parallel.
+	Parallelism int
+
+	// Schedule is the MANDATORY schedule to use.
+	Schedule dialSchedule
+}
+
+// newDialScheduler creates a new [*dialScheduler] using the [happyEyeballsSchedule],
+// the [realDialClock] and the [dialSchedulerDefaultParallelism].
+func newDialScheduler() *dialScheduler {
+	return &dialScheduler{
+		Clock:       realDialClock{},
+		Parallelism: dialSchedulerDefaultParallelism,
+		Schedule:    happyEyeballsSchedule{},
+	}
+}
+
+// dialSchedulerResult is the result of a dialing attempt.
+type dialSchedulerResult[T any] struct {
+	// Value is the result on success.
+	Value T
+
+	// Err is the error or nil.
+	Err error
+}
+
+// dialSchedulerRun uses the given [*dialScheduler] to call fx for each tactic
+// read from the channel returned by lookup, until the channel is closed. This
+// function returns the list of successful results and the list of errors.
+//
+// We call lookup with a context that we cancel after the first success, such
+// that the code emitting tactics can possibly stop early.
+//
+// After the first success or after the context has been canceled, we do not
+// call fx for the remaining tactics and we record the context error for each
+// of them. We keep reading until the tactics channel is closed, because the
+// code emitting tactics may not be able to stop early.
+func dialSchedulerRun[T any](
+	ctx context.Context,
+	s *dialScheduler,
+	lookup func(ctx context.Context) <-chan *httpsDialerTactic,
+	fx func(ctx context.Context, tactic *httpsDialerTactic) (T, error),
+) ([]T, []error) {
+	// We need a cancellable context to interrupt the other attempts and
+	// the tactics emitter as soon as we have a successful result.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Start emitting tactics.
+	tactics := lookup(ctx)
+
+	// The goroutines running attempts post their result here.
+	output := make(chan *dialSchedulerResult[T])
+
+	var (
+		// valuev contains the successful results.
+		valuev []T
+
+		// errorv contains the errors.
+		errorv []error
+
+		// idx is the index of the next attempt.
+		idx int
+
+		// running is the number of running attempts.
+		running int
+
+		// ready indicates whether it's time to start a new attempt.
+		ready = true
+
+		// timer is the timer for starting the next attempt or nil.
+		timer dialTimer
+
+		// timerC is the timer channel or nil.
+		timerC <-chan time.Time
+
+		// done is the context's done channel until we see it's done.
+		done = ctx.Done()
+	)
+
+	// stopTimer stops the timer and clears the related variables.
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		timer, timerC = nil, nil
+	}
+	defer stopTimer()
+
+	for tactics != nil || running > 0 {
+		// We read the next tactic either when we can start a new attempt or when
+		// we're draining the tactics channel after the context is done.
+		var input <-chan *httpsDialerTactic
+		if (ready && running < s.Parallelism) || ctx.Err() != nil {
+			input = tactics
+		}
+
+		select {
+		case tactic, good := <-input:
+			if !good {
+				tactics = nil
+				continue
+			}
+
+			// We're draining: record the context error.
+			if err := ctx.Err(); err != nil {
+				errorv = append(errorv, netxlite.NewTopLevelGenericErrWrapper(err))
+				continue
+			}
+
+			// Start a new attempt and arm the timer for the next one.
+			running++
+			go func() {
+				value, err := fx(ctx, tactic)
+				output <- &dialSchedulerResult[T]{Value: value, Err: err}
+			}()
+			stopTimer()
+			timer = s.Clock.NewTimer(s.Schedule.Interval(idx))
+			timerC = timer.C()
+			idx++
+			ready = false
+
+		case <-timerC:
+			// It's time to start another attempt.
+			timer, timerC = nil, nil
+			ready = true
+
+		case <-done:
+			// Stop waiting for the timer and start draining.
+			done = nil
+			stopTimer()
+
+		case result := <-output:
+			running--
+
+			// On failure, record the error and react immediately by
+			// starting another attempt without waiting for the timer.
+			if result.Err != nil {
+				errorv = append(errorv, result.Err)
+				stopTimer()
+				ready = true
+				continue
+			}
+
+			// On success, save the value and interrupt the other attempts.
+			valuev = append(valuev, result.Value)
+			cancel()
+		}
+	}
+
+	return valuev, errorv
+}
@@ -0,0 +1,334 @@
+package enginenetx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// dialSchedulerFakeTimer is a [dialTimer] that expires when we call Fire.
+type dialSchedulerFakeTimer struct {
+	ch chan time.Time
+}
+
+var _ dialTimer = &dialSchedulerFakeTimer{}
+
+// C implements dialTimer.
+func (t *dialSchedulerFakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+// Stop implements dialTimer.
+func (t *dialSchedulerFakeTimer) Stop() bool {
+	return true
+}
+
+// Fire makes the timer expire.
+func (t *dialSchedulerFakeTimer) Fire() {
+	t.ch <- time.Time{}
+}
+
+// dialSchedulerFakeClock is a [dialClock] where time only advances when
+// the test fires the timers it creates.
+type dialSchedulerFakeClock struct {
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// durations contains the durations passed to NewTimer.
+	durations []time.Duration
+
+	// timers receives each created timer.
+	timers chan *dialSchedulerFakeTimer
+}
+
+var _ dialClock = &dialSchedulerFakeClock{}
+
+func newDialSchedulerFakeClock() *dialSchedulerFakeClock {
+	return &dialSchedulerFakeClock{timers: make(chan *dialSchedulerFakeTimer, 128)}
+}
+
+// NewTimer implements dialClock.
+func (c *dialSchedulerFakeClock) NewTimer(d time.Duration) dialTimer {
+	timer := &dialSchedulerFakeTimer{ch: make(chan time.Time, 1)}
+	c.mu.Lock()
+	c.durations = append(c.durations, d)
+	c.mu.Unlock()
+	c.timers <- timer
+	return timer
+}
+
+// Durations returns the durations passed to NewTimer.
+func (c *dialSchedulerFakeClock) Durations() []time.Duration {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	return append([]time.Duration{}, c.durations...)
+}
+
+// newDialSchedulerTestTactics returns tactics for the given addresses.
+func newDialSchedulerTestTactics(addrs ...string) []*httpsDialerTactic {
+	var out []*httpsDialerTactic
+	for _, addr := range addrs {
+		out = append(out, &httpsDialerTactic{
+			Address:        addr,
+			Port:           "443",
+			SNI:            "api.ooni.io",
+			VerifyHostname: "api.ooni.io",
+		})
+	}
+	return out
+}
+
+func TestHappyEyeballsSchedule(t *testing.T) {
+	expect := []time.Duration{
+		time.Second,
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		8 * time.Second,
+		8 * time.Second,
+	}
+	var got []time.Duration
+	for idx := 0; idx < len(expect); idx++ {
+		got = append(got, happyEyeballsSchedule{}.Interval(idx))
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestRealDialClock(t *testing.T) {
+	timer := realDialClock{}.NewTimer(time.Millisecond)
+	<-timer.C()
+	if timer.Stop() {
+		t.Fatal("expected Stop to return false for an expired timer")
+	}
+}
+
+func TestDialSchedulerRun(t *testing.T) {
+	t.Run("we wait for the schedule when attempts are slow", func(t *testing.T) {
+		clock := newDialSchedulerFakeClock()
+		s := &dialScheduler{
+			Clock:       clock,
+			Parallelism: dialSchedulerDefaultParallelism,
+			Schedule:    happyEyeballsSchedule{},
+		}
+		tactics := newDialSchedulerTestTactics("10.0.0.1", "10.0.0.2", "10.0.0.3")
+
+		// each attempt tells us when it starts and blocks until we tell it
+		// what to return, which allows us to control the ordering of events
+		started := make(chan string, len(tactics))
+		results := map[string]chan error{}
+		for _, tactic := range tactics {
+			results[tactic.Address] = make(chan error, 1)
+		}
+
+		type output struct {
+			values []string
+			errors []error
+		}
+		outch := make(chan *output)
+		go func() {
+			values, errorv := dialSchedulerRun(context.Background(), s,
+				func(ctx context.Context) <-chan *httpsDialerTactic {
+					return streamTacticsFromSlice(tactics)
+				},
+				func(ctx context.Context, tactic *httpsDialerTactic) (string, error) {
+					started <- tactic.Address
+					select {
+					case err := <-results[tactic.Address]:
+						return tactic.Address, err
+					case <-ctx.Done():
+						return "", ctx.Err()
+					}
+				})
+			outch <- &output{values, errorv}
+		}()
+
+		// the first attempt starts immediately
+		if addr := <-started; addr != "10.0.0.1" {
+			t.Fatal("unexpected first attempt", addr)
+		}
+
+		// the second attempt starts when the first timer fires
+		timer := <-clock.timers
+		select {
+		case addr := <-started:
+			t.Fatal("attempt started before the timer fired", addr)
+		default:
+		}
+		timer.Fire()
+		if addr := <-started; addr != "10.0.0.2" {
+			t.Fatal("unexpected second attempt", addr)
+		}
+
+		// make the second attempt succeed
+		<-clock.timers
+		results["10.0.0.2"] <- nil
+		out := <-outch
+
+		if diff := cmp.Diff([]string{"10.0.0.2"}, out.values); diff != "" {
+			t.Fatal(diff)
+		}
+
+		// we expect to see the error of the first attempt, which we interrupted, and
+		// the error for the third tactic, which we did not attempt at all
+		if len(out.errors) != 2 {
+			t.Fatal("expected two errors, got", out.errors)
+		}
+		for _, err := range out.errors {
+			if !errors.Is(err, context.Canceled) && err.Error() != "interrupted" {
+				t.Fatal("unexpected error", err)
+			}
+		}
+
+		// make sure we have used the expected intervals
+		if diff := cmp.Diff([]time.Duration{time.Second, time.Second}, clock.Durations()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we start the next attempt immediately on failure", func(t *testing.T) {
+		clock := newDialSchedulerFakeClock()
+		s := &dialScheduler{
+			Clock:       clock,
+			Parallelism: dialSchedulerDefaultParallelism,
+			Schedule:    happyEyeballsSchedule{},
+		}
+		tactics := newDialSchedulerTestTactics("10.0.0.1", "10.0.0.2", "10.0.0.3")
+
+		// note that we never fire the timers, so this test would hang if
+		// we were waiting for the timers to start the next attempt
+		var attempts []string
+		values, errorv := dialSchedulerRun(context.Background(), s,
+			func(ctx context.Context) <-chan *httpsDialerTactic {
+				return streamTacticsFromSlice(tactics)
+			},
+			func(ctx context.Context, tactic *httpsDialerTactic) (string, error) {
+				attempts = append(attempts, tactic.Address)
+				if tactic.Address != "10.0.0.3" {
+					return "", errors.New("connection_refused")
+				}
+				return tactic.Address, nil
+			})
+
+		if diff := cmp.Diff([]string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}, attempts); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff([]string{"10.0.0.3"}, values); diff != "" {
+			t.Fatal(diff)
+		}
+		if len(errorv) != 2 {
+			t.Fatal("expected two errors, got", errorv)
+		}
+	})
+
+	t.Run("we honor the parallelism", func(t *testing.T) {
+		clock := newDialSchedulerFakeClock()
+		s := &dialScheduler{
+			Clock:       clock,
+			Parallelism: 1,
+			Schedule:    happyEyeballsSchedule{},
+		}
+		tactics := newDialSchedulerTestTactics("10.0.0.1", "10.0.0.2")
+
+		started := make(chan string, len(tactics))
+		release := make(chan any)
+		done := make(chan []error)
+		go func() {
+			_, errorv := dialSchedulerRun(context.Background(), s,
+				func(ctx context.Context) <-chan *httpsDialerTactic {
+					return streamTacticsFromSlice(tactics)
+				},
+				func(ctx context.Context, tactic *httpsDialerTactic) (string, error) {
+					started <- tactic.Address
+					<-release
+					return "", errors.New("generic_timeout_error")
+				})
+			done <- errorv
+		}()
+
+		<-started
+		(<-clock.timers).Fire()
+
+		// even though the timer fired, we cannot start another attempt
+		select {
+		case addr := <-started:
+			t.Fatal("attempt started despite the parallelism", addr)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		close(release)
+		if addr := <-started; addr != "10.0.0.2" {
+			t.Fatal("unexpected second attempt", addr)
+		}
+		if errorv := <-done; len(errorv) != 2 {
+			t.Fatal("expected two errors, got", errorv)
+		}
+	})
+
+	t.Run("we do not start attempts when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel() // immediately!
+
+		s := &dialScheduler{
+			Clock:       newDialSchedulerFakeClock(),
+			Parallelism: dialSchedulerDefaultParallelism,
+			Schedule:    happyEyeballsSchedule{},
+		}
+		tactics := newDialSchedulerTestTactics("10.0.0.1", "10.0.0.2")
+
+		var attempts int
+		values, errorv := dialSchedulerRun(ctx, s,
+			func(ctx context.Context) <-chan *httpsDialerTactic {
+				return streamTacticsFromSlice(tactics)
+			},
+			func(ctx context.Context, tactic *httpsDialerTactic) (string, error) {
+				attempts++
+				return tactic.Address, nil
+			})
+
+		if attempts != 0 {
+			t.Fatal("expected zero attempts, got", attempts)
+		}
+		if len(values) != 0 {
+			t.Fatal("expected zero values, got", values)
+		}
+		if len(errorv) != 2 || errorv[0].Error() != "interrupted" {
+			t.Fatal("unexpected errors", errorv)
+		}
+	})
+
+	t.Run("we cancel the lookup context after a success", func(t *testing.T) {
+		s := &dialScheduler{
+			Clock:       newDialSchedulerFakeClock(),
+			Parallelism: dialSchedulerDefaultParallelism,
+			Schedule:    happyEyeballsSchedule{},
+		}
+
+		canceled := make(chan any)
+		values, _ := dialSchedulerRun(context.Background(), s,
+			func(ctx context.Context) <-chan *httpsDialerTactic {
+				out := make(chan *httpsDialerTactic)
+				go func() {
+					defer close(out)
+					out <- newDialSchedulerTestTactics("10.0.0.1")[0]
+					<-ctx.Done()
+					close(canceled)
+				}()
+				return out
+			},
+			func(ctx context.Context, tactic *httpsDialerTactic) (string, error) {
+				return tactic.Address, nil
+			})
+
+		<-canceled
+		if diff := cmp.Diff([]string{"10.0.0.1"}, values); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
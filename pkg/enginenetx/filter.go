@@ -17,55 +17,44 @@ func filterOutNilTactics(input <-chan *httpsDialerTactic) <-chan *httpsDialerTac
 	return output
 }
 
-// filterOnlyKeepUniqueTactics only keeps unique tactics.
+// filterOnlyKeepTransport only keeps tactics using the given transport.
 //
 // This function returns a channel where we emit the edited
 // tactics, and which we clone when we're done.
-func filterOnlyKeepUniqueTactics(input <-chan *httpsDialerTactic) <-chan *httpsDialerTactic {
+func filterOnlyKeepTransport(input <-chan *httpsDialerTactic, transport tacticTransport) <-chan *httpsDialerTactic {
 	output := make(chan *httpsDialerTactic)
 	go func() {
-
-		// make sure we close output chan
 		defer close(output)
-
-		// useful to make sure we don't emit two equal policy in a single run
-		uniq := make(map[string]int)
-
 		for tx := range input {
-			// handle the case in which we already emitted a tactic
-			key := tx.tacticSummaryKey()
-			if uniq[key] > 0 {
-				continue
+			if tx.Transport == transport {
+				output <- tx
 			}
-			uniq[key]++
-
-			// emit the tactic
-			output <- tx
 		}
-
 	}()
 	return output
 }
 
-// filterAssignInitialDelays assigns initial delays to tactics.
+// filterOnlyKeepUniqueTactics only keeps unique tactics.
 //
 // This function returns a channel where we emit the edited
 // tactics, and which we clone when we're done.
-func filterAssignInitialDelays(input <-chan *httpsDialerTactic) <-chan *httpsDialerTactic {
+func filterOnlyKeepUniqueTactics(input <-chan *httpsDialerTactic) <-chan *httpsDialerTactic {
 	output := make(chan *httpsDialerTactic)
 	go func() {
 
 		// make sure we close output chan
 		defer close(output)
 
-		index := 0
-		for tx := range input {
-			// TODO(bassosimone): what do we do now about the user configured
-			// initial delays? Should we declare them as deprecated?
+		// useful to make sure we don't emit two equal policy in a single run
+		uniq := make(map[string]int)
 
-			// rewrite the delays
-			tx.InitialDelay = happyEyeballsDelay(index)
-			index++
+		for tx := range input {
+			// handle the case in which we already emitted a tactic
+			key := tx.tacticSummaryKey()
+			if uniq[key] > 0 {
+				continue
+			}
+			uniq[key]++
 
 			// emit the tactic
 			output <- tx
@@ -4,7 +4,6 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
-	"github.com/ooni/probe-engine/pkg/testingx"
 )
 
 func TestFilterOutNilTactics(t *testing.T) {
@@ -13,7 +12,6 @@ func TestFilterOutNilTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "x.org",
 			VerifyHostname: "api.ooni.io",
@@ -21,7 +19,6 @@ func TestFilterOutNilTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "www.polito.it",
 			VerifyHostname: "api.ooni.io",
@@ -44,28 +41,70 @@ func TestFilterOutNilTactics(t *testing.T) {
 	}
 }
 
+func TestFilterOnlyKeepTransport(t *testing.T) {
+	inputs := []*httpsDialerTactic{{
+		Address:        "130.192.91.211",
+		Port:           "443",
+		SNI:            "x.org",
+		VerifyHostname: "api.ooni.io",
+	}, {
+		Address:        "130.192.91.211",
+		Port:           "443",
+		SNI:            "x.org",
+		VerifyHostname: "api.ooni.io",
+		Transport:      tacticTransportQUIC,
+	}, {
+		Address:        "130.192.91.211",
+		Port:           "443",
+		SNI:            "www.polito.it",
+		VerifyHostname: "api.ooni.io",
+	}}
+
+	t.Run("for TCP", func(t *testing.T) {
+		expect := []*httpsDialerTactic{inputs[0], inputs[2]}
+
+		var output []*httpsDialerTactic
+		for tx := range filterOnlyKeepTransport(streamTacticsFromSlice(inputs), tacticTransportTCP) {
+			output = append(output, tx)
+		}
+
+		if diff := cmp.Diff(expect, output); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("for QUIC", func(t *testing.T) {
+		expect := []*httpsDialerTactic{inputs[1]}
+
+		var output []*httpsDialerTactic
+		for tx := range filterOnlyKeepTransport(streamTacticsFromSlice(inputs), tacticTransportQUIC) {
+			output = append(output, tx)
+		}
+
+		if diff := cmp.Diff(expect, output); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
 func TestFilterOnlyKeepUniqueTactics(t *testing.T) {
 	templates := []*httpsDialerTactic{{
 		Address:        "130.192.91.211",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "www.example.com",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.211",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "www.kernel.org",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.211",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "x.org",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.211",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "www.polito.it",
 		VerifyHostname: "api.ooni.io",
@@ -93,19 +132,3 @@ func TestFilterOnlyKeepUniqueTactics(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
-
-func TestFilterAssignInitalDelays(t *testing.T) {
-	inputs := []*httpsDialerTactic{}
-	ff := &testingx.FakeFiller{}
-	ff.Fill(&inputs)
-	idx := 0
-	for tx := range filterAssignInitialDelays(streamTacticsFromSlice(inputs)) {
-		if tx.InitialDelay != happyEyeballsDelay(idx) {
-			t.Fatal("unexpected .InitialDelay", tx.InitialDelay, "for", idx)
-		}
-		idx++
-	}
-	if idx < 1 {
-		t.Fatal("expected to see at least one entry")
-	}
-}
@@ -0,0 +1,152 @@
+package enginenetx
+
+//
+// TLS ClientHello fragmentation - splitting the ClientHello across several
+// TCP segments and, optionally, several TLS records to defeat DPI middleboxes
+// that do not reassemble the stream before inspecting the SNI
+//
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+)
+
+// fragmentKind is the kind of ClientHello fragmentation to use.
+type fragmentKind string
+
+const (
+	// fragmentNone means that we do not fragment the ClientHello.
+	fragmentNone = fragmentKind("")
+
+	// fragmentTCPSegments means that we split the TLS record containing the
+	// ClientHello across two TCP segments, cutting the SNI in half.
+	fragmentTCPSegments = fragmentKind("tcp-segments")
+
+	// fragmentTLSRecords means that we split the ClientHello across two TLS
+	// records, cutting the SNI in half, and send each record in its own TCP segment.
+	fragmentTLSRecords = fragmentKind("tls-records")
+)
+
+// fragmentAllKinds contains all the fragmentation kinds we know about except
+// for [fragmentNone], in the order in which we would like to try them.
+var fragmentAllKinds = []fragmentKind{
+	fragmentTCPSegments,
+	fragmentTLSRecords,
+}
+
+// errFragmentUnknownKind indicates that we do not know about a fragmentation kind.
+var errFragmentUnknownKind = errors.New("unknown ClientHello fragmentation kind")
+
+// fragmentValidateKind returns an error if we do not know about the given kind.
+func fragmentValidateKind(kind fragmentKind) error {
+	if kind == fragmentNone || slices.Contains(fragmentAllKinds, kind) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", errFragmentUnknownKind, string(kind))
+}
+
+// fragmentConn is a [net.Conn] that fragments the first TLS record
+// it writes, which should be the one containing the ClientHello.
+//
+// The zero value is invalid; please, init all MANDATORY fields.
+type fragmentConn struct {
+	// Conn is the MANDATORY underlying connection.
+	net.Conn
+
+	// Kind is the MANDATORY fragmentation kind.
+	Kind fragmentKind
+
+	// SNI is the MANDATORY SNI we should split.
+	SNI string
+
+	// once ensures we only fragment the first write.
+	once sync.Once
+}
+
+var _ net.Conn = &fragmentConn{}
+
+// Write implements net.Conn.
+func (c *fragmentConn) Write(data []byte) (int, error) {
+	var (
+		count int
+		err   error
+		done  bool
+	)
+	c.once.Do(func() {
+		count, err = c.writeFragmented(data)
+		done = true
+	})
+	if done {
+		return count, err
+	}
+	return c.Conn.Write(data)
+}
+
+// fragmentRecordHeaderSize is the size of a TLS record header.
+const fragmentRecordHeaderSize = 5
+
+// fragmentRecordTypeHandshake is the TLS handshake record type.
+const fragmentRecordTypeHandshake = 22
+
+func (c *fragmentConn) writeFragmented(data []byte) (int, error) {
+	// make sure what we're writing looks like a single TLS handshake record
+	// and otherwise just write the data without fragmenting it
+	if len(data) <= fragmentRecordHeaderSize+1 || data[0] != fragmentRecordTypeHandshake {
+		return c.Conn.Write(data)
+	}
+	length := int(binary.BigEndian.Uint16(data[3:fragmentRecordHeaderSize]))
+	if length != len(data)-fragmentRecordHeaderSize {
+		return c.Conn.Write(data)
+	}
+
+	// compute the fragments to write
+	var fragments [][]byte
+	offset := fragmentSplitOffset(data, c.SNI)
+	switch c.Kind {
+	case fragmentTLSRecords:
+		fragments = fragmentSplitRecord(data, offset-fragmentRecordHeaderSize)
+	default:
+		fragments = [][]byte{data[:offset], data[offset:]}
+	}
+
+	// write each fragment using a distinct write, which, given that Go disables
+	// Nagle's algorithm by default, should produce distinct TCP segments
+	for _, fragment := range fragments {
+		if _, err := c.Conn.Write(fragment); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// fragmentSplitOffset returns the offset at which we should split the record, which
+// is the middle of the SNI, if present, or the middle of the record payload.
+func fragmentSplitOffset(record []byte, sni string) int {
+	payloadSize := len(record) - fragmentRecordHeaderSize
+	offset := fragmentRecordHeaderSize + payloadSize/2
+	if sni != "" {
+		if idx := bytes.Index(record[fragmentRecordHeaderSize:], []byte(sni)); idx >= 0 {
+			offset = fragmentRecordHeaderSize + idx + len(sni)/2
+		}
+	}
+	// make sure each fragment contains at least one byte of payload
+	return min(max(offset, fragmentRecordHeaderSize+1), len(record)-1)
+}
+
+// fragmentSplitRecord splits the record payload at the given payload offset and
+// returns two TLS records with the same type and version as the original.
+func fragmentSplitRecord(record []byte, offset int) [][]byte {
+	header, payload := record[:fragmentRecordHeaderSize], record[fragmentRecordHeaderSize:]
+	newRecord := func(chunk []byte) []byte {
+		out := make([]byte, fragmentRecordHeaderSize, fragmentRecordHeaderSize+len(chunk))
+		copy(out, header)
+		binary.BigEndian.PutUint16(out[3:fragmentRecordHeaderSize], uint16(len(chunk)))
+		return append(out, chunk...)
+	}
+	return [][]byte{newRecord(payload[:offset]), newRecord(payload[offset:])}
+}
@@ -0,0 +1,189 @@
+package enginenetx
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-engine/pkg/mocks"
+)
+
+// fragmentRecordingConn returns a [*mocks.Conn] recording each write.
+func fragmentRecordingConn(writes *[][]byte) *mocks.Conn {
+	return &mocks.Conn{
+		MockWrite: func(b []byte) (int, error) {
+			*writes = append(*writes, append([]byte{}, b...))
+			return len(b), nil
+		},
+	}
+}
+
+func TestFragmentConn(t *testing.T) {
+	// record is a fake TLS handshake record containing the SNI
+	record := append([]byte{22, 3, 1, 0, 19}, []byte("\x01\x02\x03www.example.com\x04")...)
+
+	t.Run("with tcp-segments", func(t *testing.T) {
+		var writes [][]byte
+		conn := &fragmentConn{
+			Conn: fragmentRecordingConn(&writes),
+			Kind: fragmentTCPSegments,
+			SNI:  "www.example.com",
+		}
+
+		count, err := conn.Write(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(record) {
+			t.Fatal("unexpected count", count)
+		}
+
+		expect := [][]byte{
+			append([]byte{22, 3, 1, 0, 19}, []byte("\x01\x02\x03www.exa")...),
+			[]byte("mple.com\x04"),
+		}
+		if diff := cmp.Diff(expect, writes); diff != "" {
+			t.Fatal(diff)
+		}
+
+		// make sure subsequent writes are not fragmented
+		if _, err := conn.Write(record); err != nil {
+			t.Fatal(err)
+		}
+		if len(writes) != 3 || !bytes.Equal(writes[2], record) {
+			t.Fatal("expected the second write to be unmodified")
+		}
+	})
+
+	t.Run("with tls-records", func(t *testing.T) {
+		var writes [][]byte
+		conn := &fragmentConn{
+			Conn: fragmentRecordingConn(&writes),
+			Kind: fragmentTLSRecords,
+			SNI:  "www.example.com",
+		}
+
+		count, err := conn.Write(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != len(record) {
+			t.Fatal("unexpected count", count)
+		}
+
+		expect := [][]byte{
+			append([]byte{22, 3, 1, 0, 10}, []byte("\x01\x02\x03www.exa")...),
+			append([]byte{22, 3, 1, 0, 9}, []byte("mple.com\x04")...),
+		}
+		if diff := cmp.Diff(expect, writes); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when the SNI is not in the record", func(t *testing.T) {
+		var writes [][]byte
+		conn := &fragmentConn{
+			Conn: fragmentRecordingConn(&writes),
+			Kind: fragmentTCPSegments,
+			SNI:  "www.example.org",
+		}
+
+		if _, err := conn.Write(record); err != nil {
+			t.Fatal(err)
+		}
+
+		// we expect to split in the middle of the payload
+		expect := [][]byte{record[:14], record[14:]}
+		if diff := cmp.Diff(expect, writes); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when the data is not a TLS handshake record", func(t *testing.T) {
+		for _, data := range [][]byte{
+			[]byte("GET / HTTP/1.1\r\n\r\n"),    // wrong record type
+			{22, 3, 1, 0},                       // too short
+			{22, 3, 1, 0, 17, 1, 2, 3, 4, 5, 6}, // wrong length
+		} {
+			var writes [][]byte
+			conn := &fragmentConn{
+				Conn: fragmentRecordingConn(&writes),
+				Kind: fragmentTLSRecords,
+				SNI:  "www.example.com",
+			}
+			if _, err := conn.Write(data); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([][]byte{data}, writes); diff != "" {
+				t.Fatal(diff)
+			}
+		}
+	})
+
+	t.Run("when writing a fragment fails", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		conn := &fragmentConn{
+			Conn: &mocks.Conn{
+				MockWrite: func(b []byte) (int, error) {
+					return 0, expected
+				},
+			},
+			Kind: fragmentTCPSegments,
+			SNI:  "www.example.com",
+		}
+		count, err := conn.Write(record)
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if count != 0 {
+			t.Fatal("unexpected count", count)
+		}
+	})
+
+	// make sure that a real TLS server is okay with fragmented ClientHellos
+	for _, kind := range fragmentAllKinds {
+		t.Run("TLS handshake with "+string(kind), func(t *testing.T) {
+			ca := netem.MustNewCA()
+			serverConn, clientConn := net.Pipe()
+			defer serverConn.Close()
+			defer clientConn.Close()
+
+			serverErr := make(chan error, 1)
+			go func() {
+				server := tls.Server(serverConn, ca.MustNewServerTLSConfig("www.example.com"))
+				serverErr <- server.HandshakeContext(context.Background())
+			}()
+
+			client := tls.Client(&fragmentConn{
+				Conn: clientConn,
+				Kind: kind,
+				SNI:  "www.example.com",
+			}, &tls.Config{
+				RootCAs:    ca.DefaultCertPool(),
+				ServerName: "www.example.com",
+			})
+			if err := client.HandshakeContext(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if err := <-serverErr; err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestFragmentValidateKind(t *testing.T) {
+	for _, kind := range append([]fragmentKind{fragmentNone}, fragmentAllKinds...) {
+		if err := fragmentValidateKind(kind); err != nil {
+			t.Fatal("unexpected error for", kind, err)
+		}
+	}
+	if err := fragmentValidateKind("antani"); !errors.Is(err, errFragmentUnknownKind) {
+		t.Fatal("unexpected error", err)
+	}
+}
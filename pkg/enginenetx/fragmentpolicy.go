@@ -0,0 +1,58 @@
+package enginenetx
+
+//
+// fragment policy - a policy that extends the tactics sending the
+// real SNI on the wire with tactics fragmenting the ClientHello
+//
+
+import "context"
+
+// fragmentPolicy is a policy where we extend tactics sending the real SNI
+// with tactics fragmenting the ClientHello, which defeats DPI middleboxes
+// that do not reassemble the TCP stream before inspecting the SNI.
+//
+// The zero value is invalid; please, init MANDATORY fields.
+type fragmentPolicy struct {
+	// Child is the MANDATORY child policy.
+	Child httpsDialerPolicy
+}
+
+var _ httpsDialerPolicy = &fragmentPolicy{}
+
+// LookupTactics implements httpsDialerPolicy.
+func (p *fragmentPolicy) LookupTactics(ctx context.Context, domain, port string) <-chan *httpsDialerTactic {
+	out := make(chan *httpsDialerTactic)
+
+	go func() {
+		// tell the parent when we're done
+		defer close(out)
+
+		// collect tactics that we may want to modify later
+		var todo []*httpsDialerTactic
+
+		// always emit the original tactic first, such that we only use
+		// fragmentation when the network is actually interfering
+		for tactic := range p.Child.LookupTactics(ctx, domain, port) {
+			// Fragmenting only makes sense when we're sending the real SNI
+			// and the tactic is not already using fragmentation, otherwise
+			// let's remember to modify this later. Note that we must clone
+			// before emitting, since the consumer may modify the tactic.
+			if tactic.SNI == tactic.VerifyHostname && tactic.Fragment == fragmentNone {
+				todo = append(todo, tactic.Clone())
+			}
+
+			out <- tactic
+		}
+
+		// Produce tactics using each fragmentation kind.
+		for _, kind := range fragmentAllKinds {
+			for _, tactic := range todo {
+				tactic = tactic.Clone()
+				tactic.Fragment = kind
+				out <- tactic
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,113 @@
+package enginenetx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFragmentPolicy(t *testing.T) {
+	// dnsTactics contains tactics sending the real SNI
+	dnsTactics := []*httpsDialerTactic{{
+		Address:        "162.55.247.208",
+		Port:           "443",
+		SNI:            "api.ooni.io",
+		VerifyHostname: "api.ooni.io",
+	}, {
+		Address:        "2a01:4f8:1c0c:5b86::1",
+		Port:           "443",
+		SNI:            "api.ooni.io",
+		VerifyHostname: "api.ooni.io",
+	}}
+
+	// otherTactics contains tactics that we should not fragment
+	otherTactics := []*httpsDialerTactic{{
+		Address:        "162.55.247.208",
+		Port:           "443",
+		SNI:            "www.example.com",
+		VerifyHostname: "api.ooni.io",
+	}, {
+		Address:        "162.55.247.208",
+		Port:           "443",
+		SNI:            "api.ooni.io",
+		VerifyHostname: "api.ooni.io",
+		Fragment:       fragmentTLSRecords,
+	}}
+
+	// testcase is a test case implemented by this function
+	type testcase struct {
+		// name is the test case name
+		name string
+
+		// childTactics contains the tactics that the child policy
+		// should return when invoked by the policy
+		childTactics []*httpsDialerTactic
+
+		// expectExtra contains the expected tactics we
+		// want to see beyond the child tactics above
+		expectExtra []*httpsDialerTactic
+	}
+
+	cases := []testcase{{
+		name:         "when the child does not return any tactic",
+		childTactics: nil,
+		expectExtra:  nil,
+	}, {
+		name:         "when the child returns tactics we should not fragment",
+		childTactics: otherTactics,
+		expectExtra:  nil,
+	}, {
+		name:         "when the child returns tactics using the real SNI",
+		childTactics: append(append([]*httpsDialerTactic{}, dnsTactics...), otherTactics...),
+		expectExtra: []*httpsDialerTactic{{
+			Address:        "162.55.247.208",
+			Port:           "443",
+			SNI:            "api.ooni.io",
+			VerifyHostname: "api.ooni.io",
+			Fragment:       fragmentTCPSegments,
+		}, {
+			Address:        "2a01:4f8:1c0c:5b86::1",
+			Port:           "443",
+			SNI:            "api.ooni.io",
+			VerifyHostname: "api.ooni.io",
+			Fragment:       fragmentTCPSegments,
+		}, {
+			Address:        "162.55.247.208",
+			Port:           "443",
+			SNI:            "api.ooni.io",
+			VerifyHostname: "api.ooni.io",
+			Fragment:       fragmentTLSRecords,
+		}, {
+			Address:        "2a01:4f8:1c0c:5b86::1",
+			Port:           "443",
+			SNI:            "api.ooni.io",
+			VerifyHostname: "api.ooni.io",
+			Fragment:       fragmentTLSRecords,
+		}},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := &fragmentPolicy{
+				Child: &mocksPolicy{
+					MockLookupTactics: func(ctx context.Context, domain, port string) <-chan *httpsDialerTactic {
+						return streamTacticsFromSlice(tc.childTactics)
+					},
+				},
+			}
+
+			var tactics []*httpsDialerTactic
+			for entry := range policy.LookupTactics(context.Background(), "api.ooni.io", "443") {
+				tactics = append(tactics, entry)
+			}
+
+			var expect []*httpsDialerTactic
+			expect = append(expect, tc.childTactics...)
+			expect = append(expect, tc.expectExtra...)
+			if diff := cmp.Diff(expect, tactics); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
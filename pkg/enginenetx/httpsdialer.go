@@ -8,7 +8,6 @@ import (
 	"errors"
 	"fmt"
 	"net"
-	"sync"
 	"sync/atomic"
 	"time"
 
@@ -23,8 +22,11 @@ type httpsDialerTactic struct {
 	// Address is the IPv4/IPv6 address for dialing.
 	Address string
 
-	// InitialDelay is the time in nanoseconds after which
-	// you would like to start this policy.
+	// InitialDelay is DEPRECATED and IGNORED. Previous versions of this
+	// package used it to schedule tactics, but we now always use the
+	// [*dialScheduler] instead. We keep this field around so that the
+	// stats and user policies we persist remain compatible with the ones
+	// written and read by previous versions of this package.
 	InitialDelay time.Duration
 
 	// Port is the TCP port for dialing.
@@ -36,8 +38,27 @@ type httpsDialerTactic struct {
 	// VerifyHostname is the hostname using during
 	// the X.509 certificate verification.
 	VerifyHostname string
+
+	// Fragment is the OPTIONAL kind of ClientHello fragmentation to use,
+	// where the empty string means we don't fragment the ClientHello.
+	Fragment fragmentKind `json:",omitempty"`
+
+	// Transport is the OPTIONAL transport to use, where the empty
+	// string means that we should use TLS over TCP.
+	Transport tacticTransport `json:",omitempty"`
 }
 
+// tacticTransport is the transport used by a tactic.
+type tacticTransport string
+
+const (
+	// tacticTransportTCP means that we use TLS over TCP.
+	tacticTransportTCP = tacticTransport("")
+
+	// tacticTransportQUIC means that we use QUIC.
+	tacticTransportQUIC = tacticTransport("quic")
+)
+
 var _ fmt.Stringer = &httpsDialerTactic{}
 
 // Clone makes a deep copy of this [httpsDialerTactic].
@@ -48,6 +69,8 @@ func (dt *httpsDialerTactic) Clone() *httpsDialerTactic {
 		Port:           dt.Port,
 		SNI:            dt.SNI,
 		VerifyHostname: dt.VerifyHostname,
+		Fragment:       dt.Fragment,
+		Transport:      dt.Transport,
 	}
 }
 
@@ -68,18 +91,35 @@ func (dt *httpsDialerTactic) String() string {
 //
 // - VerifyHostname
 //
+// - Fragment
+//
+// - Transport
+//
 // The returned string contains the above fields separated by space with
-// `sni=` before the SNI and `verify=` before the verify hostname.
+// `sni=` before the SNI and `verify=` before the verify hostname. When the
+// tactic fragments the ClientHello, we also append `fragment=` followed by
+// the fragmentation kind, such that we keep separate stats for each variant.
+// Likewise, when the tactic uses QUIC, we append `transport=quic`.
 //
 // We should be careful not to change this format unless we also change the
-// format version used by user policies and by the state management.
+// format version used by user policies and by the state management. (Because
+// we only append `fragment=` and `transport=` when needed, the keys of TLS
+// over TCP tactics that do not use fragmentation are the same we were
+// using before introducing these features.)
 func (dt *httpsDialerTactic) tacticSummaryKey() string {
-	return fmt.Sprintf(
+	key := fmt.Sprintf(
 		"%v sni=%v verify=%v",
 		net.JoinHostPort(dt.Address, dt.Port),
 		dt.SNI,
 		dt.VerifyHostname,
 	)
+	if dt.Fragment != fragmentNone {
+		key += fmt.Sprintf(" fragment=%v", dt.Fragment)
+	}
+	if dt.Transport != tacticTransportTCP {
+		key += fmt.Sprintf(" transport=%v", dt.Transport)
+	}
+	return key
 }
 
 // domainEndpointKey returns a string consisting of the domain endpoint only.
@@ -110,6 +150,7 @@ type httpsDialerEventsHandler interface {
 	OnStarting(tactic *httpsDialerTactic)
 	OnTCPConnectError(ctx context.Context, tactic *httpsDialerTactic, err error)
 	OnTLSHandshakeError(ctx context.Context, tactic *httpsDialerTactic, err error)
+	OnQUICHandshakeError(ctx context.Context, tactic *httpsDialerTactic, err error)
 	OnTLSVerifyError(tactic *httpsDialerTactic, err error)
 	OnSuccess(tactic *httpsDialerTactic)
 }
@@ -136,6 +177,9 @@ type httpsDialer struct {
 	// rootCAs contains the root certificate pool we should use.
 	rootCAs *x509.CertPool
 
+	// scheduler decides when to start each dialing attempt.
+	scheduler *dialScheduler
+
 	// stats tracks what happens while dialing.
 	stats httpsDialerEventsHandler
 }
@@ -166,10 +210,11 @@ func newHTTPSDialer(
 			Prefix: "httpsDialer: ",
 			Logger: logger,
 		},
-		netx:    netx,
-		policy:  policy,
-		rootCAs: netx.MaybeCustomUnderlyingNetwork().Get().DefaultCertPool(),
-		stats:   stats,
+		netx:      netx,
+		policy:    policy,
+		rootCAs:   netx.MaybeCustomUnderlyingNetwork().Get().DefaultCertPool(),
+		scheduler: newDialScheduler(),
+		stats:     stats,
 	}
 }
 
@@ -180,15 +225,6 @@ func (hd *httpsDialer) CloseIdleConnections() {
 	// nothing
 }
 
-// httpsDialerErrorOrConn contains either an error or a valid conn.
-type httpsDialerErrorOrConn struct {
-	// Conn is the established TLS conn or nil.
-	Conn model.TLSConn
-
-	// Err is the error or nil.
-	Err error
-}
-
 // errDNSNoAnswer is the error returned when we have no tactic to try
 var errDNSNoAnswer = netxlite.NewErrWrapper(
 	netxlite.ClassifyResolverError,
@@ -203,101 +239,30 @@ func (hd *httpsDialer) DialTLSContext(ctx context.Context, network string, endpo
 		return nil, err
 	}
 
-	// TODO(bassosimone): this code should be refactored using the same
-	// pattern used by `./internal/httpclientx` to perform attempts faster
-	// in case there is an initial early failure.
-
-	// We need a cancellable context to interrupt the tactics emitter early when we
-	// immediately get a valid response and we don't need to use other tactics.
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	// Create structure for computing the zero dialing time once during
-	// the first dial, so that subsequent attempts use happy eyeballs based
-	// on the moment in which we tried the first dial.
-	t0 := &httpsDialerWorkerZeroTime{}
-
-	// The emitter will emit tactics and then close the channel when done. We spawn 16 workers
-	// that handle tactics in parallel and post results on the collector channel.
-	emitter := httpsDialerFilterTactics(hd.policy.LookupTactics(ctx, hostname, port))
-	collector := make(chan *httpsDialerErrorOrConn)
-	joiner := make(chan any)
-	const parallelism = 16
-	for idx := 0; idx < parallelism; idx++ {
-		go hd.worker(ctx, joiner, emitter, t0, collector)
-	}
-
-	// wait until all goroutines have joined
-	var (
-		connv     = []model.TLSConn{}
-		errorv    = []error{}
-		numJoined = 0
-	)
-	for numJoined < parallelism {
-		select {
-		case <-joiner:
-			numJoined++
-
-		case result := <-collector:
-			// If the goroutine failed, record the error and continue processing results
-			if result.Err != nil {
-				errorv = append(errorv, result.Err)
-				continue
-			}
-
-			// Save the conn
-			connv = append(connv, result.Conn)
-
-			// Interrupt other concurrent dialing attempts
-			cancel()
-		}
+	// The emitter will emit tactics and then close the channel when done. The scheduler
+	// will start a dialing attempt for each tactic, starting the next attempt either when
+	// the previous one fails or after a happy-eyeballs-like delay, and will interrupt
+	// the other attempts as soon as one of them succeeds.
+	emitter := func(ctx context.Context) <-chan *httpsDialerTactic {
+		return httpsDialerFilterTactics(hd.policy.LookupTactics(ctx, hostname, port), tacticTransportTCP)
 	}
-
+	connv, errorv := dialSchedulerRun(ctx, hd.scheduler, emitter, hd.dialTactic)
 	return httpsDialerReduceResult(connv, errorv)
 }
 
-// httpsDialerWorkerZeroTime contains the zero time used when dialing. We set this
-// zero time when we start the first dialing attempt, such that subsequent attempts
-// are correctly spaced starting from such a zero time.
-//
-// A previous approach was that we were taking the zero time when we started
-// getting tactics, but this approach was wrong, because it caused several tactics
-// to be ready, when the DNS lookup was slow.
-//
-// The zero value of this structure is ready to use.
-type httpsDialerWorkerZeroTime struct {
-	// mu provides mutual exclusion.
-	mu sync.Mutex
-
-	// t is the zero time.
-	t time.Time
-}
-
-// Get returns the zero dialing time. The first invocation of this method
-// saves the zero dialing time and subsquent invocations just return it.
-//
-// This method is safe to be called concurrently by goroutines.
-func (t0 *httpsDialerWorkerZeroTime) Get() time.Time {
-	defer t0.mu.Unlock()
-	t0.mu.Lock()
-	if t0.t.IsZero() {
-		t0.t = time.Now()
-	}
-	return t0.t
-}
-
 // httpsDialerFilterTactics filters the tactics to:
 //
 // 1. be paranoid and filter out nil tactics if any;
 //
-// 2. avoid emitting duplicate tactics as part of the same run;
+// 2. only keep the tactics using the given transport;
 //
-// 3. rewrite the happy eyeball delays.
+// 3. avoid emitting duplicate tactics as part of the same run.
 //
 // This function returns a channel where we emit the edited
 // tactics, and which we clone when we're done.
-func httpsDialerFilterTactics(input <-chan *httpsDialerTactic) <-chan *httpsDialerTactic {
-	return filterAssignInitialDelays(filterOnlyKeepUniqueTactics(filterOutNilTactics(input)))
+func httpsDialerFilterTactics(input <-chan *httpsDialerTactic, transport tacticTransport) <-chan *httpsDialerTactic {
+	return filterOnlyKeepUniqueTactics(
+		filterOnlyKeepTransport(filterOutNilTactics(input), transport))
 }
 
 // httpsDialerReduceResult returns either an established conn or an error, using [errDNSNoAnswer] in
@@ -318,46 +283,21 @@ func httpsDialerReduceResult(connv []model.TLSConn, errorv []error) (model.TLSCo
 	}
 }
 
-// worker attempts to establish a TLS connection and emits the result using
-// a [*httpsDialerErrorOrConn] for each tactic, until there are no more tactics
-// and the reader channel is closed. At which point it posts on joiner to let
-// the parent know that this goroutine has done its job.
-func (hd *httpsDialer) worker(
-	ctx context.Context,
-	joiner chan<- any,
-	reader <-chan *httpsDialerTactic,
-	t0 *httpsDialerWorkerZeroTime,
-	writer chan<- *httpsDialerErrorOrConn,
-) {
-	// let the parent know that we terminated
-	defer func() { joiner <- true }()
-
-	for tactic := range reader {
-		prefixLogger := &logx.PrefixLogger{
-			Prefix: fmt.Sprintf("[#%d] ", hd.idGenerator.Add(1)),
-			Logger: hd.logger,
-		}
-
-		// perform the actual dial
-		conn, err := hd.dialTLS(ctx, prefixLogger, t0, tactic)
-
-		// send results to the parent
-		writer <- &httpsDialerErrorOrConn{Conn: conn, Err: err}
+// dialTactic uses a prefix logger to distinguish each attempt and calls dialTLS.
+func (hd *httpsDialer) dialTactic(ctx context.Context, tactic *httpsDialerTactic) (model.TLSConn, error) {
+	prefixLogger := &logx.PrefixLogger{
+		Prefix: fmt.Sprintf("[#%d] ", hd.idGenerator.Add(1)),
+		Logger: hd.logger,
 	}
+	return hd.dialTLS(ctx, prefixLogger, tactic)
 }
 
 // dialTLS performs the actual TLS dial.
 func (hd *httpsDialer) dialTLS(
 	ctx context.Context,
 	logger model.Logger,
-	t0 *httpsDialerWorkerZeroTime,
 	tactic *httpsDialerTactic,
 ) (model.TLSConn, error) {
-	// honor happy-eyeballs delays and wait for the tactic to be ready to run
-	if err := httpsDialerTacticWaitReady(ctx, t0, tactic); err != nil {
-		return nil, err
-	}
-
 	// for debugging let the user know which tactic is ready
 	logger.Infof("tactic '%+v' is ready", tactic)
 
@@ -385,16 +325,23 @@ func (hd *httpsDialer) dialTLS(
 		ServerName:         tactic.SNI,
 	}
 
+	// possibly fragment the ClientHello we're about to send
+	var handshakeConn net.Conn = tcpConn
+	if tactic.Fragment != fragmentNone {
+		handshakeConn = &fragmentConn{Conn: tcpConn, Kind: tactic.Fragment, SNI: tactic.SNI}
+	}
+
 	// create handshaker and establish a TLS connection
 	ol = logx.NewOperationLogger(
 		logger,
-		"TLSHandshake with %s SNI=%s ALPN=%v",
+		"TLSHandshake with %s SNI=%s ALPN=%v%s",
 		endpoint,
 		tlsConfig.ServerName,
 		tlsConfig.NextProtos,
+		httpsDialerFragmentLogSuffix(tactic.Fragment),
 	)
 	thx := hd.netx.NewTLSHandshakerStdlib(logger)
-	tlsConn, err := thx.Handshake(ctx, tcpConn, tlsConfig)
+	tlsConn, err := thx.Handshake(ctx, handshakeConn, tlsConfig)
 	ol.Stop(err)
 
 	// handle handshake error
@@ -422,30 +369,13 @@ func (hd *httpsDialer) dialTLS(
 	return tlsConn, nil
 }
 
-// httpsDialerWaitReady waits for the given delay to expire or the context to be canceled. If the
-// delay is zero or negative, we immediately return nil. We also return nil when the delay expires. We
-// return the context error if the context expires.
-func httpsDialerTacticWaitReady(
-	ctx context.Context,
-	t0 *httpsDialerWorkerZeroTime,
-	tactic *httpsDialerTactic,
-) error {
-	deadline := t0.Get().Add(tactic.InitialDelay)
-	delta := time.Until(deadline)
-	if delta <= 0 {
-		return nil
-	}
-
-	timer := time.NewTimer(delta)
-	defer timer.Stop()
-
-	select {
-	case <-timer.C:
-		return nil
-
-	case <-ctx.Done():
-		return netxlite.NewTopLevelGenericErrWrapper(ctx.Err())
+// httpsDialerFragmentLogSuffix returns the suffix to add to the TLS handshake
+// log message to tell the user which kind of fragmentation we're using.
+func httpsDialerFragmentLogSuffix(kind fragmentKind) string {
+	if kind == fragmentNone {
+		return ""
 	}
+	return fmt.Sprintf(" Fragment=%s", kind)
 }
 
 // errNoPeerCertificate is an internal error returned when we don't have any peer certificate.
@@ -472,13 +402,18 @@ func httpsDialerVerifyCertificateChain(hostname string, conn model.TLSConn, root
 	//
 	// See https://github.com/golang/go/blob/go1.21.0/src/crypto/tls/handshake_client.go#L962.
 
+	return httpsDialerVerifyConnectionState(hostname, conn.ConnectionState(), rootCAs)
+}
+
+// httpsDialerVerifyConnectionState is like [httpsDialerVerifyCertificateChain] but takes
+// in input the [tls.ConnectionState], which allows us to also use it for QUIC.
+func httpsDialerVerifyConnectionState(hostname string, state tls.ConnectionState, rootCAs *x509.CertPool) error {
 	// Protect against a programming or configuration error where the
 	// programmer or user has not set the hostname.
 	if hostname == "" {
 		return errEmptyVerifyHostname
 	}
 
-	state := conn.ConnectionState()
 	opts := x509.VerifyOptions{
 		DNSName:       hostname, // note: here we're using the real hostname
 		Intermediates: x509.NewCertPool(),
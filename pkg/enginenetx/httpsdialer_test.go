@@ -7,7 +7,6 @@ import (
 	"errors"
 	"net/url"
 	"testing"
-	"time"
 
 	"github.com/apex/log"
 	"github.com/google/go-cmp/cmp"
@@ -55,6 +54,11 @@ func (*httpsDialerCancelingContextStatsTracker) OnTLSHandshakeError(ctx context.
 	// nothing
 }
 
+// OnQUICHandshakeError implements httpsDialerEventsHandler.
+func (*httpsDialerCancelingContextStatsTracker) OnQUICHandshakeError(ctx context.Context, tactic *httpsDialerTactic, err error) {
+	// nothing
+}
+
 // OnTLSVerifyError implements httpsDialerEventsHandler.
 func (*httpsDialerCancelingContextStatsTracker) OnTLSVerifyError(tactic *httpsDialerTactic, err error) {
 	// nothing
@@ -438,10 +442,9 @@ func TestHTTPSDialerNetemQA(t *testing.T) {
 
 func TestHTTPSDialerTactic(t *testing.T) {
 	t.Run("String", func(t *testing.T) {
-		expected := `{"Address":"162.55.247.208","InitialDelay":150000000,"Port":"443","SNI":"www.example.com","VerifyHostname":"api.ooni.io"}`
+		expected := `{"Address":"162.55.247.208","InitialDelay":0,"Port":"443","SNI":"www.example.com","VerifyHostname":"api.ooni.io"}`
 		ldt := &httpsDialerTactic{
 			Address:        "162.55.247.208",
-			InitialDelay:   150 * time.Millisecond,
 			Port:           "443",
 			SNI:            "www.example.com",
 			VerifyHostname: "api.ooni.io",
@@ -466,7 +469,6 @@ func TestHTTPSDialerTactic(t *testing.T) {
 		expected := `162.55.247.208:443 sni=www.example.com verify=api.ooni.io`
 		ldt := &httpsDialerTactic{
 			Address:        "162.55.247.208",
-			InitialDelay:   150 * time.Millisecond,
 			Port:           "443",
 			SNI:            "www.example.com",
 			VerifyHostname: "api.ooni.io",
@@ -476,6 +478,36 @@ func TestHTTPSDialerTactic(t *testing.T) {
 			t.Fatal(diff)
 		}
 	})
+
+	t.Run("Summary with QUIC", func(t *testing.T) {
+		expected := `162.55.247.208:443 sni=api.ooni.io verify=api.ooni.io transport=quic`
+		ldt := &httpsDialerTactic{
+			Address:        "162.55.247.208",
+			Port:           "443",
+			SNI:            "api.ooni.io",
+			VerifyHostname: "api.ooni.io",
+			Transport:      tacticTransportQUIC,
+		}
+		got := ldt.tacticSummaryKey()
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("Summary with fragmentation", func(t *testing.T) {
+		expected := `162.55.247.208:443 sni=api.ooni.io verify=api.ooni.io fragment=tls-records`
+		ldt := &httpsDialerTactic{
+			Address:        "162.55.247.208",
+			Port:           "443",
+			SNI:            "api.ooni.io",
+			VerifyHostname: "api.ooni.io",
+			Fragment:       fragmentTLSRecords,
+		}
+		got := ldt.tacticSummaryKey()
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
 }
 
 // QA using the host network
@@ -642,7 +674,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "x.org",
 			VerifyHostname: "api.ooni.io",
@@ -650,7 +681,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "www.polito.it",
 			VerifyHostname: "api.ooni.io",
@@ -659,7 +689,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "x.org",
 			VerifyHostname: "api.ooni.io",
@@ -667,7 +696,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "www.polito.it",
 			VerifyHostname: "api.ooni.io",
@@ -675,7 +703,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "x.com",
 			VerifyHostname: "api.ooni.io",
@@ -684,7 +711,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "kerneltrap.org",
 			VerifyHostname: "api.ooni.io",
@@ -694,7 +720,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "kerneltrap.org",
 			VerifyHostname: "api.ooni.io",
@@ -703,7 +728,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "freebsd.org",
 			VerifyHostname: "api.ooni.io",
@@ -712,7 +736,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "kerneltrap.org",
 			VerifyHostname: "api.ooni.io",
@@ -721,7 +744,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "dragonflybsd.org",
 			VerifyHostname: "api.ooni.io",
@@ -729,7 +751,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "kerneltrap.org",
 			VerifyHostname: "api.ooni.io",
@@ -738,7 +759,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "openbsd.org",
 			VerifyHostname: "api.ooni.io",
@@ -747,7 +767,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.231",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "openbsd.org",
 			VerifyHostname: "api.ooni.io",
@@ -756,7 +775,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.231",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "openbsd.org",
 			VerifyHostname: "api.ooni.io",
@@ -765,7 +783,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.231",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "netbsd.org",
 			VerifyHostname: "api.ooni.io",
@@ -774,7 +791,6 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 		nil,
 		{
 			Address:        "130.192.91.231",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "openbsd.org",
 			VerifyHostname: "api.ooni.io",
@@ -786,63 +802,54 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 	expect := []*httpsDialerTactic{
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "x.org",
 			VerifyHostname: "api.ooni.io",
 		},
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   time.Second,
 			Port:           "443",
 			SNI:            "www.polito.it",
 			VerifyHostname: "api.ooni.io",
 		},
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   2 * time.Second,
 			Port:           "443",
 			SNI:            "x.com",
 			VerifyHostname: "api.ooni.io",
 		},
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   4 * time.Second,
 			Port:           "443",
 			SNI:            "kerneltrap.org",
 			VerifyHostname: "api.ooni.io",
 		},
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   8 * time.Second,
 			Port:           "443",
 			SNI:            "freebsd.org",
 			VerifyHostname: "api.ooni.io",
 		},
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   16 * time.Second,
 			Port:           "443",
 			SNI:            "dragonflybsd.org",
 			VerifyHostname: "api.ooni.io",
 		},
 		{
 			Address:        "130.192.91.211",
-			InitialDelay:   24 * time.Second,
 			Port:           "443",
 			SNI:            "openbsd.org",
 			VerifyHostname: "api.ooni.io",
 		},
 		{
 			Address:        "130.192.91.231",
-			InitialDelay:   32 * time.Second,
 			Port:           "443",
 			SNI:            "openbsd.org",
 			VerifyHostname: "api.ooni.io",
 		},
 		{
 			Address:        "130.192.91.231",
-			InitialDelay:   40 * time.Second,
 			Port:           "443",
 			SNI:            "netbsd.org",
 			VerifyHostname: "api.ooni.io",
@@ -851,7 +858,7 @@ func TestHTTPSDialerFilterTactics(t *testing.T) {
 
 	// run the algorithm
 	var results []*httpsDialerTactic
-	for tx := range httpsDialerFilterTactics(streamTacticsFromSlice(inputs)) {
+	for tx := range httpsDialerFilterTactics(streamTacticsFromSlice(inputs), tacticTransportTCP) {
 		results = append(results, tx)
 	}
 
@@ -33,25 +33,21 @@ func TestMixPolicyEitherOr(t *testing.T) {
 	// policy to return when we're not using a null policy
 	expectedPrimaryTactics := []*httpsDialerTactic{{
 		Address:        "130.192.91.211",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "shelob.polito.it",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.211",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "whitespider.polito.it",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.211",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "mirkwood.polito.it",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.211",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "highgarden.polito.it",
 		VerifyHostname: "api.ooni.io",
@@ -71,19 +67,16 @@ func TestMixPolicyEitherOr(t *testing.T) {
 	// policy to return when we're not using a null policy
 	expectedFallbackTactics := []*httpsDialerTactic{{
 		Address:        "130.192.91.231",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "kingslanding.polito.it",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.231",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "pyke.polito.it",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.231",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "winterfell.polito.it",
 		VerifyHostname: "api.ooni.io",
@@ -200,25 +193,21 @@ func TestMixPolicyInterleave(t *testing.T) {
 	// policy to return when we're not using a null policy
 	expectedPrimaryTactics := []*httpsDialerTactic{{
 		Address:        "130.192.91.211",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "shelob.polito.it",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.211",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "whitespider.polito.it",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.211",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "mirkwood.polito.it",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.211",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "highgarden.polito.it",
 		VerifyHostname: "api.ooni.io",
@@ -238,19 +227,16 @@ func TestMixPolicyInterleave(t *testing.T) {
 	// policy to return when we're not using a null policy
 	expectedFallbackTactics := []*httpsDialerTactic{{
 		Address:        "130.192.91.231",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "kingslanding.polito.it",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.231",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "pyke.polito.it",
 		VerifyHostname: "api.ooni.io",
 	}, {
 		Address:        "130.192.91.231",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "winterfell.polito.it",
 		VerifyHostname: "api.ooni.io",
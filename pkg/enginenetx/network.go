@@ -44,6 +44,11 @@ func (n *Network) NewHTTPClient() *http.Client {
 	}
 }
 
+// Stats returns a summary of the dialing stats for each domain endpoint.
+func (n *Network) Stats() []*DomainEndpointStats {
+	return n.stats.Summary()
+}
+
 // Close ensures that we close idle connections and persist statistics.
 func (n *Network) Close() error {
 	// TODO(bassosimone): do we want to introduce "once" semantics in this method? It
@@ -59,6 +64,22 @@ func (n *Network) Close() error {
 	return n.stats.Close()
 }
 
+// NetworkOption is an OPTIONAL setting for [NewNetwork].
+type NetworkOption func(config *networkConfig)
+
+// networkConfig contains the settings modified by a [NetworkOption].
+type networkConfig struct {
+	raceQUIC bool
+}
+
+// NetworkOptionRaceQUIC configures whether to race TLS over TCP with QUIC when
+// we are not using a proxy. Racing is experimental, hence it is disabled by default.
+func NetworkOptionRaceQUIC(value bool) NetworkOption {
+	return func(config *networkConfig) {
+		config.raceQUIC = value
+	}
+}
+
 // NewNetwork creates a new [*Network] for the engine. This network MUST NOT be
 // used for measuring because it implements engine-specific policies.
 //
@@ -75,7 +96,13 @@ func (n *Network) Close() error {
 //
 // - proxyURL is the OPTIONAL proxy URL;
 //
-// - resolver is the [model.Resolver] to use.
+// - resolver is the [model.Resolver] to use;
+//
+// - options contains OPTIONAL settings (see [NetworkOption]).
+//
+// The engine passes the resolver implemented by the engineresolver package, such that the
+// dnsPolicy uses the DNS-over-HTTPS resolvers in the order configured by the user
+// inside the `resolvers.conf` file in the same kvStore containing `bridges.conf`.
 //
 // The presence of the proxyURL MAY cause this function to possibly build a
 // network with different behavior with respect to circumvention. If there is
@@ -86,7 +113,17 @@ func NewNetwork(
 	logger model.Logger,
 	proxyURL *url.URL,
 	resolver model.Resolver,
+	options ...NetworkOption,
 ) *Network {
+	config := &networkConfig{}
+	for _, option := range options {
+		option(config)
+	}
+
+	// We only race TCP and QUIC when the user opted in and there's no proxy,
+	// because we cannot tunnel UDP through the supported proxies.
+	raceQUIC := config.raceQUIC && proxyURL == nil
+
 	// Create a dialer ONLY used for dialing unencrypted TCP connections. The common use
 	// case of this Network is to dial encrypted connections. For this reason, here it is
 	// reasonably fine to use the legacy sequential dialer implemented in netxlite.
@@ -123,12 +160,45 @@ func NewNetwork(
 	//
 	// - this code does not work as intended when using netem and proxies
 	// as documented by TODO(https://github.com/ooni/probe/issues/2536).
+	//
+	// - when racing, we're going to race this transport with an HTTP/3
+	// transport (see below), hence we wrap the TLS dialer such
+	// that it can use the connection that won the race.
+	handoff := &raceHandoff{}
+	var tlsDialer model.TLSDialer = httpsDialer
+	if raceQUIC {
+		tlsDialer = &raceHandoffTLSDialer{Dialer: httpsDialer, Handoff: handoff}
+	}
 	txp := netxlite.NewHTTPTransportWithOptions(
-		logger, dialer, httpsDialer,
+		logger, dialer, tlsDialer,
 		netxlite.HTTPTransportOptionDisableCompression(false),
 		netxlite.HTTPTransportOptionProxyURL(proxyURL),
 	)
 
+	// When racing, create a QUIC dialer using QUIC tactics and race
+	// TLS over TCP with QUIC for each new endpoint.
+	if raceQUIC {
+		quicDialer := newQUICDialer(
+			logger,
+			&netxlite.Netx{Underlying: nil}, // nil means using netxlite's singleton
+			newQUICDialerPolicy(kvStore, logger, resolver, stats),
+			stats,
+		)
+		quicTxp := netxlite.NewHTTP3Transport(
+			logger,
+			&raceHandoffQUICDialer{Dialer: quicDialer, Handoff: handoff},
+			nil, // use the default TLS config
+		)
+		txp = &raceTransport{
+			Handoff:       handoff,
+			QUICDelay:     raceTransportQUICDelay,
+			QUICDialer:    quicDialer,
+			QUICTransport: quicTxp,
+			TCPTransport:  txp,
+			TLSDialer:     httpsDialer,
+		}
+	}
+
 	// Make sure we count the bytes sent and received as part of the session
 	txp = bytecounter.WrapHTTPTransport(txp, counter)
 
@@ -164,9 +234,16 @@ func newHTTPSDialerPolicy(
 	}
 
 	// wrap the DNS policy with a policy that extends tactics for test
-	// helpers so that we also try using different SNIs.
+	// helpers so that we also try using different SNIs, with a policy
+	// that rewrites the SNI using the SNIs that previously worked, and with
+	// a policy that extends tactics to also try fragmenting the ClientHello.
 	dnsExt := &testHelpersPolicy{
-		Child: &dnsPolicy{logger, resolver},
+		Child: &statsSNIPolicy{
+			Child: &fragmentPolicy{
+				Child: &dnsPolicy{logger, resolver},
+			},
+			Stats: stats,
+		},
 	}
 
 	// compose dnsExt and statsOrBridges such that dnsExt has
@@ -193,3 +270,51 @@ func newHTTPSDialerPolicy(
 
 	return policy
 }
+
+// newQUICDialerPolicy contains the logic to select the [HTTPSDialerPolicy] used
+// by the [*quicDialer], which only uses the tactics using [tacticTransportQUIC].
+func newQUICDialerPolicy(
+	kvStore model.KeyValueStore,
+	logger model.Logger,
+	resolver model.Resolver,
+	stats *statsManager,
+) httpsDialerPolicy {
+	// the stats policy returns all the tactics that previously worked
+	// and the dialer only keeps the ones using QUIC; note that we do not
+	// use bridges because we do not know any bridge supporting HTTP/3
+	statsPolicy := &statsPolicyV2{
+		Stats: stats,
+	}
+
+	// convert the DNS tactics, possibly extended for test helpers, to use
+	// QUIC; we don't fragment here because it's a TCP-only tactic
+	dnsExt := &quicPolicy{
+		Child: &testHelpersPolicy{
+			Child: &dnsPolicy{logger, resolver},
+		},
+	}
+
+	// compose dnsExt and statsPolicy such that dnsExt has
+	// priority in the selection of tactics
+	composed := &mixPolicyInterleave{
+		Primary:  dnsExt,
+		Fallback: statsPolicy,
+		Factor:   3,
+	}
+
+	// attempt to load a user-provided dialing policy
+	primary, err := newUserPolicyV2(kvStore)
+
+	// on error, just use composed
+	if err != nil {
+		return composed
+	}
+
+	// otherwise, finish creating the dialing policy
+	policy := &mixPolicyEitherOr{
+		Primary:  primary,
+		Fallback: composed,
+	}
+
+	return policy
+}
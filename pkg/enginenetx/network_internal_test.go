@@ -11,6 +11,7 @@ import (
 
 	"github.com/apex/log"
 	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/netem"
 	"github.com/ooni/probe-engine/pkg/bytecounter"
 	"github.com/ooni/probe-engine/pkg/kvstore"
 	"github.com/ooni/probe-engine/pkg/mocks"
@@ -159,7 +160,6 @@ func TestNetworkUnit(t *testing.T) {
 						DomainEndpoints: map[string][]*httpsDialerTactic{
 							"www.example.com:443": {{
 								Address:        netemx.AddressApiOONIIo,
-								InitialDelay:   0,
 								Port:           "443",
 								SNI:            "www.example.com",
 								VerifyHostname: "api.ooni.io",
@@ -212,6 +212,73 @@ func TestNetworkUnit(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("NewNetwork uses HTTP/3 when TCP is blocked", func(t *testing.T) {
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+
+		// make sure we cannot connect to www.example.com using TCP
+		env.DPIEngine().AddRule(&netem.DPICloseConnectionForServerEndpoint{
+			Logger:          log.Log,
+			ServerIPAddress: netemx.AddressWwwExampleCom,
+			ServerPort:      443,
+		})
+
+		env.Do(func() {
+			netx := NewNetwork(
+				bytecounter.New(),
+				&kvstore.Memory{},
+				log.Log,
+				nil, // proxy URL
+				(&netxlite.Netx{}).NewStdlibResolver(log.Log),
+				NetworkOptionRaceQUIC(true),
+			)
+			defer netx.Close()
+
+			client := netx.NewHTTPClient()
+			resp, err := client.Get("https://www.example.com/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != 200 {
+				t.Fatal("unexpected status code", resp.StatusCode)
+			}
+			if resp.ProtoMajor != 3 {
+				t.Fatal("expected to use HTTP/3, got", resp.Proto)
+			}
+		})
+	})
+
+	t.Run("NewNetwork does not race with QUIC by default", func(t *testing.T) {
+		env := netemx.MustNewScenario(netemx.InternetScenario)
+		defer env.Close()
+
+		// make sure we cannot connect to www.example.com using TCP
+		env.DPIEngine().AddRule(&netem.DPICloseConnectionForServerEndpoint{
+			Logger:          log.Log,
+			ServerIPAddress: netemx.AddressWwwExampleCom,
+			ServerPort:      443,
+		})
+
+		env.Do(func() {
+			netx := NewNetwork(
+				bytecounter.New(),
+				&kvstore.Memory{},
+				log.Log,
+				nil, // proxy URL
+				(&netxlite.Netx{}).NewStdlibResolver(log.Log),
+			)
+			defer netx.Close()
+
+			client := netx.NewHTTPClient()
+			resp, err := client.Get("https://www.example.com/")
+			if err == nil {
+				resp.Body.Close()
+				t.Fatal("expected an error, got", resp.Proto)
+			}
+		})
+	})
 }
 
 // Make sure we get the correct policy type depending on how we call newHTTPSDialerPolicy
@@ -250,7 +317,9 @@ func TestNewHTTPSDialerPolicyTypes(t *testing.T) {
 
 	// this function ensures that the DNS ext part of the chain is correct
 	verifyDNSExtChain := func(_ *testing.T, root *testHelpersPolicy) {
-		_ = root.Child.(*dnsPolicy)
+		sni := root.Child.(*statsSNIPolicy)
+		fragment := sni.Child.(*fragmentPolicy)
+		_ = fragment.Child.(*dnsPolicy)
 	}
 
 	// this function ensures that the policy used when there's no use policy has
@@ -350,6 +419,36 @@ func TestNewHTTPSDialerPolicyTypes(t *testing.T) {
 	}
 }
 
+// Make sure we get the correct policy type depending on how we call newQUICDialerPolicy
+func TestNewQUICDialerPolicyTypes(t *testing.T) {
+	// this function ensures that the policy used when there's no user policy has
+	// the correct type and anything below it also has the correct type
+	verifyNoUserPolicyChain := func(t *testing.T, root httpsDialerPolicy) {
+		interleavePolicy := root.(*mixPolicyInterleave)
+		if interleavePolicy.Factor != 3 {
+			t.Fatal("expected .Factory to be 3")
+		}
+		quic := interleavePolicy.Primary.(*quicPolicy)
+		th := quic.Child.(*testHelpersPolicy)
+		_ = th.Child.(*dnsPolicy)
+		_ = interleavePolicy.Fallback.(*statsPolicyV2)
+	}
+
+	t.Run("when there is no user policy", func(t *testing.T) {
+		p := newQUICDialerPolicy(&kvstore.Memory{}, model.DiscardLogger, &mocks.Resolver{}, &statsManager{})
+		verifyNoUserPolicyChain(t, p)
+	})
+
+	t.Run("when there is a user policy", func(t *testing.T) {
+		store := &kvstore.Memory{}
+		runtimex.Try0(store.Set(userPolicyKey, []byte(`{"Version":3}`)))
+		p := newQUICDialerPolicy(store, model.DiscardLogger, &mocks.Resolver{}, &statsManager{})
+		eitherOrPolicy := p.(*mixPolicyEitherOr)
+		_ = eitherOrPolicy.Primary.(*userPolicyV2)
+		verifyNoUserPolicyChain(t, eitherOrPolicy.Fallback)
+	})
+}
+
 // This test ensures that newHTTPSDialerPolicy is functionally working as intended.
 func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 	// testcase is a test case implemented by this func
@@ -385,7 +484,9 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 		// no state, where we want to see that we're using the DNS, and
 		// that, on top of this, we're getting bridges tactics when
 		// we're using api.ooni.io and we're getting various SNIs when
-		// instead we're using test helper domains.
+		// instead we're using test helper domains. We also expect to
+		// see tactics fragmenting the ClientHello for each address and,
+		// because the stats are empty, tactics exploring new SNIs.
 
 		{
 			name: "without proxy, with empty key-value store, and NXDOMAIN for www.example.com",
@@ -415,16 +516,14 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 				},
 			},
 			domain:               "www.example.com",
-			totalExpectedEntries: 2,
+			totalExpectedEntries: 10,
 			initialExpectedEntries: []*httpsDialerTactic{{
 				Address:        "93.184.215.14",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "www.example.com",
 				VerifyHostname: "www.example.com",
 			}, {
 				Address:        "2606:2800:21f:cb07:6820:80da:af6b:8b2c",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "www.example.com",
 				VerifyHostname: "www.example.com",
@@ -459,16 +558,14 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 				},
 			},
 			domain:               "api.ooni.io",
-			totalExpectedEntries: 154,
+			totalExpectedEntries: 162,
 			initialExpectedEntries: []*httpsDialerTactic{{
 				Address:        "130.192.91.211",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "api.ooni.io",
 				VerifyHostname: "api.ooni.io",
 			}, {
 				Address:        "130.192.91.231",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "api.ooni.io",
 				VerifyHostname: "api.ooni.io",
@@ -503,16 +600,14 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 				},
 			},
 			domain:               "0.th.ooni.org",
-			totalExpectedEntries: 306,
+			totalExpectedEntries: 314,
 			initialExpectedEntries: []*httpsDialerTactic{{
 				Address:        "130.192.91.211",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "0.th.ooni.org",
 				VerifyHostname: "0.th.ooni.org",
 			}, {
 				Address:        "130.192.91.231",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "0.th.ooni.org",
 				VerifyHostname: "0.th.ooni.org",
@@ -553,13 +648,11 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			totalExpectedEntries: 2,
 			initialExpectedEntries: []*httpsDialerTactic{{
 				Address:        "93.184.215.14",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "www.example.com",
 				VerifyHostname: "www.example.com",
 			}, {
 				Address:        "2606:2800:21f:cb07:6820:80da:af6b:8b2c",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "www.example.com",
 				VerifyHostname: "www.example.com",
@@ -597,13 +690,11 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			totalExpectedEntries: 2,
 			initialExpectedEntries: []*httpsDialerTactic{{
 				Address:        "130.192.91.211",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "api.ooni.io",
 				VerifyHostname: "api.ooni.io",
 			}, {
 				Address:        "130.192.91.231",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "api.ooni.io",
 				VerifyHostname: "api.ooni.io",
@@ -641,13 +732,11 @@ func TestNewHTTPSDialerPolicyFunctional(t *testing.T) {
 			totalExpectedEntries: 2,
 			initialExpectedEntries: []*httpsDialerTactic{{
 				Address:        "130.192.91.211",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "0.th.ooni.org",
 				VerifyHostname: "0.th.ooni.org",
 			}, {
 				Address:        "130.192.91.231",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "0.th.ooni.org",
 				VerifyHostname: "0.th.ooni.org",
@@ -0,0 +1,201 @@
+package enginenetx
+
+//
+// QUIC dialer - the QUIC counterpart of the httpsDialer, which we use
+// to create HTTP/3 connections with bridges and test helpers
+//
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+
+	"github.com/ooni/probe-engine/pkg/logx"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/netxlite"
+	"github.com/quic-go/quic-go"
+)
+
+// quicDialer is the [model.QUICDialer] used by the engine to dial HTTP/3 connections.
+//
+// The zero value of this struct is invalid; construct using [newQUICDialer].
+//
+// This dialer uses the same [*dialScheduler] used by [*httpsDialer]
+// but it only uses the tactics whose Transport is [tacticTransportQUIC].
+type quicDialer struct {
+	// idGenerator is the ID generator.
+	idGenerator *atomic.Int64
+
+	// logger is the logger to use.
+	logger model.Logger
+
+	// netx is the [*netxlite.Netx] to use.
+	netx *netxlite.Netx
+
+	// policy defines the dialing policy to use.
+	policy httpsDialerPolicy
+
+	// rootCAs contains the root certificate pool we should use.
+	rootCAs *x509.CertPool
+
+	// scheduler decides when to start each dialing attempt.
+	scheduler *dialScheduler
+
+	// stats tracks what happens while dialing.
+	stats httpsDialerEventsHandler
+}
+
+// newQUICDialer constructs a new [*quicDialer] instance.
+//
+// Arguments:
+//
+// - logger is the logger to use for logging;
+//
+// - netx is the [*netxlite.Netx] to use;
+//
+// - policy defines the dialer policy;
+//
+// - stats tracks what happens while we're dialing.
+//
+// The returned [*quicDialer] would use the underlying network's
+// DefaultCertPool to create and cache the cert pool to use.
+func newQUICDialer(
+	logger model.Logger,
+	netx *netxlite.Netx,
+	policy httpsDialerPolicy,
+	stats httpsDialerEventsHandler,
+) *quicDialer {
+	return &quicDialer{
+		idGenerator: &atomic.Int64{},
+		logger: &logx.PrefixLogger{
+			Prefix: "quicDialer: ",
+			Logger: logger,
+		},
+		netx:      netx,
+		policy:    policy,
+		rootCAs:   netx.MaybeCustomUnderlyingNetwork().Get().DefaultCertPool(),
+		scheduler: newDialScheduler(),
+		stats:     stats,
+	}
+}
+
+var _ model.QUICDialer = &quicDialer{}
+
+// CloseIdleConnections implements model.QUICDialer.
+func (qd *quicDialer) CloseIdleConnections() {
+	// nothing
+}
+
+// DialContext implements model.QUICDialer.
+//
+// We use the ALPN configured by the tlsConfig argument, which MAY be nil, and we ignore
+// the other TLS settings, since each tactic defines the SNI and the hostname to verify.
+func (qd *quicDialer) DialContext(
+	ctx context.Context, endpoint string, tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+	hostname, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine which ALPN to use, defaulting to HTTP/3.
+	alpn := []string{"h3"}
+	if tlsConfig != nil && len(tlsConfig.NextProtos) > 0 {
+		alpn = tlsConfig.NextProtos
+	}
+
+	// The emitter will emit tactics and then close the channel when done and the
+	// scheduler will start dialing attempts like it does for the [*httpsDialer].
+	emitter := func(ctx context.Context) <-chan *httpsDialerTactic {
+		return httpsDialerFilterTactics(qd.policy.LookupTactics(ctx, hostname, port), tacticTransportQUIC)
+	}
+	connv, errorv := dialSchedulerRun(ctx, qd.scheduler, emitter,
+		func(ctx context.Context, tactic *httpsDialerTactic) (quic.EarlyConnection, error) {
+			prefixLogger := &logx.PrefixLogger{
+				Prefix: fmt.Sprintf("[#%d] ", qd.idGenerator.Add(1)),
+				Logger: qd.logger,
+			}
+			return qd.dialQUIC(ctx, prefixLogger, tactic, alpn, quicConfig)
+		})
+	return quicDialerReduceResult(connv, errorv)
+}
+
+// quicDialerReduceResult returns either an established conn or an error, using [errDNSNoAnswer] in
+// case the list of connections and the list of errors are empty.
+func quicDialerReduceResult(connv []quic.EarlyConnection, errorv []error) (quic.EarlyConnection, error) {
+	switch {
+	case len(connv) >= 1:
+		for _, c := range connv[1:] {
+			_ = c.CloseWithError(0, "")
+		}
+		return connv[0], nil
+
+	case len(errorv) >= 1:
+		return nil, errors.Join(errorv...)
+
+	default:
+		return nil, errDNSNoAnswer
+	}
+}
+
+// dialQUIC performs the actual QUIC dial.
+func (qd *quicDialer) dialQUIC(
+	ctx context.Context,
+	logger model.Logger,
+	tactic *httpsDialerTactic,
+	alpn []string,
+	quicConfig *quic.Config,
+) (quic.EarlyConnection, error) {
+	// for debugging let the user know which tactic is ready
+	logger.Infof("tactic '%+v' is ready", tactic)
+
+	// tell the observer that we're starting
+	qd.stats.OnStarting(tactic)
+
+	// create TLS configuration
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true, // #nosec G402 - we verify at end of func
+		NextProtos:         alpn,
+		RootCAs:            qd.rootCAs,
+		ServerName:         tactic.SNI,
+	}
+
+	// create dialer and establish a QUIC connection
+	endpoint := net.JoinHostPort(tactic.Address, tactic.Port)
+	ol := logx.NewOperationLogger(
+		logger,
+		"QUICHandshake with %s SNI=%s ALPN=%v",
+		endpoint,
+		tlsConfig.ServerName,
+		tlsConfig.NextProtos,
+	)
+	dialer := qd.netx.NewQUICDialerWithoutResolver(qd.netx.NewUDPListener(), logger)
+	quicConn, err := dialer.DialContext(ctx, endpoint, tlsConfig, quicConfig)
+	ol.Stop(err)
+
+	// handle handshake error
+	if err != nil {
+		qd.stats.OnQUICHandshakeError(ctx, tactic, err)
+		return nil, err
+	}
+
+	// verify the certificate chain
+	ol = logx.NewOperationLogger(logger, "TLSVerifyCertificateChain %s", tactic.VerifyHostname)
+	err = httpsDialerVerifyConnectionState(tactic.VerifyHostname, quicConn.ConnectionState().TLS, qd.rootCAs)
+	ol.Stop(err)
+
+	// handle verification error
+	if err != nil {
+		qd.stats.OnTLSVerifyError(tactic, err)
+		_ = quicConn.CloseWithError(0, "")
+		return nil, err
+	}
+
+	// make sure the observer knows it worked
+	qd.stats.OnSuccess(tactic)
+
+	return quicConn, nil
+}
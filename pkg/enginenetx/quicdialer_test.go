@@ -0,0 +1,199 @@
+package enginenetx
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/mocks"
+	"github.com/ooni/probe-engine/pkg/netemx"
+	"github.com/ooni/probe-engine/pkg/netxlite"
+	"github.com/quic-go/quic-go"
+)
+
+// QA using netem
+func TestQUICDialerNetemQA(t *testing.T) {
+	// testcase is a test case implemented by this function
+	type testcase struct {
+		// name is the name of the test case
+		name string
+
+		// endpoint is the endpoint to connect to consisting of a domain
+		// name or IP address followed by a UDP port
+		endpoint string
+
+		// tlsConfig is the OPTIONAL TLS config to pass to the dialer
+		tlsConfig *tls.Config
+
+		// scenario is the netemx testing scenario to create
+		scenario []*netemx.ScenarioDomainAddresses
+
+		// expectErr is the error string we expect to see
+		expectErr string
+
+		// expectALPN is the ALPN we expect to negotiate on success
+		expectALPN string
+	}
+
+	allTestCases := []testcase{
+
+		// This test case ensures that we handle the corner case of a missing port
+		{
+			name:      "net.SplitHostPort failure",
+			endpoint:  "www.example.com", // note: here the port is missing
+			scenario:  netemx.InternetScenario,
+			expectErr: "address www.example.com: missing port in address",
+		},
+
+		// This test case ensures that we handle the case of a nonexistent domain
+		{
+			name:      "qd.policy.LookupTactics failure",
+			endpoint:  "www.example.nonexistent:443", // note: the domain does not exist
+			scenario:  netemx.InternetScenario,
+			expectErr: "dns_no_answer",
+		},
+
+		// This test case is the common case: all is good with multiple addresses to dial
+		{
+			name:     "successful dial with multiple addresses",
+			endpoint: "www.example.com:443",
+			scenario: []*netemx.ScenarioDomainAddresses{{
+				Domains: []string{
+					"www.example.com",
+				},
+				Addresses: []string{
+					"93.184.216.34",
+					"93.184.216.35",
+				},
+				Role:             netemx.ScenarioRoleWebServer,
+				ServerNameMain:   "www.example.com",
+				WebServerFactory: netemx.ExampleWebPageHandlerFactory(),
+			}},
+			expectErr:  "",
+			expectALPN: "h3",
+		},
+
+		// This test case makes sure we use the ALPN provided by the caller
+		{
+			name:      "successful dial with the caller's ALPN",
+			endpoint:  "www.example.com:443",
+			tlsConfig: &tls.Config{NextProtos: []string{"h3"}},
+			scenario: []*netemx.ScenarioDomainAddresses{{
+				Domains: []string{
+					"www.example.com",
+				},
+				Addresses: []string{
+					"93.184.216.34",
+				},
+				Role:             netemx.ScenarioRoleWebServer,
+				ServerNameMain:   "www.example.com",
+				WebServerFactory: netemx.ExampleWebPageHandlerFactory(),
+			}},
+			expectErr:  "",
+			expectALPN: "h3",
+		},
+
+		// Note: this is where we test that TLS verification is WAI when using QUIC
+		{
+			name:     "with a TLS certificate valid for ANOTHER domain",
+			endpoint: "www.example.org:443",
+			scenario: []*netemx.ScenarioDomainAddresses{{
+				Domains: []string{
+					"www.example.org",
+				},
+				Addresses: []string{
+					"93.184.216.34",
+					"93.184.216.35",
+				},
+				Role:             netemx.ScenarioRoleWebServer,
+				ServerNameMain:   "www.example.com",
+				WebServerFactory: netemx.ExampleWebPageHandlerFactory(),
+			}},
+			expectErr: "ssl_invalid_hostname\nssl_invalid_hostname",
+		}}
+
+	for _, tc := range allTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// create the QA environment
+			env := netemx.MustNewScenario(tc.scenario)
+			defer env.Close()
+
+			// create the network proper
+			netx := &netxlite.Netx{Underlying: &netxlite.NetemUnderlyingNetworkAdapter{UNet: env.ClientStack}}
+
+			// create the getaddrinfo resolver
+			resolver := netx.NewStdlibResolver(log.Log)
+
+			policy := &quicPolicy{
+				Child: &dnsPolicy{
+					Logger:   log.Log,
+					Resolver: resolver,
+				},
+			}
+
+			// create the QUIC dialer
+			dialer := newQUICDialer(log.Log, netx, policy, &nullStatsManager{})
+			defer dialer.CloseIdleConnections()
+
+			// dial the QUIC connection
+			quicConn, err := dialer.DialContext(context.Background(), tc.endpoint, tc.tlsConfig, &quic.Config{})
+
+			// make sure the error is the one we expected
+			switch {
+			case err != nil && tc.expectErr == "":
+				t.Fatal("expected", tc.expectErr, "got", err)
+
+			case err == nil && tc.expectErr != "":
+				t.Fatal("expected", tc.expectErr, "got", err)
+
+			case err != nil && tc.expectErr != "":
+				if diff := cmp.Diff(tc.expectErr, err.Error()); diff != "" {
+					t.Fatal(diff)
+				}
+
+			case err == nil && tc.expectErr == "":
+				defer quicConn.CloseWithError(0, "")
+				if alpn := quicConn.ConnectionState().TLS.NegotiatedProtocol; alpn != tc.expectALPN {
+					t.Fatal("expected", tc.expectALPN, "got", alpn)
+				}
+			}
+		})
+	}
+}
+
+func TestQUICDialerReduceResult(t *testing.T) {
+	t.Run("with no conns and no errors", func(t *testing.T) {
+		conn, err := quicDialerReduceResult(nil, nil)
+		if err != errDNSNoAnswer {
+			t.Fatal("unexpected error", err)
+		}
+		if conn != nil {
+			t.Fatal("expected nil conn")
+		}
+	})
+
+	t.Run("with multiple conns", func(t *testing.T) {
+		var closed int
+		newConn := func() quic.EarlyConnection {
+			return &mocks.QUICEarlyConnection{
+				MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+					closed++
+					return nil
+				},
+			}
+		}
+		first := newConn()
+		conn, err := quicDialerReduceResult([]quic.EarlyConnection{first, newConn(), newConn()}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conn != first {
+			t.Fatal("expected the first conn")
+		}
+		if closed != 2 {
+			t.Fatal("expected to close two conns, got", closed)
+		}
+	})
+}
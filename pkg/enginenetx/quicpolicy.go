@@ -0,0 +1,48 @@
+package enginenetx
+
+//
+// QUIC policy - a policy converting the tactics generated
+// by a child policy to tactics using QUIC
+//
+
+import "context"
+
+// quicPolicy is a policy where we convert each tactic emitted by
+// the child policy to a tactic using [tacticTransportQUIC].
+//
+// The zero value is invalid; please, init MANDATORY fields.
+type quicPolicy struct {
+	// Child is the MANDATORY child policy.
+	Child httpsDialerPolicy
+}
+
+var _ httpsDialerPolicy = &quicPolicy{}
+
+// LookupTactics implements httpsDialerPolicy.
+func (p *quicPolicy) LookupTactics(ctx context.Context, domain, port string) <-chan *httpsDialerTactic {
+	return quicConvertTactics(p.Child.LookupTactics(ctx, domain, port))
+}
+
+// quicConvertTactics converts the tactics read from the input channel to tactics
+// using QUIC and emits them on the returned channel, which is closed when done.
+func quicConvertTactics(input <-chan *httpsDialerTactic) <-chan *httpsDialerTactic {
+	out := make(chan *httpsDialerTactic)
+
+	go func() {
+		// tell the parent when we're done
+		defer close(out)
+
+		for tactic := range input {
+			// a tactic fragmenting the ClientHello is a TCP-only tactic and
+			// converting it would just produce a duplicate tactic
+			if tactic.Fragment != fragmentNone {
+				continue
+			}
+			tactic = tactic.Clone()
+			tactic.Transport = tacticTransportQUIC
+			out <- tactic
+		}
+	}()
+
+	return out
+}
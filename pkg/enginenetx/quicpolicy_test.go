@@ -0,0 +1,64 @@
+package enginenetx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQUICPolicy(t *testing.T) {
+	childTactics := []*httpsDialerTactic{{
+		Address:        "162.55.247.208",
+		Port:           "443",
+		SNI:            "api.ooni.io",
+		VerifyHostname: "api.ooni.io",
+	}, {
+		Address:        "162.55.247.208",
+		Port:           "443",
+		SNI:            "api.ooni.io",
+		VerifyHostname: "api.ooni.io",
+		Fragment:       fragmentTCPSegments,
+	}, {
+		Address:        "2a01:4f8:1c0c:5b86::1",
+		Port:           "443",
+		SNI:            "www.example.com",
+		VerifyHostname: "api.ooni.io",
+	}}
+
+	policy := &quicPolicy{
+		Child: &mocksPolicy{
+			MockLookupTactics: func(ctx context.Context, domain, port string) <-chan *httpsDialerTactic {
+				return streamTacticsFromSlice(childTactics)
+			},
+		},
+	}
+
+	var tactics []*httpsDialerTactic
+	for entry := range policy.LookupTactics(context.Background(), "api.ooni.io", "443") {
+		tactics = append(tactics, entry)
+	}
+
+	// we expect to skip fragmented tactics
+	expect := []*httpsDialerTactic{{
+		Address:        "162.55.247.208",
+		Port:           "443",
+		SNI:            "api.ooni.io",
+		VerifyHostname: "api.ooni.io",
+		Transport:      tacticTransportQUIC,
+	}, {
+		Address:        "2a01:4f8:1c0c:5b86::1",
+		Port:           "443",
+		SNI:            "www.example.com",
+		VerifyHostname: "api.ooni.io",
+		Transport:      tacticTransportQUIC,
+	}}
+	if diff := cmp.Diff(expect, tactics); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// make sure we did not modify the child's tactics
+	if childTactics[0].Transport != tacticTransportTCP {
+		t.Fatal("the policy modified the child's tactics")
+	}
+}
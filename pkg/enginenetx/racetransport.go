@@ -0,0 +1,369 @@
+package enginenetx
+
+//
+// race transport - an HTTP transport racing TLS over TCP and QUIC
+// when first connecting to an HTTPS endpoint, such that we use HTTP/3
+// when TCP is blocked or throttled but QUIC works
+//
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/quic-go/quic-go"
+)
+
+// raceTransportQUICDelay is the default delay after which we start
+// dialing QUIC when TCP is still trying to connect. We give TLS over TCP a
+// head start because it's what we've been using historically and we only
+// want to use QUIC when TCP is not working or is slow.
+const raceTransportQUICDelay = time.Second
+
+// raceTransport is a [model.HTTPTransport] that races TLS over TCP and QUIC
+// the first time we connect to a given HTTPS endpoint, and then uses either
+// the TCP transport or the HTTP/3 transport depending on which transport
+// won the race. If a round trip fails, we forget the winner such that we
+// are going to race again the next time.
+//
+// The zero value is invalid; please, init MANDATORY fields.
+type raceTransport struct {
+	// Handoff is the MANDATORY [*raceHandoff] we use to pass the connection
+	// that won the race to the corresponding transport.
+	Handoff *raceHandoff
+
+	// QUICDelay is the MANDATORY delay after which to start dialing with QUIC.
+	QUICDelay time.Duration
+
+	// QUICDialer is the MANDATORY QUIC dialer to use when racing.
+	QUICDialer model.QUICDialer
+
+	// QUICTransport is the MANDATORY HTTP/3 transport, which MUST use
+	// a [*raceHandoffQUICDialer] to dial QUIC connections.
+	QUICTransport model.HTTPTransport
+
+	// TCPTransport is the MANDATORY HTTP transport, which MUST use
+	// a [*raceHandoffTLSDialer] to dial TLS connections.
+	TCPTransport model.HTTPTransport
+
+	// TLSDialer is the MANDATORY TLS dialer to use when racing.
+	TLSDialer model.TLSDialer
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// winners maps an endpoint to the transport that won the race.
+	winners map[string]tacticTransport
+}
+
+var _ model.HTTPTransport = &raceTransport{}
+
+// CloseIdleConnections implements model.HTTPTransport.
+func (txp *raceTransport) CloseIdleConnections() {
+	txp.TCPTransport.CloseIdleConnections()
+	txp.QUICTransport.CloseIdleConnections()
+	txp.Handoff.Close()
+}
+
+// Network implements model.HTTPTransport.
+func (txp *raceTransport) Network() string {
+	return txp.TCPTransport.Network()
+}
+
+// RoundTrip implements model.HTTPTransport.
+func (txp *raceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// we only know how to race for HTTPS
+	if req.URL.Scheme != "https" {
+		return txp.TCPTransport.RoundTrip(req)
+	}
+
+	// determine which transport to use, possibly racing
+	endpoint := raceTransportEndpoint(req)
+	transport, found := txp.getWinner(endpoint)
+	if !found {
+		var err error
+		transport, err = txp.race(req.Context(), endpoint)
+		if err != nil {
+			return nil, err
+		}
+		txp.setWinner(endpoint, transport)
+	}
+
+	// perform the round trip and race again next time in case of failure
+	child := txp.TCPTransport
+	if transport == tacticTransportQUIC {
+		child = txp.QUICTransport
+	}
+	resp, err := child.RoundTrip(req)
+	if err != nil {
+		txp.forgetWinner(endpoint)
+	}
+	return resp, err
+}
+
+// raceTransportEndpoint returns the endpoint for the request using the same
+// format used by the transports when calling their dialers.
+func raceTransportEndpoint(req *http.Request) string {
+	port := req.URL.Port()
+	if port == "" {
+		port = "443"
+	}
+	return net.JoinHostPort(req.URL.Hostname(), port)
+}
+
+func (txp *raceTransport) getWinner(endpoint string) (tacticTransport, bool) {
+	defer txp.mu.Unlock()
+	txp.mu.Lock()
+	transport, found := txp.winners[endpoint]
+	return transport, found
+}
+
+func (txp *raceTransport) setWinner(endpoint string, transport tacticTransport) {
+	defer txp.mu.Unlock()
+	txp.mu.Lock()
+	if txp.winners == nil {
+		txp.winners = make(map[string]tacticTransport)
+	}
+	txp.winners[endpoint] = transport
+}
+
+func (txp *raceTransport) forgetWinner(endpoint string) {
+	defer txp.mu.Unlock()
+	txp.mu.Lock()
+	delete(txp.winners, endpoint)
+}
+
+// raceTransportResult is the result of a dial attempt while racing.
+type raceTransportResult struct {
+	// TLSConn is the TLS conn or nil.
+	TLSConn net.Conn
+
+	// QUICConn is the QUIC conn or nil.
+	QUICConn quic.EarlyConnection
+
+	// Err is the error or nil.
+	Err error
+}
+
+// race races TLS over TCP and QUIC, returns the transport that won the race and
+// posts the established connection inside the handoff for the winner's transport.
+func (txp *raceTransport) race(ctx context.Context, endpoint string) (tacticTransport, error) {
+	// We need a cancellable context to interrupt the loser.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Note: the channels are buffered such that the goroutines never block.
+	tcpch := make(chan *raceTransportResult, 1)
+	quicch := make(chan *raceTransportResult, 1)
+	tcpFailed := make(chan any)
+
+	go func() {
+		conn, err := txp.TLSDialer.DialTLSContext(ctx, "tcp", endpoint)
+		if err != nil {
+			close(tcpFailed) // start QUIC immediately
+		}
+		tcpch <- &raceTransportResult{TLSConn: conn, Err: err}
+	}()
+
+	go func() {
+		// wait for the head start to expire or for TCP to fail
+		timer := time.NewTimer(txp.QUICDelay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-tcpFailed:
+		case <-ctx.Done():
+			quicch <- &raceTransportResult{Err: ctx.Err()}
+			return
+		}
+		tlsConfig := &tls.Config{NextProtos: []string{"h3"}}
+		conn, err := txp.QUICDialer.DialContext(ctx, endpoint, tlsConfig, &quic.Config{})
+		quicch <- &raceTransportResult{QUICConn: conn, Err: err}
+	}()
+
+	// Wait for both goroutines to terminate, interrupting the loser as soon as
+	// we have a winner and closing the loser's conn if it also succeeded.
+	var (
+		tcpErr  error
+		quicErr error
+		winner  = tacticTransport("")
+		won     = false
+	)
+	for idx := 0; idx < 2; idx++ {
+		select {
+		case result := <-tcpch:
+			if result.Err != nil {
+				tcpErr = result.Err
+				continue
+			}
+			if won {
+				_ = result.TLSConn.Close()
+				continue
+			}
+			won, winner = true, tacticTransportTCP
+			txp.Handoff.PushTLS(endpoint, result.TLSConn)
+			cancel()
+
+		case result := <-quicch:
+			if result.Err != nil {
+				quicErr = result.Err
+				continue
+			}
+			if won {
+				_ = result.QUICConn.CloseWithError(0, "")
+				continue
+			}
+			won, winner = true, tacticTransportQUIC
+			txp.Handoff.PushQUIC(endpoint, result.QUICConn)
+			cancel()
+		}
+	}
+
+	if !won {
+		return "", raceTransportReduceErrors(tcpErr, quicErr)
+	}
+	return winner, nil
+}
+
+// raceTransportReduceErrors combines the TCP and the QUIC errors. When there were no
+// QUIC tactics to try, we only return the TCP error, which is more informative.
+func raceTransportReduceErrors(tcpErr, quicErr error) error {
+	if errors.Is(quicErr, errDNSNoAnswer) {
+		return tcpErr
+	}
+	return errors.Join(tcpErr, quicErr)
+}
+
+// raceHandoff passes the connections established while racing to the dialers
+// used by the transports. We keep at most one connection per endpoint and
+// per transport and close the old connection when adding a new one.
+//
+// The zero value is ready to use.
+type raceHandoff struct {
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// quicConns contains the QUIC conns.
+	quicConns map[string]quic.EarlyConnection
+
+	// tlsConns contains the TLS conns.
+	tlsConns map[string]net.Conn
+}
+
+// PushTLS adds a TLS conn for the given endpoint.
+func (h *raceHandoff) PushTLS(endpoint string, conn net.Conn) {
+	defer h.mu.Unlock()
+	h.mu.Lock()
+	if h.tlsConns == nil {
+		h.tlsConns = make(map[string]net.Conn)
+	}
+	if old := h.tlsConns[endpoint]; old != nil {
+		_ = old.Close()
+	}
+	h.tlsConns[endpoint] = conn
+}
+
+// PopTLS returns and removes the TLS conn for the given endpoint, if any.
+func (h *raceHandoff) PopTLS(endpoint string) (net.Conn, bool) {
+	defer h.mu.Unlock()
+	h.mu.Lock()
+	conn, found := h.tlsConns[endpoint]
+	delete(h.tlsConns, endpoint)
+	return conn, found
+}
+
+// PushQUIC adds a QUIC conn for the given endpoint.
+func (h *raceHandoff) PushQUIC(endpoint string, conn quic.EarlyConnection) {
+	defer h.mu.Unlock()
+	h.mu.Lock()
+	if h.quicConns == nil {
+		h.quicConns = make(map[string]quic.EarlyConnection)
+	}
+	if old := h.quicConns[endpoint]; old != nil {
+		_ = old.CloseWithError(0, "")
+	}
+	h.quicConns[endpoint] = conn
+}
+
+// PopQUIC returns and removes the QUIC conn for the given endpoint, if any.
+func (h *raceHandoff) PopQUIC(endpoint string) (quic.EarlyConnection, bool) {
+	defer h.mu.Unlock()
+	h.mu.Lock()
+	conn, found := h.quicConns[endpoint]
+	delete(h.quicConns, endpoint)
+	return conn, found
+}
+
+// Close closes all the conns that nobody has used.
+func (h *raceHandoff) Close() {
+	defer h.mu.Unlock()
+	h.mu.Lock()
+	for _, conn := range h.tlsConns {
+		_ = conn.Close()
+	}
+	for _, conn := range h.quicConns {
+		_ = conn.CloseWithError(0, "")
+	}
+	h.tlsConns = nil
+	h.quicConns = nil
+}
+
+// raceHandoffTLSDialer is a [model.TLSDialer] that uses the conn inside
+// the [*raceHandoff], if any, and otherwise uses the underlying dialer.
+//
+// The zero value is invalid; please, init MANDATORY fields.
+type raceHandoffTLSDialer struct {
+	// Dialer is the MANDATORY underlying dialer.
+	Dialer model.TLSDialer
+
+	// Handoff is the MANDATORY handoff.
+	Handoff *raceHandoff
+}
+
+var _ model.TLSDialer = &raceHandoffTLSDialer{}
+
+// CloseIdleConnections implements model.TLSDialer.
+func (d *raceHandoffTLSDialer) CloseIdleConnections() {
+	d.Dialer.CloseIdleConnections()
+}
+
+// DialTLSContext implements model.TLSDialer.
+func (d *raceHandoffTLSDialer) DialTLSContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	if conn, found := d.Handoff.PopTLS(address); found {
+		return conn, nil
+	}
+	return d.Dialer.DialTLSContext(ctx, network, address)
+}
+
+// raceHandoffQUICDialer is a [model.QUICDialer] that uses the conn inside
+// the [*raceHandoff], if any, and otherwise uses the underlying dialer.
+//
+// The zero value is invalid; please, init MANDATORY fields.
+type raceHandoffQUICDialer struct {
+	// Dialer is the MANDATORY underlying dialer.
+	Dialer model.QUICDialer
+
+	// Handoff is the MANDATORY handoff.
+	Handoff *raceHandoff
+}
+
+var _ model.QUICDialer = &raceHandoffQUICDialer{}
+
+// CloseIdleConnections implements model.QUICDialer.
+func (d *raceHandoffQUICDialer) CloseIdleConnections() {
+	d.Dialer.CloseIdleConnections()
+}
+
+// DialContext implements model.QUICDialer.
+func (d *raceHandoffQUICDialer) DialContext(
+	ctx context.Context, address string, tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+	if conn, found := d.Handoff.PopQUIC(address); found {
+		return conn, nil
+	}
+	return d.Dialer.DialContext(ctx, address, tlsConfig, quicConfig)
+}
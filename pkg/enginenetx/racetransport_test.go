@@ -0,0 +1,472 @@
+package enginenetx
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/ooni/probe-engine/pkg/mocks"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+	"github.com/quic-go/quic-go"
+)
+
+// raceTransportTestEnv contains the mocks used by raceTransport tests.
+type raceTransportTestEnv struct {
+	tlsDialCount  int
+	quicDialCount int
+	tcpRoundTrips int
+	h3RoundTrips  int
+	tcpErr        error
+	h3Err         error
+}
+
+// newTransport creates a new [*raceTransport] using the given dialers.
+func (env *raceTransportTestEnv) newTransport(tlsDialer *mocks.TLSDialer, quicDialer *mocks.QUICDialer) *raceTransport {
+	return &raceTransport{
+		Handoff:    &raceHandoff{},
+		QUICDelay:  0,
+		QUICDialer: quicDialer,
+		QUICTransport: &mocks.HTTPTransport{
+			MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+				env.h3RoundTrips++
+				return nil, env.h3Err
+			},
+		},
+		TCPTransport: &mocks.HTTPTransport{
+			MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+				env.tcpRoundTrips++
+				return nil, env.tcpErr
+			},
+		},
+		TLSDialer: tlsDialer,
+	}
+}
+
+// newRaceTransportTestRequest creates a new GET request for the given URL.
+func newRaceTransportTestRequest(URL string) *http.Request {
+	return runtimex.Try1(http.NewRequest("GET", URL, nil))
+}
+
+func TestRaceTransport(t *testing.T) {
+	t.Run("we don't race for cleartext HTTP", func(t *testing.T) {
+		env := &raceTransportTestEnv{}
+		txp := env.newTransport(nil, nil)
+		req := &http.Request{URL: &url.URL{Scheme: "http", Host: "api.ooni.io"}}
+		if _, err := txp.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		if env.tcpRoundTrips != 1 || env.h3RoundTrips != 0 {
+			t.Fatal("unexpected round trips", env.tcpRoundTrips, env.h3RoundTrips)
+		}
+	})
+
+	t.Run("when TCP wins the race", func(t *testing.T) {
+		env := &raceTransportTestEnv{}
+		tlsConn := &mocks.Conn{}
+		txp := env.newTransport(&mocks.TLSDialer{
+			MockDialTLSContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				env.tlsDialCount++
+				if address != "api.ooni.io:443" {
+					t.Error("unexpected address", address)
+				}
+				return tlsConn, nil
+			},
+		}, &mocks.QUICDialer{
+			MockDialContext: func(ctx context.Context, address string,
+				tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+				env.quicDialCount++
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		})
+
+		for idx := 0; idx < 2; idx++ {
+			if _, err := txp.RoundTrip(newRaceTransportTestRequest("https://api.ooni.io/")); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// we should only race once and use TCP twice
+		if env.tlsDialCount != 1 || env.quicDialCount > 1 {
+			t.Fatal("unexpected dial counts", env.tlsDialCount, env.quicDialCount)
+		}
+		if env.tcpRoundTrips != 2 || env.h3RoundTrips != 0 {
+			t.Fatal("unexpected round trips", env.tcpRoundTrips, env.h3RoundTrips)
+		}
+
+		// the conn should be in the handoff
+		conn, found := txp.Handoff.PopTLS("api.ooni.io:443")
+		if !found || conn != tlsConn {
+			t.Fatal("expected to find the TLS conn inside the handoff")
+		}
+	})
+
+	t.Run("when TCP fails and QUIC wins the race", func(t *testing.T) {
+		env := &raceTransportTestEnv{}
+		quicConn := &mocks.QUICEarlyConnection{}
+		txp := env.newTransport(&mocks.TLSDialer{
+			MockDialTLSContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				env.tlsDialCount++
+				return nil, errors.New("connection_reset")
+			},
+		}, &mocks.QUICDialer{
+			MockDialContext: func(ctx context.Context, address string,
+				tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+				env.quicDialCount++
+				if len(tlsConfig.NextProtos) != 1 || tlsConfig.NextProtos[0] != "h3" {
+					t.Error("unexpected ALPN", tlsConfig.NextProtos)
+				}
+				return quicConn, nil
+			},
+		})
+		txp.QUICDelay = raceTransportQUICDelay // TCP failing should start QUIC immediately
+
+		for idx := 0; idx < 2; idx++ {
+			if _, err := txp.RoundTrip(newRaceTransportTestRequest("https://api.ooni.io/")); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if env.tlsDialCount != 1 || env.quicDialCount != 1 {
+			t.Fatal("unexpected dial counts", env.tlsDialCount, env.quicDialCount)
+		}
+		if env.tcpRoundTrips != 0 || env.h3RoundTrips != 2 {
+			t.Fatal("unexpected round trips", env.tcpRoundTrips, env.h3RoundTrips)
+		}
+
+		conn, found := txp.Handoff.PopQUIC("api.ooni.io:443")
+		if !found || conn != quicConn {
+			t.Fatal("expected to find the QUIC conn inside the handoff")
+		}
+	})
+
+	t.Run("we close the loser's conn if it also succeeds", func(t *testing.T) {
+		env := &raceTransportTestEnv{}
+		var closed bool
+		txp := env.newTransport(&mocks.TLSDialer{
+			MockDialTLSContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				<-ctx.Done() // wait for QUIC to win and then pretend we succeeded
+				return &mocks.Conn{
+					MockClose: func() error {
+						closed = true
+						return nil
+					},
+				}, nil
+			},
+		}, &mocks.QUICDialer{
+			MockDialContext: func(ctx context.Context, address string,
+				tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+				return &mocks.QUICEarlyConnection{}, nil
+			},
+		})
+
+		if _, err := txp.RoundTrip(newRaceTransportTestRequest("https://api.ooni.io/")); err != nil {
+			t.Fatal(err)
+		}
+		if !closed {
+			t.Fatal("expected the loser's conn to be closed")
+		}
+		if _, found := txp.Handoff.PopTLS("api.ooni.io:443"); found {
+			t.Fatal("did not expect the loser's conn inside the handoff")
+		}
+	})
+
+	t.Run("when both TCP and QUIC fail", func(t *testing.T) {
+		type testcase struct {
+			name      string
+			quicErr   error
+			expectErr string
+		}
+
+		cases := []testcase{{
+			name:      "with QUIC tactics",
+			quicErr:   errors.New("generic_timeout_error"),
+			expectErr: "connection_reset\ngeneric_timeout_error",
+		}, {
+			name:      "without QUIC tactics",
+			quicErr:   errDNSNoAnswer,
+			expectErr: "connection_reset",
+		}}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				env := &raceTransportTestEnv{}
+				txp := env.newTransport(&mocks.TLSDialer{
+					MockDialTLSContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+						return nil, errors.New("connection_reset")
+					},
+				}, &mocks.QUICDialer{
+					MockDialContext: func(ctx context.Context, address string,
+						tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+						return nil, tc.quicErr
+					},
+				})
+
+				_, err := txp.RoundTrip(newRaceTransportTestRequest("https://api.ooni.io/"))
+				if err == nil || err.Error() != tc.expectErr {
+					t.Fatal("unexpected error", err)
+				}
+				if env.tcpRoundTrips != 0 || env.h3RoundTrips != 0 {
+					t.Fatal("unexpected round trips", env.tcpRoundTrips, env.h3RoundTrips)
+				}
+			})
+		}
+	})
+
+	t.Run("we race again after a round trip failure", func(t *testing.T) {
+		env := &raceTransportTestEnv{tcpErr: errors.New("connection_reset")}
+		txp := env.newTransport(&mocks.TLSDialer{
+			MockDialTLSContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				env.tlsDialCount++
+				return &mocks.Conn{
+					MockClose: func() error {
+						return nil
+					},
+				}, nil
+			},
+		}, &mocks.QUICDialer{
+			MockDialContext: func(ctx context.Context, address string,
+				tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		})
+
+		for idx := 0; idx < 2; idx++ {
+			if _, err := txp.RoundTrip(newRaceTransportTestRequest("https://api.ooni.io/")); err == nil {
+				t.Fatal("expected an error")
+			}
+		}
+		if env.tlsDialCount != 2 {
+			t.Fatal("expected to race twice, got", env.tlsDialCount)
+		}
+	})
+
+	t.Run("CloseIdleConnections", func(t *testing.T) {
+		var (
+			tcpCalled  bool
+			h3Called   bool
+			connClosed bool
+		)
+		txp := &raceTransport{
+			Handoff: &raceHandoff{},
+			QUICTransport: &mocks.HTTPTransport{
+				MockCloseIdleConnections: func() {
+					h3Called = true
+				},
+			},
+			TCPTransport: &mocks.HTTPTransport{
+				MockCloseIdleConnections: func() {
+					tcpCalled = true
+				},
+			},
+		}
+		txp.Handoff.PushTLS("api.ooni.io:443", &mocks.Conn{
+			MockClose: func() error {
+				connClosed = true
+				return nil
+			},
+		})
+		txp.CloseIdleConnections()
+		if !tcpCalled || !h3Called || !connClosed {
+			t.Fatal("unexpected state", tcpCalled, h3Called, connClosed)
+		}
+	})
+
+	t.Run("Network", func(t *testing.T) {
+		txp := &raceTransport{
+			TCPTransport: &mocks.HTTPTransport{
+				MockNetwork: func() string {
+					return "tcp"
+				},
+			},
+		}
+		if txp.Network() != "tcp" {
+			t.Fatal("unexpected network")
+		}
+	})
+}
+
+func TestRaceTransportEndpoint(t *testing.T) {
+	type testcase struct {
+		URL    string
+		expect string
+	}
+
+	cases := []testcase{{
+		URL:    "https://api.ooni.io/",
+		expect: "api.ooni.io:443",
+	}, {
+		URL:    "https://api.ooni.io:4443/",
+		expect: "api.ooni.io:4443",
+	}, {
+		URL:    "https://[2a01:4f8:1c0c:5b86::1]/",
+		expect: "[2a01:4f8:1c0c:5b86::1]:443",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.URL, func(t *testing.T) {
+			URL, err := url.Parse(tc.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := raceTransportEndpoint(&http.Request{URL: URL}); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
+
+func TestRaceHandoff(t *testing.T) {
+	t.Run("PushTLS closes the previous conn", func(t *testing.T) {
+		var closed int
+		newConn := func() net.Conn {
+			return &mocks.Conn{
+				MockClose: func() error {
+					closed++
+					return nil
+				},
+			}
+		}
+		h := &raceHandoff{}
+		h.PushTLS("api.ooni.io:443", newConn())
+		second := newConn()
+		h.PushTLS("api.ooni.io:443", second)
+		if closed != 1 {
+			t.Fatal("expected to close the first conn")
+		}
+		conn, found := h.PopTLS("api.ooni.io:443")
+		if !found || conn != second {
+			t.Fatal("expected to pop the second conn")
+		}
+		if _, found := h.PopTLS("api.ooni.io:443"); found {
+			t.Fatal("expected the handoff to be empty")
+		}
+	})
+
+	t.Run("PushQUIC closes the previous conn", func(t *testing.T) {
+		var closed int
+		newConn := func() quic.EarlyConnection {
+			return &mocks.QUICEarlyConnection{
+				MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+					closed++
+					return nil
+				},
+			}
+		}
+		h := &raceHandoff{}
+		h.PushQUIC("api.ooni.io:443", newConn())
+		second := newConn()
+		h.PushQUIC("api.ooni.io:443", second)
+		if closed != 1 {
+			t.Fatal("expected to close the first conn")
+		}
+		conn, found := h.PopQUIC("api.ooni.io:443")
+		if !found || conn != second {
+			t.Fatal("expected to pop the second conn")
+		}
+		if _, found := h.PopQUIC("api.ooni.io:443"); found {
+			t.Fatal("expected the handoff to be empty")
+		}
+	})
+
+	t.Run("Close closes all the conns", func(t *testing.T) {
+		var closed int
+		h := &raceHandoff{}
+		h.PushTLS("api.ooni.io:443", &mocks.Conn{
+			MockClose: func() error {
+				closed++
+				return nil
+			},
+		})
+		h.PushQUIC("api.ooni.io:443", &mocks.QUICEarlyConnection{
+			MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+				closed++
+				return nil
+			},
+		})
+		h.Close()
+		if closed != 2 {
+			t.Fatal("expected to close two conns, got", closed)
+		}
+	})
+}
+
+func TestRaceHandoffDialers(t *testing.T) {
+	t.Run("raceHandoffTLSDialer", func(t *testing.T) {
+		var dialed, closedIdle int
+		pooled := &mocks.Conn{}
+		h := &raceHandoff{}
+		h.PushTLS("api.ooni.io:443", pooled)
+		d := &raceHandoffTLSDialer{
+			Dialer: &mocks.TLSDialer{
+				MockCloseIdleConnections: func() {
+					closedIdle++
+				},
+				MockDialTLSContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+					dialed++
+					return &mocks.Conn{}, nil
+				},
+			},
+			Handoff: h,
+		}
+
+		// the first dial should use the pooled conn
+		conn, err := d.DialTLSContext(context.Background(), "tcp", "api.ooni.io:443")
+		if err != nil || conn != pooled || dialed != 0 {
+			t.Fatal("expected to use the pooled conn")
+		}
+
+		// the second dial should use the underlying dialer
+		conn, err = d.DialTLSContext(context.Background(), "tcp", "api.ooni.io:443")
+		if err != nil || conn == pooled || dialed != 1 {
+			t.Fatal("expected to use the underlying dialer")
+		}
+
+		d.CloseIdleConnections()
+		if closedIdle != 1 {
+			t.Fatal("expected to call CloseIdleConnections")
+		}
+	})
+
+	t.Run("raceHandoffQUICDialer", func(t *testing.T) {
+		var dialed, closedIdle int
+		pooled := &mocks.QUICEarlyConnection{}
+		h := &raceHandoff{}
+		h.PushQUIC("api.ooni.io:443", pooled)
+		d := &raceHandoffQUICDialer{
+			Dialer: &mocks.QUICDialer{
+				MockCloseIdleConnections: func() {
+					closedIdle++
+				},
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					dialed++
+					return &mocks.QUICEarlyConnection{}, nil
+				},
+			},
+			Handoff: h,
+		}
+
+		// the first dial should use the pooled conn
+		conn, err := d.DialContext(context.Background(), "api.ooni.io:443", &tls.Config{}, &quic.Config{})
+		if err != nil || conn != pooled || dialed != 0 {
+			t.Fatal("expected to use the pooled conn")
+		}
+
+		// the second dial should use the underlying dialer
+		conn, err = d.DialContext(context.Background(), "api.ooni.io:443", &tls.Config{}, &quic.Config{})
+		if err != nil || conn == pooled || dialed != 1 {
+			t.Fatal("expected to use the underlying dialer")
+		}
+
+		d.CloseIdleConnections()
+		if closedIdle != 1 {
+			t.Fatal("expected to call CloseIdleConnections")
+		}
+	})
+}
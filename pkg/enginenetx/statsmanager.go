@@ -44,6 +44,11 @@ func (*nullStatsManager) OnTLSHandshakeError(ctx context.Context, tactic *httpsD
 	// nothing
 }
 
+// OnQUICHandshakeError implements httpsDialerEventsHandler.
+func (*nullStatsManager) OnQUICHandshakeError(ctx context.Context, tactic *httpsDialerTactic, err error) {
+	// nothing
+}
+
 // OnTLSVerifyError implements httpsDialerEventsHandler.
 func (*nullStatsManager) OnTLSVerifyError(tactic *httpsDialerTactic, err error) {
 	// nothing
@@ -66,6 +71,12 @@ type statsTactic struct {
 	// CountTLSHandshakeInterrupt counts the number of interrupted TLS handshakes.
 	CountTLSHandshakeInterrupt int64
 
+	// CountQUICHandshakeError counts the number of QUIC handshake errors.
+	CountQUICHandshakeError int64 `json:",omitempty"`
+
+	// CountQUICHandshakeInterrupt counts the number of interrupted QUIC handshakes.
+	CountQUICHandshakeInterrupt int64 `json:",omitempty"`
+
 	// CountTLSVerificationError counts the number of TLS verification errors.
 	CountTLSVerificationError int64
 
@@ -78,6 +89,9 @@ type statsTactic struct {
 	// HistoTLSHandshakeError contains an histogram of TLS handshake errors.
 	HistoTLSHandshakeError map[string]int64
 
+	// HistoQUICHandshakeError contains an histogram of QUIC handshake errors.
+	HistoQUICHandshakeError map[string]int64 `json:",omitempty"`
+
 	// HistoTLSVerificationError contains an histogram of TLS verification errors.
 	HistoTLSVerificationError map[string]int64
 
@@ -198,18 +212,21 @@ func (st *statsTactic) Clone() *statsTactic {
 	// here we're using safe functions to clone the original struct considering
 	// that a user can edit the content on disk freely introducing nulls.
 	return &statsTactic{
-		CountStarted:               st.CountStarted,
-		CountTCPConnectError:       st.CountTCPConnectError,
-		CountTCPConnectInterrupt:   st.CountTCPConnectInterrupt,
-		CountTLSHandshakeError:     st.CountTLSHandshakeError,
-		CountTLSHandshakeInterrupt: st.CountTLSHandshakeInterrupt,
-		CountTLSVerificationError:  st.CountTLSVerificationError,
-		CountSuccess:               st.CountSuccess,
-		HistoTCPConnectError:       statsMaybeCloneMapStringInt64(st.HistoTCPConnectError),
-		HistoTLSHandshakeError:     statsMaybeCloneMapStringInt64(st.HistoTLSHandshakeError),
-		HistoTLSVerificationError:  statsMaybeCloneMapStringInt64(st.HistoTLSVerificationError),
-		LastUpdated:                st.LastUpdated,
-		Tactic:                     statsMaybeCloneTactic(st.Tactic),
+		CountStarted:                st.CountStarted,
+		CountTCPConnectError:        st.CountTCPConnectError,
+		CountTCPConnectInterrupt:    st.CountTCPConnectInterrupt,
+		CountTLSHandshakeError:      st.CountTLSHandshakeError,
+		CountTLSHandshakeInterrupt:  st.CountTLSHandshakeInterrupt,
+		CountQUICHandshakeError:     st.CountQUICHandshakeError,
+		CountQUICHandshakeInterrupt: st.CountQUICHandshakeInterrupt,
+		CountTLSVerificationError:   st.CountTLSVerificationError,
+		CountSuccess:                st.CountSuccess,
+		HistoTCPConnectError:        statsMaybeCloneMapStringInt64(st.HistoTCPConnectError),
+		HistoTLSHandshakeError:      statsMaybeCloneMapStringInt64(st.HistoTLSHandshakeError),
+		HistoQUICHandshakeError:     statsMaybeCloneMapStringInt64(st.HistoQUICHandshakeError),
+		HistoTLSVerificationError:   statsMaybeCloneMapStringInt64(st.HistoTLSVerificationError),
+		LastUpdated:                 st.LastUpdated,
+		Tactic:                      statsMaybeCloneTactic(st.Tactic),
 	}
 }
 
@@ -549,6 +566,31 @@ func (mt *statsManager) OnTLSHandshakeError(ctx context.Context, tactic *httpsDi
 	statsSafeIncrementMapStringInt64(&record.HistoTLSHandshakeError, err.Error())
 }
 
+// OnQUICHandshakeError implements httpsDialerEventsHandler.
+func (mt *statsManager) OnQUICHandshakeError(ctx context.Context, tactic *httpsDialerTactic, err error) {
+	// get exclusive access
+	defer mt.mu.Unlock()
+	mt.mu.Lock()
+
+	// get the record
+	record, found := mt.container.GetStatsTacticLocked(tactic)
+	if !found {
+		mt.logger.Warnf("statsManager.OnQUICHandshakeError: not found: %+v", tactic)
+		return
+	}
+
+	// update stats
+	record.LastUpdated = time.Now()
+	if ctx.Err() != nil {
+		record.CountQUICHandshakeInterrupt++
+		return
+	}
+
+	runtimex.Assert(err != nil, "OnQUICHandshakeError passed a nil error")
+	record.CountQUICHandshakeError++
+	statsSafeIncrementMapStringInt64(&record.HistoQUICHandshakeError, err.Error())
+}
+
 // OnTLSVerifyError implements httpsDialerEventsHandler.
 func (mt *statsManager) OnTLSVerifyError(tactic *httpsDialerTactic, err error) {
 	// get exclusive access
@@ -671,3 +713,47 @@ func (mt *statsManager) LookupTactics(domain string, port string) ([]*statsTacti
 	}
 	return out, len(out) > 0
 }
+
+// DomainEndpointStats summarizes the stats about all the tactics
+// we have used to dial a given domain endpoint.
+type DomainEndpointStats struct {
+	// DomainEndpoint is the domain endpoint (e.g., "api.ooni.io:443").
+	DomainEndpoint string
+
+	// CountStarted counts the number of operations we started.
+	CountStarted int64
+
+	// CountSuccess counts the number of successes.
+	CountSuccess int64
+}
+
+// Summary returns a summary of the stats for each domain endpoint sorted by domain endpoint.
+func (mt *statsManager) Summary() []*DomainEndpointStats {
+	out := []*DomainEndpointStats{}
+
+	// get exclusive access
+	defer mt.mu.Unlock()
+	mt.mu.Lock()
+
+	// Note: we protect against nil values since the stats are written on
+	// the disk and a user could potentially edit them
+	for domainEpnt, domainEpntRecord := range mt.container.DomainEndpoints {
+		if domainEpntRecord == nil {
+			continue
+		}
+		summary := &DomainEndpointStats{DomainEndpoint: domainEpnt}
+		for _, entry := range domainEpntRecord.Tactics {
+			if entry == nil {
+				continue
+			}
+			summary.CountStarted += entry.CountStarted
+			summary.CountSuccess += entry.CountSuccess
+		}
+		out = append(out, summary)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].DomainEndpoint < out[j].DomainEndpoint
+	})
+	return out
+}
@@ -67,7 +67,6 @@ func TestNetworkCollectsStats(t *testing.T) {
 						// us confidence that the stats are using the latter
 						"api.ooni.io:443": {{
 							Address:        netemx.AddressApiOONIIo,
-							InitialDelay:   0,
 							Port:           "443",
 							SNI:            "www.example.com",
 							VerifyHostname: "api.ooni.io",
@@ -101,7 +100,6 @@ func TestNetworkCollectsStats(t *testing.T) {
 				LastUpdated:               time.Time{},
 				Tactic: &httpsDialerTactic{
 					Address:        "162.55.247.208",
-					InitialDelay:   0,
 					Port:           "443",
 					SNI:            "www.example.com",
 					VerifyHostname: "api.ooni.io",
@@ -119,7 +117,6 @@ func TestNetworkCollectsStats(t *testing.T) {
 						// us confidence that the stats are using the latter
 						"api.ooni.io:443": {{
 							Address:        netemx.AddressApiOONIIo,
-							InitialDelay:   0,
 							Port:           "443",
 							SNI:            "www.example.com",
 							VerifyHostname: "api.ooni.io",
@@ -152,7 +149,6 @@ func TestNetworkCollectsStats(t *testing.T) {
 				LastUpdated:               time.Time{},
 				Tactic: &httpsDialerTactic{
 					Address:        "162.55.247.208",
-					InitialDelay:   0,
 					Port:           "443",
 					SNI:            "www.example.com",
 					VerifyHostname: "api.ooni.io",
@@ -170,7 +166,6 @@ func TestNetworkCollectsStats(t *testing.T) {
 						// us confidence that the stats are using the latter
 						"api.ooni.io:443": {{
 							Address:        netemx.AddressBadSSLCom,
-							InitialDelay:   0,
 							Port:           "443",
 							SNI:            "untrusted-root.badssl.com",
 							VerifyHostname: "api.ooni.io",
@@ -200,7 +195,6 @@ func TestNetworkCollectsStats(t *testing.T) {
 				LastUpdated: time.Time{},
 				Tactic: &httpsDialerTactic{
 					Address:        "104.154.89.105",
-					InitialDelay:   0,
 					Port:           "443",
 					SNI:            "untrusted-root.badssl.com",
 					VerifyHostname: "api.ooni.io",
@@ -358,7 +352,6 @@ func TestLoadStatsContainer(t *testing.T) {
 								LastUpdated: fourtyFiveMinutesAgo,
 								Tactic: &httpsDialerTactic{
 									Address:        "162.55.247.208",
-									InitialDelay:   0,
 									Port:           "443",
 									SNI:            "www.example.com",
 									VerifyHostname: "api.ooni.io",
@@ -382,7 +375,6 @@ func TestLoadStatsContainer(t *testing.T) {
 								LastUpdated: twoWeeksAgo,
 								Tactic: &httpsDialerTactic{
 									Address:        "162.55.247.208",
-									InitialDelay:   0,
 									Port:           "443",
 									SNI:            "www.example.org",
 									VerifyHostname: "api.ooni.io",
@@ -406,7 +398,6 @@ func TestLoadStatsContainer(t *testing.T) {
 								LastUpdated: time.Time{}, // zero value!
 								Tactic: &httpsDialerTactic{
 									Address:        "162.55.247.208",
-									InitialDelay:   0,
 									Port:           "443",
 									SNI:            "www.example.org",
 									VerifyHostname: "api.ooni.io",
@@ -454,7 +445,6 @@ func TestLoadStatsContainer(t *testing.T) {
 								LastUpdated: twoWeeksAgo,
 								Tactic: &httpsDialerTactic{
 									Address:        "162.55.247.208",
-									InitialDelay:   0,
 									Port:           "443",
 									SNI:            "www.example.com",
 									VerifyHostname: "www.kernel.org",
@@ -491,7 +481,6 @@ func TestLoadStatsContainer(t *testing.T) {
 							LastUpdated: fourtyFiveMinutesAgo,
 							Tactic: &httpsDialerTactic{
 								Address:        "162.55.247.208",
-								InitialDelay:   0,
 								Port:           "443",
 								SNI:            "www.example.com",
 								VerifyHostname: "api.ooni.io",
@@ -563,7 +552,6 @@ func TestStatsManagerCallbacks(t *testing.T) {
 								LastUpdated:  fourtyFiveMinutesAgo,
 								Tactic: &httpsDialerTactic{
 									Address:        "162.55.247.208",
-									InitialDelay:   0,
 									Port:           "443",
 									SNI:            "www.example.com",
 									VerifyHostname: "api.ooni.io",
@@ -580,7 +568,6 @@ func TestStatsManagerCallbacks(t *testing.T) {
 
 				tactic := &httpsDialerTactic{
 					Address:        "162.55.247.208",
-					InitialDelay:   0,
 					Port:           "443",
 					SNI:            "www.example.com",
 					VerifyHostname: "api.ooni.io",
@@ -599,7 +586,6 @@ func TestStatsManagerCallbacks(t *testing.T) {
 								CountTCPConnectInterrupt: 1,
 								Tactic: &httpsDialerTactic{
 									Address:        "162.55.247.208",
-									InitialDelay:   0,
 									Port:           "443",
 									SNI:            "www.example.com",
 									VerifyHostname: "api.ooni.io",
@@ -624,7 +610,6 @@ func TestStatsManagerCallbacks(t *testing.T) {
 
 				tactic := &httpsDialerTactic{
 					Address:        "162.55.247.208",
-					InitialDelay:   0,
 					Port:           "443",
 					SNI:            "www.example.com",
 					VerifyHostname: "api.ooni.io",
@@ -652,7 +637,6 @@ func TestStatsManagerCallbacks(t *testing.T) {
 								LastUpdated:  fourtyFiveMinutesAgo,
 								Tactic: &httpsDialerTactic{
 									Address:        "162.55.247.208",
-									InitialDelay:   0,
 									Port:           "443",
 									SNI:            "www.example.com",
 									VerifyHostname: "api.ooni.io",
@@ -669,7 +653,6 @@ func TestStatsManagerCallbacks(t *testing.T) {
 
 				tactic := &httpsDialerTactic{
 					Address:        "162.55.247.208",
-					InitialDelay:   0,
 					Port:           "443",
 					SNI:            "www.example.com",
 					VerifyHostname: "api.ooni.io",
@@ -688,7 +671,6 @@ func TestStatsManagerCallbacks(t *testing.T) {
 								CountTLSHandshakeInterrupt: 1,
 								Tactic: &httpsDialerTactic{
 									Address:        "162.55.247.208",
-									InitialDelay:   0,
 									Port:           "443",
 									SNI:            "www.example.com",
 									VerifyHostname: "api.ooni.io",
@@ -713,7 +695,6 @@ func TestStatsManagerCallbacks(t *testing.T) {
 
 				tactic := &httpsDialerTactic{
 					Address:        "162.55.247.208",
-					InitialDelay:   0,
 					Port:           "443",
 					SNI:            "www.example.com",
 					VerifyHostname: "api.ooni.io",
@@ -739,7 +720,6 @@ func TestStatsManagerCallbacks(t *testing.T) {
 			do: func(stats *statsManager) {
 				tactic := &httpsDialerTactic{
 					Address:        "162.55.247.208",
-					InitialDelay:   0,
 					Port:           "443",
 					SNI:            "www.example.com",
 					VerifyHostname: "api.ooni.io",
@@ -755,6 +735,154 @@ func TestStatsManagerCallbacks(t *testing.T) {
 			},
 		},
 
+		// When the QUIC handshake fails and the reason is a canceled context
+		{
+			name: "OnQUICHandshakeError with ctx.Error() != nil",
+			initialRoot: &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{
+					"api.ooni.io:443": {
+						Tactics: map[string]*statsTactic{
+							"162.55.247.208:443 sni=www.example.com verify=api.ooni.io transport=quic": {
+								CountStarted: 1,
+								LastUpdated:  fourtyFiveMinutesAgo,
+								Tactic: &httpsDialerTactic{
+									Address:        "162.55.247.208",
+									Port:           "443",
+									SNI:            "www.example.com",
+									VerifyHostname: "api.ooni.io",
+									Transport:      tacticTransportQUIC,
+								},
+							},
+						},
+					},
+				},
+				Version: statsContainerVersion,
+			},
+			do: func(stats *statsManager) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel() // immediately!
+
+				tactic := &httpsDialerTactic{
+					Address:        "162.55.247.208",
+					Port:           "443",
+					SNI:            "www.example.com",
+					VerifyHostname: "api.ooni.io",
+					Transport:      tacticTransportQUIC,
+				}
+				err := errors.New("generic_timeout_error")
+
+				stats.OnQUICHandshakeError(ctx, tactic, err)
+			},
+			expectWarnf: 0,
+			expectRoot: &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{
+					"api.ooni.io:443": {
+						Tactics: map[string]*statsTactic{
+							"162.55.247.208:443 sni=www.example.com verify=api.ooni.io transport=quic": {
+								CountStarted:                1,
+								CountQUICHandshakeInterrupt: 1,
+								Tactic: &httpsDialerTactic{
+									Address:        "162.55.247.208",
+									Port:           "443",
+									SNI:            "www.example.com",
+									VerifyHostname: "api.ooni.io",
+									Transport:      tacticTransportQUIC,
+								},
+							},
+						},
+					},
+				},
+				Version: statsContainerVersion,
+			},
+		},
+
+		// When the QUIC handshake fails with an error
+		{
+			name: "OnQUICHandshakeError with an error",
+			initialRoot: &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{
+					"api.ooni.io:443": {
+						Tactics: map[string]*statsTactic{
+							"162.55.247.208:443 sni=www.example.com verify=api.ooni.io transport=quic": {
+								CountStarted: 1,
+								LastUpdated:  fourtyFiveMinutesAgo,
+								Tactic: &httpsDialerTactic{
+									Address:        "162.55.247.208",
+									Port:           "443",
+									SNI:            "www.example.com",
+									VerifyHostname: "api.ooni.io",
+									Transport:      tacticTransportQUIC,
+								},
+							},
+						},
+					},
+				},
+				Version: statsContainerVersion,
+			},
+			do: func(stats *statsManager) {
+				tactic := &httpsDialerTactic{
+					Address:        "162.55.247.208",
+					Port:           "443",
+					SNI:            "www.example.com",
+					VerifyHostname: "api.ooni.io",
+					Transport:      tacticTransportQUIC,
+				}
+				err := errors.New("generic_timeout_error")
+
+				stats.OnQUICHandshakeError(context.Background(), tactic, err)
+			},
+			expectWarnf: 0,
+			expectRoot: &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{
+					"api.ooni.io:443": {
+						Tactics: map[string]*statsTactic{
+							"162.55.247.208:443 sni=www.example.com verify=api.ooni.io transport=quic": {
+								CountStarted:            1,
+								CountQUICHandshakeError: 1,
+								HistoQUICHandshakeError: map[string]int64{
+									"generic_timeout_error": 1,
+								},
+								Tactic: &httpsDialerTactic{
+									Address:        "162.55.247.208",
+									Port:           "443",
+									SNI:            "www.example.com",
+									VerifyHostname: "api.ooni.io",
+									Transport:      tacticTransportQUIC,
+								},
+							},
+						},
+					},
+				},
+				Version: statsContainerVersion,
+			},
+		},
+
+		// When the QUIC handshake fails and we don't already have a policy record
+		{
+			name: "OnQUICHandshakeError when we are missing the stats record for the domain",
+			initialRoot: &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{},
+				Version:         statsContainerVersion,
+			},
+			do: func(stats *statsManager) {
+				tactic := &httpsDialerTactic{
+					Address:        "162.55.247.208",
+					Port:           "443",
+					SNI:            "www.example.com",
+					VerifyHostname: "api.ooni.io",
+					Transport:      tacticTransportQUIC,
+				}
+				err := errors.New("generic_timeout_error")
+
+				stats.OnQUICHandshakeError(context.Background(), tactic, err)
+			},
+			expectWarnf: 1,
+			expectRoot: &statsContainer{
+				DomainEndpoints: map[string]*statsDomainEndpoint{},
+				Version:         statsContainerVersion,
+			},
+		},
+
 		// With success when we don't already have a policy record
 		{
 			name: "OnSuccess when we are missing the stats record for the domain",
@@ -765,7 +893,6 @@ func TestStatsManagerCallbacks(t *testing.T) {
 			do: func(stats *statsManager) {
 				tactic := &httpsDialerTactic{
 					Address:        "162.55.247.208",
-					InitialDelay:   0,
 					Port:           "443",
 					SNI:            "www.example.com",
 					VerifyHostname: "api.ooni.io",
@@ -856,7 +983,6 @@ func TestStatsManagerLookupTactics(t *testing.T) {
 		LastUpdated:                twentyMinutesAgo,
 		Tactic: &httpsDialerTactic{
 			Address:        "162.55.247.208",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "www.repubblica.it",
 			VerifyHostname: "api.ooni.io",
@@ -875,7 +1001,6 @@ func TestStatsManagerLookupTactics(t *testing.T) {
 		LastUpdated:                twentyMinutesAgo,
 		Tactic: &httpsDialerTactic{
 			Address:        "162.55.247.208",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "www.kernel.org",
 			VerifyHostname: "api.ooni.io",
@@ -894,7 +1019,6 @@ func TestStatsManagerLookupTactics(t *testing.T) {
 		LastUpdated:                twentyMinutesAgo,
 		Tactic: &httpsDialerTactic{
 			Address:        "162.55.247.208",
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "theconversation.com",
 			VerifyHostname: "api.ooni.io",
@@ -1059,7 +1183,6 @@ func TestStatsContainer(t *testing.T) {
 			}
 			tactic := &httpsDialerTactic{
 				Address:        "162.55.247.208",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "www.example.com",
 				VerifyHostname: "api.ooni.io",
@@ -1145,7 +1268,6 @@ func TestStatsDefensivelySortTacticsByDescendingSuccessRateWithAcceptPredicate(t
 			LastUpdated:  now.Add(-5 * time.Second),
 			Tactic: &httpsDialerTactic{
 				Address:        "130.192.91.211",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "www.repubblica.it",
 				VerifyHostname: "shelob.polito.it",
@@ -1160,7 +1282,6 @@ func TestStatsDefensivelySortTacticsByDescendingSuccessRateWithAcceptPredicate(t
 			LastUpdated:  now.Add(-5 * time.Second),
 			Tactic: &httpsDialerTactic{
 				Address:        "130.192.91.211",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "www.ilfattoquotidiano.it",
 				VerifyHostname: "shelob.polito.it",
@@ -1175,7 +1296,6 @@ func TestStatsDefensivelySortTacticsByDescendingSuccessRateWithAcceptPredicate(t
 			LastUpdated:  now.Add(-7 * time.Second),
 			Tactic: &httpsDialerTactic{
 				Address:        "130.192.91.211",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "www.ilpost.it",
 				VerifyHostname: "shelob.polito.it",
@@ -1189,7 +1309,6 @@ func TestStatsDefensivelySortTacticsByDescendingSuccessRateWithAcceptPredicate(t
 			LastUpdated:  now.Add(-2 * time.Second),
 			Tactic: &httpsDialerTactic{
 				Address:        "130.192.91.211",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "www.polito.it",
 				VerifyHostname: "shelob.polito.it",
@@ -1240,7 +1359,6 @@ func TestStatsDefensivelySortTacticsByDescendingSuccessRateWithAcceptPredicate(t
 			LastUpdated:  now.Add(-130 * time.Millisecond),
 			Tactic: &httpsDialerTactic{
 				Address:        "130.192.91.211",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "kernel.org",
 				VerifyHostname: "shelob.polito.it",
@@ -1302,7 +1420,6 @@ func TestStatsDomainEndpointPruneEntries(t *testing.T) {
 					LastUpdated:  now.Add(-24 * time.Hour * 8),
 					Tactic: &httpsDialerTactic{
 						Address:        "130.192.91.211",
-						InitialDelay:   0,
 						Port:           "443",
 						SNI:            "polito.it",
 						VerifyHostname: "shelob.polito.it",
@@ -1314,7 +1431,6 @@ func TestStatsDomainEndpointPruneEntries(t *testing.T) {
 					LastUpdated:  now.Add(-24 * time.Hour * 6),
 					Tactic: &httpsDialerTactic{
 						Address:        "130.192.91.211",
-						InitialDelay:   0,
 						Port:           "443",
 						SNI:            "garr.it",
 						VerifyHostname: "shelob.polito.it",
@@ -1331,7 +1447,6 @@ func TestStatsDomainEndpointPruneEntries(t *testing.T) {
 					LastUpdated:  now.Add(-24 * time.Hour * 6),
 					Tactic: &httpsDialerTactic{
 						Address:        "130.192.91.211",
-						InitialDelay:   0,
 						Port:           "443",
 						SNI:            "garr.it",
 						VerifyHostname: "shelob.polito.it",
@@ -1369,7 +1484,6 @@ func TestStatsDomainEndpointPruneEntries(t *testing.T) {
 				LastUpdated: now.Add(-time.Duration(idx) * time.Second),
 				Tactic: &httpsDialerTactic{
 					Address:        "130.192.91.211",
-					InitialDelay:   0,
 					Port:           "443",
 					SNI:            fmt.Sprintf("host%d.garr.it", idx),
 					VerifyHostname: "shelob.polito.it",
@@ -1393,7 +1507,6 @@ func TestStatsDomainEndpointPruneEntries(t *testing.T) {
 				LastUpdated: now.Add(-time.Duration(idx) * time.Second),
 				Tactic: &httpsDialerTactic{
 					Address:        "130.192.91.211",
-					InitialDelay:   0,
 					Port:           "443",
 					SNI:            fmt.Sprintf("host%d.garr.it", idx),
 					VerifyHostname: "shelob.polito.it",
@@ -1498,7 +1611,6 @@ func TestStatsContainerPruneEntries(t *testing.T) {
 							LastUpdated:  time.Time{}, // a long time ago!
 							Tactic: &httpsDialerTactic{
 								Address:        "130.192.91.211",
-								InitialDelay:   0,
 								Port:           "443",
 								SNI:            "garr.it",
 								VerifyHostname: "shelob.polito.it",
@@ -1529,7 +1641,6 @@ func TestStatsContainerPruneEntries(t *testing.T) {
 			LastUpdated:  time.Now().Add(-60 * time.Second), // recently
 			Tactic: &httpsDialerTactic{
 				Address:        "130.192.91.211",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "polito.it",
 				VerifyHostname: "shelob.polito.it",
@@ -1547,7 +1658,6 @@ func TestStatsContainerPruneEntries(t *testing.T) {
 							LastUpdated:  time.Time{}, // a long time ago!
 							Tactic: &httpsDialerTactic{
 								Address:        "130.192.91.211",
-								InitialDelay:   0,
 								Port:           "443",
 								SNI:            "garr.it",
 								VerifyHostname: "shelob.polito.it",
@@ -1598,7 +1708,6 @@ func TestStatsManagerTrimEntriesConcurrently(t *testing.T) {
 						LastUpdated:  time.Time{}, // a long time ago!
 						Tactic: &httpsDialerTactic{
 							Address:        "130.192.91.211",
-							InitialDelay:   0,
 							Port:           "443",
 							SNI:            "garr.it",
 							VerifyHostname: "shelob.polito.it",
@@ -1633,3 +1742,41 @@ func TestStatsManagerTrimEntriesConcurrently(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestStatsManagerSummary(t *testing.T) {
+	// create a stats manager with an empty container
+	const trimInterval = 30 * time.Second
+	stats := newStatsManager(&kvstore.Memory{}, log.Log, trimInterval)
+	defer stats.Close()
+
+	// fill the container including nil entries that a user may have
+	// written by manually editing the stats on disk
+	stats.container.DomainEndpoints = map[string]*statsDomainEndpoint{
+		"www.example.com:443": {
+			Tactics: map[string]*statsTactic{
+				"a": {CountStarted: 4, CountSuccess: 1},
+				"b": nil,
+			},
+		},
+		"api.ooni.io:443": {
+			Tactics: map[string]*statsTactic{
+				"a": {CountStarted: 5, CountSuccess: 5},
+				"b": {CountStarted: 3, CountSuccess: 1},
+			},
+		},
+		"www.example.org:443": nil,
+	}
+
+	expect := []*DomainEndpointStats{{
+		DomainEndpoint: "api.ooni.io:443",
+		CountStarted:   8,
+		CountSuccess:   6,
+	}, {
+		DomainEndpoint: "www.example.com:443",
+		CountStarted:   4,
+		CountSuccess:   1,
+	}}
+	if diff := cmp.Diff(expect, stats.Summary()); diff != "" {
+		t.Fatal(diff)
+	}
+}
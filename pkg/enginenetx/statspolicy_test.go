@@ -33,7 +33,6 @@ func TestStatsPolicyV2(t *testing.T) {
 		LastUpdated:                twentyMinutesAgo,
 		Tactic: &httpsDialerTactic{
 			Address:        bridgeAddress,
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "www.repubblica.it",
 			VerifyHostname: "api.ooni.io",
@@ -52,7 +51,6 @@ func TestStatsPolicyV2(t *testing.T) {
 		LastUpdated:                twentyMinutesAgo,
 		Tactic: &httpsDialerTactic{
 			Address:        bridgeAddress,
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "www.kernel.org",
 			VerifyHostname: "api.ooni.io",
@@ -71,7 +69,6 @@ func TestStatsPolicyV2(t *testing.T) {
 		LastUpdated:                twentyMinutesAgo,
 		Tactic: &httpsDialerTactic{
 			Address:        bridgeAddress,
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "theconversation.com",
 			VerifyHostname: "api.ooni.io",
@@ -103,7 +100,6 @@ func TestStatsPolicyV2(t *testing.T) {
 		LastUpdated:                time.Time{}, // the zero time should exclude this one
 		Tactic: &httpsDialerTactic{
 			Address:        bridgeAddress,
-			InitialDelay:   0,
 			Port:           "443",
 			SNI:            "ilpost.it",
 			VerifyHostname: "api.ooni.io",
@@ -158,9 +154,7 @@ func TestStatsPolicyV2(t *testing.T) {
 			if entry.CountSuccess <= 0 || entry.Tactic == nil {
 				continue // we SHOULD NOT include entries that systematically failed
 			}
-			t := entry.Tactic.Clone()
-			t.InitialDelay = 0
-			expect = append(expect, t)
+			expect = append(expect, entry.Tactic.Clone())
 		}
 
 		// perform the actual comparison
@@ -214,7 +208,6 @@ func TestStatsPolicyFilterStatsTactics(t *testing.T) {
 			LastUpdated: time.Now().Add(-11 * time.Second),
 			Tactic: &httpsDialerTactic{
 				Address:        "130.192.91.211",
-				InitialDelay:   0,
 				Port:           "443",
 				SNI:            "garr.it",
 				VerifyHostname: "shelob.polito.it",
@@ -258,7 +251,6 @@ func TestStatsPolicyFilterStatsTactics(t *testing.T) {
 				LastUpdated: time.Now().Add(-4 * time.Second),
 				Tactic: &httpsDialerTactic{
 					Address:        "130.192.91.211",
-					InitialDelay:   0,
 					Port:           "443",
 					SNI:            "polito.it",
 					VerifyHostname: "shelob.polito.it",
@@ -0,0 +1,197 @@
+package enginenetx
+
+//
+// Policy rewriting the SNI of the tactics generated by another policy
+// using the SNIs that previously worked according to the stats.
+//
+
+import (
+	"context"
+	"slices"
+	"sort"
+)
+
+// statsSNIPolicyMaxKnownSNIs is the maximum number of SNIs known to work
+// that we use for rewriting each tactic emitted by the child policy.
+const statsSNIPolicyMaxKnownSNIs = 3
+
+// statsSNIPolicyMaxExploredSNIs is the maximum number of SNIs we have never
+// tried before that we use during each call to LookupTactics.
+const statsSNIPolicyMaxExploredSNIs = 2
+
+// statsSNIPolicy is a policy that rewrites the SNI of the tactics emitted by
+// the child policy (typically the [*dnsPolicy]) using the SNIs that previously
+// worked for the same domain endpoint, regardless of the IP address.
+//
+// When the stats do not tell us that the real SNI works, we also explore a
+// bounded number of SNIs that we have never tried before, such that the
+// stats can learn about which SNIs work for the domain endpoint.
+//
+// The zero value is invalid; please, init MANDATORY fields.
+type statsSNIPolicy struct {
+	// Child is the MANDATORY child policy.
+	Child httpsDialerPolicy
+
+	// Stats is the MANDATORY stats manager.
+	Stats *statsManager
+}
+
+var _ httpsDialerPolicy = &statsSNIPolicy{}
+
+// LookupTactics implements httpsDialerPolicy.
+func (p *statsSNIPolicy) LookupTactics(ctx context.Context, domain, port string) <-chan *httpsDialerTactic {
+	out := make(chan *httpsDialerTactic)
+
+	go func() {
+		// tell the parent when we're done
+		defer close(out)
+
+		// figure out which SNIs worked and which SNIs we already tried
+		stats, good := p.Stats.LookupTactics(domain, port)
+		known, tried := statsSNIPolicyAnalyzeStats(domain, stats, good)
+
+		// collect tactics that we may want to modify later
+		var todo []*httpsDialerTactic
+
+		for tactic := range p.Child.LookupTactics(ctx, domain, port) {
+			// We only rewrite tactics using the real SNI with the TCP transport
+			// and without fragmentation; we pass along everything else
+			if !statsSNIPolicyCanRewrite(tactic, domain) {
+				out <- tactic
+				continue
+			}
+
+			// Note that we must clone before emitting, since the consumer
+			// may modify the tactic we have emitted
+			todo = append(todo, tactic.Clone())
+
+			// Emit the SNIs known to work first, emitting the original
+			// tactic in place of the real SNI if it's among them
+			var emitted bool
+			for _, sni := range known {
+				if sni == tactic.SNI {
+					emitted = true
+					out <- tactic
+					continue
+				}
+				out <- statsSNIPolicyRewrite(tactic, sni)
+			}
+
+			// Otherwise, emit the original tactic after the known SNIs
+			if !emitted {
+				out <- tactic
+			}
+		}
+
+		// There's no need to explore when we know the real SNI works
+		if slices.Contains(known, domain) {
+			return
+		}
+
+		// Otherwise, let's try a few SNIs we have never tried before.
+		explored := statsSNIPolicyExploredSNIs(tried)
+		for _, tactic := range todo {
+			for _, sni := range explored {
+				out <- statsSNIPolicyRewrite(tactic, sni)
+			}
+		}
+	}()
+
+	return out
+}
+
+// statsSNIPolicyCanRewrite returns whether we can rewrite the SNI of the given tactic.
+func statsSNIPolicyCanRewrite(tactic *httpsDialerTactic, domain string) bool {
+	return tactic.SNI == domain && tactic.VerifyHostname == domain &&
+		tactic.Fragment == fragmentNone && tactic.Transport == tacticTransportTCP
+}
+
+// statsSNIPolicyRewrite returns a copy of the tactic using the given SNI.
+func statsSNIPolicyRewrite(tactic *httpsDialerTactic, sni string) *httpsDialerTactic {
+	tactic = tactic.Clone()
+	tactic.SNI = sni
+	return tactic
+}
+
+// statsSNIPolicyAnalyzeStats returns the SNIs known to work sorted by descending
+// success rate and the set of SNIs we already tried. We compute the success rate of
+// each SNI by summing the stats of all the addresses where we used such a SNI.
+//
+// We consider a SNI tried when it succeeded or when it failed during the TLS
+// handshake or the TLS verification, because TCP connect errors and interrupted
+// operations do not tell us anything about the SNI.
+func statsSNIPolicyAnalyzeStats(
+	domain string, tactics []*statsTactic, good bool) (known []string, tried map[string]bool) {
+	tried = make(map[string]bool)
+
+	// when good is false, it means p.Stats.LookupTactics failed
+	if !good {
+		return
+	}
+
+	// aggregate the stats by SNI
+	var (
+		started = make(map[string]int64)
+		success = make(map[string]int64)
+	)
+	for _, st := range tactics {
+		// For robustness, be paranoid about nils here because the stats are
+		// written on the disk and a user could potentially edit them.
+		if st == nil || st.Tactic == nil {
+			continue
+		}
+
+		// only consider tactics equivalent to the ones we would rewrite
+		if st.Tactic.VerifyHostname != domain || st.Tactic.Fragment != fragmentNone ||
+			st.Tactic.Transport != tacticTransportTCP || st.CountStarted <= 0 {
+			continue
+		}
+
+		sni := st.Tactic.SNI
+		started[sni] += st.CountStarted
+		success[sni] += st.CountSuccess
+		if st.CountSuccess > 0 || st.CountTLSHandshakeError > 0 || st.CountTLSVerificationError > 0 {
+			tried[sni] = true
+		}
+	}
+
+	// only keep the SNIs with at least a success
+	for sni, count := range success {
+		if count > 0 {
+			known = append(known, sni)
+		}
+	}
+
+	// sort by descending success rate, then by descending number of
+	// successes, and finally by name, to have a deterministic order
+	sort.SliceStable(known, func(i, j int) bool {
+		ri := float64(success[known[i]]) / float64(started[known[i]])
+		rj := float64(success[known[j]]) / float64(started[known[j]])
+		if ri != rj {
+			return ri > rj
+		}
+		if success[known[i]] != success[known[j]] {
+			return success[known[i]] > success[known[j]]
+		}
+		return known[i] < known[j]
+	})
+
+	if len(known) > statsSNIPolicyMaxKnownSNIs {
+		known = known[:statsSNIPolicyMaxKnownSNIs]
+	}
+	return
+}
+
+// statsSNIPolicyExploredSNIs returns a bounded number of randomly selected
+// bridges SNIs that do not appear in the given set of tried SNIs.
+func statsSNIPolicyExploredSNIs(tried map[string]bool) (out []string) {
+	for _, sni := range bridgesDomainsInRandomOrder() {
+		if len(out) >= statsSNIPolicyMaxExploredSNIs {
+			break
+		}
+		if !tried[sni] {
+			out = append(out, sni)
+		}
+	}
+	return
+}
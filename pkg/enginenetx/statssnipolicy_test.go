@@ -0,0 +1,266 @@
+package enginenetx
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/kvstore"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+)
+
+// newStatsSNIPolicyTestStatsManager creates a stats manager given some baseline stats.
+func newStatsSNIPolicyTestStatsManager(t *testing.T, domainEndpoint string, tactics ...*statsTactic) *statsManager {
+	container := &statsContainer{
+		DomainEndpoints: map[string]*statsDomainEndpoint{
+			domainEndpoint: {
+				Tactics: map[string]*statsTactic{},
+			},
+		},
+		Version: statsContainerVersion,
+	}
+
+	for _, tx := range tactics {
+		container.DomainEndpoints[domainEndpoint].Tactics[tx.Tactic.tacticSummaryKey()] = tx
+	}
+
+	kvStore := &kvstore.Memory{}
+	if err := kvStore.Set(statsKey, runtimex.Try1(json.Marshal(container))); err != nil {
+		t.Fatal(err)
+	}
+
+	const trimInterval = 30 * time.Second
+	return newStatsManager(kvStore, log.Log, trimInterval)
+}
+
+// newStatsSNIPolicyTestStatsTactic creates a [*statsTactic] for api.ooni.io.
+func newStatsSNIPolicyTestStatsTactic(address, sni string, started, handshakeErrors, success int64) *statsTactic {
+	return &statsTactic{
+		CountStarted:           started,
+		CountTLSHandshakeError: handshakeErrors,
+		CountSuccess:           success,
+		LastUpdated:            time.Now().Add(-20 * time.Minute),
+		Tactic: &httpsDialerTactic{
+			Address:        address,
+			Port:           "443",
+			SNI:            sni,
+			VerifyHostname: "api.ooni.io",
+		},
+	}
+}
+
+func TestStatsSNIPolicy(t *testing.T) {
+	// dnsTactics contains the tactics returned by the child policy
+	dnsTactics := []*httpsDialerTactic{{
+		Address:        "130.192.91.211",
+		Port:           "443",
+		SNI:            "api.ooni.io",
+		VerifyHostname: "api.ooni.io",
+	}, {
+		Address:        "130.192.91.211",
+		Port:           "443",
+		SNI:            "api.ooni.io",
+		VerifyHostname: "api.ooni.io",
+		Fragment:       fragmentTLSRecords,
+	}}
+
+	// runPolicy runs the policy with the given stats and returns the tactics.
+	runPolicy := func(stats *statsManager) (out []*httpsDialerTactic) {
+		policy := &statsSNIPolicy{
+			Child: &mocksPolicy{
+				MockLookupTactics: func(ctx context.Context, domain, port string) <-chan *httpsDialerTactic {
+					// note: we clone to make sure the policy does not modify the originals
+					var tactics []*httpsDialerTactic
+					for _, tactic := range dnsTactics {
+						tactics = append(tactics, tactic.Clone())
+					}
+					return streamTacticsFromSlice(tactics)
+				},
+			},
+			Stats: stats,
+		}
+		for tactic := range policy.LookupTactics(context.Background(), "api.ooni.io", "443") {
+			out = append(out, tactic)
+		}
+		return
+	}
+
+	// withSNI returns a copy of the first DNS tactic using the given SNI.
+	withSNI := func(sni string) *httpsDialerTactic {
+		tactic := dnsTactics[0].Clone()
+		tactic.SNI = sni
+		return tactic
+	}
+
+	t.Run("when we do not have any stats", func(t *testing.T) {
+		tactics := runPolicy(newStatsSNIPolicyTestStatsManager(t, "api.ooni.io:443"))
+
+		// we expect to see the original tactics followed by the explored SNIs
+		if len(tactics) != 2+statsSNIPolicyMaxExploredSNIs {
+			t.Fatal("unexpected number of tactics", len(tactics))
+		}
+		if diff := cmp.Diff(dnsTactics, tactics[:2]); diff != "" {
+			t.Fatal(diff)
+		}
+		for _, tactic := range tactics[2:] {
+			if !slices.Contains(bridgesDomainsInRandomOrder(), tactic.SNI) {
+				t.Fatal("expected a bridges SNI, got", tactic.SNI)
+			}
+			if diff := cmp.Diff(withSNI(tactic.SNI), tactic); diff != "" {
+				t.Fatal(diff)
+			}
+		}
+	})
+
+	t.Run("when we know that the real SNI works", func(t *testing.T) {
+		stats := newStatsSNIPolicyTestStatsManager(
+			t, "api.ooni.io:443",
+			newStatsSNIPolicyTestStatsTactic("130.192.91.231", "api.ooni.io", 10, 0, 10),
+			newStatsSNIPolicyTestStatsTactic("130.192.91.231", "www.kernel.org", 10, 5, 5),
+		)
+		tactics := runPolicy(stats)
+
+		// we expect to see the known SNIs by descending success rate, without
+		// exploring, and the fragmented tactic passed along unmodified
+		expect := []*httpsDialerTactic{
+			dnsTactics[1],
+			withSNI("api.ooni.io"),
+			withSNI("www.kernel.org"),
+		}
+		slices.SortStableFunc(tactics, func(a, b *httpsDialerTactic) int {
+			// the fragmented tactic is passed along as soon as we see it, so
+			// we move it first to make the comparison easier
+			if a.Fragment != b.Fragment {
+				if a.Fragment != fragmentNone {
+					return -1
+				}
+				return 1
+			}
+			return 0
+		})
+		if diff := cmp.Diff(expect, tactics); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when we know that another SNI works", func(t *testing.T) {
+		stats := newStatsSNIPolicyTestStatsManager(
+			t, "api.ooni.io:443",
+			newStatsSNIPolicyTestStatsTactic("130.192.91.231", "api.ooni.io", 10, 10, 0),
+			newStatsSNIPolicyTestStatsTactic("130.192.91.231", "www.kernel.org", 10, 0, 10),
+		)
+		tactics := runPolicy(stats)
+
+		// we expect to see the SNI known to work first, then the original
+		// tactics, and then we expect to explore new SNIs
+		if len(tactics) != 3+statsSNIPolicyMaxExploredSNIs {
+			t.Fatal("unexpected number of tactics", len(tactics))
+		}
+		expect := []*httpsDialerTactic{
+			withSNI("www.kernel.org"),
+			dnsTactics[0],
+			dnsTactics[1],
+		}
+		if diff := cmp.Diff(expect, tactics[:3]); diff != "" {
+			t.Fatal(diff)
+		}
+		for _, tactic := range tactics[3:] {
+			if tactic.SNI == "www.kernel.org" || tactic.SNI == "api.ooni.io" {
+				t.Fatal("we should not explore SNIs we already tried", tactic.SNI)
+			}
+		}
+	})
+}
+
+func TestStatsSNIPolicyAnalyzeStats(t *testing.T) {
+	t.Run("when the stats lookup failed", func(t *testing.T) {
+		known, tried := statsSNIPolicyAnalyzeStats("api.ooni.io", nil, false)
+		if len(known) != 0 || len(tried) != 0 {
+			t.Fatal("expected empty results")
+		}
+	})
+
+	t.Run("with several entries", func(t *testing.T) {
+		fragmented := newStatsSNIPolicyTestStatsTactic("130.192.91.211", "www.example.com", 10, 0, 10)
+		fragmented.Tactic.Fragment = fragmentTCPSegments
+
+		quic := newStatsSNIPolicyTestStatsTactic("130.192.91.211", "www.example.org", 10, 0, 10)
+		quic.Tactic.Transport = tacticTransportQUIC
+
+		otherDomain := newStatsSNIPolicyTestStatsTactic("130.192.91.211", "www.example.net", 10, 0, 10)
+		otherDomain.Tactic.VerifyHostname = "www.example.net"
+
+		// the interrupted entry did not succeed nor fail
+		interrupted := newStatsSNIPolicyTestStatsTactic("130.192.91.211", "www.torproject.org", 1, 0, 0)
+		interrupted.CountTLSHandshakeInterrupt = 1
+
+		input := []*statsTactic{
+			nil,
+			{Tactic: nil},
+			fragmented,
+			quic,
+			otherDomain,
+			interrupted,
+
+			// the aggregated success rate of www.kernel.org is 0.5
+			newStatsSNIPolicyTestStatsTactic("130.192.91.211", "www.kernel.org", 10, 10, 0),
+			newStatsSNIPolicyTestStatsTactic("130.192.91.231", "www.kernel.org", 10, 0, 10),
+
+			// these have the same success rate but a different number of successes
+			newStatsSNIPolicyTestStatsTactic("130.192.91.211", "www.repubblica.it", 2, 0, 2),
+			newStatsSNIPolicyTestStatsTactic("130.192.91.211", "api.ooni.io", 4, 0, 4),
+
+			// this has the same success rate and number of successes of api.ooni.io
+			newStatsSNIPolicyTestStatsTactic("130.192.91.211", "ilpost.it", 4, 0, 4),
+
+			// this one always failed
+			newStatsSNIPolicyTestStatsTactic("130.192.91.211", "theconversation.com", 3, 3, 0),
+		}
+
+		known, tried := statsSNIPolicyAnalyzeStats("api.ooni.io", input, true)
+
+		// note that the list is truncated and www.kernel.org is excluded
+		expectKnown := []string{"api.ooni.io", "ilpost.it", "www.repubblica.it"}
+		if diff := cmp.Diff(expectKnown, known); diff != "" {
+			t.Fatal(diff)
+		}
+
+		expectTried := map[string]bool{
+			"api.ooni.io":         true,
+			"ilpost.it":           true,
+			"theconversation.com": true,
+			"www.kernel.org":      true,
+			"www.repubblica.it":   true,
+		}
+		if diff := cmp.Diff(expectTried, tried); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestStatsSNIPolicyExploredSNIs(t *testing.T) {
+	t.Run("we skip the SNIs we already tried", func(t *testing.T) {
+		// mark all SNIs as tried except for the last one
+		all := bridgesDomainsInRandomOrder()
+		tried := make(map[string]bool)
+		for _, sni := range all[1:] {
+			tried[sni] = true
+		}
+
+		explored := statsSNIPolicyExploredSNIs(tried)
+		if diff := cmp.Diff([]string{all[0]}, explored); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we return a bounded number of SNIs", func(t *testing.T) {
+		explored := statsSNIPolicyExploredSNIs(map[string]bool{})
+		if len(explored) != statsSNIPolicyMaxExploredSNIs {
+			t.Fatal("unexpected number of SNIs", len(explored))
+		}
+	})
+}
@@ -48,6 +48,18 @@ func (p *testHelpersPolicy) LookupTactics(ctx context.Context, domain, port stri
 				continue
 			}
 
+			// Likewise, there's no need to change the SNI of tactics that
+			// are fragmenting the ClientHello to hide the real SNI
+			if tactic.Fragment != fragmentNone {
+				continue
+			}
+
+			// Also, there's no need to change the SNI of tactics that are
+			// already using another SNI (e.g., after [*statsSNIPolicy])
+			if tactic.SNI != tactic.VerifyHostname {
+				continue
+			}
+
 			// otherwise, let's rememeber to modify this later
 			todo = append(todo, tactic)
 		}
@@ -58,7 +70,6 @@ func (p *testHelpersPolicy) LookupTactics(ctx context.Context, domain, port stri
 			for _, sni := range bridgesDomainsInRandomOrder() {
 				out <- &httpsDialerTactic{
 					Address:        tactic.Address,
-					InitialDelay:   0, // set when dialing
 					Port:           tactic.Port,
 					SNI:            sni,
 					VerifyHostname: tactic.VerifyHostname,
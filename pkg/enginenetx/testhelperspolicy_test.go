@@ -12,13 +12,11 @@ func TestTestHelpersPolicy(t *testing.T) {
 	// testHelperTactics contains tactics related to test helpers
 	testHelperTactics := []*httpsDialerTactic{{
 		Address:        "18.195.190.71",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "0.th.ooni.org",
 		VerifyHostname: "0.th.ooni.org",
 	}, {
 		Address:        "18.198.214.127",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "0.th.ooni.org",
 		VerifyHostname: "0.th.ooni.org",
@@ -27,13 +25,11 @@ func TestTestHelpersPolicy(t *testing.T) {
 	// wwwExampleComTactics contains tactics related to www.example.com
 	wwwExampleComTactic := []*httpsDialerTactic{{
 		Address:        "93.184.215.14",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "www.example.com",
 		VerifyHostname: "www.example.com",
 	}, {
 		Address:        "2606:2800:21f:cb07:6820:80da:af6b:8b2c",
-		InitialDelay:   0,
 		Port:           "443",
 		SNI:            "www.example.com",
 		VerifyHostname: "www.example.com",
@@ -71,6 +67,27 @@ func TestTestHelpersPolicy(t *testing.T) {
 		childTactics: testHelperTactics,
 		domain:       testHelperTactics[0].VerifyHostname,
 		expectExtra:  304,
+	}, {
+		name: "when the children returns a TH domain with fragmentation",
+		childTactics: []*httpsDialerTactic{{
+			Address:        "18.195.190.71",
+			Port:           "443",
+			SNI:            "0.th.ooni.org",
+			VerifyHostname: "0.th.ooni.org",
+			Fragment:       fragmentTCPSegments,
+		}},
+		domain:      "0.th.ooni.org",
+		expectExtra: 0,
+	}, {
+		name: "when the children returns a TH domain with another SNI",
+		childTactics: []*httpsDialerTactic{{
+			Address:        "18.195.190.71",
+			Port:           "443",
+			SNI:            "www.example.com",
+			VerifyHostname: "0.th.ooni.org",
+		}},
+		domain:      "0.th.ooni.org",
+		expectExtra: 0,
 	}}
 
 	for _, tc := range cases {
@@ -58,6 +58,19 @@ func newUserPolicyV2(kvStore model.KeyValueStore) (*userPolicyV2, error) {
 		return nil, err
 	}
 
+	// make sure we know about the fragmentation kinds, so that we do not
+	// silently dial using a different kind than the user asked for
+	for endpoint, tactics := range root.DomainEndpoints {
+		for _, tactic := range tactics {
+			if tactic == nil {
+				continue
+			}
+			if err := fragmentValidateKind(tactic.Fragment); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", userPolicyKey, endpoint, err)
+			}
+		}
+	}
+
 	out := &userPolicyV2{Root: &root}
 	return out, nil
 }
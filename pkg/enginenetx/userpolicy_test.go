@@ -55,6 +55,23 @@ func TestUserPolicyV2(t *testing.T) {
 			input:          []byte(`{}`),
 			expectErr:      "bridges.conf: wrong user policy version: expected=3 got=0",
 			expectedPolicy: nil,
+		}, {
+			name: "with unknown fragmentation kind",
+			key:  userPolicyKey,
+			input: []byte(`{
+				"DomainEndpoints": {
+					"api.ooni.io:443": [{
+						"Address": "162.55.247.208",
+						"Port": "443",
+						"SNI": "api.ooni.io",
+						"VerifyHostname": "api.ooni.io",
+						"Fragment": "tls-record"
+					}]
+				},
+				"Version": 3
+			}`),
+			expectErr:      `bridges.conf: api.ooni.io:443: unknown ClientHello fragmentation kind: "tls-record"`,
+			expectedPolicy: nil,
 		}, {
 			name: "with real serialized policy",
 			key:  userPolicyKey,
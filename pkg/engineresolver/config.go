@@ -0,0 +1,211 @@
+package engineresolver
+
+//
+// User configuration - the possibility of loading from a JSON document
+// named `resolvers.conf` in $OONI_HOME/engine the list of resolvers
+// that the user would like to add, pin, or disable.
+//
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/ooni/probe-engine/pkg/hujsonx"
+	"github.com/ooni/probe-engine/pkg/kvstore"
+)
+
+// configKey is the kvstore key used to retrieve the user configuration.
+const configKey = "resolvers.conf"
+
+// configVersion is the current version of the user configuration.
+const configVersion = 1
+
+// configAddedScore is the initial score of the resolvers added by the user, which
+// is the maximum score, such that we try them early until they fail.
+const configAddedScore = 1.0
+
+// errConfigWrongVersion means that the user configuration has the wrong version number.
+var errConfigWrongVersion = errors.New("sessionresolver: wrong resolvers.conf version")
+
+// errConfigInvalidEntry means that the user configuration contains an invalid entry.
+var errConfigInvalidEntry = errors.New("sessionresolver: invalid resolvers.conf entry")
+
+// errConfigNoEnabledEntries means that the user configuration disables all the resolvers.
+var errConfigNoEnabledEntries = errors.New("sessionresolver: resolvers.conf disables all resolvers")
+
+// configEntry is an entry of the user configuration.
+type configEntry struct {
+	// URL is the MANDATORY resolver URL. We support DNS-over-HTTPS URLs using
+	// the https scheme, the http3 scheme for using DNS-over-HTTPS with HTTP/3,
+	// and the system:/// URL for using the system resolver. When the URL is
+	// not one of the built-in URLs, we add the resolver to the list.
+	URL string
+
+	// Pinned OPTIONALLY indicates that we should always try this resolver
+	// before the resolvers that are not pinned regardless of the score. We try
+	// pinned resolvers in the order in which they appear in the configuration.
+	Pinned bool
+
+	// Disabled OPTIONALLY indicates that we should never use this resolver.
+	Disabled bool
+}
+
+// configRoot is the root of the user configuration.
+type configRoot struct {
+	// Resolvers contains the configured resolvers.
+	Resolvers []*configEntry
+
+	// Version is the data structure version.
+	Version int
+}
+
+// readconfig reads the user configuration from the kvstore. The typical
+// error case is the one in which there's no configKey in the kvstore.
+func (r *Resolver) readconfig() (*configRoot, error) {
+	if r.KVStore == nil {
+		return nil, ErrNilKVStore
+	}
+	data, err := r.KVStore.Get(configKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// use human-readable JSON so that users can add comments
+	var root configRoot
+	if err := hujsonx.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if err := root.validate(); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// readconfigdefault is like readconfig but returns an empty configuration on error. We
+// only emit a warning when the configuration exists but is not valid.
+func (r *Resolver) readconfigdefault() *configRoot {
+	root, err := r.readconfig()
+	if err != nil {
+		if !errors.Is(err, kvstore.ErrNoSuchKey) && !errors.Is(err, ErrNilKVStore) {
+			r.logger().Warnf("sessionresolver: ignoring %s: %s", configKey, err.Error())
+		}
+		return &configRoot{Version: configVersion}
+	}
+	return root
+}
+
+// validate returns an error if the configuration is not valid.
+func (c *configRoot) validate() error {
+	if c.Version != configVersion {
+		return fmt.Errorf("%w: expected=%d got=%d", errConfigWrongVersion, configVersion, c.Version)
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range c.Resolvers {
+		if e == nil {
+			return fmt.Errorf("%w: null entry", errConfigInvalidEntry)
+		}
+		if err := configValidateURL(e.URL); err != nil {
+			return fmt.Errorf("%w: %s: %s", errConfigInvalidEntry, e.URL, err.Error())
+		}
+		if seen[e.URL] {
+			return fmt.Errorf("%w: %s: duplicate entry", errConfigInvalidEntry, e.URL)
+		}
+		seen[e.URL] = true
+		if e.Pinned && e.Disabled {
+			return fmt.Errorf("%w: %s: both pinned and disabled", errConfigInvalidEntry, e.URL)
+		}
+	}
+
+	// make sure there's at least a resolver we can use
+	for _, e := range allmakers {
+		if !c.disabled(e.url) {
+			return nil
+		}
+	}
+	if len(c.added()) > 0 {
+		return nil
+	}
+	return errConfigNoEnabledEntries
+}
+
+// errConfigUnsupportedURL indicates that a URL is not supported.
+var errConfigUnsupportedURL = errors.New("unsupported URL")
+
+// configValidateURL returns an error if we cannot use the given resolver URL.
+func configValidateURL(URL string) error {
+	parsed, err := url.Parse(URL)
+	if err != nil {
+		return err
+	}
+	switch {
+	case URL == systemResolverURL:
+		return nil
+	case (parsed.Scheme == "https" || parsed.Scheme == "http3") && parsed.Host != "":
+		return nil
+	default:
+		return errConfigUnsupportedURL
+	}
+}
+
+// disabled returns whether the user disabled the given URL.
+func (c *configRoot) disabled(URL string) bool {
+	for _, e := range c.Resolvers {
+		if e.URL == URL {
+			return e.Disabled
+		}
+	}
+	return false
+}
+
+// added returns the URLs added by the user, excluding the built-in ones.
+func (c *configRoot) added() (out []string) {
+	for _, e := range c.Resolvers {
+		if _, found := allbyurl[e.URL]; !found && !e.Disabled {
+			out = append(out, e.URL)
+		}
+	}
+	return
+}
+
+// supports returns whether we should keep the given URL, i.e., whether it
+// is a built-in or added URL that the user did not disable.
+func (c *configRoot) supports(URL string) bool {
+	if c.disabled(URL) {
+		return false
+	}
+	if _, found := allbyurl[URL]; found {
+		return true
+	}
+	for _, e := range c.Resolvers {
+		if e.URL == URL {
+			return true
+		}
+	}
+	return false
+}
+
+// movepinned moves the pinned entries, in the order in which they appear inside
+// the configuration, at the beginning of ri and returns their number.
+func (c *configRoot) movepinned(ri []*resolverinfo) int {
+	byurl := make(map[string]*resolverinfo)
+	for _, e := range ri {
+		byurl[e.URL] = e
+	}
+	var out []*resolverinfo
+	for _, e := range c.Resolvers {
+		if entry, found := byurl[e.URL]; found && e.Pinned {
+			out = append(out, entry)
+			delete(byurl, e.URL)
+		}
+	}
+	pinned := len(out)
+	for _, e := range ri {
+		if _, found := byurl[e.URL]; found {
+			out = append(out, e)
+		}
+	}
+	copy(ri, out)
+	return pinned
+}
@@ -0,0 +1,320 @@
+package engineresolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/kvstore"
+	"github.com/ooni/probe-engine/pkg/mocks"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+)
+
+func TestReadConfig(t *testing.T) {
+	// testcase is a test case implemented by this function
+	type testcase struct {
+		// name is the test case name
+		name string
+
+		// input is the OPTIONAL content of resolvers.conf
+		input []byte
+
+		// expectErr is the error we expect
+		expectErr error
+
+		// expectRoot is the root we expect on success
+		expectRoot *configRoot
+	}
+
+	cases := []testcase{{
+		name:      "when there is no config",
+		input:     nil,
+		expectErr: kvstore.ErrNoSuchKey,
+	}, {
+		name:      "when the config is not valid JSON",
+		input:     []byte(`{`),
+		expectErr: errors.New("hujson: line 1, column 2: parsing value: unexpected EOF"),
+	}, {
+		name:      "when the config has the wrong version",
+		input:     []byte(`{"Version":0}`),
+		expectErr: errConfigWrongVersion,
+	}, {
+		name:      "when the config contains a null entry",
+		input:     []byte(`{"Resolvers":[null],"Version":1}`),
+		expectErr: errConfigInvalidEntry,
+	}, {
+		name:      "when the config contains an unparseable URL",
+		input:     []byte(`{"Resolvers":[{"URL":"\t"}],"Version":1}`),
+		expectErr: errConfigInvalidEntry,
+	}, {
+		name:      "when the config contains an unsupported scheme",
+		input:     []byte(`{"Resolvers":[{"URL":"udp://8.8.8.8:53"}],"Version":1}`),
+		expectErr: errConfigInvalidEntry,
+	}, {
+		name:      "when the config contains an URL without host",
+		input:     []byte(`{"Resolvers":[{"URL":"https:///dns-query"}],"Version":1}`),
+		expectErr: errConfigInvalidEntry,
+	}, {
+		name: "when the config contains duplicate entries",
+		input: []byte(`{"Resolvers":[
+			{"URL":"https://dns.google/dns-query"},
+			{"URL":"https://dns.google/dns-query"}
+		],"Version":1}`),
+		expectErr: errConfigInvalidEntry,
+	}, {
+		name:      "when an entry is both pinned and disabled",
+		input:     []byte(`{"Resolvers":[{"URL":"system:///","Pinned":true,"Disabled":true}],"Version":1}`),
+		expectErr: errConfigInvalidEntry,
+	}, {
+		name: "when the config disables all the resolvers",
+		input: func() []byte {
+			root := &configRoot{Version: configVersion}
+			for _, e := range allmakers {
+				root.Resolvers = append(root.Resolvers, &configEntry{URL: e.url, Disabled: true})
+			}
+			return runtimex.Try1((&jsonCodecStdlib{}).Encode(root))
+		}(),
+		expectErr: errConfigNoEnabledEntries,
+	}, {
+		name: "when the config disables all the built-in resolvers but adds one",
+		input: func() []byte {
+			root := &configRoot{Version: configVersion}
+			for _, e := range allmakers {
+				root.Resolvers = append(root.Resolvers, &configEntry{URL: e.url, Disabled: true})
+			}
+			root.Resolvers = append(root.Resolvers, &configEntry{URL: "https://dns.example.com/dns-query"})
+			return runtimex.Try1((&jsonCodecStdlib{}).Encode(root))
+		}(),
+		expectErr: nil,
+		expectRoot: func() *configRoot {
+			root := &configRoot{Version: configVersion}
+			for _, e := range allmakers {
+				root.Resolvers = append(root.Resolvers, &configEntry{URL: e.url, Disabled: true})
+			}
+			root.Resolvers = append(root.Resolvers, &configEntry{URL: "https://dns.example.com/dns-query"})
+			return root
+		}(),
+	}, {
+		name: "with a valid config containing comments",
+		input: []byte(`{
+			// we want to use this resolver first
+			"Resolvers": [{
+				"URL": "http3://dns.example.com/dns-query",
+				"Pinned": true,
+			}, {
+				// the system resolver does not work here
+				"URL": "system:///",
+				"Disabled": true,
+			}],
+			"Version": 1,
+		}`),
+		expectErr: nil,
+		expectRoot: &configRoot{
+			Resolvers: []*configEntry{{
+				URL:    "http3://dns.example.com/dns-query",
+				Pinned: true,
+			}, {
+				URL:      "system:///",
+				Disabled: true,
+			}},
+			Version: 1,
+		},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reso := &Resolver{KVStore: &kvstore.Memory{}}
+			if tc.input != nil {
+				if err := reso.KVStore.Set(configKey, tc.input); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			root, err := reso.readconfig()
+
+			switch {
+			case tc.expectErr == nil && err != nil:
+				t.Fatal("expected", tc.expectErr, "got", err)
+			case tc.expectErr != nil && err == nil:
+				t.Fatal("expected", tc.expectErr, "got", err)
+			case tc.expectErr != nil && err != nil:
+				if !errors.Is(err, tc.expectErr) && err.Error() != tc.expectErr.Error() {
+					t.Fatal("expected", tc.expectErr, "got", err)
+				}
+			}
+
+			if diff := cmp.Diff(tc.expectRoot, root); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+
+	t.Run("when the KVStore is nil", func(t *testing.T) {
+		reso := &Resolver{}
+		root, err := reso.readconfig()
+		if !errors.Is(err, ErrNilKVStore) {
+			t.Fatal("not the error we expected", err)
+		}
+		if root != nil {
+			t.Fatal("expected nil here")
+		}
+	})
+}
+
+func TestReadConfigDefault(t *testing.T) {
+	// newResolver creates a resolver counting the warnings.
+	newResolver := func(warnings *int) *Resolver {
+		return &Resolver{
+			KVStore: &kvstore.Memory{},
+			Logger: &mocks.Logger{
+				MockWarnf: func(format string, v ...interface{}) {
+					*warnings++
+				},
+			},
+		}
+	}
+
+	t.Run("when there is no config we do not warn", func(t *testing.T) {
+		var warnings int
+		reso := newResolver(&warnings)
+		root := reso.readconfigdefault()
+		if diff := cmp.Diff(&configRoot{Version: configVersion}, root); diff != "" {
+			t.Fatal(diff)
+		}
+		if warnings != 0 {
+			t.Fatal("expected no warnings")
+		}
+	})
+
+	t.Run("when the config is invalid we warn", func(t *testing.T) {
+		var warnings int
+		reso := newResolver(&warnings)
+		if err := reso.KVStore.Set(configKey, []byte(`{"Version":0}`)); err != nil {
+			t.Fatal(err)
+		}
+		root := reso.readconfigdefault()
+		if diff := cmp.Diff(&configRoot{Version: configVersion}, root); diff != "" {
+			t.Fatal(diff)
+		}
+		if warnings != 1 {
+			t.Fatal("expected one warning")
+		}
+	})
+}
+
+func TestReadStateDefaultWithConfig(t *testing.T) {
+	const (
+		addedURL  = "https://dns.example.com/dns-query"
+		pinnedURL = "http3://dns.example.org/dns-query"
+	)
+
+	config := &configRoot{
+		Resolvers: []*configEntry{{
+			URL:    pinnedURL,
+			Pinned: true,
+		}, {
+			URL: addedURL,
+		}, {
+			URL:      systemResolverURL,
+			Disabled: true,
+		}, {
+			URL:    "https://dns.google/dns-query",
+			Pinned: true,
+		}},
+		Version: configVersion,
+	}
+
+	reso := &Resolver{KVStore: &kvstore.Memory{}}
+
+	// simulate previous runs where the added resolver was not working and
+	// where the system resolver was the best resolver
+	state := []*resolverinfo{{
+		URL:   systemResolverURL,
+		Score: 0.99,
+	}, {
+		URL:   addedURL,
+		Score: 0.001,
+	}}
+	if err := reso.writestate(state); err != nil {
+		t.Fatal(err)
+	}
+
+	out, pinned := reso.readstatedefault(config)
+
+	// the pinned entries come first in the configuration order
+	if pinned != 2 {
+		t.Fatal("expected two pinned entries, got", pinned)
+	}
+	if out[0].URL != pinnedURL || out[0].Score != configAddedScore {
+		t.Fatal("unexpected first entry", out[0])
+	}
+	if out[1].URL != "https://dns.google/dns-query" {
+		t.Fatal("unexpected second entry", out[1])
+	}
+
+	// the remaining entries are sorted by score
+	for idx := pinned + 1; idx < len(out); idx++ {
+		if out[idx-1].Score < out[idx].Score {
+			t.Fatal("entries are not sorted by descending score")
+		}
+	}
+
+	// we expect to see all the built-in resolvers except for the disabled one and
+	// the added resolvers with the score we previously saved
+	urls := make(map[string]float64)
+	for _, e := range out {
+		urls[e.URL] = e.Score
+	}
+	if len(urls) != len(allmakers)-1+2 {
+		t.Fatal("unexpected number of entries", len(urls))
+	}
+	if _, found := urls[systemResolverURL]; found {
+		t.Fatal("did not expect to see the disabled resolver")
+	}
+	if score := urls[addedURL]; score != 0.001 {
+		t.Fatal("unexpected score for the added resolver", score)
+	}
+}
+
+func TestResolverUsesPinnedResolversFirst(t *testing.T) {
+	const pinnedURL = "https://dns.example.com/dns-query"
+
+	// others counts the lookups using resolvers that are not pinned
+	var others int
+
+	reso := &Resolver{
+		KVStore: &kvstore.Memory{},
+		newChildResolverFn: func(h3 bool, URL string) (model.Resolver, error) {
+			return &mocks.Resolver{
+				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+					if URL != pinnedURL {
+						others++
+						return nil, errors.New("mocked error")
+					}
+					return []string{"130.192.91.211"}, nil
+				},
+			}, nil
+		},
+	}
+	config := []byte(`{"Resolvers":[{"URL":"https://dns.example.com/dns-query","Pinned":true}],"Version":1}`)
+	if err := reso.KVStore.Set(configKey, config); err != nil {
+		t.Fatal(err)
+	}
+
+	// because of maybeConfusion, repeat several times to make sure we
+	// always try the pinned resolver first regardless of the score
+	for idx := 0; idx < 16; idx++ {
+		addrs, err := reso.LookupHost(context.Background(), "api.ooni.io")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"130.192.91.211"}, addrs); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+	if others != 0 {
+		t.Fatal("expected to only use the pinned resolver, got", others, "other lookups")
+	}
+}
@@ -18,6 +18,27 @@
 // is failing us. (We will still occasionally probe for other working
 // resolvers and increase their score on success.)
 //
+// Users can customize the resolvers we use by writing a JSON document
+// named resolvers.conf inside the key-value store (i.e., inside the
+// $OONI_HOME/engine directory). This document allows users to add
+// their own DNS-over-HTTPS resolvers, to pin resolvers, which we then
+// always try first in the configured order regardless of their score,
+// and to disable resolvers. For example:
+//
+//	{
+//		"Resolvers": [{
+//			"URL": "https://dns.example.com/dns-query",
+//			"Pinned": true
+//		}, {
+//			"URL": "system:///",
+//			"Disabled": true
+//		}],
+//		"Version": 1
+//	}
+//
+// We ignore the whole document, emitting a warning, when it is not
+// valid (e.g., because it disables all the resolvers).
+//
 // We also support a socks5 proxy. When such a proxy is configured,
 // the code WILL skip http3 resolvers AS WELL AS the system
 // resolver, in an attempt to avoid leaking your queries.
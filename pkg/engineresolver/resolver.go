@@ -100,8 +100,8 @@ var ErrLookupHost = errors.New("sessionresolver: LookupHost failed")
 // multierror.Union error on failure, so you can see individual errors
 // and get a better picture of what's been going wrong.
 func (r *Resolver) LookupHost(ctx context.Context, hostname string) ([]string, error) {
-	state := r.readstatedefault()
-	r.maybeConfusion(state, time.Now().UnixNano())
+	state, pinned := r.readstatedefault(r.readconfigdefault())
+	r.maybeConfusion(state[pinned:], time.Now().UnixNano()) // do not reorder pinned entries
 	defer r.writestate(state)
 	me := multierror.New(ErrLookupHost)
 	for _, e := range state {
@@ -49,15 +49,15 @@ func (r *Resolver) readstate() ([]*resolverinfo, error) {
 var errNoEntries = errors.New("sessionresolver: no available entries")
 
 // readstateandprune reads the state from disk and removes all the
-// entries that we don't actually support.
-func (r *Resolver) readstateandprune() ([]*resolverinfo, error) {
+// entries that we don't actually support given the user config.
+func (r *Resolver) readstateandprune(config *configRoot) ([]*resolverinfo, error) {
 	ri, err := r.readstate()
 	if err != nil {
 		return nil, err
 	}
 	var out []*resolverinfo
 	for _, e := range ri {
-		if _, found := allbyurl[e.URL]; !found {
+		if !config.supports(e.URL) {
 			continue // we don't support this specific entry
 		}
 		out = append(out, e)
@@ -76,24 +76,37 @@ func sortstate(ri []*resolverinfo) {
 }
 
 // readstatedefault reads the state from disk and merges the state
-// so that all supported entries are represented.
-func (r *Resolver) readstatedefault() []*resolverinfo {
-	ri, _ := r.readstateandprune()
+// with the user config so that all supported entries are represented.
+//
+// This function returns the state sorted by descending score except for
+// the entries pinned by the user, which come first, and the number of
+// pinned entries, which MUST NOT be reordered.
+func (r *Resolver) readstatedefault(config *configRoot) ([]*resolverinfo, int) {
+	ri, _ := r.readstateandprune(config)
 	here := make(map[string]bool)
 	for _, e := range ri {
 		here[e.URL] = true // record what we already have
 	}
 	for _, e := range allmakers {
-		if _, found := here[e.url]; found {
-			continue // already here so no need to add
+		if _, found := here[e.url]; found || config.disabled(e.url) {
+			continue // already here or disabled so no need to add
 		}
 		ri = append(ri, &resolverinfo{
 			URL:   e.url,
 			Score: e.score,
 		})
 	}
+	for _, URL := range config.added() {
+		if _, found := here[URL]; found {
+			continue // already here so no need to add
+		}
+		ri = append(ri, &resolverinfo{
+			URL:   URL,
+			Score: configAddedScore,
+		})
+	}
 	sortstate(ri)
-	return ri
+	return ri, config.movepinned(ri)
 }
 
 // writestate writes the state to the kvstore.
@@ -107,3 +120,15 @@ func (r *Resolver) writestate(ri []*resolverinfo) error {
 	}
 	return r.KVStore.Set(storekey, data)
 }
+
+// Scores returns the current score of each resolver we may use indexed
+// by the resolver URL. This includes resolvers that we have not used yet,
+// for which we return the default score.
+func (r *Resolver) Scores() map[string]float64 {
+	ri, _ := r.readstatedefault(r.readconfigdefault())
+	out := make(map[string]float64)
+	for _, e := range ri {
+		out[e.URL] = e.Score
+	}
+	return out
+}
@@ -49,7 +49,7 @@ func TestReadStateDecodeError(t *testing.T) {
 
 func TestReadStateAndPruneReadStateError(t *testing.T) {
 	reso := &Resolver{KVStore: &kvstore.Memory{}}
-	out, err := reso.readstateandprune()
+	out, err := reso.readstateandprune(&configRoot{})
 	if !errors.Is(err, kvstore.ErrNoSuchKey) {
 		t.Fatal("not the error we expected", err)
 	}
@@ -65,7 +65,7 @@ func TestReadStateAndPruneWithUnsupportedEntries(t *testing.T) {
 	if err := reso.writestate(in); err != nil {
 		t.Fatal(err)
 	}
-	out, err := reso.readstateandprune()
+	out, err := reso.readstateandprune(&configRoot{})
 	if !errors.Is(err, errNoEntries) {
 		t.Fatal("not the error we expected", err)
 	}
@@ -88,7 +88,10 @@ func TestReadStateDefaultWithMissingEntries(t *testing.T) {
 		t.Fatal(err)
 	}
 	// let us seee what we read
-	out := reso.readstatedefault()
+	out, pinned := reso.readstatedefault(&configRoot{})
+	if pinned != 0 {
+		t.Fatal("expected no pinned entries")
+	}
 	if len(out) < 1 {
 		t.Fatal("expected non-empty output")
 	}
@@ -146,3 +149,20 @@ func TestWriteStateCannotSerialize(t *testing.T) {
 		t.Fatal("not the error we expected", err)
 	}
 }
+
+func TestScores(t *testing.T) {
+	reso := &Resolver{KVStore: &kvstore.Memory{}}
+	if err := reso.writestate([]*resolverinfo{{
+		URL:   "https://dns.google/dns-query",
+		Score: 0.5,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	scores := reso.Scores()
+	if len(scores) != len(allmakers) {
+		t.Fatal("expected to see all the resolvers", scores)
+	}
+	if scores["https://dns.google/dns-query"] != 0.5 {
+		t.Fatal("unexpected score", scores["https://dns.google/dns-query"])
+	}
+}
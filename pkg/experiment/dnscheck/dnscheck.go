@@ -24,7 +24,7 @@ import (
 
 const (
 	testName      = "dnscheck"
-	testVersion   = "0.9.2"
+	testVersion   = "0.11.0"
 	defaultDomain = "example.org"
 )
 
@@ -166,7 +166,7 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		return fmt.Errorf("%w: %s", ErrInvalidURL, err.Error())
 	}
 	switch URL.Scheme {
-	case "https", "dot", "udp", "tcp":
+	case "https", "dot", "doq", "odoh", "udp", "tcp":
 		// all good
 	default:
 		return ErrUnsupportedURLScheme
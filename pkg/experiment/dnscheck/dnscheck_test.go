@@ -45,7 +45,7 @@ func TestExperimentNameAndVersion(t *testing.T) {
 	if measurer.ExperimentName() != "dnscheck" {
 		t.Error("unexpected experiment name")
 	}
-	if measurer.ExperimentVersion() != "0.9.2" {
+	if measurer.ExperimentVersion() != "0.11.0" {
 		t.Error("unexpected experiment version")
 	}
 }
@@ -139,6 +139,62 @@ func TestWithCancelledContext(t *testing.T) {
 	}
 }
 
+func TestWithCancelledContextAndDoQ(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // immediately cancel the context
+	measurer := NewExperimentMeasurer()
+	measurement := &model.Measurement{Input: "doq://dns.adguard-dns.com"}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(log.Log),
+		Measurement: measurement,
+		Session:     newsession(),
+		Target: &Target{
+			URL: "doq://dns.adguard-dns.com",
+			Config: &Config{
+				DefaultAddrs: "94.140.14.14 94.140.15.15",
+			},
+		},
+	}
+	err := measurer.Run(ctx, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := measurement.TestKeys.(*TestKeys)
+	for _, resolverURL := range []string{"doq://94.140.14.14", "doq://94.140.15.15"} {
+		if _, found := tk.Lookups[resolverURL]; !found {
+			t.Fatal("missing lookup for", resolverURL)
+		}
+	}
+}
+
+func TestWithCancelledContextAndODoH(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // immediately cancel the context
+	measurer := NewExperimentMeasurer()
+	input := "odoh://odoh1.surfdomeinen.nl/proxy?targethost=odoh.cloudflare-dns.com&targetpath=%2Fdns-query"
+	measurement := &model.Measurement{Input: model.MeasurementInput(input)}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(log.Log),
+		Measurement: measurement,
+		Session:     newsession(),
+		Target: &Target{
+			URL: input,
+			Config: &Config{
+				DefaultAddrs: "145.100.185.15",
+			},
+		},
+	}
+	err := measurer.Run(ctx, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := measurement.TestKeys.(*TestKeys)
+	resolverURL := "odoh://145.100.185.15/proxy?targethost=odoh.cloudflare-dns.com&targetpath=%2Fdns-query"
+	if _, found := tk.Lookups[resolverURL]; !found {
+		t.Fatal("missing lookup for", resolverURL)
+	}
+}
+
 func TestDNSCheckFailsWithNilTarget(t *testing.T) {
 	measurer := NewExperimentMeasurer()
 	measurement := &model.Measurement{Input: "dot://one.one.one.one"}
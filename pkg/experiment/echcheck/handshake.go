@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/base64"
 	"fmt"
 	"net/url"
 	"time"
@@ -95,9 +94,8 @@ func handshake(ctx context.Context, isGrease bool, startTime time.Time, address
 	hs := measurexlite.NewArchivalTLSOrQUICHandshakeResult(0, start.Sub(startTime),
 		"tcp", address, tlsConfig, connState, err, finish.Sub(startTime))
 	if isGrease {
+		// measurexlite records the config list, but the GREASE one is random
 		hs.ECHConfig = "GREASE"
-	} else {
-		hs.ECHConfig = base64.StdEncoding.EncodeToString(tlsConfig.EncryptedClientHelloConfigList)
 	}
 	osn, err := getUnambiguousOuterServerName(tlsConfig.EncryptedClientHelloConfigList)
 	if err != nil {
@@ -137,6 +137,12 @@ func (g Getter) get(ctx context.Context, saver *tracex.Saver) (TestKeys, error)
 		return tk, err
 	}
 	defer configuration.CloseIdleConnections()
+	// possibly fetch the ECH config list
+	if g.Config.TLSEnableECH {
+		if err := g.configureECH(ctx, &configuration); err != nil {
+			return tk, err
+		}
+	}
 	// run the measurement
 	runner := Runner{
 		Config:     g.Config,
@@ -145,3 +151,22 @@ func (g Getter) get(ctx context.Context, saver *tracex.Saver) (TestKeys, error)
 	}
 	return tk, runner.Run(ctx)
 }
+
+// configureECH looks up the ECH config list for the target's TLS server name
+// using the DNS HTTPS record and configures the TLS config to use it.
+func (g Getter) configureECH(ctx context.Context, configuration *Configuration) error {
+	targetURL, err := url.Parse(g.Target)
+	if err != nil {
+		return err
+	}
+	serverName := configuration.HTTPConfig.TLSConfig.ServerName
+	if serverName == "" {
+		serverName = targetURL.Hostname()
+	}
+	echConfigList, err := netxlite.LookupECHConfigList(ctx, configuration.DNSClient, serverName)
+	if err != nil {
+		return err
+	}
+	configuration.HTTPConfig.TLSConfig.EncryptedClientHelloConfigList = echConfigList
+	return nil
+}
@@ -2,12 +2,17 @@ package urlgetter
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"net/http"
 	"testing"
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-engine/pkg/legacy/mockable"
+	"github.com/ooni/probe-engine/pkg/legacy/netx"
+	"github.com/ooni/probe-engine/pkg/mocks"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/netxlite"
 )
 
 func TestGetterHTTPSWithTunnelCannotCreateTempDir(t *testing.T) {
@@ -71,3 +76,67 @@ func TestGetterHTTPSWithTunnelCannotCreateTempDir(t *testing.T) {
 		t.Fatal("not the HTTPResponseBody we expected")
 	}
 }
+
+func TestGetterConfigureECH(t *testing.T) {
+	newConfiguration := func(serverName string, reso model.Resolver) *Configuration {
+		return &Configuration{
+			HTTPConfig: netx.Config{
+				TLSConfig: &tls.Config{ServerName: serverName},
+			},
+			DNSClient: reso,
+		}
+	}
+
+	t.Run("we use the ECH config list for the target's hostname", func(t *testing.T) {
+		var domain string
+		reso := &mocks.Resolver{
+			MockLookupHTTPS: func(ctx context.Context, d string) (*model.HTTPSSvc, error) {
+				domain = d
+				return &model.HTTPSSvc{Ech: []byte{0x00, 0x45, 0xfe, 0x0d}}, nil
+			},
+		}
+		g := Getter{Target: "https://www.example.com/"}
+		configuration := newConfiguration("", reso)
+		if err := g.configureECH(context.Background(), configuration); err != nil {
+			t.Fatal(err)
+		}
+		if domain != "www.example.com" {
+			t.Fatal("unexpected domain", domain)
+		}
+		if len(configuration.HTTPConfig.TLSConfig.EncryptedClientHelloConfigList) != 4 {
+			t.Fatal("expected the ECH config list to be set")
+		}
+	})
+
+	t.Run("we prefer the configured TLS server name", func(t *testing.T) {
+		var domain string
+		reso := &mocks.Resolver{
+			MockLookupHTTPS: func(ctx context.Context, d string) (*model.HTTPSSvc, error) {
+				domain = d
+				return &model.HTTPSSvc{Ech: []byte{0x00, 0x45, 0xfe, 0x0d}}, nil
+			},
+		}
+		g := Getter{Target: "tlshandshake://8.8.8.8:443"}
+		configuration := newConfiguration("dns.google", reso)
+		if err := g.configureECH(context.Background(), configuration); err != nil {
+			t.Fatal(err)
+		}
+		if domain != "dns.google" {
+			t.Fatal("unexpected domain", domain)
+		}
+	})
+
+	t.Run("we fail if there is no ECH config list", func(t *testing.T) {
+		reso := &mocks.Resolver{
+			MockLookupHTTPS: func(ctx context.Context, d string) (*model.HTTPSSvc, error) {
+				return &model.HTTPSSvc{}, nil
+			},
+		}
+		g := Getter{Target: "https://www.example.com/"}
+		configuration := newConfiguration("", reso)
+		err := g.configureECH(context.Background(), configuration)
+		if !errors.Is(err, netxlite.ErrNoECHConfigList) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
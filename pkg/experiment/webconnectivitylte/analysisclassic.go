@@ -8,7 +8,9 @@ package webconnectivitylte
 //
 
 import (
+	"io"
 	"net"
+	"os"
 
 	"github.com/ooni/probe-engine/pkg/geoipx"
 	"github.com/ooni/probe-engine/pkg/minipipeline"
@@ -26,6 +28,12 @@ func analysisEngineClassic(tk *TestKeys, logger model.Logger) {
 }
 
 func (tk *TestKeys) analysisClassic(lookupper model.GeoIPASNLookupper, _ model.Logger) {
+	tk.analysisClassicWithWriter(lookupper, os.Stdout)
+}
+
+// analysisClassicWithWriter is like analysisClassic but writes the human
+// readable summary of the extended analysis to the given writer.
+func (tk *TestKeys) analysisClassicWithWriter(lookupper model.GeoIPASNLookupper, w io.Writer) {
 	// Since we run after all tasks have completed (or so we assume) we're
 	// not going to use any form of locking here.
 
@@ -34,7 +42,9 @@ func (tk *TestKeys) analysisClassic(lookupper model.GeoIPASNLookupper, _ model.L
 	container.IngestDNSLookupEvents(lookupper, tk.Queries...)
 	container.IngestTCPConnectEvents(lookupper, tk.TCPConnect...)
 	container.IngestTLSHandshakeEvents(tk.TLSHandshakes...)
+	container.IngestQUICHandshakeEvents(lookupper, tk.QUICHandshakes...)
 	container.IngestHTTPRoundTripEvents(tk.Requests...)
+	container.IngestNetworkEvents(tk.NetworkEvents...)
 
 	// be defensive in case the control request or response are not defined
 	if tk.ControlRequest != nil && tk.Control != nil {
@@ -44,7 +54,7 @@ func (tk *TestKeys) analysisClassic(lookupper model.GeoIPASNLookupper, _ model.L
 	}
 
 	// 2. compute extended analysis flags
-	analysisExtMain(lookupper, tk, container)
+	analysisExtMain(lookupper, tk, container, w)
 
 	// 3. filter observations to only include results collected by the
 	// system resolver, which approximates v0.4's results
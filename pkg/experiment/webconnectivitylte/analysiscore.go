@@ -76,3 +76,20 @@ const (
 	// different addresses from the probe
 	AnalysisDNSFlagUnexpectedAddrs
 )
+
+const (
+	// AnalysisTLSFlagUnexpectedCertificate indicates the probe could not validate
+	// certificates the TH did not receive, while the TH handshake succeeded, which
+	// typically means that someone is intercepting the TLS traffic
+	AnalysisTLSFlagUnexpectedCertificate = 1 << iota
+)
+
+const (
+	// AnalysisThrottlingFlagSpeedCollapse indicates that the download speed collapsed
+	// after receiving some bytes (e.g., right after the TLS handshake)
+	AnalysisThrottlingFlagSpeedCollapse = 1 << iota
+
+	// AnalysisThrottlingFlagSlowDownload indicates that the download failed while making
+	// slow progress and before receiving as many bytes as the control
+	AnalysisThrottlingFlagSlowDownload
+)
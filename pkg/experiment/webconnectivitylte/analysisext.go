@@ -18,11 +18,12 @@ import (
 
 // analysisExtMain computes the extended analysis.
 //
-// This function MUTATES the [*TestKeys].
+// This function MUTATES the [*TestKeys] and writes a summary to w.
 func analysisExtMain(
 	lookupper model.GeoIPASNLookupper,
 	tk *TestKeys,
 	container *minipipeline.WebObservationsContainer,
+	w io.Writer,
 ) {
 	// compute the web analysis
 	analysis := minipipeline.AnalyzeWebObservationsWithoutLinearAnalysis(lookupper, container)
@@ -45,17 +46,29 @@ func analysisExtMain(
 	// HTTP success analysis (i.e., only if we manage to get an HTTP response)
 	analysisExtHTTPFinalResponse(tk, analysis, &info)
 
+	// compare the webpages fetched from every address, when we're configured to do so
+	analysisExtExtraFetches(analysis, &info)
+
+	// compare HTTP/3 results with the control, when we're configured to measure HTTP/3
+	analysisExtQUIC(analysis, &info)
+
+	// compare ECH handshakes with the ones not using ECH, when we're configured to use ECH
+	analysisExtECH(analysis, &info)
+
+	// analyze the goodput of the downloads to detect throttling
+	analysisExtThrottling(tk, analysis, &info)
+
 	// handle the cases where the probe and the TH both failed, which we can confidently
 	// only evaluate for DNS, TCP, and TLS during the 0-th redirect.
 	analysisExtExpectedFailures(tk, analysis, &info)
 
 	// print the content of the analysis only if there's some content to print
 	if content := info.String(); content != "" {
-		fmt.Printf("\n")
-		fmt.Printf("Extended Analysis\n")
-		fmt.Printf("-----------------\n")
-		fmt.Printf("%s", content)
-		fmt.Printf("\n\n")
+		fmt.Fprintf(w, "\n")
+		fmt.Fprintf(w, "Extended Analysis\n")
+		fmt.Fprintf(w, "-----------------\n")
+		fmt.Fprintf(w, "%s", content)
+		fmt.Fprintf(w, "\n\n")
 	}
 }
 
@@ -89,6 +102,16 @@ func analysisExtDNS(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Wr
 		tk.DNSFlags |= AnalysisDNSFlagUnexpectedAddrs
 		fmt.Fprintf(info, "- transactions with invalid IP addrs: %s\n", failures.String())
 	}
+
+	// attribute the DNS failures and invalid addrs to the resolvers causing them, which
+	// allows to distinguish between a misbehaving resolver and on-path DNS injection
+	if resolvers := analysis.DNSResolverWithUnexpectedFailure; resolvers.Len() > 0 {
+		fmt.Fprintf(info, "- resolvers with unexpected DNS lookup failures: %s\n", resolvers.String())
+	}
+
+	if resolvers := analysis.DNSResolverWithInvalidAddresses; resolvers.Len() > 0 {
+		fmt.Fprintf(info, "- resolvers returning invalid IP addrs: %s\n", resolvers.String())
+	}
 }
 
 func analysisExtEndpointFailure(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) {
@@ -110,6 +133,10 @@ func analysisExtEndpointFailure(tk *TestKeys, analysis *minipipeline.WebAnalysis
 		tk.BlockingFlags |= AnalysisBlockingFlagTLSBlocking
 		fmt.Fprintf(info, "- transactions with unexpected TLS handshake failures: %s\n", failures.String())
 	}
+	if failures := analysis.TLSHandshakeUnexpectedCertificate; failures.Len() > 0 {
+		tk.TLSFlags |= AnalysisTLSFlagUnexpectedCertificate
+		fmt.Fprintf(info, "- transactions with certificates not seen by the control: %s\n", failures.String())
+	}
 
 	// HTTP failure analysis
 	if failures := analysis.HTTPRoundTripUnexpectedFailure; failures.Len() > 0 {
@@ -167,6 +194,81 @@ func analysisExtHTTPFinalResponse(tk *TestKeys, analysis *minipipeline.WebAnalys
 	}
 }
 
+func analysisExtExtraFetches(analysis *minipipeline.WebAnalysis, info io.Writer) {
+	// Implementation note: we do not set any blocking flag here because different
+	// addresses of the same website (e.g., CDN edges) may legitimately serve slightly
+	// different webpages. So, we just annotate what we see for further analysis.
+
+	if failures := analysis.HTTPExtraFetchFailure; failures.Len() > 0 {
+		fmt.Fprintf(info, "- extra fetch transactions with HTTP failures: %s\n", failures.String())
+	}
+
+	if divergences := analysis.HTTPExtraFetchBodyDivergence; divergences.Len() > 0 {
+		fmt.Fprintf(info, "- extra fetch transactions with diverging body: %s\n", divergences.String())
+	}
+
+	if divergences := analysis.HTTPExtraFetchTitleDivergence; divergences.Len() > 0 {
+		fmt.Fprintf(info, "- extra fetch transactions with diverging title: %s\n", divergences.String())
+	}
+}
+
+func analysisExtQUIC(analysis *minipipeline.WebAnalysis, info io.Writer) {
+	// Implementation note: we do not set any blocking flag here because QUIC is an
+	// optional measurement and browsers fall back to TCP when HTTP/3 does not work. So,
+	// we just annotate what we see for further analysis.
+
+	if failures := analysis.QUICHandshakeUnexpectedFailure; failures.Len() > 0 {
+		fmt.Fprintf(info, "- transactions with unexpected QUIC handshake failures: %s\n", failures.String())
+	}
+
+	if failures := analysis.QUICHandshakeUnexplainedFailure; failures.Len() > 0 {
+		fmt.Fprintf(info, "- transactions with unexplained QUIC handshake failures: %s\n", failures.String())
+	}
+
+	if failures := analysis.QUICHandshakeExpectedFailure; failures.Len() > 0 {
+		fmt.Fprintf(info, "- transactions with expected QUIC handshake failures: %s\n", failures.String())
+	}
+
+	if failures := analysis.HTTP3RoundTripUnexpectedFailure; failures.Len() > 0 {
+		fmt.Fprintf(info, "- transactions with unexpected HTTP/3 failures: %s\n", failures.String())
+	}
+
+	if divergences := analysis.HTTP3ResponseDiffersFromControl; divergences.Len() > 0 {
+		fmt.Fprintf(info, "- HTTP/3 transactions whose response differs from the control: %s\n", divergences.String())
+	}
+}
+
+func analysisExtECH(analysis *minipipeline.WebAnalysis, info io.Writer) {
+	// Implementation note: we do not set any blocking flag here because ECH is an
+	// optional measurement that we compare with the TLS handshakes not using it
+	// rather than with the control. So, we just annotate what we see.
+
+	if successes := analysis.TLSHandshakeECHSuccess; successes.Len() > 0 {
+		fmt.Fprintf(info, "- transactions with successful ECH handshakes: %s\n", successes.String())
+	}
+
+	if failures := analysis.TLSHandshakeECHUnexpectedFailure; failures.Len() > 0 {
+		fmt.Fprintf(info, "- transactions with unexpected ECH handshake failures: %s\n", failures.String())
+	}
+}
+
+func analysisExtThrottling(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) {
+	// Implementation note: we do not set any blocking flag here because throttling does
+	// not prevent accessing the website per se. When throttling is so severe that the
+	// download fails, we already flag HTTP blocking when analyzing failures.
+	tk.Throttled = analysis.Throttled
+
+	if collapses := analysis.HTTPThrottlingSpeedCollapse; collapses.Len() > 0 {
+		tk.ThrottlingFlags |= AnalysisThrottlingFlagSpeedCollapse
+		fmt.Fprintf(info, "- transactions where the download speed collapsed: %s\n", collapses.String())
+	}
+
+	if downloads := analysis.HTTPThrottlingSlowDownload; downloads.Len() > 0 {
+		tk.ThrottlingFlags |= AnalysisThrottlingFlagSlowDownload
+		fmt.Fprintf(info, "- transactions with slow and failed downloads: %s\n", downloads.String())
+	}
+}
+
 func analysisExtRedirectErrors(tk *TestKeys, analysis *minipipeline.WebAnalysis, info io.Writer) {
 	// Implementation note: we care about cases in which we don't have a final response
 	// to compare to and we have unexplained failures. We define "unexplained failure" a
@@ -0,0 +1,72 @@
+package webconnectivitylte
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-engine/pkg/netemx"
+	"github.com/ooni/probe-engine/pkg/webconnectivityqa"
+)
+
+func TestAnalysisExtThrottling(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+
+	// measure runs the given test case and returns the test keys
+	measure := func(t *testing.T, tc *webconnectivityqa.TestCase) *TestKeys {
+		measurer := NewExperimentMeasurer(&Config{})
+		measurement, err := webconnectivityqa.MeasureTestCase(measurer, tc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return measurement.TestKeys.(*TestKeys)
+	}
+
+	t.Run("when the download is not throttled", func(t *testing.T) {
+		tc := &webconnectivityqa.TestCase{
+			Name:  "successWithLargeFile",
+			Input: "https://largefile.com/",
+		}
+		tk := measure(t, tc)
+
+		if tk.ThrottlingFlags != 0 {
+			t.Fatal("unexpected throttling flags", tk.ThrottlingFlags)
+		}
+		if tk.Throttled.UnwrapOr(true) {
+			t.Fatal("expected the throttled verdict to be false", tk.Throttled)
+		}
+	})
+
+	t.Run("when the download is throttled", func(t *testing.T) {
+		tc := &webconnectivityqa.TestCase{
+			Name:  "throttlingWithHTTP",
+			Input: "http://largefile.com/",
+			Configure: func(env *netemx.QAEnv) {
+				env.DPIEngine().AddRule(&netem.DPIThrottleTrafficForTCPEndpoint{
+					Delay:           300 * time.Millisecond,
+					Logger:          log.Log,
+					PLR:             0.1,
+					ServerIPAddress: netemx.AddressLargeFileCom1,
+					ServerPort:      80,
+				})
+			},
+		}
+		tk := measure(t, tc)
+
+		// make sure we flag the slow download
+		if tk.ThrottlingFlags&AnalysisThrottlingFlagSlowDownload == 0 {
+			t.Fatal("expected to see the slow download flag", tk.ThrottlingFlags)
+		}
+		if !tk.Throttled.UnwrapOr(false) {
+			t.Fatal("expected the throttled verdict to be true", tk.Throttled)
+		}
+
+		// make sure throttling does not change how we flag the failure
+		if tk.BlockingFlags != AnalysisBlockingFlagHTTPBlocking {
+			t.Fatal("unexpected blocking flags", tk.BlockingFlags)
+		}
+	})
+}
@@ -65,6 +65,10 @@ type CleartextFlow struct {
 	// CookieJar contains the OPTIONAL cookie jar, used for redirects.
 	CookieJar http.CookieJar
 
+	// FetchBudget is the OPTIONAL budget for fetching the webpage when
+	// the [*prioritySelector] does not grant us permission to fetch.
+	FetchBudget *FetchBudget
+
 	// FollowRedirects is OPTIONAL and instructs this flow
 	// to follow HTTP redirects (if any).
 	FollowRedirects bool
@@ -79,6 +83,9 @@ type CleartextFlow struct {
 	// Referer contains the OPTIONAL referer, used for redirects.
 	Referer string
 
+	// Resolvers contains the OPTIONAL URLs of the resolvers to use.
+	Resolvers []string
+
 	// UDPAddress is the OPTIONAL address of the UDP resolver to use. If this
 	// field is not set we use a default one (e.g., `8.8.8.8:53`).
 	UDPAddress string
@@ -145,10 +152,24 @@ func (t *CleartextFlow) Run(parentCtx context.Context, index int64) error {
 
 	alpn := "" // no ALPN because we're not using TLS
 
-	// Determine whether we're allowed to fetch the webpage
+	// Determine whether we're allowed to fetch the webpage or, failing that,
+	// whether we're allowed to perform an extra fetch of the webpage
+	var (
+		extraFetch        bool
+		extraFetchMaxBody int64
+		extraFetchBytes   int64
+	)
 	if t.PrioSelector == nil || !t.PrioSelector.permissionToFetch(t.Address) {
-		ol.Stop("stop after TCP connect")
-		return errNotPermittedToFetch
+		if t.PrioSelector != nil {
+			extraFetchMaxBody, extraFetch = t.FetchBudget.Acquire(parentCtx, fetchBudgetMaxBodySize)
+		}
+		if !extraFetch {
+			ol.Stop("stop after TCP connect")
+			return errNotPermittedToFetch
+		}
+		defer func() {
+			t.FetchBudget.Release(extraFetchMaxBody, extraFetchBytes)
+		}()
 	}
 
 	// create HTTP transport
@@ -184,14 +205,19 @@ func (t *CleartextFlow) Run(parentCtx context.Context, index int64) error {
 		httpTransport,
 		httpReq,
 		trace,
+		extraFetch,
+		extraFetchMaxBody,
 	)
+	extraFetchBytes = int64(len(httpRespBody))
 	if err != nil {
 		ol.Stop(err)
 		return err
 	}
 
-	// if enabled, follow possible redirects
-	t.maybeFollowRedirects(parentCtx, httpResp)
+	// if enabled, follow possible redirects (unless this is an extra fetch)
+	if !extraFetch {
+		t.maybeFollowRedirects(parentCtx, httpResp)
+	}
 
 	// ignore the response body
 	_ = httpRespBody
@@ -252,8 +278,14 @@ func (t *CleartextFlow) newHTTPRequest(ctx context.Context) (*http.Request, erro
 
 // httpTransaction runs the HTTP transaction and saves the results.
 func (t *CleartextFlow) httpTransaction(ctx context.Context, network, address, alpn string,
-	txp model.HTTPTransport, req *http.Request, trace *measurexlite.Trace) (*http.Response, []byte, error) {
-	const maxbody = 1 << 19
+	txp model.HTTPTransport, req *http.Request, trace *measurexlite.Trace,
+	extraFetch bool, extraFetchMaxBody int64) (*http.Response, []byte, error) {
+	maxbody := int64(fetchBudgetMaxBodySize)
+	tags := trace.Tags()
+	if extraFetch {
+		maxbody = extraFetchMaxBody
+		tags = generateTagsForExtraFetch(tags)
+	}
 	started := trace.TimeSince(trace.ZeroTime())
 
 	// Implementation note: we want to emit http_transaction_start when we actually start doing
@@ -267,7 +299,7 @@ func (t *CleartextFlow) httpTransaction(ctx context.Context, network, address, a
 		0,
 		nil,
 		started,
-		trace.Tags()...,
+		tags...,
 	))
 
 	resp, err := txp.RoundTrip(req)
@@ -282,7 +314,7 @@ func (t *CleartextFlow) httpTransaction(ctx context.Context, network, address, a
 	}
 	if err == nil && httpRedirectIsRedirect(resp) {
 		err = httpValidateRedirect(resp)
-		if err == nil && t.FollowRedirects && !t.NumRedirects.CanFollowOneMoreRedirect() {
+		if err == nil && t.FollowRedirects && !extraFetch && !t.NumRedirects.CanFollowOneMoreRedirect() {
 			err = ErrTooManyRedirects
 		}
 	}
@@ -297,7 +329,7 @@ func (t *CleartextFlow) httpTransaction(ctx context.Context, network, address, a
 		0,
 		nil,
 		finished,
-		trace.Tags()...,
+		tags...,
 	))
 
 	ev := measurexlite.NewArchivalHTTPRequestResult(
@@ -313,7 +345,7 @@ func (t *CleartextFlow) httpTransaction(ctx context.Context, network, address, a
 		body,
 		err,
 		finished,
-		trace.Tags()...,
+		tags...,
 	)
 
 	t.TestKeys.PrependRequests(ev)
@@ -344,6 +376,8 @@ func (t *CleartextFlow) maybeFollowRedirects(ctx context.Context, resp *http.Res
 			Referer:                 resp.Request.URL.String(),
 			Session:                 nil, // no need to issue another control request
 			TestHelpers:             nil, // ditto
+			FetchBudget:             t.FetchBudget,
+			Resolvers:               t.Resolvers,
 			UDPAddress:              t.UDPAddress,
 		}
 		resolvers.Start(ctx)
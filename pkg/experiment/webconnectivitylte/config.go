@@ -7,4 +7,52 @@ package webconnectivitylte
 // Config contains webconnectivity experiment configuration.
 type Config struct {
 	DNSOverUDPResolver string
+
+	// DNSResolvers contains the OPTIONAL URLs of the resolvers to use, which
+	// replace the default system and DNS-over-UDP resolvers. See the documentation
+	// of [DNSResolvers] for the URL schemes we support.
+	DNSResolvers []string
+
+	// EnableECH OPTIONALLY instructs us to perform additional TLS handshakes using the
+	// Encrypted Client Hello config list advertised by DNS HTTPS records.
+	EnableECH bool
+
+	// EnableQUIC OPTIONALLY instructs us to measure the HTTP/3 endpoints discovered
+	// by the test helper using Alt-Svc and the ones advertised by DNS HTTPS records.
+	EnableQUIC bool
+
+	// FetchAllAddresses OPTIONALLY instructs us to fetch the webpage from all the
+	// addresses rather than just from the highest priority one.
+	FetchAllAddresses bool
+
+	// FetchAllMaxConcurrency is the OPTIONAL maximum number of parallel extra fetches
+	// when FetchAllAddresses is true. If zero, we use a reasonable default.
+	FetchAllMaxConcurrency int64
+
+	// FetchAllMaxBytes is the OPTIONAL maximum number of body bytes downloaded by the
+	// extra fetches when FetchAllAddresses is true. If zero, we use a reasonable default.
+	FetchAllMaxBytes int64
+}
+
+// Default values for the extra fetches when FetchAllAddresses is true.
+const (
+	defaultFetchAllMaxConcurrency = 4
+	defaultFetchAllMaxBytes       = 1 << 22
+)
+
+// fetchBudget returns the [*FetchBudget] to use or nil if we should only fetch
+// the webpage from the highest priority address.
+func (c *Config) fetchBudget() *FetchBudget {
+	if !c.FetchAllAddresses {
+		return nil
+	}
+	concurrency := c.FetchAllMaxConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultFetchAllMaxConcurrency
+	}
+	bytes := c.FetchAllMaxBytes
+	if bytes <= 0 {
+		bytes = defaultFetchAllMaxBytes
+	}
+	return NewFetchBudget(concurrency, bytes)
 }
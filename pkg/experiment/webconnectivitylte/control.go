@@ -24,6 +24,9 @@ type EndpointMeasurementsStarter interface {
 
 	// startSecureFlows is like startCleartextFlows but for HTTPS.
 	startSecureFlows(ctx context.Context, ps *prioritySelector, addresses []DNSEntry)
+
+	// startQUICFlows starts an HTTP/3 measurement flow for each IP addr using the given port.
+	startQUICFlows(ctx context.Context, addresses []DNSEntry, port string)
 }
 
 // Control issues a Control request and saves the results
@@ -35,6 +38,10 @@ type Control struct {
 	// Addresses contains the MANDATORY addresses we've looked up.
 	Addresses []string
 
+	// EnableQUIC OPTIONALLY asks the TH to discover and measure the HTTP/3 endpoint
+	// and, if the TH discovers it, starts measuring it.
+	EnableQUIC bool
+
 	// ExtraMeasurementsStarter is MANDATORY and allows this struct to
 	// start additional measurements using new TH-discovered addrs.
 	ExtraMeasurementsStarter EndpointMeasurementsStarter
@@ -96,7 +103,8 @@ func (c *Control) Run(parentCtx context.Context) {
 			"Accept-Language": {model.HTTPHeaderAcceptLanguage},
 			"User-Agent":      {model.HTTPHeaderUserAgent},
 		},
-		TCPConnect: endpoints,
+		TCPConnect:   endpoints,
+		XQUICEnabled: c.EnableQUIC,
 	}
 	c.TestKeys.SetControlRequest(creq)
 
@@ -130,6 +138,39 @@ func (c *Control) Run(parentCtx context.Context) {
 	// if the TH returned us addresses we did not previously were
 	// aware of, make sure we also measure them
 	c.maybeStartExtraMeasurements(parentCtx, cresp.DNS.Addrs)
+
+	// if the TH discovered an HTTP/3 endpoint, make sure we also measure it
+	c.maybeStartQUICMeasurements(parentCtx, cresp.HTTPRequest.DiscoveredH3Endpoint, cresp.DNS.Addrs)
+}
+
+// This function determines whether we should start background measurements
+// for the HTTP/3 endpoint discovered by the TH using Alt-Svc.
+func (c *Control) maybeStartQUICMeasurements(ctx context.Context, h3Endpoint string, thAddrs []string) {
+	if !c.EnableQUIC || h3Endpoint == "" {
+		return
+	}
+	host, port, err := net.SplitHostPort(h3Endpoint)
+	if err != nil {
+		c.Logger.Warnf("cannot parse HTTP/3 endpoint discovered by the TH: %s", err.Error())
+		return
+	}
+	if host != c.URL.Hostname() {
+		// This happens when the TH followed redirects to another domain, in which
+		// case the endpoint is not an alternative service for the URL we're measuring.
+		c.Logger.Infof("ignoring HTTP/3 endpoint for another domain: %s", h3Endpoint)
+		return
+	}
+
+	c.Logger.Infof("HTTP/3 endpoint discovered by the TH: %s", h3Endpoint)
+
+	var entries []DNSEntry
+	for _, addr := range c.Addresses {
+		entries = append(entries, DNSEntry{Addr: addr, Flags: 0})
+	}
+	for _, addr := range thAddrs {
+		entries = append(entries, DNSEntry{Addr: addr, Flags: 0})
+	}
+	c.ExtraMeasurementsStarter.startQUICFlows(ctx, entries, port)
 }
 
 // This function determines whether we should start new
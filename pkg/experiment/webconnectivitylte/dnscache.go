@@ -20,8 +20,21 @@ const (
 
 	// DNSAddrFlagHTTPS means we discovered this addr using the DNS-over-HTTPS resolver.
 	DNSAddrFlagHTTPS
+
+	// DNSAddrFlagTCP means we discovered this addr using a DNS-over-TCP resolver.
+	DNSAddrFlagTCP
+
+	// DNSAddrFlagTLS means we discovered this addr using a DNS-over-TLS resolver.
+	DNSAddrFlagTLS
+
+	// DNSAddrFlagQUIC means we discovered this addr using a DNS-over-QUIC resolver.
+	DNSAddrFlagQUIC
 )
 
+// dnsAddrFlagsOther contains the flags of the resolvers that we only use when
+// they have been explicitly configured using [Config].
+const dnsAddrFlagsOther = DNSAddrFlagTCP | DNSAddrFlagTLS | DNSAddrFlagQUIC
+
 // DNSCache wraps a model.Resolver to provide DNS caching.
 //
 // The zero value is invalid; please, use NewDNSCache to construct.
@@ -8,10 +8,12 @@ package webconnectivitylte
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"slices"
 	"sync"
 	"time"
 
@@ -64,6 +66,21 @@ type DNSResolvers struct {
 	// CookieJar contains the OPTIONAL cookie jar, used for redirects.
 	CookieJar http.CookieJar
 
+	// EnableECH OPTIONALLY instructs us to perform additional TLS handshakes using the
+	// Encrypted Client Hello config list advertised by DNS HTTPS records. Like Session,
+	// this field only makes sense during the first iteration.
+	EnableECH bool
+
+	// EnableQUIC OPTIONALLY instructs us to measure the HTTP/3 endpoints advertised
+	// by DNS HTTPS records and discovered by the test helper using Alt-Svc. Like
+	// Session, this field only makes sense during the first iteration.
+	EnableQUIC bool
+
+	// FetchBudget is the OPTIONAL budget for fetching the webpage from the
+	// addresses other than the highest priority one. When this field is nil,
+	// we only fetch the webpage from the highest priority address.
+	FetchBudget *FetchBudget
+
 	// Referer contains the OPTIONAL referer, used for redirects.
 	Referer string
 
@@ -78,9 +95,39 @@ type DNSResolvers struct {
 	// empty, we are not going to try to contact any test helper.
 	TestHelpers []model.OOAPIService
 
+	// Resolvers contains the OPTIONAL URLs of the resolvers to use. When this
+	// field is empty, we use the system resolver and the UDP resolver. We support
+	// these URL schemes (and the related default ports):
+	//
+	//	- "system" (e.g., "system:///") for the system resolver;
+	//
+	//	- "udp" (e.g., "udp://8.8.8.8:53") for DNS-over-UDP;
+	//
+	//	- "tcp" (e.g., "tcp://8.8.8.8:53") for DNS-over-TCP;
+	//
+	//	- "dot" (e.g., "dot://8.8.8.8:853") for DNS-over-TLS;
+	//
+	//	- "https" (e.g., "https://dns.google/dns-query") for DNS-over-HTTPS;
+	//
+	//	- "doq" (e.g., "doq://94.140.14.14:853") for DNS-over-QUIC.
+	//
+	// Except for DNS-over-HTTPS, the URL host MUST be an IP address. We tag each
+	// lookup with "resolver=<URL>" to attribute the results to each resolver.
+	Resolvers []string
+
 	// UDPAddress is the OPTIONAL address of the UDP resolver to use. If this
 	// field is not set we use a default one (e.g., `8.8.8.8:53`).
 	UDPAddress string
+
+	// quicPrioSelector is the priority selector for HTTP/3 flows, which is
+	// only initialized by Run when EnableQUIC is true.
+	quicPrioSelector *prioritySelector
+
+	// quicMu protects quicStarted.
+	quicMu sync.Mutex
+
+	// quicStarted contains the HTTP/3 endpoints we've already started measuring.
+	quicStarted map[string]bool
 }
 
 // Start starts this task in a background goroutine.
@@ -99,27 +146,31 @@ var MaybeSortAddresses = func(entries []DNSEntry) {
 
 // run performs a DNS lookup and returns the looked up addrs
 func (t *DNSResolvers) run(parentCtx context.Context) []DNSEntry {
+	udpAddress := t.udpAddress()
+	resolvers := t.resolvers(udpAddress)
+
 	// create output channels for the lookup
-	systemOut := make(chan []string)
-	udpOut := make(chan []string)
+	resolversOut := make([]chan []string, len(resolvers))
 	httpsOut := make(chan []string)
 	whoamiSystemV4Out := make(chan []webconnectivityalgo.DNSWhoamiInfoEntry)
 	whoamiUDPv4Out := make(chan []webconnectivityalgo.DNSWhoamiInfoEntry)
 
 	// TODO(https://github.com/ooni/probe/issues/1521): detecting DNS interception
 
-	udpAddress := t.udpAddress()
-
 	// start asynchronous lookups
-	go t.lookupHostSystem(parentCtx, systemOut)
-	go t.lookupHostUDP(parentCtx, udpAddress, udpOut)
+	for idx, resolverURL := range resolvers {
+		resolversOut[idx] = make(chan []string)
+		go t.lookupHostResolver(parentCtx, resolverURL, resolversOut[idx])
+	}
 	go t.lookupHostDNSOverHTTPS(parentCtx, httpsOut)
 	go t.whoamiSystemV4(parentCtx, whoamiSystemV4Out)
 	go t.whoamiUDPv4(parentCtx, udpAddress, whoamiUDPv4Out)
 
 	// collect resulting IP addresses (which may be nil/empty lists)
-	systemAddrs := <-systemOut
-	udpAddrs := <-udpOut
+	resolversAddrs := make([][]string, len(resolvers))
+	for idx := range resolvers {
+		resolversAddrs[idx] = <-resolversOut[idx]
+	}
 	httpsAddrs := <-httpsOut
 
 	// collect whoami results (which also may be nil/empty)
@@ -132,19 +183,15 @@ func (t *DNSResolvers) run(parentCtx context.Context) []DNSEntry {
 
 	// merge the resolved IP addresses
 	merged := map[string]*DNSEntry{}
-	for _, addr := range systemAddrs {
-		if _, found := merged[addr]; !found {
-			merged[addr] = &DNSEntry{}
+	for idx, resolverURL := range resolvers {
+		flags := dnsResolverFlags(resolverURL)
+		for _, addr := range resolversAddrs[idx] {
+			if _, found := merged[addr]; !found {
+				merged[addr] = &DNSEntry{}
+			}
+			merged[addr].Addr = addr
+			merged[addr].Flags |= flags
 		}
-		merged[addr].Addr = addr
-		merged[addr].Flags |= DNSAddrFlagSystemResolver
-	}
-	for _, addr := range udpAddrs {
-		if _, found := merged[addr]; !found {
-			merged[addr] = &DNSEntry{}
-		}
-		merged[addr].Addr = addr
-		merged[addr].Flags |= DNSAddrFlagUDP
 	}
 	for _, addr := range httpsAddrs {
 		if _, found := merged[addr]; !found {
@@ -164,6 +211,113 @@ func (t *DNSResolvers) run(parentCtx context.Context) []DNSEntry {
 	return entries
 }
 
+// resolvers returns the URLs of the resolvers we should be using.
+func (t *DNSResolvers) resolvers(udpAddress string) []string {
+	if len(t.Resolvers) > 0 {
+		return t.Resolvers
+	}
+	return []string{dnsResolverSystemURL, dnsResolverURL("udp", udpAddress)}
+}
+
+// dnsResolverSystemURL is the URL of the system resolver.
+const dnsResolverSystemURL = "system:///"
+
+// dnsResolverURL returns the URL of a resolver given its scheme and endpoint address.
+func dnsResolverURL(scheme, address string) string {
+	URL := &url.URL{Scheme: scheme, Host: address}
+	return URL.String()
+}
+
+// dnsResolverTag returns the tag attributing a lookup to the resolver with the given URL.
+func dnsResolverTag(resolverURL string) string {
+	return fmt.Sprintf("resolver=%s", resolverURL)
+}
+
+// errInvalidDNSResolver indicates that we do not support a resolver URL.
+var errInvalidDNSResolver = errors.New("webconnectivity: invalid DNS resolver URL")
+
+// dnsResolverValidateURL returns an error if the resolver with the given URL
+// uses a scheme we do not support or, except for DNS-over-HTTPS and the system
+// resolver, if the URL host is not an IP address.
+func dnsResolverValidateURL(resolverURL string) error {
+	URL, err := url.Parse(resolverURL)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errInvalidDNSResolver, err.Error())
+	}
+	switch URL.Scheme {
+	case "system", "https":
+		return nil
+	case "udp", "tcp", "dot", "doq":
+		if net.ParseIP(URL.Hostname()) == nil {
+			return fmt.Errorf("%w: %s: host is not an IP address", errInvalidDNSResolver, resolverURL)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %s: unsupported scheme", errInvalidDNSResolver, resolverURL)
+	}
+}
+
+// dnsResolverFlags returns the [DNSEntry] flags of the resolver with the given URL.
+func dnsResolverFlags(resolverURL string) int64 {
+	URL, err := url.Parse(resolverURL)
+	if err != nil {
+		return 0
+	}
+	switch URL.Scheme {
+	case "system":
+		return DNSAddrFlagSystemResolver
+	case "udp":
+		return DNSAddrFlagUDP
+	case "tcp":
+		return DNSAddrFlagTCP
+	case "dot":
+		return DNSAddrFlagTLS
+	case "https":
+		return DNSAddrFlagHTTPS
+	case "doq":
+		return DNSAddrFlagQUIC
+	default:
+		return 0
+	}
+}
+
+// dnsResolverAddress returns the endpoint address of the resolver with the
+// given URL, using the given default port when the URL contains no port.
+func dnsResolverAddress(URL *url.URL, defaultPort string) string {
+	if URL.Port() != "" {
+		return URL.Host
+	}
+	return net.JoinHostPort(URL.Hostname(), defaultPort)
+}
+
+// lookupHostResolver performs a DNS lookup using the resolver with the given URL. This
+// function must always emit an ouput on the [out] channel to synchronize with the caller func.
+func (t *DNSResolvers) lookupHostResolver(parentCtx context.Context, resolverURL string, out chan<- []string) {
+	URL, err := url.Parse(resolverURL)
+	if err != nil {
+		t.Logger.Warnf("cannot parse resolver URL %s: %s", resolverURL, err.Error())
+		out <- []string{}
+		return
+	}
+	switch URL.Scheme {
+	case "system":
+		t.lookupHostSystem(parentCtx, out)
+	case "udp":
+		t.lookupHostUDP(parentCtx, dnsResolverAddress(URL, "53"), out)
+	case "tcp":
+		t.lookupHostDNSOverTCP(parentCtx, dnsResolverAddress(URL, "53"), out)
+	case "dot":
+		t.lookupHostDNSOverTLS(parentCtx, dnsResolverAddress(URL, "853"), out)
+	case "https":
+		t.lookupHostDNSOverHTTPSURL(parentCtx, resolverURL, out)
+	case "doq":
+		t.lookupHostDNSOverQUIC(parentCtx, dnsResolverAddress(URL, "853"), out)
+	default:
+		t.Logger.Warnf("unsupported resolver URL: %s", resolverURL)
+		out <- []string{}
+	}
+}
+
 // Run runs this task in the current goroutine.
 func (t *DNSResolvers) Run(parentCtx context.Context) {
 	var (
@@ -189,6 +343,18 @@ func (t *DNSResolvers) Run(parentCtx context.Context) {
 	// create priority selector
 	ps := newPrioritySelector(parentCtx, t.ZeroTime, t.TestKeys, t.Logger, addresses)
 
+	// possibly create a distinct priority selector for HTTP/3 flows, which we must
+	// do before starting the control flow, since it may start HTTP/3 flows
+	if t.EnableQUIC {
+		t.quicPrioSelector = newPrioritySelector(parentCtx, t.ZeroTime, t.TestKeys, t.Logger, addresses)
+	}
+
+	// possibly use the HTTPS record to start HTTP/3 flows and ECH flows
+	if t.EnableQUIC || t.EnableECH {
+		t.WaitGroup.Add(1)
+		go t.lookupHTTPSAndStartExtraFlows(parentCtx, addresses)
+	}
+
 	// fan out a number of child async tasks to use the IP addrs
 	t.startCleartextFlows(parentCtx, ps, addresses)
 	t.startSecureFlows(parentCtx, ps, addresses)
@@ -223,7 +389,8 @@ func (t *DNSResolvers) lookupHostSystem(parentCtx context.Context, out chan<- []
 	index := t.IDGenerator.NewIDForGetaddrinfo()
 
 	// create trace
-	trace := measurexlite.NewTrace(index, t.ZeroTime, "classic", fmt.Sprintf("depth=%d", t.Depth))
+	trace := measurexlite.NewTrace(
+		index, t.ZeroTime, "classic", fmt.Sprintf("depth=%d", t.Depth), dnsResolverTag(dnsResolverSystemURL))
 
 	// start the operation logger
 	ol := logx.NewOperationLogger(
@@ -250,7 +417,8 @@ func (t *DNSResolvers) lookupHostUDP(parentCtx context.Context, udpAddress strin
 	index := t.IDGenerator.NewIDForDNSOverUDP()
 
 	// create trace
-	trace := measurexlite.NewTrace(index, t.ZeroTime, fmt.Sprintf("depth=%d", t.Depth))
+	trace := measurexlite.NewTrace(
+		index, t.ZeroTime, fmt.Sprintf("depth=%d", t.Depth), dnsResolverTag(dnsResolverURL("udp", udpAddress)))
 
 	// start the operation logger
 	ol := logx.NewOperationLogger(
@@ -320,7 +488,12 @@ func (t *DNSResolvers) lookupHostDNSOverHTTPS(parentCtx context.Context, out cha
 		out <- []string{}
 		return
 	}
+	t.lookupHostDNSOverHTTPSURL(parentCtx, URL, out)
+}
 
+// lookupHostDNSOverHTTPSURL performs a DNS lookup using the DoH resolver with the given URL. This
+// function must always emit an ouput on the [out] channel to synchronize with the caller func.
+func (t *DNSResolvers) lookupHostDNSOverHTTPSURL(parentCtx context.Context, URL string, out chan<- []string) {
 	// create context with attached a timeout
 	const timeout = 4 * time.Second
 	lookupCtx, lookpCancel := context.WithTimeout(parentCtx, timeout)
@@ -330,7 +503,7 @@ func (t *DNSResolvers) lookupHostDNSOverHTTPS(parentCtx context.Context, out cha
 	index := t.IDGenerator.NewIDForDNSOverHTTPS()
 
 	// create trace
-	trace := measurexlite.NewTrace(index, t.ZeroTime, fmt.Sprintf("depth=%d", t.Depth))
+	trace := measurexlite.NewTrace(index, t.ZeroTime, fmt.Sprintf("depth=%d", t.Depth), dnsResolverTag(URL))
 
 	// start the operation logger
 	ol := logx.NewOperationLogger(
@@ -356,6 +529,139 @@ func (t *DNSResolvers) lookupHostDNSOverHTTPS(parentCtx context.Context, out cha
 	out <- addrs
 }
 
+// lookupHostDNSOverTCP performs a DNS lookup using a DNS-over-TCP resolver. This function must
+// always emit an ouput on the [out] channel to synchronize with the caller func.
+func (t *DNSResolvers) lookupHostDNSOverTCP(parentCtx context.Context, address string, out chan<- []string) {
+	// create context with attached a timeout
+	const timeout = 4 * time.Second
+	lookupCtx, lookpCancel := context.WithTimeout(parentCtx, timeout)
+	defer lookpCancel()
+
+	// create trace's index
+	index := t.IDGenerator.NewIDForDNSOverTCP()
+
+	// create trace
+	trace := measurexlite.NewTrace(
+		index, t.ZeroTime, fmt.Sprintf("depth=%d", t.Depth), dnsResolverTag(dnsResolverURL("tcp", address)))
+
+	// start the operation logger
+	ol := logx.NewOperationLogger(
+		t.Logger, "[#%d] lookup %s using %s/tcp", index, t.Domain, address,
+	)
+
+	// runs the lookup
+	dialer := trace.NewDialerWithoutResolver(t.Logger)
+	reso := trace.NewParallelDNSOverTCPResolver(t.Logger, dialer, address)
+	addrs, err := reso.LookupHost(lookupCtx, t.Domain)
+
+	// saves the results making sure we split Do53 queries from other queries
+	do53, other := t.do53SplitQueries(trace.DNSLookupsFromRoundTrip())
+	t.TestKeys.AppendQueries(do53...)
+	t.TestKeys.WithTestKeysDo53(func(tkd *TestKeysDo53) {
+		tkd.Queries = append(tkd.Queries, other...)
+		tkd.NetworkEvents = append(tkd.NetworkEvents, trace.NetworkEvents()...)
+	})
+
+	ol.Stop(err)
+	out <- addrs
+}
+
+// lookupHostDNSOverTLS performs a DNS lookup using a DoT resolver. This function must
+// always emit an ouput on the [out] channel to synchronize with the caller func.
+func (t *DNSResolvers) lookupHostDNSOverTLS(parentCtx context.Context, address string, out chan<- []string) {
+	// create context with attached a timeout
+	const timeout = 4 * time.Second
+	lookupCtx, lookpCancel := context.WithTimeout(parentCtx, timeout)
+	defer lookpCancel()
+
+	// create trace's index
+	index := t.IDGenerator.NewIDForDNSOverTLS()
+
+	// create trace
+	trace := measurexlite.NewTrace(
+		index, t.ZeroTime, fmt.Sprintf("depth=%d", t.Depth), dnsResolverTag(dnsResolverURL("dot", address)))
+
+	// start the operation logger
+	ol := logx.NewOperationLogger(
+		t.Logger, "[#%d] lookup %s using %s/dot", index, t.Domain, address,
+	)
+
+	// runs the lookup
+	dialer := netxlite.NewTLSDialer(
+		trace.NewDialerWithoutResolver(t.Logger),
+		trace.NewTLSHandshakerStdlib(t.Logger),
+	)
+	reso := trace.NewParallelDNSOverTLSResolver(t.Logger, dialer, address)
+	addrs, err := reso.LookupHost(lookupCtx, t.Domain)
+
+	// save results making sure we properly split DoT queries from other queries
+	dot, other := t.encryptedSplitQueries("dot", trace.DNSLookupsFromRoundTrip())
+	t.TestKeys.AppendQueries(dot...)
+	t.TestKeys.WithTestKeysDoT(func(tkdt *TestKeysDoT) {
+		tkdt.Queries = append(tkdt.Queries, other...)
+		tkdt.NetworkEvents = append(tkdt.NetworkEvents, trace.NetworkEvents()...)
+		tkdt.TCPConnect = append(tkdt.TCPConnect, trace.TCPConnects()...)
+		tkdt.TLSHandshakes = append(tkdt.TLSHandshakes, trace.TLSHandshakes()...)
+	})
+
+	ol.Stop(err)
+	out <- addrs
+}
+
+// lookupHostDNSOverQUIC performs a DNS lookup using a DoQ resolver. This function must
+// always emit an ouput on the [out] channel to synchronize with the caller func.
+func (t *DNSResolvers) lookupHostDNSOverQUIC(parentCtx context.Context, address string, out chan<- []string) {
+	// create context with attached a timeout
+	const timeout = 4 * time.Second
+	lookupCtx, lookpCancel := context.WithTimeout(parentCtx, timeout)
+	defer lookpCancel()
+
+	// create trace's index
+	index := t.IDGenerator.NewIDForDNSOverQUIC()
+
+	// create trace
+	trace := measurexlite.NewTrace(
+		index, t.ZeroTime, fmt.Sprintf("depth=%d", t.Depth), dnsResolverTag(dnsResolverURL("doq", address)))
+
+	// start the operation logger
+	ol := logx.NewOperationLogger(
+		t.Logger, "[#%d] lookup %s using %s/doq", index, t.Domain, address,
+	)
+
+	// runs the lookup
+	dialer := trace.NewQUICDialerWithoutResolver(trace.NewUDPListener(), t.Logger)
+	reso := trace.NewParallelDNSOverQUICResolver(t.Logger, dialer, address)
+	addrs, err := reso.LookupHost(lookupCtx, t.Domain)
+	reso.CloseIdleConnections()
+
+	// save results making sure we properly split DoQ queries from other queries
+	doq, other := t.encryptedSplitQueries("doq", trace.DNSLookupsFromRoundTrip())
+	t.TestKeys.AppendQueries(doq...)
+	t.TestKeys.WithTestKeysDoT(func(tkdt *TestKeysDoT) {
+		tkdt.Queries = append(tkdt.Queries, other...)
+		tkdt.NetworkEvents = append(tkdt.NetworkEvents, trace.NetworkEvents()...)
+		tkdt.QUICHandshakes = append(tkdt.QUICHandshakes, trace.QUICHandshakes()...)
+	})
+
+	ol.Stop(err)
+	out <- addrs
+}
+
+// Divides queries generated by an encrypted resolver in queries using the given
+// engine (e.g., "dot") and other queries.
+func (t *DNSResolvers) encryptedSplitQueries(engine string,
+	input []*model.ArchivalDNSLookupResult) (proper, other []*model.ArchivalDNSLookupResult) {
+	for _, query := range input {
+		switch query.Engine {
+		case engine:
+			proper = append(proper, query)
+		default:
+			other = append(other, query)
+		}
+	}
+	return
+}
+
 // Divides queries generated by DoH in DoH-proper queries and other queries.
 func (t *DNSResolvers) dohSplitQueries(
 	input []*model.ArchivalDNSLookupResult) (doh, other []*model.ArchivalDNSLookupResult) {
@@ -405,10 +711,12 @@ func (t *DNSResolvers) startCleartextFlows(
 			ZeroTime:                t.ZeroTime,
 			WaitGroup:               t.WaitGroup,
 			CookieJar:               t.CookieJar,
+			FetchBudget:             t.FetchBudget,
 			FollowRedirects:         t.URL.Scheme == "http",
 			HostHeader:              t.URL.Host,
 			PrioSelector:            ps,
 			Referer:                 t.Referer,
+			Resolvers:               t.Resolvers,
 			UDPAddress:              t.UDPAddress,
 			URLPath:                 t.URL.Path,
 			URLRawQuery:             t.URL.RawQuery,
@@ -457,11 +765,13 @@ func (t *DNSResolvers) startSecureFlows(
 			WaitGroup:               t.WaitGroup,
 			ALPN:                    []string{"h2", "http/1.1"},
 			CookieJar:               t.CookieJar,
+			FetchBudget:             t.FetchBudget,
 			FollowRedirects:         t.URL.Scheme == "https",
 			SNI:                     t.URL.Hostname(),
 			HostHeader:              t.URL.Host,
 			PrioSelector:            ps,
 			Referer:                 t.Referer,
+			Resolvers:               t.Resolvers,
 			UDPAddress:              t.UDPAddress,
 			URLPath:                 t.URL.Path,
 			URLRawQuery:             t.URL.RawQuery,
@@ -485,6 +795,7 @@ func (t *DNSResolvers) maybeStartControlFlow(
 		}
 		ctrl := &Control{
 			Addresses:                addrs,
+			EnableQUIC:               t.EnableQUIC,
 			ExtraMeasurementsStarter: t, // allows starting follow-up measurement flows
 			Logger:                   t.Logger,
 			PrioSelector:             ps,
@@ -497,3 +808,155 @@ func (t *DNSResolvers) maybeStartControlFlow(
 		ctrl.Start(ctx)
 	}
 }
+
+// lookupHTTPSAndStartExtraFlows queries for the HTTPS record of the domain using the
+// UDP resolver and starts HTTP/3 flows when the record advertises "h3" as well as ECH
+// flows when the record contains an ECH config list.
+func (t *DNSResolvers) lookupHTTPSAndStartExtraFlows(parentCtx context.Context, addresses []DNSEntry) {
+	defer t.WaitGroup.Done() // synchronize with the parent
+
+	if t.URL.Scheme != "https" || t.URL.Port() != "" {
+		// The HTTPS record we query describes the https://<domain>/ origin, so
+		// it is not applicable to other schemes and ports.
+		return
+	}
+
+	// create context with attached a timeout
+	const timeout = 4 * time.Second
+	lookupCtx, lookpCancel := context.WithTimeout(parentCtx, timeout)
+	defer lookpCancel()
+
+	// create trace's index
+	index := t.IDGenerator.NewIDForDNSOverUDP()
+
+	// create trace
+	udpAddress := t.udpAddress()
+	trace := measurexlite.NewTrace(
+		index, t.ZeroTime, fmt.Sprintf("depth=%d", t.Depth), dnsResolverTag(dnsResolverURL("udp", udpAddress)))
+
+	// start the operation logger
+	ol := logx.NewOperationLogger(
+		t.Logger, "[#%d] lookup HTTPS %s using %s", index, t.Domain, udpAddress,
+	)
+
+	// runs the lookup
+	netx := &netxlite.Netx{}
+	dialer := netx.NewDialerWithoutResolver(t.Logger)
+	reso := trace.NewParallelUDPResolver(t.Logger, dialer, udpAddress)
+	https, err := reso.LookupHTTPS(lookupCtx, t.Domain)
+
+	// saves the results along with other queries not used for determining addresses
+	t.TestKeys.WithTestKeysDo53(func(tkd *TestKeysDo53) {
+		tkd.Queries = append(tkd.Queries, trace.DNSLookupsFromRoundTrip()...)
+		tkd.NetworkEvents = append(tkd.NetworkEvents, trace.NetworkEvents()...)
+	})
+
+	ol.Stop(err)
+	if err != nil {
+		return
+	}
+
+	if t.EnableECH {
+		t.maybeStartECHFlows(parentCtx, addresses, https)
+	}
+	if t.EnableQUIC {
+		t.maybeStartQUICFlows(parentCtx, addresses, https)
+	}
+}
+
+// maybeStartQUICFlows starts HTTP/3 flows for the resolved addresses as well as for
+// the addresses hints when the given HTTPS record advertises "h3".
+func (t *DNSResolvers) maybeStartQUICFlows(ctx context.Context, addresses []DNSEntry, https *model.HTTPSSvc) {
+	if !slices.Contains(https.ALPN, "h3") {
+		t.Logger.Infof("HTTPS record for %s does not advertise h3", t.Domain)
+		return
+	}
+
+	// measure the resolved addresses as well as the addresses hints
+	entries := slices.Clone(addresses)
+	for _, addr := range append(slices.Clone(https.IPv4), https.IPv6...) {
+		entries = append(entries, DNSEntry{Addr: addr, Flags: 0})
+	}
+	t.startQUICFlows(ctx, entries, "443")
+}
+
+// maybeStartECHFlows starts a TCP+TLS flow using ECH for each resolved address when
+// the given HTTPS record contains an ECH config list. These flows only check for
+// connectivity and we compare them with the secure flows not using ECH.
+func (t *DNSResolvers) maybeStartECHFlows(ctx context.Context, addresses []DNSEntry, https *model.HTTPSSvc) {
+	if len(https.Ech) <= 0 {
+		t.Logger.Infof("HTTPS record for %s does not contain an ECH config list", t.Domain)
+		return
+	}
+	for _, addr := range addresses {
+		task := &SecureFlow{
+			Address:                 net.JoinHostPort(addr.Addr, "443"),
+			Classic:                 false,
+			Depth:                   t.Depth,
+			DNSCache:                t.DNSCache,
+			DNSOverHTTPSURLProvider: t.DNSOverHTTPSURLProvider,
+			IDGenerator:             t.IDGenerator,
+			Logger:                  t.Logger,
+			NumRedirects:            t.NumRedirects,
+			TestKeys:                t.TestKeys,
+			ZeroTime:                t.ZeroTime,
+			WaitGroup:               t.WaitGroup,
+			ALPN:                    []string{"h2", "http/1.1"},
+			CookieJar:               t.CookieJar,
+			ECHConfigList:           https.Ech,
+			FollowRedirects:         false,
+			SNI:                     t.URL.Hostname(),
+			HostHeader:              t.URL.Host,
+			PrioSelector:            nil,
+			Referer:                 t.Referer,
+			Resolvers:               t.Resolvers,
+			UDPAddress:              t.UDPAddress,
+			URLPath:                 t.URL.Path,
+			URLRawQuery:             t.URL.RawQuery,
+		}
+		task.Start(ctx)
+	}
+}
+
+// startQUICFlows starts an HTTP/3 measurement flow for each IP addr using the given
+// port, unless we have already started measuring the resulting endpoint.
+func (t *DNSResolvers) startQUICFlows(
+	ctx context.Context,
+	addresses []DNSEntry,
+	port string,
+) {
+	if t.quicPrioSelector == nil {
+		// Measuring HTTP/3 has not been enabled.
+		return
+	}
+	for _, addr := range addresses {
+		endpoint := net.JoinHostPort(addr.Addr, port)
+		t.quicMu.Lock()
+		if t.quicStarted == nil {
+			t.quicStarted = map[string]bool{}
+		}
+		started := t.quicStarted[endpoint]
+		t.quicStarted[endpoint] = true
+		t.quicMu.Unlock()
+		if started {
+			continue
+		}
+		task := &QUICFlow{
+			Address:      endpoint,
+			Depth:        t.Depth,
+			IDGenerator:  t.IDGenerator,
+			Logger:       t.Logger,
+			TestKeys:     t.TestKeys,
+			ZeroTime:     t.ZeroTime,
+			WaitGroup:    t.WaitGroup,
+			CookieJar:    t.CookieJar,
+			HostHeader:   t.URL.Hostname(),
+			PrioSelector: t.quicPrioSelector,
+			Referer:      t.Referer,
+			SNI:          t.URL.Hostname(),
+			URLPath:      t.URL.Path,
+			URLRawQuery:  t.URL.RawQuery,
+		}
+		task.Start(ctx)
+	}
+}
@@ -0,0 +1,143 @@
+package webconnectivitylte
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/geoipx"
+	"github.com/ooni/probe-engine/pkg/minipipeline"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/must"
+	"github.com/ooni/probe-engine/pkg/netemx"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+	"github.com/ooni/probe-engine/pkg/webconnectivityqa"
+)
+
+func TestDNSResolverFlags(t *testing.T) {
+	cases := map[string]int64{
+		"system:///":                   DNSAddrFlagSystemResolver,
+		"udp://8.8.8.8:53":             DNSAddrFlagUDP,
+		"tcp://8.8.8.8:53":             DNSAddrFlagTCP,
+		"dot://8.8.8.8:853":            DNSAddrFlagTLS,
+		"https://dns.google/dns-query": DNSAddrFlagHTTPS,
+		"doq://94.140.14.14:853":       DNSAddrFlagQUIC,
+		"antani://8.8.8.8:53":          0,
+		"\t":                           0,
+	}
+	for resolverURL, expect := range cases {
+		t.Run(resolverURL, func(t *testing.T) {
+			if got := dnsResolverFlags(resolverURL); got != expect {
+				t.Fatal("expected", expect, "got", got)
+			}
+		})
+	}
+}
+
+func TestDNSResolverValidateURL(t *testing.T) {
+	cases := map[string]bool{
+		"system:///":                   true,
+		"udp://8.8.8.8:53":             true,
+		"tcp://8.8.8.8":                true,
+		"dot://[2001:4860:4860::8888]": true,
+		"https://dns.google/dns-query": true,
+		"doq://94.140.14.14:853":       true,
+		"udp://dns.google:53":          false,
+		"dot://dns.google":             false,
+		"doq://dns.adguard.com:853":    false,
+		"antani://8.8.8.8:53":          false,
+		"\t":                           false,
+	}
+	for resolverURL, valid := range cases {
+		t.Run(resolverURL, func(t *testing.T) {
+			err := dnsResolverValidateURL(resolverURL)
+			switch {
+			case valid && err != nil:
+				t.Fatal("unexpected error", err)
+			case !valid && !errors.Is(err, errInvalidDNSResolver):
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
+
+func TestMeasurerRejectsInvalidDNSResolvers(t *testing.T) {
+	measurer := NewExperimentMeasurer(&Config{
+		DNSResolvers: []string{"system:///", "dot://dns.google:853"},
+	})
+	args := &model.ExperimentArgs{
+		Measurement: &model.Measurement{Input: "https://www.example.com/"},
+	}
+	if err := measurer.Run(context.Background(), args); !errors.Is(err, errInvalidDNSResolver) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestDNSResolversResolvers(t *testing.T) {
+	t.Run("by default we use the system and the UDP resolvers", func(t *testing.T) {
+		t0 := &DNSResolvers{}
+		expect := []string{"system:///", "udp://8.8.8.8:53"}
+		if diff := cmp.Diff(expect, t0.resolvers("8.8.8.8:53")); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we use the configured resolvers otherwise", func(t *testing.T) {
+		expect := []string{"dot://8.8.8.8:853", "https://dns.google/dns-query"}
+		t0 := &DNSResolvers{Resolvers: expect}
+		if diff := cmp.Diff(expect, t0.resolvers("8.8.8.8:53")); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestDNSResolversWithConfiguredResolvers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+
+	// the ISP resolver, which the system resolver uses, lies about the website
+	// addresses, while the root resolver we configure returns the correct addrs
+	udpResolverURL := "udp://" + net.JoinHostPort(netemx.RootResolverAddress, "53")
+	tc := &webconnectivityqa.TestCase{
+		Name:  "dnsHijackingByTheISPResolver",
+		Input: "http://www.example.com/",
+		Configure: func(env *netemx.QAEnv) {
+			env.ISPResolverConfig().RemoveRecord("www.example.com")
+			runtimex.Try0(env.ISPResolverConfig().AddRecord("www.example.com", "", "10.10.34.35"))
+		},
+	}
+	measurer := NewExperimentMeasurer(&Config{
+		DNSResolvers: []string{"system:///", udpResolverURL},
+	})
+	measurement, err := webconnectivityqa.MeasureTestCase(measurer, tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// make sure we tagged the DNS lookups with the resolver URL
+	var webmeas minipipeline.WebMeasurement
+	must.UnmarshalJSON(must.MarshalJSON(measurement), &webmeas)
+	lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
+	container := runtimex.Try1(minipipeline.IngestWebMeasurement(lookupper, &webmeas))
+	resolvers := map[string]bool{}
+	for _, obs := range container.DNSLookupSuccesses {
+		resolvers[obs.DNSResolver.UnwrapOr("")] = true
+	}
+	for _, resolverURL := range []string{"system:///", udpResolverURL} {
+		if !resolvers[resolverURL] {
+			t.Fatal("missing DNS lookups using", resolverURL, resolvers)
+		}
+	}
+
+	// make sure the analysis attributes the invalid addresses to the system resolver
+	analysis := minipipeline.AnalyzeWebObservationsWithoutLinearAnalysis(lookupper, container)
+	if diff := cmp.Diff([]string{"system:///"}, analysis.DNSResolverWithInvalidAddresses.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+	if !analysis.DNSResolverWithValidAddress.Contains(udpResolverURL) {
+		t.Fatal("expected valid addresses for", udpResolverURL, analysis.DNSResolverWithValidAddress)
+	}
+}
@@ -0,0 +1,75 @@
+package webconnectivitylte
+
+//
+// Budget for fetching the webpage from all the addresses
+//
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// FetchBudget bounds the extra webpage fetches we perform when fetching
+// the webpage from every address (see [Config]). That is, the fetches
+// other than the one granted by the [*prioritySelector].
+//
+// A nil [*FetchBudget] does not allow any extra fetch.
+type FetchBudget struct {
+	// bytes is the number of body bytes we can still download.
+	bytes *atomic.Int64
+
+	// sema limits the number of concurrent extra fetches.
+	sema chan bool
+}
+
+// NewFetchBudget creates a new [*FetchBudget] allowing up to [concurrency]
+// parallel extra fetches downloading at most [bytes] body bytes overall.
+func NewFetchBudget(concurrency, bytes int64) *FetchBudget {
+	count := &atomic.Int64{}
+	count.Add(bytes)
+	return &FetchBudget{
+		bytes: count,
+		sema:  make(chan bool, max(concurrency, 1)),
+	}
+}
+
+// fetchBudgetMaxBodySize is the maximum number of body bytes we download
+// when fetching the webpage, including when performing extra fetches.
+const fetchBudgetMaxBodySize = 1 << 19
+
+// Acquire blocks until we are allowed to perform one more extra fetch, reserves the
+// minimum between [limit] and the remaining budget, and returns the number of reserved
+// bytes, which is the maximum body size the extra fetch can download, and true. It
+// returns zero and false when we've exhausted the budget or [ctx] is done. When this
+// function returns true, you MUST call Release when the extra fetch is done.
+func (fb *FetchBudget) Acquire(ctx context.Context, limit int64) (int64, bool) {
+	if fb == nil || limit <= 0 || fb.bytes.Load() <= 0 {
+		return 0, false
+	}
+	select {
+	case fb.sema <- true:
+	case <-ctx.Done():
+		return 0, false
+	}
+	// Note: we reserve the bytes atomically such that concurrent extra
+	// fetches cannot collectively download more than the budget
+	for {
+		remaining := fb.bytes.Load()
+		if remaining <= 0 {
+			<-fb.sema
+			return 0, false
+		}
+		reserved := min(limit, remaining)
+		if fb.bytes.CompareAndSwap(remaining, remaining-reserved) {
+			return reserved, true
+		}
+	}
+}
+
+// Release releases the budget acquired with Acquire given the number of bytes
+// [reserved] by Acquire and the number of body bytes [nbytes] downloaded by the
+// extra fetch, such that we refund the reserved bytes that we did not use.
+func (fb *FetchBudget) Release(reserved, nbytes int64) {
+	fb.bytes.Add(max(reserved-nbytes, 0))
+	<-fb.sema
+}
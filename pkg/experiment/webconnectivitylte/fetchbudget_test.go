@@ -0,0 +1,199 @@
+package webconnectivitylte
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ooni/probe-engine/pkg/geoipx"
+	"github.com/ooni/probe-engine/pkg/minipipeline"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/must"
+	"github.com/ooni/probe-engine/pkg/netemx"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+	"github.com/ooni/probe-engine/pkg/webconnectivityqa"
+)
+
+func TestFetchBudget(t *testing.T) {
+	t.Run("a nil budget does not allow extra fetches", func(t *testing.T) {
+		var fb *FetchBudget
+		if _, good := fb.Acquire(context.Background(), fetchBudgetMaxBodySize); good {
+			t.Fatal("expected false")
+		}
+	})
+
+	t.Run("we reserve at most the remaining budget", func(t *testing.T) {
+		fb := NewFetchBudget(2, 1000)
+		reserved, good := fb.Acquire(context.Background(), 600)
+		if !good || reserved != 600 {
+			t.Fatal("expected to reserve 600 bytes, got", reserved, good)
+		}
+		reserved, good = fb.Acquire(context.Background(), 600)
+		if !good || reserved != 400 {
+			t.Fatal("expected to reserve 400 bytes, got", reserved, good)
+		}
+	})
+
+	t.Run("we refund the reserved bytes we did not use", func(t *testing.T) {
+		fb := NewFetchBudget(1, 1000)
+		reserved, good := fb.Acquire(context.Background(), fetchBudgetMaxBodySize)
+		if !good || reserved != 1000 {
+			t.Fatal("expected to reserve 1000 bytes, got", reserved, good)
+		}
+		fb.Release(reserved, 600)
+		if value := fb.bytes.Load(); value != 400 {
+			t.Fatal("expected 400, got", value)
+		}
+	})
+
+	t.Run("we stop when we have exhausted the budget", func(t *testing.T) {
+		fb := NewFetchBudget(1, 1000)
+		reserved, good := fb.Acquire(context.Background(), fetchBudgetMaxBodySize)
+		if !good {
+			t.Fatal("expected true")
+		}
+		fb.Release(reserved, 1000)
+		if _, good := fb.Acquire(context.Background(), fetchBudgetMaxBodySize); good {
+			t.Fatal("expected false")
+		}
+	})
+
+	t.Run("we limit the number of parallel extra fetches", func(t *testing.T) {
+		fb := NewFetchBudget(1, 1000)
+		reserved, good := fb.Acquire(context.Background(), 100)
+		if !good {
+			t.Fatal("expected true")
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel() // fail immediately because we're already using the only slot
+		if _, good := fb.Acquire(ctx, 100); good {
+			t.Fatal("expected false")
+		}
+		fb.Release(reserved, 0)
+		if _, good := fb.Acquire(context.Background(), 100); !good {
+			t.Fatal("expected true")
+		}
+	})
+
+	t.Run("concurrent extra fetches cannot exceed the budget", func(t *testing.T) {
+		const (
+			concurrency = 4
+			budget      = 1000
+		)
+		fb := NewFetchBudget(concurrency, budget)
+		var (
+			mu       sync.Mutex
+			total    int64
+			wg       sync.WaitGroup
+			inflight sync.WaitGroup
+		)
+		// make sure all the goroutines hold a slot at the same time
+		inflight.Add(concurrency)
+		for idx := 0; idx < concurrency; idx++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				reserved, good := fb.Acquire(context.Background(), 600)
+				inflight.Done()
+				if !good {
+					return
+				}
+				inflight.Wait()
+				// pretend we downloaded all the bytes we could download
+				mu.Lock()
+				total += reserved
+				mu.Unlock()
+				fb.Release(reserved, reserved)
+			}()
+		}
+		wg.Wait()
+		if total != budget {
+			t.Fatal("expected to download", budget, "bytes, got", total)
+		}
+		if value := fb.bytes.Load(); value != 0 {
+			t.Fatal("expected 0, got", value)
+		}
+	})
+}
+
+func TestConfigFetchBudget(t *testing.T) {
+	t.Run("we return nil unless we should fetch from all addresses", func(t *testing.T) {
+		config := &Config{}
+		if config.fetchBudget() != nil {
+			t.Fatal("expected nil")
+		}
+	})
+
+	t.Run("we use default values when the config values are zero", func(t *testing.T) {
+		config := &Config{FetchAllAddresses: true}
+		fb := config.fetchBudget()
+		if value := cap(fb.sema); value != defaultFetchAllMaxConcurrency {
+			t.Fatal("unexpected concurrency", value)
+		}
+		if value := fb.bytes.Load(); value != defaultFetchAllMaxBytes {
+			t.Fatal("unexpected bytes", value)
+		}
+	})
+
+	t.Run("we honour the config values", func(t *testing.T) {
+		config := &Config{FetchAllAddresses: true, FetchAllMaxConcurrency: 2, FetchAllMaxBytes: 100}
+		fb := config.fetchBudget()
+		if value := cap(fb.sema); value != 2 {
+			t.Fatal("unexpected concurrency", value)
+		}
+		if value := fb.bytes.Load(); value != 100 {
+			t.Fatal("unexpected bytes", value)
+		}
+	})
+}
+
+func TestFetchAllAddresses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+
+	// the ISP resolver returns both the legitimate address and the address
+	// of a blockpage server, so only one of the fetches gets the real webpage
+	tc := &webconnectivityqa.TestCase{
+		Name:  "dnsReturnsLegitimateAndBlockpageAddresses",
+		Input: "http://www.example.com/",
+		Configure: func(env *netemx.QAEnv) {
+			env.ISPResolverConfig().RemoveRecord("www.example.com")
+			runtimex.Try0(env.ISPResolverConfig().AddRecord(
+				"www.example.com", "", netemx.AddressWwwExampleCom, netemx.AddressPublicBlockpage))
+		},
+	}
+	measurer := NewExperimentMeasurer(&Config{FetchAllAddresses: true})
+	measurement, err := webconnectivityqa.MeasureTestCase(measurer, tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// make sure we fetched from both addresses and tagged the extra fetch
+	var webmeas minipipeline.WebMeasurement
+	must.UnmarshalJSON(must.MarshalJSON(measurement), &webmeas)
+	lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
+	container := runtimex.Try1(minipipeline.IngestWebMeasurement(lookupper, &webmeas))
+	fetches := map[string]bool{}
+	for _, obs := range container.KnownTCPEndpoints {
+		if obs.HTTPFailure.UnwrapOr("unknown_failure") != "" {
+			continue
+		}
+		fetches[obs.IPAddress.UnwrapOr("")] = obs.TagExtraFetch.UnwrapOr(false)
+	}
+	if len(fetches) != 2 {
+		t.Fatal("expected two successful fetches", fetches)
+	}
+	if fetches[netemx.AddressWwwExampleCom] == fetches[netemx.AddressPublicBlockpage] {
+		t.Fatal("expected exactly one extra fetch", fetches)
+	}
+
+	// make sure the analysis flags the divergence between the two webpages
+	analysis := minipipeline.AnalyzeWebObservationsWithoutLinearAnalysis(lookupper, container)
+	if analysis.HTTPExtraFetchBodyDivergence.Len() != 1 {
+		t.Fatal("expected body divergence", analysis.HTTPExtraFetchBodyDivergence)
+	}
+	if analysis.HTTPExtraFetchTitleDivergence.Len() != 1 {
+		t.Fatal("expected title divergence", analysis.HTTPExtraFetchTitleDivergence)
+	}
+}
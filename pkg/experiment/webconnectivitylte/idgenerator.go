@@ -8,6 +8,10 @@ const (
 	idGeneratorDNSOverHTTPSOffset      = 30_000
 	idGeneratorEndpointCleartextOffset = 40_000
 	idGeneratorEndpointSecureOffset    = 50_000
+	idGeneratorDNSOverTCPOffset        = 60_000
+	idGeneratorDNSOverTLSOffset        = 70_000
+	idGeneratorDNSOverQUICOffset       = 80_000
+	idGeneratorEndpointQUICOffset      = 90_000
 )
 
 // IDGenerator helps with generating IDs that neatly fall into namespaces.
@@ -28,6 +32,18 @@ type IDGenerator struct {
 
 	// endpointSecure generates IDs for endpoints using HTTPS.
 	endpointSecure *atomic.Int64
+
+	// dnsOverTCP generates IDs for DNS-over-TCP lookups.
+	dnsOverTCP *atomic.Int64
+
+	// dnsOverTLS generates IDs for DNS-over-TLS lookups.
+	dnsOverTLS *atomic.Int64
+
+	// dnsOverQUIC generates IDs for DNS-over-QUIC lookups.
+	dnsOverQUIC *atomic.Int64
+
+	// endpointQUIC generates IDs for endpoints using HTTP/3.
+	endpointQUIC *atomic.Int64
 }
 
 // NewIDGenerator creates a new [*IDGenerator] instance.
@@ -38,6 +54,10 @@ func NewIDGenerator() *IDGenerator {
 		dnsOverHTTPS:      &atomic.Int64{},
 		endpointCleartext: &atomic.Int64{},
 		endpointSecure:    &atomic.Int64{},
+		dnsOverTCP:        &atomic.Int64{},
+		dnsOverTLS:        &atomic.Int64{},
+		dnsOverQUIC:       &atomic.Int64{},
+		endpointQUIC:      &atomic.Int64{},
 	}
 }
 
@@ -65,3 +85,23 @@ func (idgen *IDGenerator) NewIDForEndpointCleartext() int64 {
 func (idgen *IDGenerator) NewIDForEndpointSecure() int64 {
 	return idgen.endpointSecure.Add(1) + idGeneratorEndpointSecureOffset
 }
+
+// NewIDForDNSOverTCP returns a new ID for a DNS-over-TCP lookup.
+func (idgen *IDGenerator) NewIDForDNSOverTCP() int64 {
+	return idgen.dnsOverTCP.Add(1) + idGeneratorDNSOverTCPOffset
+}
+
+// NewIDForDNSOverTLS returns a new ID for a DNS-over-TLS lookup.
+func (idgen *IDGenerator) NewIDForDNSOverTLS() int64 {
+	return idgen.dnsOverTLS.Add(1) + idGeneratorDNSOverTLSOffset
+}
+
+// NewIDForDNSOverQUIC returns a new ID for a DNS-over-QUIC lookup.
+func (idgen *IDGenerator) NewIDForDNSOverQUIC() int64 {
+	return idgen.dnsOverQUIC.Add(1) + idGeneratorDNSOverQUICOffset
+}
+
+// NewIDForEndpointQUIC returns a new ID for a QUIC endpoint operation.
+func (idgen *IDGenerator) NewIDForEndpointQUIC() int64 {
+	return idgen.endpointQUIC.Add(1) + idGeneratorEndpointQUICOffset
+}
@@ -83,6 +83,13 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		return err
 	}
 
+	// make sure we support the configured resolvers
+	for _, resolverURL := range m.Config.DNSResolvers {
+		if err := dnsResolverValidateURL(resolverURL); err != nil {
+			return err
+		}
+	}
+
 	// initialize the experiment's test keys
 	tk := NewTestKeys()
 	measurement.TestKeys = tk
@@ -126,9 +133,13 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		ZeroTime:                measurement.MeasurementStartTimeSaved,
 		WaitGroup:               wg,
 		CookieJar:               jar,
+		EnableECH:               m.Config.EnableECH,
+		EnableQUIC:              m.Config.EnableQUIC,
+		FetchBudget:             m.Config.fetchBudget(),
 		Referer:                 "",
 		Session:                 sess,
 		TestHelpers:             testhelpers,
+		Resolvers:               m.Config.DNSResolvers,
 		UDPAddress:              m.Config.DNSOverUDPResolver,
 	}
 	resos.Start(ctx)
@@ -61,6 +61,9 @@ type prioritySelector struct {
 	// nudp is the nunber of addrs resolver using UDP
 	nudp int
 
+	// nother is the number of addrs resolved using DNS-over-TCP, DoT, or DoQ
+	nother int
+
 	// tk contains the TestKeys.
 	tk *TestKeys
 
@@ -93,6 +96,7 @@ func newPrioritySelector(
 		nhttps:   0,
 		nsystem:  0,
 		nudp:     0,
+		nother:   0,
 		tk:       tk,
 		zeroTime: zeroTime,
 	}
@@ -109,6 +113,9 @@ func newPrioritySelector(
 		if (flags & DNSAddrFlagHTTPS) != 0 {
 			ps.nhttps++
 		}
+		if (flags & dnsAddrFlagsOther) != 0 {
+			ps.nother++
+		}
 	}
 	go ps.selector(ctx)
 	return ps
@@ -239,6 +246,10 @@ func (ps *prioritySelector) isHighestPriority(r *priorityRequest) bool {
 		if (flags & DNSAddrFlagHTTPS) != 0 {
 			return true
 		}
+	} else if ps.nother > 0 {
+		if (flags & dnsAddrFlagsOther) != 0 {
+			return true
+		}
 	} else {
 		// Happens when we only have addresses from the TH
 		return true
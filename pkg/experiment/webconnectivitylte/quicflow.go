@@ -0,0 +1,300 @@
+package webconnectivitylte
+
+//
+// QUICFlow
+//
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-engine/pkg/logx"
+	"github.com/ooni/probe-engine/pkg/measurexlite"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/netxlite"
+	"github.com/quic-go/quic-go"
+)
+
+// Measures HTTP/3 endpoints.
+//
+// The zero value of this structure IS NOT valid and you MUST initialize
+// all the fields marked as MANDATORY before using this structure.
+type QUICFlow struct {
+	// Address is the MANDATORY address to connect to.
+	Address string
+
+	// Depth is the OPTIONAL current redirect depth.
+	Depth int64
+
+	// IDGenerator is the MANDATORY atomic int64 to generate task IDs.
+	IDGenerator *IDGenerator
+
+	// Logger is the MANDATORY logger to use.
+	Logger model.Logger
+
+	// TestKeys is MANDATORY and contains the TestKeys.
+	TestKeys *TestKeys
+
+	// ZeroTime is the MANDATORY measurement's zero time.
+	ZeroTime time.Time
+
+	// WaitGroup is the MANDATORY wait group this task belongs to.
+	WaitGroup *sync.WaitGroup
+
+	// CookieJar contains the OPTIONAL cookie jar.
+	CookieJar http.CookieJar
+
+	// HostHeader is the OPTIONAL host header to use.
+	HostHeader string
+
+	// PrioSelector is the OPTIONAL priority selector to use to determine
+	// whether this flow is allowed to fetch the webpage. Note that HTTP/3 flows
+	// MUST use a distinct [*prioritySelector] than TCP-based flows, because we
+	// fetch the webpage once using TCP and once using QUIC.
+	PrioSelector *prioritySelector
+
+	// Referer contains the OPTIONAL referer.
+	Referer string
+
+	// SNI is the OPTIONAL SNI to use.
+	SNI string
+
+	// URLPath is the OPTIONAL URL path.
+	URLPath string
+
+	// URLRawQuery is the OPTIONAL URL raw query.
+	URLRawQuery string
+}
+
+// Start starts this task in a background goroutine.
+func (t *QUICFlow) Start(ctx context.Context) {
+	t.WaitGroup.Add(1)
+	index := t.IDGenerator.NewIDForEndpointQUIC()
+	go func() {
+		defer t.WaitGroup.Done() // synchronize with the parent
+		_ = t.Run(ctx, index)
+	}()
+}
+
+// Run runs this task in the current goroutine.
+func (t *QUICFlow) Run(parentCtx context.Context, index int64) error {
+	if err := allowedToConnect(t.Address); err != nil {
+		t.Logger.Warnf("QUICFlow: %s", err.Error())
+		return err
+	}
+
+	// create trace
+	trace := measurexlite.NewTrace(index, t.ZeroTime, generateTagsForQUICEndpoints(t.Depth, t.PrioSelector)...)
+
+	// start the operation logger
+	ol := logx.NewOperationLogger(
+		t.Logger, "[#%d] GET https://%s using %s/udp", index, t.HostHeader, t.Address,
+	)
+
+	// perform the QUIC handshake
+	quicSNI, err := t.sni()
+	if err != nil {
+		t.TestKeys.SetFundamentalFailure(err)
+		ol.Stop(err)
+		return err
+	}
+	quicDialer := trace.NewQUICDialerWithoutResolver(trace.NewUDPListener(), t.Logger)
+	// See https://github.com/ooni/probe/issues/2413 to understand
+	// why we're using nil to force netxlite to use the cached
+	// default Mozilla cert pool.
+	tlsConfig := &tls.Config{ // #nosec G402 - we need to use a large TLS versions range for measuring
+		NextProtos: []string{"h3"},
+		RootCAs:    nil,
+		ServerName: quicSNI,
+	}
+	const quicTimeout = 10 * time.Second
+	quicCtx, quicCancel := context.WithTimeout(parentCtx, quicTimeout)
+	defer quicCancel()
+	quicConn, err := quicDialer.DialContext(quicCtx, t.Address, tlsConfig, &quic.Config{})
+	t.TestKeys.AppendQUICHandshakes(trace.QUICHandshakes()...)
+	defer func() {
+		// Like in SecureFlow, we must call trace.NetworkEvents()... inside the defer
+		// block otherwise we would miss the events occurring after the handshake.
+		t.TestKeys.AppendNetworkEvents(trace.NetworkEvents()...)
+	}()
+	if err != nil {
+		ol.Stop(err)
+		return err
+	}
+	defer measurexlite.MaybeCloseQUICConn(quicConn)
+
+	// Determine whether we're allowed to fetch the webpage
+	if t.PrioSelector == nil || !t.PrioSelector.permissionToFetch(t.Address) {
+		ol.Stop("stop after QUIC handshake")
+		return errNotPermittedToFetch
+	}
+
+	// create HTTP transport
+	httpTransport := netxlite.NewHTTP3Transport(
+		t.Logger,
+		netxlite.NewSingleUseQUICDialer(quicConn),
+		tlsConfig,
+	)
+	defer httpTransport.CloseIdleConnections()
+
+	// create HTTP request
+	const httpTimeout = 10 * time.Second
+	httpCtx, httpCancel := context.WithTimeout(parentCtx, httpTimeout)
+	defer httpCancel()
+	httpReq, err := t.newHTTPRequest(httpCtx)
+	if err != nil {
+		ol.Stop(err)
+		return err
+	}
+
+	// perform HTTP transaction
+	_, _, err = t.httpTransaction(
+		httpCtx,
+		"udp",
+		t.Address,
+		"h3",
+		httpTransport,
+		httpReq,
+		trace,
+	)
+	if err != nil {
+		ol.Stop(err)
+		return err
+	}
+
+	// completed successfully
+	ol.Stop(nil)
+	return nil
+}
+
+// sni returns the user-configured SNI or a reasonable default
+func (t *QUICFlow) sni() (string, error) {
+	if t.SNI != "" {
+		return t.SNI, nil
+	}
+	addr, _, err := net.SplitHostPort(t.Address)
+	if err != nil {
+		return "", err
+	}
+	return addr, nil
+}
+
+// urlHost computes the host to include into the URL
+func (t *QUICFlow) urlHost() (string, error) {
+	addr, port, err := net.SplitHostPort(t.Address)
+	if err != nil {
+		t.Logger.Warnf("BUG: net.SplitHostPort failed for %s: %s", t.Address, err.Error())
+		return "", err
+	}
+	urlHost := t.HostHeader
+	if urlHost == "" {
+		urlHost = addr
+	}
+	if port == "443" {
+		return urlHost, nil
+	}
+	urlHost = net.JoinHostPort(urlHost, port)
+	return urlHost, nil
+}
+
+// newHTTPRequest creates a new HTTP request.
+func (t *QUICFlow) newHTTPRequest(ctx context.Context) (*http.Request, error) {
+	urlHost, err := t.urlHost()
+	if err != nil {
+		return nil, err
+	}
+	httpURL := &url.URL{
+		Scheme:   "https",
+		Host:     urlHost,
+		Path:     t.URLPath,
+		RawQuery: t.URLRawQuery,
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", httpURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Host", t.HostHeader)
+	httpReq.Header.Set("Accept", model.HTTPHeaderAccept)
+	httpReq.Header.Set("Accept-Language", model.HTTPHeaderAcceptLanguage)
+	httpReq.Header.Set("Referer", t.Referer)
+	httpReq.Header.Set("User-Agent", model.HTTPHeaderUserAgent)
+	httpReq.Host = t.HostHeader
+	if t.CookieJar != nil {
+		for _, cookie := range t.CookieJar.Cookies(httpURL) {
+			httpReq.AddCookie(cookie)
+		}
+	}
+	return httpReq, nil
+}
+
+// httpTransaction runs the HTTP transaction and saves the results. Unlike SecureFlow
+// and CleartextFlow, we never follow redirects because the HTTP/3 fetch is not part
+// of the redirect chain: we just compare what we get with what the TH gets.
+func (t *QUICFlow) httpTransaction(ctx context.Context, network, address, alpn string,
+	txp model.HTTPTransport, req *http.Request, trace *measurexlite.Trace) (*http.Response, []byte, error) {
+	const maxbody = 1 << 19
+	started := trace.TimeSince(trace.ZeroTime())
+
+	// Implementation note: we want to emit http_transaction_start when we actually start doing
+	// HTTP things such that it's possible to correctly classify network events
+	t.TestKeys.AppendNetworkEvents(measurexlite.NewArchivalNetworkEvent(
+		trace.Index(),
+		started,
+		"http_transaction_start",
+		network,
+		address,
+		0,
+		nil,
+		started,
+		trace.Tags()...,
+	))
+
+	resp, err := txp.RoundTrip(req)
+	var body []byte
+	if err == nil {
+		defer resp.Body.Close()
+		reader := io.LimitReader(resp.Body, maxbody)
+		body, err = netxlite.StreamAllContext(ctx, reader)
+	}
+
+	finished := trace.TimeSince(trace.ZeroTime())
+	t.TestKeys.AppendNetworkEvents(measurexlite.NewArchivalNetworkEvent(
+		trace.Index(),
+		finished,
+		"http_transaction_done",
+		network,
+		address,
+		0,
+		nil,
+		finished,
+		trace.Tags()...,
+	))
+
+	ev := measurexlite.NewArchivalHTTPRequestResult(
+		trace.Index(),
+		started,
+		network,
+		address,
+		alpn,
+		txp.Network(),
+		req,
+		resp,
+		maxbody,
+		body,
+		err,
+		finished,
+		trace.Tags()...,
+	)
+
+	// Implementation note: we append rather than prepend because consumers of
+	// Web Connectivity expect the first request to be the final request of the
+	// redirect chain, while HTTP/3 requests are not part of the chain.
+	t.TestKeys.AppendRequests(ev)
+	return resp, body, err
+}
@@ -0,0 +1,96 @@
+package webconnectivitylte
+
+import (
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/google/gopacket/layers"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-engine/pkg/geoipx"
+	"github.com/ooni/probe-engine/pkg/minipipeline"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/must"
+	"github.com/ooni/probe-engine/pkg/netemx"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+	"github.com/ooni/probe-engine/pkg/webconnectivityqa"
+)
+
+func TestMeasureQUIC(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+
+	// measure runs the given test case with QUIC enabled and returns the analysis
+	measure := func(t *testing.T, tc *webconnectivityqa.TestCase) (*TestKeys, *minipipeline.WebAnalysis) {
+		measurer := NewExperimentMeasurer(&Config{EnableQUIC: true})
+		measurement, err := webconnectivityqa.MeasureTestCase(measurer, tc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var webmeas minipipeline.WebMeasurement
+		must.UnmarshalJSON(must.MarshalJSON(measurement), &webmeas)
+		lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
+		container := runtimex.Try1(minipipeline.IngestWebMeasurement(lookupper, &webmeas))
+		analysis := minipipeline.AnalyzeWebObservationsWithoutLinearAnalysis(lookupper, container)
+		return measurement.TestKeys.(*TestKeys), analysis
+	}
+
+	t.Run("when HTTP/3 works", func(t *testing.T) {
+		tc := &webconnectivityqa.TestCase{
+			Name:  "successWithHTTP3",
+			Input: "https://www.example.com/",
+		}
+		tk, analysis := measure(t, tc)
+
+		// make sure we've measured the HTTP/3 endpoint discovered by the TH
+		if len(tk.QUICHandshakes) <= 0 {
+			t.Fatal("expected to see QUIC handshakes")
+		}
+		var http3 int
+		for _, req := range tk.Requests {
+			if req.Network == "udp" && req.Failure == nil {
+				http3++
+			}
+		}
+		if http3 != 1 {
+			t.Fatal("expected exactly one successful HTTP/3 request, got", http3)
+		}
+
+		// make sure the analysis does not flag anything and the verdict is unaffected
+		if analysis.QUICHandshakeUnexpectedFailure.Len() != 0 {
+			t.Fatal("unexpected QUIC failures", analysis.QUICHandshakeUnexpectedFailure)
+		}
+		if analysis.HTTP3ResponseDiffersFromControl.Len() != 0 {
+			t.Fatal("unexpected HTTP/3 divergence", analysis.HTTP3ResponseDiffersFromControl)
+		}
+		if tk.BlockingFlags != AnalysisBlockingFlagSuccess {
+			t.Fatal("unexpected blocking flags", tk.BlockingFlags)
+		}
+	})
+
+	t.Run("when QUIC is blocked", func(t *testing.T) {
+		tc := &webconnectivityqa.TestCase{
+			Name:  "quicBlockingWithHTTPS",
+			Input: "https://www.example.com/",
+			Configure: func(env *netemx.QAEnv) {
+				env.DPIEngine().AddRule(&netem.DPIDropTrafficForServerEndpoint{
+					Logger:          log.Log,
+					ServerIPAddress: netemx.AddressWwwExampleCom,
+					ServerPort:      443,
+					ServerProtocol:  layers.IPProtocolUDP,
+				})
+			},
+		}
+		tk, analysis := measure(t, tc)
+
+		// make sure we flag the QUIC handshake failure
+		if analysis.QUICHandshakeUnexpectedFailure.Len() != 1 {
+			t.Fatal("expected one unexpected QUIC failure", analysis.QUICHandshakeUnexpectedFailure)
+		}
+
+		// make sure QUIC blocking does not influence the verdict
+		if tk.BlockingFlags != AnalysisBlockingFlagSuccess {
+			t.Fatal("unexpected blocking flags", tk.BlockingFlags)
+		}
+	})
+}
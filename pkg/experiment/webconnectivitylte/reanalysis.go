@@ -0,0 +1,73 @@
+package webconnectivitylte
+
+//
+// Reanalysis of previously collected measurements.
+//
+
+import (
+	"io"
+	"net/url"
+
+	"github.com/ooni/probe-engine/pkg/minipipeline"
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+// AnalyzeWebMeasurement runs the analysis engine on the observations contained
+// inside a previously collected Web Connectivity measurement.
+//
+// The returned [*TestKeys] only contains the observations used by the analysis
+// along with freshly computed analysis results, therefore it does not depend on
+// the analysis results stored inside the original measurement. Unlike what
+// happens when measuring, we do not print the extended analysis summary.
+func AnalyzeWebMeasurement(
+	lookupper model.GeoIPASNLookupper, meas *minipipeline.WebMeasurement) (*TestKeys, error) {
+	mtk := meas.TestKeys.UnwrapOr(nil)
+	if mtk == nil {
+		return nil, minipipeline.ErrNoTestKeys
+	}
+
+	tk := NewTestKeys()
+	tk.Queries = mtk.Queries
+	tk.Requests = mtk.Requests
+	tk.TCPConnect = mtk.TCPConnect
+	tk.TLSHandshakes = mtk.TLSHandshakes
+	tk.QUICHandshakes = mtk.QUICHandshakes
+	tk.NetworkEvents = mtk.NetworkEvents
+	tk.ControlRequest = mtk.XControlRequest.UnwrapOr(nil)
+	tk.Control = mtk.Control.UnwrapOr(nil)
+
+	// the analysis panics when the control request URL does not parse, which is
+	// fine when measuring but not when processing arbitrary measurements
+	if tk.ControlRequest != nil && tk.Control != nil {
+		if _, err := url.Parse(tk.ControlRequest.HTTPRequest); err != nil {
+			return nil, err
+		}
+	}
+
+	tk.analysisClassicWithWriter(lookupper, io.Discard)
+	return tk, nil
+}
+
+// BlockingFlagsVerdict reduces the BlockingFlags computed by the extended
+// analysis to one of the values that the Blocking field may contain, such
+// that one could compare the two algorithms. When BlockingFlags contains
+// several blocking flags we return the one occurring earlier in the chain
+// of operations required to fetch a webpage (i.e., DNS before TCP/IP). When
+// there are no blocking flags, NullNullFlags tell us whether the extended
+// analysis was able to explain the failures (e.g., the website is down).
+func (tk *TestKeys) BlockingFlagsVerdict() any {
+	switch flags := tk.BlockingFlags; {
+	case (flags & AnalysisBlockingFlagDNSBlocking) != 0:
+		return "dns"
+	case (flags & AnalysisBlockingFlagTCPIPBlocking) != 0:
+		return "tcp_ip"
+	case (flags & (AnalysisBlockingFlagTLSBlocking | AnalysisBlockingFlagHTTPBlocking)) != 0:
+		return "http-failure"
+	case (flags & AnalysisBlockingFlagHTTPDiff) != 0:
+		return "http-diff"
+	case (flags&AnalysisBlockingFlagSuccess) != 0 || tk.NullNullFlags != 0:
+		return false
+	default:
+		return nil
+	}
+}
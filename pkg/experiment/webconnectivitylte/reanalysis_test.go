@@ -0,0 +1,241 @@
+package webconnectivitylte
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ooni/probe-engine/pkg/geoipx"
+	"github.com/ooni/probe-engine/pkg/minipipeline"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/must"
+	"github.com/ooni/probe-engine/pkg/netxlite"
+	"github.com/ooni/probe-engine/pkg/optional"
+	"github.com/ooni/probe-engine/pkg/throttling"
+)
+
+func TestAnalyzeWebMeasurement(t *testing.T) {
+	lookupper := model.GeoIPASNLookupperFunc(geoipx.LookupASN)
+
+	// loadMeasurement loads a measurement generated by the minipipeline QA suite.
+	loadMeasurement := func(name string) *minipipeline.WebMeasurement {
+		var meas minipipeline.WebMeasurement
+		fpath := filepath.Join(
+			"..", "..", "minipipeline", "testdata", "webconnectivity", "generated", name, "measurement.json")
+		must.UnmarshalJSON(must.ReadFile(fpath), &meas)
+		return &meas
+	}
+
+	t.Run("we recompute the classic and extended results", func(t *testing.T) {
+		meas := loadMeasurement("dnsHijackingToProxyWithHTTPSURL")
+		tk, err := AnalyzeWebMeasurement(lookupper, meas)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tk.Blocking != false || tk.Accessible.UnwrapOr(false) != true {
+			t.Fatal("unexpected classic results", tk.Blocking, tk.Accessible)
+		}
+		if tk.BlockingFlags != AnalysisBlockingFlagDNSBlocking|AnalysisBlockingFlagSuccess {
+			t.Fatal("unexpected blocking flags", tk.BlockingFlags)
+		}
+		if verdict := tk.BlockingFlagsVerdict(); verdict != "dns" {
+			t.Fatal("unexpected verdict", verdict)
+		}
+	})
+
+	t.Run("we flag certificates not received by the control", func(t *testing.T) {
+		failure := netxlite.FailureSSLUnknownAuthority
+		meas := &minipipeline.WebMeasurement{
+			TestKeys: optional.Some(&minipipeline.WebMeasurementTestKeys{
+				Control: optional.Some(&model.THResponse{
+					TCPConnect: map[string]model.THTCPConnectResult{
+						"93.184.216.34:443": {Status: true},
+					},
+					TLSHandshake: map[string]model.THTLSHandshakeResult{
+						"93.184.216.34:443": {
+							ServerName:               "www.example.com",
+							Status:                   true,
+							XCertificateFingerprints: []string{"deadbeef"},
+						},
+					},
+				}),
+				TCPConnect: []*model.ArchivalTCPConnectResult{{
+					IP:            "93.184.216.34",
+					Port:          443,
+					Status:        model.ArchivalTCPConnectStatus{Success: true},
+					Tags:          []string{"depth=0", "fetch_body=true"},
+					TransactionID: 1,
+				}},
+				TLSHandshakes: []*model.ArchivalTLSOrQUICHandshakeResult{{
+					Address:          "93.184.216.34:443",
+					Failure:          &failure,
+					PeerCertificates: []model.ArchivalBinaryData{model.ArchivalBinaryData("mitm")},
+					ServerName:       "www.example.com",
+					Tags:             []string{"depth=0", "fetch_body=true"},
+					TransactionID:    1,
+				}},
+				XControlRequest: optional.Some(&model.THRequest{HTTPRequest: "https://www.example.com/"}),
+			}),
+		}
+		tk, err := AnalyzeWebMeasurement(lookupper, meas)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (tk.BlockingFlags & AnalysisBlockingFlagTLSBlocking) == 0 {
+			t.Fatal("unexpected blocking flags", tk.BlockingFlags)
+		}
+		if tk.TLSFlags != AnalysisTLSFlagUnexpectedCertificate {
+			t.Fatal("unexpected TLS flags", tk.TLSFlags)
+		}
+	})
+
+	t.Run("we use the QUIC handshakes", func(t *testing.T) {
+		handshake := &model.ArchivalTLSOrQUICHandshakeResult{
+			Address:       "93.184.216.34:443",
+			ServerName:    "www.example.com",
+			Tags:          []string{"depth=0"},
+			TransactionID: 1,
+		}
+		meas := &minipipeline.WebMeasurement{
+			TestKeys: optional.Some(&minipipeline.WebMeasurementTestKeys{
+				QUICHandshakes: []*model.ArchivalTLSOrQUICHandshakeResult{handshake},
+			}),
+		}
+		tk, err := AnalyzeWebMeasurement(lookupper, meas)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tk.QUICHandshakes) != 1 || tk.QUICHandshakes[0] != handshake {
+			t.Fatal("unexpected QUIC handshakes", tk.QUICHandshakes)
+		}
+	})
+
+	t.Run("we use the network events to detect throttling", func(t *testing.T) {
+		events := []*model.ArchivalNetworkEvent{{
+			Address:       "93.184.216.34:80",
+			Operation:     netxlite.ConnectOperation,
+			T:             0,
+			TransactionID: 1,
+		}}
+		for idx, numBytes := range []int64{5000, 5500, 6000, 6500} {
+			events = append(events, &model.ArchivalNetworkEvent{
+				Address:       "93.184.216.34:80",
+				NumBytes:      numBytes,
+				Operation:     throttling.BytesReceivedCumulativeOperation,
+				T:             0.1 + 0.5*float64(idx),
+				TransactionID: 1,
+			})
+		}
+		meas := &minipipeline.WebMeasurement{
+			TestKeys: optional.Some(&minipipeline.WebMeasurementTestKeys{
+				NetworkEvents: events,
+				Requests: []*model.ArchivalHTTPRequestResult{{
+					Request: model.ArchivalHTTPRequest{
+						Method: "GET",
+						URL:    "http://www.example.com/",
+					},
+					Response: model.ArchivalHTTPResponse{
+						Body: model.ArchivalScrubbedMaybeBinaryString("<html></html>"),
+						Code: 200,
+					},
+					TransactionID: 1,
+				}},
+				TCPConnect: []*model.ArchivalTCPConnectResult{{
+					IP:            "93.184.216.34",
+					Port:          80,
+					Status:        model.ArchivalTCPConnectStatus{Success: true},
+					Tags:          []string{"depth=0", "fetch_body=true"},
+					TransactionID: 1,
+				}},
+			}),
+		}
+		tk, err := AnalyzeWebMeasurement(lookupper, meas)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tk.NetworkEvents) != len(events) {
+			t.Fatal("unexpected network events", tk.NetworkEvents)
+		}
+		if !tk.Throttled.UnwrapOr(false) {
+			t.Fatal("expected the throttled verdict to be true", tk.Throttled)
+		}
+	})
+
+	t.Run("without test keys", func(t *testing.T) {
+		meas := &minipipeline.WebMeasurement{}
+		tk, err := AnalyzeWebMeasurement(lookupper, meas)
+		if !errors.Is(err, minipipeline.ErrNoTestKeys) {
+			t.Fatal("unexpected error", err)
+		}
+		if tk != nil {
+			t.Fatal("expected nil test keys")
+		}
+	})
+
+	t.Run("with an invalid control request URL", func(t *testing.T) {
+		meas := &minipipeline.WebMeasurement{
+			TestKeys: optional.Some(&minipipeline.WebMeasurementTestKeys{
+				Control:         optional.Some(&model.THResponse{}),
+				XControlRequest: optional.Some(&model.THRequest{HTTPRequest: "\t"}),
+			}),
+		}
+		tk, err := AnalyzeWebMeasurement(lookupper, meas)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if tk != nil {
+			t.Fatal("expected nil test keys")
+		}
+	})
+}
+
+func TestTestKeysBlockingFlagsVerdict(t *testing.T) {
+	type testcase struct {
+		name          string
+		blockingFlags int64
+		nullNullFlags int64
+		expect        any
+	}
+
+	cases := []testcase{{
+		name:          "with DNS blocking and success",
+		blockingFlags: AnalysisBlockingFlagDNSBlocking | AnalysisBlockingFlagSuccess,
+		expect:        "dns",
+	}, {
+		name:          "with TCP/IP and TLS blocking",
+		blockingFlags: AnalysisBlockingFlagTCPIPBlocking | AnalysisBlockingFlagTLSBlocking,
+		expect:        "tcp_ip",
+	}, {
+		name:          "with TLS blocking",
+		blockingFlags: AnalysisBlockingFlagTLSBlocking,
+		expect:        "http-failure",
+	}, {
+		name:          "with HTTP blocking",
+		blockingFlags: AnalysisBlockingFlagHTTPBlocking,
+		expect:        "http-failure",
+	}, {
+		name:          "with HTTP diff",
+		blockingFlags: AnalysisBlockingFlagHTTPDiff,
+		expect:        "http-diff",
+	}, {
+		name:          "with success",
+		blockingFlags: AnalysisBlockingFlagSuccess,
+		expect:        false,
+	}, {
+		name:          "with expected failures",
+		nullNullFlags: AnalysisFlagNullNullExpectedTCPConnectFailure,
+		expect:        false,
+	}, {
+		name:   "without any flag",
+		expect: nil,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tk := &TestKeys{BlockingFlags: tc.blockingFlags, NullNullFlags: tc.nullNullFlags}
+			if got := tk.BlockingFlagsVerdict(); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
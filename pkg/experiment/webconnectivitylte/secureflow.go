@@ -70,6 +70,13 @@ type SecureFlow struct {
 	// CookieJar contains the OPTIONAL cookie jar, used for redirects.
 	CookieJar http.CookieJar
 
+	// ECHConfigList is the OPTIONAL Encrypted Client Hello config list to use.
+	ECHConfigList []byte
+
+	// FetchBudget is the OPTIONAL budget for fetching the webpage when
+	// the [*prioritySelector] does not grant us permission to fetch.
+	FetchBudget *FetchBudget
+
 	// FollowRedirects is OPTIONAL and instructs this flow
 	// to follow HTTP redirects (if any).
 	FollowRedirects bool
@@ -87,6 +94,9 @@ type SecureFlow struct {
 	// SNI is the OPTIONAL SNI to use.
 	SNI string
 
+	// Resolvers contains the OPTIONAL URLs of the resolvers to use.
+	Resolvers []string
+
 	// UDPAddress is the OPTIONAL address of the UDP resolver to use. If this
 	// field is not set we use a default one (e.g., `8.8.8.8:53`).
 	UDPAddress string
@@ -116,7 +126,11 @@ func (t *SecureFlow) Run(parentCtx context.Context, index int64) error {
 	}
 
 	// create trace
-	trace := measurexlite.NewTrace(index, t.ZeroTime, generateTagsForEndpoints(t.Depth, t.PrioSelector, t.Classic)...)
+	tags := generateTagsForEndpoints(t.Depth, t.PrioSelector, t.Classic)
+	if len(t.ECHConfigList) > 0 {
+		tags = generateTagsForECH(tags)
+	}
+	trace := measurexlite.NewTrace(index, t.ZeroTime, tags...)
 
 	// start measuring throttling
 	sampler := throttling.NewSampler(trace)
@@ -163,9 +177,10 @@ func (t *SecureFlow) Run(parentCtx context.Context, index int64) error {
 	// why we're using nil to force netxlite to use the cached
 	// default Mozilla cert pool.
 	tlsConfig := &tls.Config{ // #nosec G402 - we need to use a large TLS versions range for measuring
-		NextProtos: t.alpn(),
-		RootCAs:    nil,
-		ServerName: tlsSNI,
+		NextProtos:                     t.alpn(),
+		RootCAs:                        nil,
+		ServerName:                     tlsSNI,
+		EncryptedClientHelloConfigList: t.ECHConfigList,
 	}
 	const tlsTimeout = 10 * time.Second
 	tlsCtx, tlsCancel := context.WithTimeout(parentCtx, tlsTimeout)
@@ -181,10 +196,24 @@ func (t *SecureFlow) Run(parentCtx context.Context, index int64) error {
 	tlsConnState := netxlite.MaybeTLSConnectionState(tlsConn)
 	alpn := tlsConnState.NegotiatedProtocol
 
-	// Determine whether we're allowed to fetch the webpage
+	// Determine whether we're allowed to fetch the webpage or, failing that,
+	// whether we're allowed to perform an extra fetch of the webpage
+	var (
+		extraFetch        bool
+		extraFetchMaxBody int64
+		extraFetchBytes   int64
+	)
 	if t.PrioSelector == nil || !t.PrioSelector.permissionToFetch(t.Address) {
-		ol.Stop("stop after TLS handshake")
-		return errNotPermittedToFetch
+		if t.PrioSelector != nil {
+			extraFetchMaxBody, extraFetch = t.FetchBudget.Acquire(parentCtx, fetchBudgetMaxBodySize)
+		}
+		if !extraFetch {
+			ol.Stop("stop after TLS handshake")
+			return errNotPermittedToFetch
+		}
+		defer func() {
+			t.FetchBudget.Release(extraFetchMaxBody, extraFetchBytes)
+		}()
 	}
 
 	// create HTTP transport
@@ -220,14 +249,19 @@ func (t *SecureFlow) Run(parentCtx context.Context, index int64) error {
 		httpTransport,
 		httpReq,
 		trace,
+		extraFetch,
+		extraFetchMaxBody,
 	)
+	extraFetchBytes = int64(len(httpRespBody))
 	if err != nil {
 		ol.Stop(err)
 		return err
 	}
 
-	// if enabled, follow possible redirects
-	t.maybeFollowRedirects(parentCtx, httpResp)
+	// if enabled, follow possible redirects (unless this is an extra fetch)
+	if !extraFetch {
+		t.maybeFollowRedirects(parentCtx, httpResp)
+	}
 
 	// ignore the response body
 	_ = httpRespBody
@@ -311,8 +345,14 @@ var ErrTooManyRedirects = errors.New("stopped after too many redirects")
 
 // httpTransaction runs the HTTP transaction and saves the results.
 func (t *SecureFlow) httpTransaction(ctx context.Context, network, address, alpn string,
-	txp model.HTTPTransport, req *http.Request, trace *measurexlite.Trace) (*http.Response, []byte, error) {
-	const maxbody = 1 << 19
+	txp model.HTTPTransport, req *http.Request, trace *measurexlite.Trace,
+	extraFetch bool, extraFetchMaxBody int64) (*http.Response, []byte, error) {
+	maxbody := int64(fetchBudgetMaxBodySize)
+	tags := trace.Tags()
+	if extraFetch {
+		maxbody = extraFetchMaxBody
+		tags = generateTagsForExtraFetch(tags)
+	}
 	started := trace.TimeSince(trace.ZeroTime())
 
 	// Implementation note: we want to emit http_transaction_start when we actually start doing
@@ -326,7 +366,7 @@ func (t *SecureFlow) httpTransaction(ctx context.Context, network, address, alpn
 		0,
 		nil,
 		started,
-		trace.Tags()...,
+		tags...,
 	))
 
 	resp, err := txp.RoundTrip(req)
@@ -341,7 +381,7 @@ func (t *SecureFlow) httpTransaction(ctx context.Context, network, address, alpn
 	}
 	if err == nil && httpRedirectIsRedirect(resp) {
 		err = httpValidateRedirect(resp)
-		if err == nil && t.FollowRedirects && !t.NumRedirects.CanFollowOneMoreRedirect() {
+		if err == nil && t.FollowRedirects && !extraFetch && !t.NumRedirects.CanFollowOneMoreRedirect() {
 			err = ErrTooManyRedirects
 		}
 	}
@@ -356,7 +396,7 @@ func (t *SecureFlow) httpTransaction(ctx context.Context, network, address, alpn
 		0,
 		nil,
 		finished,
-		trace.Tags()...,
+		tags...,
 	))
 
 	ev := measurexlite.NewArchivalHTTPRequestResult(
@@ -372,7 +412,7 @@ func (t *SecureFlow) httpTransaction(ctx context.Context, network, address, alpn
 		body,
 		err,
 		finished,
-		trace.Tags()...,
+		tags...,
 	)
 
 	t.TestKeys.PrependRequests(ev)
@@ -403,6 +443,8 @@ func (t *SecureFlow) maybeFollowRedirects(ctx context.Context, resp *http.Respon
 			Referer:                 resp.Request.URL.String(),
 			Session:                 nil, // no need to issue another control request
 			TestHelpers:             nil, // ditto
+			FetchBudget:             t.FetchBudget,
+			Resolvers:               t.Resolvers,
 			UDPAddress:              t.UDPAddress,
 		}
 		resolvers.Start(ctx)
@@ -2,13 +2,21 @@ package webconnectivitylte
 
 import (
 	"context"
+	"crypto/ecdh"
+	"crypto/rand"
 	"errors"
+	"net"
 	"net/http"
+	"slices"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/netemx"
+	"github.com/ooni/probe-engine/pkg/netxlite"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+	"golang.org/x/crypto/cryptobyte"
 )
 
 func TestSecureFlow_Run(t *testing.T) {
@@ -94,3 +102,77 @@ func TestSecureFlow_Run(t *testing.T) {
 		})
 	}
 }
+
+// newTestingECHConfigList returns an ECH config list using the given public name and
+// a random X25519 public key, which no server knows the private key for.
+func newTestingECHConfigList(publicName string) []byte {
+	key := runtimex.Try1(ecdh.X25519().GenerateKey(rand.Reader))
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16(0xfe0d) // version
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8(1)       // config_id
+			b.AddUint16(0x0020) // DHKEM(X25519, HKDF-SHA256)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(key.PublicKey().Bytes())
+			})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16(0x0001) // HKDF-SHA256
+				b.AddUint16(0x0001) // AES-128-GCM
+			})
+			b.AddUint8(0) // maximum_name_length
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes([]byte(publicName))
+			})
+			b.AddUint16(0) // extensions
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+func TestSecureFlowWithECH(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+
+	env := netemx.MustNewScenario(netemx.InternetScenario)
+	defer env.Close()
+
+	env.Do(func() {
+		tk := NewTestKeys()
+		task := &SecureFlow{
+			Address:       net.JoinHostPort(netemx.AddressWwwExampleCom, "443"),
+			IDGenerator:   NewIDGenerator(),
+			Logger:        model.DiscardLogger,
+			NumRedirects:  NewNumRedirects(0),
+			TestKeys:      tk,
+			ZeroTime:      time.Now(),
+			WaitGroup:     &sync.WaitGroup{},
+			ALPN:          []string{"h2", "http/1.1"},
+			ECHConfigList: newTestingECHConfigList("www.example.com"),
+			HostHeader:    "www.example.com",
+			SNI:           "www.example.com",
+		}
+
+		// the server does not support ECH, so it completes the handshake using the
+		// outer ClientHello and the client rejects the connection
+		err := task.Run(context.Background(), 1)
+		if err == nil || err.Error() != netxlite.FailureSSLECHRejected {
+			t.Fatal("unexpected error", err)
+		}
+
+		if len(tk.TLSHandshakes) != 1 {
+			t.Fatal("expected exactly one TLS handshake")
+		}
+		hs := tk.TLSHandshakes[0]
+		if hs.ECHAccepted == nil || *hs.ECHAccepted {
+			t.Fatal("expected ECH not to be accepted")
+		}
+		if hs.ECHConfig == "" {
+			t.Fatal("expected to see the ECH config list")
+		}
+		if !slices.Contains(hs.Tags, "ech=true") || !slices.Contains(hs.Tags, "fetch_body=false") {
+			t.Fatal("unexpected tags", hs.Tags)
+		}
+	})
+}
@@ -29,3 +29,26 @@ func generateTagsForEndpoints(depth int64, ps *prioritySelector, classic bool) (
 
 	return output
 }
+
+// generateTagsForExtraFetch generates the tags for an extra fetch given the tags of
+// the endpoint. The extra_fetch=true tag allows to distinguish the webpages fetched
+// from every address (see [FetchBudget]) from the one fetched from the highest
+// priority address, which is the only one we consider when following redirects.
+func generateTagsForExtraFetch(tags []string) []string {
+	return append(append([]string{}, tags...), "extra_fetch=true")
+}
+
+// generateTagsForECH generates the tags for an endpoint using ECH given the tags of
+// the endpoint. The ech=true tag allows to distinguish the TLS handshakes using ECH
+// from the ones not using ECH, which we compare with each other.
+func generateTagsForECH(tags []string) []string {
+	return append(append([]string{}, tags...), "ech=true")
+}
+
+// generateTagsForQUICEndpoints is like generateTagsForEndpoints but for QUIC endpoints,
+// which are never classic and do not exist in Web Connectivity v0.4.
+func generateTagsForQUICEndpoints(depth int64, ps *prioritySelector) (output []string) {
+	output = append(output, fmt.Sprintf("depth=%d", depth))
+	output = append(output, fmt.Sprintf("fetch_body=%v", ps != nil))
+	return output
+}
@@ -45,6 +45,9 @@ type TestKeys struct {
 	// Do53 contains ancillary observations collected by Do53 resolvers.
 	Do53 *TestKeysDo53 `json:"x_do53"`
 
+	// DoT contains ancillary observations collected by DoT and DoQ resolvers.
+	DoT *TestKeysDoT `json:"x_dot"`
+
 	// DNSDuplicateResponses contains late/duplicate responses we didn't expect to receive from
 	// a resolver (which may raise eyebrows if they're different).
 	DNSDuplicateResponses []*model.ArchivalDNSLookupResult `json:"x_dns_duplicate_responses"`
@@ -61,6 +64,9 @@ type TestKeys struct {
 	// TLSHandshakes contains TLS handshakes results.
 	TLSHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
 
+	// QUICHandshakes contains QUIC handshakes results.
+	QUICHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"quic_handshakes"`
+
 	// ControlRequest is the control request we sent.
 	ControlRequest *webconnectivity.ControlRequest `json:"x_control_request"`
 
@@ -92,6 +98,17 @@ type TestKeys struct {
 	// BlockingFlags explains why we think that the website is blocked.
 	BlockingFlags int64 `json:"x_blocking_flags"`
 
+	// TLSFlags describes specific TLS anomalies we observed.
+	TLSFlags int64 `json:"x_tls_flags"`
+
+	// ThrottlingFlags describes why we think that the download was throttled. When
+	// this field is zero, we did not detect any throttling.
+	ThrottlingFlags int64 `json:"x_throttling_flags"`
+
+	// Throttled is the throttling verdict: true when we detected throttling, false
+	// when we did not, and null when we could not analyze any download.
+	Throttled optional.Value[bool] `json:"x_throttled"`
+
 	// NullNullFlags describes what the algorithm to avoid emitting
 	// blocking = null, accessible = null measurements did
 	NullNullFlags int64 `json:"x_null_null_flags"`
@@ -195,6 +212,27 @@ type TestKeysDo53 struct {
 	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
 }
 
+// TestKeysDoT contains ancillary observations collected using DoT or DoQ (e.g.,
+// the TCP connects and TLS or QUIC handshakes caused by given lookups).
+//
+// They are on a separate hierarchy to simplify processing.
+type TestKeysDoT struct {
+	// NetworkEvents contains network events.
+	NetworkEvents []*model.ArchivalNetworkEvent `json:"network_events"`
+
+	// Queries contains DNS queries.
+	Queries []*model.ArchivalDNSLookupResult `json:"queries"`
+
+	// TCPConnect contains TCP connect results.
+	TCPConnect []*model.ArchivalTCPConnectResult `json:"tcp_connect"`
+
+	// TLSHandshakes contains TLS handshakes results.
+	TLSHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"tls_handshakes"`
+
+	// QUICHandshakes contains QUIC handshakes results.
+	QUICHandshakes []*model.ArchivalTLSOrQUICHandshakeResult `json:"quic_handshakes"`
+}
+
 // AppendNetworkEvents appends to NetworkEvents.
 func (tk *TestKeys) AppendNetworkEvents(v ...*model.ArchivalNetworkEvent) {
 	tk.mu.Lock()
@@ -225,6 +263,13 @@ func (tk *TestKeys) PrependRequests(v ...*model.ArchivalHTTPRequestResult) {
 	tk.mu.Unlock()
 }
 
+// AppendRequests appends to Requests.
+func (tk *TestKeys) AppendRequests(v ...*model.ArchivalHTTPRequestResult) {
+	tk.mu.Lock()
+	tk.Requests = append(tk.Requests, v...)
+	tk.mu.Unlock()
+}
+
 // AppendTCPConnectResults appends to TCPConnect.
 func (tk *TestKeys) AppendTCPConnectResults(v ...*model.ArchivalTCPConnectResult) {
 	tk.mu.Lock()
@@ -239,6 +284,13 @@ func (tk *TestKeys) AppendTLSHandshakes(v ...*model.ArchivalTLSOrQUICHandshakeRe
 	tk.mu.Unlock()
 }
 
+// AppendQUICHandshakes appends to QUICHandshakes.
+func (tk *TestKeys) AppendQUICHandshakes(v ...*model.ArchivalTLSOrQUICHandshakeResult) {
+	tk.mu.Lock()
+	tk.QUICHandshakes = append(tk.QUICHandshakes, v...)
+	tk.mu.Unlock()
+}
+
 // SetControlRequest sets the value of controlRequest.
 func (tk *TestKeys) SetControlRequest(v *webconnectivity.ControlRequest) {
 	tk.mu.Lock()
@@ -275,6 +327,14 @@ func (tk *TestKeys) WithTestKeysDoH(f func(*TestKeysDoH)) {
 	tk.mu.Unlock()
 }
 
+// WithTestKeysDoT calls the given function with the mutex locked passing to
+// it as argument the pointer to the DoT field.
+func (tk *TestKeys) WithTestKeysDoT(f func(*TestKeysDoT)) {
+	tk.mu.Lock()
+	f(tk.DoT)
+	tk.mu.Unlock()
+}
+
 // WithTestKeysDo53 calls the given function with the mutex locked passing to
 // it as argument the pointer to the Do53 field.
 func (tk *TestKeys) WithTestKeysDo53(f func(*TestKeysDo53)) {
@@ -336,11 +396,19 @@ func NewTestKeys() *TestKeys {
 			NetworkEvents: []*model.ArchivalNetworkEvent{},
 			Queries:       []*model.ArchivalDNSLookupResult{},
 		},
+		DoT: &TestKeysDoT{
+			NetworkEvents:  []*model.ArchivalNetworkEvent{},
+			Queries:        []*model.ArchivalDNSLookupResult{},
+			TCPConnect:     []*model.ArchivalTCPConnectResult{},
+			TLSHandshakes:  []*model.ArchivalTLSOrQUICHandshakeResult{},
+			QUICHandshakes: []*model.ArchivalTLSOrQUICHandshakeResult{},
+		},
 		DNSDuplicateResponses: []*model.ArchivalDNSLookupResult{},
 		Queries:               []*model.ArchivalDNSLookupResult{},
 		Requests:              []*model.ArchivalHTTPRequestResult{},
 		TCPConnect:            []*model.ArchivalTCPConnectResult{},
 		TLSHandshakes:         []*model.ArchivalTLSOrQUICHandshakeResult{},
+		QUICHandshakes:        []*model.ArchivalTLSOrQUICHandshakeResult{},
 		Control:               nil,
 		ConnPriorityLog:       []*ConnPriorityLogEntry{},
 		ControlFailure:        nil,
@@ -349,6 +417,9 @@ func NewTestKeys() *TestKeys {
 		DNSConsistency:        optional.None[string](),
 		HTTPExperimentFailure: optional.None[string](),
 		BlockingFlags:         0,
+		TLSFlags:              0,
+		ThrottlingFlags:       0,
+		Throttled:             optional.None[bool](),
 		NullNullFlags:         0,
 		BodyProportion:        0,
 		BodyLengthMatch:       optional.None[bool](),
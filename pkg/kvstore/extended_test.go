@@ -0,0 +1,183 @@
+package kvstore
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+// testExtendedKeyValueStore checks whether kvs behaves as expected.
+func testExtendedKeyValueStore(t *testing.T, kvs model.ExtendedKeyValueStore) {
+	t.Run("Get, Set, and Delete", func(t *testing.T) {
+		if _, err := kvs.Get("antani"); !errors.Is(err, ErrNoSuchKey) {
+			t.Fatal("unexpected err", err)
+		}
+		if err := kvs.Set("antani", []byte("mascetti")); err != nil {
+			t.Fatal(err)
+		}
+		value, err := kvs.Get("antani")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]byte("mascetti"), value); diff != "" {
+			t.Fatal(diff)
+		}
+		if err := kvs.Delete("antani"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := kvs.Get("antani"); !errors.Is(err, ErrNoSuchKey) {
+			t.Fatal("unexpected err", err)
+		}
+		// deleting a nonexisting key is not an error
+		if err := kvs.Delete("antani"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Set with empty value", func(t *testing.T) {
+		if err := kvs.Set("empty", nil); err != nil {
+			t.Fatal(err)
+		}
+		value, err := kvs.Get("empty")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(value) != 0 {
+			t.Fatal("expected empty value")
+		}
+		if err := kvs.Delete("empty"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		for _, key := range []string{"foo.state", "bar.state", "foobar.state", "baz.state"} {
+			if err := kvs.Set(key, []byte(key)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		allKeys, err := kvs.Keys("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectAll := []string{"bar.state", "baz.state", "foo.state", "foobar.state"}
+		if diff := cmp.Diff(expectAll, allKeys); diff != "" {
+			t.Fatal(diff)
+		}
+
+		fooKeys, err := kvs.Keys("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"foo.state", "foobar.state"}, fooKeys); diff != "" {
+			t.Fatal(diff)
+		}
+
+		noKeys, err := kvs.Keys("nonexistent")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(noKeys) != 0 {
+			t.Fatal("expected no keys")
+		}
+
+		for _, key := range allKeys {
+			if err := kvs.Delete(key); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("CompareAndSwap", func(t *testing.T) {
+		// we cannot swap a nonexisting key unless oldValue is nil
+		swapped, err := kvs.CompareAndSwap("counter", []byte("0"), []byte("1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if swapped {
+			t.Fatal("should not have swapped")
+		}
+
+		// a nil oldValue means the key should not exist
+		swapped, err = kvs.CompareAndSwap("counter", nil, []byte("0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !swapped {
+			t.Fatal("should have swapped")
+		}
+		swapped, err = kvs.CompareAndSwap("counter", nil, []byte("0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if swapped {
+			t.Fatal("should not have swapped")
+		}
+
+		// we only swap when the current value matches
+		swapped, err = kvs.CompareAndSwap("counter", []byte("1"), []byte("2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if swapped {
+			t.Fatal("should not have swapped")
+		}
+		swapped, err = kvs.CompareAndSwap("counter", []byte("0"), []byte("1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !swapped {
+			t.Fatal("should have swapped")
+		}
+		value, err := kvs.Get("counter")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]byte("1"), value); diff != "" {
+			t.Fatal(diff)
+		}
+
+		// an empty oldValue matches an existing empty value
+		if err := kvs.Set("counter", []byte{}); err != nil {
+			t.Fatal(err)
+		}
+		swapped, err = kvs.CompareAndSwap("counter", []byte{}, []byte("0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !swapped {
+			t.Fatal("should have swapped")
+		}
+
+		if err := kvs.Delete("counter"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestExtendedKeyValueStore(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		testExtendedKeyValueStore(t, &Memory{})
+	})
+
+	t.Run("FS", func(t *testing.T) {
+		kvs, err := NewFS(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		testExtendedKeyValueStore(t, kvs)
+	})
+
+	t.Run("SQLite", func(t *testing.T) {
+		kvs, err := NewSQLite(filepath.Join(t.TempDir(), "kvstore.sqlite3"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer kvs.Close()
+		testExtendedKeyValueStore(t, kvs)
+	})
+}
@@ -1,22 +1,42 @@
 package kvstore
 
 import (
-	"bytes"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/ooni/probe-engine/pkg/model"
 	"github.com/rogpeppe/go-internal/lockedfile"
 )
 
 // FS is a file-system based KVStore.
+//
+// We store each key as a file inside the base directory. We write values
+// into a temporary file, which we rename over the key's file once we have
+// flushed it to disk, so a crash never leaves behind a truncated value.
+//
+// Reads and writes hold the same [lockedfile] mutex, so no goroutine or
+// process using this type keeps a key's file open while we rename over it.
+// This matters on Windows, where renaming over a file that another process
+// has open fails. Code reading the files without holding the mutex may
+// still cause such a rename to fail, in which case Set returns an error.
 type FS struct {
 	basedir string
 }
 
-var _ model.KeyValueStore = &FS{}
+var _ model.ExtendedKeyValueStore = &FS{}
+
+const (
+	// fsLockFile is the name of the file we use to serialize reads and
+	// writes between goroutines and between processes.
+	fsLockFile = ".lock"
+
+	// fsTempPattern is the pattern for temporary files.
+	fsTempPattern = ".tmp-*"
+)
 
 // NewFS creates a new kvstore.FileSystem.
 func NewFS(basedir string) (kvs *FS, err error) {
@@ -32,7 +52,23 @@ func newFileSystem(basedir string, mkdir osMkdirAll) (*FS, error) {
 	if err := mkdir(basedir, 0700); err != nil {
 		return nil, err
 	}
-	return &FS{basedir: basedir}, nil
+	kvs := &FS{basedir: basedir}
+	kvs.removeStaleTempFiles()
+	return kvs, nil
+}
+
+// removeStaleTempFiles removes the temporary files left behind
+// by a process that crashed while writing a value.
+func (kvs *FS) removeStaleTempFiles() {
+	unlock, err := kvs.lock()
+	if err != nil {
+		return
+	}
+	defer unlock()
+	matches, _ := filepath.Glob(filepath.Join(kvs.basedir, fsTempPattern))
+	for _, match := range matches {
+		_ = os.Remove(match)
+	}
 }
 
 // filename returns the filename for a given key.
@@ -40,17 +76,124 @@ func (kvs *FS) filename(key string) string {
 	return filepath.Join(kvs.basedir, key)
 }
 
+// lock acquires the lock protecting reads and writes.
+func (kvs *FS) lock() (func(), error) {
+	return lockedfile.MutexAt(filepath.Join(kvs.basedir, fsLockFile)).Lock()
+}
+
 // Get returns the specified key's value. In case of error, the
 // error type is such that errors.Is(err, ErrNoSuchKey).
 func (kvs *FS) Get(key string) ([]byte, error) {
-	data, err := lockedfile.Read(kvs.filename(key))
+	unlock, err := kvs.lock()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchKey, err.Error())
+	}
+	defer unlock()
+	data, err := os.ReadFile(kvs.filename(key))
 	if err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrNoSuchKey, err.Error())
 	}
 	return data, nil
 }
 
-// Set sets the value of a specific key.
+// Set atomically sets the value of a specific key.
 func (kvs *FS) Set(key string, value []byte) error {
-	return lockedfile.Write(kvs.filename(key), bytes.NewReader(value), 0600)
+	unlock, err := kvs.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return kvs.writeFileAtomic(key, value)
+}
+
+// writeFileAtomic writes the value into a temporary file and
+// then renames the temporary file to the key's file.
+func (kvs *FS) writeFileAtomic(key string, value []byte) error {
+	filep, err := os.CreateTemp(kvs.basedir, fsTempPattern)
+	if err != nil {
+		return err
+	}
+	tempname := filep.Name()
+	defer os.Remove(tempname) // fails with ENOENT after a successful rename
+	if _, err := filep.Write(value); err != nil {
+		filep.Close()
+		return err
+	}
+	if err := filep.Sync(); err != nil {
+		filep.Close()
+		return err
+	}
+	if err := filep.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tempname, kvs.filename(key)); err != nil {
+		return err
+	}
+	kvs.syncDir()
+	return nil
+}
+
+// syncDir flushes the directory entries to disk. This operation is
+// not supported on all systems, hence we ignore its errors.
+func (kvs *FS) syncDir() {
+	dirp, err := os.Open(kvs.basedir)
+	if err != nil {
+		return
+	}
+	_ = dirp.Sync()
+	dirp.Close()
+}
+
+// Delete removes a specific key.
+func (kvs *FS) Delete(key string) error {
+	unlock, err := kvs.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := os.Remove(kvs.filename(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Keys returns the sorted list of keys starting with prefix.
+func (kvs *FS) Keys(prefix string) ([]string, error) {
+	entries, err := os.ReadDir(kvs.basedir)
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		// skip the lock file, temporary files, and directories
+		if strings.HasPrefix(name, ".") || !entry.Type().IsRegular() {
+			continue
+		}
+		if strings.HasPrefix(name, prefix) {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// CompareAndSwap sets the key to newValue if its value is oldValue.
+func (kvs *FS) CompareAndSwap(key string, oldValue, newValue []byte) (bool, error) {
+	unlock, err := kvs.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	value, err := os.ReadFile(kvs.filename(key))
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if !compareValues(value, err == nil, oldValue) {
+		return false, nil
+	}
+	if err := kvs.writeFileAtomic(key, newValue); err != nil {
+		return false, err
+	}
+	return true, nil
 }
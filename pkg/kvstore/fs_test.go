@@ -7,14 +7,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestFileSystemGood(t *testing.T) {
-	dirpath := filepath.Join("testdata", "kvstore2")
-	if err := os.RemoveAll(dirpath); err != nil {
-		t.Fatal(err)
-	}
-	kvstore, err := NewFS(dirpath)
+	kvstore, err := NewFS(t.TempDir())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -32,11 +29,7 @@ func TestFileSystemGood(t *testing.T) {
 }
 
 func TestFileSystemNoSuchKey(t *testing.T) {
-	dirpath := filepath.Join("testdata", "kvstore2")
-	if err := os.RemoveAll(dirpath); err != nil {
-		t.Fatal(err)
-	}
-	kvstore, err := NewFS(dirpath)
+	kvstore, err := NewFS(t.TempDir())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -55,7 +48,7 @@ func TestFileSystemWithFailure(t *testing.T) {
 		return expect
 	}
 	kvstore, err := newFileSystem(
-		filepath.Join("testdata", "kvstore2"),
+		filepath.Join(t.TempDir(), "kvstore2"),
 		mkdir,
 	)
 	if !errors.Is(err, expect) {
@@ -65,3 +58,112 @@ func TestFileSystemWithFailure(t *testing.T) {
 		t.Fatal("expected nil here")
 	}
 }
+
+func TestFileSystemAtomicWrites(t *testing.T) {
+	t.Run("we remove stale temporary files", func(t *testing.T) {
+		dirpath := t.TempDir()
+		stale := filepath.Join(dirpath, ".tmp-123456")
+		if err := os.WriteFile(stale, []byte("trunc"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewFS(dirpath); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(stale); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatal("expected the stale file to be removed", err)
+		}
+	})
+
+	t.Run("Keys ignores lock files, temporary files, and directories", func(t *testing.T) {
+		dirpath := t.TempDir()
+		kvstore, err := NewFS(dirpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := kvstore.Set("antani", []byte("mascetti")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dirpath, ".tmp-123456"), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Mkdir(filepath.Join(dirpath, "subdir"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		keys, err := kvstore.Keys("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 1 || keys[0] != "antani" {
+			t.Fatal("unexpected keys", keys)
+		}
+	})
+
+	t.Run("we can read values written by older versions", func(t *testing.T) {
+		dirpath := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dirpath, "sessionstate"), []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		kvstore, err := NewFS(dirpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err := kvstore.Get("sessionstate")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(value, []byte("{}")) {
+			t.Fatal("invalid value")
+		}
+	})
+
+	t.Run("Get waits for concurrent writers", func(t *testing.T) {
+		kvstore, err := NewFS(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		unlock, err := kvstore.lock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error)
+		go func() {
+			_, err := kvstore.Get("antani")
+			done <- err
+		}()
+		select {
+		case <-done:
+			t.Fatal("Get did not wait for the lock")
+		case <-time.After(100 * time.Millisecond):
+		}
+		unlock()
+		if err := <-done; !errors.Is(err, ErrNoSuchKey) {
+			t.Fatal("unexpected err", err)
+		}
+	})
+
+	t.Run("operations fail when the directory is gone", func(t *testing.T) {
+		dirpath := filepath.Join(t.TempDir(), "kvstore")
+		kvstore, err := NewFS(dirpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.RemoveAll(dirpath); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := kvstore.Get("antani"); !errors.Is(err, ErrNoSuchKey) {
+			t.Fatal("unexpected err", err)
+		}
+		if err := kvstore.Set("antani", nil); err == nil {
+			t.Fatal("expected an error here")
+		}
+		if err := kvstore.Delete("antani"); err == nil {
+			t.Fatal("expected an error here")
+		}
+		if _, err := kvstore.CompareAndSwap("antani", nil, nil); err == nil {
+			t.Fatal("expected an error here")
+		}
+		if _, err := kvstore.Keys(""); err == nil {
+			t.Fatal("expected an error here")
+		}
+	})
+}
@@ -1,7 +1,10 @@
 package kvstore
 
 import (
+	"bytes"
 	"errors"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/ooni/probe-engine/pkg/model"
@@ -21,7 +24,7 @@ type Memory struct {
 	mu sync.Mutex
 }
 
-var _ model.KeyValueStore = &Memory{}
+var _ model.ExtendedKeyValueStore = &Memory{}
 
 // Get returns the specified key's value. In case of error, the
 // error type is such that errors.Is(err, ErrNoSuchKey).
@@ -45,3 +48,50 @@ func (kvs *Memory) Set(key string, value []byte) error {
 	kvs.m[key] = value
 	return nil
 }
+
+// Delete removes a key from the key-value store.
+func (kvs *Memory) Delete(key string) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	delete(kvs.m, key)
+	return nil
+}
+
+// Keys returns the sorted list of keys starting with prefix.
+func (kvs *Memory) Keys(prefix string) ([]string, error) {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	keys := []string{}
+	for key := range kvs.m {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// CompareAndSwap sets the key to newValue if its value is oldValue.
+func (kvs *Memory) CompareAndSwap(key string, oldValue, newValue []byte) (bool, error) {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	value, found := kvs.m[key]
+	if !compareValues(value, found, oldValue) {
+		return false, nil
+	}
+	if kvs.m == nil {
+		kvs.m = make(map[string][]byte)
+	}
+	kvs.m[key] = newValue
+	return true, nil
+}
+
+// compareValues returns whether the current value of a key, along with
+// whether the key exists, matches the expected oldValue, where a nil
+// oldValue means that the key should not exist.
+func compareValues(value []byte, found bool, oldValue []byte) bool {
+	if oldValue == nil {
+		return !found
+	}
+	return found && bytes.Equal(value, oldValue)
+}
@@ -0,0 +1,46 @@
+package kvstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+// migratedKey is the key that [Migrate] writes into dst once it has
+// copied all the keys, such that we only migrate once.
+const migratedKey = "kvstore.migrated"
+
+// Migrate copies all the keys in src to dst, e.g., to import the keys that
+// an older version of the engine stored using the file-system based [FS] layout.
+//
+// We do not overwrite the keys that already exist in dst. Once we have copied
+// all the keys, we write a marker key into dst and we do nothing when the marker
+// is already there. Therefore, it is safe to call this function every time we open
+// dst, because we do not resurrect keys that the engine deleted after the migration.
+func Migrate(dst, src model.ExtendedKeyValueStore) error {
+	_, err := dst.Get(migratedKey)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, ErrNoSuchKey) {
+		return fmt.Errorf("kvstore: cannot check whether we already migrated: %w", err)
+	}
+	keys, err := src.Keys("")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		value, err := src.Get(key)
+		if err != nil {
+			return fmt.Errorf("kvstore: cannot migrate %s: %w", key, err)
+		}
+		if _, err := dst.CompareAndSwap(key, nil, value); err != nil {
+			return fmt.Errorf("kvstore: cannot migrate %s: %w", key, err)
+		}
+	}
+	if err := dst.Set(migratedKey, []byte("true")); err != nil {
+		return fmt.Errorf("kvstore: cannot mark the migration as done: %w", err)
+	}
+	return nil
+}
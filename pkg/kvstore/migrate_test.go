@@ -0,0 +1,138 @@
+package kvstore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/mocks"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Run("we copy the keys without overwriting existing keys", func(t *testing.T) {
+		src, err := NewFS(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := src.Set("sessionstate", []byte("old-session")); err != nil {
+			t.Fatal(err)
+		}
+		if err := src.Set("httpsdialerstats.state", []byte("old-stats")); err != nil {
+			t.Fatal(err)
+		}
+		dst := &Memory{}
+		if err := dst.Set("sessionstate", []byte("new-session")); err != nil {
+			t.Fatal(err)
+		}
+		if err := Migrate(dst, src); err != nil {
+			t.Fatal(err)
+		}
+		expect := map[string]string{
+			"httpsdialerstats.state": "old-stats",
+			"sessionstate":           "new-session",
+		}
+		got := map[string]string{}
+		for key := range expect {
+			value, err := dst.Get(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got[key] = string(value)
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we do not resurrect keys deleted after the migration", func(t *testing.T) {
+		src := &Memory{}
+		if err := src.Set("sessionstate", []byte("old-session")); err != nil {
+			t.Fatal(err)
+		}
+		dst := &Memory{}
+		if err := Migrate(dst, src); err != nil {
+			t.Fatal(err)
+		}
+		if err := dst.Delete("sessionstate"); err != nil {
+			t.Fatal(err)
+		}
+		if err := Migrate(dst, src); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dst.Get("sessionstate"); !errors.Is(err, ErrNoSuchKey) {
+			t.Fatal("unexpected err", err)
+		}
+	})
+
+	t.Run("when we cannot check whether we already migrated", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		dst := &mocks.ExtendedKeyValueStore{
+			MockGet: func(key string) ([]byte, error) {
+				return nil, expect
+			},
+		}
+		if err := Migrate(dst, &Memory{}); !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+	})
+
+	t.Run("when we cannot list the keys", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		src := &mocks.ExtendedKeyValueStore{
+			MockKeys: func(prefix string) ([]string, error) {
+				return nil, expect
+			},
+		}
+		if err := Migrate(&Memory{}, src); !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+	})
+
+	t.Run("when we cannot read a key", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		src := &mocks.ExtendedKeyValueStore{
+			MockKeys: func(prefix string) ([]string, error) {
+				return []string{"antani"}, nil
+			},
+			MockGet: func(key string) ([]byte, error) {
+				return nil, expect
+			},
+		}
+		if err := Migrate(&Memory{}, src); !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+	})
+
+	t.Run("when we cannot write a key", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		src := &Memory{}
+		if err := src.Set("antani", []byte("mascetti")); err != nil {
+			t.Fatal(err)
+		}
+		dst := &mocks.ExtendedKeyValueStore{
+			MockGet: func(key string) ([]byte, error) {
+				return nil, ErrNoSuchKey
+			},
+			MockCompareAndSwap: func(key string, oldValue, newValue []byte) (bool, error) {
+				return false, expect
+			},
+		}
+		if err := Migrate(dst, src); !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+	})
+	t.Run("when we cannot write the marker", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		dst := &mocks.ExtendedKeyValueStore{
+			MockGet: func(key string) ([]byte, error) {
+				return nil, ErrNoSuchKey
+			},
+			MockSet: func(key string, value []byte) error {
+				return expect
+			},
+		}
+		if err := Migrate(dst, &Memory{}); !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+	})
+}
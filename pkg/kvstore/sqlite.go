@@ -0,0 +1,149 @@
+package kvstore
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+// SQLite is a KVStore storing all the keys inside a single SQLite database file.
+//
+// We open the database in WAL mode with full synchronization, such that a
+// crash or a power loss never leaves behind a partially written value.
+type SQLite struct {
+	db *sql.DB
+}
+
+var _ model.ExtendedKeyValueStore = &SQLite{}
+
+// sqliteSchema is the schema of the SQLite database.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS kvstore (
+	key TEXT PRIMARY KEY NOT NULL,
+	value BLOB NOT NULL
+)`
+
+// NewSQLite opens or creates the SQLite database at the given path. You
+// MUST call Close when you are done using the returned store.
+func NewSQLite(path string) (*SQLite, error) {
+	// Implementation note: we use immediate transactions such that the
+	// CompareAndSwap transaction acquires the write lock upfront.
+	options := url.Values{}
+	options.Set("_busy_timeout", "5000")
+	options.Set("_journal_mode", "WAL")
+	options.Set("_synchronous", "FULL")
+	options.Set("_txlock", "immediate")
+	db, err := sql.Open("sqlite3", "file:"+path+"?"+options.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLite{db: db}, nil
+}
+
+// Close closes the underlying database.
+func (kvs *SQLite) Close() error {
+	return kvs.db.Close()
+}
+
+// Get returns the specified key's value. In case of error, the
+// error type is such that errors.Is(err, ErrNoSuchKey).
+func (kvs *SQLite) Get(key string) ([]byte, error) {
+	return sqliteGet(kvs.db, key)
+}
+
+// sqliteQueryRower is the common interface of [*sql.DB] and [*sql.Tx] used by sqliteGet.
+type sqliteQueryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// sqliteGet reads the value of a key using the given [sqliteQueryRower].
+func sqliteGet(q sqliteQueryRower, key string) ([]byte, error) {
+	var value []byte
+	err := q.QueryRow("SELECT value FROM kvstore WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoSuchKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = []byte{} // make sure we distinguish empty values from missing keys
+	}
+	return value, nil
+}
+
+// Set atomically sets the value of a specific key.
+func (kvs *SQLite) Set(key string, value []byte) error {
+	_, err := kvs.db.Exec("INSERT OR REPLACE INTO kvstore (key, value) VALUES (?, ?)", key, sqliteValue(value))
+	return err
+}
+
+// sqliteValue makes sure we never attempt to store a NULL value.
+func sqliteValue(value []byte) []byte {
+	if value == nil {
+		return []byte{}
+	}
+	return value
+}
+
+// Delete removes a specific key.
+func (kvs *SQLite) Delete(key string) error {
+	_, err := kvs.db.Exec("DELETE FROM kvstore WHERE key = ?", key)
+	return err
+}
+
+// Keys returns the sorted list of keys starting with prefix.
+func (kvs *SQLite) Keys(prefix string) ([]string, error) {
+	// Implementation note: SQLite compares TEXT using memcmp by default, hence all the
+	// keys starting with prefix are contiguous and not smaller than the prefix.
+	rows, err := kvs.db.Query("SELECT key FROM kvstore WHERE key >= ? ORDER BY key", prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CompareAndSwap sets the key to newValue if its value is oldValue.
+func (kvs *SQLite) CompareAndSwap(key string, oldValue, newValue []byte) (bool, error) {
+	tx, err := kvs.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback() // fails with ErrTxDone after a successful commit
+	value, err := sqliteGet(tx, key)
+	if err != nil && !errors.Is(err, ErrNoSuchKey) {
+		return false, err
+	}
+	if !compareValues(value, err == nil, oldValue) {
+		return false, nil
+	}
+	if _, err := tx.Exec("INSERT OR REPLACE INTO kvstore (key, value) VALUES (?, ?)", key, sqliteValue(newValue)); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,71 @@
+package kvstore
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLite(t *testing.T) {
+	t.Run("values persist across reopening the database", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "kvstore.sqlite3")
+		kvs, err := NewSQLite(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := kvs.Set("antani", []byte("mascetti")); err != nil {
+			t.Fatal(err)
+		}
+		if err := kvs.Close(); err != nil {
+			t.Fatal(err)
+		}
+		kvs, err = NewSQLite(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer kvs.Close()
+		value, err := kvs.Get("antani")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(value, []byte("mascetti")) {
+			t.Fatal("invalid value")
+		}
+	})
+
+	t.Run("NewSQLite fails when we cannot create the database", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nonexistent", "kvstore.sqlite3")
+		kvs, err := NewSQLite(path)
+		if err == nil {
+			t.Fatal("expected an error here")
+		}
+		if kvs != nil {
+			t.Fatal("expected nil kvs")
+		}
+	})
+
+	t.Run("operations fail after Close", func(t *testing.T) {
+		kvs, err := NewSQLite(filepath.Join(t.TempDir(), "kvstore.sqlite3"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := kvs.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := kvs.Get("antani"); err == nil {
+			t.Fatal("expected an error here")
+		}
+		if err := kvs.Set("antani", nil); err == nil {
+			t.Fatal("expected an error here")
+		}
+		if err := kvs.Delete("antani"); err == nil {
+			t.Fatal("expected an error here")
+		}
+		if _, err := kvs.Keys(""); err == nil {
+			t.Fatal("expected an error here")
+		}
+		if _, err := kvs.CompareAndSwap("antani", nil, nil); err == nil {
+			t.Fatal("expected an error here")
+		}
+	})
+}
@@ -33,6 +33,15 @@ import (
 // - if the URL starts with `udp://`, then we create a client using
 // a resolver that uses the specified UDP endpoint.
 //
+// - if the URL starts with `doq://`, then we create a DNS-over-QUIC
+// client using the specified endpoint (RFC9250).
+//
+// - if the URL starts with `odoh://`, then we create an Oblivious
+// DNS-over-HTTPS client (RFC9230) using the URL host and path as the
+// HTTPS proxy and the `targethost` and `targetpath` query parameters
+// as the target (e.g., `odoh://odoh1.surfdomeinen.nl/proxy?targethost=
+// odoh.cloudflare-dns.com&targetpath=/dns-query`).
+//
 // We return error if the URL does not parse or the URL scheme does not
 // fall into one of the cases described above.
 //
@@ -95,6 +104,38 @@ func NewDNSClientWithOverrides(config Config, URL, hostOverride, SNIOverride,
 			tlsDialer.DialTLSContext, endpoint)
 		txp = config.Saver.WrapDNSTransport(txp) // safe when config.Saver == nil
 		return netxlite.NewUnwrappedSerialResolver(txp), nil
+	case "doq":
+		config.TLSConfig.NextProtos = []string{"doq"}
+		quicDialer := NewQUICDialer(config)
+		endpoint, err := makeValidEndpoint(resolverURL)
+		if err != nil {
+			return nil, err
+		}
+		var txp model.DNSTransport = netxlite.NewUnwrappedDNSOverQUICTransportWithTLSConfig(
+			quicDialer, endpoint, config.TLSConfig)
+		txp = config.Saver.WrapDNSTransport(txp) // safe when config.Saver == nil
+		return netxlite.NewUnwrappedSerialResolver(txp), nil
+	case "odoh":
+		proxyURL, targetURL, err := makeODoHURLs(resolverURL)
+		if err != nil {
+			return nil, err
+		}
+		// Note: we fetch the configs directly from the target, hence we
+		// must not apply the SNI override, which is meant for the proxy
+		configsConfig := config
+		configsConfig.TLSConfig = &tls.Config{
+			NextProtos: []string{"h2", "http/1.1"},
+			MinVersion: config.TLSConfig.MinVersion,
+			MaxVersion: config.TLSConfig.MaxVersion,
+		}
+		config.TLSConfig.NextProtos = []string{"h2", "http/1.1"}
+		httpClient := &http.Client{Transport: NewHTTPTransport(config)}
+		odoh := netxlite.NewUnwrappedDNSOverObliviousHTTPSTransport(httpClient, proxyURL, targetURL)
+		odoh.ConfigsClient = &http.Client{Transport: NewHTTPTransport(configsConfig)}
+		odoh.HostOverride = hostOverride
+		var txp model.DNSTransport = odoh
+		txp = config.Saver.WrapDNSTransport(txp) // safe when config.Saver == nil
+		return netxlite.NewUnwrappedSerialResolver(txp), nil
 	case "tcp":
 		dialer := NewDialer(config)
 		endpoint, err := makeValidEndpoint(resolverURL)
@@ -110,11 +151,23 @@ func NewDNSClientWithOverrides(config Config, URL, hostOverride, SNIOverride,
 	}
 }
 
-// makeValidEndpoint makes a valid endpoint for DoT and Do53 given the
-// input URL representing such endpoint. Specifically, we are
+// makeODoHURLs returns the HTTPS proxy URL and target URL given
+// the input URL representing an Oblivious DNS-over-HTTPS client.
+func makeODoHURLs(URL *url.URL) (string, string, error) {
+	targetHost := URL.Query().Get("targethost")
+	if targetHost == "" {
+		return "", "", errors.New("odoh: missing targethost")
+	}
+	proxyURL := &url.URL{Scheme: "https", Host: URL.Host, Path: URL.Path}
+	targetURL := &url.URL{Scheme: "https", Host: targetHost, Path: URL.Query().Get("targetpath")}
+	return proxyURL.String(), targetURL.String(), nil
+}
+
+// makeValidEndpoint makes a valid endpoint for DoT, DoQ, and Do53 given
+// the input URL representing such endpoint. Specifically, we are
 // concerned with the case where the port is missing. In such a
 // case, we ensure that we are using the default port 853 for DoT
-// and default port 53 for TCP and UDP.
+// and DoQ and default port 53 for TCP and UDP.
 func makeValidEndpoint(URL *url.URL) (string, error) {
 	// Implementation note: when we're using a quoted IPv6
 	// address, URL.Host contains the quotes but instead the
@@ -152,7 +205,7 @@ func makeValidEndpoint(URL *url.URL) (string, error) {
 	// For this reason we check again whether we can split it using
 	// net.SplitHostPort. If we cannot, we were in case four.
 	host := URL.Host
-	if URL.Scheme == "dot" {
+	if URL.Scheme == "dot" || URL.Scheme == "doq" {
 		host += ":853"
 	} else {
 		host += ":53"
@@ -220,6 +220,163 @@ func TestNewDNSCLientDoTWithoutPort(t *testing.T) {
 	}
 }
 
+func TestNewDNSClientDoQ(t *testing.T) {
+	dnsclient, err := NewDNSClient(
+		Config{}, "doq://94.140.14.14:853")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := dnsclient.(*netxlite.SerialResolver)
+	if !ok {
+		t.Fatal("not the resolver we expected")
+	}
+	txp, ok := r.Transport().(*netxlite.DNSOverQUICTransport)
+	if !ok {
+		t.Fatal("not the transport we expected")
+	}
+	if txp.Network() != "doq" {
+		t.Fatal("not the Network we expected")
+	}
+	dnsclient.CloseIdleConnections()
+}
+
+func TestNewDNSClientDoQDNSSaver(t *testing.T) {
+	saver := new(tracex.Saver)
+	dnsclient, err := NewDNSClient(
+		Config{Saver: saver}, "doq://94.140.14.14:853")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := dnsclient.(*netxlite.SerialResolver)
+	if !ok {
+		t.Fatal("not the resolver we expected")
+	}
+	txp, ok := r.Transport().(*tracex.DNSTransportSaver)
+	if !ok {
+		t.Fatal("not the transport we expected")
+	}
+	doquic, ok := txp.DNSTransport.(*netxlite.DNSOverQUICTransport)
+	if !ok {
+		t.Fatal("not the transport we expected")
+	}
+	if doquic.Network() != "doq" {
+		t.Fatal("not the Network we expected")
+	}
+	dnsclient.CloseIdleConnections()
+}
+
+func TestNewDNSCLientDoQWithoutPort(t *testing.T) {
+	c, err := NewDNSClientWithOverrides(
+		Config{}, "doq://94.140.14.14", "", "dns.adguard-dns.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Address() != "94.140.14.14:853" {
+		t.Fatal("expected default port to be added")
+	}
+}
+
+func TestNewDNSClientBadDoQEndpoint(t *testing.T) {
+	_, err := NewDNSClient(
+		Config{}, "doq://bad:endpoint:853")
+	if err == nil || !strings.Contains(err.Error(), "too many colons in address") {
+		t.Fatal("expected error with bad endpoint")
+	}
+}
+
+func TestNewDNSClientODoH(t *testing.T) {
+	dnsclient, err := NewDNSClient(Config{},
+		"odoh://odoh1.surfdomeinen.nl/proxy?targethost=odoh.cloudflare-dns.com&targetpath=/dns-query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := dnsclient.(*netxlite.SerialResolver)
+	if !ok {
+		t.Fatal("not the resolver we expected")
+	}
+	txp, ok := r.Transport().(*netxlite.DNSOverObliviousHTTPSTransport)
+	if !ok {
+		t.Fatal("not the transport we expected")
+	}
+	if txp.ProxyURL != "https://odoh1.surfdomeinen.nl/proxy" {
+		t.Fatal("not the ProxyURL we expected", txp.ProxyURL)
+	}
+	if txp.TargetURL != "https://odoh.cloudflare-dns.com/dns-query" {
+		t.Fatal("not the TargetURL we expected", txp.TargetURL)
+	}
+	if txp.ConfigsClient == nil {
+		t.Fatal("expected non-nil ConfigsClient")
+	}
+	if txp.Network() != "odoh" {
+		t.Fatal("not the Network we expected")
+	}
+	dnsclient.CloseIdleConnections()
+}
+
+func TestNewDNSClientODoHDNSSaver(t *testing.T) {
+	saver := new(tracex.Saver)
+	dnsclient, err := NewDNSClient(Config{Saver: saver},
+		"odoh://odoh1.surfdomeinen.nl/proxy?targethost=odoh.cloudflare-dns.com&targetpath=/dns-query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := dnsclient.(*netxlite.SerialResolver)
+	if !ok {
+		t.Fatal("not the resolver we expected")
+	}
+	txp, ok := r.Transport().(*tracex.DNSTransportSaver)
+	if !ok {
+		t.Fatal("not the transport we expected")
+	}
+	odoh, ok := txp.DNSTransport.(*netxlite.DNSOverObliviousHTTPSTransport)
+	if !ok {
+		t.Fatal("not the transport we expected")
+	}
+	if odoh.Network() != "odoh" {
+		t.Fatal("not the Network we expected")
+	}
+	dnsclient.CloseIdleConnections()
+}
+
+func TestNewDNSClientODoHWithOverrides(t *testing.T) {
+	dnsclient, err := NewDNSClientWithOverrides(Config{},
+		"odoh://145.100.185.15/proxy?targethost=odoh.cloudflare-dns.com&targetpath=/dns-query",
+		"odoh1.surfdomeinen.nl", "odoh1.surfdomeinen.nl", "TLSv1.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := dnsclient.(*netxlite.SerialResolver)
+	txp := r.Transport().(*netxlite.DNSOverObliviousHTTPSTransport)
+	if txp.HostOverride != "odoh1.surfdomeinen.nl" {
+		t.Fatal("not the HostOverride we expected")
+	}
+	if txp.ProxyURL != "https://145.100.185.15/proxy" {
+		t.Fatal("not the ProxyURL we expected", txp.ProxyURL)
+	}
+}
+
+func TestNewDNSClientODoHWithoutTargetHost(t *testing.T) {
+	dnsclient, err := NewDNSClient(Config{}, "odoh://odoh1.surfdomeinen.nl/proxy")
+	if err == nil || err.Error() != "odoh: missing targethost" {
+		t.Fatal("not the error we expected", err)
+	}
+	if dnsclient != nil {
+		t.Fatal("expected nil resolver here")
+	}
+}
+
+func TestNewDNSClientODoHWithInvalidTLSVersion(t *testing.T) {
+	dnsclient, err := NewDNSClientWithOverrides(Config{},
+		"odoh://odoh1.surfdomeinen.nl/proxy?targethost=odoh.cloudflare-dns.com",
+		"", "", "TLSv1_3")
+	if !errors.Is(err, netxlite.ErrInvalidTLSVersion) {
+		t.Fatal("not the error we expected", err)
+	}
+	if dnsclient != nil {
+		t.Fatal("expected nil resolver here")
+	}
+}
+
 func TestNewDNSCLientTCPWithoutPort(t *testing.T) {
 	c, err := NewDNSClientWithOverrides(
 		Config{}, "tcp://8.8.8.8", "", "8.8.8.8", "")
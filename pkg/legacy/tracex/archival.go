@@ -5,6 +5,7 @@ package tracex
 //
 
 import (
+	"encoding/base64"
 	"errors"
 	"net"
 	"strconv"
@@ -260,6 +261,7 @@ func NewTLSHandshakesList(begin time.Time, events []Event) (out []TLSHandshake)
 			continue // not interested
 		}
 		ev := wrapper.Value()
+		echConfig, echAccepted := tlsMakeECHInfo(ev)
 		out = append(out, TLSHandshake{
 			Address:            ev.Address,
 			CipherSuite:        ev.TLSCipherSuite,
@@ -268,6 +270,8 @@ func NewTLSHandshakesList(begin time.Time, events []Event) (out []TLSHandshake)
 			NoTLSVerify:        ev.NoTLSVerify,
 			PeerCertificates:   tlsMakePeerCerts(ev.TLSPeerCerts),
 			ServerName:         ev.TLSServerName,
+			ECHConfig:          echConfig,
+			ECHAccepted:        echAccepted,
 			T:                  ev.Time.Sub(begin).Seconds(),
 			TLSVersion:         ev.TLSVersion,
 		})
@@ -275,6 +279,16 @@ func NewTLSHandshakesList(begin time.Time, events []Event) (out []TLSHandshake)
 	return
 }
 
+// tlsMakeECHInfo returns the base64-encoded ECH config list and whether the server
+// accepted ECH, or an empty string and nil when the handshake did not use ECH.
+func tlsMakeECHInfo(ev *EventValue) (string, *bool) {
+	if len(ev.TLSECHConfigList) <= 0 {
+		return "", nil
+	}
+	accepted := ev.TLSECHAccepted
+	return base64.StdEncoding.EncodeToString(ev.TLSECHConfigList), &accepted
+}
+
 func tlsMakePeerCerts(in [][]byte) (out []model.ArchivalBinaryData) {
 	for _, entry := range in {
 		out = append(out, model.ArchivalBinaryData(entry))
@@ -572,6 +572,41 @@ func TestNewTLSHandshakesList(t *testing.T) {
 			T:          0.055,
 			TLSVersion: "TLSv1.3",
 		}},
+	}, {
+		name: "realistic run with TLS and ECH",
+		args: args{
+			begin: begin,
+			events: []Event{&EventTLSHandshakeDone{&EventValue{
+				Address:            "131.252.210.176:443",
+				NoTLSVerify:        false,
+				Proto:              "tcp",
+				TLSCipherSuite:     "SUITE",
+				TLSECHAccepted:     true,
+				TLSECHConfigList:   []byte{0x00, 0x45, 0xfe, 0x0d},
+				TLSNegotiatedProto: "h2",
+				TLSPeerCerts: [][]byte{
+					[]byte("deadbeef"),
+				},
+				TLSServerName: "x.org",
+				TLSVersion:    "TLSv1.3",
+				Time:          begin.Add(55 * time.Millisecond),
+			}}},
+		},
+		want: []TLSHandshake{{
+			Address:            "131.252.210.176:443",
+			CipherSuite:        "SUITE",
+			Failure:            nil,
+			NegotiatedProtocol: "h2",
+			NoTLSVerify:        false,
+			PeerCertificates: []model.ArchivalBinaryData{
+				model.ArchivalBinaryData("deadbeef"),
+			},
+			ServerName:  "x.org",
+			ECHConfig:   "AEX+DQ==",
+			ECHAccepted: func() *bool { v := true; return &v }(),
+			T:           0.055,
+			TLSVersion:  "TLSv1.3",
+		}},
 	}, {
 		name: "realistic run with no suitable events",
 		args: args{
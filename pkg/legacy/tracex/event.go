@@ -294,6 +294,8 @@ type EventValue struct {
 	Proto                       string        `json:",omitempty"`
 	TLSServerName               string        `json:",omitempty"`
 	TLSCipherSuite              string        `json:",omitempty"`
+	TLSECHAccepted              bool          `json:",omitempty"`
+	TLSECHConfigList            []byte        `json:",omitempty"`
 	TLSNegotiatedProto          string        `json:",omitempty"`
 	TLSNextProtos               []string      `json:",omitempty"`
 	TLSPeerCerts                [][]byte      `json:",omitempty"`
@@ -64,6 +64,8 @@ func (h *TLSHandshakerSaver) Handshake(
 		NoTLSVerify:        config.InsecureSkipVerify,
 		Proto:              proto,
 		TLSCipherSuite:     netxlite.TLSCipherSuiteString(tstate.CipherSuite),
+		TLSECHAccepted:     tstate.ECHAccepted,
+		TLSECHConfigList:   config.EncryptedClientHelloConfigList,
 		TLSNegotiatedProto: tstate.NegotiatedProtocol,
 		TLSNextProtos:      config.NextProtos,
 		TLSPeerCerts:       tlsPeerCerts(tstate, err),
@@ -0,0 +1,11 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+#include "engine.h"
+
+#include "_cgo_export.h"
+
+// cgo exports C strings as char *, so we cannot declare OONITaskStart as
+// taking a const char * directly from Go. We wrap it here instead.
+OONITask OONITaskStart(const char *request) {
+  return ooniTaskStart((char *)request);
+}
@@ -10,6 +10,7 @@ package main
 import "C"
 
 import (
+	"time"
 	"unsafe"
 
 	"github.com/ooni/probe-engine/pkg/version"
@@ -21,8 +22,57 @@ func OONIEngineVersion() *C.char {
 }
 
 //export OONIEngineFreeMemory
-func OONIEngineFreeMemory(ptr *C.void) {
-	C.free(unsafe.Pointer(ptr))
+func OONIEngineFreeMemory(ptr unsafe.Pointer) {
+	C.free(ptr)
+}
+
+// ooniTaskStart implements OONITaskStart, which is defined in engine.c
+// because cgo cannot export a function taking a const char *.
+//
+//export ooniTaskStart
+func ooniTaskStart(request *C.char) C.OONITask {
+	if request == nil {
+		return 0
+	}
+	fn := newTaskFunc([]byte(C.GoString(request)))
+	return C.OONITask(tasks.add(startTask(fn)))
+}
+
+//export OONITaskWaitForNextEvent
+func OONITaskWaitForNextEvent(handle C.OONITask, timeout C.int32_t) *C.char {
+	t := tasks.get(int64(handle))
+	if t == nil {
+		return nil
+	}
+	data := t.waitForNextEvent(time.Duration(timeout) * time.Millisecond)
+	if data == nil {
+		return nil
+	}
+	return C.CString(string(data))
+}
+
+//export OONITaskIsDone
+func OONITaskIsDone(handle C.OONITask) C.uint8_t {
+	t := tasks.get(int64(handle))
+	if t == nil || t.isDone() {
+		return 1
+	}
+	return 0
+}
+
+//export OONITaskInterrupt
+func OONITaskInterrupt(handle C.OONITask) {
+	if t := tasks.get(int64(handle)); t != nil {
+		t.interrupt()
+	}
+}
+
+//export OONITaskFree
+func OONITaskFree(handle C.OONITask) {
+	if t := tasks.remove(int64(handle)); t != nil {
+		t.interrupt()
+		<-t.done
+	}
 }
 
 func main() {
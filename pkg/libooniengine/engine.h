@@ -6,11 +6,41 @@
 ///
 /// C API for using the OONI engine.
 ///
+/// The API is task based. You start a task using a JSON request, which
+/// contains the session configuration along with either an experiment
+/// or an OONI Run v2 descriptor. The task runs in the background and you
+/// poll its JSON events (e.g., logs, progress, measurements, submission
+/// results) until it is done. The last event emitted by a task has type
+/// "task_done" and tells you whether the task failed.
+///
+/// A JSON request looks like this:
+///
+///     {
+///       "session": {
+///         "software_name": "example",
+///         "software_version": "0.1.0",
+///         "state_dir": "/path/to/state/dir"
+///       },
+///       "experiment": {
+///         "name": "web_connectivity",
+///         "inputs": ["https://www.example.com/"]
+///       }
+///     }
+///
+/// Each event is a JSON object like this:
+///
+///     {"type": "log", "t": "2024-01-01T00:00:00Z", "data": {...}}
+///
+
+#include <stdint.h>
 
 #ifdef __cplusplus
 extern "C" {
 #endif
 
+/// OONITask is the handle of a background task. Zero is an invalid handle.
+typedef int64_t OONITask;
+
 /// OONIEngineVersion return the current engine version.
 ///
 /// @return A char pointer with the current version string.
@@ -19,7 +49,46 @@ char *OONIEngineVersion(void);
 /// OONIEngineFreeMemory frees the memory allocated by the engine.
 ///
 /// @param ptr a void pointer refering to the memory to be freed.
-void OONIENgineFreeMemory(void *ptr);
+void OONIEngineFreeMemory(void *ptr);
+
+/// OONITaskStart starts a new background task.
+///
+/// @param request a char pointer to the JSON request describing the task. We
+/// report invalid requests using the "task_done" event.
+///
+/// @return The task handle or zero if request is NULL. You MUST free the
+/// task using OONITaskFree when you are done using it.
+OONITask OONITaskStart(const char *request);
+
+/// OONITaskWaitForNextEvent waits for the next event emitted by a task.
+///
+/// @param task the task handle.
+/// @param timeout the maximum number of milliseconds to wait.
+///
+/// @return A char pointer with the JSON event or NULL if the timeout expired,
+/// the task is done and there are no more events, or the handle is invalid. You
+/// MUST free the returned string using OONIEngineFreeMemory.
+char *OONITaskWaitForNextEvent(OONITask task, int32_t timeout);
+
+/// OONITaskIsDone returns whether a task is done.
+///
+/// @param task the task handle.
+///
+/// @return One if the task is done and you have read all its events, or if the
+/// handle is invalid. Zero otherwise.
+uint8_t OONITaskIsDone(OONITask task);
+
+/// OONITaskInterrupt interrupts a running task. The task will emit its
+/// remaining events, including "task_done", and then terminate.
+///
+/// @param task the task handle.
+void OONITaskInterrupt(OONITask task);
+
+/// OONITaskFree interrupts a task, waits for it to terminate, and frees
+/// the resources it uses. The handle is invalid after this call.
+///
+/// @param task the task handle.
+void OONITaskFree(OONITask task);
 
 #ifdef __cplusplus
 }
@@ -0,0 +1,41 @@
+//go:build linux && cgo
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestCHarness builds libooniengine as a shared library and uses it from
+// the C test harness, to make sure the C API works as intended.
+func TestCHarness(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+	dir := t.TempDir()
+
+	build := exec.Command("go", "build", "-buildmode=c-shared",
+		"-o", filepath.Join(dir, "libooniengine.so"), ".")
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	harness := filepath.Join(dir, "harness")
+	cc := exec.Command("cc", "-Wall", "-Werror", "-I", ".", "-o", harness,
+		filepath.Join("testdata", "harness.c"), "-L", dir, "-looniengine")
+	cc.Stdout, cc.Stderr = os.Stdout, os.Stderr
+	if err := cc.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	run := exec.Command(harness, t.TempDir())
+	run.Env = append(os.Environ(), "LD_LIBRARY_PATH="+dir)
+	run.Stdout, run.Stderr = os.Stdout, os.Stderr
+	if err := run.Run(); err != nil {
+		t.Fatal(err)
+	}
+}
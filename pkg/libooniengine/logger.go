@@ -0,0 +1,68 @@
+package main
+
+//
+// Logging
+//
+
+import (
+	"fmt"
+
+	"github.com/ooni/probe-engine/pkg/logx"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/runevents"
+)
+
+// taskLogger is a [model.Logger] emitting log events.
+type taskLogger struct {
+	// events is the sink for log events.
+	events runevents.Sink
+
+	// verbose indicates whether to emit debug messages.
+	verbose bool
+}
+
+var _ model.Logger = &taskLogger{}
+
+// newTaskLogger creates a new [model.Logger] emitting log events with
+// scrubbing, since we don't know where the logs will end up.
+func newTaskLogger(events runevents.Sink, verbose bool) model.Logger {
+	return &logx.ScrubberLogger{Logger: &taskLogger{events: events, verbose: verbose}}
+}
+
+// Debug implements model.Logger.
+func (tl *taskLogger) Debug(msg string) {
+	if tl.verbose {
+		tl.emit("DEBUG", msg)
+	}
+}
+
+// Debugf implements model.Logger.
+func (tl *taskLogger) Debugf(format string, v ...any) {
+	if tl.verbose {
+		tl.emit("DEBUG", fmt.Sprintf(format, v...))
+	}
+}
+
+// Info implements model.Logger.
+func (tl *taskLogger) Info(msg string) {
+	tl.emit("INFO", msg)
+}
+
+// Infof implements model.Logger.
+func (tl *taskLogger) Infof(format string, v ...any) {
+	tl.emit("INFO", fmt.Sprintf(format, v...))
+}
+
+// Warn implements model.Logger.
+func (tl *taskLogger) Warn(msg string) {
+	tl.emit("WARNING", msg)
+}
+
+// Warnf implements model.Logger.
+func (tl *taskLogger) Warnf(format string, v ...any) {
+	tl.emit("WARNING", fmt.Sprintf(format, v...))
+}
+
+func (tl *taskLogger) emit(level, msg string) {
+	runevents.Emit(tl.events, &runevents.Log{Level: level, Message: msg})
+}
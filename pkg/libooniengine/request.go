@@ -0,0 +1,213 @@
+package main
+
+//
+// Task requests
+//
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/ooni/probe-engine/pkg/engine"
+	"github.com/ooni/probe-engine/pkg/kvstore"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/oonirun"
+	"github.com/ooni/probe-engine/pkg/runevents"
+)
+
+// taskRequest is the JSON request to start a task. Exactly one of
+// the Experiment and OONIRunV2 fields MUST be set.
+type taskRequest struct {
+	// Annotations contains OPTIONAL annotations for the measurements.
+	Annotations map[string]string `json:"annotations"`
+
+	// Experiment describes the experiment to run.
+	Experiment *taskExperiment `json:"experiment"`
+
+	// MaxRuntime is the OPTIONAL maximum runtime in seconds.
+	MaxRuntime int64 `json:"max_runtime"`
+
+	// NoCollector OPTIONALLY disables submitting measurements.
+	NoCollector bool `json:"no_collector"`
+
+	// OONIRunV2 is the OONI Run v2 descriptor to run.
+	OONIRunV2 *oonirun.V2Descriptor `json:"oonirun_v2"`
+
+	// Random OPTIONALLY randomizes the inputs.
+	Random bool `json:"random"`
+
+	// Session is the MANDATORY session configuration.
+	Session taskSession `json:"session"`
+}
+
+// taskExperiment describes an experiment to run.
+type taskExperiment struct {
+	// Inputs contains OPTIONAL inputs for the experiment.
+	Inputs []string `json:"inputs"`
+
+	// Name is the MANDATORY experiment name.
+	Name string `json:"name"`
+
+	// Options contains OPTIONAL experiment options.
+	Options map[string]any `json:"options"`
+}
+
+// taskSession is the configuration of the session used by a task.
+type taskSession struct {
+	// ProbeServicesURL is the OPTIONAL probe services URL to use.
+	ProbeServicesURL string `json:"probe_services_url"`
+
+	// Proxy is the OPTIONAL proxy URL.
+	Proxy string `json:"proxy"`
+
+	// SnowflakeRendezvous is the OPTIONAL snowflake rendezvous method.
+	SnowflakeRendezvous string `json:"snowflake_rendezvous"`
+
+	// SoftwareName is the MANDATORY name of the application.
+	SoftwareName string `json:"software_name"`
+
+	// SoftwareVersion is the MANDATORY version of the application.
+	SoftwareVersion string `json:"software_version"`
+
+	// StateDir is the MANDATORY directory where to store persistent state.
+	StateDir string `json:"state_dir"`
+
+	// TempDir is the OPTIONAL directory where to create temporary files.
+	TempDir string `json:"temp_dir"`
+
+	// TorArgs contains OPTIONAL extra arguments for tor.
+	TorArgs []string `json:"tor_args"`
+
+	// TorBinary is the OPTIONAL path to the tor binary.
+	TorBinary string `json:"tor_binary"`
+
+	// Verbose OPTIONALLY enables emitting debug logs.
+	Verbose bool `json:"verbose"`
+}
+
+var (
+	// errInvalidRequest indicates that a task request is invalid.
+	errInvalidRequest = errors.New("libooniengine: invalid task request")
+
+	// errMissingStateDir indicates that the session state_dir is missing.
+	errMissingStateDir = errors.New("libooniengine: missing session state_dir")
+)
+
+// newTaskFunc parses the given JSON request and returns the function
+// implementing the corresponding task. We defer returning errors to
+// when the task runs, such that the caller sees them as events.
+func newTaskFunc(rawRequest []byte) func(ctx context.Context, t *task) error {
+	var req taskRequest
+	if err := json.Unmarshal(rawRequest, &req); err != nil {
+		return newFailingTaskFunc(err)
+	}
+	if (req.Experiment == nil) == (req.OONIRunV2 == nil) {
+		return newFailingTaskFunc(errInvalidRequest)
+	}
+	return func(ctx context.Context, t *task) error {
+		return runTask(ctx, t, &req)
+	}
+}
+
+// newFailingTaskFunc returns a task function immediately failing.
+func newFailingTaskFunc(err error) func(ctx context.Context, t *task) error {
+	return func(ctx context.Context, t *task) error {
+		return err
+	}
+}
+
+// runTask creates a session, bootstraps it, and runs the request.
+func runTask(ctx context.Context, t *task, req *taskRequest) error {
+	logger := newTaskLogger(t, req.Session.Verbose)
+	sess, err := newSession(ctx, &req.Session, logger, t)
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+	if err := sess.MaybeLookupBackendsContext(ctx); err != nil {
+		return err
+	}
+	if err := sess.MaybeLookupLocationContext(ctx); err != nil {
+		return err
+	}
+	if req.Experiment != nil {
+		exp := &oonirun.Experiment{
+			Annotations:      req.Annotations,
+			EmitMeasurements: true,
+			Events:           t,
+			ExtraOptions:     req.Experiment.Options,
+			Inputs:           req.Experiment.Inputs,
+			MaxRuntime:       req.MaxRuntime,
+			Name:             req.Experiment.Name,
+			NoCollector:      req.NoCollector,
+			NoJSON:           true,
+			Random:           req.Random,
+			Session:          sess,
+		}
+		return exp.Run(ctx)
+	}
+	config := &oonirun.LinkConfig{
+		Annotations:      req.Annotations,
+		EmitMeasurements: true,
+		Events:           t,
+		MaxRuntime:       req.MaxRuntime,
+		NoCollector:      req.NoCollector,
+		NoJSON:           true,
+		Random:           req.Random,
+		Session:          sess,
+	}
+	return oonirun.V2MeasureDescriptor(ctx, config, req.OONIRunV2)
+}
+
+// newSession creates a new session using the given config.
+func newSession(ctx context.Context, config *taskSession,
+	logger model.Logger, events runevents.Sink) (*engine.Session, error) {
+	if config.StateDir == "" {
+		return nil, errMissingStateDir
+	}
+	var proxyURL *url.URL
+	if config.Proxy != "" {
+		URL, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		proxyURL = URL
+	}
+	store, err := kvstore.NewFS(filepath.Join(config.StateDir, "engine"))
+	if err != nil {
+		return nil, err
+	}
+	tunnelDir := filepath.Join(config.StateDir, "tunnel")
+	if err := os.MkdirAll(tunnelDir, 0700); err != nil {
+		return nil, err
+	}
+	submitQueueDir := filepath.Join(config.StateDir, "submitqueue")
+	if err := os.MkdirAll(submitQueueDir, 0700); err != nil {
+		return nil, err
+	}
+	sessionConfig := engine.SessionConfig{
+		Events:              events,
+		KVStore:             store,
+		Logger:              logger,
+		ProxyURL:            proxyURL,
+		SnowflakeRendezvous: config.SnowflakeRendezvous,
+		SoftwareName:        config.SoftwareName,
+		SoftwareVersion:     config.SoftwareVersion,
+		SubmitQueueDir:      submitQueueDir,
+		TempDir:             config.TempDir,
+		TorArgs:             config.TorArgs,
+		TorBinary:           config.TorBinary,
+		TunnelDir:           tunnelDir,
+	}
+	if config.ProbeServicesURL != "" {
+		sessionConfig.AvailableProbeServices = []model.OOAPIService{{
+			Address: config.ProbeServicesURL,
+			Type:    "https",
+		}}
+	}
+	return engine.NewSession(ctx, sessionConfig)
+}
@@ -0,0 +1,189 @@
+package main
+
+//
+// Tasks
+//
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ooni/probe-engine/pkg/runevents"
+)
+
+// taskDone is the last event emitted by a task.
+type taskDone struct {
+	// Failure is the failure that occurred, if any.
+	Failure *string `json:"failure"`
+}
+
+// EventType implements runevents.Data.
+func (*taskDone) EventType() string {
+	return "task_done"
+}
+
+// task is a background task. A task runs in its own goroutine and we
+// retrieve its events using [*task.waitForNextEvent].
+type task struct {
+	// cancel interrupts the task.
+	cancel context.CancelFunc
+
+	// done is closed when the task goroutine has terminated.
+	done chan struct{}
+
+	// mu provides mutual exclusion for queue.
+	mu sync.Mutex
+
+	// notify is signalled when we append to the queue.
+	notify chan struct{}
+
+	// queue contains the serialized events not yet retrieved.
+	queue [][]byte
+}
+
+var _ runevents.Sink = &task{}
+
+// startTask starts running the given function in the background. The
+// function receives the task, which is also the sink for events. When
+// the function returns, we emit the final task_done event. If the function
+// panics, we recover and emit a failed task_done event, such that a bug in
+// a task does not crash the application embedding the engine.
+func startTask(fn func(ctx context.Context, t *task) error) *task {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &task{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		notify: make(chan struct{}, 1),
+	}
+	go func() {
+		defer close(t.done)
+		defer cancel()
+		err := runTaskFunc(ctx, t, fn)
+		runevents.Emit(t, &taskDone{Failure: runevents.NewFailure(err)})
+	}()
+	return t
+}
+
+// errTaskPanic indicates that a task function panicked.
+var errTaskPanic = errors.New("task panicked")
+
+// runTaskFunc calls fn and converts a panic into an error.
+func runTaskFunc(ctx context.Context, t *task, fn func(ctx context.Context, t *task) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", errTaskPanic, r)
+		}
+	}()
+	return fn(ctx, t)
+}
+
+// Emit implements runevents.Sink.
+func (t *task) Emit(ev *runevents.Event) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return // should not happen with the data types we define
+	}
+	t.mu.Lock()
+	t.queue = append(t.queue, data)
+	t.mu.Unlock()
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+}
+
+// waitForNextEvent returns the next serialized event or nil if the
+// timeout expires or the task is done and there are no more events.
+func (t *task) waitForNextEvent(timeout time.Duration) []byte {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		if data := t.dequeue(); data != nil {
+			return data
+		}
+		select {
+		case <-t.notify:
+		case <-t.done:
+			// the task emits its last event before terminating
+			return t.dequeue()
+		case <-timer.C:
+			return nil
+		}
+	}
+}
+
+// dequeue returns the first event inside the queue or nil.
+func (t *task) dequeue() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.queue) <= 0 {
+		return nil
+	}
+	data := t.queue[0]
+	t.queue = t.queue[1:]
+	return data
+}
+
+// isDone returns whether the task is done and we retrieved all its events.
+func (t *task) isDone() bool {
+	select {
+	case <-t.done:
+	default:
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.queue) <= 0
+}
+
+// interrupt interrupts the task.
+func (t *task) interrupt() {
+	t.cancel()
+}
+
+// taskRegistry maps handles we give to C code to tasks.
+type taskRegistry struct {
+	// mu provides mutual exclusion for tasks.
+	mu sync.Mutex
+
+	// next is the next handle to assign.
+	next atomic.Int64
+
+	// tasks maps handles to tasks.
+	tasks map[int64]*task
+}
+
+// tasks is the global tasks registry.
+var tasks = &taskRegistry{tasks: make(map[int64]*task)}
+
+// add adds a task to the registry and returns its handle, which
+// is always positive because zero means there's no task.
+func (r *taskRegistry) add(t *task) int64 {
+	handle := r.next.Add(1)
+	r.mu.Lock()
+	r.tasks[handle] = t
+	r.mu.Unlock()
+	return handle
+}
+
+// get returns the task associated to the handle or nil.
+func (r *taskRegistry) get(handle int64) *task {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tasks[handle]
+}
+
+// remove removes the task associated to the handle from the registry
+// and returns it or returns nil if there is no such task.
+func (r *taskRegistry) remove(handle int64) *task {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.tasks[handle]
+	delete(r.tasks, handle)
+	return t
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// taskEvent is the deserialized version of an event.
+type taskEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// waitForAllEvents returns all the events emitted by a task.
+func waitForAllEvents(t *testing.T, tk *task) (out []taskEvent) {
+	for !tk.isDone() {
+		data := tk.waitForNextEvent(time.Second)
+		if data == nil {
+			continue
+		}
+		var ev taskEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, ev)
+	}
+	return
+}
+
+// taskFailure returns the failure of a task_done event.
+func taskFailure(t *testing.T, ev taskEvent) *string {
+	if ev.Type != "task_done" {
+		t.Fatal("expected task_done, got", ev.Type)
+	}
+	var done taskDone
+	if err := json.Unmarshal(ev.Data, &done); err != nil {
+		t.Fatal(err)
+	}
+	return done.Failure
+}
+
+func TestTask(t *testing.T) {
+	t.Run("we receive the events and then task_done", func(t *testing.T) {
+		tk := startTask(func(ctx context.Context, tk *task) error {
+			newTaskLogger(tk, false).Info("hello")
+			return errors.New("mocked error")
+		})
+		events := waitForAllEvents(t, tk)
+		if len(events) != 2 {
+			t.Fatal("expected two events, got", len(events))
+		}
+		if events[0].Type != "log" {
+			t.Fatal("unexpected event type", events[0].Type)
+		}
+		if failure := taskFailure(t, events[1]); failure == nil || *failure != "mocked error" {
+			t.Fatal("unexpected failure", failure)
+		}
+		if data := tk.waitForNextEvent(time.Millisecond); data != nil {
+			t.Fatal("expected no more events")
+		}
+	})
+
+	t.Run("waitForNextEvent honours the timeout", func(t *testing.T) {
+		tk := startTask(func(ctx context.Context, tk *task) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if data := tk.waitForNextEvent(10 * time.Millisecond); data != nil {
+			t.Fatal("expected nil data")
+		}
+		if tk.isDone() {
+			t.Fatal("expected the task to be running")
+		}
+		tk.interrupt()
+		events := waitForAllEvents(t, tk)
+		if len(events) != 1 {
+			t.Fatal("expected one event, got", len(events))
+		}
+		if failure := taskFailure(t, events[0]); failure == nil || *failure != context.Canceled.Error() {
+			t.Fatal("unexpected failure", failure)
+		}
+	})
+
+	t.Run("we emit a failed task_done when the task panics", func(t *testing.T) {
+		tk := startTask(func(ctx context.Context, tk *task) error {
+			panic("mocked panic")
+		})
+		events := waitForAllEvents(t, tk)
+		if len(events) != 1 {
+			t.Fatal("expected one event, got", len(events))
+		}
+		expect := errTaskPanic.Error() + ": mocked panic"
+		if failure := taskFailure(t, events[0]); failure == nil || *failure != expect {
+			t.Fatal("unexpected failure", failure)
+		}
+	})
+}
+
+func TestTaskRegistry(t *testing.T) {
+	r := &taskRegistry{tasks: make(map[int64]*task)}
+	tk := &task{}
+	handle := r.add(tk)
+	if handle <= 0 {
+		t.Fatal("expected positive handle")
+	}
+	if r.get(handle) != tk {
+		t.Fatal("get returned the wrong task")
+	}
+	if r.remove(handle) != tk {
+		t.Fatal("remove returned the wrong task")
+	}
+	if r.get(handle) != nil || r.remove(handle) != nil {
+		t.Fatal("expected the task to have been removed")
+	}
+}
+
+func TestNewTaskFunc(t *testing.T) {
+	run := func(t *testing.T, request string) []taskEvent {
+		return waitForAllEvents(t, startTask(newTaskFunc([]byte(request))))
+	}
+
+	t.Run("with invalid JSON", func(t *testing.T) {
+		events := run(t, "{")
+		if failure := taskFailure(t, events[len(events)-1]); failure == nil {
+			t.Fatal("expected a failure")
+		}
+	})
+
+	t.Run("without experiment and descriptor", func(t *testing.T) {
+		events := run(t, `{}`)
+		failure := taskFailure(t, events[len(events)-1])
+		if failure == nil || *failure != errInvalidRequest.Error() {
+			t.Fatal("unexpected failure", failure)
+		}
+	})
+
+	t.Run("without state_dir", func(t *testing.T) {
+		events := run(t, `{"experiment":{"name":"example"}}`)
+		failure := taskFailure(t, events[len(events)-1])
+		if failure == nil || *failure != errMissingStateDir.Error() {
+			t.Fatal("unexpected failure", failure)
+		}
+	})
+
+	t.Run("we can interrupt a task", func(t *testing.T) {
+		request := map[string]any{
+			"no_collector": true,
+			"session": map[string]any{
+				"software_name":    "miniooni",
+				"software_version": "0.1.0-dev",
+				"state_dir":        t.TempDir(),
+			},
+			"experiment": map[string]any{
+				"name": "example",
+			},
+		}
+		data, err := json.Marshal(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk := startTask(newTaskFunc(data))
+		tk.interrupt()
+		events := waitForAllEvents(t, tk)
+		if failure := taskFailure(t, events[len(events)-1]); failure == nil {
+			t.Fatal("expected a failure")
+		}
+	})
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+//
+// Test harness for the C API. Usage: harness <state_dir>
+//
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "engine.h"
+
+// check exits with failure if cond is false.
+#define check(cond)                                                            \
+  do {                                                                         \
+    if (!(cond)) {                                                             \
+      fprintf(stderr, "harness: %s:%d: check failed: %s\n", __FILE__,         \
+              __LINE__, #cond);                                                \
+      exit(EXIT_FAILURE);                                                      \
+    }                                                                          \
+  } while (0)
+
+// drain reads all the events emitted by task and returns the last one.
+static char *drain(OONITask task) {
+  char *last = NULL;
+  while (!OONITaskIsDone(task)) {
+    char *ev = OONITaskWaitForNextEvent(task, 1000);
+    if (ev == NULL) {
+      continue;
+    }
+    printf("%s\n", ev);
+    OONIEngineFreeMemory(last);
+    last = ev;
+  }
+  return last;
+}
+
+int main(int argc, char **argv) {
+  check(argc == 2);
+
+  // we can get the version
+  char *version = OONIEngineVersion();
+  check(version != NULL && strlen(version) > 0);
+  OONIEngineFreeMemory(version);
+
+  // a NULL request is not a valid task
+  check(OONITaskStart(NULL) == 0);
+
+  // invalid handles are done and don't have events
+  check(OONITaskIsDone(1 << 30) == 1);
+  check(OONITaskWaitForNextEvent(1 << 30, 1) == NULL);
+
+  // an invalid request fails with task_done
+  OONITask task = OONITaskStart("{}");
+  check(task != 0);
+  char *last = drain(task);
+  check(last != NULL);
+  check(strstr(last, "\"task_done\"") != NULL);
+  check(strstr(last, "invalid task request") != NULL);
+  OONIEngineFreeMemory(last);
+  OONITaskFree(task);
+
+  // we can interrupt a running task
+  char request[4096];
+  snprintf(request, sizeof(request),
+           "{\"no_collector\":true,"
+           "\"session\":{\"software_name\":\"miniooni\","
+           "\"software_version\":\"0.1.0-dev\",\"state_dir\":\"%s\"},"
+           "\"experiment\":{\"name\":\"example\"}}",
+           argv[1]);
+  task = OONITaskStart(request);
+  check(task != 0);
+  OONITaskInterrupt(task);
+  last = drain(task);
+  check(last != NULL);
+  check(strstr(last, "\"task_done\"") != NULL);
+  check(strstr(last, "\"failure\":null") == NULL);
+  OONIEngineFreeMemory(last);
+  OONITaskFree(task);
+
+  // we can free a task without reading its events
+  task = OONITaskStart(request);
+  check(task != 0);
+  OONITaskFree(task);
+  check(OONITaskIsDone(task) == 1);
+
+  return EXIT_SUCCESS;
+}
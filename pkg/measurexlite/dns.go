@@ -108,6 +108,21 @@ func (tx *Trace) NewParallelDNSOverHTTPSResolver(logger model.DebugLogger, URL s
 	return tx.wrapResolver(tx.Netx.NewParallelDNSOverHTTPSResolver(logger, URL))
 }
 
+// NewParallelDNSOverQUICResolver returns a trace-aware parallel DoQ resolver
+func (tx *Trace) NewParallelDNSOverQUICResolver(logger model.DebugLogger, dialer model.QUICDialer, address string) model.Resolver {
+	return tx.wrapResolver(tx.Netx.NewParallelDNSOverQUICResolver(logger, dialer, address))
+}
+
+// NewParallelDNSOverTCPResolver returns a trace-aware parallel DNS-over-TCP resolver
+func (tx *Trace) NewParallelDNSOverTCPResolver(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
+	return tx.wrapResolver(tx.Netx.NewParallelDNSOverTCPResolver(logger, dialer, address))
+}
+
+// NewParallelDNSOverTLSResolver returns a trace-aware parallel DoT resolver
+func (tx *Trace) NewParallelDNSOverTLSResolver(logger model.DebugLogger, dialer model.TLSDialer, address string) model.Resolver {
+	return tx.wrapResolver(tx.Netx.NewParallelDNSOverTLSResolver(logger, dialer, address))
+}
+
 // OnDNSRoundTripForLookupHost implements model.Trace.OnDNSRoundTripForLookupHost
 func (tx *Trace) OnDNSRoundTripForLookupHost(started time.Time, reso model.Resolver, query model.DNSQuery,
 	response model.DNSResponse, addrs []string, err error, finished time.Time) {
@@ -325,6 +325,51 @@ func TestNewWrappedResolvers(t *testing.T) {
 		}
 	})
 
+	t.Run("NewParallelDNSOverQUICResolver works as intended", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		dialer := trace.NewQUICDialerWithoutResolver(trace.NewUDPListener(), model.DiscardLogger)
+		resolver := trace.NewParallelDNSOverQUICResolver(model.DiscardLogger, dialer, "94.140.14.14:853")
+		resolvert := resolver.(*resolverTrace)
+		if resolvert.tx != trace {
+			t.Fatal("invalid trace")
+		}
+		if resolver.Network() != "doq" {
+			t.Fatal("unexpected resolver network")
+		}
+	})
+
+	t.Run("NewParallelDNSOverTCPResolver works as intended", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		dialer := trace.NewDialerWithoutResolver(model.DiscardLogger)
+		resolver := trace.NewParallelDNSOverTCPResolver(model.DiscardLogger, dialer, "8.8.8.8:53")
+		resolvert := resolver.(*resolverTrace)
+		if resolvert.tx != trace {
+			t.Fatal("invalid trace")
+		}
+		if resolver.Network() != "tcp" {
+			t.Fatal("unexpected resolver network")
+		}
+	})
+
+	t.Run("NewParallelDNSOverTLSResolver works as intended", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		dialer := netxlite.NewTLSDialer(
+			trace.NewDialerWithoutResolver(model.DiscardLogger),
+			trace.NewTLSHandshakerStdlib(model.DiscardLogger),
+		)
+		resolver := trace.NewParallelDNSOverTLSResolver(model.DiscardLogger, dialer, "8.8.8.8:853")
+		resolvert := resolver.(*resolverTrace)
+		if resolvert.tx != trace {
+			t.Fatal("invalid trace")
+		}
+		if resolver.Network() != "dot" {
+			t.Fatal("unexpected resolver network")
+		}
+	})
+
 	t.Run("NewStdlibResolver works as intended", func(t *testing.T) {
 		zeroTime := time.Now()
 		trace := NewTrace(0, zeroTime)
@@ -8,6 +8,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"errors"
 	"net"
 	"time"
@@ -100,6 +101,7 @@ func NewArchivalTLSOrQUICHandshakeResult(
 	index int64, started time.Duration, network string, address string, config *tls.Config,
 	state tls.ConnectionState, err error, finished time.Duration,
 	tags ...string) *model.ArchivalTLSOrQUICHandshakeResult {
+	echConfig, echAccepted := tlsECHInfo(config, state)
 	return &model.ArchivalTLSOrQUICHandshakeResult{
 		Network:            network,
 		Address:            address,
@@ -109,6 +111,8 @@ func NewArchivalTLSOrQUICHandshakeResult(
 		NoTLSVerify:        config.InsecureSkipVerify,
 		PeerCertificates:   TLSPeerCerts(state, err),
 		ServerName:         config.ServerName,
+		ECHConfig:          echConfig,
+		ECHAccepted:        echAccepted,
 		T0:                 started.Seconds(),
 		T:                  finished.Seconds(),
 		Tags:               copyAndNormalizeTags(tags),
@@ -117,6 +121,17 @@ func NewArchivalTLSOrQUICHandshakeResult(
 	}
 }
 
+// tlsECHInfo returns the base64-encoded ECH config list and whether the server
+// accepted ECH. When the config does not use ECH, this function returns an
+// empty string and nil, so the related archival fields are omitted.
+func tlsECHInfo(config *tls.Config, state tls.ConnectionState) (string, *bool) {
+	if len(config.EncryptedClientHelloConfigList) <= 0 {
+		return "", nil
+	}
+	accepted := state.ECHAccepted
+	return base64.StdEncoding.EncodeToString(config.EncryptedClientHelloConfigList), &accepted
+}
+
 // TLSPeerCerts extracts the certificates either from the list of certificates
 // in the connection state or from the error that occurred.
 func TLSPeerCerts(
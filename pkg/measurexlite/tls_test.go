@@ -6,6 +6,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
+	"fmt"
 	"net"
 	"testing"
 	"time"
@@ -467,3 +468,35 @@ func TestTLSPeerCerts(t *testing.T) {
 		})
 	}
 }
+
+func TestNewArchivalTLSOrQUICHandshakeResultWithECH(t *testing.T) {
+	t.Run("without ECH", func(t *testing.T) {
+		config := &tls.Config{ServerName: "www.example.com"}
+		result := NewArchivalTLSOrQUICHandshakeResult(
+			1, 0, "tcp", "93.184.216.34:443", config, tls.ConnectionState{}, nil, time.Second)
+		if result.ECHConfig != "" {
+			t.Fatal("unexpected ECHConfig", result.ECHConfig)
+		}
+		if result.ECHAccepted != nil {
+			t.Fatal("unexpected ECHAccepted", *result.ECHAccepted)
+		}
+	})
+
+	for _, accepted := range []bool{false, true} {
+		t.Run(fmt.Sprintf("with ECH and accepted=%v", accepted), func(t *testing.T) {
+			config := &tls.Config{
+				EncryptedClientHelloConfigList: []byte{0x00, 0x45, 0xfe, 0x0d},
+				ServerName:                     "www.example.com",
+			}
+			state := tls.ConnectionState{ECHAccepted: accepted}
+			result := NewArchivalTLSOrQUICHandshakeResult(
+				1, 0, "tcp", "93.184.216.34:443", config, state, nil, time.Second)
+			if result.ECHConfig != "AEX+DQ==" {
+				t.Fatal("unexpected ECHConfig", result.ECHConfig)
+			}
+			if result.ECHAccepted == nil || *result.ECHAccepted != accepted {
+				t.Fatal("unexpected ECHAccepted", result.ECHAccepted)
+			}
+		})
+	}
+}
@@ -80,6 +80,17 @@ func NewTimer(c Config) (*time.Timer, error) {
 	return newTimer(c), nil
 }
 
+// Duration returns a single random wait time distributed according to the
+// config. This is useful when you cannot use a timer directly, e.g., because
+// you need to persist on disk the time of the next run.
+func Duration(c Config) (time.Duration, error) {
+	if err := c.Check(); err != nil {
+		return 0, err
+	}
+
+	return c.waittime(), nil
+}
+
 // AfterFunc constructs a single-shot time.Timer that, if repeatedly used to
 // construct a series of timers, will ensure that the resulting events conform
 // to the memoryless distribution. For more on how this could and should be
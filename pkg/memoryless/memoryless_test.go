@@ -154,3 +154,26 @@ func TestAfterFunc(t *testing.T) {
 		t.Error("It should be:", start, "<=", funcTime, "<=", end)
 	}
 }
+
+func TestDuration(t *testing.T) {
+	t.Run("with a valid config", func(t *testing.T) {
+		config := memoryless.Config{Expected: time.Second, Min: time.Second / 2, Max: 2 * time.Second}
+		for i := 0; i < 1000; i++ {
+			d, err := memoryless.Duration(config)
+			runtimex.PanicOnError(err, "Could not compute duration")
+			if d < config.Min || d > config.Max {
+				t.Error("It should be:", config.Min, "<=", d, "<=", config.Max)
+			}
+		}
+	})
+
+	t.Run("with an invalid config", func(t *testing.T) {
+		d, err := memoryless.Duration(memoryless.Config{Expected: time.Second, Min: 2 * time.Second})
+		if err == nil {
+			t.Error("Should have returned an error")
+		}
+		if d != 0 {
+			t.Error("Should have returned a zero duration")
+		}
+	})
+}
@@ -20,6 +20,9 @@ import (
 //
 // 3. with [WebObservationType] being equal, by ascending failure string;
 //
+// Note that we do not include the KnownQUICEndpoints in the returned list, since
+// the HTTP/3 fetches are not part of the redirect chain.
+//
 // This means that you divide the list in groups like this:
 //
 //	+------------+------------+------------+------------+
@@ -108,11 +111,16 @@ func AnalyzeWebObservationsWithoutLinearAnalysis(
 	analysis.dnsComputeSuccessMetrics(lookupper, container)
 	analysis.dnsComputeSuccessMetricsClassic(lookupper, container)
 	analysis.dnsComputeFailureMetrics(container)
+	analysis.dnsComputeResolverMetrics(container)
 
 	analysis.tcpComputeMetrics(container)
 	analysis.tlsComputeMetrics(container)
 	analysis.httpComputeFailureMetrics(container)
 	analysis.httpComputeFinalResponseMetrics(container)
+	analysis.httpComputeExtraFetchMetrics(container)
+	analysis.quicComputeMetrics(container)
+	analysis.echComputeMetrics(container)
+	analysis.throttlingComputeMetrics(container)
 
 	return analysis
 }
@@ -174,6 +182,21 @@ type WebAnalysis struct {
 	// DNSLookupExpectedSuccess contains DNS transactions with expected successes.
 	DNSLookupExpectedSuccess Set[int64]
 
+	// DNSResolverWithInvalidAddresses contains the URLs of the resolvers (see
+	// [WebObservation.DNSResolver]) performing DNSLookupSuccessWithInvalidAddresses lookups.
+	//
+	// Comparing this field with DNSResolverWithValidAddress allows to distinguish
+	// between resolvers returning invalid addresses and on-path DNS injection.
+	DNSResolverWithInvalidAddresses Set[string]
+
+	// DNSResolverWithValidAddress contains the URLs of the resolvers
+	// performing DNSLookupSuccessWithValidAddress lookups.
+	DNSResolverWithValidAddress Set[string]
+
+	// DNSResolverWithUnexpectedFailure contains the URLs of the resolvers
+	// performing DNSLookupUnexpectedFailure lookups.
+	DNSResolverWithUnexpectedFailure Set[string]
+
 	// TCPConnectExpectedFailure contains TCP connect transactions that failed
 	// consistently for the probe and the test helper.
 	TCPConnectExpectedFailure Set[int64]
@@ -208,6 +231,11 @@ type WebAnalysis struct {
 	// TLSHandshakeUnexpectedFailure contains TLS endpoint transactions with unexpected failures.
 	TLSHandshakeUnexpectedFailure Set[int64]
 
+	// TLSHandshakeUnexpectedCertificate contains TLS endpoint transactions with unexpected
+	// failures where the probe failed to validate certificates that the control did not
+	// receive, which typically indicates that someone is intercepting the TLS traffic.
+	TLSHandshakeUnexpectedCertificate Set[int64]
+
 	// TLSHandshakeUnexpectedFailureDuringWebFetch contains TLS endpoint transactions with unexpected failures.
 	// while performing a web fetch, as opposed to checking for connectivity.
 	TLSHandshakeUnexpectedFailureDuringWebFetch Set[int64]
@@ -235,6 +263,63 @@ type WebAnalysis struct {
 	// failures for which there's no corresponding control info.
 	HTTPRoundTripUnexplainedFailure Set[int64]
 
+	// HTTPExtraFetchFailure contains the HTTP endpoint transactions fetching the webpage
+	// from an address other than the highest priority one that failed.
+	HTTPExtraFetchFailure Set[int64]
+
+	// HTTPExtraFetchBodyDivergence contains the HTTP endpoint transactions fetching the
+	// webpage from an address other than the highest priority one whose status code or body
+	// length significantly differ from the response fetched from the highest priority address.
+	HTTPExtraFetchBodyDivergence Set[int64]
+
+	// HTTPExtraFetchTitleDivergence contains the HTTP endpoint transactions fetching the
+	// webpage from an address other than the highest priority one whose title differs from
+	// the title of the response fetched from the highest priority address.
+	HTTPExtraFetchTitleDivergence Set[int64]
+
+	// QUICHandshakeExpectedFailure contains QUIC endpoint transactions where both the
+	// probe and the control failed to perform the QUIC handshake.
+	QUICHandshakeExpectedFailure Set[int64]
+
+	// QUICHandshakeUnexpectedFailure contains QUIC endpoint transactions where the
+	// probe failed to perform the QUIC handshake while the control succeeded.
+	QUICHandshakeUnexpectedFailure Set[int64]
+
+	// QUICHandshakeUnexplainedFailure contains QUIC endpoint transactions where the
+	// probe failed to perform the QUIC handshake and there's no control information.
+	QUICHandshakeUnexplainedFailure Set[int64]
+
+	// HTTP3RoundTripUnexpectedFailure contains QUIC endpoint transactions where the
+	// HTTP/3 round trip failed while the control's HTTP/3 round trip succeeded.
+	HTTP3RoundTripUnexpectedFailure Set[int64]
+
+	// HTTP3ResponseDiffersFromControl contains QUIC endpoint transactions whose status
+	// code or body length significantly differ from the control's HTTP/3 response.
+	HTTP3ResponseDiffersFromControl Set[int64]
+
+	// TLSHandshakeECHSuccess contains TLS endpoint transactions where the probe
+	// successfully performed a TLS handshake using Encrypted Client Hello.
+	TLSHandshakeECHSuccess Set[int64]
+
+	// TLSHandshakeECHUnexpectedFailure contains TLS endpoint transactions where the
+	// TLS handshake using Encrypted Client Hello failed while a TLS handshake not
+	// using it with the same endpoint and server name succeeded.
+	TLSHandshakeECHUnexpectedFailure Set[int64]
+
+	// HTTPThrottlingSpeedCollapse contains TCP endpoint transactions fetching the webpage
+	// where the goodput collapsed after receiving some bytes (e.g., after the TLS handshake).
+	HTTPThrottlingSpeedCollapse Set[int64]
+
+	// HTTPThrottlingSlowDownload contains TCP endpoint transactions fetching the webpage
+	// where the HTTP round trip failed while the download was making slow progress and
+	// had not received yet as many bytes as the control's body length.
+	HTTPThrottlingSlowDownload Set[int64]
+
+	// Throttled is true when HTTPThrottlingSpeedCollapse or HTTPThrottlingSlowDownload are
+	// not empty, false when they are empty, and none when we could not compute the
+	// goodput curve of any TCP endpoint transaction fetching the webpage.
+	Throttled optional.Value[bool]
+
 	// HTTPFinalResponseSuccessTLSWithoutControl contains the ID of the final response
 	// transaction when the final response succeeded without control and with TLS.
 	HTTPFinalResponseSuccessTLSWithoutControl optional.Value[int64]
@@ -473,6 +558,31 @@ func (wa *WebAnalysis) dnsComputeFailureMetrics(c *WebObservationsContainer) {
 	}
 }
 
+func (wa *WebAnalysis) dnsComputeResolverMetrics(c *WebObservationsContainer) {
+	var observations []*WebObservation
+	observations = append(observations, c.DNSLookupSuccesses...)
+	observations = append(observations, c.DNSLookupFailures...)
+
+	for _, obs := range observations {
+		// we cannot attribute lookups that were not tagged with the resolver
+		resolver := obs.DNSResolver.UnwrapOr("")
+		if resolver == "" {
+			continue
+		}
+
+		// note: by construction these sets are disjoint
+		txid := obs.DNSTransactionID.Unwrap()
+		switch {
+		case wa.DNSLookupSuccessWithInvalidAddresses.Contains(txid):
+			wa.DNSResolverWithInvalidAddresses.Add(resolver)
+		case wa.DNSLookupSuccessWithValidAddress.Contains(txid):
+			wa.DNSResolverWithValidAddress.Add(resolver)
+		case wa.DNSLookupUnexpectedFailure.Contains(txid):
+			wa.DNSResolverWithUnexpectedFailure.Add(resolver)
+		}
+	}
+}
+
 func (wa *WebAnalysis) tcpComputeMetrics(c *WebObservationsContainer) {
 	for _, obs := range c.KnownTCPEndpoints {
 		// handle the case where there is no measurement
@@ -587,11 +697,37 @@ func (wa *WebAnalysis) tlsComputeMetrics(c *WebObservationsContainer) {
 				wa.TLSHandshakeUnexpectedFailureDuringConnectivityCheck.Add(obs.EndpointTransactionID.Unwrap())
 			}
 			wa.TLSHandshakeUnexpectedFailure.Add(obs.EndpointTransactionID.Unwrap())
+			if analysisTLSCertificateIsUnexpected(obs) {
+				wa.TLSHandshakeUnexpectedCertificate.Add(obs.EndpointTransactionID.Unwrap())
+			}
 			continue
 		}
 	}
 }
 
+// analysisTLSCertificateIsUnexpected returns true when the probe failed to validate
+// a certificate that is not part of the chain received by the control.
+func analysisTLSCertificateIsUnexpected(obs *WebObservation) bool {
+	switch obs.TLSHandshakeFailure.UnwrapOr("") {
+	case netxlite.FailureSSLInvalidHostname,
+		netxlite.FailureSSLUnknownAuthority,
+		netxlite.FailureSSLInvalidCertificate:
+	default:
+		return false
+	}
+	probe := obs.TLSCertificateFingerprints.UnwrapOr(nil)
+	control := NewSet(obs.ControlTLSCertificateFingerprints.UnwrapOr(nil)...)
+	if len(probe) <= 0 || control.Len() <= 0 {
+		return false
+	}
+	for _, fingerprint := range probe {
+		if control.Contains(fingerprint) {
+			return false
+		}
+	}
+	return true
+}
+
 func (wa *WebAnalysis) httpComputeFailureMetrics(c *WebObservationsContainer) {
 	for _, obs := range c.KnownTCPEndpoints {
 		// Implementation note: here we don't limit the search to depth==0 because the
@@ -602,6 +738,11 @@ func (wa *WebAnalysis) httpComputeFailureMetrics(c *WebObservationsContainer) {
 			continue
 		}
 
+		// skip extra fetches, which httpComputeExtraFetchMetrics handles
+		if obs.TagExtraFetch.UnwrapOr(false) {
+			continue
+		}
+
 		// handle the case where there is no control information
 		if obs.ControlHTTPFailure.IsNone() {
 			if obs.HTTPFailure.Unwrap() != "" {
@@ -636,6 +777,11 @@ func (wa *WebAnalysis) httpComputeFinalResponseMetrics(c *WebObservationsContain
 			continue
 		}
 
+		// skip extra fetches, which httpComputeExtraFetchMetrics handles
+		if obs.TagExtraFetch.UnwrapOr(false) {
+			continue
+		}
+
 		// stop after processing the first final response (there's at most
 		// one when we're analyzing LTE results)
 		wa.httpHandleFinalResponse(obs)
@@ -643,6 +789,218 @@ func (wa *WebAnalysis) httpComputeFinalResponseMetrics(c *WebObservationsContain
 	}
 }
 
+// analysisExtraFetchBodyProportionFactor is the minimum body proportion factor between
+// an extra fetch and the reference fetch above which we consider the bodies similar.
+const analysisExtraFetchBodyProportionFactor = 0.7
+
+func (wa *WebAnalysis) httpComputeExtraFetchMetrics(c *WebObservationsContainer) {
+	// build the reference responses, i.e., the successful responses fetched
+	// from the highest priority address, indexed by request URL (there's at
+	// most one for each URL when we're analyzing LTE results)
+	references := map[string]*WebObservation{}
+	for _, obs := range c.KnownTCPEndpoints {
+		if obs.HTTPFailure.IsNone() || obs.HTTPFailure.Unwrap() != "" {
+			continue
+		}
+		if obs.TagExtraFetch.UnwrapOr(false) {
+			continue
+		}
+		references[obs.HTTPRequestURL.UnwrapOr("")] = obs
+	}
+
+	for _, obs := range c.KnownTCPEndpoints {
+		// we only care about extra fetches here
+		if obs.HTTPFailure.IsNone() || !obs.TagExtraFetch.UnwrapOr(false) {
+			continue
+		}
+		txid := obs.EndpointTransactionID.Unwrap()
+
+		// handle the case where the extra fetch failed
+		if obs.HTTPFailure.Unwrap() != "" {
+			wa.HTTPExtraFetchFailure.Add(txid)
+			continue
+		}
+
+		// we cannot say anything without a reference response
+		ref, found := references[obs.HTTPRequestURL.UnwrapOr("")]
+		if !found {
+			continue
+		}
+
+		// compare the status code and, if possible, the body length
+		if analysisExtraFetchBodyDiverges(obs, ref) {
+			wa.HTTPExtraFetchBodyDivergence.Add(txid)
+		}
+
+		// compare the title
+		measurement := obs.HTTPResponseTitle.UnwrapOr("")
+		reference := ref.HTTPResponseTitle.UnwrapOr("")
+		if len(ComputeHTTPDiffTitleDifferentLongWords(measurement, reference)) > 0 {
+			wa.HTTPExtraFetchTitleDivergence.Add(txid)
+		}
+	}
+}
+
+// analysisExtraFetchBodyDiverges returns whether the response of an extra fetch significantly
+// differs from the reference response in terms of status code or body length. We only
+// compare the body length when neither body is empty or truncated.
+func analysisExtraFetchBodyDiverges(obs, ref *WebObservation) bool {
+	if obs.HTTPResponseStatusCode.UnwrapOr(0) != ref.HTTPResponseStatusCode.UnwrapOr(0) {
+		return true
+	}
+	measurement := obs.HTTPResponseBodyLength.UnwrapOr(0)
+	reference := ref.HTTPResponseBodyLength.UnwrapOr(0)
+	if measurement <= 0 || reference <= 0 {
+		return false
+	}
+	if obs.HTTPResponseBodyIsTruncated.UnwrapOr(true) || ref.HTTPResponseBodyIsTruncated.UnwrapOr(true) {
+		return false
+	}
+	proportion := ComputeHTTPDiffBodyProportionFactor(measurement, reference)
+	return proportion <= analysisExtraFetchBodyProportionFactor
+}
+
+// analysisHTTP3BodyProportionFactor is the minimum body proportion factor between an
+// HTTP/3 response and the control's HTTP/3 response above which we consider the bodies similar.
+const analysisHTTP3BodyProportionFactor = 0.7
+
+func (wa *WebAnalysis) quicComputeMetrics(c *WebObservationsContainer) {
+	for _, obs := range c.KnownQUICEndpoints {
+		// handle the case where there is no measurement
+		if obs.QUICHandshakeFailure.IsNone() {
+			continue
+		}
+		txid := obs.EndpointTransactionID.Unwrap()
+
+		// handle the case where the QUIC handshake failed
+		if obs.QUICHandshakeFailure.Unwrap() != "" {
+			switch {
+			case obs.ControlQUICHandshakeFailure.IsNone():
+				wa.QUICHandshakeUnexplainedFailure.Add(txid)
+			case obs.ControlQUICHandshakeFailure.Unwrap() != "":
+				wa.QUICHandshakeExpectedFailure.Add(txid)
+			default:
+				wa.QUICHandshakeUnexpectedFailure.Add(txid)
+			}
+			continue
+		}
+
+		// handle the case where there is no HTTP/3 round trip or no control for it
+		if obs.HTTPFailure.IsNone() || obs.ControlHTTPFailure.IsNone() {
+			continue
+		}
+
+		// handle the case where the control failed
+		if obs.ControlHTTPFailure.Unwrap() != "" {
+			continue
+		}
+
+		// handle the case where only the probe failed
+		if obs.HTTPFailure.Unwrap() != "" {
+			wa.HTTP3RoundTripUnexpectedFailure.Add(txid)
+			continue
+		}
+
+		// compare the response with the control's response
+		if analysisHTTP3ResponseDiverges(obs) {
+			wa.HTTP3ResponseDiffersFromControl.Add(txid)
+		}
+	}
+}
+
+func (wa *WebAnalysis) echComputeMetrics(c *WebObservationsContainer) {
+	for _, obs := range c.KnownTCPEndpoints {
+		// handle the case where this is not a TLS handshake using ECH
+		if obs.TLSECHAccepted.IsNone() || obs.TLSHandshakeFailure.IsNone() {
+			continue
+		}
+		txid := obs.EndpointTransactionID.Unwrap()
+
+		// handle the case where the handshake succeeded
+		if obs.TLSHandshakeFailure.Unwrap() == "" {
+			wa.TLSHandshakeECHSuccess.Add(txid)
+			continue
+		}
+
+		// handle the case where the handshake without ECH succeeded
+		if analysisTLSHandshakeWithoutECHSucceeded(c, obs) {
+			wa.TLSHandshakeECHUnexpectedFailure.Add(txid)
+		}
+	}
+}
+
+// analysisTLSHandshakeWithoutECHSucceeded returns whether there is a successful TLS
+// handshake not using ECH with the same endpoint and server name of obs.
+func analysisTLSHandshakeWithoutECHSucceeded(c *WebObservationsContainer, obs *WebObservation) bool {
+	for _, other := range c.KnownTCPEndpoints {
+		if !other.TLSECHAccepted.IsNone() || other.TLSHandshakeFailure.IsNone() {
+			continue
+		}
+		if other.TLSHandshakeFailure.Unwrap() != "" {
+			continue
+		}
+		if other.EndpointAddress.UnwrapOr("") == obs.EndpointAddress.UnwrapOr("") &&
+			other.TLSServerName.UnwrapOr("") == obs.TLSServerName.UnwrapOr("") {
+			return true
+		}
+	}
+	return false
+}
+
+// analysisHTTP3ResponseDiverges returns whether the HTTP/3 response significantly differs
+// from the control's HTTP/3 response in terms of status code or body length. We only
+// compare the body length when neither body is empty or truncated.
+func analysisHTTP3ResponseDiverges(obs *WebObservation) bool {
+	if obs.HTTPResponseStatusCode.UnwrapOr(0) != obs.ControlHTTPResponseStatusCode.UnwrapOr(0) {
+		return true
+	}
+	measurement := obs.HTTPResponseBodyLength.UnwrapOr(0)
+	control := obs.ControlHTTPResponseBodyLength.UnwrapOr(0)
+	if measurement <= 0 || control <= 0 || obs.HTTPResponseBodyIsTruncated.UnwrapOr(true) {
+		return false
+	}
+	proportion := ComputeHTTPDiffBodyProportionFactor(measurement, control)
+	return proportion <= analysisHTTP3BodyProportionFactor
+}
+
+func (wa *WebAnalysis) throttlingComputeMetrics(c *WebObservationsContainer) {
+	for _, obs := range c.KnownTCPEndpoints {
+		// handle the case where we did not fetch the webpage or have no goodput curve
+		if obs.TagFetchBody.IsNone() || !obs.TagFetchBody.Unwrap() || obs.HTTPFailure.IsNone() {
+			continue
+		}
+
+		// skip the extra fetches since they are not part of the webpage download
+		if obs.TagExtraFetch.UnwrapOr(false) {
+			continue
+		}
+		curve := obs.GoodputCurve.UnwrapOr(nil)
+		if len(curve) <= 0 {
+			continue
+		}
+		txid := obs.EndpointTransactionID.Unwrap()
+		if wa.Throttled.IsNone() {
+			wa.Throttled = optional.Some(false)
+		}
+
+		// handle the case where the goodput collapsed
+		if _, found := throttlingSpeedCollapse(curve); found {
+			wa.HTTPThrottlingSpeedCollapse.Add(txid)
+			wa.Throttled = optional.Some(true)
+		}
+
+		// handle the case where the download failed while making slow progress, which
+		// we can only evaluate when we know the control's body length
+		if obs.HTTPFailure.Unwrap() == "" || obs.ControlHTTPResponseBodyLength.IsNone() {
+			continue
+		}
+		if throttlingSlowDownload(curve, obs.ControlHTTPResponseBodyLength.Unwrap()) {
+			wa.HTTPThrottlingSlowDownload.Add(txid)
+			wa.Throttled = optional.Some(true)
+		}
+	}
+}
+
 func (wa *WebAnalysis) httpHandleFinalResponse(obs *WebObservation) {
 	// handle the case where there's no control
 	if obs.ControlHTTPFailure.IsNone() {
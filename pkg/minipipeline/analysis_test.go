@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/netxlite"
 	"github.com/ooni/probe-engine/pkg/optional"
 )
 
@@ -482,3 +483,463 @@ func TestHTTPDiffTitleDifferentLongWords(t *testing.T) {
 		})
 	}
 }
+
+func TestTLSHandshakeUnexpectedCertificate(t *testing.T) {
+	type testcase struct {
+		name    string
+		failure string
+		probe   optional.Value[[]string]
+		control optional.Value[[]string]
+		expect  bool
+	}
+
+	cases := []testcase{{
+		name:    "with a certificate not received by the control",
+		failure: netxlite.FailureSSLUnknownAuthority,
+		probe:   optional.Some([]string{"deadbeef"}),
+		control: optional.Some([]string{"abad1dea", "0xdeadbeef"}),
+		expect:  true,
+	}, {
+		name:    "with a certificate also received by the control",
+		failure: netxlite.FailureSSLInvalidHostname,
+		probe:   optional.Some([]string{"deadbeef"}),
+		control: optional.Some([]string{"deadbeef", "abad1dea"}),
+		expect:  false,
+	}, {
+		name:    "with a failure not related to certificates",
+		failure: netxlite.FailureConnectionReset,
+		probe:   optional.Some([]string{}),
+		control: optional.Some([]string{"abad1dea"}),
+		expect:  false,
+	}, {
+		name:    "when the control does not provide fingerprints",
+		failure: netxlite.FailureSSLInvalidCertificate,
+		probe:   optional.Some([]string{"deadbeef"}),
+		control: optional.None[[]string](),
+		expect:  false,
+	}, {
+		name:    "when the probe did not save certificates",
+		failure: netxlite.FailureSSLInvalidCertificate,
+		probe:   optional.Some([]string{}),
+		control: optional.Some([]string{"abad1dea"}),
+		expect:  false,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			container := &WebObservationsContainer{
+				KnownTCPEndpoints: map[int64]*WebObservation{
+					1: {
+						EndpointTransactionID:             optional.Some(int64(1)),
+						TLSHandshakeFailure:               optional.Some(tc.failure),
+						TLSCertificateFingerprints:        tc.probe,
+						ControlTLSHandshakeFailure:        optional.Some(""),
+						ControlTLSCertificateFingerprints: tc.control,
+						TagDepth:                          optional.Some(int64(0)),
+					},
+				},
+			}
+			wa := &WebAnalysis{}
+			wa.tlsComputeMetrics(container)
+			if !wa.TLSHandshakeUnexpectedFailure.Contains(1) {
+				t.Fatal("expected an unexpected failure")
+			}
+			if got := wa.TLSHandshakeUnexpectedCertificate.Contains(1); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
+
+func TestDNSResolverMetrics(t *testing.T) {
+	container := &WebObservationsContainer{
+		DNSLookupFailures: []*WebObservation{{
+			DNSTransactionID: optional.Some(int64(3)),
+			DNSResolver:      optional.Some("udp://8.8.8.8:53"),
+		}},
+		DNSLookupSuccesses: []*WebObservation{{
+			DNSTransactionID: optional.Some(int64(1)),
+			DNSResolver:      optional.Some("system:///"),
+		}, {
+			DNSTransactionID: optional.Some(int64(1)),
+			DNSResolver:      optional.Some("system:///"),
+		}, {
+			DNSTransactionID: optional.Some(int64(2)),
+			DNSResolver:      optional.Some("dot://8.8.8.8:853"),
+		}, {
+			DNSTransactionID: optional.Some(int64(4)),
+			DNSResolver:      optional.None[string](), // not attributable
+		}},
+	}
+	wa := &WebAnalysis{
+		DNSLookupSuccessWithInvalidAddresses: NewSet[int64](1, 4),
+		DNSLookupSuccessWithValidAddress:     NewSet[int64](2),
+		DNSLookupUnexpectedFailure:           NewSet[int64](3),
+	}
+	wa.dnsComputeResolverMetrics(container)
+
+	if diff := cmp.Diff([]string{"system:///"}, wa.DNSResolverWithInvalidAddresses.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]string{"dot://8.8.8.8:853"}, wa.DNSResolverWithValidAddress.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]string{"udp://8.8.8.8:53"}, wa.DNSResolverWithUnexpectedFailure.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestHTTPExtraFetchMetrics(t *testing.T) {
+	const webpage = "http://www.example.com/"
+	container := &WebObservationsContainer{
+		KnownTCPEndpoints: map[int64]*WebObservation{
+			// the fetch from the highest priority address
+			1: {
+				EndpointTransactionID:       optional.Some(int64(1)),
+				HTTPRequestURL:              optional.Some(webpage),
+				HTTPFailure:                 optional.Some(""),
+				HTTPResponseStatusCode:      optional.Some(int64(200)),
+				HTTPResponseBodyLength:      optional.Some(int64(1000)),
+				HTTPResponseBodyIsTruncated: optional.Some(false),
+				HTTPResponseTitle:           optional.Some("Example Domain"),
+				HTTPResponseIsFinal:         optional.Some(true),
+			},
+
+			// an extra fetch returning the same webpage
+			2: {
+				EndpointTransactionID:       optional.Some(int64(2)),
+				HTTPRequestURL:              optional.Some(webpage),
+				HTTPFailure:                 optional.Some(""),
+				HTTPResponseStatusCode:      optional.Some(int64(200)),
+				HTTPResponseBodyLength:      optional.Some(int64(990)),
+				HTTPResponseBodyIsTruncated: optional.Some(false),
+				HTTPResponseTitle:           optional.Some("Example Domain"),
+				HTTPResponseIsFinal:         optional.Some(true),
+				TagExtraFetch:               optional.Some(true),
+			},
+
+			// an extra fetch returning a blockpage
+			3: {
+				EndpointTransactionID:       optional.Some(int64(3)),
+				HTTPRequestURL:              optional.Some(webpage),
+				HTTPFailure:                 optional.Some(""),
+				HTTPResponseStatusCode:      optional.Some(int64(200)),
+				HTTPResponseBodyLength:      optional.Some(int64(100)),
+				HTTPResponseBodyIsTruncated: optional.Some(false),
+				HTTPResponseTitle:           optional.Some("Access Forbidden"),
+				HTTPResponseIsFinal:         optional.Some(true),
+				TagExtraFetch:               optional.Some(true),
+			},
+
+			// an extra fetch with a different status code and a truncated body
+			4: {
+				EndpointTransactionID:       optional.Some(int64(4)),
+				HTTPRequestURL:              optional.Some(webpage),
+				HTTPFailure:                 optional.Some(""),
+				HTTPResponseStatusCode:      optional.Some(int64(403)),
+				HTTPResponseBodyLength:      optional.Some(int64(1 << 19)),
+				HTTPResponseBodyIsTruncated: optional.Some(true),
+				HTTPResponseTitle:           optional.Some("Example Domain"),
+				HTTPResponseIsFinal:         optional.Some(true),
+				TagExtraFetch:               optional.Some(true),
+			},
+
+			// an extra fetch that failed
+			5: {
+				EndpointTransactionID: optional.Some(int64(5)),
+				HTTPRequestURL:        optional.Some(webpage),
+				HTTPFailure:           optional.Some(netxlite.FailureConnectionReset),
+				TagExtraFetch:         optional.Some(true),
+			},
+
+			// an extra fetch without a reference response
+			6: {
+				EndpointTransactionID:       optional.Some(int64(6)),
+				HTTPRequestURL:              optional.Some("http://www.example.org/"),
+				HTTPFailure:                 optional.Some(""),
+				HTTPResponseStatusCode:      optional.Some(int64(403)),
+				HTTPResponseBodyLength:      optional.Some(int64(100)),
+				HTTPResponseBodyIsTruncated: optional.Some(false),
+				HTTPResponseIsFinal:         optional.Some(true),
+				TagExtraFetch:               optional.Some(true),
+			},
+		},
+	}
+
+	wa := &WebAnalysis{}
+	wa.httpComputeFailureMetrics(container)
+	wa.httpComputeFinalResponseMetrics(container)
+	wa.httpComputeExtraFetchMetrics(container)
+
+	if diff := cmp.Diff([]int64{5}, wa.HTTPExtraFetchFailure.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]int64{3, 4}, wa.HTTPExtraFetchBodyDivergence.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]int64{3}, wa.HTTPExtraFetchTitleDivergence.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// make sure the extra fetches do not influence the other HTTP metrics
+	if wa.HTTPRoundTripUnexplainedFailure.Len() != 0 {
+		t.Fatal("expected no unexplained failures", wa.HTTPRoundTripUnexplainedFailure.String())
+	}
+	if value := wa.HTTPFinalResponseSuccessTCPWithoutControl.UnwrapOr(0); value != 1 {
+		t.Fatal("expected 1, got", value)
+	}
+}
+
+func TestQUICMetrics(t *testing.T) {
+	container := &WebObservationsContainer{
+		KnownQUICEndpoints: map[int64]*WebObservation{
+			// a QUIC handshake failing while the control succeeds
+			1: {
+				EndpointTransactionID:       optional.Some(int64(1)),
+				QUICHandshakeFailure:        optional.Some(netxlite.FailureGenericTimeoutError),
+				ControlQUICHandshakeFailure: optional.Some(""),
+			},
+
+			// a QUIC handshake failing without control information
+			2: {
+				EndpointTransactionID: optional.Some(int64(2)),
+				QUICHandshakeFailure:  optional.Some(netxlite.FailureGenericTimeoutError),
+			},
+
+			// a QUIC handshake failing for both the probe and the control
+			3: {
+				EndpointTransactionID:       optional.Some(int64(3)),
+				QUICHandshakeFailure:        optional.Some(netxlite.FailureGenericTimeoutError),
+				ControlQUICHandshakeFailure: optional.Some(netxlite.FailureGenericTimeoutError),
+			},
+
+			// an HTTP/3 round trip failing while the control succeeds
+			4: {
+				EndpointTransactionID:       optional.Some(int64(4)),
+				QUICHandshakeFailure:        optional.Some(""),
+				ControlQUICHandshakeFailure: optional.Some(""),
+				HTTPFailure:                 optional.Some(netxlite.FailureConnectionReset),
+				ControlHTTPFailure:          optional.Some(""),
+			},
+
+			// an HTTP/3 response matching the control
+			5: {
+				EndpointTransactionID:         optional.Some(int64(5)),
+				QUICHandshakeFailure:          optional.Some(""),
+				HTTPFailure:                   optional.Some(""),
+				HTTPResponseStatusCode:        optional.Some(int64(200)),
+				HTTPResponseBodyLength:        optional.Some(int64(990)),
+				HTTPResponseBodyIsTruncated:   optional.Some(false),
+				ControlHTTPFailure:            optional.Some(""),
+				ControlHTTPResponseStatusCode: optional.Some(int64(200)),
+				ControlHTTPResponseBodyLength: optional.Some(int64(1000)),
+			},
+
+			// an HTTP/3 response differing from the control
+			6: {
+				EndpointTransactionID:         optional.Some(int64(6)),
+				QUICHandshakeFailure:          optional.Some(""),
+				HTTPFailure:                   optional.Some(""),
+				HTTPResponseStatusCode:        optional.Some(int64(200)),
+				HTTPResponseBodyLength:        optional.Some(int64(100)),
+				HTTPResponseBodyIsTruncated:   optional.Some(false),
+				ControlHTTPFailure:            optional.Some(""),
+				ControlHTTPResponseStatusCode: optional.Some(int64(200)),
+				ControlHTTPResponseBodyLength: optional.Some(int64(1000)),
+			},
+
+			// an HTTP/3 round trip failing for both the probe and the control
+			7: {
+				EndpointTransactionID: optional.Some(int64(7)),
+				QUICHandshakeFailure:  optional.Some(""),
+				HTTPFailure:           optional.Some(netxlite.FailureConnectionReset),
+				ControlHTTPFailure:    optional.Some(netxlite.FailureConnectionReset),
+			},
+		},
+	}
+
+	wa := &WebAnalysis{}
+	wa.quicComputeMetrics(container)
+
+	if diff := cmp.Diff([]int64{1}, wa.QUICHandshakeUnexpectedFailure.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]int64{2}, wa.QUICHandshakeUnexplainedFailure.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]int64{3}, wa.QUICHandshakeExpectedFailure.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]int64{4}, wa.HTTP3RoundTripUnexpectedFailure.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]int64{6}, wa.HTTP3ResponseDiffersFromControl.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestECHMetrics(t *testing.T) {
+	container := &WebObservationsContainer{
+		KnownTCPEndpoints: map[int64]*WebObservation{
+			// a TLS handshake not using ECH that succeeds
+			1: {
+				EndpointTransactionID: optional.Some(int64(1)),
+				EndpointAddress:       optional.Some("93.184.216.34:443"),
+				TLSHandshakeFailure:   optional.Some(""),
+				TLSServerName:         optional.Some("www.example.com"),
+			},
+
+			// a TLS handshake using ECH that succeeds
+			2: {
+				EndpointTransactionID: optional.Some(int64(2)),
+				EndpointAddress:       optional.Some("93.184.216.34:443"),
+				TLSHandshakeFailure:   optional.Some(""),
+				TLSServerName:         optional.Some("www.example.com"),
+				TLSECHAccepted:        optional.Some(true),
+			},
+
+			// a TLS handshake using ECH that fails while not using ECH succeeds
+			3: {
+				EndpointTransactionID: optional.Some(int64(3)),
+				EndpointAddress:       optional.Some("93.184.216.34:443"),
+				TLSHandshakeFailure:   optional.Some(netxlite.FailureConnectionReset),
+				TLSServerName:         optional.Some("www.example.com"),
+				TLSECHAccepted:        optional.Some(false),
+			},
+
+			// a TLS handshake using ECH that fails for an endpoint without handshakes not using ECH
+			4: {
+				EndpointTransactionID: optional.Some(int64(4)),
+				EndpointAddress:       optional.Some("93.184.216.35:443"),
+				TLSHandshakeFailure:   optional.Some(netxlite.FailureConnectionReset),
+				TLSServerName:         optional.Some("www.example.com"),
+				TLSECHAccepted:        optional.Some(false),
+			},
+		},
+	}
+
+	wa := &WebAnalysis{}
+	wa.echComputeMetrics(container)
+
+	if diff := cmp.Diff([]int64{2}, wa.TLSHandshakeECHSuccess.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]int64{3}, wa.TLSHandshakeECHUnexpectedFailure.Keys()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestThrottlingMetrics(t *testing.T) {
+	t.Run("when there are no goodput curves", func(t *testing.T) {
+		container := &WebObservationsContainer{
+			KnownTCPEndpoints: map[int64]*WebObservation{
+				1: {
+					EndpointTransactionID: optional.Some(int64(1)),
+					TagFetchBody:          optional.Some(true),
+					HTTPFailure:           optional.Some(""),
+				},
+			},
+		}
+
+		wa := &WebAnalysis{}
+		wa.throttlingComputeMetrics(container)
+
+		if !wa.Throttled.IsNone() {
+			t.Fatal("expected Throttled to be none")
+		}
+	})
+
+	t.Run("when there are goodput curves", func(t *testing.T) {
+		container := &WebObservationsContainer{
+			KnownTCPEndpoints: map[int64]*WebObservation{
+				// a successful download with constant goodput
+				1: {
+					EndpointTransactionID:         optional.Some(int64(1)),
+					TagFetchBody:                  optional.Some(true),
+					HTTPFailure:                   optional.Some(""),
+					GoodputCurve:                  optional.Some(newTestingGoodputCurve(0, 0.5, 50000, 1.0, 100000, 1.5, 150000)),
+					ControlHTTPResponseBodyLength: optional.Some(int64(150000)),
+				},
+
+				// a successful download where the goodput collapsed
+				2: {
+					EndpointTransactionID:         optional.Some(int64(2)),
+					TagFetchBody:                  optional.Some(true),
+					HTTPFailure:                   optional.Some(""),
+					GoodputCurve:                  optional.Some(newTestingGoodputCurve(0, 0.1, 5000, 0.6, 5500, 1.1, 6000, 1.6, 6500)),
+					ControlHTTPResponseBodyLength: optional.Some(int64(6000)),
+				},
+
+				// a slow download failing before receiving the control's body length
+				3: {
+					EndpointTransactionID:         optional.Some(int64(3)),
+					TagFetchBody:                  optional.Some(true),
+					HTTPFailure:                   optional.Some(netxlite.FailureGenericTimeoutError),
+					GoodputCurve:                  optional.Some(newTestingGoodputCurve(0, 1.0, 10000, 2.0, 20000, 3.0, 30000)),
+					ControlHTTPResponseBodyLength: optional.Some(int64(1 << 20)),
+				},
+
+				// a slow download failing without control information
+				4: {
+					EndpointTransactionID: optional.Some(int64(4)),
+					TagFetchBody:          optional.Some(true),
+					HTTPFailure:           optional.Some(netxlite.FailureGenericTimeoutError),
+					GoodputCurve:          optional.Some(newTestingGoodputCurve(0, 1.0, 10000, 2.0, 20000, 3.0, 30000)),
+				},
+
+				// a connectivity check where the goodput collapsed
+				5: {
+					EndpointTransactionID: optional.Some(int64(5)),
+					TagFetchBody:          optional.Some(false),
+					HTTPFailure:           optional.Some(""),
+					GoodputCurve:          optional.Some(newTestingGoodputCurve(0, 0.1, 5000, 0.6, 5500, 1.1, 6000, 1.6, 6500)),
+				},
+
+				// an extra fetch where the goodput collapsed
+				6: {
+					EndpointTransactionID: optional.Some(int64(6)),
+					TagFetchBody:          optional.Some(true),
+					TagExtraFetch:         optional.Some(true),
+					HTTPFailure:           optional.Some(""),
+					GoodputCurve:          optional.Some(newTestingGoodputCurve(0, 0.1, 5000, 0.6, 5500, 1.1, 6000, 1.6, 6500)),
+				},
+			},
+		}
+
+		wa := &WebAnalysis{}
+		wa.throttlingComputeMetrics(container)
+
+		if diff := cmp.Diff([]int64{2}, wa.HTTPThrottlingSpeedCollapse.Keys()); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff([]int64{3}, wa.HTTPThrottlingSlowDownload.Keys()); diff != "" {
+			t.Fatal(diff)
+		}
+		if !wa.Throttled.UnwrapOr(false) {
+			t.Fatal("expected Throttled to be true")
+		}
+	})
+	t.Run("we ignore the extra fetches", func(t *testing.T) {
+		container := &WebObservationsContainer{
+			KnownTCPEndpoints: map[int64]*WebObservation{
+				1: {
+					EndpointTransactionID: optional.Some(int64(1)),
+					TagFetchBody:          optional.Some(true),
+					TagExtraFetch:         optional.Some(true),
+					HTTPFailure:           optional.Some(""),
+					GoodputCurve:          optional.Some(newTestingGoodputCurve(0, 0.1, 5000, 0.6, 5500, 1.1, 6000, 1.6, 6500)),
+				},
+			},
+		}
+
+		wa := &WebAnalysis{}
+		wa.throttlingComputeMetrics(container)
+
+		if wa.HTTPThrottlingSpeedCollapse.Len() != 0 {
+			t.Fatal("expected no speed collapse")
+		}
+		if !wa.Throttled.IsNone() {
+			t.Fatal("expected Throttled to be none")
+		}
+	})
+}
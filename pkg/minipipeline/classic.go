@@ -9,6 +9,8 @@ package minipipeline
 //
 // 3. endpoints using such IP addresses.
 //
+// Because v0.4 did not measure HTTP/3, the output never contains QUIC endpoints.
+//
 // We use this filter to produce a backward compatible Web Connectivity analysis
 // when the input [*WebObservationsContainer] was built using LTE.
 //
@@ -20,6 +22,7 @@ func ClassicFilter(input *WebObservationsContainer) (output *WebObservationsCont
 		DNSLookupFailures:  []*WebObservation{},
 		DNSLookupSuccesses: []*WebObservation{},
 		KnownTCPEndpoints:  map[int64]*WebObservation{},
+		KnownQUICEndpoints: map[int64]*WebObservation{},
 		knownIPAddresses:   map[string]*WebObservation{},
 	}
 
@@ -72,6 +75,13 @@ func ClassicFilter(input *WebObservationsContainer) (output *WebObservationsCont
 			continue
 		}
 
+		// Discard all the entries where we're fetching the body from an address
+		// other than the highest priority one, because classic analysis expects
+		// to see at most one web fetch for each redirect
+		if entry.TagExtraFetch.UnwrapOr(false) {
+			continue
+		}
+
 		output.KnownTCPEndpoints[txid] = entry
 	}
 
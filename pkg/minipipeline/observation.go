@@ -10,6 +10,7 @@ import (
 	"github.com/ooni/probe-engine/pkg/model"
 	"github.com/ooni/probe-engine/pkg/netxlite"
 	"github.com/ooni/probe-engine/pkg/optional"
+	"github.com/ooni/probe-engine/pkg/throttling"
 )
 
 // ErrNoTestKeys indicates that a [*WebMeasurement] does not contain [*MeasurementTestKeys].
@@ -32,7 +33,9 @@ func IngestWebMeasurement(lookupper model.GeoIPASNLookupper, meas *WebMeasuremen
 	container.IngestDNSLookupEvents(lookupper, tk.Queries...)
 	container.IngestTCPConnectEvents(lookupper, tk.TCPConnect...)
 	container.IngestTLSHandshakeEvents(tk.TLSHandshakes...)
+	container.IngestQUICHandshakeEvents(lookupper, tk.QUICHandshakes...)
 	container.IngestHTTPRoundTripEvents(tk.Requests...)
+	container.IngestNetworkEvents(tk.NetworkEvents...)
 
 	// be defensive in case the control request or control are not defined
 	if !tk.XControlRequest.IsNone() && !tk.Control.IsNone() {
@@ -63,6 +66,9 @@ const (
 
 	// The last operation is an HTTP round trip.
 	WebObservationTypeHTTPRoundTrip
+
+	// The last operation is a QUIC handshake.
+	WebObservationTypeQUICHandshake
 )
 
 // These are the possible origins for IP addresses.
@@ -117,6 +123,12 @@ type WebObservation struct {
 	// tries fetching the body and only one will actually do it.)
 	TagFetchBody optional.Value[bool]
 
+	// TagExtraFetch is the value of the extra_fetch=<bool> tag. We use this tag in
+	// Web Connectivity LTE to indicate that the current transaction fetched the webpage
+	// body in addition to the transaction fetching it from the highest priority address,
+	// which happens when we're configured to fetch the webpage from all the addresses.
+	TagExtraFetch optional.Value[bool]
+
 	// The following fields are optional.Some when you process the DNS
 	// lookup events contained inside an OONI measurement:
 
@@ -140,6 +152,10 @@ type WebObservation struct {
 	// DNSEngine is the DNS engine that we're using (e.g., "getaddrinfo").
 	DNSEngine optional.Value[string]
 
+	// DNSResolver is the URL of the resolver that we're using (e.g., "udp://8.8.8.8:53"),
+	// which we only know when the lookup is tagged with "resolver=<URL>".
+	DNSResolver optional.Value[string]
+
 	// DNSResolvedAddrs contains the list of DNS-resolved addrs.
 	DNSResolvedAddrs optional.Value[Set[string]]
 
@@ -196,6 +212,28 @@ type WebObservation struct {
 	// TLSServerName is the optional TLS server name used by the TLS handshake.
 	TLSServerName optional.Value[string]
 
+	// TLSCertificateFingerprints contains the hex-encoded SHA-256 fingerprints of
+	// the certificates received by the probe, starting with the leaf certificate.
+	TLSCertificateFingerprints optional.Value[[]string]
+
+	// TLSECHAccepted is optional.Some only when the TLS handshake used Encrypted
+	// Client Hello and indicates whether the server accepted it.
+	TLSECHAccepted optional.Value[bool]
+
+	// The following fields are optional.Some when you process the QUIC
+	// handshake events contained inside an OONI measurement:
+
+	// QUICHandshakeFailure is the optional QUIC handshake failure.
+	QUICHandshakeFailure optional.Value[string]
+
+	// The following fields are optional.Some when you process the network
+	// events contained inside an OONI measurement:
+
+	// GoodputCurve contains the goodput curve of the connection computed using the
+	// bytes_received_cumulative network events, sorted by time. The first sample is
+	// the time when we established the connection, when we had received zero bytes.
+	GoodputCurve optional.Value[[]GoodputSample]
+
 	// The following fields are optional.Some when you process the HTTP round
 	// trip events contained inside an OONI measurement:
 
@@ -246,6 +284,13 @@ type WebObservation struct {
 	// ControlTLSHandshakeFailure is the control's TLS handshake failure.
 	ControlTLSHandshakeFailure optional.Value[string]
 
+	// ControlTLSCertificateFingerprints contains the fingerprints of the certificates
+	// received by the control, when the control is recent enough to provide them.
+	ControlTLSCertificateFingerprints optional.Value[[]string]
+
+	// ControlQUICHandshakeFailure is the control's QUIC handshake failure.
+	ControlQUICHandshakeFailure optional.Value[string]
+
 	// ControlHTTPFailure is the HTTP failure seen by the control.
 	ControlHTTPFailure optional.Value[string]
 
@@ -292,6 +337,12 @@ type WebObservationsContainer struct {
 	// KnownTCPEndpoints maps transaction IDs to TCP observations.
 	KnownTCPEndpoints map[int64]*WebObservation
 
+	// KnownQUICEndpoints maps transaction IDs to QUIC observations. We keep these
+	// observations separate from KnownTCPEndpoints because the HTTP/3 fetches are
+	// not part of the redirect chain and we analyze them separately. For QUIC
+	// observations, the ControlHTTP* fields refer to the control's HTTP/3 request.
+	KnownQUICEndpoints map[int64]*WebObservation
+
 	// ControlExpectations summarizes the expectations we have based on the control results.
 	ControlExpectations optional.Value[*WebObservationsControlExpectations]
 
@@ -306,6 +357,7 @@ func NewWebObservationsContainer() *WebObservationsContainer {
 		DNSLookupFailures:  []*WebObservation{},
 		DNSLookupSuccesses: []*WebObservation{},
 		KnownTCPEndpoints:  map[int64]*WebObservation{},
+		KnownQUICEndpoints: map[int64]*WebObservation{},
 		knownIPAddresses:   map[string]*WebObservation{},
 	}
 }
@@ -336,6 +388,7 @@ func (c *WebObservationsContainer) ingestDNSLookupFailures(evs ...*model.Archiva
 			DNSLookupFailure: failure,
 			DNSQueryType:     optional.Some(ev.QueryType),
 			DNSEngine:        optional.Some(ev.Engine),
+			DNSResolver:      utilsExtractTagResolver(ev.Tags),
 			TagDepth:         utilsExtractTagDepth(ev.Tags),
 		}
 
@@ -365,6 +418,7 @@ func (c *WebObservationsContainer) ingestDNSLookupSuccesses(
 				DNSLookupFailure: optional.Some(""),
 				DNSQueryType:     optional.Some(ev.QueryType),
 				DNSEngine:        optional.Some(ev.Engine),
+				DNSResolver:      utilsExtractTagResolver(ev.Tags),
 				DNSResolvedAddrs: optional.Some(addrs),
 				IPAddressOrigin:  optional.Some(IPAddressOriginDNS),
 				IPAddress:        optional.Some(ipAddr),
@@ -448,15 +502,77 @@ func (c *WebObservationsContainer) IngestTLSHandshakeEvents(evs ...*model.Archiv
 		obs.Failure = failure
 		obs.TLSHandshakeFailure = failure
 		obs.TLSServerName = optional.Some(ev.ServerName)
+		obs.TLSCertificateFingerprints = optional.Some(utilsCertificateFingerprints(ev.PeerCertificates))
+		if ev.ECHAccepted != nil {
+			obs.TLSECHAccepted = optional.Some(*ev.ECHAccepted)
+		}
+	}
+}
+
+// IngestQUICHandshakeEvents ingests QUIC handshake events from a OONI measurement. You MUST
+// ingest these events after DNS events and before ingesting HTTP round trip events.
+func (c *WebObservationsContainer) IngestQUICHandshakeEvents(
+	lookupper model.GeoIPASNLookupper, evs ...*model.ArchivalTLSOrQUICHandshakeResult) {
+	for _, ev := range evs {
+		// obtain the IP address and the port
+		ipAddr, portString, err := net.SplitHostPort(ev.Address)
+		if err != nil {
+			continue
+		}
+
+		// create or fetch a record
+		obs, found := c.knownIPAddresses[ipAddr]
+		if !found {
+			obs = &WebObservation{
+				IPAddressOrigin: optional.None[string](), // we don't know!
+				IPAddress:       optional.Some(ipAddr),
+				IPAddressASN:    utilsGeoipxLookupASN(lookupper, ipAddr),
+				IPAddressBogon:  optional.Some(netxlite.IsBogon(ipAddr)),
+			}
+		}
+
+		// clone the record because the same IP address MAY belong
+		// to multiple endpoints across the same measurement
+		//
+		// while there also fill endpoint specific info
+		failure := optional.Some(utilsStringPointerToString(ev.Failure))
+		obs = &WebObservation{
+			Type:                       WebObservationTypeQUICHandshake,
+			Failure:                    failure,
+			TransactionID:              ev.TransactionID,
+			DNSTransactionID:           obs.DNSTransactionID,
+			DNSDomain:                  obs.DNSDomain,
+			DNSLookupFailure:           obs.DNSLookupFailure,
+			DNSResolvedAddrs:           obs.DNSResolvedAddrs,
+			IPAddressOrigin:            obs.IPAddressOrigin,
+			IPAddress:                  obs.IPAddress,
+			IPAddressASN:               obs.IPAddressASN,
+			IPAddressBogon:             obs.IPAddressBogon,
+			EndpointTransactionID:      optional.Some(ev.TransactionID),
+			EndpointProto:              optional.Some("udp"),
+			EndpointPort:               optional.Some(portString),
+			EndpointAddress:            optional.Some(ev.Address),
+			TLSServerName:              optional.Some(ev.ServerName),
+			TLSCertificateFingerprints: optional.Some(utilsCertificateFingerprints(ev.PeerCertificates)),
+			QUICHandshakeFailure:       failure,
+			TagDepth:                   utilsExtractTagDepth(ev.Tags),
+			TagFetchBody:               utilsExtractTagFetchBody(ev.Tags),
+		}
+
+		// register the observation
+		c.KnownQUICEndpoints[ev.TransactionID] = obs
 	}
 }
 
 // IngestHTTPRoundTripEvents ingests HTTP round trip events from a OONI measurement. You
-// MUST ingest these events after ingesting TCP connect events.
+// MUST ingest these events after ingesting TCP connect and QUIC handshake events.
 func (c *WebObservationsContainer) IngestHTTPRoundTripEvents(evs ...*model.ArchivalHTTPRequestResult) {
 	for _, ev := range evs {
 		// find the corresponding obs
 		obs, found := c.KnownTCPEndpoints[ev.TransactionID]
+		if !found {
+			obs, found = c.KnownQUICEndpoints[ev.TransactionID]
+		}
 		if !found {
 			continue
 		}
@@ -467,6 +583,7 @@ func (c *WebObservationsContainer) IngestHTTPRoundTripEvents(evs ...*model.Archi
 		obs.Failure = failure
 		obs.HTTPRequestURL = optional.Some(ev.Request.URL)
 		obs.HTTPFailure = failure
+		obs.TagExtraFetch = utilsExtractTagExtraFetch(ev.Tags)
 
 		// consider the response authoritative only in case of success
 		if ev.Failure == nil {
@@ -481,6 +598,40 @@ func (c *WebObservationsContainer) IngestHTTPRoundTripEvents(evs ...*model.Archi
 	}
 }
 
+// IngestNetworkEvents ingests network events from a OONI measurement to compute the
+// goodput curve of TCP endpoints. You MUST ingest these events after ingesting TCP
+// connect events. We ignore the events not related to a successful TCP connect.
+func (c *WebObservationsContainer) IngestNetworkEvents(evs ...*model.ArchivalNetworkEvent) {
+	origins := map[int64]float64{}
+	samples := map[int64][]*model.ArchivalNetworkEvent{}
+	for _, ev := range evs {
+		// find the corresponding obs
+		obs, found := c.KnownTCPEndpoints[ev.TransactionID]
+		if !found || ev.Address != obs.EndpointAddress.UnwrapOr("") {
+			continue
+		}
+
+		// collect the connect time and the samples
+		switch ev.Operation {
+		case netxlite.ConnectOperation:
+			if ev.Failure == nil {
+				origins[ev.TransactionID] = ev.T
+			}
+		case throttling.BytesReceivedCumulativeOperation:
+			samples[ev.TransactionID] = append(samples[ev.TransactionID], ev)
+		}
+	}
+
+	// update the records
+	for txid, values := range samples {
+		origin, found := origins[txid]
+		if !found {
+			continue
+		}
+		c.KnownTCPEndpoints[txid].GoodputCurve = optional.Some(newGoodputCurve(origin, values...))
+	}
+}
+
 // IngestControlMessages ingests the control request and response. You MUST call
 // this method last, after you've ingested all the other measurement events.
 //
@@ -497,6 +648,7 @@ func (c *WebObservationsContainer) IngestControlMessages(req *model.THRequest, r
 	c.controlXrefTCPIPFailures(resp)
 	c.controlXrefTLSFailures(resp)
 	c.controlSetHTTPFinalResponseExpectation(resp)
+	c.controlXrefQUICEndpoints(inputDomain, resp)
 
 	return nil
 }
@@ -596,6 +748,12 @@ func (c *WebObservationsContainer) controlXrefTLSFailures(resp *model.THResponse
 		}
 		serverName := obs.TLSServerName.Unwrap()
 
+		// skip handshakes using ECH since the control does not use ECH and we compare
+		// them with the probe's own handshakes not using ECH instead
+		if !obs.TLSECHAccepted.IsNone() {
+			continue
+		}
+
 		// skip when we don't have a record
 		tls, found := resp.TLSHandshake[endpointAddress]
 		if !found {
@@ -609,6 +767,9 @@ func (c *WebObservationsContainer) controlXrefTLSFailures(resp *model.THResponse
 
 		// save the corresponding control result
 		obs.ControlTLSHandshakeFailure = optional.Some(utilsStringPointerToString(tls.Failure))
+		if len(tls.XCertificateFingerprints) > 0 {
+			obs.ControlTLSCertificateFingerprints = optional.Some(tls.XCertificateFingerprints)
+		}
 	}
 }
 
@@ -646,3 +807,47 @@ func (c *WebObservationsContainer) controlSetHTTPFinalResponseExpectation(resp *
 		obs.ControlHTTPResponseTitle = optional.Some(resp.HTTPRequest.Title)
 	}
 }
+
+func (c *WebObservationsContainer) controlXrefQUICEndpoints(inputDomain string, resp *model.THResponse) {
+	for _, obs := range c.KnownQUICEndpoints {
+		// register the DNS information like we do for TCP endpoints
+		domain := obs.DNSDomain.UnwrapOr("")
+		if domain == "" && NewSet(resp.DNS.Addrs...).Contains(obs.IPAddress.Unwrap()) {
+			obs.IPAddressOrigin = optional.Some(IPAddressOriginTH)
+		}
+		if domain == "" || domain == inputDomain {
+			obs.ControlDNSDomain = optional.Some(inputDomain)
+			obs.ControlDNSLookupFailure = optional.Some(utilsStringPointerToString(resp.DNS.Failure))
+			if resp.DNS.Failure == nil {
+				obs.ControlDNSResolvedAddrs = optional.Some(NewSet(resp.DNS.Addrs...))
+			}
+		}
+
+		// the TH indexes the QUIC handshake results using the TCP endpoints so the
+		// results are only comparable when the port is the same
+		quic, found := resp.QUICHandshake[obs.EndpointAddress.Unwrap()]
+		if found && quic.ServerName == obs.TLSServerName.UnwrapOr("") {
+			obs.ControlQUICHandshakeFailure = optional.Some(utilsStringPointerToString(quic.Failure))
+			if len(quic.XCertificateFingerprints) > 0 {
+				obs.ControlTLSCertificateFingerprints = optional.Some(quic.XCertificateFingerprints)
+			}
+		}
+
+		// skip when the TH did not fetch the webpage using HTTP/3
+		if resp.HTTP3Request == nil {
+			continue
+		}
+		obs.ControlHTTPFailure = optional.Some(utilsStringPointerToString(resp.HTTP3Request.Failure))
+
+		// leave everything else nil if there was a failure, like we
+		// already do when processing the probe events
+		if resp.HTTP3Request.Failure != nil {
+			continue
+		}
+
+		obs.ControlHTTPResponseStatusCode = optional.Some(resp.HTTP3Request.StatusCode)
+		obs.ControlHTTPResponseBodyLength = optional.Some(resp.HTTP3Request.BodyLength)
+		obs.ControlHTTPResponseHeadersKeys = utilsExtractHTTPHeaderKeys(resp.HTTP3Request.Headers)
+		obs.ControlHTTPResponseTitle = optional.Some(resp.HTTP3Request.Title)
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-engine/pkg/geoipx"
 	"github.com/ooni/probe-engine/pkg/model"
 	"github.com/ooni/probe-engine/pkg/netxlite"
 	"github.com/ooni/probe-engine/pkg/optional"
+	"github.com/ooni/probe-engine/pkg/throttling"
 )
 
 func TestLoadWebObservations(t *testing.T) {
@@ -90,6 +92,102 @@ func TestWebObservationsContainerIngestTLSHandshakeEvents(t *testing.T) {
 			t.Fatal("the number of known TCP endpoints should not have changed")
 		}
 	})
+
+	t.Run("when the handshake uses ECH", func(t *testing.T) {
+		container := &WebObservationsContainer{
+			KnownTCPEndpoints: map[int64]*WebObservation{
+				1: {EndpointTransactionID: optional.Some(int64(1))},
+				2: {EndpointTransactionID: optional.Some(int64(2))},
+			},
+		}
+
+		accepted := true
+		container.IngestTLSHandshakeEvents(&model.ArchivalTLSOrQUICHandshakeResult{
+			ServerName:    "www.example.com",
+			TransactionID: 1,
+		}, &model.ArchivalTLSOrQUICHandshakeResult{
+			ServerName:    "www.example.com",
+			ECHConfig:     "AEX+DQ==",
+			ECHAccepted:   &accepted,
+			TransactionID: 2,
+		})
+
+		if !container.KnownTCPEndpoints[1].TLSECHAccepted.IsNone() {
+			t.Fatal("expected TLSECHAccepted to be none without ECH")
+		}
+		if !container.KnownTCPEndpoints[2].TLSECHAccepted.UnwrapOr(false) {
+			t.Fatal("expected TLSECHAccepted to be true")
+		}
+	})
+}
+
+func TestWebObservationsContainerIngestNetworkEvents(t *testing.T) {
+	container := &WebObservationsContainer{
+		KnownTCPEndpoints: map[int64]*WebObservation{
+			1: {EndpointAddress: optional.Some("93.184.216.34:443")},
+			2: {EndpointAddress: optional.Some("93.184.216.34:443")},
+		},
+	}
+
+	failure := netxlite.FailureConnectionRefused
+	container.IngestNetworkEvents(&model.ArchivalNetworkEvent{
+		Address:       "93.184.216.34:443",
+		Operation:     netxlite.ConnectOperation,
+		T:             0.5,
+		TransactionID: 1,
+	}, &model.ArchivalNetworkEvent{
+		Address:       "93.184.216.34:443",
+		NumBytes:      1000,
+		Operation:     throttling.BytesReceivedCumulativeOperation,
+		T:             1.0,
+		TransactionID: 1,
+	}, &model.ArchivalNetworkEvent{
+		Address:       "93.184.216.34:443",
+		NumBytes:      4000,
+		Operation:     throttling.BytesReceivedCumulativeOperation,
+		T:             1.5,
+		TransactionID: 1,
+	}, &model.ArchivalNetworkEvent{
+		Address:       "93.184.216.34:443",
+		NumBytes:      1000,
+		Operation:     netxlite.ReadOperation,
+		T:             1.2,
+		TransactionID: 1,
+	}, &model.ArchivalNetworkEvent{
+		Address:       "93.184.216.34:443",
+		Failure:       &failure,
+		Operation:     netxlite.ConnectOperation,
+		T:             0.5,
+		TransactionID: 2,
+	}, &model.ArchivalNetworkEvent{
+		Address:       "93.184.216.34:443",
+		NumBytes:      0,
+		Operation:     throttling.BytesReceivedCumulativeOperation,
+		T:             1.0,
+		TransactionID: 2,
+	})
+
+	expect := []GoodputSample{{
+		T:              0.5,
+		NumBytes:       0,
+		BytesPerSecond: 0,
+	}, {
+		T:              1.0,
+		NumBytes:       1000,
+		BytesPerSecond: 2000,
+	}, {
+		T:              1.5,
+		NumBytes:       4000,
+		BytesPerSecond: 6000,
+	}}
+	if diff := cmp.Diff(expect, container.KnownTCPEndpoints[1].GoodputCurve.UnwrapOr(nil)); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// we should not compute the goodput curve when the connect failed
+	if !container.KnownTCPEndpoints[2].GoodputCurve.IsNone() {
+		t.Fatal("expected no goodput curve")
+	}
 }
 
 func TestWebObservationsContainerIngestHTTPRoundTripEvents(t *testing.T) {
@@ -123,6 +221,51 @@ func TestWebObservationsContainerIngestHTTPRoundTripEvents(t *testing.T) {
 }
 
 func TestWebObservationsContainerIngestControlMessages(t *testing.T) {
+	t.Run("we save the control certificate fingerprints when available", func(t *testing.T) {
+		newContainer := func() *WebObservationsContainer {
+			return &WebObservationsContainer{
+				DNSLookupFailures: []*WebObservation{},
+				KnownTCPEndpoints: map[int64]*WebObservation{
+					1: {
+						IPAddress:             optional.Some("8.8.8.8"),
+						EndpointTransactionID: optional.Some(int64(1)),
+						EndpointPort:          optional.Some("443"),
+						EndpointAddress:       optional.Some("8.8.8.8:443"),
+						TLSServerName:         optional.Some("dns.google"),
+					},
+				},
+				knownIPAddresses: map[string]*WebObservation{},
+			}
+		}
+
+		thRequest := &model.THRequest{
+			HTTPRequest: "https://dns.google/",
+		}
+
+		for _, fingerprints := range [][]string{nil, {"deadbeef"}} {
+			container := newContainer()
+			thResponse := &model.THResponse{
+				TLSHandshake: map[string]model.THTLSHandshakeResult{
+					"8.8.8.8:443": {
+						ServerName:               "dns.google",
+						Status:                   true,
+						XCertificateFingerprints: fingerprints,
+					},
+				},
+			}
+			if err := container.IngestControlMessages(thRequest, thResponse); err != nil {
+				t.Fatal(err)
+			}
+			got := container.KnownTCPEndpoints[1].ControlTLSCertificateFingerprints
+			if diff := cmp.Diff(fingerprints, got.UnwrapOr(nil)); diff != "" {
+				t.Fatal(diff)
+			}
+			if got.IsNone() != (len(fingerprints) <= 0) {
+				t.Fatal("unexpected ControlTLSCertificateFingerprints", got)
+			}
+		}
+	})
+
 	t.Run("we don't save TLS handshake failures when the SNI is different", func(t *testing.T) {
 		container := &WebObservationsContainer{
 			DNSLookupFailures: []*WebObservation{},
@@ -211,3 +354,104 @@ func TestWebObservationsContainerIngestControlMessages(t *testing.T) {
 		}
 	})
 }
+
+func TestWebObservationsContainerIngestQUICHandshakeEvents(t *testing.T) {
+	t.Run("we create QUIC endpoints and cross reference them with the control", func(t *testing.T) {
+		container := NewWebObservationsContainer()
+
+		container.IngestQUICHandshakeEvents(model.GeoIPASNLookupperFunc(geoipx.LookupASN), &model.ArchivalTLSOrQUICHandshakeResult{
+			Network:       "udp",
+			Address:       "8.8.8.8:443",
+			Failure:       nil,
+			ServerName:    "dns.google",
+			Tags:          []string{"depth=0", "fetch_body=true"},
+			TransactionID: 90001,
+		})
+
+		container.IngestHTTPRoundTripEvents(&model.ArchivalHTTPRequestResult{
+			Network: "udp",
+			Address: "8.8.8.8:443",
+			ALPN:    "h3",
+			Failure: nil,
+			Request: model.ArchivalHTTPRequest{
+				URL: "https://dns.google/",
+			},
+			Response: model.ArchivalHTTPResponse{
+				Body: model.ArchivalScrubbedMaybeBinaryString("<title>Google Public DNS</title>"),
+				Code: 200,
+			},
+			Tags:          []string{"depth=0", "fetch_body=true"},
+			TransactionID: 90001,
+		})
+
+		thRequest := &model.THRequest{
+			HTTPRequest: "https://dns.google/",
+		}
+
+		thResponse := &model.THResponse{
+			QUICHandshake: map[string]model.THTLSHandshakeResult{
+				"8.8.8.8:443": {
+					ServerName: "dns.google",
+					Status:     true,
+					Failure:    nil,
+				},
+			},
+			HTTP3Request: &model.THHTTPRequestResult{
+				BodyLength: 32,
+				StatusCode: 200,
+				Title:      "Google Public DNS",
+			},
+			DNS: model.THDNSResult{
+				Addrs: []string{"8.8.8.8", "8.8.4.4"},
+			},
+		}
+
+		if err := container.IngestControlMessages(thRequest, thResponse); err != nil {
+			t.Fatal(err)
+		}
+
+		// we should not have created any TCP endpoint
+		if len(container.KnownTCPEndpoints) != 0 {
+			t.Fatal("expected no TCP endpoints")
+		}
+
+		obs := container.KnownQUICEndpoints[90001]
+		if obs == nil {
+			t.Fatal("expected to find the QUIC endpoint")
+		}
+		if obs.Type != WebObservationTypeHTTPRoundTrip {
+			t.Fatal("unexpected observation type", obs.Type)
+		}
+		if value := obs.EndpointProto.UnwrapOr(""); value != "udp" {
+			t.Fatal("unexpected EndpointProto", value)
+		}
+		if value := obs.IPAddressOrigin.UnwrapOr(""); value != IPAddressOriginTH {
+			t.Fatal("unexpected IPAddressOrigin", value)
+		}
+		if value := obs.QUICHandshakeFailure.UnwrapOr("x"); value != "" {
+			t.Fatal("unexpected QUICHandshakeFailure", value)
+		}
+		if value := obs.ControlQUICHandshakeFailure.UnwrapOr("x"); value != "" {
+			t.Fatal("unexpected ControlQUICHandshakeFailure", value)
+		}
+		if value := obs.HTTPResponseTitle.UnwrapOr(""); value != "Google Public DNS" {
+			t.Fatal("unexpected HTTPResponseTitle", value)
+		}
+		if value := obs.ControlHTTPResponseStatusCode.UnwrapOr(0); value != 200 {
+			t.Fatal("unexpected ControlHTTPResponseStatusCode", value)
+		}
+	})
+
+	t.Run("we skip events with an invalid address", func(t *testing.T) {
+		container := NewWebObservationsContainer()
+
+		container.IngestQUICHandshakeEvents(model.GeoIPASNLookupperFunc(geoipx.LookupASN), &model.ArchivalTLSOrQUICHandshakeResult{
+			Address:       "8.8.8.8",
+			TransactionID: 90001,
+		})
+
+		if len(container.KnownQUICEndpoints) != 0 {
+			t.Fatal("expected no QUIC endpoints")
+		}
+	})
+}
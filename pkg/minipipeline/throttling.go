@@ -0,0 +1,115 @@
+package minipipeline
+
+import (
+	"sort"
+
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+// GoodputSample is a sample of the goodput curve of a connection.
+type GoodputSample struct {
+	// T is the time when we collected this sample relative to the measurement zero time.
+	T float64
+
+	// NumBytes is the cumulative number of bytes received when we collected this sample.
+	NumBytes int64
+
+	// BytesPerSecond is the goodput between the previous sample and this sample.
+	BytesPerSecond float64
+}
+
+// newGoodputCurve computes the goodput curve of a connection established at the origin
+// time given the bytes_received_cumulative network events collected for it. The first
+// sample of the curve is the origin, when we had received zero bytes.
+func newGoodputCurve(origin float64, evs ...*model.ArchivalNetworkEvent) []GoodputSample {
+	evs = append([]*model.ArchivalNetworkEvent{}, evs...)
+	sort.SliceStable(evs, func(i, j int) bool {
+		return evs[i].T < evs[j].T
+	})
+	prev := GoodputSample{T: origin}
+	curve := []GoodputSample{prev}
+	for _, ev := range evs {
+		sample := GoodputSample{T: ev.T, NumBytes: ev.NumBytes}
+		if elapsed := sample.T - prev.T; elapsed > 0 {
+			sample.BytesPerSecond = float64(sample.NumBytes-prev.NumBytes) / elapsed
+		}
+		curve = append(curve, sample)
+		prev = sample
+	}
+	return curve
+}
+
+// throttlingTrimCurve returns the goodput curve without the trailing samples where the
+// number of bytes did not change, which typically occur after the download completed.
+func throttlingTrimCurve(curve []GoodputSample) []GoodputSample {
+	for len(curve) > 1 && curve[len(curve)-1].NumBytes == curve[len(curve)-2].NumBytes {
+		curve = curve[:len(curve)-1]
+	}
+	return curve
+}
+
+// throttlingAverageGoodput returns the average goodput between two samples.
+func throttlingAverageGoodput(begin, end GoodputSample) float64 {
+	elapsed := end.T - begin.T
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(end.NumBytes-begin.NumBytes) / elapsed
+}
+
+// Thresholds used to detect throttling.
+const (
+	// throttlingCollapseFactor is the maximum ratio between the goodput after a
+	// collapse and the goodput before the collapse.
+	throttlingCollapseFactor = 0.1
+
+	// throttlingMinBytesBeforeCollapse is the minimum number of bytes we must receive
+	// before a collapse, which is less than a typical TLS handshake such that we can
+	// also detect collapses occurring right after the TLS handshake.
+	throttlingMinBytesBeforeCollapse = 1 << 10
+
+	// throttlingMinSeconds is the minimum duration in seconds of the slow phase of
+	// the download, to avoid drawing conclusions from too few samples.
+	throttlingMinSeconds = 1.0
+
+	// throttlingSlowGoodput is the goodput in bytes per second below which we consider
+	// a download to be slow, which is roughly 256 kbit/s.
+	throttlingSlowGoodput = 1 << 15
+)
+
+// throttlingSpeedCollapse returns whether the goodput curve shows the goodput collapsing
+// after receiving some bytes (e.g., after the TLS handshake) and, in such a case, the
+// number of bytes received before the collapse.
+func throttlingSpeedCollapse(curve []GoodputSample) (int64, bool) {
+	curve = throttlingTrimCurve(curve)
+	if len(curve) < 3 {
+		return 0, false
+	}
+	first, last := curve[0], curve[len(curve)-1]
+	for _, split := range curve[1 : len(curve)-1] {
+		if split.NumBytes < throttlingMinBytesBeforeCollapse || last.T-split.T < throttlingMinSeconds {
+			continue
+		}
+		before := throttlingAverageGoodput(first, split)
+		after := throttlingAverageGoodput(split, last)
+		if before > 0 && after > 0 && after <= before*throttlingCollapseFactor {
+			return split.NumBytes, true
+		}
+	}
+	return 0, false
+}
+
+// throttlingSlowDownload returns whether the goodput curve shows a download that was
+// making slow progress and that received fewer bytes than the expected body length.
+func throttlingSlowDownload(curve []GoodputSample, expectedBodyLength int64) bool {
+	curve = throttlingTrimCurve(curve)
+	if len(curve) < 2 {
+		return false
+	}
+	first, last := curve[0], curve[len(curve)-1]
+	if last.T-first.T < throttlingMinSeconds || last.NumBytes >= expectedBodyLength {
+		return false
+	}
+	goodput := throttlingAverageGoodput(first, last)
+	return goodput > 0 && goodput < throttlingSlowGoodput
+}
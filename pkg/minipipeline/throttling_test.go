@@ -0,0 +1,145 @@
+package minipipeline
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+// newTestingGoodputCurve creates a goodput curve from pairs of time and bytes.
+func newTestingGoodputCurve(origin float64, values ...float64) []GoodputSample {
+	var evs []*model.ArchivalNetworkEvent
+	for idx := 0; idx+1 < len(values); idx += 2 {
+		evs = append(evs, &model.ArchivalNetworkEvent{T: values[idx], NumBytes: int64(values[idx+1])})
+	}
+	return newGoodputCurve(origin, evs...)
+}
+
+func TestNewGoodputCurve(t *testing.T) {
+	t.Run("we sort the samples and compute the goodput", func(t *testing.T) {
+		curve := newTestingGoodputCurve(1.0, 2.0, 3000, 1.5, 1000)
+		expect := []GoodputSample{{
+			T:              1.0,
+			NumBytes:       0,
+			BytesPerSecond: 0,
+		}, {
+			T:              1.5,
+			NumBytes:       1000,
+			BytesPerSecond: 2000,
+		}, {
+			T:              2.0,
+			NumBytes:       3000,
+			BytesPerSecond: 4000,
+		}}
+		if diff := cmp.Diff(expect, curve); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we do not divide by zero with samples collected at the same time", func(t *testing.T) {
+		curve := newTestingGoodputCurve(1.0, 1.0, 1000)
+		if curve[1].BytesPerSecond != 0 {
+			t.Fatal("unexpected goodput", curve[1].BytesPerSecond)
+		}
+	})
+}
+
+func TestThrottlingSpeedCollapse(t *testing.T) {
+	type testcase struct {
+		name      string
+		curve     []GoodputSample
+		expectN   int64
+		expectHit bool
+	}
+
+	cases := []testcase{{
+		name:      "with an empty curve",
+		curve:     newTestingGoodputCurve(0),
+		expectN:   0,
+		expectHit: false,
+	}, {
+		name:      "with a constant goodput",
+		curve:     newTestingGoodputCurve(0, 0.5, 50000, 1.0, 100000, 1.5, 150000, 2.0, 200000),
+		expectN:   0,
+		expectHit: false,
+	}, {
+		name:      "with the goodput collapsing after the TLS handshake",
+		curve:     newTestingGoodputCurve(0, 0.1, 5000, 0.6, 5500, 1.1, 6000, 1.6, 6500),
+		expectN:   5000,
+		expectHit: true,
+	}, {
+		name:      "with the goodput collapsing after some bytes",
+		curve:     newTestingGoodputCurve(0, 0.5, 100000, 1.0, 200000, 1.5, 202000, 2.0, 204000, 2.5, 206000),
+		expectN:   200000,
+		expectHit: true,
+	}, {
+		name:      "with the goodput collapsing for too short a time",
+		curve:     newTestingGoodputCurve(0, 0.5, 100000, 1.0, 200000, 1.5, 202000),
+		expectN:   0,
+		expectHit: false,
+	}, {
+		name:      "with the connection being idle after the download completed",
+		curve:     newTestingGoodputCurve(0, 0.5, 100000, 1.0, 200000, 1.5, 200000, 2.0, 200000, 2.5, 200000),
+		expectN:   0,
+		expectHit: false,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			n, hit := throttlingSpeedCollapse(tc.curve)
+			if n != tc.expectN || hit != tc.expectHit {
+				t.Fatal("expected", tc.expectN, tc.expectHit, "got", n, hit)
+			}
+		})
+	}
+}
+
+func TestThrottlingSlowDownload(t *testing.T) {
+	type testcase struct {
+		name               string
+		curve              []GoodputSample
+		expectedBodyLength int64
+		expect             bool
+	}
+
+	cases := []testcase{{
+		name:               "with an empty curve",
+		curve:              newTestingGoodputCurve(0),
+		expectedBodyLength: 1 << 20,
+		expect:             false,
+	}, {
+		name:               "with a slow download",
+		curve:              newTestingGoodputCurve(0, 1.0, 10000, 2.0, 20000, 3.0, 30000),
+		expectedBodyLength: 1 << 20,
+		expect:             true,
+	}, {
+		name:               "with a fast download",
+		curve:              newTestingGoodputCurve(0, 1.0, 100000, 2.0, 200000, 3.0, 300000),
+		expectedBodyLength: 1 << 20,
+		expect:             false,
+	}, {
+		name:               "with a slow download receiving the whole body",
+		curve:              newTestingGoodputCurve(0, 1.0, 10000, 2.0, 20000, 3.0, 30000),
+		expectedBodyLength: 20000,
+		expect:             false,
+	}, {
+		name:               "with a download that is too short",
+		curve:              newTestingGoodputCurve(0, 0.5, 10000),
+		expectedBodyLength: 1 << 20,
+		expect:             false,
+	}, {
+		name:               "with a download that never received any byte",
+		curve:              newTestingGoodputCurve(0, 1.0, 0, 2.0, 0, 3.0, 0),
+		expectedBodyLength: 1 << 20,
+		expect:             false,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := throttlingSlowDownload(tc.curve, tc.expectedBodyLength); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package minipipeline
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"strconv"
 	"strings"
 
@@ -16,6 +18,15 @@ func utilsStringPointerToString(failure *string) (out string) {
 	return
 }
 
+func utilsCertificateFingerprints(certs []model.ArchivalBinaryData) []string {
+	out := []string{}
+	for _, cert := range certs {
+		sum := sha256.Sum256(cert)
+		out = append(out, hex.EncodeToString(sum[:]))
+	}
+	return out
+}
+
 func utilsGeoipxLookupASN(lookupper model.GeoIPASNLookupper, ipAddress string) optional.Value[int64] {
 	if asn, _, err := lookupper.LookupASN(ipAddress); err == nil && asn > 0 {
 		return optional.Some(int64(asn))
@@ -101,6 +112,29 @@ func utilsExtractTagFetchBody(tags []string) (result optional.Value[bool]) {
 	return
 }
 
+func utilsExtractTagExtraFetch(tags []string) (result optional.Value[bool]) {
+	result = optional.None[bool]()
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, "extra_fetch=") {
+			continue
+		}
+		tag = strings.TrimPrefix(tag, "extra_fetch=")
+		result = optional.Some(tag == "true")
+	}
+	return
+}
+
+func utilsExtractTagResolver(tags []string) (result optional.Value[string]) {
+	result = optional.None[string]()
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, "resolver=") {
+			continue
+		}
+		result = optional.Some(strings.TrimPrefix(tag, "resolver="))
+	}
+	return
+}
+
 func utilsDNSLookupFailureIsDNSNoAnswerForAAAA(obs *WebObservation) bool {
 	return obs.DNSQueryType.UnwrapOr("") == "AAAA" &&
 		obs.DNSLookupFailure.UnwrapOr("") == netxlite.FailureDNSNoAnswer
@@ -94,3 +94,55 @@ func TestUtilsTagFetchBody(t *testing.T) {
 		}
 	})
 }
+
+func TestUtilsExtractTagExtraFetch(t *testing.T) {
+	t.Run("with nil tags list", func(t *testing.T) {
+		result := utilsExtractTagExtraFetch(nil)
+		if !result.IsNone() {
+			t.Fatal("expected none")
+		}
+	})
+
+	t.Run("with missing extra_fetch=BOOL tag", func(t *testing.T) {
+		result := utilsExtractTagExtraFetch([]string{"depth=0", "fetch_body=true"})
+		if !result.IsNone() {
+			t.Fatal("expected none")
+		}
+	})
+
+	t.Run("with extra_fetch=true tag", func(t *testing.T) {
+		result := utilsExtractTagExtraFetch([]string{"fetch_body=true", "extra_fetch=true"})
+		if result.IsNone() {
+			t.Fatal("expected not none")
+		}
+		if value := result.Unwrap(); value != true {
+			t.Fatal("expected true, got", value)
+		}
+	})
+}
+
+func TestUtilsExtractTagResolver(t *testing.T) {
+	t.Run("with nil tags list", func(t *testing.T) {
+		result := utilsExtractTagResolver(nil)
+		if !result.IsNone() {
+			t.Fatal("expected none")
+		}
+	})
+
+	t.Run("with missing resolver=URL tag", func(t *testing.T) {
+		result := utilsExtractTagResolver([]string{"depth=0", "classic"})
+		if !result.IsNone() {
+			t.Fatal("expected none")
+		}
+	})
+
+	t.Run("with resolver=URL tag", func(t *testing.T) {
+		result := utilsExtractTagResolver([]string{"depth=0", "resolver=dot://8.8.8.8:853"})
+		if result.IsNone() {
+			t.Fatal("expected not none")
+		}
+		if value := result.Unwrap(); value != "dot://8.8.8.8:853" {
+			t.Fatal("expected dot://8.8.8.8:853, got", value)
+		}
+	})
+}
@@ -24,6 +24,12 @@ type Database struct {
 	MockListResults        func() ([]model.DatabaseResultNetwork, []model.DatabaseResultNetwork, error)
 	MockListMeasurements   func(resultID int64) ([]model.DatabaseMeasurementURLNetwork, error)
 	MockGetMeasurementJSON func(msmtID int64) (map[string]interface{}, error)
+	MockGetMeasurement     func(msmtID int64) (*model.DatabaseMeasurement, error)
+	MockGetResult          func(resultID int64) (*model.DatabaseResult, error)
+	MockSearchMeasurements func(filter *model.DatabaseMeasurementFilter) (
+		[]model.DatabaseMeasurementURLNetwork, int64, error)
+	MockCountMeasurementsByNetwork func(filter *model.DatabaseMeasurementFilter) ([]model.DatabaseNetworkCount, error)
+	MockCountMeasurementsByTest    func(filter *model.DatabaseMeasurementFilter) ([]model.DatabaseTestCount, error)
 }
 
 var _ model.WritableDatabase = &Database{}
@@ -105,3 +111,29 @@ func (d *Database) ListMeasurements(resultID int64) ([]model.DatabaseMeasurement
 func (d *Database) GetMeasurementJSON(msmtID int64) (map[string]interface{}, error) {
 	return d.MockGetMeasurementJSON(msmtID)
 }
+
+// GetMeasurement calls MockGetMeasurement
+func (d *Database) GetMeasurement(msmtID int64) (*model.DatabaseMeasurement, error) {
+	return d.MockGetMeasurement(msmtID)
+}
+
+// GetResult calls MockGetResult
+func (d *Database) GetResult(resultID int64) (*model.DatabaseResult, error) {
+	return d.MockGetResult(resultID)
+}
+
+// SearchMeasurements calls MockSearchMeasurements
+func (d *Database) SearchMeasurements(
+	filter *model.DatabaseMeasurementFilter) ([]model.DatabaseMeasurementURLNetwork, int64, error) {
+	return d.MockSearchMeasurements(filter)
+}
+
+// CountMeasurementsByNetwork calls MockCountMeasurementsByNetwork
+func (d *Database) CountMeasurementsByNetwork(filter *model.DatabaseMeasurementFilter) ([]model.DatabaseNetworkCount, error) {
+	return d.MockCountMeasurementsByNetwork(filter)
+}
+
+// CountMeasurementsByTest calls MockCountMeasurementsByTest
+func (d *Database) CountMeasurementsByTest(filter *model.DatabaseMeasurementFilter) ([]model.DatabaseTestCount, error) {
+	return d.MockCountMeasurementsByTest(filter)
+}
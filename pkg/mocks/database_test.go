@@ -243,4 +243,88 @@ func TestDatabase(t *testing.T) {
 			t.Fatal("not the error we expected")
 		}
 	})
+
+	t.Run("GetMeasurement", func(t *testing.T) {
+		expected := errors.New("mocked")
+		db := &Database{
+			MockGetMeasurement: func(msmtID int64) (*model.DatabaseMeasurement, error) {
+				return nil, expected
+			},
+		}
+		msmt, err := db.GetMeasurement(0)
+		if msmt != nil {
+			t.Fatal("expected nil measurement")
+		}
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected")
+		}
+	})
+
+	t.Run("GetResult", func(t *testing.T) {
+		expected := errors.New("mocked")
+		db := &Database{
+			MockGetResult: func(resultID int64) (*model.DatabaseResult, error) {
+				return nil, expected
+			},
+		}
+		result, err := db.GetResult(0)
+		if result != nil {
+			t.Fatal("expected nil result")
+		}
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected")
+		}
+	})
+
+	t.Run("SearchMeasurements", func(t *testing.T) {
+		expected := errors.New("mocked")
+		db := &Database{
+			MockSearchMeasurements: func(filter *model.DatabaseMeasurementFilter) (
+				[]model.DatabaseMeasurementURLNetwork, int64, error) {
+				return nil, 0, expected
+			},
+		}
+		msmts, total, err := db.SearchMeasurements(&model.DatabaseMeasurementFilter{})
+		if msmts != nil {
+			t.Fatal("expected nil measurements")
+		}
+		if total != 0 {
+			t.Fatal("expected zero total")
+		}
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected")
+		}
+	})
+
+	t.Run("CountMeasurementsByNetwork", func(t *testing.T) {
+		expected := errors.New("mocked")
+		db := &Database{
+			MockCountMeasurementsByNetwork: func(filter *model.DatabaseMeasurementFilter) ([]model.DatabaseNetworkCount, error) {
+				return nil, expected
+			},
+		}
+		counts, err := db.CountMeasurementsByNetwork(&model.DatabaseMeasurementFilter{})
+		if counts != nil {
+			t.Fatal("expected nil counts")
+		}
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected")
+		}
+	})
+
+	t.Run("CountMeasurementsByTest", func(t *testing.T) {
+		expected := errors.New("mocked")
+		db := &Database{
+			MockCountMeasurementsByTest: func(filter *model.DatabaseMeasurementFilter) ([]model.DatabaseTestCount, error) {
+				return nil, expected
+			},
+		}
+		counts, err := db.CountMeasurementsByTest(&model.DatabaseMeasurementFilter{})
+		if counts != nil {
+			t.Fatal("expected nil counts")
+		}
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected")
+		}
+	})
 }
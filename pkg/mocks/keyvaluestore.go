@@ -18,3 +18,38 @@ func (kvs *KeyValueStore) Get(key string) (value []byte, err error) {
 func (kvs *KeyValueStore) Set(key string, value []byte) (err error) {
 	return kvs.MockSet(key, value)
 }
+
+// ExtendedKeyValueStore allows mocking model.ExtendedKeyValueStore.
+type ExtendedKeyValueStore struct {
+	MockGet func(key string) (value []byte, err error)
+
+	MockSet func(key string, value []byte) (err error)
+
+	MockDelete func(key string) (err error)
+
+	MockKeys func(prefix string) (keys []string, err error)
+
+	MockCompareAndSwap func(key string, oldValue, newValue []byte) (swapped bool, err error)
+}
+
+var _ model.ExtendedKeyValueStore = &ExtendedKeyValueStore{}
+
+func (kvs *ExtendedKeyValueStore) Get(key string) (value []byte, err error) {
+	return kvs.MockGet(key)
+}
+
+func (kvs *ExtendedKeyValueStore) Set(key string, value []byte) (err error) {
+	return kvs.MockSet(key, value)
+}
+
+func (kvs *ExtendedKeyValueStore) Delete(key string) (err error) {
+	return kvs.MockDelete(key)
+}
+
+func (kvs *ExtendedKeyValueStore) Keys(prefix string) (keys []string, err error) {
+	return kvs.MockKeys(prefix)
+}
+
+func (kvs *ExtendedKeyValueStore) CompareAndSwap(key string, oldValue, newValue []byte) (swapped bool, err error) {
+	return kvs.MockCompareAndSwap(key, oldValue, newValue)
+}
@@ -35,3 +35,79 @@ func TestKeyValueStore(t *testing.T) {
 		}
 	})
 }
+
+func TestExtendedKeyValueStore(t *testing.T) {
+	t.Run("Get", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		kvs := &ExtendedKeyValueStore{
+			MockGet: func(key string) (value []byte, err error) {
+				return nil, expect
+			},
+		}
+		out, err := kvs.Get("antani")
+		if !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+		if out != nil {
+			t.Fatal("unexpected out")
+		}
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		kvs := &ExtendedKeyValueStore{
+			MockSet: func(key string, value []byte) (err error) {
+				return expect
+			},
+		}
+		err := kvs.Set("antani", nil)
+		if !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		kvs := &ExtendedKeyValueStore{
+			MockDelete: func(key string) (err error) {
+				return expect
+			},
+		}
+		err := kvs.Delete("antani")
+		if !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		kvs := &ExtendedKeyValueStore{
+			MockKeys: func(prefix string) (keys []string, err error) {
+				return nil, expect
+			},
+		}
+		keys, err := kvs.Keys("antani")
+		if !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+		if len(keys) != 0 {
+			t.Fatal("unexpected keys")
+		}
+	})
+
+	t.Run("CompareAndSwap", func(t *testing.T) {
+		expect := errors.New("mocked error")
+		kvs := &ExtendedKeyValueStore{
+			MockCompareAndSwap: func(key string, oldValue, newValue []byte) (swapped bool, err error) {
+				return false, expect
+			},
+		}
+		swapped, err := kvs.CompareAndSwap("antani", nil, nil)
+		if !errors.Is(err, expect) {
+			t.Fatal("unexpected err", err)
+		}
+		if swapped {
+			t.Fatal("unexpected swapped")
+		}
+	})
+}
@@ -11,6 +11,12 @@ type MeasuringNetwork struct {
 
 	MockNewParallelDNSOverHTTPSResolver func(logger model.DebugLogger, URL string) model.Resolver
 
+	MockNewParallelDNSOverQUICResolver func(logger model.DebugLogger, dialer model.QUICDialer, address string) model.Resolver
+
+	MockNewParallelDNSOverTCPResolver func(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver
+
+	MockNewParallelDNSOverTLSResolver func(logger model.DebugLogger, dialer model.TLSDialer, address string) model.Resolver
+
 	MockNewParallelUDPResolver func(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver
 
 	MockNewQUICDialerWithoutResolver func(listener model.UDPListener, logger model.DebugLogger, w ...model.QUICDialerWrapper) model.QUICDialer
@@ -36,6 +42,21 @@ func (mn *MeasuringNetwork) NewParallelDNSOverHTTPSResolver(logger model.DebugLo
 	return mn.MockNewParallelDNSOverHTTPSResolver(logger, URL)
 }
 
+// NewParallelDNSOverQUICResolver implements model.MeasuringNetwork.
+func (mn *MeasuringNetwork) NewParallelDNSOverQUICResolver(logger model.DebugLogger, dialer model.QUICDialer, address string) model.Resolver {
+	return mn.MockNewParallelDNSOverQUICResolver(logger, dialer, address)
+}
+
+// NewParallelDNSOverTCPResolver implements model.MeasuringNetwork.
+func (mn *MeasuringNetwork) NewParallelDNSOverTCPResolver(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
+	return mn.MockNewParallelDNSOverTCPResolver(logger, dialer, address)
+}
+
+// NewParallelDNSOverTLSResolver implements model.MeasuringNetwork.
+func (mn *MeasuringNetwork) NewParallelDNSOverTLSResolver(logger model.DebugLogger, dialer model.TLSDialer, address string) model.Resolver {
+	return mn.MockNewParallelDNSOverTLSResolver(logger, dialer, address)
+}
+
 // NewParallelUDPResolver implements model.MeasuringNetwork.
 func (mn *MeasuringNetwork) NewParallelUDPResolver(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
 	return mn.MockNewParallelUDPResolver(logger, dialer, address)
@@ -34,6 +34,45 @@ func TestMeasuringN(t *testing.T) {
 		}
 	})
 
+	t.Run("MockNewParallelDNSOverQUICResolver", func(t *testing.T) {
+		expected := &Resolver{}
+		mn := &MeasuringNetwork{
+			MockNewParallelDNSOverQUICResolver: func(logger model.DebugLogger, dialer model.QUICDialer, address string) model.Resolver {
+				return expected
+			},
+		}
+		got := mn.NewParallelDNSOverQUICResolver(nil, nil, "")
+		if expected != got {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("MockNewParallelDNSOverTCPResolver", func(t *testing.T) {
+		expected := &Resolver{}
+		mn := &MeasuringNetwork{
+			MockNewParallelDNSOverTCPResolver: func(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
+				return expected
+			},
+		}
+		got := mn.NewParallelDNSOverTCPResolver(nil, nil, "")
+		if expected != got {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("MockNewParallelDNSOverTLSResolver", func(t *testing.T) {
+		expected := &Resolver{}
+		mn := &MeasuringNetwork{
+			MockNewParallelDNSOverTLSResolver: func(logger model.DebugLogger, dialer model.TLSDialer, address string) model.Resolver {
+				return expected
+			},
+		}
+		got := mn.NewParallelDNSOverTLSResolver(nil, nil, "")
+		if expected != got {
+			t.Fatal("unexpected result")
+		}
+	})
+
 	t.Run("MockNewParallelUDPResolver", func(t *testing.T) {
 		expected := &Resolver{}
 		mn := &MeasuringNetwork{
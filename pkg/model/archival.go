@@ -250,6 +250,7 @@ type ArchivalTLSOrQUICHandshakeResult struct {
 	ServerName         string               `json:"server_name"`
 	OuterServerName    string               `json:"outer_server_name,omitempty"`
 	ECHConfig          string               `json:"echconfig,omitempty"`
+	ECHAccepted        *bool                `json:"ech_accepted,omitempty"`
 	T0                 float64              `json:"t0,omitempty"`
 	T                  float64              `json:"t"`
 	Tags               []string             `json:"tags"`
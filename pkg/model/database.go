@@ -175,6 +175,107 @@ type ReadableDatabase interface {
 	//
 	// Returns the measurement JSON or an error
 	GetMeasurementJSON(msmtID int64) (map[string]interface{}, error)
+
+	// GetMeasurement returns the measurement with the given ID
+	//
+	// Arguments:
+	//
+	// - msmtID is the id of the measurement to return
+	//
+	// Returns either the database measurement or an error
+	GetMeasurement(msmtID int64) (*DatabaseMeasurement, error)
+
+	// GetResult returns the result with the given ID
+	//
+	// Arguments:
+	//
+	// - resultID is the id of the result to return
+	//
+	// Returns either the database result or an error
+	GetResult(resultID int64) (*DatabaseResult, error)
+
+	// SearchMeasurements returns the measurements matching a filter
+	//
+	// Arguments:
+	//
+	// - filter selects the measurements to return and the page to return
+	//
+	// Returns the measurements in the requested page, sorted from the most
+	// recent to the least recent, along with the total number of measurements
+	// matching the filter, or an error
+	SearchMeasurements(filter *DatabaseMeasurementFilter) ([]DatabaseMeasurementURLNetwork, int64, error)
+
+	// CountMeasurementsByNetwork aggregates the measurements per network
+	//
+	// Arguments:
+	//
+	// - filter selects the measurements to aggregate (pagination is ignored)
+	//
+	// Returns the counts for each network or an error
+	CountMeasurementsByNetwork(filter *DatabaseMeasurementFilter) ([]DatabaseNetworkCount, error)
+
+	// CountMeasurementsByTest aggregates the measurements per test name
+	//
+	// Arguments:
+	//
+	// - filter selects the measurements to aggregate (pagination is ignored)
+	//
+	// Returns the counts for each test name or an error
+	CountMeasurementsByTest(filter *DatabaseMeasurementFilter) ([]DatabaseTestCount, error)
+}
+
+// DatabaseMeasurementFilter selects measurements when searching and
+// aggregating. The zero value selects all the measurements.
+type DatabaseMeasurementFilter struct {
+	// URL selects the measurements whose input URL contains this string.
+	URL string
+
+	// CategoryCode selects the measurements whose input URL has this category code.
+	CategoryCode string
+
+	// TestName selects the measurements for this test name.
+	TestName string
+
+	// ASN selects the measurements collected from this ASN.
+	ASN sql.NullInt64
+
+	// Since selects the measurements started at or after this time.
+	Since time.Time
+
+	// Until selects the measurements started before this time.
+	Until time.Time
+
+	// IsAnomaly selects the measurements with this anomaly state.
+	IsAnomaly sql.NullBool
+
+	// IsFailed selects the measurements with this failure state.
+	IsFailed sql.NullBool
+
+	// IsUploaded selects the measurements with this upload state.
+	IsUploaded sql.NullBool
+
+	// Limit is the maximum number of measurements to return. Zero
+	// or negative values mean that there is no limit.
+	Limit int
+
+	// Offset is the number of measurements to skip.
+	Offset int
+}
+
+// DatabaseNetworkCount contains the measurement counts for a network.
+type DatabaseNetworkCount struct {
+	DatabaseNetwork `db:",inline"`
+	AnomalyCount    uint64 `db:"anomaly_count"`
+	FailureCount    uint64 `db:"failure_count"`
+	TotalCount      uint64 `db:"total_count"`
+}
+
+// DatabaseTestCount contains the measurement counts for a test name.
+type DatabaseTestCount struct {
+	TestName     string `db:"test_name"`
+	AnomalyCount uint64 `db:"anomaly_count"`
+	FailureCount uint64 `db:"failure_count"`
+	TotalCount   uint64 `db:"total_count"`
 }
 
 // ResultNetwork is used to represent the structure made from the JOIN
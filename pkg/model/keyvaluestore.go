@@ -15,3 +15,23 @@ type KeyValueStore interface {
 	// whether the operation was successful or not.
 	Set(key string, value []byte) (err error)
 }
+
+// ExtendedKeyValueStore is a [KeyValueStore] that also allows
+// to delete and list keys and to atomically replace values.
+type ExtendedKeyValueStore interface {
+	KeyValueStore
+
+	// Delete removes the given key. Deleting a key that
+	// does not exist is not an error.
+	Delete(key string) (err error)
+
+	// Keys returns the sorted list of keys starting with
+	// the given prefix. Use "" to list all keys.
+	Keys(prefix string) (keys []string, err error)
+
+	// CompareAndSwap sets the value of the given key to newValue
+	// only if its current value is equal to oldValue and returns
+	// whether it replaced the value. A nil oldValue means that
+	// the key must not exist yet.
+	CompareAndSwap(key string, oldValue, newValue []byte) (swapped bool, err error)
+}
@@ -198,19 +198,25 @@ var ErrInvalidProbeIP = errors.New("model: invalid probe IP")
 // Scrubbed is the string that replaces IP addresses.
 const Scrubbed = `[scrubbed]`
 
-// ScrubMeasurement removes [currentIP] from [m] by rewriting
-// it in place while preserving the underlying types
-func ScrubMeasurement(m *Measurement, currentIP string) error {
-	if net.ParseIP(currentIP) == nil {
+// ScrubMeasurement removes [currentIPs] from [m] by rewriting
+// it in place while preserving the underlying types. A dual stack
+// probe SHOULD pass both its IPv4 and its IPv6 addresses.
+func ScrubMeasurement(m *Measurement, currentIPs ...string) error {
+	if len(currentIPs) <= 0 {
 		return ErrInvalidProbeIP
 	}
+	for _, currentIP := range currentIPs {
+		if net.ParseIP(currentIP) == nil {
+			return ErrInvalidProbeIP
+		}
+	}
 	m.ProbeIP = DefaultProbeIP
-	if err := scrubTestKeys(m, currentIP); err != nil {
+	if err := scrubTestKeys(m, currentIPs); err != nil {
 		return err
 	}
 	testKeys := m.TestKeys
 	m.TestKeys = nil
-	if err := scrubTopLevelKeys(m, currentIP); err != nil {
+	if err := scrubTopLevelKeys(m, currentIPs); err != nil {
 		return err
 	}
 	m.TestKeys = testKeys
@@ -220,23 +226,31 @@ func ScrubMeasurement(m *Measurement, currentIP string) error {
 // scrubJSONUnmarshalTopLevelKeys allows to mock json.Unmarshal
 var scrubJSONUnmarshalTopLevelKeys = json.Unmarshal
 
-// scrubTopLevelKeys removes [currentIP] from the top-level keys
+// scrubTopLevelKeys removes [currentIPs] from the top-level keys
 // of [m] by rewriting these keys in place.
-func scrubTopLevelKeys(m *Measurement, currentIP string) error {
+func scrubTopLevelKeys(m *Measurement, currentIPs []string) error {
 	data, err := json.Marshal(m)
 	runtimex.PanicOnError(err, "json.Marshal(m) failed") // m must serialize
-	data = bytes.ReplaceAll(data, []byte(currentIP), []byte(Scrubbed))
+	data = scrubBytes(data, currentIPs)
 	return scrubJSONUnmarshalTopLevelKeys(data, &m)
 }
 
 // scrubJSONUnmarshalTestKeys allows to mock json.Unmarshal
 var scrubJSONUnmarshalTestKeys = json.Unmarshal
 
-// scrubTestKeys removes [currentIP] from the TestKeys by rewriting
+// scrubTestKeys removes [currentIPs] from the TestKeys by rewriting
 // them in place while preserving their original type
-func scrubTestKeys(m *Measurement, currentIP string) error {
+func scrubTestKeys(m *Measurement, currentIPs []string) error {
 	data, err := json.Marshal(m.TestKeys)
 	runtimex.PanicOnError(err, "json.Marshal(m.TestKeys) failed") // m.TestKeys must serialize
-	data = bytes.ReplaceAll(data, []byte(currentIP), []byte(Scrubbed))
+	data = scrubBytes(data, currentIPs)
 	return scrubJSONUnmarshalTestKeys(data, &m.TestKeys)
 }
+
+// scrubBytes replaces each of [currentIPs] inside [data] with [Scrubbed].
+func scrubBytes(data []byte, currentIPs []string) []byte {
+	for _, currentIP := range currentIPs {
+		data = bytes.ReplaceAll(data, []byte(currentIP), []byte(Scrubbed))
+	}
+	return data
+}
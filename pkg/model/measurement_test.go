@@ -208,3 +208,57 @@ func TestScrubMeasurementInvalidIP(t *testing.T) {
 		t.Fatal("not the error we expected")
 	}
 }
+
+func TestScrubMeasurementDualStack(t *testing.T) {
+	const probeIPv6 = "2001:db8:85a3::8a2e:370:7334"
+	config := makeMeasurementConfig{
+		Input:      "https://[" + probeIPv6 + "]/",
+		ProbeIP:    "130.192.91.211",
+		ProbeASN:   "AS137",
+		ProbeCC:    "IT",
+		ResolverIP: "8.8.8.8",
+	}
+	m := makeMeasurement(config)
+	m.TestKeys.(*fakeTestKeys).Body += "and your IPv6 is " + probeIPv6
+	if err := ScrubMeasurement(m, config.ProbeIP, probeIPv6); err != nil {
+		t.Fatal(err)
+	}
+	if m.Input != "https://["+Scrubbed+"]/" {
+		t.Fatal("Input HAS NOT been scrubbed", m.Input)
+	}
+	if m.ResolverIP != config.ResolverIP {
+		t.Fatal("ResolverIP has been scrubbed")
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Count(data, []byte(config.ProbeIP)) != 0 {
+		t.Fatal("ProbeIP not fully redacted")
+	}
+	if bytes.Count(data, []byte(probeIPv6)) != 0 {
+		t.Fatal("IPv6 ProbeIP not fully redacted")
+	}
+}
+
+func TestScrubMeasurementWithoutIPs(t *testing.T) {
+	m := &Measurement{
+		ProbeASN: "AS1234",
+		ProbeCC:  "IT",
+	}
+	err := ScrubMeasurement(m)
+	if !errors.Is(err, ErrInvalidProbeIP) {
+		t.Fatal("not the error we expected")
+	}
+}
+
+func TestScrubMeasurementOneInvalidIP(t *testing.T) {
+	m := &Measurement{
+		ProbeASN: "AS1234",
+		ProbeCC:  "IT",
+	}
+	err := ScrubMeasurement(m, "10.0.0.1", "") // the second IP is invalid
+	if !errors.Is(err, ErrInvalidProbeIP) {
+		t.Fatal("not the error we expected")
+	}
+}
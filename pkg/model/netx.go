@@ -205,6 +205,27 @@ type MeasuringNetwork interface {
 	// NewParallelDNSOverHTTPSResolver creates a new DNS-over-HTTPS resolver with error wrapping.
 	NewParallelDNSOverHTTPSResolver(logger DebugLogger, URL string) Resolver
 
+	// NewParallelDNSOverQUICResolver creates a new Resolver using DNS-over-QUIC
+	// that performs parallel A/AAAA lookups during LookupHost.
+	//
+	// The address argument is the UDP endpoint address (e.g., 94.140.14.14:853) and
+	// the dialer SHOULD be a dialer without resolver (see NewQUICDialerWithoutResolver).
+	NewParallelDNSOverQUICResolver(logger DebugLogger, dialer QUICDialer, address string) Resolver
+
+	// NewParallelDNSOverTCPResolver creates a new Resolver using DNS-over-TCP
+	// that performs parallel A/AAAA lookups during LookupHost.
+	//
+	// The address argument is the TCP endpoint address (e.g., 8.8.8.8:53) and
+	// the dialer SHOULD be a dialer without resolver (see NewDialerWithoutResolver).
+	NewParallelDNSOverTCPResolver(logger DebugLogger, dialer Dialer, address string) Resolver
+
+	// NewParallelDNSOverTLSResolver creates a new Resolver using DNS-over-TLS
+	// that performs parallel A/AAAA lookups during LookupHost.
+	//
+	// The address argument is the TCP endpoint address (e.g., 8.8.8.8:853) and
+	// the dialer SHOULD be a TLS dialer constructed using a dialer without resolver.
+	NewParallelDNSOverTLSResolver(logger DebugLogger, dialer TLSDialer, address string) Resolver
+
 	// NewParallelUDPResolver creates a new Resolver using DNS-over-UDP
 	// that performs parallel A/AAAA lookups during LookupHost.
 	//
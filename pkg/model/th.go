@@ -24,6 +24,10 @@ type THRequest struct {
 type THTCPConnectResult struct {
 	Status  bool    `json:"status"`
 	Failure *string `json:"failure"`
+
+	// XAddressFamily is the address family of the endpoint, which
+	// is one of THAddressFamilyIPv4 and THAddressFamilyIPv6.
+	XAddressFamily string `json:"x_address_family,omitempty"`
 }
 
 // THTLSHandshakeResult is the result of the TLS handshake
@@ -32,6 +36,35 @@ type THTLSHandshakeResult struct {
 	ServerName string  `json:"server_name"`
 	Status     bool    `json:"status"`
 	Failure    *string `json:"failure"`
+
+	// XCertificateFingerprints contains the hex-encoded SHA-256 fingerprints of the
+	// certificates sent by the server, starting with the leaf certificate. When the
+	// handshake fails because of the certificate, we only include the offending one.
+	XCertificateFingerprints []string `json:"x_certificate_fingerprints,omitempty"`
+
+	// XNegotiatedProtocol is the negotiated ALPN protocol.
+	XNegotiatedProtocol string `json:"x_negotiated_protocol,omitempty"`
+
+	// XTLSVersion is the negotiated TLS version (e.g., "TLSv1.3").
+	XTLSVersion string `json:"x_tls_version,omitempty"`
+}
+
+const (
+	// THAddressFamilyIPv4 is the IPv4 address family.
+	THAddressFamilyIPv4 = "ipv4"
+
+	// THAddressFamilyIPv6 is the IPv6 address family.
+	THAddressFamilyIPv6 = "ipv6"
+)
+
+// THAddressFamilyResult summarizes the TCP connect attempts performed
+// by the control vantage point for a given address family.
+type THAddressFamilyResult struct {
+	// Endpoints is the number of endpoints we attempted to connect to.
+	Endpoints int64 `json:"endpoints"`
+
+	// Reachable is the number of endpoints to which we could connect.
+	Reachable int64 `json:"reachable"`
 }
 
 // THHTTPRequestResult is the result of the HTTP request
@@ -94,4 +127,8 @@ type THResponse struct {
 	HTTP3Request  *THHTTPRequestResult            `json:"http3_request"` // optional!
 	DNS           THDNSResult                     `json:"dns"`
 	IPInfo        map[string]*THIPInfo            `json:"ip_info,omitempty"`
+
+	// XAddressFamilies maps each address family to the corresponding TCP connect
+	// results, which allows to know whether the control could use such a family.
+	XAddressFamilies map[string]THAddressFamilyResult `json:"x_address_families,omitempty"`
 }
@@ -89,3 +89,11 @@ const AddressOpenDNS220 = "208.67.220.220"
 
 // AddressNextDNSIo is a dns.nextdns.io address.
 const AddressNextDNSIo = "38.175.119.129"
+
+// AddressODoHCloudflareDNSCom is an odoh.cloudflare-dns.com address, which
+// our QA environment could use for an Oblivious DNS-over-HTTPS target.
+const AddressODoHCloudflareDNSCom = "104.16.249.249"
+
+// AddressODoHProxySurfdomeinenNl is an odoh1.surfdomeinen.nl address, which
+// our QA environment could use for an Oblivious DNS-over-HTTPS proxy.
+const AddressODoHProxySurfdomeinenNl = "145.100.185.15"
@@ -3,6 +3,7 @@ package netemx_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
@@ -471,11 +472,26 @@ func Example_oohelperdWithInternetScenario() {
 			log.Fatalf("netxlite.ReadAllContext failed: %s", err.Error())
 		}
 
+		// the certificates change every time we create a scenario, therefore
+		// we need to remove their fingerprints to have a stable output
+		var thResponse model.THResponse
+		if err := json.Unmarshal(body, &thResponse); err != nil {
+			log.Fatalf("json.Unmarshal failed: %s", err.Error())
+		}
+		for endpoint, result := range thResponse.TLSHandshake {
+			result.XCertificateFingerprints = nil
+			thResponse.TLSHandshake[endpoint] = result
+		}
+		body, err = json.Marshal(thResponse)
+		if err != nil {
+			log.Fatalf("json.Marshal failed: %s", err.Error())
+		}
+
 		fmt.Printf("%+v\n", string(body))
 	})
 
 	// Output:
-	// {"tcp_connect":{"93.184.216.34:443":{"status":true,"failure":null}},"tls_handshake":{"93.184.216.34:443":{"server_name":"www.example.com","status":true,"failure":null}},"quic_handshake":{},"http_request":{"body_length":1533,"discovered_h3_endpoint":"www.example.com:443","failure":null,"title":"Default Web Page","headers":{"Alt-Svc":"h3=\":443\"","Content-Length":"1533","Content-Type":"text/html; charset=utf-8","Date":"Thu, 24 Aug 2023 14:35:29 GMT"},"status_code":200},"http3_request":null,"dns":{"failure":null,"addrs":["93.184.216.34"]},"ip_info":{"93.184.216.34":{"asn":15133,"flags":11}}}
+	// {"tcp_connect":{"93.184.216.34:443":{"status":true,"failure":null,"x_address_family":"ipv4"}},"tls_handshake":{"93.184.216.34:443":{"server_name":"www.example.com","status":true,"failure":null,"x_negotiated_protocol":"http/1.1","x_tls_version":"TLSv1.3"}},"quic_handshake":{},"http_request":{"body_length":1533,"discovered_h3_endpoint":"www.example.com:443","failure":null,"title":"Default Web Page","headers":{"Alt-Svc":"h3=\":443\"","Content-Length":"1533","Content-Type":"text/html; charset=utf-8","Date":"Thu, 24 Aug 2023 14:35:29 GMT"},"status_code":200},"http3_request":null,"dns":{"failure":null,"addrs":["93.184.216.34"]},"ip_info":{"93.184.216.34":{"asn":15133,"flags":11}},"x_address_families":{"ipv4":{"endpoints":1,"reachable":1}}}
 }
 
 // This example shows how the [InternetScenario] defines a GeoIP service like Ubuntu's one.
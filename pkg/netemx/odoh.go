@@ -0,0 +1,41 @@
+package netemx
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/apex/log"
+	"github.com/ooni/netem"
+	"github.com/ooni/probe-engine/pkg/logx"
+	"github.com/ooni/probe-engine/pkg/netxlite"
+	"github.com/ooni/probe-engine/pkg/odohx"
+	"github.com/ooni/probe-engine/pkg/testingx"
+)
+
+// ObliviousDNSOverHTTPSTargetHandlerFactory returns an [HTTPHandlerFactory] for
+// [testingx.ObliviousDNSOverHTTPSTargetHandler] using the given key pair and resolving
+// queries using the [NetStackServerFactoryEnv.OtherResolversConfig].
+func ObliviousDNSOverHTTPSTargetHandlerFactory(kp *odohx.KeyPair) HTTPHandlerFactory {
+	return HTTPHandlerFactoryFunc(func(env NetStackServerFactoryEnv, stack *netem.UNetStack) http.Handler {
+		return &testingx.ObliviousDNSOverHTTPSTargetHandler{
+			KeyPair:      kp,
+			RoundTripper: testingx.NewDNSRoundTripperWithDNSConfig(env.OtherResolversConfig()),
+		}
+	})
+}
+
+// ObliviousDNSOverHTTPSProxyHandlerFactory returns an [HTTPHandlerFactory] for
+// [testingx.ObliviousDNSOverHTTPSProxyHandler] forwarding queries to the target
+// using the given [netem.UNetStack].
+func ObliviousDNSOverHTTPSProxyHandlerFactory() HTTPHandlerFactory {
+	return HTTPHandlerFactoryFunc(func(env NetStackServerFactoryEnv, stack *netem.UNetStack) http.Handler {
+		netx := &netxlite.Netx{Underlying: &netxlite.NetemUnderlyingNetworkAdapter{UNet: stack}}
+		logger := &logx.PrefixLogger{
+			Prefix: fmt.Sprintf("%-16s", "ODOH_PROXY"),
+			Logger: log.Log,
+		}
+		return &testingx.ObliviousDNSOverHTTPSProxyHandler{
+			Client: netxlite.NewHTTPClient(netx.NewHTTPTransportStdlib(logger)),
+		}
+	})
+}
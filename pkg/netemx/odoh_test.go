@@ -0,0 +1,52 @@
+package netemx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/netxlite"
+	"github.com/ooni/probe-engine/pkg/odohx"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+)
+
+func TestObliviousDNSOverHTTPSHandlerFactories(t *testing.T) {
+	kp := runtimex.Try1(odohx.NewKeyPair())
+
+	env := MustNewQAEnv(
+		QAEnvOptionNetStack(AddressODoHCloudflareDNSCom, &HTTPSecureServerFactory{
+			Factory:        ObliviousDNSOverHTTPSTargetHandlerFactory(kp),
+			Ports:          []int{443},
+			ServerNameMain: "odoh.cloudflare-dns.com",
+		}),
+		QAEnvOptionNetStack(AddressODoHProxySurfdomeinenNl, &HTTPSecureServerFactory{
+			Factory:        ObliviousDNSOverHTTPSProxyHandlerFactory(),
+			Ports:          []int{443},
+			ServerNameMain: "odoh1.surfdomeinen.nl",
+		}),
+	)
+	defer env.Close()
+
+	env.AddRecordToAllResolvers("odoh.cloudflare-dns.com", "", AddressODoHCloudflareDNSCom)
+	env.AddRecordToAllResolvers("odoh1.surfdomeinen.nl", "", AddressODoHProxySurfdomeinenNl)
+	env.AddRecordToAllResolvers("www.example.com", "", AddressWwwExampleCom)
+
+	env.Do(func() {
+		// TODO(https://github.com/ooni/probe/issues/2534): NewHTTPClientStdlib has QUIRKS but they're not needed here
+		client := netxlite.NewHTTPClientStdlib(log.Log)
+		txp := netxlite.NewDNSOverObliviousHTTPSTransport(
+			client,
+			"https://odoh1.surfdomeinen.nl/proxy",
+			"https://odoh.cloudflare-dns.com/dns-query",
+		)
+		reso := netxlite.NewUnwrappedParallelResolver(txp)
+		addrs, err := reso.LookupHost(context.Background(), "www.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{AddressWwwExampleCom}, addrs); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
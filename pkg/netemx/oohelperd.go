@@ -25,5 +25,6 @@ func (f *OOHelperDFactory) NewHandler(env NetStackServerFactoryEnv, unet *netem.
 		Logger: log.Log,
 	}
 	handler := oohelperd.NewHandler(logger, netx)
+	handler.EnableQUIC = true // only used by clients setting the x_quic_enabled flag
 	return handler
 }
@@ -62,18 +62,31 @@ func TestOOHelperDHandler(t *testing.T) {
 		expectedTHResp := &model.THResponse{
 			TCPConnect: map[string]model.THTCPConnectResult{
 				"93.184.216.34:443": {
-					Status:  true,
-					Failure: nil,
+					Status:         true,
+					Failure:        nil,
+					XAddressFamily: model.THAddressFamilyIPv4,
 				},
 			},
 			TLSHandshake: map[string]model.THTLSHandshakeResult{
 				"93.184.216.34:443": {
-					ServerName: "www.example.com",
-					Status:     true,
-					Failure:    nil,
+					ServerName:               "www.example.com",
+					Status:                   true,
+					Failure:                  nil,
+					XCertificateFingerprints: nil, // see below
+					XNegotiatedProtocol:      "http/1.1",
+					XTLSVersion:              "TLSv1.3",
+				},
+			},
+			QUICHandshake: map[string]model.THTLSHandshakeResult{
+				"93.184.216.34:443": {
+					ServerName:               "www.example.com",
+					Status:                   true,
+					Failure:                  nil,
+					XCertificateFingerprints: nil, // see below
+					XNegotiatedProtocol:      "h3",
+					XTLSVersion:              "TLSv1.3",
 				},
 			},
-			QUICHandshake: map[string]model.THTLSHandshakeResult{}, // since https://github.com/ooni/probe-cli/pull/1549
 			HTTPRequest: model.THHTTPRequestResult{
 				BodyLength:           1533,
 				DiscoveredH3Endpoint: "www.example.com:443",
@@ -87,7 +100,19 @@ func TestOOHelperDHandler(t *testing.T) {
 				},
 				StatusCode: 200,
 			},
-			HTTP3Request: nil, // since https://github.com/ooni/probe-cli/pull/1549
+			HTTP3Request: &model.THHTTPRequestResult{
+				BodyLength:           1533,
+				DiscoveredH3Endpoint: "",
+				Failure:              nil,
+				Title:                "Default Web Page",
+				Headers: map[string]string{
+					"Alt-Svc":        `h3=":443"`,
+					"Content-Length": "1533",
+					"Content-Type":   "text/html; charset=utf-8",
+					"Date":           "Thu, 24 Aug 2023 14:35:29 GMT",
+				},
+				StatusCode: 200,
+			},
 			DNS: model.THDNSResult{
 				Failure: nil,
 				Addrs:   []string{"93.184.216.34"},
@@ -99,7 +124,28 @@ func TestOOHelperDHandler(t *testing.T) {
 					Flags: 10,
 				},
 			},
+			XAddressFamilies: map[string]model.THAddressFamilyResult{
+				model.THAddressFamilyIPv4: {
+					Endpoints: 1,
+					Reachable: 1,
+				},
+			},
+		}
+
+		// the certificates change every time we create a scenario, therefore we
+		// only check whether we have the leaf and the CA fingerprints
+		tlsResult := thResp.TLSHandshake["93.184.216.34:443"]
+		if len(tlsResult.XCertificateFingerprints) != 2 {
+			t.Fatal("unexpected certificate fingerprints", tlsResult.XCertificateFingerprints)
+		}
+		tlsResult.XCertificateFingerprints = nil
+		thResp.TLSHandshake["93.184.216.34:443"] = tlsResult
+		quicResult := thResp.QUICHandshake["93.184.216.34:443"]
+		if len(quicResult.XCertificateFingerprints) != 2 {
+			t.Fatal("unexpected certificate fingerprints", quicResult.XCertificateFingerprints)
 		}
+		quicResult.XCertificateFingerprints = nil
+		thResp.QUICHandshake["93.184.216.34:443"] = quicResult
 
 		if diff := cmp.Diff(expectedTHResp, &thResp); diff != "" {
 			t.Fatal(diff)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/apex/log"
@@ -255,8 +256,8 @@ func TestQAEnv(t *testing.T) {
 	})
 
 	t.Run("we can collect PCAPs", func(t *testing.T) {
-		// create random PCAP file name
-		pcapFilename := randx.Letters(10) + ".pcap"
+		// create random PCAP file name inside a temporary directory
+		pcapFilename := filepath.Join(t.TempDir(), randx.Letters(10)+".pcap")
 		t.Log(pcapFilename)
 
 		// create PCAP dumper
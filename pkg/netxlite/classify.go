@@ -6,6 +6,7 @@ package netxlite
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
@@ -341,6 +342,12 @@ func ClassifyTLSHandshakeError(err error) string {
 		return errwrapper.Error() // we've already wrapped it
 	}
 
+	var echRejectionError *tls.ECHRejectionError
+	if errors.As(err, &echRejectionError) {
+		// The server completed the handshake using the outer ClientHello, which
+		// typically means it does not support (or has rotated) the ECH config.
+		return FailureSSLECHRejected
+	}
 	var x509HostnameError x509.HostnameError
 	if errors.As(err, &x509HostnameError) {
 		// Test case: https://wrong.host.badssl.com/
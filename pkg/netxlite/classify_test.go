@@ -2,8 +2,10 @@ package netxlite
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"errors"
+	"fmt"
 	"io"
 	"testing"
 
@@ -311,6 +313,13 @@ func TestClassifyTLSHandshakeError(t *testing.T) {
 		}
 	})
 
+	t.Run("for *tls.ECHRejectionError", func(t *testing.T) {
+		err := fmt.Errorf("handshake: %w", &tls.ECHRejectionError{})
+		if ClassifyTLSHandshakeError(err) != FailureSSLECHRejected {
+			t.Fatal("unexpected result")
+		}
+	})
+
 	t.Run("for x509.HostnameError", func(t *testing.T) {
 		var err x509.HostnameError
 		if ClassifyTLSHandshakeError(err) != FailureSSLInvalidHostname {
@@ -0,0 +1,269 @@
+package netxlite
+
+//
+// Oblivious DNS-over-HTTPS transport
+//
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/odohx"
+)
+
+// DNSOverObliviousHTTPSTransport is an Oblivious DNS-over-HTTPS (ODoH) DNSTransport
+// implementing RFC9230. We send the HPKE-encapsulated queries to the proxy, which
+// forwards them to the target, so that the target does not see our IP address and
+// the proxy does not see the content of the queries.
+//
+// Before sending the first query, we fetch the target configs, which contain the
+// target public key, by connecting directly to the target. We cache the configs
+// and fetch them again if the target tells us the key ID is unknown.
+type DNSOverObliviousHTTPSTransport struct {
+	// Client is the MANDATORY http client used to talk to the proxy.
+	Client model.HTTPClient
+
+	// ConfigsClient is the OPTIONAL http client used to fetch the target configs. When
+	// this field is nil, we use the Client to fetch the configs.
+	ConfigsClient model.HTTPClient
+
+	// Decoder is the MANDATORY DNSDecoder.
+	Decoder model.DNSDecoder
+
+	// ProxyURL is the MANDATORY URL of the ODoH proxy (e.g., https://odoh.example.com/proxy).
+	ProxyURL string
+
+	// TargetURL is the MANDATORY URL of the ODoH target (e.g., https://odoh.cloudflare-dns.com/dns-query).
+	TargetURL string
+
+	// HostOverride is OPTIONAL and allows to override the
+	// Host header sent in every request to the proxy.
+	HostOverride string
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// config is the cached target config.
+	config *odohx.Config
+}
+
+// NewUnwrappedDNSOverObliviousHTTPSTransport creates a new DNSOverObliviousHTTPSTransport
+// instance that has not been wrapped yet.
+//
+// Arguments:
+//
+// - client is a model.HTTPClient type;
+//
+// - proxyURL is the ODoH proxy URL (e.g., https://odoh.example.com/proxy);
+//
+// - targetURL is the ODoH target URL (e.g., https://odoh.cloudflare-dns.com/dns-query).
+func NewUnwrappedDNSOverObliviousHTTPSTransport(
+	client model.HTTPClient, proxyURL, targetURL string) *DNSOverObliviousHTTPSTransport {
+	return &DNSOverObliviousHTTPSTransport{
+		Client:    client,
+		Decoder:   &DNSDecoderMiekg{},
+		ProxyURL:  proxyURL,
+		TargetURL: targetURL,
+	}
+}
+
+// NewDNSOverObliviousHTTPSTransport is like NewUnwrappedDNSOverObliviousHTTPSTransport
+// but returns an already wrapped DNSTransport.
+func NewDNSOverObliviousHTTPSTransport(client model.HTTPClient, proxyURL, targetURL string) model.DNSTransport {
+	return wrapDNSTransport(NewUnwrappedDNSOverObliviousHTTPSTransport(client, proxyURL, targetURL))
+}
+
+// errODoHUnknownKeyID indicates that the target does not know our key ID.
+var errODoHUnknownKeyID = errors.New("odoh: target does not know our key ID")
+
+// RoundTrip sends a query and receives a reply.
+func (t *DNSOverObliviousHTTPSTransport) RoundTrip(
+	ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 45*time.Second)
+	defer cancel()
+	resp, err := t.roundTrip(ctx, query)
+	if errors.Is(err, errODoHUnknownKeyID) {
+		// the target has possibly rotated its key, so try again once
+		resp, err = t.roundTrip(ctx, query)
+	}
+	return resp, err
+}
+
+func (t *DNSOverObliviousHTTPSTransport) roundTrip(
+	ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+	rawQuery, err := query.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	config, err := t.targetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Note: we don't need extra padding since the query is already padded
+	encryptedQuery, qc, err := odohx.EncryptQuery(config, rawQuery, 0)
+	if err != nil {
+		return nil, err
+	}
+	URL, err := t.requestURL()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", URL, bytes.NewReader(encryptedQuery))
+	if err != nil {
+		return nil, err
+	}
+	req.Host = t.HostOverride
+	req.Header.Set("user-agent", model.HTTPHeaderUserAgent)
+	req.Header.Set("content-type", odohx.ContentType)
+	req.Header.Set("accept", odohx.ContentType)
+	resp, err := t.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		t.forgetTargetConfig(config)
+		return nil, errODoHUnknownKeyID
+	}
+	if resp.StatusCode != 200 {
+		return nil, errors.New("odoh: server returned error")
+	}
+	if resp.Header.Get("content-type") != odohx.ContentType {
+		return nil, errors.New("odoh: invalid content-type")
+	}
+	const maxresponsesize = 1 << 20
+	limitReader := io.LimitReader(resp.Body, maxresponsesize)
+	rawResponse, err := ReadAllContext(ctx, limitReader)
+	if err != nil {
+		return nil, err
+	}
+	decryptedResponse, err := qc.DecryptResponse(rawResponse)
+	if err != nil {
+		return nil, err
+	}
+	return t.Decoder.DecodeResponse(decryptedResponse, query)
+}
+
+// requestURL returns the URL to which we should POST queries, which is
+// the proxy URL with the targethost and targetpath query parameters.
+func (t *DNSOverObliviousHTTPSTransport) requestURL() (string, error) {
+	proxyURL, err := url.Parse(t.ProxyURL)
+	if err != nil {
+		return "", err
+	}
+	targetURL, err := url.Parse(t.TargetURL)
+	if err != nil {
+		return "", err
+	}
+	if targetURL.Host == "" {
+		return "", fmt.Errorf("odoh: invalid target URL: %s", t.TargetURL)
+	}
+	targetPath := targetURL.Path
+	if targetPath == "" {
+		targetPath = "/"
+	}
+	values := proxyURL.Query()
+	values.Set("targethost", targetURL.Host)
+	values.Set("targetpath", targetPath)
+	proxyURL.RawQuery = values.Encode()
+	return proxyURL.String(), nil
+}
+
+// targetConfig returns the cached target config or fetches it.
+func (t *DNSOverObliviousHTTPSTransport) targetConfig(ctx context.Context) (*odohx.Config, error) {
+	defer t.mu.Unlock()
+	t.mu.Lock()
+	if t.config != nil {
+		return t.config, nil
+	}
+	config, err := t.fetchTargetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.config = config
+	return config, nil
+}
+
+// forgetTargetConfig clears the cached config if it's the given config.
+func (t *DNSOverObliviousHTTPSTransport) forgetTargetConfig(config *odohx.Config) {
+	defer t.mu.Unlock()
+	t.mu.Lock()
+	if t.config == config {
+		t.config = nil
+	}
+}
+
+// fetchTargetConfig fetches the target configs from the well-known URL.
+func (t *DNSOverObliviousHTTPSTransport) fetchTargetConfig(ctx context.Context) (*odohx.Config, error) {
+	targetURL, err := url.Parse(t.TargetURL)
+	if err != nil {
+		return nil, err
+	}
+	configsURL := &url.URL{Scheme: targetURL.Scheme, Host: targetURL.Host, Path: odohx.ConfigsPath}
+	req, err := http.NewRequest("GET", configsURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("user-agent", model.HTTPHeaderUserAgent)
+	resp, err := t.configsClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, errors.New("odoh: cannot fetch target configs")
+	}
+	const maxconfigssize = 1 << 16
+	limitReader := io.LimitReader(resp.Body, maxconfigssize)
+	rawConfigs, err := ReadAllContext(ctx, limitReader)
+	if err != nil {
+		return nil, err
+	}
+	return odohx.ParseConfigs(rawConfigs)
+}
+
+// configsClient returns the client to use for fetching the configs.
+func (t *DNSOverObliviousHTTPSTransport) configsClient() model.HTTPClient {
+	if t.ConfigsClient != nil {
+		return t.ConfigsClient
+	}
+	return t.Client
+}
+
+// RequiresPadding returns true for ODoH according to RFC9230 Section 4.3.
+func (t *DNSOverObliviousHTTPSTransport) RequiresPadding() bool {
+	return true
+}
+
+// Network returns the transport network, i.e., "odoh".
+func (t *DNSOverObliviousHTTPSTransport) Network() string {
+	return "odoh"
+}
+
+// Address returns the URL we're using for sending queries to the
+// proxy, which also contains the target host and path.
+func (t *DNSOverObliviousHTTPSTransport) Address() string {
+	URL, err := t.requestURL()
+	if err != nil {
+		return t.ProxyURL
+	}
+	return URL
+}
+
+// CloseIdleConnections closes idle connections, if any.
+func (t *DNSOverObliviousHTTPSTransport) CloseIdleConnections() {
+	t.Client.CloseIdleConnections()
+	if t.ConfigsClient != nil {
+		t.ConfigsClient.CloseIdleConnections()
+	}
+}
+
+var _ model.DNSTransport = &DNSOverObliviousHTTPSTransport{}
@@ -0,0 +1,415 @@
+package netxlite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-engine/pkg/mocks"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/odohx"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+)
+
+const (
+	// odohTestProxyURL is the proxy URL used by tests.
+	odohTestProxyURL = "https://odoh1.surfdomeinen.nl/proxy"
+
+	// odohTestTargetURL is the target URL used by tests.
+	odohTestTargetURL = "https://odoh.cloudflare-dns.com/dns-query"
+
+	// odohTestRequestURL is the URL to which we expect the transport to POST queries.
+	odohTestRequestURL = "https://odoh1.surfdomeinen.nl/proxy?targethost=odoh.cloudflare-dns.com&targetpath=%2Fdns-query"
+)
+
+// odohTestServer simulates an ODoH proxy and target.
+type odohTestServer struct {
+	// keyPair is the key pair used by the target.
+	keyPair *odohx.KeyPair
+
+	// configsFetched counts the number of times we fetched the configs.
+	configsFetched int
+
+	// queries counts the number of queries we received.
+	queries int
+}
+
+// newODoHTestServer creates a new [*odohTestServer].
+func newODoHTestServer() *odohTestServer {
+	return &odohTestServer{keyPair: runtimex.Try1(odohx.NewKeyPair())}
+}
+
+// newResponse creates a new [*http.Response].
+func (s *odohTestServer) newResponse(status int, contentType string, body []byte) *http.Response {
+	resp := &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+	if contentType != "" {
+		resp.Header.Set("content-type", contentType)
+	}
+	return resp
+}
+
+// Do simulates sending a request to the proxy or to the target.
+func (s *odohTestServer) Do(req *http.Request) (*http.Response, error) {
+	if req.Method == "GET" && req.URL.String() == "https://odoh.cloudflare-dns.com/.well-known/odohconfigs" {
+		s.configsFetched++
+		return s.newResponse(200, "", odohx.MarshalConfigs(s.keyPair.Config)), nil
+	}
+	if req.Method != "POST" || req.URL.String() != odohTestRequestURL {
+		return s.newResponse(404, "", nil), nil
+	}
+	s.queries++
+	rawQuery := runtimex.Try1(io.ReadAll(req.Body))
+	query, rc, err := s.keyPair.DecryptQuery(rawQuery)
+	if errors.Is(err, odohx.ErrUnknownKeyID) {
+		return s.newResponse(401, "", nil), nil
+	}
+	runtimex.PanicOnError(err, "s.keyPair.DecryptQuery failed")
+	queryMsg := &dns.Msg{}
+	runtimex.PanicOnError(queryMsg.Unpack(query), "queryMsg.Unpack failed")
+	respMsg := &dns.Msg{}
+	respMsg.SetReply(queryMsg)
+	respMsg.Answer = append(respMsg.Answer, &dns.A{
+		Hdr: dns.RR_Header{
+			Name:   queryMsg.Question[0].Name,
+			Rrtype: dns.TypeA,
+			Class:  dns.ClassINET,
+			Ttl:    300,
+		},
+		A: []byte{8, 8, 8, 8},
+	})
+	rawResponse := runtimex.Try1(rc.EncryptResponse(runtimex.Try1(respMsg.Pack()), 0))
+	return s.newResponse(200, odohx.ContentType, rawResponse), nil
+}
+
+func TestDNSOverObliviousHTTPSTransport(t *testing.T) {
+	t.Run("NewDNSOverObliviousHTTPSTransport returns a wrapped transport", func(t *testing.T) {
+		clnt := &mocks.HTTPClient{}
+		txp := NewDNSOverObliviousHTTPSTransport(clnt, odohTestProxyURL, odohTestTargetURL)
+		ew := txp.(*dnsTransportErrWrapper)
+		odoh := ew.DNSTransport.(*DNSOverObliviousHTTPSTransport)
+		if odoh.Client != clnt {
+			t.Fatal("invalid client")
+		}
+		if odoh.ProxyURL != odohTestProxyURL {
+			t.Fatal("invalid proxy URL")
+		}
+		if odoh.TargetURL != odohTestTargetURL {
+			t.Fatal("invalid target URL")
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		newQuery := func() model.DNSQuery {
+			return (&DNSEncoderMiekg{}).Encode("dns.google", dns.TypeA, true)
+		}
+
+		t.Run("query serialization failure", func(t *testing.T) {
+			txp := NewUnwrappedDNSOverObliviousHTTPSTransport(
+				&mocks.HTTPClient{}, odohTestProxyURL, odohTestTargetURL)
+			expected := errors.New("mocked error")
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return nil, expected
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, expected) {
+				t.Fatal("unexpected err", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil resp here")
+			}
+		})
+
+		t.Run("successful case", func(t *testing.T) {
+			srv := newODoHTestServer()
+			txp := NewUnwrappedDNSOverObliviousHTTPSTransport(
+				&mocks.HTTPClient{MockDo: srv.Do}, odohTestProxyURL, odohTestTargetURL)
+			for idx := 0; idx < 2; idx++ {
+				resp, err := txp.RoundTrip(context.Background(), newQuery())
+				if err != nil {
+					t.Fatal(err)
+				}
+				addrs, err := resp.DecodeLookupHost()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff([]string{"8.8.8.8"}, addrs); diff != "" {
+					t.Fatal(diff)
+				}
+			}
+			// we expect to cache the configs
+			if srv.configsFetched != 1 {
+				t.Fatal("unexpected number of configs fetches", srv.configsFetched)
+			}
+		})
+
+		t.Run("we use the ConfigsClient when set", func(t *testing.T) {
+			srv := newODoHTestServer()
+			var configsClientUsed bool
+			txp := NewUnwrappedDNSOverObliviousHTTPSTransport(&mocks.HTTPClient{
+				MockDo: func(req *http.Request) (*http.Response, error) {
+					if req.Method == "GET" {
+						return nil, errors.New("should not use the Client for fetching configs")
+					}
+					return srv.Do(req)
+				},
+			}, odohTestProxyURL, odohTestTargetURL)
+			txp.ConfigsClient = &mocks.HTTPClient{
+				MockDo: func(req *http.Request) (*http.Response, error) {
+					configsClientUsed = true
+					return srv.Do(req)
+				},
+			}
+			if _, err := txp.RoundTrip(context.Background(), newQuery()); err != nil {
+				t.Fatal(err)
+			}
+			if !configsClientUsed {
+				t.Fatal("did not use the ConfigsClient")
+			}
+		})
+
+		t.Run("we refetch the configs when the target rotates its key", func(t *testing.T) {
+			srv := newODoHTestServer()
+			txp := NewUnwrappedDNSOverObliviousHTTPSTransport(
+				&mocks.HTTPClient{MockDo: srv.Do}, odohTestProxyURL, odohTestTargetURL)
+			if _, err := txp.RoundTrip(context.Background(), newQuery()); err != nil {
+				t.Fatal(err)
+			}
+			srv.keyPair = runtimex.Try1(odohx.NewKeyPair())
+			if _, err := txp.RoundTrip(context.Background(), newQuery()); err != nil {
+				t.Fatal(err)
+			}
+			if srv.configsFetched != 2 {
+				t.Fatal("unexpected number of configs fetches", srv.configsFetched)
+			}
+			if srv.queries != 3 {
+				t.Fatal("unexpected number of queries", srv.queries)
+			}
+		})
+
+		t.Run("we only retry once when the key ID is unknown", func(t *testing.T) {
+			srv := newODoHTestServer()
+			var queries int
+			txp := NewUnwrappedDNSOverObliviousHTTPSTransport(&mocks.HTTPClient{
+				MockDo: func(req *http.Request) (*http.Response, error) {
+					if req.Method == "GET" {
+						return srv.Do(req)
+					}
+					queries++
+					return srv.newResponse(401, "", nil), nil
+				},
+			}, odohTestProxyURL, odohTestTargetURL)
+			resp, err := txp.RoundTrip(context.Background(), newQuery())
+			if !errors.Is(err, errODoHUnknownKeyID) {
+				t.Fatal("unexpected err", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil resp here")
+			}
+			if queries != 2 {
+				t.Fatal("unexpected number of queries", queries)
+			}
+		})
+
+		// failingConfigsCase is a test case where fetching the configs fails
+		type failingConfigsCase struct {
+			name      string
+			do        func(req *http.Request) (*http.Response, error)
+			targetURL string
+			expectErr string
+		}
+
+		srv := newODoHTestServer()
+		for _, tc := range []failingConfigsCase{{
+			name: "when we cannot fetch the configs",
+			do: func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("mocked error")
+			},
+			targetURL: odohTestTargetURL,
+			expectErr: "mocked error",
+		}, {
+			name: "when fetching the configs returns an error status",
+			do: func(req *http.Request) (*http.Response, error) {
+				return srv.newResponse(500, "", nil), nil
+			},
+			targetURL: odohTestTargetURL,
+			expectErr: "odoh: cannot fetch target configs",
+		}, {
+			name: "when the configs are invalid",
+			do: func(req *http.Request) (*http.Response, error) {
+				return srv.newResponse(200, "", []byte{0}), nil
+			},
+			targetURL: odohTestTargetURL,
+			expectErr: odohx.ErrInvalidConfigs.Error(),
+		}, {
+			name:      "when the target URL is invalid",
+			do:        srv.Do,
+			targetURL: "\t",
+			expectErr: `parse "\t": net/url: invalid control character in URL`,
+		}} {
+			t.Run(tc.name, func(t *testing.T) {
+				txp := NewUnwrappedDNSOverObliviousHTTPSTransport(
+					&mocks.HTTPClient{MockDo: tc.do}, odohTestProxyURL, tc.targetURL)
+				resp, err := txp.RoundTrip(context.Background(), newQuery())
+				if err == nil || err.Error() != tc.expectErr {
+					t.Fatal("unexpected err", err)
+				}
+				if resp != nil {
+					t.Fatal("expected nil resp here")
+				}
+			})
+		}
+
+		// failingQueryCase is a test case where sending the query fails
+		type failingQueryCase struct {
+			name      string
+			do        func(srv *odohTestServer, req *http.Request) (*http.Response, error)
+			expectErr string
+		}
+
+		for _, tc := range []failingQueryCase{{
+			name: "when the proxy request fails",
+			do: func(srv *odohTestServer, req *http.Request) (*http.Response, error) {
+				return nil, errors.New("mocked error")
+			},
+			expectErr: "mocked error",
+		}, {
+			name: "when the proxy returns an error status",
+			do: func(srv *odohTestServer, req *http.Request) (*http.Response, error) {
+				return srv.newResponse(502, odohx.ContentType, nil), nil
+			},
+			expectErr: "odoh: server returned error",
+		}, {
+			name: "when the proxy returns the wrong content-type",
+			do: func(srv *odohTestServer, req *http.Request) (*http.Response, error) {
+				return srv.newResponse(200, "text/plain", nil), nil
+			},
+			expectErr: "odoh: invalid content-type",
+		}, {
+			name: "when we cannot decrypt the response",
+			do: func(srv *odohTestServer, req *http.Request) (*http.Response, error) {
+				return srv.newResponse(200, odohx.ContentType, []byte{0x02}), nil
+			},
+			expectErr: odohx.ErrInvalidMessage.Error(),
+		}, {
+			name: "when we cannot read the response body",
+			do: func(srv *odohTestServer, req *http.Request) (*http.Response, error) {
+				resp := srv.newResponse(200, odohx.ContentType, nil)
+				resp.Body = io.NopCloser(&mocks.Reader{
+					MockRead: func(b []byte) (int, error) {
+						return 0, errors.New("mocked read error")
+					},
+				})
+				return resp, nil
+			},
+			expectErr: "unknown_failure: mocked read error",
+		}} {
+			t.Run(tc.name, func(t *testing.T) {
+				srv := newODoHTestServer()
+				txp := NewUnwrappedDNSOverObliviousHTTPSTransport(&mocks.HTTPClient{
+					MockDo: func(req *http.Request) (*http.Response, error) {
+						if req.Method == "GET" {
+							return srv.Do(req)
+						}
+						return tc.do(srv, req)
+					},
+				}, odohTestProxyURL, odohTestTargetURL)
+				resp, err := txp.RoundTrip(context.Background(), newQuery())
+				if err == nil || err.Error() != tc.expectErr {
+					t.Fatal("unexpected err", err)
+				}
+				if resp != nil {
+					t.Fatal("expected nil resp here")
+				}
+			})
+		}
+
+		t.Run("we set the Host header override", func(t *testing.T) {
+			srv := newODoHTestServer()
+			var gotHost string
+			txp := NewUnwrappedDNSOverObliviousHTTPSTransport(&mocks.HTTPClient{
+				MockDo: func(req *http.Request) (*http.Response, error) {
+					if req.Method == "POST" {
+						gotHost = req.Host
+					}
+					return srv.Do(req)
+				},
+			}, odohTestProxyURL, odohTestTargetURL)
+			txp.HostOverride = "odoh.example.com"
+			if _, err := txp.RoundTrip(context.Background(), newQuery()); err != nil {
+				t.Fatal(err)
+			}
+			if gotHost != "odoh.example.com" {
+				t.Fatal("unexpected Host", gotHost)
+			}
+		})
+	})
+
+	t.Run("Address", func(t *testing.T) {
+		t.Run("contains the proxy URL and the target", func(t *testing.T) {
+			txp := NewUnwrappedDNSOverObliviousHTTPSTransport(
+				&mocks.HTTPClient{}, odohTestProxyURL, odohTestTargetURL)
+			if txp.Address() != odohTestRequestURL {
+				t.Fatal("unexpected Address", txp.Address())
+			}
+		})
+
+		t.Run("uses the root path when the target path is empty", func(t *testing.T) {
+			txp := NewUnwrappedDNSOverObliviousHTTPSTransport(
+				&mocks.HTTPClient{}, odohTestProxyURL, "https://odoh.cloudflare-dns.com")
+			expect := "https://odoh1.surfdomeinen.nl/proxy?targethost=odoh.cloudflare-dns.com&targetpath=%2F"
+			if txp.Address() != expect {
+				t.Fatal("unexpected Address", txp.Address())
+			}
+		})
+
+		t.Run("returns the proxy URL when the target URL has no host", func(t *testing.T) {
+			txp := NewUnwrappedDNSOverObliviousHTTPSTransport(
+				&mocks.HTTPClient{}, odohTestProxyURL, "/dns-query")
+			if txp.Address() != odohTestProxyURL {
+				t.Fatal("unexpected Address", txp.Address())
+			}
+		})
+
+		t.Run("returns the proxy URL when the proxy URL is invalid", func(t *testing.T) {
+			txp := NewUnwrappedDNSOverObliviousHTTPSTransport(
+				&mocks.HTTPClient{}, "\t", odohTestTargetURL)
+			if txp.Address() != "\t" {
+				t.Fatal("unexpected Address", txp.Address())
+			}
+		})
+	})
+
+	t.Run("other functions behave correctly", func(t *testing.T) {
+		var called int
+		newClient := func() *mocks.HTTPClient {
+			return &mocks.HTTPClient{
+				MockCloseIdleConnections: func() {
+					called++
+				},
+			}
+		}
+		txp := NewUnwrappedDNSOverObliviousHTTPSTransport(newClient(), odohTestProxyURL, odohTestTargetURL)
+		txp.ConfigsClient = newClient()
+		if !txp.RequiresPadding() {
+			t.Fatal("should require padding")
+		}
+		if txp.Network() != "odoh" {
+			t.Fatal("invalid network")
+		}
+		txp.CloseIdleConnections()
+		if called != 2 {
+			t.Fatal("not called")
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package netxlite
+
+//
+// DNS-over-QUIC transport
+//
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/quic-go/quic-go"
+)
+
+// DNSOverQUICTransport is a DNS-over-QUIC DNSTransport implementing RFC9250.
+//
+// Note: this implementation always creates a new QUIC connection for each query
+// and sends each query using a distinct bidirectional stream. Like for the
+// DNSOverTCPTransport, this strategy is less efficient but MAY be more robust
+// when querying for a blocked domain name causes endpoint blocking.
+type DNSOverQUICTransport struct {
+	dialer    model.QUICDialer
+	decoder   model.DNSDecoder
+	address   string
+	tlsConfig *tls.Config
+}
+
+// NewUnwrappedDNSOverQUICTransport creates a new DNSOverQUICTransport
+// that has not been wrapped yet.
+//
+// Arguments:
+//
+// - dialer is the model.QUICDialer to use;
+//
+// - address is the endpoint address (e.g., 94.140.14.14:853).
+func NewUnwrappedDNSOverQUICTransport(dialer model.QUICDialer, address string) *DNSOverQUICTransport {
+	return NewUnwrappedDNSOverQUICTransportWithTLSConfig(dialer, address, &tls.Config{})
+}
+
+// NewUnwrappedDNSOverQUICTransportWithTLSConfig is like NewUnwrappedDNSOverQUICTransport
+// but additionally allows to specify the TLS config to use. When the config's ServerName
+// is empty, we use the hostname of the endpoint address. When the config's NextProtos is
+// empty, we use the "doq" ALPN mandated by RFC9250.
+func NewUnwrappedDNSOverQUICTransportWithTLSConfig(
+	dialer model.QUICDialer, address string, tlsConfig *tls.Config) *DNSOverQUICTransport {
+	return &DNSOverQUICTransport{
+		dialer:    dialer,
+		decoder:   &DNSDecoderMiekg{},
+		address:   address,
+		tlsConfig: tlsConfig,
+	}
+}
+
+// NewDNSOverQUICTransport is like NewUnwrappedDNSOverQUICTransport but
+// returns an already wrapped DNSTransport.
+func NewDNSOverQUICTransport(dialer model.QUICDialer, address string) model.DNSTransport {
+	return wrapDNSTransport(NewUnwrappedDNSOverQUICTransport(dialer, address))
+}
+
+// RoundTrip sends a query and receives a reply.
+func (t *DNSOverQUICTransport) RoundTrip(
+	ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+	rawQuery, err := query.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(rawQuery) > math.MaxUint16 {
+		return nil, errQueryTooLarge
+	}
+
+	// See https://datatracker.ietf.org/doc/html/rfc9250#section-4.2.1: the
+	// DNS message ID MUST be set to zero when sending queries. We clone the
+	// query bytes because they're memoized and shared with other readers.
+	if len(rawQuery) >= 2 {
+		rawQuery = slices.Clone(rawQuery)
+		binary.BigEndian.PutUint16(rawQuery, 0)
+	}
+
+	qconn, err := t.dialer.DialContext(ctx, t.address, t.newTLSConfig(), &quic.Config{})
+	if err != nil {
+		return nil, err
+	}
+	// RFC9250 Section 4.3 says we should use DOQ_NO_ERROR, which is zero
+	defer qconn.CloseWithError(0, "")
+
+	stream, err := qconn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	const iotimeout = 10 * time.Second
+	_ = stream.SetDeadline(time.Now().Add(iotimeout))
+
+	// Write the request and signal that we're not going to send more
+	// data, as mandated by RFC9250 Section 4.2
+	buf := binary.BigEndian.AppendUint16(nil, uint16(len(rawQuery)))
+	buf = append(buf, rawQuery...)
+	if _, err = stream.Write(buf); err != nil {
+		return nil, err
+	}
+	_ = stream.Close() // only closes the sending side
+
+	// Read response
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(stream, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header))
+	rawResponse := make([]byte, length)
+	if _, err = io.ReadFull(stream, rawResponse); err != nil {
+		return nil, err
+	}
+
+	// Restore the original ID such that the decoder matches the response
+	// with the query like it happens for the other transports
+	if len(rawResponse) >= 2 {
+		binary.BigEndian.PutUint16(rawResponse, query.ID())
+	}
+	return t.decoder.DecodeResponse(rawResponse, query)
+}
+
+// newTLSConfig returns the TLS config to use for dialing.
+func (t *DNSOverQUICTransport) newTLSConfig() *tls.Config {
+	config := t.tlsConfig.Clone()
+	if config.ServerName == "" {
+		if host, _, err := net.SplitHostPort(t.address); err == nil {
+			config.ServerName = host
+		}
+	}
+	if len(config.NextProtos) <= 0 {
+		config.NextProtos = []string{"doq"}
+	}
+	return config
+}
+
+// RequiresPadding returns true for DoQ according to RFC9250 Section 5.4.
+func (t *DNSOverQUICTransport) RequiresPadding() bool {
+	return true
+}
+
+// Network returns the transport network, i.e., "doq".
+func (t *DNSOverQUICTransport) Network() string {
+	return "doq"
+}
+
+// Address returns the upstream server endpoint (e.g., "94.140.14.14:853").
+func (t *DNSOverQUICTransport) Address() string {
+	return t.address
+}
+
+// CloseIdleConnections closes idle connections, if any.
+func (t *DNSOverQUICTransport) CloseIdleConnections() {
+	t.dialer.CloseIdleConnections()
+}
+
+var _ model.DNSTransport = &DNSOverQUICTransport{}
@@ -0,0 +1,404 @@
+package netxlite
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-engine/pkg/mocks"
+	"github.com/quic-go/quic-go"
+)
+
+// dnsOverQUICTestStream is a quic.Stream for testing DNSOverQUICTransport.
+type dnsOverQUICTestStream struct {
+	// embed the interface to avoid implementing unused methods
+	quic.Stream
+
+	// MockRead, MockWrite, and MockClose mock the corresponding methods.
+	MockRead  func(b []byte) (int, error)
+	MockWrite func(b []byte) (int, error)
+	MockClose func() error
+}
+
+func (s *dnsOverQUICTestStream) Read(b []byte) (int, error) {
+	return s.MockRead(b)
+}
+
+func (s *dnsOverQUICTestStream) Write(b []byte) (int, error) {
+	return s.MockWrite(b)
+}
+
+func (s *dnsOverQUICTestStream) Close() error {
+	return s.MockClose()
+}
+
+func (s *dnsOverQUICTestStream) SetDeadline(t time.Time) error {
+	return nil
+}
+
+// newDNSOverQUICTestDialer returns a dialer returning a connection that
+// uses the given stream and records whether we closed the connection.
+func newDNSOverQUICTestDialer(stream quic.Stream, closed *bool) *mocks.QUICDialer {
+	return &mocks.QUICDialer{
+		MockDialContext: func(ctx context.Context, address string,
+			tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+			return &mocks.QUICEarlyConnection{
+				MockOpenStreamSync: func(ctx context.Context) (quic.Stream, error) {
+					return stream, nil
+				},
+				MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+					*closed = true
+					return nil
+				},
+			}, nil
+		},
+	}
+}
+
+func TestDNSOverQUICTransport(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		t.Run("cannot encode query", func(t *testing.T) {
+			expected := errors.New("mocked error")
+			const address = "9.9.9.9:853"
+			txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{}, address)
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return nil, expected
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, expected) {
+				t.Fatal("unexpected err", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil response here")
+			}
+		})
+
+		t.Run("query too large", func(t *testing.T) {
+			const address = "9.9.9.9:853"
+			txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{}, address)
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, math.MaxUint16+1), nil
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, errQueryTooLarge) {
+				t.Fatal("unexpected err", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil response here")
+			}
+		})
+
+		t.Run("dial failure", func(t *testing.T) {
+			const address = "9.9.9.9:853"
+			mocked := errors.New("mocked error")
+			var gotTLSConfig *tls.Config
+			dialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					gotTLSConfig = tlsConfig
+					return nil, mocked
+				},
+			}
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(dialer, address)
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, mocked) {
+				t.Fatal("not the error we expected")
+			}
+			if resp != nil {
+				t.Fatal("expected no response here")
+			}
+			if gotTLSConfig.ServerName != "9.9.9.9" {
+				t.Fatal("unexpected ServerName", gotTLSConfig.ServerName)
+			}
+			if diff := cmp.Diff([]string{"doq"}, gotTLSConfig.NextProtos); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("we honour the TLS config", func(t *testing.T) {
+			const address = "94.140.14.14:853"
+			mocked := errors.New("mocked error")
+			var gotTLSConfig *tls.Config
+			dialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					gotTLSConfig = tlsConfig
+					return nil, mocked
+				},
+			}
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			tlsConfig := &tls.Config{ServerName: "dns.adguard-dns.com", NextProtos: []string{"doq-i02"}}
+			txp := NewUnwrappedDNSOverQUICTransportWithTLSConfig(dialer, address, tlsConfig)
+			_, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, mocked) {
+				t.Fatal("not the error we expected")
+			}
+			if gotTLSConfig == tlsConfig {
+				t.Fatal("expected the transport to clone the TLS config")
+			}
+			if gotTLSConfig.ServerName != "dns.adguard-dns.com" {
+				t.Fatal("unexpected ServerName", gotTLSConfig.ServerName)
+			}
+			if diff := cmp.Diff([]string{"doq-i02"}, gotTLSConfig.NextProtos); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("open stream failure", func(t *testing.T) {
+			const address = "9.9.9.9:853"
+			mocked := errors.New("mocked error")
+			var closed bool
+			dialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					return &mocks.QUICEarlyConnection{
+						MockOpenStreamSync: func(ctx context.Context) (quic.Stream, error) {
+							return nil, mocked
+						},
+						MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+							closed = true
+							return nil
+						},
+					}, nil
+				},
+			}
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(dialer, address)
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, mocked) {
+				t.Fatal("not the error we expected")
+			}
+			if resp != nil {
+				t.Fatal("expected no response here")
+			}
+			if !closed {
+				t.Fatal("did not close the connection")
+			}
+		})
+
+		t.Run("write failure", func(t *testing.T) {
+			const address = "9.9.9.9:853"
+			mocked := errors.New("mocked error")
+			var closed bool
+			stream := &dnsOverQUICTestStream{
+				MockWrite: func(b []byte) (int, error) {
+					return 0, mocked
+				},
+			}
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(newDNSOverQUICTestDialer(stream, &closed), address)
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, mocked) {
+				t.Fatal("not the error we expected")
+			}
+			if resp != nil {
+				t.Fatal("expected no response here")
+			}
+			if !closed {
+				t.Fatal("did not close the connection")
+			}
+		})
+
+		t.Run("first read fails", func(t *testing.T) {
+			const address = "9.9.9.9:853"
+			mocked := errors.New("mocked error")
+			var closed bool
+			stream := &dnsOverQUICTestStream{
+				MockWrite: func(b []byte) (int, error) {
+					return len(b), nil
+				},
+				MockClose: func() error {
+					return nil
+				},
+				MockRead: func(b []byte) (int, error) {
+					return 0, mocked
+				},
+			}
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(newDNSOverQUICTestDialer(stream, &closed), address)
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, mocked) {
+				t.Fatal("not the error we expected")
+			}
+			if resp != nil {
+				t.Fatal("expected no response here")
+			}
+		})
+
+		t.Run("second read fails", func(t *testing.T) {
+			const address = "9.9.9.9:853"
+			var closed bool
+			input := io.MultiReader(
+				bytes.NewReader([]byte{byte(0), byte(2)}),
+				bytes.NewReader([]byte{byte(0)}),
+			)
+			stream := &dnsOverQUICTestStream{
+				MockWrite: func(b []byte) (int, error) {
+					return len(b), nil
+				},
+				MockClose: func() error {
+					return nil
+				},
+				MockRead: input.Read,
+			}
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(newDNSOverQUICTestDialer(stream, &closed), address)
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatal("not the error we expected", err)
+			}
+			if resp != nil {
+				t.Fatal("expected no response here")
+			}
+		})
+
+		t.Run("successful case", func(t *testing.T) {
+			const address = "9.9.9.9:853"
+			var closed bool
+
+			// create the query and the response, where the response has a zero
+			// ID as mandated by RFC9250 Section 4.2.1
+			query := (&DNSEncoderMiekg{}).Encode("dns.google", dns.TypeA, true)
+			origQuery, err := query.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			origQuery = slices.Clone(origQuery)
+			rawResp := &dns.Msg{}
+			rawResp.SetQuestion("dns.google.", dns.TypeA)
+			rawResp.Response = true
+			rawResp.Id = 0
+			rawResp.Answer = append(rawResp.Answer, &dns.A{
+				Hdr: dns.RR_Header{
+					Name:   "dns.google.",
+					Rrtype: dns.TypeA,
+					Class:  dns.ClassINET,
+					Ttl:    300,
+				},
+				A: []byte{8, 8, 8, 8},
+			})
+			rawRespBytes, err := rawResp.Pack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			input := bytes.NewReader(append([]byte{
+				byte(len(rawRespBytes) >> 8), byte(len(rawRespBytes))}, rawRespBytes...))
+
+			// make sure we send a query with a zero ID and close the sending side
+			var (
+				sentID     = -1
+				sendClosed bool
+			)
+			stream := &dnsOverQUICTestStream{
+				MockWrite: func(b []byte) (int, error) {
+					sentID = int(b[2])<<8 | int(b[3])
+					return len(b), nil
+				},
+				MockClose: func() error {
+					sendClosed = true
+					return nil
+				},
+				MockRead: input.Read,
+			}
+
+			txp := NewUnwrappedDNSOverQUICTransport(newDNSOverQUICTestDialer(stream, &closed), address)
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sentID != 0 {
+				t.Fatal("expected to send a zero ID, got", sentID)
+			}
+			// make sure we did not modify the bytes of the query, which
+			// are memoized and archived by measurexlite
+			rawQuery, err := query.Bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(origQuery, rawQuery); diff != "" {
+				t.Fatal(diff)
+			}
+			if !sendClosed {
+				t.Fatal("did not close the sending side of the stream")
+			}
+			if !closed {
+				t.Fatal("did not close the connection")
+			}
+			addrs, err := resp.DecodeLookupHost()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]string{"8.8.8.8"}, addrs); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	})
+
+	t.Run("other functions okay", func(t *testing.T) {
+		const address = "9.9.9.9:853"
+		var called bool
+		dialer := &mocks.QUICDialer{
+			MockCloseIdleConnections: func() {
+				called = true
+			},
+		}
+		txp := NewUnwrappedDNSOverQUICTransport(dialer, address)
+		if txp.RequiresPadding() != true {
+			t.Fatal("invalid RequiresPadding")
+		}
+		if txp.Network() != "doq" {
+			t.Fatal("invalid Network")
+		}
+		if txp.Address() != address {
+			t.Fatal("invalid Address")
+		}
+		txp.CloseIdleConnections()
+		if !called {
+			t.Fatal("did not call CloseIdleConnections")
+		}
+	})
+
+	t.Run("NewDNSOverQUICTransport returns a wrapped transport", func(t *testing.T) {
+		txp := NewDNSOverQUICTransport(&mocks.QUICDialer{}, "9.9.9.9:853")
+		if _, good := txp.(*dnsTransportErrWrapper); !good {
+			t.Fatal("not the type we expected")
+		}
+	})
+}
@@ -60,7 +60,7 @@
 // 1. establishing a TCP connection;
 //
 // 2. performing a domain name resolution with the "stdlib" resolver
-// (i.e., getaddrinfo on Unix) or custom DNS transports (e.g., DoT, DoH);
+// (i.e., getaddrinfo on Unix) or custom DNS transports (e.g., DoT, DoH, DoQ, ODoH);
 //
 // 3. performing the TLS handshake;
 //
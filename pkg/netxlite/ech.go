@@ -0,0 +1,34 @@
+package netxlite
+
+//
+// Encrypted Client Hello (ECH)
+//
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+// ErrNoECHConfigList indicates that the HTTPS record of a domain does not
+// contain any Encrypted Client Hello configuration list.
+var ErrNoECHConfigList = errors.New("netxlite: no ECH config list")
+
+// LookupECHConfigList uses the given resolver to query the HTTPS record of the given
+// domain and returns the ECH configuration list contained in such a record. The returned
+// list is suitable for the EncryptedClientHelloConfigList field of [*tls.Config], which
+// the stdlib TLS handshaker supports. This function returns [ErrNoECHConfigList] when
+// the lookup succeeds but the HTTPS record does not contain an ECH configuration list.
+//
+// To trace the lookup, pass to this function a resolver created using a trace.
+func LookupECHConfigList(ctx context.Context, reso model.Resolver, domain string) ([]byte, error) {
+	https, err := reso.LookupHTTPS(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	if len(https.Ech) <= 0 {
+		return nil, ErrNoECHConfigList
+	}
+	return https.Ech, nil
+}
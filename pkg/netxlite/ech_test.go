@@ -0,0 +1,70 @@
+package netxlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/mocks"
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+func TestLookupECHConfigList(t *testing.T) {
+	t.Run("when the lookup fails", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		reso := &mocks.Resolver{
+			MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+				return nil, expected
+			},
+		}
+		ecl, err := LookupECHConfigList(context.Background(), reso, "www.example.com")
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(ecl) != 0 {
+			t.Fatal("expected empty ECH config list")
+		}
+	})
+
+	t.Run("when the record does not contain an ECH config list", func(t *testing.T) {
+		reso := &mocks.Resolver{
+			MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+				return &model.HTTPSSvc{
+					ALPN: []string{"h2", "http/1.1"},
+					IPv4: []string{"93.184.216.34"},
+					IPv6: []string{},
+					Ech:  []byte{},
+				}, nil
+			},
+		}
+		ecl, err := LookupECHConfigList(context.Background(), reso, "www.example.com")
+		if !errors.Is(err, ErrNoECHConfigList) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(ecl) != 0 {
+			t.Fatal("expected empty ECH config list")
+		}
+	})
+
+	t.Run("when the record contains an ECH config list", func(t *testing.T) {
+		expected := []byte{0x00, 0x45, 0xfe, 0x0d}
+		reso := &mocks.Resolver{
+			MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+				return &model.HTTPSSvc{
+					ALPN: []string{"h2", "http/1.1"},
+					IPv4: []string{},
+					IPv6: []string{},
+					Ech:  expected,
+				}, nil
+			},
+		}
+		ecl, err := LookupECHConfigList(context.Background(), reso, "www.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, ecl); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
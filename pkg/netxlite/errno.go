@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// Generated: 2026-02-10 11:10:23.213756 +0530 IST m=+0.590748126
+// Generated: 2026-10-17 05:27:00.209080974 +0000 UTC m=+0.360639265
 
 package netxlite
 
@@ -50,6 +50,7 @@ const (
 	FailurePermissionDenied                = "permission_denied"
 	FailureProtocolNotSupported            = "protocol_not_supported"
 	FailureQUICIncompatibleVersion         = "quic_incompatible_version"
+	FailureSSLECHRejected                  = "ssl_ech_rejected"
 	FailureSSLFailedHandshake              = "ssl_failed_handshake"
 	FailureSSLInvalidCertificate           = "ssl_invalid_certificate"
 	FailureSSLInvalidHostname              = "ssl_invalid_hostname"
@@ -102,6 +103,7 @@ var failuresMap = map[string]string{
 	"permission_denied":                   "permission_denied",
 	"protocol_not_supported":              "protocol_not_supported",
 	"quic_incompatible_version":           "quic_incompatible_version",
+	"ssl_ech_rejected":                    "ssl_ech_rejected",
 	"ssl_failed_handshake":                "ssl_failed_handshake",
 	"ssl_invalid_certificate":             "ssl_invalid_certificate",
 	"ssl_invalid_hostname":                "ssl_invalid_hostname",
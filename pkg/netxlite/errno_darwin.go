@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// Generated: 2026-02-10 11:10:22.624142 +0530 IST m=+0.001132918
+// Generated: 2026-10-17 05:26:59.849375494 +0000 UTC m=+0.000933764
 
 package netxlite
 
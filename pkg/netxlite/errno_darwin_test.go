@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// Generated: 2026-02-10 11:10:23.06469 +0530 IST m=+0.441681876
+// Generated: 2026-10-17 05:26:59.892219806 +0000 UTC m=+0.043778093
 
 package netxlite
 
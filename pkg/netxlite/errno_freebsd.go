@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// Generated: 2026-02-10 11:10:23.093362 +0530 IST m=+0.470353334
+// Generated: 2026-10-17 05:26:59.932538992 +0000 UTC m=+0.084097252
 
 package netxlite
 
@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// Generated: 2026-02-10 11:10:23.112387 +0530 IST m=+0.489378459
+// Generated: 2026-10-17 05:26:59.970385978 +0000 UTC m=+0.121944257
 
 package netxlite
 
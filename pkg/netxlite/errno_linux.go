@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// Generated: 2026-02-10 11:10:23.156193 +0530 IST m=+0.533185084
+// Generated: 2026-10-17 05:27:00.070560719 +0000 UTC m=+0.222119005
 
 package netxlite
 
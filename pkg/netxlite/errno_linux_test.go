@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// Generated: 2026-02-10 11:10:23.171362 +0530 IST m=+0.548353668
+// Generated: 2026-10-17 05:27:00.106643095 +0000 UTC m=+0.258201368
 
 package netxlite
 
@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// Generated: 2026-02-10 11:10:23.127575 +0530 IST m=+0.504567126
+// Generated: 2026-10-17 05:27:00.002993096 +0000 UTC m=+0.154551359
 
 package netxlite
 
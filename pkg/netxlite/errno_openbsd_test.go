@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// Generated: 2026-02-10 11:10:23.142602 +0530 IST m=+0.519593918
+// Generated: 2026-10-17 05:27:00.029744278 +0000 UTC m=+0.181302566
 
 package netxlite
 
@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// Generated: 2026-02-10 11:10:23.184978 +0530 IST m=+0.561969793
+// Generated: 2026-10-17 05:27:00.141498253 +0000 UTC m=+0.293056532
 
 package netxlite
 
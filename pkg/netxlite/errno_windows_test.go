@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// Generated: 2026-02-10 11:10:23.198585 +0530 IST m=+0.575577084
+// Generated: 2026-10-17 05:27:00.173922775 +0000 UTC m=+0.325481040
 
 package netxlite
 
@@ -168,6 +168,7 @@ var Specs = []*ErrorSpec{
 	NewLibraryError("SSL_invalid_hostname"),
 	NewLibraryError("SSL_unknown_authority"),
 	NewLibraryError("SSL_invalid_certificate"),
+	NewLibraryError("SSL_ECH_rejected"),
 	NewLibraryError("JSON_parse_error"),
 	NewLibraryError("connection_already_closed"),
 	NewLibraryError("HTTP_invalid_redirect_location_host"),
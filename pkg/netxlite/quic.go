@@ -157,7 +157,7 @@ func (d *quicDialerQUICGo) dialEarly(ctx context.Context,
 }
 
 // maybeApplyTLSDefaults ensures that we're using our certificate pool, if
-// needed, and that we use a suitable ALPN, if needed, for h3 and dq.
+// needed, and that we use a suitable ALPN, if needed, for h3, dq, and doq.
 func (d *quicDialerQUICGo) maybeApplyTLSDefaults(config *tls.Config, port int) *tls.Config {
 	config = config.Clone()
 	if config.RootCAs == nil {
@@ -171,6 +171,9 @@ func (d *quicDialerQUICGo) maybeApplyTLSDefaults(config *tls.Config, port int) *
 		case 8853:
 			// See https://datatracker.ietf.org/doc/html/draft-ietf-dprive-dnsoquic-02#section-10
 			config.NextProtos = []string{"dq"}
+		case 853:
+			// See https://datatracker.ietf.org/doc/html/rfc9250#section-4.1.1
+			config.NextProtos = []string{"doq"}
 		}
 	}
 	return config
@@ -301,6 +301,47 @@ func TestQUICDialerQUICGo(t *testing.T) {
 			}
 		})
 
+		t.Run("TLS defaults for RFC9250 DoQ", func(t *testing.T) {
+			expected := errors.New("mocked error")
+			var gotTLSConfig *tls.Config
+			tlsConfig := &tls.Config{
+				ServerName: "dns.google",
+			}
+			systemdialer := quicDialerQUICGo{
+				UDPListener: &udpListenerStdlib{},
+				mockDialEarly: func(ctx context.Context, pconn net.PacketConn,
+					remoteAddr net.Addr, tlsConfig *tls.Config,
+					quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					gotTLSConfig = tlsConfig
+					return nil, expected
+				},
+			}
+			ctx := context.Background()
+			qconn, err := systemdialer.DialContext(
+				ctx, "8.8.8.8:853", tlsConfig, &quic.Config{})
+			if !errors.Is(err, expected) {
+				t.Fatal("not the error we expected", err)
+			}
+			if qconn != nil {
+				t.Fatal("expected nil connection here")
+			}
+			if tlsConfig.RootCAs != nil {
+				t.Fatal("tlsConfig.RootCAs should not have been changed")
+			}
+			if gotTLSConfig.RootCAs != tproxyDefaultCertPool {
+				t.Fatal("gotTLSConfig.RootCAs should have been set")
+			}
+			if tlsConfig.NextProtos != nil {
+				t.Fatal("tlsConfig.NextProtos should not have been changed")
+			}
+			if diff := cmp.Diff(gotTLSConfig.NextProtos, []string{"doq"}); diff != "" {
+				t.Fatal("invalid gotTLSConfig.NextProtos", diff)
+			}
+			if tlsConfig.ServerName != gotTLSConfig.ServerName {
+				t.Fatal("the ServerName field must match")
+			}
+		})
+
 		t.Run("returns a quicDialerOwnConn in case of success", func(t *testing.T) {
 			tlsConfig := &tls.Config{
 				ServerName: "dns.google",
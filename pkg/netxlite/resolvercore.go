@@ -70,6 +70,27 @@ func NewSerialUDPResolver(logger model.DebugLogger, dialer model.Dialer, address
 	))
 }
 
+// NewParallelDNSOverQUICResolver implements [model.MeasuringNetwork].
+func (netx *Netx) NewParallelDNSOverQUICResolver(logger model.DebugLogger, dialer model.QUICDialer, address string) model.Resolver {
+	return WrapResolver(logger, NewUnwrappedParallelResolver(
+		wrapDNSTransport(NewUnwrappedDNSOverQUICTransport(dialer, address)),
+	))
+}
+
+// NewParallelDNSOverTCPResolver implements [model.MeasuringNetwork].
+func (netx *Netx) NewParallelDNSOverTCPResolver(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
+	return WrapResolver(logger, NewUnwrappedParallelResolver(
+		wrapDNSTransport(NewUnwrappedDNSOverTCPTransport(dialer.DialContext, address)),
+	))
+}
+
+// NewParallelDNSOverTLSResolver implements [model.MeasuringNetwork].
+func (netx *Netx) NewParallelDNSOverTLSResolver(logger model.DebugLogger, dialer model.TLSDialer, address string) model.Resolver {
+	return WrapResolver(logger, NewUnwrappedParallelResolver(
+		wrapDNSTransport(NewUnwrappedDNSOverTLSTransport(dialer.DialTLSContext, address)),
+	))
+}
+
 // NewParallelUDPResolver implements [model.MeasuringNetwork].
 func (netx *Netx) NewParallelUDPResolver(logger model.DebugLogger, dialer model.Dialer, address string) model.Resolver {
 	return WrapResolver(logger, NewUnwrappedParallelResolver(
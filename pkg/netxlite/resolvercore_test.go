@@ -92,6 +92,69 @@ func TestNewParallelDNSOverHTTPSResolver(t *testing.T) {
 	}
 }
 
+func TestNewParallelDNSOverQUICResolver(t *testing.T) {
+	netx := &Netx{}
+	d := netx.NewQUICDialerWithoutResolver(netx.NewUDPListener(), log.Log)
+	resolver := netx.NewParallelDNSOverQUICResolver(log.Log, d, "94.140.14.14:853")
+	idnaReso := resolver.(*resolverIDNA)
+	logger := idnaReso.Resolver.(*resolverLogger)
+	if logger.Logger != log.Log {
+		t.Fatal("invalid logger")
+	}
+	shortCircuit := logger.Resolver.(*ResolverShortCircuitIPAddr)
+	errWrapper := shortCircuit.Resolver.(*resolverErrWrapper)
+	para := errWrapper.Resolver.(*ParallelResolver)
+	txp := para.Transport().(*dnsTransportErrWrapper)
+	dnsTxp := txp.DNSTransport.(*DNSOverQUICTransport)
+	if dnsTxp.Address() != "94.140.14.14:853" {
+		t.Fatal("invalid address")
+	}
+}
+
+func TestNewParallelDNSOverTCPResolver(t *testing.T) {
+	netx := &Netx{}
+	d := netx.NewDialerWithoutResolver(log.Log)
+	resolver := netx.NewParallelDNSOverTCPResolver(log.Log, d, "8.8.8.8:53")
+	idnaReso := resolver.(*resolverIDNA)
+	logger := idnaReso.Resolver.(*resolverLogger)
+	if logger.Logger != log.Log {
+		t.Fatal("invalid logger")
+	}
+	shortCircuit := logger.Resolver.(*ResolverShortCircuitIPAddr)
+	errWrapper := shortCircuit.Resolver.(*resolverErrWrapper)
+	para := errWrapper.Resolver.(*ParallelResolver)
+	txp := para.Transport().(*dnsTransportErrWrapper)
+	dnsTxp := txp.DNSTransport.(*DNSOverTCPTransport)
+	if dnsTxp.Address() != "8.8.8.8:53" {
+		t.Fatal("invalid address")
+	}
+	if dnsTxp.Network() != "tcp" {
+		t.Fatal("invalid network")
+	}
+}
+
+func TestNewParallelDNSOverTLSResolver(t *testing.T) {
+	netx := &Netx{}
+	d := NewTLSDialer(netx.NewDialerWithoutResolver(log.Log), netx.NewTLSHandshakerStdlib(log.Log))
+	resolver := netx.NewParallelDNSOverTLSResolver(log.Log, d, "8.8.8.8:853")
+	idnaReso := resolver.(*resolverIDNA)
+	logger := idnaReso.Resolver.(*resolverLogger)
+	if logger.Logger != log.Log {
+		t.Fatal("invalid logger")
+	}
+	shortCircuit := logger.Resolver.(*ResolverShortCircuitIPAddr)
+	errWrapper := shortCircuit.Resolver.(*resolverErrWrapper)
+	para := errWrapper.Resolver.(*ParallelResolver)
+	txp := para.Transport().(*dnsTransportErrWrapper)
+	dnsTxp := txp.DNSTransport.(*DNSOverTCPTransport)
+	if dnsTxp.Address() != "8.8.8.8:853" {
+		t.Fatal("invalid address")
+	}
+	if dnsTxp.Network() != "dot" {
+		t.Fatal("invalid network")
+	}
+}
+
 func TestResolverSystem(t *testing.T) {
 	t.Run("Network", func(t *testing.T) {
 		expected := "antani"
@@ -949,3 +949,33 @@ func TestMaybeTLSConnectionState(t *testing.T) {
 		}
 	})
 }
+
+func TestNewClientConnStdlib(t *testing.T) {
+	t.Run("we accept an ECH config list", func(t *testing.T) {
+		config := &tls.Config{
+			EncryptedClientHelloConfigList: []byte{0x00, 0x45, 0xfe, 0x0d},
+			ServerName:                     "www.example.com",
+		}
+		conn, err := NewClientConnStdlib(&mocks.Conn{}, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conn == nil {
+			t.Fatal("expected non-nil conn")
+		}
+	})
+
+	t.Run("we reject unsupported fields", func(t *testing.T) {
+		config := &tls.Config{
+			ServerName:             "www.example.com",
+			SessionTicketsDisabled: true,
+		}
+		conn, err := NewClientConnStdlib(&mocks.Conn{}, config)
+		if !errors.Is(err, ErrIncompatibleStdlibConfig) {
+			t.Fatal("unexpected error", err)
+		}
+		if conn != nil {
+			t.Fatal("expected nil conn")
+		}
+	})
+}
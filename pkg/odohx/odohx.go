@@ -0,0 +1,415 @@
+// Package odohx implements the message format and the cryptography of
+// Oblivious DNS-over-HTTPS (ODoH) as specified by RFC9230.
+//
+// We use this package for implementing the ODoH client in [netxlite] and
+// the ODoH target and proxy stand-ins used for testing in [testingx].
+//
+// [netxlite]: https://pkg.go.dev/github.com/ooni/probe-engine/pkg/netxlite
+// [testingx]: https://pkg.go.dev/github.com/ooni/probe-engine/pkg/testingx
+package odohx
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"github.com/cloudflare/circl/hpke"
+	"github.com/cloudflare/circl/kem"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// ContentType is the content type of ODoH messages.
+const ContentType = "application/oblivious-dns-message"
+
+// ConfigsPath is the well-known path where a target publishes its configs.
+const ConfigsPath = "/.well-known/odohconfigs"
+
+// ConfigVersion is the only config version defined by RFC9230.
+const ConfigVersion = 0x0001
+
+const (
+	// messageTypeQuery is the type of ODoH queries.
+	messageTypeQuery = 0x01
+
+	// messageTypeResponse is the type of ODoH responses.
+	messageTypeResponse = 0x02
+)
+
+var (
+	// ErrInvalidConfigs indicates that we cannot parse the configs.
+	ErrInvalidConfigs = errors.New("odoh: invalid configs")
+
+	// ErrNoSupportedConfig indicates that none of the configs is supported.
+	ErrNoSupportedConfig = errors.New("odoh: no supported config")
+
+	// ErrInvalidMessage indicates that we cannot parse a message.
+	ErrInvalidMessage = errors.New("odoh: invalid message")
+
+	// ErrUnexpectedMessageType indicates that a message has the wrong type.
+	ErrUnexpectedMessageType = errors.New("odoh: unexpected message type")
+
+	// ErrUnknownKeyID indicates that a query uses a key ID we don't know.
+	ErrUnknownKeyID = errors.New("odoh: unknown key ID")
+)
+
+// Config contains the ObliviousDoHConfigContents of a target.
+type Config struct {
+	// KEM is the HPKE key encapsulation mechanism.
+	KEM hpke.KEM
+
+	// KDF is the HPKE key derivation function.
+	KDF hpke.KDF
+
+	// AEAD is the HPKE authenticated encryption scheme.
+	AEAD hpke.AEAD
+
+	// PublicKey is the target's serialized public key.
+	PublicKey []byte
+}
+
+// Marshal serializes the ObliviousDoHConfigContents.
+func (c *Config) Marshal() []byte {
+	var b cryptobyte.Builder
+	b.AddUint16(uint16(c.KEM))
+	b.AddUint16(uint16(c.KDF))
+	b.AddUint16(uint16(c.AEAD))
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(c.PublicKey)
+	})
+	return b.BytesOrPanic()
+}
+
+// KeyID returns the key ID identifying this config, computed as
+// Expand(Extract("", config), "odoh key id", Nh).
+func (c *Config) KeyID() []byte {
+	prk := c.KDF.Extract(c.Marshal(), nil)
+	return c.KDF.Expand(prk, []byte("odoh key id"), uint(c.KDF.ExtractSize()))
+}
+
+// supported returns whether we support the given config.
+func (c *Config) supported() bool {
+	return c.KEM.IsValid() && c.KDF.IsValid() && c.AEAD.IsValid()
+}
+
+// MarshalConfigs serializes a list of configs as ObliviousDoHConfigs.
+func MarshalConfigs(configs ...*Config) []byte {
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, c := range configs {
+			b.AddUint16(ConfigVersion)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(c.Marshal())
+			})
+		}
+	})
+	return b.BytesOrPanic()
+}
+
+// ParseConfigs parses ObliviousDoHConfigs and returns the first config we support,
+// ignoring the configs with unknown versions or using unsupported algorithms.
+func ParseConfigs(data []byte) (*Config, error) {
+	input := cryptobyte.String(data)
+	var list cryptobyte.String
+	if !input.ReadUint16LengthPrefixed(&list) || !input.Empty() {
+		return nil, ErrInvalidConfigs
+	}
+	for !list.Empty() {
+		var (
+			version  uint16
+			contents cryptobyte.String
+		)
+		if !list.ReadUint16(&version) || !list.ReadUint16LengthPrefixed(&contents) {
+			return nil, ErrInvalidConfigs
+		}
+		if version != ConfigVersion {
+			continue // as mandated by RFC9230 Section 6.1
+		}
+		var (
+			kemID, kdfID, aeadID uint16
+			publicKey            cryptobyte.String
+		)
+		if !contents.ReadUint16(&kemID) || !contents.ReadUint16(&kdfID) || !contents.ReadUint16(&aeadID) ||
+			!contents.ReadUint16LengthPrefixed(&publicKey) || !contents.Empty() {
+			return nil, ErrInvalidConfigs
+		}
+		config := &Config{
+			KEM:       hpke.KEM(kemID),
+			KDF:       hpke.KDF(kdfID),
+			AEAD:      hpke.AEAD(aeadID),
+			PublicKey: publicKey,
+		}
+		if !config.supported() {
+			continue
+		}
+		if _, err := config.KEM.Scheme().UnmarshalBinaryPublicKey(config.PublicKey); err != nil {
+			continue
+		}
+		return config, nil
+	}
+	return nil, ErrNoSupportedConfig
+}
+
+// message is an ObliviousDoHMessage.
+type message struct {
+	messageType uint8
+	keyID       []byte
+	encrypted   []byte
+}
+
+// marshal serializes the message.
+func (m *message) marshal() []byte {
+	var b cryptobyte.Builder
+	b.AddUint8(m.messageType)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(m.keyID)
+	})
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(m.encrypted)
+	})
+	return b.BytesOrPanic()
+}
+
+// parseMessage parses a message with the given type.
+func parseMessage(data []byte, messageType uint8) (*message, error) {
+	input := cryptobyte.String(data)
+	var (
+		m                    message
+		keyID, encryptedData cryptobyte.String
+	)
+	if !input.ReadUint8(&m.messageType) || !input.ReadUint16LengthPrefixed(&keyID) ||
+		!input.ReadUint16LengthPrefixed(&encryptedData) || !input.Empty() {
+		return nil, ErrInvalidMessage
+	}
+	if m.messageType != messageType {
+		return nil, ErrUnexpectedMessageType
+	}
+	m.keyID, m.encrypted = keyID, encryptedData
+	return &m, nil
+}
+
+// marshalPlaintext serializes an ObliviousDoHMessagePlaintext.
+func marshalPlaintext(dnsMessage []byte, padding int) ([]byte, error) {
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(dnsMessage)
+	})
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(make([]byte, padding))
+	})
+	return b.Bytes()
+}
+
+// parsePlaintext parses an ObliviousDoHMessagePlaintext and returns the DNS message.
+func parsePlaintext(data []byte) ([]byte, error) {
+	input := cryptobyte.String(data)
+	var dnsMessage, padding cryptobyte.String
+	if !input.ReadUint16LengthPrefixed(&dnsMessage) || !input.ReadUint16LengthPrefixed(&padding) ||
+		!input.Empty() || len(dnsMessage) <= 0 {
+		return nil, ErrInvalidMessage
+	}
+	// RFC9230 Section 4.3 says the padding MUST be all zeros
+	if subtle.ConstantTimeCompare(padding, make([]byte, len(padding))) != 1 {
+		return nil, ErrInvalidMessage
+	}
+	return dnsMessage, nil
+}
+
+// lengthPrefixedAAD returns the AAD used for queries and responses, which is
+// the message type followed by the length-prefixed key ID or nonce.
+func lengthPrefixedAAD(messageType uint8, value []byte) []byte {
+	var b cryptobyte.Builder
+	b.AddUint8(messageType)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(value)
+	})
+	return b.BytesOrPanic()
+}
+
+// deriveResponseAEAD derives the AEAD and the nonce for encrypting or decrypting
+// the response to the given query as described by RFC9230 Section 6.4.
+func deriveResponseAEAD(config *Config, context hpke.Context,
+	queryPlaintext, responseNonce []byte) (cipher.AEAD, []byte, error) {
+	secret := context.Export([]byte("odoh response"), config.AEAD.KeySize())
+	var salt cryptobyte.Builder
+	salt.AddBytes(queryPlaintext)
+	salt.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(responseNonce)
+	})
+	prk := config.KDF.Extract(secret, salt.BytesOrPanic())
+	key := config.KDF.Expand(prk, []byte("odoh key"), config.AEAD.KeySize())
+	nonce := config.KDF.Expand(prk, []byte("odoh nonce"), config.AEAD.NonceSize())
+	aead, err := config.AEAD.New(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, nonce, nil
+}
+
+// QueryContext is the client-side context of a query that allows
+// to decrypt the corresponding response.
+type QueryContext struct {
+	config    *Config
+	plaintext []byte
+	sealer    hpke.Sealer
+}
+
+// EncryptQuery encrypts the given DNS message, adding the given amount of zero
+// padding, using the given target config. On success, this function returns the
+// serialized ObliviousDoHMessage along with the context for decrypting the response.
+func EncryptQuery(config *Config, dnsMessage []byte, padding int) ([]byte, *QueryContext, error) {
+	plaintext, err := marshalPlaintext(dnsMessage, padding)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidMessage, err.Error())
+	}
+	publicKey, err := config.KEM.Scheme().UnmarshalBinaryPublicKey(config.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	sender, err := hpke.NewSuite(config.KEM, config.KDF, config.AEAD).NewSender(publicKey, []byte("odoh query"))
+	if err != nil {
+		return nil, nil, err
+	}
+	enc, sealer, err := sender.Setup(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyID := config.KeyID()
+	ciphertext, err := sealer.Seal(plaintext, lengthPrefixedAAD(messageTypeQuery, keyID))
+	if err != nil {
+		return nil, nil, err
+	}
+	msg := &message{
+		messageType: messageTypeQuery,
+		keyID:       keyID,
+		encrypted:   append(enc, ciphertext...),
+	}
+	qc := &QueryContext{
+		config:    config,
+		plaintext: plaintext,
+		sealer:    sealer,
+	}
+	return msg.marshal(), qc, nil
+}
+
+// DecryptResponse decrypts the serialized ObliviousDoHMessage containing the
+// response and returns the DNS message it contains.
+func (qc *QueryContext) DecryptResponse(data []byte) ([]byte, error) {
+	msg, err := parseMessage(data, messageTypeResponse)
+	if err != nil {
+		return nil, err
+	}
+	aead, nonce, err := deriveResponseAEAD(qc.config, qc.sealer, qc.plaintext, msg.keyID)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, msg.encrypted, lengthPrefixedAAD(messageTypeResponse, msg.keyID))
+	if err != nil {
+		return nil, err
+	}
+	return parsePlaintext(plaintext)
+}
+
+// KeyPair is a target's key pair.
+type KeyPair struct {
+	// Config is the config containing the public key.
+	Config *Config
+
+	// privateKey is the private key.
+	privateKey kem.PrivateKey
+}
+
+// NewKeyPair generates a new [*KeyPair] using the X25519, HKDF-SHA256 and
+// AES-128-GCM algorithms, which are the ones mandated by RFC9230.
+func NewKeyPair() (*KeyPair, error) {
+	kemID := hpke.KEM_X25519_HKDF_SHA256
+	publicKey, privateKey, err := kemID.Scheme().GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	rawPublicKey, err := publicKey.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	kp := &KeyPair{
+		Config: &Config{
+			KEM:       kemID,
+			KDF:       hpke.KDF_HKDF_SHA256,
+			AEAD:      hpke.AEAD_AES128GCM,
+			PublicKey: rawPublicKey,
+		},
+		privateKey: privateKey,
+	}
+	return kp, nil
+}
+
+// ResponseContext is the target-side context of a query that allows
+// to encrypt the corresponding response.
+type ResponseContext struct {
+	config    *Config
+	plaintext []byte
+	opener    hpke.Opener
+}
+
+// DecryptQuery decrypts the serialized ObliviousDoHMessage containing a query
+// and returns the DNS message along with the context to encrypt the response.
+func (kp *KeyPair) DecryptQuery(data []byte) ([]byte, *ResponseContext, error) {
+	msg, err := parseMessage(data, messageTypeQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+	if subtle.ConstantTimeCompare(msg.keyID, kp.Config.KeyID()) != 1 {
+		return nil, nil, ErrUnknownKeyID
+	}
+	encSize := kp.Config.KEM.Scheme().CiphertextSize()
+	if len(msg.encrypted) < encSize {
+		return nil, nil, ErrInvalidMessage
+	}
+	suite := hpke.NewSuite(kp.Config.KEM, kp.Config.KDF, kp.Config.AEAD)
+	receiver, err := suite.NewReceiver(kp.privateKey, []byte("odoh query"))
+	if err != nil {
+		return nil, nil, err
+	}
+	opener, err := receiver.Setup(msg.encrypted[:encSize])
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := opener.Open(msg.encrypted[encSize:], lengthPrefixedAAD(messageTypeQuery, msg.keyID))
+	if err != nil {
+		return nil, nil, err
+	}
+	dnsMessage, err := parsePlaintext(plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc := &ResponseContext{
+		config:    kp.Config,
+		plaintext: plaintext,
+		opener:    opener,
+	}
+	return dnsMessage, rc, nil
+}
+
+// EncryptResponse encrypts the given DNS message, adding the given amount
+// of zero padding, and returns the serialized ObliviousDoHMessage.
+func (rc *ResponseContext) EncryptResponse(dnsMessage []byte, padding int) ([]byte, error) {
+	plaintext, err := marshalPlaintext(dnsMessage, padding)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMessage, err.Error())
+	}
+	responseNonce := make([]byte, max(rc.config.AEAD.KeySize(), rc.config.AEAD.NonceSize()))
+	if _, err := rand.Read(responseNonce); err != nil {
+		return nil, err
+	}
+	aead, nonce, err := deriveResponseAEAD(rc.config, rc.opener, rc.plaintext, responseNonce)
+	if err != nil {
+		return nil, err
+	}
+	msg := &message{
+		messageType: messageTypeResponse,
+		keyID:       responseNonce,
+		encrypted:   aead.Seal(nil, nonce, plaintext, lengthPrefixedAAD(messageTypeResponse, responseNonce)),
+	}
+	return msg.marshal(), nil
+}
@@ -0,0 +1,242 @@
+package odohx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cloudflare/circl/hpke"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func TestConfigs(t *testing.T) {
+	kp := runtimex.Try1(NewKeyPair())
+
+	t.Run("we can parse the configs we serialize", func(t *testing.T) {
+		config, err := ParseConfigs(MarshalConfigs(kp.Config))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(kp.Config, config); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we skip unknown versions and unsupported algorithms", func(t *testing.T) {
+		var b cryptobyte.Builder
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			// a config with an unknown version
+			b.AddUint16(0xff00)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes([]byte("antani"))
+			})
+
+			// a config using an unsupported AEAD
+			unsupported := *kp.Config
+			unsupported.AEAD = hpke.AEAD(0xffff)
+			b.AddUint16(ConfigVersion)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(unsupported.Marshal())
+			})
+
+			// a config using an invalid public key
+			invalid := *kp.Config
+			invalid.PublicKey = []byte{1, 2, 3}
+			b.AddUint16(ConfigVersion)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(invalid.Marshal())
+			})
+
+			// the valid config
+			b.AddUint16(ConfigVersion)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(kp.Config.Marshal())
+			})
+		})
+		config, err := ParseConfigs(b.BytesOrPanic())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(kp.Config, config); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we fail when no config is supported", func(t *testing.T) {
+		config, err := ParseConfigs(MarshalConfigs())
+		if !errors.Is(err, ErrNoSupportedConfig) {
+			t.Fatal("unexpected error", err)
+		}
+		if config != nil {
+			t.Fatal("expected nil config")
+		}
+	})
+
+	t.Run("we fail with invalid configs", func(t *testing.T) {
+		inputs := [][]byte{
+			nil,
+			{0, 4, 0, 1},
+			append(MarshalConfigs(kp.Config), 0),
+			{0, 6, 0, 1, 0, 2, 0, 1},
+		}
+		for _, input := range inputs {
+			config, err := ParseConfigs(input)
+			if !errors.Is(err, ErrInvalidConfigs) {
+				t.Fatal("unexpected error", err)
+			}
+			if config != nil {
+				t.Fatal("expected nil config")
+			}
+		}
+	})
+
+	t.Run("the key ID has the expected length", func(t *testing.T) {
+		if len(kp.Config.KeyID()) != kp.Config.KDF.ExtractSize() {
+			t.Fatal("unexpected key ID length")
+		}
+	})
+}
+
+func TestQueryAndResponse(t *testing.T) {
+	kp := runtimex.Try1(NewKeyPair())
+	query := []byte("this is the DNS query")
+	response := []byte("this is the DNS response")
+
+	t.Run("we can exchange a query and a response", func(t *testing.T) {
+		rawQuery, qc, err := EncryptQuery(kp.Config, query, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		gotQuery, rc, err := kp.DecryptQuery(rawQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(query, gotQuery); diff != "" {
+			t.Fatal(diff)
+		}
+
+		rawResponse, err := rc.EncryptResponse(response, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		gotResponse, err := qc.DecryptResponse(rawResponse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(response, gotResponse); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we cannot decrypt the response to another query", func(t *testing.T) {
+		_, qc, err := EncryptQuery(kp.Config, query, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawQuery, _, err := EncryptQuery(kp.Config, query, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, rc, err := kp.DecryptQuery(rawQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawResponse, err := rc.EncryptResponse(response, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := qc.DecryptResponse(rawResponse); err == nil {
+			t.Fatal("expected an error here")
+		}
+	})
+
+	t.Run("the target rejects queries for another key", func(t *testing.T) {
+		other := runtimex.Try1(NewKeyPair())
+		rawQuery, _, err := EncryptQuery(other.Config, query, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := kp.DecryptQuery(rawQuery); !errors.Is(err, ErrUnknownKeyID) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("the target rejects tampered queries", func(t *testing.T) {
+		rawQuery, _, err := EncryptQuery(kp.Config, query, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawQuery[len(rawQuery)-1] ^= 0xff
+		if _, _, err := kp.DecryptQuery(rawQuery); err == nil {
+			t.Fatal("expected an error here")
+		}
+	})
+
+	t.Run("the target rejects truncated queries", func(t *testing.T) {
+		msg := &message{
+			messageType: messageTypeQuery,
+			keyID:       kp.Config.KeyID(),
+			encrypted:   []byte{1, 2, 3},
+		}
+		if _, _, err := kp.DecryptQuery(msg.marshal()); !errors.Is(err, ErrInvalidMessage) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we check the message type", func(t *testing.T) {
+		rawQuery, qc, err := EncryptQuery(kp.Config, query, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := qc.DecryptResponse(rawQuery); !errors.Is(err, ErrUnexpectedMessageType) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we reject invalid messages", func(t *testing.T) {
+		_, qc, err := EncryptQuery(kp.Config, query, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := qc.DecryptResponse([]byte{messageTypeResponse}); !errors.Is(err, ErrInvalidMessage) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we reject a too large query", func(t *testing.T) {
+		_, _, err := EncryptQuery(kp.Config, make([]byte, 1<<16), 0)
+		if !errors.Is(err, ErrInvalidMessage) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestParsePlaintext(t *testing.T) {
+	t.Run("we reject a nonzero padding", func(t *testing.T) {
+		data := []byte{0, 1, 'a', 0, 2, 0, 1}
+		if _, err := parsePlaintext(data); !errors.Is(err, ErrInvalidMessage) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we reject an empty DNS message", func(t *testing.T) {
+		data := []byte{0, 0, 0, 0}
+		if _, err := parsePlaintext(data); !errors.Is(err, ErrInvalidMessage) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we accept a zero padding", func(t *testing.T) {
+		data := []byte{0, 1, 'a', 0, 2, 0, 0}
+		msg, err := parsePlaintext(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]byte("a"), msg); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
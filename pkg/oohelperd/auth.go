@@ -0,0 +1,69 @@
+package oohelperd
+
+//
+// Client authentication
+//
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewToken returns a token signed with the given key and expiring at the given
+// time, which clients could use to authenticate with a [*Handler] whose TokenKey
+// field is set to the same key. The token has the following format:
+//
+//	<expiry as unix time>.<base64url(HMAC-SHA256(key, expiry as unix time))>
+//
+// Clients should send the token using the Authorization header as a bearer token.
+func NewToken(key []byte, expiry time.Time) string {
+	payload := strconv.FormatInt(expiry.Unix(), 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(tokenSignature(key, payload))
+}
+
+// tokenSignature returns the signature of a token payload.
+func tokenSignature(key []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// tokenIsValid returns whether the token is signed with key and not expired.
+func tokenIsValid(key []byte, token string, now time.Time) bool {
+	payload, encodedSignature, found := strings.Cut(token, ".")
+	if !found {
+		return false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, tokenSignature(key, payload)) {
+		return false
+	}
+	expiry, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		return false
+	}
+	return now.Before(time.Unix(expiry, 0))
+}
+
+// handlerIsAuthorized returns whether the request is authorized. When the handler
+// does not require authentication, all requests are authorized. Otherwise, the
+// request must contain either the shared secret or a valid signed token.
+func handlerIsAuthorized(h *Handler, req *http.Request, now time.Time) bool {
+	if h.SharedSecret == "" && len(h.TokenKey) <= 0 {
+		return true
+	}
+	token, found := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return false
+	}
+	if h.SharedSecret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.SharedSecret)) == 1 {
+		return true
+	}
+	return len(h.TokenKey) > 0 && tokenIsValid(h.TokenKey, token, now)
+}
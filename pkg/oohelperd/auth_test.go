@@ -0,0 +1,101 @@
+package oohelperd
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandlerIsAuthorized(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	key := []byte("0xdeadbeef")
+
+	type testcase struct {
+		name          string
+		sharedSecret  string
+		tokenKey      []byte
+		authorization string
+		expect        bool
+	}
+
+	cases := []testcase{{
+		name:   "when we do not require authentication",
+		expect: true,
+	}, {
+		name:          "with the correct shared secret",
+		sharedSecret:  "antani",
+		authorization: "Bearer antani",
+		expect:        true,
+	}, {
+		name:          "with the wrong shared secret",
+		sharedSecret:  "antani",
+		authorization: "Bearer mascetti",
+		expect:        false,
+	}, {
+		name:         "without the authorization header",
+		sharedSecret: "antani",
+		expect:       false,
+	}, {
+		name:          "without a bearer token",
+		sharedSecret:  "antani",
+		authorization: "Basic antani",
+		expect:        false,
+	}, {
+		name:          "with a valid signed token",
+		tokenKey:      key,
+		authorization: "Bearer " + NewToken(key, now.Add(time.Hour)),
+		expect:        true,
+	}, {
+		name:          "with a valid signed token and a shared secret",
+		sharedSecret:  "antani",
+		tokenKey:      key,
+		authorization: "Bearer " + NewToken(key, now.Add(time.Hour)),
+		expect:        true,
+	}, {
+		name:          "with a signed token when we only accept the shared secret",
+		sharedSecret:  "antani",
+		authorization: "Bearer " + NewToken(key, now.Add(time.Hour)),
+		expect:        false,
+	}, {
+		name:          "with an expired signed token",
+		tokenKey:      key,
+		authorization: "Bearer " + NewToken(key, now.Add(-time.Hour)),
+		expect:        false,
+	}, {
+		name:          "with a token signed with another key",
+		tokenKey:      key,
+		authorization: "Bearer " + NewToken([]byte("antani"), now.Add(time.Hour)),
+		expect:        false,
+	}, {
+		name:          "with a token without signature",
+		tokenKey:      key,
+		authorization: "Bearer 1704164645",
+		expect:        false,
+	}, {
+		name:          "with a token with invalid base64",
+		tokenKey:      key,
+		authorization: "Bearer 1704164645.@@@",
+		expect:        false,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &Handler{SharedSecret: tc.sharedSecret, TokenKey: tc.tokenKey}
+			req := &http.Request{Header: http.Header{}}
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			if got := handlerIsAuthorized(h, req, now); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+
+	t.Run("a correctly signed token with an invalid expiry is invalid", func(t *testing.T) {
+		token := "antani." + base64.RawURLEncoding.EncodeToString(tokenSignature(key, "antani"))
+		if tokenIsValid(key, token, now) {
+			t.Fatal("expected the token to be invalid")
+		}
+	})
+}
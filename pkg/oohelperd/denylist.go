@@ -0,0 +1,101 @@
+package oohelperd
+
+//
+// Deny-list of destination networks
+//
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/netxlite"
+)
+
+// errDeniedDestination indicates that we refuse measuring a given destination
+// because it belongs to one of the networks we've been configured to deny.
+var errDeniedDestination = errors.New("oohelperd: denied destination")
+
+// denyListContains returns whether the given IP address belongs to any of the
+// given networks. Passing to this function a non-IP address causes it to return false.
+func denyListContains(networks []netip.Prefix, address string) bool {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// denyListCheckRedirect returns a function suitable for the CheckRedirect field of
+// [http.Client] that refuses following redirects to denied IP addresses. We need this
+// function because [denyListResolver] does not see IP addresses inside URLs.
+func denyListCheckRedirect(networks []netip.Prefix) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if denyListContains(networks, req.URL.Hostname()) {
+			return errDeniedDestination
+		}
+		// same policy of the default redirect policy
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+}
+
+// denyListResolver is a resolver failing when a domain resolves to a denied
+// IP address, thus preventing the HTTP client from connecting to it.
+type denyListResolver struct {
+	Networks []netip.Prefix
+	Resolver model.Resolver
+}
+
+var _ model.Resolver = &denyListResolver{}
+
+// LookupHost implements Resolver.LookupHost
+func (r *denyListResolver) LookupHost(ctx context.Context, hostname string) ([]string, error) {
+	addrs, err := r.Resolver.LookupHost(ctx, hostname)
+	if err != nil {
+		return nil, err // not our responsibility to wrap this error
+	}
+	for _, addr := range addrs {
+		if denyListContains(r.Networks, addr) {
+			return nil, netxlite.NewErrWrapper(
+				netxlite.ClassifyResolverError, netxlite.ResolveOperation, errDeniedDestination)
+		}
+	}
+	return addrs, nil
+}
+
+// LookupHTTPS implements Resolver.LookupHTTPS
+func (r *denyListResolver) LookupHTTPS(ctx context.Context, hostname string) (*model.HTTPSSvc, error) {
+	return nil, netxlite.ErrNoDNSTransport
+}
+
+// LookupNS implements Resolver.LookupNS
+func (r *denyListResolver) LookupNS(ctx context.Context, hostname string) ([]*net.NS, error) {
+	return nil, netxlite.ErrNoDNSTransport
+}
+
+// Network implements Resolver.Network
+func (r *denyListResolver) Network() string {
+	return r.Resolver.Network()
+}
+
+// Address implements Resolver.Address
+func (r *denyListResolver) Address() string {
+	return r.Resolver.Address()
+}
+
+// CloseIdleConnections implements Resolver.CloseIdleConnections
+func (r *denyListResolver) CloseIdleConnections() {
+	r.Resolver.CloseIdleConnections()
+}
@@ -0,0 +1,129 @@
+package oohelperd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"testing"
+
+	"github.com/ooni/probe-engine/pkg/mocks"
+	"github.com/ooni/probe-engine/pkg/netxlite"
+)
+
+func TestDenyListContains(t *testing.T) {
+	networks := []netip.Prefix{
+		netip.MustParsePrefix("8.8.8.0/24"),
+		netip.MustParsePrefix("2001:4860::/32"),
+	}
+	cases := map[string]bool{
+		"8.8.8.8":            true,
+		"::ffff:8.8.8.8":     true,
+		"8.8.4.4":            false,
+		"2001:4860:4860::88": true,
+		"2a00:1450::1":       false,
+		"dns.google":         false,
+		"":                   false,
+	}
+	for address, expect := range cases {
+		if got := denyListContains(networks, address); got != expect {
+			t.Fatal("for", address, "expected", expect, "got", got)
+		}
+	}
+}
+
+func TestDenyListCheckRedirect(t *testing.T) {
+	checkRedirect := denyListCheckRedirect([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+
+	newRequest := func(URL string) *http.Request {
+		return &http.Request{URL: &url.URL{Scheme: "http", Host: URL}}
+	}
+
+	t.Run("we refuse redirects to denied addresses", func(t *testing.T) {
+		err := checkRedirect(newRequest("10.0.0.1:8080"), nil)
+		if !errors.Is(err, errDeniedDestination) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we follow other redirects", func(t *testing.T) {
+		if err := checkRedirect(newRequest("www.example.com"), nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("we stop after ten redirects", func(t *testing.T) {
+		if err := checkRedirect(newRequest("www.example.com"), make([]*http.Request, 10)); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestDenyListResolver(t *testing.T) {
+	newResolver := func(addrs []string, err error) *denyListResolver {
+		return &denyListResolver{
+			Networks: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			Resolver: &mocks.Resolver{
+				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+					return addrs, err
+				},
+				MockNetwork: func() string {
+					return "doh"
+				},
+				MockAddress: func() string {
+					return "https://dns.google/dns-query"
+				},
+				MockCloseIdleConnections: func() {},
+			},
+		}
+	}
+
+	t.Run("LookupHost", func(t *testing.T) {
+		t.Run("with failure", func(t *testing.T) {
+			expected := errors.New("mocked error")
+			addrs, err := newResolver(nil, expected).LookupHost(context.Background(), "www.example.com")
+			if !errors.Is(err, expected) || len(addrs) != 0 {
+				t.Fatal("unexpected result", addrs, err)
+			}
+		})
+
+		t.Run("with denied addresses", func(t *testing.T) {
+			reso := newResolver([]string{"8.8.8.8", "10.0.0.1"}, nil)
+			addrs, err := reso.LookupHost(context.Background(), "www.example.com")
+			if !errors.Is(err, errDeniedDestination) || len(addrs) != 0 {
+				t.Fatal("unexpected result", addrs, err)
+			}
+			var ew *netxlite.ErrWrapper
+			if !errors.As(err, &ew) || ew.Operation != netxlite.ResolveOperation {
+				t.Fatal("expected a wrapped resolve error", err)
+			}
+		})
+
+		t.Run("with allowed addresses", func(t *testing.T) {
+			reso := newResolver([]string{"8.8.8.8"}, nil)
+			addrs, err := reso.LookupHost(context.Background(), "www.example.com")
+			if err != nil || len(addrs) != 1 {
+				t.Fatal("unexpected result", addrs, err)
+			}
+		})
+	})
+
+	t.Run("LookupHTTPS and LookupNS", func(t *testing.T) {
+		reso := newResolver(nil, nil)
+		if _, err := reso.LookupHTTPS(context.Background(), "www.example.com"); !errors.Is(err, netxlite.ErrNoDNSTransport) {
+			t.Fatal("unexpected error", err)
+		}
+		if _, err := reso.LookupNS(context.Background(), "www.example.com"); !errors.Is(err, netxlite.ErrNoDNSTransport) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("we forward the other methods", func(t *testing.T) {
+		reso := newResolver(nil, nil)
+		if reso.Network() != "doh" || reso.Address() != "https://dns.google/dns-query" {
+			t.Fatal("unexpected network or address")
+		}
+		reso.CloseIdleConnections()
+	})
+}
@@ -11,7 +11,10 @@ import (
 	"io"
 	"net/http"
 	"net/http/cookiejar"
+	"net/netip"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -32,9 +35,30 @@ const maxAcceptableBodySize = 1 << 24
 //
 // The zero value is invalid; construct using [NewHandler].
 type Handler struct {
+	// ClientIPHeader OPTIONALLY is the header containing the client IP address, which
+	// is useful when running behind a reverse proxy (e.g., "X-Forwarded-For"). When
+	// empty, we use the remote address of the connection.
+	ClientIPHeader string
+
+	// DenyNetworks OPTIONALLY contains networks that we refuse to measure
+	// in addition to bogons (e.g., the network of our data center).
+	DenyNetworks []netip.Prefix
+
 	// EnableQUIC OPTIONALLY enables QUIC.
 	EnableQUIC bool
 
+	// RateLimiter OPTIONALLY limits the number of requests per client IP.
+	RateLimiter *RateLimiter
+
+	// SharedSecret OPTIONALLY is a secret that clients must send as a bearer
+	// token using the Authorization header. When both SharedSecret and TokenKey
+	// are set, we accept either the shared secret or a signed token.
+	SharedSecret string
+
+	// TokenKey OPTIONALLY is the key used to verify the bearer tokens that clients
+	// must send using the Authorization header. See [NewToken] for details.
+	TokenKey []byte
+
 	// baseLogger is the MANDATORY logger to use.
 	baseLogger model.Logger
 
@@ -69,6 +93,9 @@ type Handler struct {
 
 	// newTLSHandshaker is the MANDATORY factory for creating a new TLS handshaker.
 	newTLSHandshaker func(model.Logger) model.TLSHandshaker
+
+	// timeNow is the MANDATORY function returning the current time.
+	timeNow func() time.Time
 }
 
 var _ http.Handler = &Handler{}
@@ -78,7 +105,10 @@ var enableQUIC = (os.Getenv("OOHELPERD_ENABLE_QUIC") == "1")
 
 // NewHandler constructs the [handler].
 func NewHandler(logger model.Logger, netx *netxlite.Netx) *Handler {
-	return &Handler{
+	// Implementation note: the HTTP client factories read the handler's DenyNetworks
+	// when they are invoked, such that we honour later changes of this field.
+	var h *Handler
+	h = &Handler{
 		EnableQUIC:        enableQUIC,
 		baseLogger:        logger,
 		countRequests:     &atomic.Int64{},
@@ -92,6 +122,7 @@ func NewHandler(logger model.Logger, netx *netxlite.Netx) *Handler {
 			return newHTTPClientWithTransportFactory(
 				netx, logger,
 				netxlite.NewHTTPTransportWithResolver,
+				h.DenyNetworks,
 			)
 		},
 
@@ -99,6 +130,7 @@ func NewHandler(logger model.Logger, netx *netxlite.Netx) *Handler {
 			return newHTTPClientWithTransportFactory(
 				netx, logger,
 				netxlite.NewHTTP3TransportWithResolver,
+				h.DenyNetworks,
 			)
 		},
 
@@ -120,7 +152,10 @@ func NewHandler(logger model.Logger, netx *netxlite.Netx) *Handler {
 		newTLSHandshaker: func(logger model.Logger) model.TLSHandshaker {
 			return netx.NewTLSHandshakerStdlib(logger)
 		},
+
+		timeNow: time.Now,
 	}
+	return h
 }
 
 // handlerShouldThrottleClient returns true if the handler should throttle
@@ -176,6 +211,18 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// make sure the client is authorized, if we require authentication
+	if !handlerIsAuthorized(h, req, h.timeNow()) {
+		handlerReject(w, 401, "unauthorized")
+		return
+	}
+
+	// make sure the client is not sending too many requests
+	if h.RateLimiter != nil && !h.RateLimiter.Allow(handlerClientIP(req, h.ClientIPHeader)) {
+		handlerReject(w, 429, "rate_limited")
+		return
+	}
+
 	// protect against too many requests in flight
 	if handlerShouldThrottleClient(h.countRequests.Load(), req.Header.Get("user-agent")) {
 		metricRequestsCount.WithLabelValues("503", "service_unavailable").Inc()
@@ -200,6 +247,12 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// refuse to measure URLs containing denied IP addresses
+	if URL, err := url.Parse(creq.HTTPRequest); err == nil && denyListContains(h.DenyNetworks, URL.Hostname()) {
+		handlerReject(w, 403, "denied_destination")
+		return
+	}
+
 	// measure the given input
 	started := time.Now()
 	cresp, err := h.measure(req.Context(), h, &creq)
@@ -226,6 +279,13 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	_, _ = w.Write(data)
 }
 
+// handlerReject rejects the request with the given status code and reason.
+func handlerReject(w http.ResponseWriter, code int, reason string) {
+	metricRequestsCount.WithLabelValues(strconv.Itoa(code), reason).Inc()
+	metricRejectedRequestsCount.WithLabelValues(reason).Inc()
+	w.WriteHeader(code)
+}
+
 // newResolver creates a new [model.Resolver] suitable for serving
 // requests coming from ooniprobe clients.
 func newResolver(logger model.Logger, netx *netxlite.Netx) model.Resolver {
@@ -250,6 +310,7 @@ func newCookieJar() *cookiejar.Jar {
 func newHTTPClientWithTransportFactory(
 	netx *netxlite.Netx, logger model.Logger,
 	txpFactory func(*netxlite.Netx, model.DebugLogger, model.Resolver) model.HTTPTransport,
+	denyNetworks []netip.Prefix,
 ) model.HTTPClient {
 	// If the DoH resolver we're using insists that a given domain maps to
 	// bogons, make sure we're going to fail the HTTP measurement.
@@ -270,12 +331,17 @@ func newHTTPClientWithTransportFactory(
 		newResolver(logger, netx),
 	)
 
+	// Likewise, make sure we're not going to fetch from denied networks.
+	if len(denyNetworks) > 0 {
+		reso = &denyListResolver{Networks: denyNetworks, Resolver: reso}
+	}
+
 	// fix: We MUST set a cookie jar for measuring HTTP. See
 	// https://github.com/ooni/probe/issues/2488 for additional
 	// context and pointers to the relevant measurements.
 	client := &http.Client{
 		Transport:     txpFactory(netx, logger, reso),
-		CheckRedirect: nil,
+		CheckRedirect: denyListCheckRedirect(denyNetworks),
 		Jar:           newCookieJar(),
 		Timeout:       0,
 	}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/netip"
 	"strings"
 	"testing"
 
@@ -15,6 +17,7 @@ import (
 	"github.com/ooni/probe-engine/pkg/model"
 	"github.com/ooni/probe-engine/pkg/netxlite"
 	"github.com/ooni/probe-engine/pkg/version"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 // simpleRequestForHandler is a simple request for the [handler].
@@ -269,3 +272,76 @@ func TestNewHandlerEnableQUIC(t *testing.T) {
 		t.Fatal("expected to see false here (is the the environment variable OOHELPERD_ENABLE_QUIC set?!)")
 	}
 }
+
+func TestHandlerRejectsRequests(t *testing.T) {
+	// newHandler creates a handler that does not perform any measurement.
+	newHandler := func() *Handler {
+		handler := NewHandler(log.Log, &netxlite.Netx{})
+		handler.measure = func(ctx context.Context, config *Handler, creq *model.THRequest) (*model.THResponse, error) {
+			return &model.THResponse{}, nil
+		}
+		return handler
+	}
+
+	// serve sends a request with the given body and authorization to the handler.
+	serve := func(handler *Handler, body, authorization string) int {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.RemoteAddr = "130.192.91.211:54321"
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// rejected returns the number of requests rejected for the given reason.
+	rejected := func(reason string) float64 {
+		return testutil.ToFloat64(metricRejectedRequestsCount.WithLabelValues(reason))
+	}
+
+	t.Run("when the client is not authorized", func(t *testing.T) {
+		handler := newHandler()
+		handler.SharedSecret = "antani"
+		before := rejected("unauthorized")
+		if code := serve(handler, simpleRequestForHandler, "Bearer mascetti"); code != 401 {
+			t.Fatal("unexpected status code", code)
+		}
+		if code := serve(handler, simpleRequestForHandler, "Bearer antani"); code != 200 {
+			t.Fatal("unexpected status code", code)
+		}
+		if delta := rejected("unauthorized") - before; delta != 1 {
+			t.Fatal("unexpected number of rejected requests", delta)
+		}
+	})
+
+	t.Run("when the client sends too many requests", func(t *testing.T) {
+		handler := newHandler()
+		handler.RateLimiter = NewRateLimiter(0.001, 1)
+		before := rejected("rate_limited")
+		if code := serve(handler, simpleRequestForHandler, ""); code != 200 {
+			t.Fatal("unexpected status code", code)
+		}
+		if code := serve(handler, simpleRequestForHandler, ""); code != 429 {
+			t.Fatal("unexpected status code", code)
+		}
+		if delta := rejected("rate_limited") - before; delta != 1 {
+			t.Fatal("unexpected number of rejected requests", delta)
+		}
+	})
+
+	t.Run("when the URL contains a denied address", func(t *testing.T) {
+		handler := newHandler()
+		handler.DenyNetworks = []netip.Prefix{netip.MustParsePrefix("8.8.8.0/24")}
+		before := rejected("denied_destination")
+		if code := serve(handler, requestWithoutDomainName, ""); code != 403 {
+			t.Fatal("unexpected status code", code)
+		}
+		if code := serve(handler, simpleRequestForHandler, ""); code != 200 {
+			t.Fatal("unexpected status code", code)
+		}
+		if delta := rejected("denied_destination") - before; delta != 1 {
+			t.Fatal("unexpected number of rejected requests", delta)
+		}
+	})
+}
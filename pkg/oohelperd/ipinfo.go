@@ -6,6 +6,7 @@ package oohelperd
 
 import (
 	"net"
+	"net/netip"
 	"net/url"
 	"sort"
 	"strings"
@@ -74,7 +75,10 @@ type endpointInfo struct {
 // whether an IP address is valid for a domain;
 //
 // 4. otherwise, we don't generate any endpoint to measure.
-func ipInfoToEndpoints(logger model.Logger, URL *url.URL, ipinfo map[string]*model.THIPInfo) []endpointInfo {
+//
+// We skip bogons as well as addresses belonging to the denyNetworks.
+func ipInfoToEndpoints(
+	logger model.Logger, URL *url.URL, ipinfo map[string]*model.THIPInfo, denyNetworks []netip.Prefix) []endpointInfo {
 	var ports []string
 
 	if port := URL.Port(); port != "" {
@@ -91,6 +95,10 @@ func ipInfoToEndpoints(logger model.Logger, URL *url.URL, ipinfo map[string]*mod
 			logger.Infof("IPInfo: skip bogon IP address: %s", addr)
 			continue // as documented
 		}
+		if denyListContains(denyNetworks, addr) {
+			logger.Infof("IPInfo: skip denied IP address: %s", addr)
+			continue // as documented
+		}
 		for _, port := range ports {
 			epnt := net.JoinHostPort(addr, port)
 			out = append(out, endpointInfo{
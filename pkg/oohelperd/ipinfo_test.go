@@ -1,6 +1,7 @@
 package oohelperd
 
 import (
+	"net/netip"
 	"net/url"
 	"testing"
 
@@ -124,8 +125,9 @@ func Test_newIPInfo(t *testing.T) {
 
 func Test_ipInfoToEndpoints(t *testing.T) {
 	type args struct {
-		URL    *url.URL
-		ipinfo map[string]*model.THIPInfo
+		URL          *url.URL
+		ipinfo       map[string]*model.THIPInfo
+		denyNetworks []netip.Prefix
 	}
 	tests := []struct {
 		name string
@@ -267,10 +269,33 @@ func Test_ipInfoToEndpoints(t *testing.T) {
 			Epnt: "8.8.8.8:443",
 			TLS:  true,
 		}},
+	}, {
+		name: "with addresses belonging to denied networks",
+		args: args{
+			URL: &url.URL{
+				Scheme: "https",
+			},
+			ipinfo: map[string]*model.THIPInfo{
+				"8.8.8.8": {
+					ASN:   15169,
+					Flags: model.THIPInfoFlagResolvedByProbe,
+				},
+				"8.8.4.4": {
+					ASN:   15169,
+					Flags: model.THIPInfoFlagResolvedByTH,
+				},
+			},
+			denyNetworks: []netip.Prefix{netip.MustParsePrefix("8.8.8.0/24")},
+		},
+		want: []endpointInfo{{
+			Addr: "8.8.4.4",
+			Epnt: "8.8.4.4:443",
+			TLS:  true,
+		}},
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ipInfoToEndpoints(log.Log, tt.args.URL, tt.args.ipinfo)
+			got := ipInfoToEndpoints(log.Log, tt.args.URL, tt.args.ipinfo, tt.args.denyNetworks)
 			if diff := cmp.Diff(tt.want, got); diff != "" {
 				t.Fatal(diff)
 			}
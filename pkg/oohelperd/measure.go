@@ -65,6 +65,8 @@ func measure(ctx context.Context, config *Handler, creq *ctrlRequest) (*ctrlResp
 		HTTP3Request:  nil, // optional field!
 		DNS:           model.THDNSResult{},
 		IPInfo:        map[string]*model.THIPInfo{},
+
+		XAddressFamilies: map[string]model.THAddressFamilyResult{},
 	}
 	select {
 	case cresp.DNS = <-dnsch:
@@ -80,7 +82,7 @@ func measure(ctx context.Context, config *Handler, creq *ctrlRequest) (*ctrlResp
 
 	// obtain IP info and figure out the endpoints measurement plan
 	cresp.IPInfo = newIPInfo(creq, cresp.DNS.Addrs)
-	endpoints := ipInfoToEndpoints(logger, URL, cresp.IPInfo)
+	endpoints := ipInfoToEndpoints(logger, URL, cresp.IPInfo, config.DenyNetworks)
 
 	// tcpconnect: start over all the endpoints
 	tcpconnch := make(chan *tcpResultPair, len(endpoints))
@@ -150,7 +152,7 @@ func measure(ctx context.Context, config *Handler, creq *ctrlRequest) (*ctrlResp
 			MaxAcceptableBody: config.maxAcceptableBody,
 			NewClient:         config.newHTTP3Client,
 			Out:               http3ch,
-			URL:               "https://" + cresp.HTTPRequest.DiscoveredH3Endpoint,
+			URL:               http3URL(URL, cresp.HTTPRequest.DiscoveredH3Endpoint),
 			Wg:                wg,
 			searchForH3:       false,
 		})
@@ -165,6 +167,12 @@ Loop:
 		select {
 		case tcpconn := <-tcpconnch:
 			cresp.TCPConnect[tcpconn.Endpoint] = tcpconn.TCP
+			family := cresp.XAddressFamilies[tcpconn.TCP.XAddressFamily]
+			family.Endpoints++
+			if tcpconn.TCP.Status {
+				family.Reachable++
+			}
+			cresp.XAddressFamilies[tcpconn.TCP.XAddressFamily] = family
 			if tcpconn.TLS != nil {
 				cresp.TLSHandshake[tcpconn.Endpoint] = *tcpconn.TLS
 				if info := cresp.IPInfo[tcpconn.Address]; info != nil && tcpconn.TLS.Failure == nil {
@@ -180,3 +188,16 @@ Loop:
 
 	return cresp, nil
 }
+
+// http3URL returns the URL to fetch from the given HTTP/3 endpoint, which preserves
+// the original URL's path and query, so we fetch the same resource using HTTP/3.
+func http3URL(URL *url.URL, endpoint string) string {
+	h3URL := &url.URL{
+		Scheme:   "https",
+		Host:     endpoint,
+		Path:     URL.Path,
+		RawPath:  URL.RawPath,
+		RawQuery: URL.RawQuery,
+	}
+	return h3URL.String()
+}
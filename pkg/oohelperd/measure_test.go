@@ -0,0 +1,43 @@
+package oohelperd
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/ooni/probe-engine/pkg/runtimex"
+)
+
+func TestHTTP3URL(t *testing.T) {
+	type testcase struct {
+		name     string
+		input    string
+		endpoint string
+		expect   string
+	}
+
+	cases := []testcase{{
+		name:     "with empty path",
+		input:    "https://www.example.com",
+		endpoint: "www.example.com:443",
+		expect:   "https://www.example.com:443",
+	}, {
+		name:     "with path and query",
+		input:    "https://www.example.com/foo/bar?baz=1",
+		endpoint: "www.example.com:443",
+		expect:   "https://www.example.com:443/foo/bar?baz=1",
+	}, {
+		name:     "with cleartext URL and custom port",
+		input:    "http://www.example.com/foo",
+		endpoint: "www.example.com:8443",
+		expect:   "https://www.example.com:8443/foo",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			URL := runtimex.Try1(url.Parse(tc.input))
+			if got := http3URL(URL, tc.endpoint); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
@@ -33,6 +33,12 @@ var (
 		Help: "Total number of processed requests",
 	}, []string{"code", "reason"})
 
+	// metricRejectedRequestsCount counts the number of requests we rejected.
+	metricRejectedRequestsCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oohelperd_rejected_requests_count",
+		Help: "Total number of requests rejected because of authentication, rate limiting, or deny-lists",
+	}, []string{"reason"})
+
 	// metricRequestsInflight gauges the number of requests currently inflight.
 	metricRequestsInflight = promauto.NewGauge(prometheus.GaugeOpts{
 		Name: "oohelperd_requests_inflight_gauge",
@@ -90,9 +90,9 @@ func quicDo(ctx context.Context, config *quicConfig) {
 	defer measurexlite.MaybeCloseQUICConn(quicConn)
 	ol.Stop(err)
 
-	out.QUIC = ctrlQUICResult{
-		ServerName: config.URLHostname,
-		Status:     err == nil,
-		Failure:    newfailure(err),
+	var state tls.ConnectionState
+	if quicConn != nil {
+		state = quicConn.ConnectionState().TLS
 	}
+	out.QUIC = *tlsNewResult(config.URLHostname, state, err)
 }
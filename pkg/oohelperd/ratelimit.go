@@ -0,0 +1,125 @@
+package oohelperd
+
+//
+// Per-client rate limiting
+//
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdleTimeout is the time after which we forget about a client
+// that is not sending us any request, so its bucket is full again.
+const rateLimiterIdleTimeout = 10 * time.Minute
+
+// RateLimiter is a per-client-IP token-bucket rate limiter. Because a single
+// IPv6 client typically controls at least a /64 network, we rate limit IPv6
+// clients by their /64 prefix rather than by their full address.
+//
+// The zero value is invalid; construct using [NewRateLimiter].
+type RateLimiter struct {
+	// burst is the maximum number of requests in a burst.
+	burst int
+
+	// clients maps a client key (see [rateLimiterKey]) to its token bucket.
+	clients map[string]*rateLimiterClient
+
+	// lastSweep is the last time we removed idle clients.
+	lastSweep time.Time
+
+	// limit is the number of requests per second we allow.
+	limit rate.Limit
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// timeNow allows overriding time.Now in tests.
+	timeNow func() time.Time
+}
+
+// rateLimiterClient is the state of a client of [RateLimiter].
+type rateLimiterClient struct {
+	// lastSeen is the last time we've seen the client.
+	lastSeen time.Time
+
+	// limiter is the client's token bucket.
+	limiter *rate.Limiter
+}
+
+// NewRateLimiter creates a new [*RateLimiter] allowing each client IP to
+// send on average requestsPerSecond requests and bursts of burst requests.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		burst:     burst,
+		clients:   map[string]*rateLimiterClient{},
+		lastSweep: time.Time{},
+		limit:     rate.Limit(requestsPerSecond),
+		mu:        sync.Mutex{},
+		timeNow:   time.Now,
+	}
+}
+
+// Allow returns whether we should serve a request from the given client IP.
+func (rl *RateLimiter) Allow(clientIP string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := rl.timeNow()
+
+	// periodically forget about idle clients to bound memory usage
+	if now.Sub(rl.lastSweep) >= rateLimiterIdleTimeout {
+		for key, client := range rl.clients {
+			if now.Sub(client.lastSeen) >= rateLimiterIdleTimeout {
+				delete(rl.clients, key)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	key := rateLimiterKey(clientIP)
+	client := rl.clients[key]
+	if client == nil {
+		client = &rateLimiterClient{limiter: rate.NewLimiter(rl.limit, rl.burst)}
+		rl.clients[key] = client
+	}
+	client.lastSeen = now
+	return client.limiter.AllowN(now, 1)
+}
+
+// rateLimiterIPv6PrefixLen is the length of the prefix we use to identify IPv6 clients.
+const rateLimiterIPv6PrefixLen = 64
+
+// rateLimiterKey returns the key identifying the client with the given IP address, which
+// is the /64 prefix for IPv6 addresses and the IP address itself otherwise.
+func rateLimiterKey(clientIP string) string {
+	ip := net.ParseIP(clientIP)
+	if ip == nil || ip.To4() != nil {
+		return clientIP
+	}
+	mask := net.CIDRMask(rateLimiterIPv6PrefixLen, 8*net.IPv6len)
+	prefix := &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+	return prefix.String()
+}
+
+// handlerClientIP returns the IP address of the client that sent the request. When
+// header is not empty, we read the IP address from such a header, which we assume
+// to be set by a reverse proxy. In such a case, we use the last entry of the header
+// because that is the one added by the reverse proxy we trust.
+func handlerClientIP(req *http.Request, header string) string {
+	if header != "" {
+		if values := req.Header.Values(header); len(values) > 0 {
+			entries := strings.Split(values[len(values)-1], ",")
+			return strings.TrimSpace(entries[len(entries)-1])
+		}
+	}
+	addr, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return addr
+}
@@ -0,0 +1,130 @@
+package oohelperd
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("we limit each client independently", func(t *testing.T) {
+		now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		rl := NewRateLimiter(1, 2)
+		rl.timeNow = func() time.Time {
+			return now
+		}
+
+		for idx, expect := range []bool{true, true, false} {
+			if got := rl.Allow("130.192.91.211"); got != expect {
+				t.Fatal("request", idx, "expected", expect, "got", got)
+			}
+		}
+		if !rl.Allow("8.8.8.8") {
+			t.Fatal("expected to allow another client")
+		}
+
+		now = now.Add(time.Second)
+		if !rl.Allow("130.192.91.211") {
+			t.Fatal("expected to allow the client after the bucket refilled")
+		}
+	})
+
+	t.Run("we limit IPv6 clients by their /64 prefix", func(t *testing.T) {
+		now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		rl := NewRateLimiter(1, 1)
+		rl.timeNow = func() time.Time {
+			return now
+		}
+		if !rl.Allow("2001:db8:1:2::1") {
+			t.Fatal("expected to allow the first request")
+		}
+		if rl.Allow("2001:db8:1:2:ffff::2") {
+			t.Fatal("expected to limit another address in the same /64")
+		}
+		if !rl.Allow("2001:db8:1:3::1") {
+			t.Fatal("expected to allow an address in another /64")
+		}
+	})
+
+	t.Run("we forget about idle clients", func(t *testing.T) {
+		now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		rl := NewRateLimiter(1, 1)
+		rl.timeNow = func() time.Time {
+			return now
+		}
+		rl.Allow("130.192.91.211")
+		now = now.Add(rateLimiterIdleTimeout / 2)
+		rl.Allow("8.8.8.8")
+		if len(rl.clients) != 2 {
+			t.Fatal("expected two clients")
+		}
+
+		now = now.Add(rateLimiterIdleTimeout / 2)
+		rl.Allow("8.8.4.4")
+		if _, found := rl.clients["130.192.91.211"]; found || len(rl.clients) != 2 {
+			t.Fatal("unexpected clients", rl.clients)
+		}
+	})
+}
+
+func TestRateLimiterKey(t *testing.T) {
+	cases := map[string]string{
+		"130.192.91.211":        "130.192.91.211",
+		"::ffff:130.192.91.211": "::ffff:130.192.91.211",
+		"2001:db8:1:2:3:4:5:6":  "2001:db8:1:2::/64",
+		"2001:db8::1":           "2001:db8::/64",
+		"antani":                "antani",
+	}
+	for input, expect := range cases {
+		t.Run(input, func(t *testing.T) {
+			if got := rateLimiterKey(input); got != expect {
+				t.Fatal("expected", expect, "got", got)
+			}
+		})
+	}
+}
+
+func TestHandlerClientIP(t *testing.T) {
+	type testcase struct {
+		name       string
+		remoteAddr string
+		headers    []string
+		header     string
+		expect     string
+	}
+
+	cases := []testcase{{
+		name:       "with the remote address",
+		remoteAddr: "130.192.91.211:54321",
+		headers:    []string{"8.8.8.8"},
+		header:     "",
+		expect:     "130.192.91.211",
+	}, {
+		name:       "with an invalid remote address",
+		remoteAddr: "130.192.91.211",
+		expect:     "130.192.91.211",
+	}, {
+		name:       "with the header set by the reverse proxy",
+		remoteAddr: "127.0.0.1:54321",
+		headers:    []string{"8.8.8.8", "1.1.1.1, 130.192.91.211"},
+		header:     "X-Forwarded-For",
+		expect:     "130.192.91.211",
+	}, {
+		name:       "without the header set by the reverse proxy",
+		remoteAddr: "127.0.0.1:54321",
+		header:     "X-Forwarded-For",
+		expect:     "127.0.0.1",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &http.Request{Header: http.Header{}, RemoteAddr: tc.remoteAddr}
+			for _, value := range tc.headers {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := handlerClientIP(req, tc.header); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
@@ -6,7 +6,10 @@ package oohelperd
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
+	"net/netip"
 	"sync"
 	"time"
 
@@ -82,8 +85,10 @@ func tcpTLSDo(ctx context.Context, config *tcpTLSConfig) {
 	out := &tcpResultPair{
 		Address:  config.Address,
 		Endpoint: config.Endpoint,
-		TCP:      model.THTCPConnectResult{},
-		TLS:      nil, // means: not measured
+		TCP: model.THTCPConnectResult{
+			XAddressFamily: tcpAddressFamily(config.Address),
+		},
+		TLS: nil, // means: not measured
 	}
 	defer func() {
 		config.Out <- out
@@ -140,6 +145,10 @@ func tcpTLSDo(ctx context.Context, config *tcpTLSConfig) {
 
 	// perform the handshake
 	tlsConn, err := thx.Handshake(ctx, conn, tlsConfig)
+	var state tls.ConnectionState
+	if tlsConn != nil {
+		state = tlsConn.ConnectionState()
+	}
 	_ = measurexlite.MaybeClose(tlsConn)
 
 	// publish time required to handshake
@@ -149,11 +158,33 @@ func tcpTLSDo(ctx context.Context, config *tcpTLSConfig) {
 	ol.Stop(err)
 
 	// we're good and we can fill the result
-	out.TLS = &ctrlTLSResult{
-		ServerName: config.URLHostname,
-		Status:     err == nil,
-		Failure:    newfailure(err),
+	out.TLS = tlsNewResult(config.URLHostname, state, err)
+}
+
+// tlsNewResult creates a new [*ctrlTLSResult] from the result of a TLS
+// handshake, which we also use for QUIC handshakes.
+func tlsNewResult(serverName string, state tls.ConnectionState, err error) *ctrlTLSResult {
+	out := &ctrlTLSResult{
+		ServerName:               serverName,
+		Status:                   err == nil,
+		Failure:                  newfailure(err),
+		XCertificateFingerprints: nil,
+		XNegotiatedProtocol:      state.NegotiatedProtocol,
+		XTLSVersion:              netxlite.TLSVersionString(state.Version),
+	}
+	for _, cert := range measurexlite.TLSPeerCerts(state, err) {
+		sum := sha256.Sum256(cert)
+		out.XCertificateFingerprints = append(out.XCertificateFingerprints, hex.EncodeToString(sum[:]))
+	}
+	return out
+}
+
+// tcpAddressFamily returns the address family of the given IP address.
+func tcpAddressFamily(address string) string {
+	if addr, err := netip.ParseAddr(address); err == nil && addr.Unmap().Is4() {
+		return model.THAddressFamilyIPv4
 	}
+	return model.THAddressFamilyIPv6
 }
 
 // tcpMapFailure attempts to map netxlite failures to the strings
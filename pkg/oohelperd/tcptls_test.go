@@ -1,9 +1,15 @@
 package oohelperd
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/model"
 	"github.com/ooni/probe-engine/pkg/netxlite"
 )
 
@@ -38,3 +44,77 @@ func Test_tcpMapFailure(t *testing.T) {
 		})
 	}
 }
+
+func Test_tlsNewResult(t *testing.T) {
+	// fingerprint returns the expected fingerprint of the given raw certificate.
+	fingerprint := func(raw string) string {
+		sum := sha256.Sum256([]byte(raw))
+		return hex.EncodeToString(sum[:])
+	}
+
+	t.Run("on success", func(t *testing.T) {
+		state := tls.ConnectionState{
+			Version:            tls.VersionTLS13,
+			NegotiatedProtocol: "h2",
+			PeerCertificates: []*x509.Certificate{{
+				Raw: []byte("leaf"),
+			}, {
+				Raw: []byte("intermediate"),
+			}},
+		}
+		got := tlsNewResult("www.example.com", state, nil)
+		expect := &ctrlTLSResult{
+			ServerName:               "www.example.com",
+			Status:                   true,
+			Failure:                  nil,
+			XCertificateFingerprints: []string{fingerprint("leaf"), fingerprint("intermediate")},
+			XNegotiatedProtocol:      "h2",
+			XTLSVersion:              "TLSv1.3",
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with a certificate error", func(t *testing.T) {
+		err := netxlite.NewTopLevelGenericErrWrapper(x509.UnknownAuthorityError{
+			Cert: &x509.Certificate{Raw: []byte("leaf")},
+		})
+		got := tlsNewResult("www.example.com", tls.ConnectionState{}, err)
+		expect := &ctrlTLSResult{
+			ServerName:               "www.example.com",
+			Status:                   false,
+			Failure:                  newfailure(err),
+			XCertificateFingerprints: []string{fingerprint("leaf")},
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with any other error", func(t *testing.T) {
+		err := errors.New("mocked error")
+		got := tlsNewResult("www.example.com", tls.ConnectionState{}, err)
+		expect := &ctrlTLSResult{
+			ServerName: "www.example.com",
+			Status:     false,
+			Failure:    newfailure(err),
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func Test_tcpAddressFamily(t *testing.T) {
+	cases := map[string]string{
+		"8.8.8.8":              model.THAddressFamilyIPv4,
+		"::ffff:8.8.8.8":       model.THAddressFamilyIPv4,
+		"2001:4860:4860::8888": model.THAddressFamilyIPv6,
+	}
+	for address, expect := range cases {
+		if got := tcpAddressFamily(address); got != expect {
+			t.Fatal("for", address, "expected", expect, "got", got)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/ooni/probe-engine/pkg/humanize"
 	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/runevents"
 )
 
 // experimentShuffledInputs counts how many times we shuffled inputs
@@ -24,6 +25,14 @@ type Experiment struct {
 	// Annotations contains OPTIONAL Annotations for the experiment.
 	Annotations map[string]string
 
+	// Events is the OPTIONAL sink for the events we emit while running.
+	Events runevents.Sink
+
+	// EmitMeasurements OPTIONALLY indicates that we should emit each
+	// measurement using Events, which is useful when we're not saving
+	// measurements to a JSON file and the caller wants to see them.
+	EmitMeasurements bool
+
 	// ExtraOptions contains OPTIONAL extra options that modify the
 	// default experiment-specific configuration. We apply
 	// the changes described by this field after using the InitialOptions
@@ -79,6 +88,9 @@ type Experiment struct {
 	// newInputProcessorFn is OPTIONAL and used for testing.
 	newInputProcessorFn func(experiment model.Experiment, inputList []model.ExperimentTarget,
 		saver model.Saver, submitter model.Submitter) inputProcessor
+
+	// callbacks is set by Run when we need to emit progress events.
+	callbacks *experimentCallbacks
 }
 
 // Run runs the given experiment.
@@ -117,13 +129,28 @@ func (ed *Experiment) Run(ctx context.Context) error {
 	}
 
 	// 5. construct the experiment instance
-	experiment := builder.NewExperiment()
 	logger := ed.Session.Logger()
+	if ed.Events != nil {
+		ed.callbacks = &experimentCallbacks{
+			child:  model.NewPrinterCallbacks(logger),
+			events: ed.Events,
+			name:   ed.Name,
+		}
+		builder.SetCallbacks(ed.callbacks)
+	}
+	experiment := builder.NewExperiment()
+	runevents.Emit(ed.Events, &runevents.ExperimentStart{Name: ed.Name, Inputs: len(targetList)})
 	defer func() {
 		logger.Infof("experiment: recv %s, sent %s",
 			humanize.SI(experiment.KibiBytesReceived()*1024, "byte"),
 			humanize.SI(experiment.KibiBytesSent()*1024, "byte"),
 		)
+		runevents.Emit(ed.Events, &runevents.Bytes{
+			Scope:             "experiment",
+			Name:              ed.Name,
+			KibiBytesSent:     experiment.KibiBytesSent(),
+			KibiBytesReceived: experiment.KibiBytesReceived(),
+		})
 	}()
 
 	// 6. create the submitter
@@ -164,20 +191,36 @@ func (ed *Experiment) newInputProcessor(experiment model.Experiment,
 	if ed.newInputProcessorFn != nil {
 		return ed.newInputProcessorFn(experiment, inputList, saver, submitter)
 	}
+	submitterWrapper := &experimentSubmitterWrapper{
+		child:  NewInputProcessorSubmitterWrapper(submitter),
+		logger: ed.Session.Logger(),
+	}
+	saverWrapper := &experimentSaverWrapper{
+		child: NewInputProcessorSaverWrapper(saver),
+	}
+	// Note: we only emit saving and submission events when we're actually
+	// saving and submitting, to avoid confusing the consumer of events.
+	if !ed.NoJSON {
+		saverWrapper.events = ed.Events
+	}
+	if !ed.NoCollector {
+		submitterWrapper.events = ed.Events
+	}
+	if ed.EmitMeasurements {
+		saverWrapper.measurements = ed.Events
+	}
 	return &InputProcessor{
 		Annotations: ed.Annotations,
 		Experiment: &experimentWrapper{
-			child:  NewInputProcessorExperimentWrapper(experiment),
-			logger: ed.Session.Logger(),
-			total:  len(inputList),
+			callbacks: ed.callbacks,
+			child:     NewInputProcessorExperimentWrapper(experiment),
+			logger:    ed.Session.Logger(),
+			total:     len(inputList),
 		},
 		Inputs:     inputList,
 		MaxRuntime: time.Duration(ed.MaxRuntime) * time.Second,
-		Saver:      NewInputProcessorSaverWrapper(saver),
-		Submitter: &experimentSubmitterWrapper{
-			child:  NewInputProcessorSubmitterWrapper(submitter),
-			logger: ed.Session.Logger(),
-		},
+		Saver:      saverWrapper,
+		Submitter:  submitterWrapper,
 	}
 }
 
@@ -235,6 +278,9 @@ func (ed *Experiment) newTargetLoader(builder model.ExperimentBuilder) targetLoa
 
 // experimentWrapper wraps an experiment and logs progress
 type experimentWrapper struct {
+	// callbacks is the OPTIONAL callbacks emitting progress events
+	callbacks *experimentCallbacks
+
 	// child is the child experiment wrapper
 	child InputProcessorExperimentWrapper
 
@@ -250,6 +296,9 @@ func (ew *experimentWrapper) MeasureWithContext(
 	if target.Input() != "" {
 		ew.logger.Infof("[%d/%d] running with input: %s", idx+1, ew.total, target)
 	}
+	if ew.callbacks != nil {
+		ew.callbacks.idx.Store(int64(idx))
+	}
 	return ew.child.MeasureWithContext(ctx, target, idx)
 }
 
@@ -259,6 +308,9 @@ type experimentSubmitterWrapper struct {
 	// child is the child submitter wrapper
 	child InputProcessorSubmitterWrapper
 
+	// events is the OPTIONAL sink for submission events
+	events runevents.Sink
+
 	// logger is the logger to use
 	logger model.Logger
 }
@@ -268,6 +320,74 @@ func (sw *experimentSubmitterWrapper) Submit(ctx context.Context, idx int, m *mo
 	if err != nil {
 		sw.logger.Warnf("submitting measurement failed: %s", err.Error())
 	}
+	runevents.Emit(sw.events, &runevents.Submission{
+		Idx:            idx,
+		Input:          string(m.Input),
+		ReportID:       m.ReportID,
+		MeasurementUID: mstUID,
+		Failure:        runevents.NewFailure(err),
+	})
 	// policy: we do not stop the loop if measurement submission fails
 	return mstUID, nil
 }
+
+// experimentSaverWrapper emits events when saving each measurement. We
+// do that when saving because at this point we have also submitted the
+// measurement, hence we know its report ID.
+type experimentSaverWrapper struct {
+	// child is the child saver wrapper
+	child InputProcessorSaverWrapper
+
+	// events is the OPTIONAL sink for saving events
+	events runevents.Sink
+
+	// measurements is the OPTIONAL sink for measurement events
+	measurements runevents.Sink
+}
+
+func (sw *experimentSaverWrapper) SaveMeasurement(idx int, m *model.Measurement) error {
+	if sw.measurements != nil {
+		if data, err := json.Marshal(m); err == nil {
+			runevents.Emit(sw.measurements, &runevents.Measurement{
+				Idx:         idx,
+				Input:       string(m.Input),
+				Measurement: data,
+			})
+		}
+	}
+	err := sw.child.SaveMeasurement(idx, m)
+	runevents.Emit(sw.events, &runevents.MeasurementSaved{
+		Idx:     idx,
+		Input:   string(m.Input),
+		Failure: runevents.NewFailure(err),
+	})
+	return err
+}
+
+// experimentCallbacks logs the experiment progress and emits progress events.
+type experimentCallbacks struct {
+	// child is the callbacks logging progress
+	child model.ExperimentCallbacks
+
+	// events is the sink for progress events
+	events runevents.Sink
+
+	// idx is the index of the input we're currently measuring
+	idx atomic.Int64
+
+	// name is the experiment name
+	name string
+}
+
+var _ model.ExperimentCallbacks = &experimentCallbacks{}
+
+// OnProgress implements model.ExperimentCallbacks.
+func (cb *experimentCallbacks) OnProgress(percentage float64, message string) {
+	cb.child.OnProgress(percentage, message)
+	runevents.Emit(cb.events, &runevents.Progress{
+		Name:       cb.name,
+		Idx:        int(cb.idx.Load()),
+		Percentage: percentage,
+		Message:    message,
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/ooni/probe-engine/pkg/engine"
 	"github.com/ooni/probe-engine/pkg/mocks"
 	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/runevents"
 	"github.com/ooni/probe-engine/pkg/testingx"
 )
 
@@ -126,6 +128,93 @@ func TestExperimentRunWithFailureToSubmitAndShuffle(t *testing.T) {
 	}
 }
 
+// eventsCollector is a [runevents.Sink] collecting event types.
+type eventsCollector struct {
+	mu    sync.Mutex
+	types []string
+}
+
+func (ec *eventsCollector) Emit(ev *runevents.Event) {
+	ec.mu.Lock()
+	ec.types = append(ec.types, ev.Type)
+	ec.mu.Unlock()
+}
+
+func TestExperimentRunWithEvents(t *testing.T) {
+	events := &eventsCollector{}
+	var callbacks model.ExperimentCallbacks
+	desc := &Experiment{
+		EmitMeasurements: true,
+		Events:           events,
+		Name:             "example",
+		NoCollector:      false,
+		NoJSON:           true,
+		Session: &mocks.Session{
+			MockNewExperimentBuilder: func(name string) (model.ExperimentBuilder, error) {
+				eb := &mocks.ExperimentBuilder{
+					MockInputPolicy: func() model.InputPolicy {
+						return model.InputOptional
+					},
+					MockSetOptionsJSON: func(value json.RawMessage) error {
+						return nil
+					},
+					MockSetOptionsAny: func(options map[string]any) error {
+						return nil
+					},
+					MockSetCallbacks: func(cb model.ExperimentCallbacks) {
+						callbacks = cb
+					},
+					MockNewExperiment: func() model.Experiment {
+						exp := &mocks.Experiment{
+							MockMeasureWithContext: func(
+								ctx context.Context, target model.ExperimentTarget) (*model.Measurement, error) {
+								callbacks.OnProgress(0.5, "halfway")
+								return &model.Measurement{Input: model.MeasurementInput(target.Input())}, nil
+							},
+							MockKibiBytesReceived: func() float64 {
+								return 1.453
+							},
+							MockKibiBytesSent: func() float64 {
+								return 1.648
+							},
+						}
+						return exp
+					},
+					MockNewTargetLoader: func(config *model.ExperimentTargetLoaderConfig) model.ExperimentTargetLoader {
+						return &mocks.ExperimentTargetLoader{
+							MockLoad: func(ctx context.Context) ([]model.ExperimentTarget, error) {
+								results := []model.ExperimentTarget{
+									model.NewOOAPIURLInfoWithDefaultCategoryAndCountry("a"),
+								}
+								return results, nil
+							},
+						}
+					},
+				}
+				return eb, nil
+			},
+			MockLogger: func() model.Logger {
+				return model.DiscardLogger
+			},
+		},
+		newSubmitterFn: func(ctx context.Context) (model.Submitter, error) {
+			subm := &mocks.Submitter{
+				MockSubmit: func(ctx context.Context, m *model.Measurement) (string, error) {
+					return "20240101T000000Z_example_IT_1234_n1_abc", nil
+				},
+			}
+			return subm, nil
+		},
+	}
+	if err := desc.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"experiment_start", "progress", "submission", "measurement", "bytes"}
+	if diff := cmp.Diff(expect, events.types); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
 // This test ensures that we honour InitialOptions then ExtraOptions.
 func TestExperimentSetOptions(t *testing.T) {
 	ctx := context.Background()
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/runevents"
 )
 
 // LinkConfig contains config for an OONI Run link. You MUST fill all the fields that
@@ -26,6 +27,13 @@ type LinkConfig struct {
 	// Annotations contains OPTIONAL Annotations for the experiment.
 	Annotations map[string]string
 
+	// EmitMeasurements OPTIONALLY indicates that we should emit each
+	// measurement using Events.
+	EmitMeasurements bool
+
+	// Events is the OPTIONAL sink for the events we emit while running.
+	Events runevents.Sink
+
 	// KVStore is the MANDATORY key-value store to use to keep track of
 	// OONI Run links and know when they are new or modified.
 	KVStore model.KeyValueStore
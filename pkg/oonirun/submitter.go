@@ -6,9 +6,6 @@ import (
 	"github.com/ooni/probe-engine/pkg/model"
 )
 
-// TODO(bassosimone): maybe keep track of which measurements
-// could not be submitted by a specific submitter?
-
 // Submitter is an alias for model.Submitter
 type Submitter = model.Submitter
 
@@ -73,6 +73,8 @@ func v1Measure(ctx context.Context, config *LinkConfig, URL string) error {
 	}
 	exp := &Experiment{
 		Annotations:            config.Annotations,
+		EmitMeasurements:       config.EmitMeasurements,
+		Events:                 config.Events,
 		ExtraOptions:           nil, // no way to specify with v1 URLs
 		Inputs:                 inputs,
 		InputFilePaths:         nil,
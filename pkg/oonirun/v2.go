@@ -191,6 +191,8 @@ func V2MeasureDescriptor(ctx context.Context, config *LinkConfig, desc *V2Descri
 		// construct an experiment from the current nettest
 		exp := &Experiment{
 			Annotations:            config.Annotations,
+			EmitMeasurements:       config.EmitMeasurements,
+			Events:                 config.Events,
 			ExtraOptions:           make(map[string]any),
 			InitialOptions:         nettest.Options,
 			Inputs:                 nettest.Inputs,
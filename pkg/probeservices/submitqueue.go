@@ -0,0 +1,406 @@
+package probeservices
+
+//
+// Durable submission queue
+//
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-engine/pkg/httpclientx"
+	"github.com/ooni/probe-engine/pkg/memoryless"
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+const (
+	// submitQueuePrefix is the prefix of the keys used by the queue.
+	submitQueuePrefix = "submitqueue."
+
+	// submitQueueBackoffBase is the expected delay after the first failure.
+	submitQueueBackoffBase = time.Minute
+
+	// submitQueueBackoffMax is the maximum expected delay between attempts.
+	submitQueueBackoffMax = 24 * time.Hour
+
+	// submitQueueMaxAttempts is the number of attempts after which we give up.
+	submitQueueMaxAttempts = 16
+)
+
+// SubmitQueueDatabase is the SubmitQueue's view of the results database.
+type SubmitQueueDatabase interface {
+	GetMeasurement(msmtID int64) (*model.DatabaseMeasurement, error)
+	GetResult(resultID int64) (*model.DatabaseResult, error)
+	UploadFailed(msmt *model.DatabaseMeasurement, failure string) error
+	UploadSucceeded(msmt *model.DatabaseMeasurement) error
+	UpdateUploadedStatus(result *model.DatabaseResult) error
+}
+
+// SubmitQueueEntry is a measurement waiting to be submitted.
+type SubmitQueueEntry struct {
+	// Attempts is the number of failed submission attempts.
+	Attempts int `json:"attempts"`
+
+	// CreatedAt is when we enqueued the measurement.
+	CreatedAt time.Time `json:"created_at"`
+
+	// DatabaseMeasurementID is the OPTIONAL ID of the measurement
+	// inside the results database (zero means there's no such row).
+	DatabaseMeasurementID int64 `json:"database_measurement_id,omitempty"`
+
+	// DatabaseResultID is the OPTIONAL ID of the result inside the
+	// results database (zero means there's no such row).
+	DatabaseResultID int64 `json:"database_result_id,omitempty"`
+
+	// LastFailure is the error that occurred during the last attempt.
+	LastFailure string `json:"last_failure,omitempty"`
+
+	// Measurement is the serialized measurement.
+	Measurement json.RawMessage `json:"measurement"`
+
+	// NextAttempt is the time after which we should try again.
+	NextAttempt time.Time `json:"next_attempt"`
+}
+
+// SubmitQueue is a persistent queue of measurements to submit. We store each
+// measurement inside a key-value store before attempting to submit it and
+// we only remove it once the submission succeeded. When the submission fails,
+// we retry later using an exponential backoff with memoryless jitter, which
+// works across process restarts since we store the next attempt time. We
+// use a [Submitter] internally, so we open new reports when needed.
+//
+// You MUST NOT modify the public fields after first use.
+type SubmitQueue struct {
+	// Database is the OPTIONAL results database. When set, we use it to
+	// mark the rows referenced by each entry as uploaded.
+	Database SubmitQueueDatabase
+
+	// Logger is the MANDATORY logger to use.
+	Logger model.Logger
+
+	// Opener is the MANDATORY [ReportOpener] to use.
+	Opener ReportOpener
+
+	// Store is the MANDATORY key-value store holding the queue.
+	Store model.ExtendedKeyValueStore
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// sub is the submitter we're currently using.
+	sub *Submitter
+
+	// timeNow is the OPTIONAL function to get the current time.
+	timeNow func() time.Time
+}
+
+// NewSubmitQueue creates a new [*SubmitQueue] instance.
+func NewSubmitQueue(store model.ExtendedKeyValueStore, opener ReportOpener, logger model.Logger) *SubmitQueue {
+	return &SubmitQueue{
+		Logger: logger,
+		Opener: opener,
+		Store:  store,
+	}
+}
+
+var _ model.Submitter = &SubmitQueue{}
+
+// Submit enqueues the measurement and immediately attempts to submit it. When
+// this method returns an error, the measurement remains inside the queue and we
+// will attempt to submit it again during a subsequent [SubmitQueue.Flush].
+func (q *SubmitQueue) Submit(ctx context.Context, m *model.Measurement) (string, error) {
+	// Note: we hold the mutex while enqueueing such that a concurrent
+	// Flush cannot submit the entry before we have a chance to do so
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	key, err := q.enqueue(m, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	entry, err := q.load(key)
+	if err != nil {
+		return "", err
+	}
+	// Note: we submit the caller's measurement such that, like the [Submitter]
+	// does, we set its report ID once the submission succeeded
+	return q.submit(ctx, key, entry, m)
+}
+
+// Enqueue adds the given measurement to the queue and returns the key
+// used to store it. The msmtID and resultID arguments are the IDs of the
+// corresponding rows inside the results database, if any, or zero. When
+// msmtID is nonzero and the queue already contains an entry for the same
+// row, we do not enqueue the measurement again and return the existing key.
+func (q *SubmitQueue) Enqueue(m *model.Measurement, msmtID, resultID int64) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.enqueue(m, msmtID, resultID)
+}
+
+// enqueue implements Enqueue. This function assumes the caller is
+// holding the mutex protecting the queue.
+func (q *SubmitQueue) enqueue(m *model.Measurement, msmtID, resultID int64) (string, error) {
+	if msmtID != 0 {
+		if key, found := q.findDatabaseMeasurement(msmtID); found {
+			return key, nil
+		}
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	now := q.now()
+	entry := &SubmitQueueEntry{
+		CreatedAt:             now,
+		DatabaseMeasurementID: msmtID,
+		DatabaseResultID:      resultID,
+		Measurement:           data,
+		NextAttempt:           now,
+	}
+	key, err := newSubmitQueueKey(now)
+	if err != nil {
+		return "", err
+	}
+	if err := q.store(key, entry); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// findDatabaseMeasurement returns the key of the entry referencing the given
+// measurement row, if any. This function assumes the caller is holding the
+// mutex protecting the queue.
+func (q *SubmitQueue) findDatabaseMeasurement(msmtID int64) (string, bool) {
+	keys, err := q.Store.Keys(submitQueuePrefix)
+	if err != nil {
+		return "", false
+	}
+	for _, key := range keys {
+		entry, err := q.load(key)
+		if err == nil && entry.DatabaseMeasurementID == msmtID {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// Flush attempts to submit all the entries whose next attempt time is in the past
+// and returns the number of successfully submitted entries. We stop at the first
+// retryable submission failure, since it likely means that the following submissions
+// would fail as well. The returned error is the one that caused us to stop, if any.
+// Conversely, we drop the entries that failed with a non-retryable error (see
+// [submitQueueIsPermanentError]) and we continue with the next entries.
+func (q *SubmitQueue) Flush(ctx context.Context) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	keys, err := q.Store.Keys(submitQueuePrefix)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+		entry, err := q.load(key)
+		if err != nil {
+			// the entry may have been removed by another process
+			q.Logger.Warnf("submitqueue: cannot load %s: %s", key, err.Error())
+			continue
+		}
+		if entry.NextAttempt.After(q.now()) {
+			continue
+		}
+		var m model.Measurement
+		if err := json.Unmarshal(entry.Measurement, &m); err != nil {
+			// there is no point in retrying if we cannot parse the measurement
+			q.Logger.Warnf("submitqueue: removing unparseable %s: %s", key, err.Error())
+			q.remove(key)
+			return count, err
+		}
+		if _, err := q.submit(ctx, key, entry, &m); err != nil {
+			if submitQueueIsPermanentError(err) {
+				continue
+			}
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Pending returns the keys of the entries that are still inside the queue.
+func (q *SubmitQueue) Pending() ([]string, error) {
+	return q.Store.Keys(submitQueuePrefix)
+}
+
+// submit attempts to submit the measurement m stored inside the given entry. This
+// function assumes the caller is holding the mutex protecting the queue.
+func (q *SubmitQueue) submit(
+	ctx context.Context, key string, entry *SubmitQueueEntry, m *model.Measurement) (string, error) {
+	if q.sub == nil {
+		q.sub = NewSubmitter(q.Opener, q.Logger)
+	}
+	muid, err := q.sub.Submit(ctx, m)
+	if err != nil {
+		// make sure we open a new report on the next attempt
+		q.sub = nil
+		q.markFailed(entry, err)
+		if submitQueueIsPermanentError(err) {
+			q.Logger.Warnf("submitqueue: giving up on %s: %s", key, err.Error())
+			q.remove(key)
+			return "", err
+		}
+		entry.Attempts++
+		entry.LastFailure = err.Error()
+		if entry.Attempts >= submitQueueMaxAttempts {
+			q.Logger.Warnf("submitqueue: giving up on %s after %d attempts", key, entry.Attempts)
+			q.remove(key)
+			return "", err
+		}
+		entry.NextAttempt = q.now().Add(submitQueueBackoff(entry.Attempts))
+		if err := q.store(key, entry); err != nil {
+			q.Logger.Warnf("submitqueue: cannot update %s: %s", key, err.Error())
+		}
+		return "", err
+	}
+	q.remove(key)
+	q.markUploaded(entry, m)
+	return muid, nil
+}
+
+// submitQueueIsPermanentError returns whether the given submission error would
+// occur again if we retried, such that we should drop the measurement. This
+// happens when the backend rejects the request (e.g., because it does not know
+// the report ID) or when the measurement is not compatible with the collector.
+func submitQueueIsPermanentError(err error) bool {
+	var reqErr *httpclientx.ErrRequestFailed
+	if errors.As(err, &reqErr) {
+		// Note: 408 and 429 indicate that we may try again later
+		code := reqErr.StatusCode
+		return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+	}
+	return errors.Is(err, ErrUnsupportedDataFormatVersion) ||
+		errors.Is(err, ErrUnsupportedFormat) ||
+		errors.Is(err, ErrJSONFormatNotSupported)
+}
+
+// markFailed records the upload failure inside the results database.
+func (q *SubmitQueue) markFailed(entry *SubmitQueueEntry, err error) {
+	if q.Database == nil || entry.DatabaseMeasurementID == 0 {
+		return
+	}
+	msmt, dbErr := q.Database.GetMeasurement(entry.DatabaseMeasurementID)
+	if dbErr != nil {
+		q.Logger.Warnf("submitqueue: cannot get measurement: %s", dbErr.Error())
+		return
+	}
+	if dbErr := q.Database.UploadFailed(msmt, err.Error()); dbErr != nil {
+		q.Logger.Warnf("submitqueue: cannot mark measurement as failed: %s", dbErr.Error())
+	}
+}
+
+// markUploaded records the upload success inside the results database.
+func (q *SubmitQueue) markUploaded(entry *SubmitQueueEntry, m *model.Measurement) {
+	if q.Database == nil || entry.DatabaseMeasurementID == 0 {
+		return
+	}
+	msmt, err := q.Database.GetMeasurement(entry.DatabaseMeasurementID)
+	if err != nil {
+		q.Logger.Warnf("submitqueue: cannot get measurement: %s", err.Error())
+		return
+	}
+	// the report ID changes when we need to open a new report
+	if m.ReportID != "" {
+		msmt.ReportID.String, msmt.ReportID.Valid = m.ReportID, true
+	}
+	if err := q.Database.UploadSucceeded(msmt); err != nil {
+		q.Logger.Warnf("submitqueue: cannot mark measurement as uploaded: %s", err.Error())
+		return
+	}
+	if entry.DatabaseResultID == 0 {
+		return
+	}
+	result, err := q.Database.GetResult(entry.DatabaseResultID)
+	if err != nil {
+		q.Logger.Warnf("submitqueue: cannot get result: %s", err.Error())
+		return
+	}
+	if err := q.Database.UpdateUploadedStatus(result); err != nil {
+		q.Logger.Warnf("submitqueue: cannot update result uploaded status: %s", err.Error())
+	}
+}
+
+// remove removes an entry from the key-value store.
+func (q *SubmitQueue) remove(key string) {
+	if err := q.Store.Delete(key); err != nil {
+		q.Logger.Warnf("submitqueue: cannot remove %s: %s", key, err.Error())
+	}
+}
+
+// load loads an entry from the key-value store.
+func (q *SubmitQueue) load(key string) (*SubmitQueueEntry, error) {
+	data, err := q.Store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	var entry SubmitQueueEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// store saves an entry into the key-value store.
+func (q *SubmitQueue) store(key string, entry *SubmitQueueEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return q.Store.Set(key, data)
+}
+
+// now returns the current time.
+func (q *SubmitQueue) now() time.Time {
+	if q.timeNow != nil {
+		return q.timeNow()
+	}
+	return time.Now()
+}
+
+// newSubmitQueueKey returns a new key such that sorting keys
+// lexicographically sorts entries by enqueue time.
+func newSubmitQueueKey(now time.Time) (string, error) {
+	var suffix [8]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%020d.%s", submitQueuePrefix, now.UnixNano(), hex.EncodeToString(suffix[:])), nil
+}
+
+// submitQueueBackoff returns the delay before the next attempt given the
+// number of failed attempts. The expected delay grows exponentially and we
+// draw the actual delay from a memoryless distribution around it, such that
+// probes that failed together do not all retry at the same time.
+func submitQueueBackoff(attempts int) time.Duration {
+	expected := submitQueueBackoffMax
+	if shift := attempts - 1; shift < 16 && submitQueueBackoffBase<<shift < submitQueueBackoffMax {
+		expected = submitQueueBackoffBase << shift
+	}
+	d, err := memoryless.Duration(memoryless.Config{
+		Expected: expected,
+		Min:      expected / 10,
+		Max:      expected * 5 / 2,
+	})
+	if err != nil {
+		return expected // should not happen, but let's be defensive
+	}
+	return d
+}
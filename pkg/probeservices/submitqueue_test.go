@@ -0,0 +1,436 @@
+package probeservices
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-engine/pkg/httpclientx"
+	"github.com/ooni/probe-engine/pkg/kvstore"
+	"github.com/ooni/probe-engine/pkg/mocks"
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+// failingReportOpener is a [ReportOpener] that fails until we clear err.
+type failingReportOpener struct {
+	err   error
+	child RecordingReportOpener
+}
+
+func (fro *failingReportOpener) OpenReport(
+	ctx context.Context, rt model.OOAPIReportTemplate) (ReportChannel, error) {
+	if fro.err != nil {
+		return nil, fro.err
+	}
+	return fro.child.OpenReport(ctx, rt)
+}
+
+// newSubmitQueueForTesting returns a queue with a controllable clock.
+func newSubmitQueueForTesting(opener ReportOpener) (*SubmitQueue, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	queue := NewSubmitQueue(&kvstore.Memory{}, opener, log.Log)
+	queue.timeNow = func() time.Time {
+		return now
+	}
+	return queue, &now
+}
+
+// pendingEntries returns the entries inside the queue.
+func pendingEntries(t *testing.T, queue *SubmitQueue) []*SubmitQueueEntry {
+	keys, err := queue.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []*SubmitQueueEntry
+	for _, key := range keys {
+		entry, err := queue.load(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSubmitQueue(t *testing.T) {
+	t.Run("Submit removes the entry on success", func(t *testing.T) {
+		rro := &RecordingReportOpener{}
+		queue, _ := newSubmitQueueForTesting(rro)
+		for _, testName := range []string{"example", "example", "example_extended"} {
+			if _, err := queue.Submit(context.Background(), makeMeasurementWithoutTemplate(testName)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if entries := pendingEntries(t, queue); len(entries) != 0 {
+			t.Fatal("expected no pending entries")
+		}
+		// we reuse the same report when we can
+		if len(rro.channels) != 2 {
+			t.Fatal("unexpected number of channels")
+		}
+	})
+
+	t.Run("Submit submits the caller's measurement", func(t *testing.T) {
+		// Note: this is what allows the report channel to set the
+		// report ID of the measurement the caller owns
+		rro := &RecordingReportOpener{}
+		queue, _ := newSubmitQueueForTesting(rro)
+		m := makeMeasurementWithoutTemplate("example")
+		if _, err := queue.Submit(context.Background(), m); err != nil {
+			t.Fatal(err)
+		}
+		if len(rro.channels) != 1 || len(rro.channels[0].m) != 1 || rro.channels[0].m[0] != m {
+			t.Fatal("did not submit the caller's measurement")
+		}
+	})
+
+	t.Run("Submit keeps the entry and Flush retries after the backoff", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		fro := &failingReportOpener{err: expected}
+		queue, now := newSubmitQueueForTesting(fro)
+
+		if _, err := queue.Submit(context.Background(), makeMeasurementWithoutTemplate("example")); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		entries := pendingEntries(t, queue)
+		if len(entries) != 1 {
+			t.Fatal("expected one pending entry")
+		}
+		if entries[0].Attempts != 1 || entries[0].LastFailure != expected.Error() {
+			t.Fatal("unexpected entry", entries[0])
+		}
+		if !entries[0].NextAttempt.After(*now) {
+			t.Fatal("expected the next attempt to be in the future")
+		}
+
+		// the network is now working but the entry is not due yet
+		fro.err = nil
+		count, err := queue.Flush(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 || len(fro.child.channels) != 0 {
+			t.Fatal("should not have attempted to submit")
+		}
+
+		// once the entry is due, we submit it
+		*now = entries[0].NextAttempt
+		count, err = queue.Flush(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 || len(fro.child.channels) != 1 {
+			t.Fatal("should have submitted")
+		}
+		if entries := pendingEntries(t, queue); len(entries) != 0 {
+			t.Fatal("expected no pending entries")
+		}
+	})
+
+	t.Run("we open a new report after a submission failure", func(t *testing.T) {
+		rro := &RecordingReportOpener{}
+		queue, now := newSubmitQueueForTesting(rro)
+		if _, err := queue.Submit(context.Background(), makeMeasurementWithoutTemplate("example")); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel() // make the submission fail
+		if _, err := queue.Submit(ctx, makeMeasurementWithoutTemplate("example")); !errors.Is(err, context.Canceled) {
+			t.Fatal("unexpected error", err)
+		}
+
+		*now = now.Add(submitQueueBackoffMax * 3)
+		count, err := queue.Flush(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatal("should have submitted")
+		}
+		if len(rro.channels) != 2 {
+			t.Fatal("unexpected number of channels")
+		}
+	})
+
+	t.Run("Flush stops at the first failure", func(t *testing.T) {
+		fro := &failingReportOpener{}
+		queue, _ := newSubmitQueueForTesting(fro)
+		for idx := 0; idx < 3; idx++ {
+			if _, err := queue.Enqueue(makeMeasurementWithoutTemplate("example"), 0, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		fro.err = errors.New("mocked error")
+		count, err := queue.Flush(context.Background())
+		if !errors.Is(err, fro.err) {
+			t.Fatal("unexpected error", err)
+		}
+		if count != 0 {
+			t.Fatal("unexpected count", count)
+		}
+		var failed int
+		for _, entry := range pendingEntries(t, queue) {
+			failed += entry.Attempts
+		}
+		if failed != 1 {
+			t.Fatal("expected a single attempt", failed)
+		}
+	})
+
+	t.Run("Flush honours the context", func(t *testing.T) {
+		rro := &RecordingReportOpener{}
+		queue, _ := newSubmitQueueForTesting(rro)
+		if _, err := queue.Enqueue(makeMeasurementWithoutTemplate("example"), 0, 0); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		count, err := queue.Flush(ctx)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatal("unexpected error", err)
+		}
+		if count != 0 || len(pendingEntries(t, queue)) != 1 {
+			t.Fatal("should not have submitted")
+		}
+	})
+
+	t.Run("Flush handles errors listing keys", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		queue := NewSubmitQueue(&mocks.ExtendedKeyValueStore{
+			MockKeys: func(prefix string) ([]string, error) {
+				return nil, expected
+			},
+		}, &RecordingReportOpener{}, log.Log)
+		count, err := queue.Flush(context.Background())
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if count != 0 {
+			t.Fatal("unexpected count", count)
+		}
+	})
+
+	t.Run("Flush skips entries it cannot load", func(t *testing.T) {
+		queue := NewSubmitQueue(&mocks.ExtendedKeyValueStore{
+			MockKeys: func(prefix string) ([]string, error) {
+				return []string{submitQueuePrefix + "0"}, nil
+			},
+			MockGet: func(key string) ([]byte, error) {
+				return nil, kvstore.ErrNoSuchKey
+			},
+		}, &RecordingReportOpener{}, log.Log)
+		count, err := queue.Flush(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatal("unexpected count", count)
+		}
+	})
+
+	t.Run("we remove unparseable measurements", func(t *testing.T) {
+		rro := &RecordingReportOpener{}
+		queue, _ := newSubmitQueueForTesting(rro)
+		entry := &SubmitQueueEntry{Measurement: []byte(`[]`)}
+		if err := queue.store(submitQueuePrefix+"0", entry); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := queue.Flush(context.Background()); err == nil {
+			t.Fatal("expected an error")
+		}
+		if len(pendingEntries(t, queue)) != 0 || len(rro.channels) != 0 {
+			t.Fatal("should have removed the entry without submitting")
+		}
+	})
+
+	t.Run("we give up after too many attempts", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		queue, now := newSubmitQueueForTesting(&failingReportOpener{err: expected})
+		if _, err := queue.Enqueue(makeMeasurementWithoutTemplate("example"), 0, 0); err != nil {
+			t.Fatal(err)
+		}
+		for idx := 0; idx < submitQueueMaxAttempts; idx++ {
+			if _, err := queue.Flush(context.Background()); !errors.Is(err, expected) {
+				t.Fatal("unexpected error", err)
+			}
+			*now = now.Add(submitQueueBackoffMax * 3)
+		}
+		if len(pendingEntries(t, queue)) != 0 {
+			t.Fatal("should have removed the entry")
+		}
+	})
+
+	t.Run("we drop measurements that failed with a permanent error", func(t *testing.T) {
+		fro := &failingReportOpener{err: &httpclientx.ErrRequestFailed{StatusCode: 400}}
+		queue, _ := newSubmitQueueForTesting(fro)
+		for idx := 0; idx < 2; idx++ {
+			if _, err := queue.Enqueue(makeMeasurementWithoutTemplate("example"), 0, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		count, err := queue.Flush(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatal("unexpected count", count)
+		}
+		if len(pendingEntries(t, queue)) != 0 {
+			t.Fatal("should have removed the entries")
+		}
+	})
+
+	t.Run("we do not enqueue the same database measurement twice", func(t *testing.T) {
+		queue, _ := newSubmitQueueForTesting(&failingReportOpener{})
+		first, err := queue.Enqueue(makeMeasurementWithoutTemplate("example"), 3, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := queue.Enqueue(makeMeasurementWithoutTemplate("example"), 3, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first != second || len(pendingEntries(t, queue)) != 1 {
+			t.Fatal("should have enqueued the measurement once")
+		}
+	})
+
+	t.Run("we update the results database", func(t *testing.T) {
+		var (
+			failures []string
+			uploaded *model.DatabaseMeasurement
+			updated  *model.DatabaseResult
+		)
+		database := &mocks.Database{
+			MockGetMeasurement: func(msmtID int64) (*model.DatabaseMeasurement, error) {
+				return &model.DatabaseMeasurement{ID: msmtID, ResultID: 7}, nil
+			},
+			MockGetResult: func(resultID int64) (*model.DatabaseResult, error) {
+				return &model.DatabaseResult{ID: resultID}, nil
+			},
+			MockUploadFailed: func(msmt *model.DatabaseMeasurement, failure string) error {
+				failures = append(failures, failure)
+				return nil
+			},
+			MockUploadSucceeded: func(msmt *model.DatabaseMeasurement) error {
+				uploaded = msmt
+				return nil
+			},
+			MockUpdateUploadedStatus: func(result *model.DatabaseResult) error {
+				updated = result
+				return nil
+			},
+		}
+		fro := &failingReportOpener{err: errors.New("mocked error")}
+		queue, now := newSubmitQueueForTesting(fro)
+		queue.Database = database
+		if _, err := queue.Enqueue(makeMeasurementWithoutTemplate("example"), 3, 7); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := queue.Flush(context.Background()); err == nil {
+			t.Fatal("expected an error")
+		}
+		if len(failures) != 1 || failures[0] != "mocked error" {
+			t.Fatal("unexpected failures", failures)
+		}
+
+		fro.err = nil
+		*now = now.Add(submitQueueBackoffMax * 3)
+		if _, err := queue.Flush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if uploaded == nil || uploaded.ID != 3 {
+			t.Fatal("unexpected uploaded measurement", uploaded)
+		}
+		if updated == nil || updated.ID != 7 {
+			t.Fatal("unexpected updated result", updated)
+		}
+	})
+
+	t.Run("we record the new report ID in the results database", func(t *testing.T) {
+		var uploaded *model.DatabaseMeasurement
+		database := &mocks.Database{
+			MockGetMeasurement: func(msmtID int64) (*model.DatabaseMeasurement, error) {
+				return &model.DatabaseMeasurement{ID: msmtID, ReportID: sql.NullString{String: "old", Valid: true}}, nil
+			},
+			MockUploadSucceeded: func(msmt *model.DatabaseMeasurement) error {
+				uploaded = msmt
+				return nil
+			},
+		}
+		queue, _ := newSubmitQueueForTesting(&RecordingReportOpener{})
+		queue.Database = database
+		entry := &SubmitQueueEntry{DatabaseMeasurementID: 3}
+		queue.markUploaded(entry, &model.Measurement{ReportID: "new"})
+		if uploaded == nil || uploaded.ReportID.String != "new" {
+			t.Fatal("unexpected uploaded measurement", uploaded)
+		}
+	})
+
+	t.Run("we tolerate results database errors", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		database := &mocks.Database{
+			MockGetMeasurement: func(msmtID int64) (*model.DatabaseMeasurement, error) {
+				return nil, expected
+			},
+		}
+		queue, _ := newSubmitQueueForTesting(&RecordingReportOpener{})
+		queue.Database = database
+		if _, err := queue.Enqueue(makeMeasurementWithoutTemplate("example"), 3, 7); err != nil {
+			t.Fatal(err)
+		}
+		count, err := queue.Flush(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatal("unexpected count", count)
+		}
+	})
+}
+
+func TestSubmitQueueIsPermanentError(t *testing.T) {
+	cases := []struct {
+		err    error
+		expect bool
+	}{
+		{errors.New("mocked error"), false},
+		{context.Canceled, false},
+		{&httpclientx.ErrRequestFailed{StatusCode: 400}, true},
+		{&httpclientx.ErrRequestFailed{StatusCode: 404}, true},
+		{&httpclientx.ErrRequestFailed{StatusCode: 408}, false},
+		{&httpclientx.ErrRequestFailed{StatusCode: 429}, false},
+		{&httpclientx.ErrRequestFailed{StatusCode: 500}, false},
+		{ErrUnsupportedDataFormatVersion, true},
+		{ErrUnsupportedFormat, true},
+		{ErrJSONFormatNotSupported, true},
+	}
+	for _, tc := range cases {
+		if got := submitQueueIsPermanentError(tc.err); got != tc.expect {
+			t.Fatal("for", tc.err, "expected", tc.expect, "got", got)
+		}
+	}
+}
+
+func TestSubmitQueueBackoff(t *testing.T) {
+	for attempts := 1; attempts < 40; attempts++ {
+		expected := submitQueueBackoffMax
+		if attempts < 12 {
+			expected = submitQueueBackoffBase << (attempts - 1)
+		}
+		if expected > submitQueueBackoffMax {
+			expected = submitQueueBackoffMax
+		}
+		for idx := 0; idx < 100; idx++ {
+			d := submitQueueBackoff(attempts)
+			if d < expected/10 || d > expected*5/2 {
+				t.Fatal("unexpected backoff", attempts, d)
+			}
+		}
+	}
+}
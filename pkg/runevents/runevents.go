@@ -0,0 +1,257 @@
+// Package runevents contains the structured events emitted while bootstrapping
+// a session and running experiments. Tools driving the engine (e.g., miniooni
+// as a subprocess) can consume these events rather than parsing logs.
+package runevents
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event is a structured event.
+type Event struct {
+	// Type is the event type (e.g., "geolocation").
+	Type string `json:"type"`
+
+	// Time is the time when we emitted the event.
+	Time time.Time `json:"t"`
+
+	// Data contains event-type-specific data.
+	Data Data `json:"data"`
+}
+
+// Data is the data carried by an [*Event].
+type Data interface {
+	// EventType returns the type of the [*Event] carrying the data.
+	EventType() string
+}
+
+// Sink receives events. Implementations MUST be safe for concurrent use.
+type Sink interface {
+	Emit(ev *Event)
+}
+
+// Emit emits an event carrying the given data using the given sink. This
+// function does nothing when the sink is nil, which means that the code emitting
+// events does not need to check whether there's an interested party.
+func Emit(sink Sink, data Data) {
+	if sink == nil {
+		return
+	}
+	sink.Emit(&Event{
+		Type: data.EventType(),
+		Time: time.Now().UTC(),
+		Data: data,
+	})
+}
+
+// JSONLWriter is a [Sink] that serializes events as JSON lines.
+type JSONLWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLWriter creates a new [*JSONLWriter] writing into w.
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	return &JSONLWriter{w: w}
+}
+
+var _ Sink = &JSONLWriter{}
+
+// Emit implements Sink.
+func (jw *JSONLWriter) Emit(ev *Event) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return // should not happen with the data types we define
+	}
+	data = append(data, '\n')
+	defer jw.mu.Unlock()
+	jw.mu.Lock()
+	_, _ = jw.w.Write(data)
+}
+
+// NewFailure converts an error to a failure string or nil.
+func NewFailure(err error) *string {
+	if err == nil {
+		return nil
+	}
+	s := err.Error()
+	return &s
+}
+
+// Log is emitted for each log message, when the code driving the
+// engine routes logs through the events sink.
+type Log struct {
+	// Level is the log level (e.g., "INFO").
+	Level string `json:"level"`
+
+	// Message is the log message.
+	Message string `json:"message"`
+}
+
+// EventType implements Data.
+func (*Log) EventType() string {
+	return "log"
+}
+
+// SessionBootstrap is emitted after looking up the probe services.
+type SessionBootstrap struct {
+	// ProbeServicesURL is the URL of the selected probe services.
+	ProbeServicesURL string `json:"probe_services_url,omitempty"`
+
+	// Failure is the failure that occurred, if any.
+	Failure *string `json:"failure"`
+}
+
+// EventType implements Data.
+func (*SessionBootstrap) EventType() string {
+	return "session_bootstrap"
+}
+
+// Geolocation is emitted after looking up the probe location. We intentionally
+// do not include the probe IP addresses, to avoid leaking them.
+type Geolocation struct {
+	ProbeASN            string  `json:"probe_asn"`
+	ProbeCC             string  `json:"probe_cc"`
+	ProbeNetworkName    string  `json:"probe_network_name"`
+	ResolverASN         string  `json:"resolver_asn"`
+	ResolverIP          string  `json:"resolver_ip"`
+	ResolverNetworkName string  `json:"resolver_network_name"`
+	Failure             *string `json:"failure"`
+}
+
+// EventType implements Data.
+func (*Geolocation) EventType() string {
+	return "geolocation"
+}
+
+// CheckIn is emitted after calling the check-in API.
+type CheckIn struct {
+	// ReportID is the web_connectivity report ID returned by the API.
+	ReportID string `json:"report_id,omitempty"`
+
+	// URLs is the number of web_connectivity URLs returned by the API.
+	URLs int `json:"urls"`
+
+	// Failure is the failure that occurred, if any.
+	Failure *string `json:"failure"`
+}
+
+// EventType implements Data.
+func (*CheckIn) EventType() string {
+	return "check_in"
+}
+
+// ExperimentStart is emitted before measuring the experiment inputs.
+type ExperimentStart struct {
+	// Name is the experiment name.
+	Name string `json:"name"`
+
+	// Inputs is the number of inputs to measure.
+	Inputs int `json:"inputs"`
+}
+
+// EventType implements Data.
+func (*ExperimentStart) EventType() string {
+	return "experiment_start"
+}
+
+// Progress is emitted when an experiment reports progress.
+type Progress struct {
+	// Name is the experiment name.
+	Name string `json:"name"`
+
+	// Idx is the index of the input being measured.
+	Idx int `json:"idx"`
+
+	// Percentage is the progress within the current input, between 0 and 1.
+	Percentage float64 `json:"percentage"`
+
+	// Message is the progress message.
+	Message string `json:"message"`
+}
+
+// EventType implements Data.
+func (*Progress) EventType() string {
+	return "progress"
+}
+
+// MeasurementSaved is emitted after saving a measurement.
+type MeasurementSaved struct {
+	// Idx is the index of the measured input.
+	Idx int `json:"idx"`
+
+	// Input is the measured input.
+	Input string `json:"input"`
+
+	// Failure is the failure that occurred, if any.
+	Failure *string `json:"failure"`
+}
+
+// EventType implements Data.
+func (*MeasurementSaved) EventType() string {
+	return "measurement_saved"
+}
+
+// Measurement is emitted after measuring an input, when the code driving
+// the experiment explicitly asked us to emit measurements.
+type Measurement struct {
+	// Idx is the index of the measured input.
+	Idx int `json:"idx"`
+
+	// Input is the measured input.
+	Input string `json:"input"`
+
+	// Measurement is the serialized measurement.
+	Measurement json.RawMessage `json:"measurement"`
+}
+
+// EventType implements Data.
+func (*Measurement) EventType() string {
+	return "measurement"
+}
+
+// Submission is emitted after attempting to submit a measurement.
+type Submission struct {
+	// Idx is the index of the measured input.
+	Idx int `json:"idx"`
+
+	// Input is the measured input.
+	Input string `json:"input"`
+
+	// ReportID is the report ID, if known.
+	ReportID string `json:"report_id,omitempty"`
+
+	// MeasurementUID is the measurement UID, if the submission succeeded.
+	MeasurementUID string `json:"measurement_uid,omitempty"`
+
+	// Failure is the failure that occurred, if any.
+	Failure *string `json:"failure"`
+}
+
+// EventType implements Data.
+func (*Submission) EventType() string {
+	return "submission"
+}
+
+// Bytes is emitted to report the bytes sent and received.
+type Bytes struct {
+	// Scope is either "experiment" or "session".
+	Scope string `json:"scope"`
+
+	// Name is the experiment name when Scope is "experiment".
+	Name string `json:"name,omitempty"`
+
+	// KibiBytesSent is the number of KiB sent.
+	KibiBytesSent float64 `json:"kibibytes_sent"`
+
+	// KibiBytesReceived is the number of KiB received.
+	KibiBytesReceived float64 `json:"kibibytes_received"`
+}
+
+// EventType implements Data.
+func (*Bytes) EventType() string {
+	return "bytes"
+}
@@ -0,0 +1,62 @@
+package runevents
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEmit(t *testing.T) {
+	t.Run("with a nil sink", func(t *testing.T) {
+		Emit(nil, &CheckIn{}) // should not crash
+	})
+
+	t.Run("with a JSONLWriter", func(t *testing.T) {
+		var buf bytes.Buffer
+		sink := NewJSONLWriter(&buf)
+		Emit(sink, &ExperimentStart{Name: "example", Inputs: 1})
+		Emit(sink, &Submission{Idx: 0, Failure: NewFailure(errors.New("mocked error"))})
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatal("expected two lines, got", len(lines))
+		}
+
+		type rawEvent struct {
+			Type string         `json:"type"`
+			Data map[string]any `json:"data"`
+		}
+		var got []rawEvent
+		for _, line := range lines {
+			var ev rawEvent
+			if err := json.Unmarshal([]byte(line), &ev); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, ev)
+		}
+
+		expect := []rawEvent{{
+			Type: "experiment_start",
+			Data: map[string]any{"name": "example", "inputs": float64(1)},
+		}, {
+			Type: "submission",
+			Data: map[string]any{"idx": float64(0), "input": "", "failure": "mocked error"},
+		}}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestNewFailure(t *testing.T) {
+	if NewFailure(nil) != nil {
+		t.Fatal("expected nil")
+	}
+	if s := NewFailure(errors.New("antani")); s == nil || *s != "antani" {
+		t.Fatal("unexpected failure")
+	}
+}
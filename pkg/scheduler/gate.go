@@ -0,0 +1,90 @@
+package scheduler
+
+//
+// Gates
+//
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/shellx"
+)
+
+// ErrHookSaysSkip indicates that a [*CommandGate] command failed.
+var ErrHookSaysSkip = errors.New("scheduler: hook command says we should skip running")
+
+// CommandGate is a [Gate] running a command and allowing us to run
+// jobs only when the command exits successfully. For example, one could
+// use `on_ac_power` to avoid running jobs when on battery.
+type CommandGate struct {
+	// Argv is the MANDATORY command to run along with its arguments.
+	Argv []string
+
+	// run is the OPTIONAL function to run the command.
+	run func(command string, args ...string) error
+}
+
+var _ Gate = &CommandGate{}
+
+// Check implements Gate.
+func (g *CommandGate) Check(ctx context.Context) error {
+	if len(g.Argv) <= 0 {
+		return fmt.Errorf("%w: empty command", ErrHookSaysSkip)
+	}
+	run := g.run
+	if run == nil {
+		run = shellx.RunQuiet
+	}
+	if err := run(g.Argv[0], g.Argv[1:]...); err != nil {
+		return fmt.Errorf("%w: %s", ErrHookSaysSkip, err.Error())
+	}
+	return nil
+}
+
+// ErrMeteredNetwork indicates that the network is metered.
+var ErrMeteredNetwork = errors.New("scheduler: the network is metered")
+
+// NetworkManagerMeteredGate is a [Gate] preventing us from running jobs when
+// NetworkManager says that the network is metered. When we cannot query
+// NetworkManager (e.g., because it's not running), we assume that the
+// network is not metered, which is the common case for wired devices.
+type NetworkManagerMeteredGate struct {
+	// Logger is the MANDATORY logger to use.
+	Logger model.Logger
+
+	// output is the OPTIONAL function to get the command output.
+	output func(command string, args ...string) ([]byte, error)
+}
+
+var _ Gate = &NetworkManagerMeteredGate{}
+
+// Check implements Gate.
+func (g *NetworkManagerMeteredGate) Check(ctx context.Context) error {
+	output := g.output
+	if output == nil {
+		output = shellx.OutputQuiet
+	}
+	data, err := output(
+		"busctl", "get-property",
+		"org.freedesktop.NetworkManager",
+		"/org/freedesktop/NetworkManager",
+		"org.freedesktop.NetworkManager",
+		"Metered",
+	)
+	if err != nil {
+		g.Logger.Debugf("scheduler: cannot query NetworkManager: %s", err.Error())
+		return nil
+	}
+	// The output looks like `u 1` where the number is a NMMetered value. See
+	// https://networkmanager.dev/docs/api/latest/nm-dbus-types.html#NMMetered.
+	switch strings.TrimSpace(string(data)) {
+	case "u 1", "u 3": // NM_METERED_YES, NM_METERED_GUESS_YES
+		return ErrMeteredNetwork
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/model"
+)
+
+func TestCommandGate(t *testing.T) {
+	t.Run("with empty command", func(t *testing.T) {
+		g := &CommandGate{}
+		if err := g.Check(context.Background()); !errors.Is(err, ErrHookSaysSkip) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when the command succeeds", func(t *testing.T) {
+		var argv []string
+		g := &CommandGate{
+			Argv: []string{"on_ac_power", "-v"},
+			run: func(command string, args ...string) error {
+				argv = append([]string{command}, args...)
+				return nil
+			},
+		}
+		if err := g.Check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(g.Argv, argv); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when the command fails", func(t *testing.T) {
+		g := &CommandGate{
+			Argv: []string{"on_ac_power"},
+			run: func(command string, args ...string) error {
+				return errors.New("exit status 1")
+			},
+		}
+		if err := g.Check(context.Background()); !errors.Is(err, ErrHookSaysSkip) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestNetworkManagerMeteredGate(t *testing.T) {
+	type testcase struct {
+		name   string
+		output string
+		err    error
+		expect error
+	}
+
+	cases := []testcase{{
+		name:   "when we cannot query NetworkManager",
+		err:    errors.New("mocked error"),
+		expect: nil,
+	}, {
+		name:   "when the network is metered",
+		output: "u 1\n",
+		expect: ErrMeteredNetwork,
+	}, {
+		name:   "when NetworkManager guesses the network is metered",
+		output: "u 3\n",
+		expect: ErrMeteredNetwork,
+	}, {
+		name:   "when the network is not metered",
+		output: "u 2\n",
+		expect: nil,
+	}, {
+		name:   "when NetworkManager does not know",
+		output: "u 0\n",
+		expect: nil,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := &NetworkManagerMeteredGate{
+				Logger: model.DiscardLogger,
+				output: func(command string, args ...string) ([]byte, error) {
+					return []byte(tc.output), tc.err
+				},
+			}
+			if err := g.Check(context.Background()); !errors.Is(err, tc.expect) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+// Package scheduler runs measurement jobs periodically. Each job runs at
+// memoryless intervals independently of the other jobs, and we persist the
+// schedule inside a key-value store, such that restarting the process does
+// not reset it. Before running a job, we consult a list of gates, which
+// allow us to skip running, e.g., when the network is metered.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-engine/pkg/memoryless"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/oonirun"
+)
+
+// stateKey is the key-value store key containing the schedule.
+const stateKey = "scheduler.state"
+
+// Job is a job to run periodically. Exactly one of Descriptor
+// and URL MUST be set.
+type Job struct {
+	// Name is the MANDATORY name uniquely identifying the job.
+	Name string `json:"name"`
+
+	// Interval is the MANDATORY expected interval between runs in seconds.
+	Interval int64 `json:"interval"`
+
+	// Descriptor is the OPTIONAL OONI Run v2 descriptor describing the group
+	// of experiments to run.
+	Descriptor *oonirun.V2Descriptor `json:"descriptor,omitempty"`
+
+	// URL is the OPTIONAL URL of the OONI Run link to run.
+	URL string `json:"url,omitempty"`
+}
+
+// ErrInvalidJob indicates that a job is invalid.
+var ErrInvalidJob = errors.New("scheduler: invalid job")
+
+// validate returns an error if the job is invalid.
+func (j *Job) validate() error {
+	if j.Name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidJob)
+	}
+	if j.Interval <= 0 {
+		return fmt.Errorf("%w: %s: interval must be positive", ErrInvalidJob, j.Name)
+	}
+	if (j.Descriptor == nil) == (j.URL == "") {
+		return fmt.Errorf("%w: %s: exactly one of descriptor and url must be set", ErrInvalidJob, j.Name)
+	}
+	return nil
+}
+
+// memoryless returns the memoryless config for the job.
+func (j *Job) memoryless() memoryless.Config {
+	expected := time.Duration(j.Interval) * time.Second
+	return memoryless.Config{
+		Expected: expected,
+		Min:      expected / 10,
+		Max:      expected * 5 / 2,
+	}
+}
+
+// JobState is the persistent state of a job.
+type JobState struct {
+	// LastFailure is the error that occurred during the last run, if any.
+	LastFailure string `json:"last_failure,omitempty"`
+
+	// LastRun is the last time we ran the job.
+	LastRun time.Time `json:"last_run"`
+
+	// LastSkipReason is the reason why we last skipped running, if any.
+	LastSkipReason string `json:"last_skip_reason,omitempty"`
+
+	// NextRun is the time after which we should run the job again.
+	NextRun time.Time `json:"next_run"`
+
+	// Runs is the number of times we ran the job.
+	Runs int64 `json:"runs"`
+
+	// Skips is the number of times we skipped running the job.
+	Skips int64 `json:"skips"`
+}
+
+// Runner runs jobs.
+type Runner interface {
+	RunJob(ctx context.Context, job *Job) error
+}
+
+// Gate decides whether we can run a job now.
+type Gate interface {
+	// Check returns nil when we can run a job and otherwise an
+	// error explaining why we should skip running it.
+	Check(ctx context.Context) error
+}
+
+// Scheduler runs jobs periodically. We run a single job at a time, to
+// avoid jobs interfering with each other's measurements.
+//
+// You MUST NOT modify the public fields after calling Run.
+type Scheduler struct {
+	// Gates contains the OPTIONAL gates to consult before running a job.
+	Gates []Gate
+
+	// Jobs contains the MANDATORY jobs to run.
+	Jobs []*Job
+
+	// Logger is the MANDATORY logger to use.
+	Logger model.Logger
+
+	// Runner is the MANDATORY [Runner] to use.
+	Runner Runner
+
+	// Store is the MANDATORY key-value store holding the schedule.
+	Store model.KeyValueStore
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// running is the name of the running job, if any.
+	running string
+
+	// startedAt is when we started running.
+	startedAt time.Time
+
+	// state maps a job name to its state.
+	state map[string]*JobState
+
+	// timeNow is the OPTIONAL function to get the current time.
+	timeNow func() time.Time
+}
+
+// NewScheduler creates a new [*Scheduler] instance. This function
+// returns an error if the jobs are invalid.
+func NewScheduler(store model.KeyValueStore, runner Runner,
+	logger model.Logger, jobs ...*Job) (*Scheduler, error) {
+	if len(jobs) <= 0 {
+		return nil, fmt.Errorf("%w: no jobs to run", ErrInvalidJob)
+	}
+	names := make(map[string]bool)
+	for _, job := range jobs {
+		if err := job.validate(); err != nil {
+			return nil, err
+		}
+		if names[job.Name] {
+			return nil, fmt.Errorf("%w: %s: duplicate name", ErrInvalidJob, job.Name)
+		}
+		names[job.Name] = true
+	}
+	s := &Scheduler{
+		Jobs:   jobs,
+		Logger: logger,
+		Runner: runner,
+		Store:  store,
+	}
+	return s, nil
+}
+
+// Run runs the jobs until the context is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.loadState()
+	for {
+		job, when := s.next()
+		s.Logger.Infof("scheduler: next job is %s at %s", job.Name, when.UTC().Format(time.RFC3339))
+		timer := time.NewTimer(when.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		s.runJob(ctx, job)
+	}
+}
+
+// loadState loads the schedule from the key-value store.
+func (s *Scheduler) loadState() {
+	state := make(map[string]*JobState)
+	if data, err := s.Store.Get(stateKey); err == nil {
+		if err := json.Unmarshal(data, &state); err != nil {
+			s.Logger.Warnf("scheduler: cannot parse the schedule: %s", err.Error())
+			state = make(map[string]*JobState)
+		}
+	}
+	s.mu.Lock()
+	s.startedAt = s.now()
+	s.state = state
+	s.mu.Unlock()
+}
+
+// saveState saves the schedule into the key-value store. This function
+// assumes the caller is holding the mutex.
+func (s *Scheduler) saveState() {
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		s.Logger.Warnf("scheduler: cannot serialize the schedule: %s", err.Error())
+		return
+	}
+	if err := s.Store.Set(stateKey, data); err != nil {
+		s.Logger.Warnf("scheduler: cannot save the schedule: %s", err.Error())
+	}
+}
+
+// next returns the next job to run and when to run it. We schedule jobs
+// we have never seen before, such that new jobs don't all run at once.
+func (s *Scheduler) next() (*Job, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var (
+		nextJob  *Job
+		nextTime time.Time
+	)
+	for _, job := range s.Jobs {
+		st := s.state[job.Name]
+		if st == nil {
+			st = &JobState{}
+			s.state[job.Name] = st
+		}
+		if st.NextRun.IsZero() {
+			st.NextRun = s.now().Add(s.delay(job))
+			s.saveState()
+		}
+		if nextJob == nil || st.NextRun.Before(nextTime) {
+			nextJob, nextTime = job, st.NextRun
+		}
+	}
+	return nextJob, nextTime
+}
+
+// runJob consults the gates, runs the job if possible, and reschedules it.
+func (s *Scheduler) runJob(ctx context.Context, job *Job) {
+	for _, gate := range s.Gates {
+		if err := gate.Check(ctx); err != nil {
+			s.Logger.Infof("scheduler: skipping %s: %s", job.Name, err.Error())
+			s.update(job, func(st *JobState) {
+				st.LastSkipReason = err.Error()
+				st.Skips++
+			})
+			return
+		}
+	}
+	s.Logger.Infof("scheduler: running %s", job.Name)
+	s.mu.Lock()
+	s.running = job.Name
+	s.mu.Unlock()
+	err := s.Runner.RunJob(ctx, job)
+	if err != nil {
+		s.Logger.Warnf("scheduler: running %s failed: %s", job.Name, err.Error())
+	}
+	s.update(job, func(st *JobState) {
+		st.LastFailure = ""
+		if err != nil {
+			st.LastFailure = err.Error()
+		}
+		st.LastRun = s.now()
+		st.LastSkipReason = ""
+		st.Runs++
+	})
+}
+
+// update updates the job state, reschedules the job, and saves the schedule.
+func (s *Scheduler) update(job *Job, fn func(st *JobState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = ""
+	st := s.state[job.Name]
+	fn(st)
+	st.NextRun = s.now().Add(s.delay(job))
+	s.saveState()
+}
+
+// delay returns the delay before running job again.
+func (s *Scheduler) delay(job *Job) time.Duration {
+	d, err := memoryless.Duration(job.memoryless())
+	if err != nil {
+		return time.Duration(job.Interval) * time.Second // should not happen
+	}
+	return d
+}
+
+// now returns the current time.
+func (s *Scheduler) now() time.Time {
+	if s.timeNow != nil {
+		return s.timeNow()
+	}
+	return time.Now()
+}
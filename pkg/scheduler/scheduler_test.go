@@ -0,0 +1,243 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-engine/pkg/kvstore"
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/oonirun"
+)
+
+// runnerFunc adapts a func to be a [Runner].
+type runnerFunc func(ctx context.Context, job *Job) error
+
+func (fx runnerFunc) RunJob(ctx context.Context, job *Job) error {
+	return fx(ctx, job)
+}
+
+// gateFunc adapts a func to be a [Gate].
+type gateFunc func(ctx context.Context) error
+
+func (fx gateFunc) Check(ctx context.Context) error {
+	return fx(ctx)
+}
+
+func TestNewScheduler(t *testing.T) {
+	descr := &oonirun.V2Descriptor{}
+
+	type testcase struct {
+		name string
+		jobs []*Job
+	}
+
+	cases := []testcase{{
+		name: "without jobs",
+		jobs: nil,
+	}, {
+		name: "with empty name",
+		jobs: []*Job{{Interval: 10, Descriptor: descr}},
+	}, {
+		name: "with zero interval",
+		jobs: []*Job{{Name: "a", Descriptor: descr}},
+	}, {
+		name: "with neither descriptor nor URL",
+		jobs: []*Job{{Name: "a", Interval: 10}},
+	}, {
+		name: "with both descriptor and URL",
+		jobs: []*Job{{Name: "a", Interval: 10, Descriptor: descr, URL: "https://example.com/"}},
+	}, {
+		name: "with duplicate names",
+		jobs: []*Job{{Name: "a", Interval: 10, Descriptor: descr}, {Name: "a", Interval: 10, URL: "https://example.com/"}},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewScheduler(&kvstore.Memory{}, nil, model.DiscardLogger, tc.jobs...)
+			if !errors.Is(err, ErrInvalidJob) {
+				t.Fatal("unexpected error", err)
+			}
+			if s != nil {
+				t.Fatal("expected nil scheduler")
+			}
+		})
+	}
+}
+
+// newSchedulerForTesting creates a scheduler for testing where the
+// job named "due" must run immediately and the job named "later" must
+// run in a long time. The given runner cancels the context.
+func newSchedulerForTesting(t *testing.T, runner runnerFunc) (*Scheduler, *kvstore.Memory) {
+	store := &kvstore.Memory{}
+	state := map[string]*JobState{
+		"due":   {NextRun: time.Now().Add(-time.Minute)},
+		"later": {NextRun: time.Now().Add(time.Hour)},
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(stateKey, data); err != nil {
+		t.Fatal(err)
+	}
+	jobs := []*Job{
+		{Name: "later", Interval: 3600, URL: "https://example.com/"},
+		{Name: "due", Interval: 3600, Descriptor: &oonirun.V2Descriptor{}},
+	}
+	s, err := NewScheduler(store, runner, model.DiscardLogger, jobs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, store
+}
+
+// loadStateForTesting loads the schedule from the store.
+func loadStateForTesting(t *testing.T, store model.KeyValueStore) map[string]*JobState {
+	data, err := store.Get(stateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state map[string]*JobState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestSchedulerRun(t *testing.T) {
+	t.Run("we run the job that is due and reschedule it", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var ran []string
+		s, store := newSchedulerForTesting(t, func(ctx context.Context, job *Job) error {
+			ran = append(ran, job.Name)
+			cancel()
+			return errors.New("mocked error")
+		})
+		if err := s.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(ran) != 1 || ran[0] != "due" {
+			t.Fatal("unexpected jobs", ran)
+		}
+		state := loadStateForTesting(t, store)
+		due := state["due"]
+		if due.Runs != 1 || due.LastFailure != "mocked error" || due.LastRun.IsZero() {
+			t.Fatalf("unexpected state %+v", due)
+		}
+		if !due.NextRun.After(due.LastRun) {
+			t.Fatal("did not reschedule the job")
+		}
+		if state["later"].Runs != 0 {
+			t.Fatal("should not have run the other job")
+		}
+	})
+
+	t.Run("we skip the job when a gate says so", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s, store := newSchedulerForTesting(t, func(ctx context.Context, job *Job) error {
+			t.Fatal("should not be called")
+			return nil
+		})
+		s.Gates = []Gate{gateFunc(func(ctx context.Context) error {
+			cancel()
+			return ErrMeteredNetwork
+		})}
+		if err := s.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatal("unexpected error", err)
+		}
+		due := loadStateForTesting(t, store)["due"]
+		if due.Runs != 0 || due.Skips != 1 || due.LastSkipReason != ErrMeteredNetwork.Error() {
+			t.Fatalf("unexpected state %+v", due)
+		}
+		if due.NextRun.Before(time.Now()) {
+			t.Fatal("did not reschedule the job")
+		}
+	})
+
+	t.Run("we schedule new jobs and persist the schedule", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel() // stop as soon as we have scheduled the jobs
+		store := &kvstore.Memory{}
+		job := &Job{Name: "new", Interval: 3600, URL: "https://example.com/"}
+		s, err := NewScheduler(store, nil, model.DiscardLogger, job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatal("unexpected error", err)
+		}
+		st := loadStateForTesting(t, store)["new"]
+		if st == nil || !st.NextRun.After(time.Now()) {
+			t.Fatalf("unexpected state %+v", st)
+		}
+	})
+
+	t.Run("we ignore an unparseable schedule", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		store := &kvstore.Memory{}
+		if err := store.Set(stateKey, []byte("{")); err != nil {
+			t.Fatal(err)
+		}
+		job := &Job{Name: "new", Interval: 3600, URL: "https://example.com/"}
+		s, err := NewScheduler(store, nil, model.DiscardLogger, job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatal("unexpected error", err)
+		}
+		if st := loadStateForTesting(t, store)["new"]; st == nil {
+			t.Fatal("expected to see the new job")
+		}
+	})
+}
+
+func TestSchedulerServeHTTP(t *testing.T) {
+	t.Run("we return the status", func(t *testing.T) {
+		s, _ := newSchedulerForTesting(t, nil)
+		s.loadState()
+		s.running = "due"
+		srv := httptest.NewServer(s)
+		defer srv.Close()
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+		var status Status
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			t.Fatal(err)
+		}
+		if status.Running != "due" || len(status.Jobs) != 2 {
+			t.Fatalf("unexpected status %+v", status)
+		}
+		if status.Jobs[0].Name != "later" || status.Jobs[0].NextRun.IsZero() {
+			t.Fatalf("unexpected job status %+v", status.Jobs[0])
+		}
+	})
+
+	t.Run("we only accept GET", func(t *testing.T) {
+		s, _ := newSchedulerForTesting(t, nil)
+		srv := httptest.NewServer(s)
+		defer srv.Close()
+		resp, err := http.Post(srv.URL, "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+	})
+}
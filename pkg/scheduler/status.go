@@ -0,0 +1,68 @@
+package scheduler
+
+//
+// Status endpoint
+//
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Status is the status of the [*Scheduler].
+type Status struct {
+	// Jobs contains the status of each job.
+	Jobs []JobStatus `json:"jobs"`
+
+	// Running is the name of the running job, if any.
+	Running string `json:"running,omitempty"`
+
+	// StartedAt is when the scheduler started running.
+	StartedAt time.Time `json:"started_at"`
+}
+
+// JobStatus is the status of a job.
+type JobStatus struct {
+	// Name is the job name.
+	Name string `json:"name"`
+
+	// JobState is the job state.
+	JobState
+}
+
+// Status returns the current status.
+func (s *Scheduler) Status() *Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := &Status{
+		Jobs:      []JobStatus{},
+		Running:   s.running,
+		StartedAt: s.startedAt,
+	}
+	for _, job := range s.Jobs {
+		js := JobStatus{Name: job.Name}
+		if st := s.state[job.Name]; st != nil {
+			js.JobState = *st
+		}
+		status.Jobs = append(status.Jobs, js)
+	}
+	return status
+}
+
+var _ http.Handler = &Scheduler{}
+
+// ServeHTTP implements http.Handler by serving the JSON status.
+func (s *Scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := json.Marshal(s.Status())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,134 @@
+package testingx
+
+//
+// Oblivious DNS-over-HTTPS (RFC9230) target and proxy.
+//
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ooni/probe-engine/pkg/model"
+	"github.com/ooni/probe-engine/pkg/odohx"
+)
+
+// odohMaxBodySize is the maximum body size accepted by the ODoH target and proxy.
+const odohMaxBodySize = 1 << 16
+
+// ObliviousDNSOverHTTPSTargetHandler is an [http.Handler] implementing an
+// Oblivious DNS-over-HTTPS target. It serves its configs at [odohx.ConfigsPath]
+// and answers to the queries POSTed to any other path.
+type ObliviousDNSOverHTTPSTargetHandler struct {
+	// KeyPair is the MANDATORY key pair to use.
+	KeyPair *odohx.KeyPair
+
+	// RoundTripper is the MANDATORY round tripper to use.
+	RoundTripper DNSRoundTripper
+}
+
+var _ http.Handler = &ObliviousDNSOverHTTPSTargetHandler{}
+
+// ServeHTTP implements [http.Handler].
+func (p *ObliviousDNSOverHTTPSTargetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && r.URL.Path == odohx.ConfigsPath {
+		_, _ = w.Write(odohx.MarshalConfigs(p.KeyPair.Config))
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("content-type") != odohx.ContentType {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	rawQuery, err := io.ReadAll(io.LimitReader(r.Body, odohMaxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	query, rc, err := p.KeyPair.DecryptQuery(rawQuery)
+	if err != nil {
+		// RFC9230 Section 4.3 says we should use 401 when the key ID is unknown
+		// such that the client knows it should refresh the configs
+		if errors.Is(err, odohx.ErrUnknownKeyID) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	response, err := p.RoundTripper.RoundTrip(r.Context(), query)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	rawResponse, err := rc.EncryptResponse(response, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", odohx.ContentType)
+	_, _ = w.Write(rawResponse)
+}
+
+// ObliviousDNSOverHTTPSProxyHandler is an [http.Handler] implementing an Oblivious
+// DNS-over-HTTPS proxy. It forwards the queries to the target indicated by the
+// targethost and targetpath query parameters, as described by RFC9230 Section 4.1.
+type ObliviousDNSOverHTTPSProxyHandler struct {
+	// Client is the MANDATORY HTTP client to use for talking to targets.
+	Client model.HTTPClient
+}
+
+var _ http.Handler = &ObliviousDNSOverHTTPSProxyHandler{}
+
+// ServeHTTP implements [http.Handler].
+func (p *ObliviousDNSOverHTTPSProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("content-type") != odohx.ContentType {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	targetHost := r.URL.Query().Get("targethost")
+	targetPath := r.URL.Query().Get("targetpath")
+	if targetHost == "" || !strings.HasPrefix(targetPath, "/") {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rawQuery, err := io.ReadAll(io.LimitReader(r.Body, odohMaxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	targetURL := &url.URL{Scheme: "https", Host: targetHost, Path: targetPath}
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, targetURL.String(), bytes.NewReader(rawQuery))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	req.Header.Set("content-type", odohx.ContentType)
+	req.Header.Set("accept", odohx.ContentType)
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	rawResponse, err := io.ReadAll(io.LimitReader(resp.Body, odohMaxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	if value := resp.Header.Get("content-type"); value != "" {
+		w.Header().Set("content-type", value)
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(rawResponse)
+}
@@ -0,0 +1,287 @@
+package testingx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-engine/pkg/mocks"
+	"github.com/ooni/probe-engine/pkg/odohx"
+	"github.com/ooni/probe-engine/pkg/runtimex"
+)
+
+func TestObliviousDNSOverHTTPSTargetHandler(t *testing.T) {
+	kp := runtimex.Try1(odohx.NewKeyPair())
+
+	// echo is a round tripper returning the query as the response
+	echo := DNSRoundTripperFunc(func(ctx context.Context, req []byte) ([]byte, error) {
+		return req, nil
+	})
+
+	t.Run("we serve the configs", func(t *testing.T) {
+		server := httptest.NewServer(&ObliviousDNSOverHTTPSTargetHandler{KeyPair: kp, RoundTripper: echo})
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + odohx.ConfigsPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+		config, err := odohx.ParseConfigs(runtimex.Try1(io.ReadAll(resp.Body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(kp.Config.Marshal(), config.Marshal()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we answer to queries", func(t *testing.T) {
+		server := httptest.NewServer(&ObliviousDNSOverHTTPSTargetHandler{KeyPair: kp, RoundTripper: echo})
+		defer server.Close()
+
+		query := []byte("deadbeef")
+		encrypted, qc := runtimex.Try2(odohx.EncryptQuery(kp.Config, query, 0))
+		req := runtimex.Try1(http.NewRequest("POST", server.URL+"/dns-query", bytes.NewReader(encrypted)))
+		req.Header.Set("content-type", odohx.ContentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+		if resp.Header.Get("content-type") != odohx.ContentType {
+			t.Fatal("unexpected content-type")
+		}
+		response, err := qc.DecryptResponse(runtimex.Try1(io.ReadAll(resp.Body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(query, response); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	type testconfig struct {
+		name         string
+		method       string
+		contentType  string
+		body         func() []byte
+		roundTripper DNSRoundTripper
+		expectStatus int
+	}
+
+	validQuery := func() []byte {
+		encrypted, _ := runtimex.Try2(odohx.EncryptQuery(kp.Config, []byte("deadbeef"), 0))
+		return encrypted
+	}
+
+	testcases := []testconfig{{
+		name:         "with invalid method",
+		method:       "PUT",
+		contentType:  odohx.ContentType,
+		body:         validQuery,
+		roundTripper: echo,
+		expectStatus: http.StatusMethodNotAllowed,
+	}, {
+		name:         "with invalid content-type",
+		method:       "POST",
+		contentType:  "application/dns-message",
+		body:         validQuery,
+		roundTripper: echo,
+		expectStatus: http.StatusUnsupportedMediaType,
+	}, {
+		name:        "with unknown key ID",
+		method:      "POST",
+		contentType: odohx.ContentType,
+		body: func() []byte {
+			other := runtimex.Try1(odohx.NewKeyPair())
+			encrypted, _ := runtimex.Try2(odohx.EncryptQuery(other.Config, []byte("deadbeef"), 0))
+			return encrypted
+		},
+		roundTripper: echo,
+		expectStatus: http.StatusUnauthorized,
+	}, {
+		name:        "with invalid message",
+		method:      "POST",
+		contentType: odohx.ContentType,
+		body: func() []byte {
+			return []byte{0x01}
+		},
+		roundTripper: echo,
+		expectStatus: http.StatusBadRequest,
+	}, {
+		name:        "with internal round trip error",
+		method:      "POST",
+		contentType: odohx.ContentType,
+		body:        validQuery,
+		roundTripper: DNSRoundTripperFunc(func(ctx context.Context, req []byte) ([]byte, error) {
+			return nil, errors.New("mocked error")
+		}),
+		expectStatus: http.StatusInternalServerError,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(&ObliviousDNSOverHTTPSTargetHandler{
+				KeyPair:      kp,
+				RoundTripper: tc.roundTripper,
+			})
+			defer server.Close()
+
+			req := runtimex.Try1(http.NewRequest(tc.method, server.URL+"/dns-query", bytes.NewReader(tc.body())))
+			req.Header.Set("content-type", tc.contentType)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatal("invalid status code: expected", tc.expectStatus, "got", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestObliviousDNSOverHTTPSProxyHandler(t *testing.T) {
+	// newClient returns a client that routes requests to the given handler.
+	newClient := func(handler http.Handler) *mocks.HTTPClient {
+		return &mocks.HTTPClient{
+			MockDo: func(req *http.Request) (*http.Response, error) {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				return rr.Result(), nil
+			},
+		}
+	}
+
+	t.Run("we forward queries to the target", func(t *testing.T) {
+		var gotURL string
+		target := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotURL = r.URL.String()
+			if r.Header.Get("content-type") != odohx.ContentType {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			w.Header().Set("content-type", odohx.ContentType)
+			_, _ = w.Write(runtimex.Try1(io.ReadAll(r.Body)))
+		})
+		server := httptest.NewServer(&ObliviousDNSOverHTTPSProxyHandler{Client: newClient(target)})
+		defer server.Close()
+
+		values := url.Values{}
+		values.Set("targethost", "odoh.cloudflare-dns.com")
+		values.Set("targetpath", "/dns-query")
+		req := runtimex.Try1(http.NewRequest(
+			"POST", server.URL+"/proxy?"+values.Encode(), bytes.NewReader([]byte("deadbeef"))))
+		req.Header.Set("content-type", odohx.ContentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Fatal("unexpected status code", resp.StatusCode)
+		}
+		if resp.Header.Get("content-type") != odohx.ContentType {
+			t.Fatal("unexpected content-type")
+		}
+		if diff := cmp.Diff([]byte("deadbeef"), runtimex.Try1(io.ReadAll(resp.Body))); diff != "" {
+			t.Fatal(diff)
+		}
+		if gotURL != "https://odoh.cloudflare-dns.com/dns-query" {
+			t.Fatal("unexpected target URL", gotURL)
+		}
+	})
+
+	type testconfig struct {
+		name         string
+		method       string
+		contentType  string
+		query        string
+		client       *mocks.HTTPClient
+		expectStatus int
+	}
+
+	okClient := newClient(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	testcases := []testconfig{{
+		name:         "with invalid method",
+		method:       "GET",
+		contentType:  odohx.ContentType,
+		query:        "targethost=odoh.cloudflare-dns.com&targetpath=%2Fdns-query",
+		client:       okClient,
+		expectStatus: http.StatusMethodNotAllowed,
+	}, {
+		name:         "with invalid content-type",
+		method:       "POST",
+		contentType:  "application/dns-message",
+		query:        "targethost=odoh.cloudflare-dns.com&targetpath=%2Fdns-query",
+		client:       okClient,
+		expectStatus: http.StatusUnsupportedMediaType,
+	}, {
+		name:         "without targethost",
+		method:       "POST",
+		contentType:  odohx.ContentType,
+		query:        "targetpath=%2Fdns-query",
+		client:       okClient,
+		expectStatus: http.StatusBadRequest,
+	}, {
+		name:         "with invalid targetpath",
+		method:       "POST",
+		contentType:  odohx.ContentType,
+		query:        "targethost=odoh.cloudflare-dns.com&targetpath=dns-query",
+		client:       okClient,
+		expectStatus: http.StatusBadRequest,
+	}, {
+		name:        "when we cannot contact the target",
+		method:      "POST",
+		contentType: odohx.ContentType,
+		query:       "targethost=odoh.cloudflare-dns.com&targetpath=%2Fdns-query",
+		client: &mocks.HTTPClient{
+			MockDo: func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("mocked error")
+			},
+		},
+		expectStatus: http.StatusBadGateway,
+	}, {
+		name:         "we forward the target status code",
+		method:       "POST",
+		contentType:  odohx.ContentType,
+		query:        "targethost=odoh.cloudflare-dns.com&targetpath=%2Fdns-query",
+		client:       okClient,
+		expectStatus: http.StatusTeapot,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(&ObliviousDNSOverHTTPSProxyHandler{Client: tc.client})
+			defer server.Close()
+
+			req := runtimex.Try1(http.NewRequest(
+				tc.method, server.URL+"/proxy?"+tc.query, bytes.NewReader([]byte("deadbeef"))))
+			req.Header.Set("content-type", tc.contentType)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatal("invalid status code: expected", tc.expectStatus, "got", resp.StatusCode)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package tunnel_test
 
 import (
 	"context"
-	"io/ioutil"
 	"testing"
 
 	"github.com/apex/log"
@@ -12,10 +11,7 @@ import (
 
 func TestFakeStartStop(t *testing.T) {
 	// no need to skip because the bootstrap is obviously fast
-	tunnelDir, err := ioutil.TempDir("testdata", "fake")
-	if err != nil {
-		t.Fatal(err)
-	}
+	tunnelDir := t.TempDir()
 	ctx := context.Background()
 	sess, err := engine.NewSession(ctx, engine.SessionConfig{
 		Logger:          log.Log,
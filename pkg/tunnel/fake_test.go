@@ -16,7 +16,7 @@ func TestFakeWithCancelledContext(t *testing.T) {
 	sess := &MockableSession{}
 	tunnel, _, err := fakeStart(ctx, &Config{
 		Session:   sess,
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 	})
 	if !errors.Is(err, context.Canceled) {
 		t.Fatal("not the error we expected")
@@ -47,7 +47,7 @@ func TestFakeWithFailingMkdirAll(t *testing.T) {
 	sess := &MockableSession{}
 	tunnel, _, err := fakeStart(ctx, &Config{
 		Session:   sess,
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 		testMkdirAll: func(dir string, mode os.FileMode) error {
 			return expected
 		},
@@ -66,7 +66,7 @@ func TestFakeSocks5NewFails(t *testing.T) {
 	sess := &MockableSession{}
 	tunnel, _, err := fakeStart(ctx, &Config{
 		Session:   sess,
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 		testSocks5New: func(conf *socks5.Config) (*socks5.Server, error) {
 			return nil, expected
 		},
@@ -85,7 +85,7 @@ func TestFakeNetListenFails(t *testing.T) {
 	sess := &MockableSession{}
 	tunnel, _, err := fakeStart(ctx, &Config{
 		Session:   sess,
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 		testNetListen: func(network, address string) (net.Listener, error) {
 			return nil, expected
 		},
@@ -2,7 +2,6 @@ package tunnel_test
 
 import (
 	"context"
-	"io/ioutil"
 	"testing"
 
 	"github.com/apex/log"
@@ -18,10 +17,7 @@ func TestPsiphonStartStop(t *testing.T) {
 	if !psiphonfeat.Enabled {
 		t.Skip("psiphon feature not enabled")
 	}
-	tunnelDir, err := ioutil.TempDir("testdata", "psiphon")
-	if err != nil {
-		t.Fatal(err)
-	}
+	tunnelDir := t.TempDir()
 	ctx := context.Background()
 	sess, err := engine.NewSession(ctx, engine.SessionConfig{
 		Logger:          log.Log,
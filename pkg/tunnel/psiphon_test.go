@@ -15,7 +15,7 @@ func TestPsiphonWithCancelledContext(t *testing.T) {
 	sess := &MockableSession{}
 	tunnel, _, err := psiphonStart(ctx, &Config{
 		Session:   sess,
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 	})
 	if !errors.Is(err, context.Canceled) {
 		t.Fatal("not the error we expected")
@@ -47,7 +47,7 @@ func TestPsiphonFetchPsiphonConfigFailure(t *testing.T) {
 	}
 	tunnel, _, err := psiphonStart(context.Background(), &Config{
 		Session:   sess,
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 	})
 	if !errors.Is(err, expected) {
 		t.Fatal("not the error we expected")
@@ -64,7 +64,7 @@ func TestPsiphonMkdirAllFailure(t *testing.T) {
 	}
 	tunnel, _, err := psiphonStart(context.Background(), &Config{
 		Session:   sess,
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 		testMkdirAll: func(path string, perm os.FileMode) error {
 			return expected
 		},
@@ -92,7 +92,7 @@ func TestPsiphonStartFailure(t *testing.T) {
 	}
 	tunnel, _, err := psiphonStart(context.Background(), &Config{
 		Session:   sess,
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 	})
 	if !errors.Is(err, expected) {
 		t.Fatal("not the error we expected")
@@ -2,7 +2,6 @@ package tunnel_test
 
 import (
 	"context"
-	"io/ioutil"
 	"testing"
 
 	"github.com/apex/log"
@@ -19,10 +18,7 @@ func TestTorStartStop(t *testing.T) {
 	if err != nil {
 		t.Skip("missing precondition for the test: tor not in PATH")
 	}
-	tunnelDir, err := ioutil.TempDir("testdata", "tor")
-	if err != nil {
-		t.Fatal(err)
-	}
+	tunnelDir := t.TempDir()
 	ctx := context.Background()
 	sess, err := engine.NewSession(ctx, engine.SessionConfig{
 		Logger:          log.Log,
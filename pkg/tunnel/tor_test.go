@@ -51,7 +51,7 @@ func TestTorWithCancelledContext(t *testing.T) {
 	cancel() // fail immediately
 	tun, _, err := torStart(ctx, &Config{
 		Session:   &MockableSession{},
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 	})
 	if !errors.Is(err, context.Canceled) {
 		t.Fatal("not the error we expected")
@@ -80,7 +80,7 @@ func TestTorBinaryNotFoundFailure(t *testing.T) {
 	tun, _, err := torStart(ctx, &Config{
 		Session:   &MockableSession{},
 		TorBinary: "/nonexistent/directory/tor",
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 	})
 	if !errors.Is(err, ErrCannotFindTorBinary) {
 		t.Fatal("not the error we expected", err)
@@ -95,7 +95,7 @@ func TestTorStartFailure(t *testing.T) {
 	ctx := context.Background()
 	tun, _, err := torStart(ctx, &Config{
 		Session:   &MockableSession{},
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 		testExecabsLookPath: func(name string) (string, error) {
 			return "/usr/local/bin/tor", nil
 		},
@@ -116,7 +116,7 @@ func TestTorGetProtocolInfoFailure(t *testing.T) {
 	ctx := context.Background()
 	tun, _, err := torStart(ctx, &Config{
 		Session:   &MockableSession{},
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 		testExecabsLookPath: func(name string) (string, error) {
 			return "/usr/local/bin/tor", nil
 		},
@@ -140,7 +140,7 @@ func TestTorEnableNetworkFailure(t *testing.T) {
 	ctx := context.Background()
 	tun, _, err := torStart(ctx, &Config{
 		Session:   &MockableSession{},
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 		testExecabsLookPath: func(name string) (string, error) {
 			return "/usr/local/bin/tor", nil
 		},
@@ -167,7 +167,7 @@ func TestTorGetInfoFailure(t *testing.T) {
 	ctx := context.Background()
 	tun, _, err := torStart(ctx, &Config{
 		Session:   &MockableSession{},
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 		testExecabsLookPath: func(name string) (string, error) {
 			return "/usr/local/bin/tor", nil
 		},
@@ -196,7 +196,7 @@ func TestTorGetInfoInvalidNumberOfKeys(t *testing.T) {
 	ctx := context.Background()
 	tun, _, err := torStart(ctx, &Config{
 		Session:   &MockableSession{},
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 		testExecabsLookPath: func(name string) (string, error) {
 			return "/usr/local/bin/tor", nil
 		},
@@ -225,7 +225,7 @@ func TestTorGetInfoInvalidKey(t *testing.T) {
 	ctx := context.Background()
 	tun, _, err := torStart(ctx, &Config{
 		Session:   &MockableSession{},
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 		testExecabsLookPath: func(name string) (string, error) {
 			return "/usr/local/bin/tor", nil
 		},
@@ -254,7 +254,7 @@ func TestTorGetInfoInvalidProxyType(t *testing.T) {
 	ctx := context.Background()
 	tun, _, err := torStart(ctx, &Config{
 		Session:   &MockableSession{},
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 		testExecabsLookPath: func(name string) (string, error) {
 			return "/usr/local/bin/tor", nil
 		},
@@ -283,7 +283,7 @@ func TestTorUnsupportedProxy(t *testing.T) {
 	ctx := context.Background()
 	tun, _, err := torStart(ctx, &Config{
 		Session:   &MockableSession{},
-		TunnelDir: "testdata",
+		TunnelDir: t.TempDir(),
 		testExecabsLookPath: func(name string) (string, error) {
 			return "/usr/local/bin/tor", nil
 		},
@@ -309,13 +309,7 @@ func TestTorUnsupportedProxy(t *testing.T) {
 }
 
 func TestMaybeCleanupTunnelDir(t *testing.T) {
-	fakeTunDir := filepath.Join("testdata", "fake-tun-dir")
-	if err := os.RemoveAll(fakeTunDir); err != nil {
-		t.Fatal(err)
-	}
-	if err := os.MkdirAll(fakeTunDir, 0700); err != nil {
-		t.Fatal(err)
-	}
+	fakeTunDir := t.TempDir()
 	fakeData := []byte("deadbeef\n")
 	logfile := filepath.Join(fakeTunDir, "tor.log")
 	if err := os.WriteFile(logfile, fakeData, 0600); err != nil {